@@ -0,0 +1,151 @@
+// Command migrate aplica las migraciones versionadas de un servicio.
+//
+//	migrate -service user-service status
+//	migrate -service user-service up
+//	migrate -service user-service down 1
+//	migrate -service user-service create add_organizations
+//
+// El DSN sale de -dsn o de la variable del servicio (USER_DB_DSN, AUTH_DB_DSN,
+// BILLING_DB_DSN, PAYMENT_DB_DSN).
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"saas-subscription-platform/libs/migrate"
+	authmigrations "saas-subscription-platform/services/auth-service/migrations"
+	billingmigrations "saas-subscription-platform/services/billing-service/migrations"
+	paymentmigrations "saas-subscription-platform/services/payment-service/migrations"
+	usermigrations "saas-subscription-platform/services/user-service/migrations"
+)
+
+// target es un servicio con migraciones propias.
+type target struct {
+	fsys   fs.FS
+	dsnEnv string
+	// dir es donde create escribe los archivos, relativo a la raíz del repo.
+	dir string
+}
+
+var targets = map[string]target{
+	"auth-service":    {fsys: authmigrations.FS, dsnEnv: "AUTH_DB_DSN", dir: "services/auth-service/migrations"},
+	"billing-service": {fsys: billingmigrations.FS, dsnEnv: "BILLING_DB_DSN", dir: "services/billing-service/migrations"},
+	"payment-service": {fsys: paymentmigrations.FS, dsnEnv: "PAYMENT_DB_DSN", dir: "services/payment-service/migrations"},
+	"user-service":    {fsys: usermigrations.FS, dsnEnv: "USER_DB_DSN", dir: "services/user-service/migrations"},
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	service := flags.String("service", "", "service whose migrations to run ("+strings.Join(targetNames(), ", ")+")")
+	dsn := flags.String("dsn", "", "postgres DSN (default: the service's *_DB_DSN env var)")
+	dir := flags.String("dir", "", "migrations directory for create (default: the service's migrations dir)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	t, ok := targets[*service]
+	if !ok {
+		return fmt.Errorf("unknown service %q (one of: %s)", *service, strings.Join(targetNames(), ", "))
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("missing command: status, up, down N or create NAME")
+	}
+	cmd, rest := flags.Arg(0), flags.Args()[1:]
+
+	if cmd == "create" {
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: create NAME")
+		}
+		if *dir == "" {
+			*dir = t.dir
+		}
+		up, down, err := migrate.Create(*dir, rest[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(up)
+		fmt.Println(down)
+		return nil
+	}
+
+	if *dsn == "" {
+		*dsn = os.Getenv(t.dsnEnv)
+	}
+	if *dsn == "" {
+		return fmt.Errorf("missing DSN: pass -dsn or set %s", t.dsnEnv)
+	}
+	db, err := sql.Open("pgx", *dsn)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	m, err := migrate.New(db, *service, t.fsys)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch cmd {
+	case "status":
+		states, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range states {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05Z07:00")
+			}
+			fmt.Printf("%03d  %-40s %s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	case "up":
+		n, err := m.Up(ctx)
+		fmt.Printf("applied %d migration(s)\n", n)
+		return err
+	case "down":
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: down N")
+		}
+		steps, err := strconv.Atoi(rest[0])
+		if err != nil || steps <= 0 {
+			return fmt.Errorf("down needs a positive number of migrations, got %q", rest[0])
+		}
+		n, err := m.Down(ctx, steps)
+		fmt.Printf("reverted %d migration(s)\n", n)
+		return err
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
+
+func targetNames() []string {
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
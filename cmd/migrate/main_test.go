@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"saas-subscription-platform/libs/migrate"
+)
+
+// Cada migración embebida tiene que cargar y poder revertirse.
+func TestTargetsHaveReversibleMigrations(t *testing.T) {
+	for name, target := range targets {
+		migrations, err := migrate.Load(target.fsys)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(migrations) == 0 {
+			t.Fatalf("%s: no migrations embedded", name)
+		}
+		for i, mig := range migrations {
+			if mig.Version != i+1 {
+				t.Fatalf("%s: expected version %d, got %03d_%s", name, i+1, mig.Version, mig.Name)
+			}
+			if strings.TrimSpace(mig.Down) == "" {
+				t.Fatalf("%s: %03d_%s has no down script", name, mig.Version, mig.Name)
+			}
+		}
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	if err := run([]string{"-service", "nope", "up"}); err == nil {
+		t.Fatal("expected error for unknown service")
+	}
+	if err := run([]string{"-service", "user-service", "-dsn", "postgres://x", "down", "0"}); err == nil {
+		t.Fatal("expected error for down 0")
+	}
+}
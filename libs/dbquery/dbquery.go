@@ -0,0 +1,55 @@
+// Package dbquery aplica deadlines por query y loguea las queries lentas con el request_id.
+package dbquery
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"saas-subscription-platform/libs/trace"
+)
+
+// Limits configura el deadline y el umbral de query lenta de un repositorio.
+// Los valores en cero desactivan cada límite.
+type Limits struct {
+	// Service identifica al servicio en los logs.
+	Service string
+	// Timeout es el máximo que puede durar una query, además del deadline del request.
+	Timeout time.Duration
+	// SlowThreshold es a partir de cuánto una query se loguea como lenta.
+	SlowThreshold time.Duration
+}
+
+// logf es reemplazable en tests.
+var logf = log.Printf
+
+// Start deriva de ctx el contexto de una query. El done devuelto libera el
+// deadline y loguea la query si fue lenta o se cortó por timeout; hay que
+// llamarlo cuando terminó de leerse el resultado.
+func (l Limits) Start(ctx context.Context, name string) (context.Context, func()) {
+	start := time.Now()
+	cancel := context.CancelFunc(func() {})
+	if l.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, l.Timeout)
+	}
+
+	return ctx, func() {
+		elapsed := time.Since(start)
+		err := ctx.Err()
+		cancel()
+
+		requestID := trace.RequestIDFromContext(ctx)
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			logf("query_timeout service=%s query=%s duration_ms=%d request_id=%s",
+				l.Service, name, elapsed.Milliseconds(), requestID)
+		case errors.Is(err, context.Canceled):
+			logf("query_canceled service=%s query=%s duration_ms=%d request_id=%s",
+				l.Service, name, elapsed.Milliseconds(), requestID)
+		case l.SlowThreshold > 0 && elapsed >= l.SlowThreshold:
+			logf("slow_query service=%s query=%s duration_ms=%d request_id=%s",
+				l.Service, name, elapsed.Milliseconds(), requestID)
+		}
+	}
+}
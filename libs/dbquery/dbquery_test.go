@@ -0,0 +1,67 @@
+package dbquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/libs/trace"
+)
+
+func captureLogs(t *testing.T) *[]string {
+	t.Helper()
+	var lines []string
+	prev := logf
+	logf = func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	t.Cleanup(func() { logf = prev })
+	return &lines
+}
+
+func TestStart_AppliesTimeout(t *testing.T) {
+	logs := captureLogs(t)
+	l := Limits{Service: "user-service", Timeout: 10 * time.Millisecond}
+
+	ctx, done := l.Start(trace.WithRequestID(context.Background(), "req-1"), "users.get_by_id")
+	<-ctx.Done()
+	done()
+
+	if len(*logs) != 1 || !strings.HasPrefix((*logs)[0], "query_timeout") {
+		t.Fatalf("expected timeout log, got %v", *logs)
+	}
+	if !strings.Contains((*logs)[0], "request_id=req-1") {
+		t.Fatalf("expected request_id in log, got %q", (*logs)[0])
+	}
+}
+
+func TestStart_KeepsParentDeadline(t *testing.T) {
+	captureLogs(t)
+	parent, cancel := context.WithCancel(context.Background())
+	ctx, done := Limits{Timeout: time.Hour}.Start(parent, "q")
+	defer done()
+
+	cancel()
+	if ctx.Err() == nil {
+		t.Fatal("expected query context to be canceled with its parent")
+	}
+}
+
+func TestStart_LogsSlowQueries(t *testing.T) {
+	logs := captureLogs(t)
+
+	_, done := Limits{Service: "billing-service", SlowThreshold: time.Millisecond}.Start(context.Background(), "invoices.list")
+	time.Sleep(2 * time.Millisecond)
+	done()
+	if len(*logs) != 1 || !strings.HasPrefix((*logs)[0], "slow_query service=billing-service query=invoices.list") {
+		t.Fatalf("expected slow query log, got %v", *logs)
+	}
+
+	_, done = Limits{SlowThreshold: time.Hour}.Start(context.Background(), "invoices.list")
+	done()
+	if len(*logs) != 1 {
+		t.Fatalf("fast query should not be logged, got %v", *logs)
+	}
+}
@@ -0,0 +1,50 @@
+// Package emailaddr normaliza y valida direcciones de email para que todos los
+// servicios las comparen de la misma forma.
+package emailaddr
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var ErrInvalidEmail = errors.New("invalid email")
+
+const (
+	maxLength      = 254 // RFC 5321, camino completo
+	maxLocalLength = 64
+)
+
+// Normalize recorta espacios, pasa a Unicode NFC y baja a minúsculas el
+// dominio. La parte local conserva su forma (algunos servidores distinguen
+// mayúsculas); la unicidad se resuelve comparando lower(email) en la base.
+func Normalize(s string) (string, error) {
+	s = norm.NFC.String(strings.TrimSpace(s))
+	if s == "" || utf8.RuneCountInString(s) > maxLength {
+		return "", ErrInvalidEmail
+	}
+
+	at := strings.LastIndexByte(s, '@')
+	if at <= 0 || at == len(s)-1 {
+		return "", ErrInvalidEmail
+	}
+	local, domain := s[:at], strings.ToLower(s[at+1:])
+	if utf8.RuneCountInString(local) > maxLocalLength {
+		return "", ErrInvalidEmail
+	}
+	s = local + "@" + domain
+
+	// ParseAddress acepta también "Nombre <a@b>" y comentarios: exigimos que
+	// la dirección parseada sea exactamente la entrada.
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Name != "" || addr.Address != s {
+		return "", ErrInvalidEmail
+	}
+	if strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") || strings.Contains(domain, "..") {
+		return "", ErrInvalidEmail
+	}
+	return s, nil
+}
@@ -0,0 +1,41 @@
+package emailaddr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"  Jane.Doe@Example.COM ": "Jane.Doe@example.com",
+		"jane+tag@example.com":    "jane+tag@example.com",
+		// "é" descompuesto (e + acento combinado) queda en su forma NFC.
+		"jose\u0301@example.com": "jos\u00e9@example.com",
+		"erased+u-1@invalid":     "erased+u-1@invalid",
+	}
+	for in, want := range cases {
+		got, err := Normalize(in)
+		if err != nil || got != want {
+			t.Fatalf("Normalize(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestNormalizeRejectsInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"plainaddress",
+		"@example.com",
+		"jane@",
+		"jane@@example.com",
+		"Jane <jane@example.com>",
+		"jane doe@example.com",
+		"jane@example..com",
+		"jane@.example.com",
+		strings.Repeat("a", 65) + "@example.com",
+	} {
+		if _, err := Normalize(in); err != ErrInvalidEmail {
+			t.Fatalf("Normalize(%q): expected ErrInvalidEmail, got %v", in, err)
+		}
+	}
+}
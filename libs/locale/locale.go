@@ -0,0 +1,120 @@
+// Package locale concentra la validación y el formateo dependientes del idioma
+// y la zona horaria del usuario, para que todos los servicios muestren montos y
+// fechas de la misma forma.
+package locale
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	_ "time/tzdata" // las imágenes mínimas no traen la base IANA
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const (
+	// DefaultLocale y DefaultTimezone son los valores de un usuario sin preferencias.
+	DefaultLocale   = "en"
+	DefaultTimezone = "UTC"
+
+	// HeaderLocale y HeaderTimezone propagan las preferencias del usuario entre servicios.
+	HeaderLocale   = "X-Internal-User-Locale"
+	HeaderTimezone = "X-Internal-User-Timezone"
+)
+
+var (
+	ErrInvalidLocale   = errors.New("invalid locale")
+	ErrInvalidTimezone = errors.New("invalid timezone")
+)
+
+// ParseLocale valida un tag BCP 47 y devuelve su forma canónica ("es-ar" -> "es-AR").
+func ParseLocale(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", ErrInvalidLocale
+	}
+	tag, err := language.Parse(s)
+	if err != nil || tag == language.Und {
+		return "", ErrInvalidLocale
+	}
+	return tag.String(), nil
+}
+
+// ParseTimezone valida un nombre de zona IANA ("America/Argentina/Buenos_Aires").
+func ParseTimezone(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	// "Local" depende del host, no es una preferencia válida.
+	if s == "" || s == "Local" {
+		return "", ErrInvalidTimezone
+	}
+	if _, err := time.LoadLocation(s); err != nil {
+		return "", ErrInvalidTimezone
+	}
+	return s, nil
+}
+
+// tag devuelve el tag del locale o el default si es inválido.
+func tag(loc string) language.Tag {
+	if t, err := language.Parse(loc); err == nil && t != language.Und {
+		return t
+	}
+	return language.MustParse(DefaultLocale)
+}
+
+// location devuelve la zona o UTC si es inválida.
+func location(tz string) *time.Location {
+	if tz == "" || tz == "Local" {
+		return time.UTC
+	}
+	l, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.UTC
+	}
+	return l
+}
+
+// FormatMoney formatea un monto en unidades mínimas (centavos) según el locale.
+// Una moneda desconocida se muestra como "1234.50 XYZ".
+func FormatMoney(amountMinor int64, currencyCode, loc string) string {
+	unit, err := currency.ParseISO(currencyCode)
+	if err != nil {
+		return fmt.Sprintf("%.2f %s", float64(amountMinor)/100, strings.ToUpper(currencyCode))
+	}
+	scale, _ := currency.Standard.Rounding(unit)
+	amount := float64(amountMinor) / math.Pow10(scale)
+	return message.NewPrinter(tag(loc)).Sprint(currency.Symbol(unit.Amount(amount)))
+}
+
+// dateLayouts asigna un formato de fecha y hora por región o idioma.
+var dateLayouts = map[string]string{
+	"US": "01/02/2006 3:04 PM MST",
+	"en": "02/01/2006 15:04 MST",
+	"es": "02/01/2006 15:04 MST",
+	"pt": "02/01/2006 15:04 MST",
+	"fr": "02/01/2006 15:04 MST",
+	"it": "02/01/2006 15:04 MST",
+	"de": "02.01.2006 15:04 MST",
+	"ja": "2006/01/02 15:04 MST",
+	"zh": "2006/01/02 15:04 MST",
+}
+
+// defaultDateLayout se usa para idiomas sin formato propio.
+const defaultDateLayout = "2006-01-02 15:04 MST"
+
+// FormatTime formatea t en la zona horaria y con el orden de fecha del locale.
+func FormatTime(t time.Time, tz, loc string) string {
+	lt := tag(loc)
+	layout := defaultDateLayout
+	base, _ := lt.Base()
+	region, conf := lt.Region()
+	if l, ok := dateLayouts[region.String()]; ok && conf == language.Exact {
+		layout = l
+	} else if l, ok := dateLayouts[base.String()]; ok {
+		layout = l
+	}
+	return t.In(location(tz)).Format(layout)
+}
@@ -0,0 +1,64 @@
+package locale
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLocale(t *testing.T) {
+	got, err := ParseLocale("es-ar")
+	if err != nil || got != "es-AR" {
+		t.Fatalf("expected es-AR, got %q (%v)", got, err)
+	}
+	for _, bad := range []string{"", "not a locale", "und"} {
+		if _, err := ParseLocale(bad); err != ErrInvalidLocale {
+			t.Fatalf("expected ErrInvalidLocale for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestParseTimezone(t *testing.T) {
+	if got, err := ParseTimezone("America/Argentina/Buenos_Aires"); err != nil || got != "America/Argentina/Buenos_Aires" {
+		t.Fatalf("unexpected result %q (%v)", got, err)
+	}
+	for _, bad := range []string{"", "Local", "Mars/Olympus"} {
+		if _, err := ParseTimezone(bad); err != ErrInvalidTimezone {
+			t.Fatalf("expected ErrInvalidTimezone for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	cases := []struct {
+		amount   int64
+		currency string
+		locale   string
+		want     string
+	}{
+		{123450, "USD", "en-US", "$ 1,234.50"},
+		{123450, "EUR", "de-DE", "€ 1.234,50"},
+		{123450, "EUR", "es-AR", "EUR 1.234,50"},
+		{1500, "JPY", "ja", "￥ 1,500"},
+		{999, "XYZ", "en", "9.99 XYZ"},
+		{500, "USD", "garbage", "$ 5.00"},
+	}
+	for _, tc := range cases {
+		if got := FormatMoney(tc.amount, tc.currency, tc.locale); got != tc.want {
+			t.Fatalf("FormatMoney(%d, %s, %s) = %q, want %q", tc.amount, tc.currency, tc.locale, got, tc.want)
+		}
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	ts := time.Date(2026, 3, 4, 18, 30, 0, 0, time.UTC)
+
+	if got := FormatTime(ts, "America/New_York", "en-US"); got != "03/04/2026 1:30 PM EST" {
+		t.Fatalf("unexpected en-US format %q", got)
+	}
+	if got := FormatTime(ts, "Europe/Berlin", "de"); got != "04.03.2026 19:30 CET" {
+		t.Fatalf("unexpected de format %q", got)
+	}
+	if got := FormatTime(ts, "", "ko"); got != "2026-03-04 18:30 UTC" {
+		t.Fatalf("unexpected default format %q", got)
+	}
+}
@@ -0,0 +1,111 @@
+// Package metadata valida los pares clave/valor libres que las integraciones
+// adjuntan a usuarios y facturas (estilo Stripe) y parsea sus filtros de query.
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInvalid envuelve todos los errores de validación.
+var ErrInvalid = errors.New("invalid metadata")
+
+const (
+	MaxKeys        = 50
+	MaxKeyLength   = 40
+	MaxValueLength = 500
+	// MaxFilters es cuántos pares metadata[k]=v acepta un listado.
+	MaxFilters = 5
+)
+
+// Validate controla cantidad y tamaño de las claves y valores. Con allowEmpty,
+// un valor "" es válido (en un update significa borrar la clave).
+func Validate(m map[string]string, allowEmpty bool) error {
+	if len(m) > MaxKeys {
+		return fmt.Errorf("%w: at most %d keys", ErrInvalid, MaxKeys)
+	}
+	for _, k := range sortedKeys(m) {
+		if err := validateKey(k); err != nil {
+			return err
+		}
+		v := m[k]
+		if v == "" && !allowEmpty {
+			return fmt.Errorf("%w: value for %q must not be empty", ErrInvalid, k)
+		}
+		if utf8.RuneCountInString(v) > MaxValueLength {
+			return fmt.Errorf("%w: value for %q must be at most %d characters", ErrInvalid, k, MaxValueLength)
+		}
+	}
+	return nil
+}
+
+// validateKey rechaza corchetes para que metadata[k]=v se parsee sin ambigüedad.
+func validateKey(k string) error {
+	switch {
+	case k == "":
+		return fmt.Errorf("%w: keys must not be empty", ErrInvalid)
+	case utf8.RuneCountInString(k) > MaxKeyLength:
+		return fmt.Errorf("%w: key %q must be at most %d characters", ErrInvalid, k, MaxKeyLength)
+	case strings.ContainsAny(k, "[]") || strings.IndexFunc(k, unicode.IsControl) >= 0:
+		return fmt.Errorf("%w: key %q contains invalid characters", ErrInvalid, k)
+	}
+	return nil
+}
+
+// Split separa un patch de update en las claves a setear y las que se borran
+// (valor ""). El merge se hace en la base para que sea atómico.
+func Split(patch map[string]string) (set map[string]string, unset []string) {
+	set = make(map[string]string, len(patch))
+	unset = []string{}
+	for _, k := range sortedKeys(patch) {
+		if patch[k] == "" {
+			unset = append(unset, k)
+			continue
+		}
+		set[k] = patch[k]
+	}
+	return set, unset
+}
+
+// ParseFilter lee los parámetros metadata[clave]=valor de un listado. Devuelve
+// nil si no hay ninguno.
+func ParseFilter(q url.Values) (map[string]string, error) {
+	var filter map[string]string
+	for param, values := range q {
+		if !strings.HasPrefix(param, "metadata[") || !strings.HasSuffix(param, "]") {
+			continue
+		}
+		key := param[len("metadata[") : len(param)-1]
+		if err := validateKey(key); err != nil {
+			return nil, err
+		}
+		if len(values) != 1 || values[0] == "" {
+			return nil, fmt.Errorf("%w: filter %s needs exactly one non-empty value", ErrInvalid, param)
+		}
+		if filter == nil {
+			filter = map[string]string{}
+		}
+		filter[key] = values[0]
+	}
+	if len(filter) > MaxFilters {
+		return nil, fmt.Errorf("%w: at most %d metadata filters", ErrInvalid, MaxFilters)
+	}
+	if err := Validate(filter, false); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
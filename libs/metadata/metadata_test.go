@@ -0,0 +1,62 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	if err := Validate(map[string]string{"crm_id": "C-42"}, false); err != nil {
+		t.Fatalf("Validate valid: %v", err)
+	}
+
+	tooMany := map[string]string{}
+	for i := 0; i <= MaxKeys; i++ {
+		tooMany[fmt.Sprintf("k%d", i)] = "v"
+	}
+	for name, m := range map[string]map[string]string{
+		"too many keys": tooMany,
+		"empty key":     {"": "v"},
+		"long key":      {strings.Repeat("k", MaxKeyLength+1): "v"},
+		"bracket key":   {"a[b]": "v"},
+		"long value":    {"k": strings.Repeat("v", MaxValueLength+1)},
+		"empty value":   {"k": ""},
+	} {
+		if err := Validate(m, false); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("%s: got %v, want ErrInvalid", name, err)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	set, unset := Split(map[string]string{"a": "1", "b": "", "c": ""})
+	if len(set) != 1 || set["a"] != "1" {
+		t.Fatalf("Split set = %v", set)
+	}
+	if len(unset) != 2 || unset[0] != "b" || unset[1] != "c" {
+		t.Fatalf("Split unset = %v", unset)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	q, _ := url.ParseQuery("metadata[crm_id]=C-42&status=paid")
+	got, err := ParseFilter(q)
+	if err != nil || len(got) != 1 || got["crm_id"] != "C-42" {
+		t.Fatalf("ParseFilter = %v, %v", got, err)
+	}
+
+	q, _ = url.ParseQuery("status=paid")
+	if got, err := ParseFilter(q); err != nil || got != nil {
+		t.Fatalf("ParseFilter without filters = %v, %v", got, err)
+	}
+
+	for _, raw := range []string{"metadata[]=x", "metadata[k]=", "metadata[k]=a&metadata[k]=b"} {
+		q, _ = url.ParseQuery(raw)
+		if _, err := ParseFilter(q); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("ParseFilter(%q): got %v, want ErrInvalid", raw, err)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+// Package migrate aplica migraciones SQL versionadas por servicio.
+//
+// Cada migración son dos archivos NNN_nombre.up.sql y NNN_nombre.down.sql. Las
+// aplicadas se registran en schema_migrations por servicio, y un advisory lock
+// serializa a las réplicas que arrancan a la vez.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockKey es el advisory lock de todas las migraciones: uno solo para todos
+// los servicios, así también se serializa el CREATE de schema_migrations.
+const lockKey int64 = 7_261_014_553
+
+var fileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+var (
+	// ErrNoDown: la migración no tiene script de rollback.
+	ErrNoDown = errors.New("migration has no down script")
+	// ErrInvalidName: el nombre de una migración nueva tiene que ser snake_case.
+	ErrInvalidName = errors.New("migration name must be snake_case")
+)
+
+// Migration es una versión con su SQL de ida y vuelta.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// State es una migración conocida y, si está aplicada, cuándo se aplicó.
+type State struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Load lee las migraciones de fsys ordenadas por versión. Ignora archivos que
+// no siguen el formato; falla si una versión se repite o no tiene up.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		m := fileRe.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %q: %w", e.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if strings.TrimSpace(mig.Up) == "" {
+			return nil, fmt.Errorf("migration %03d_%s has no up script", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator aplica las migraciones de un servicio sobre db.
+type Migrator struct {
+	db         *sql.DB
+	service    string
+	migrations []Migration
+	logf       func(format string, args ...interface{})
+}
+
+// New carga las migraciones de fsys para service.
+func New(db *sql.DB, service string, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, service: service, migrations: migrations, logf: log.Printf}, nil
+}
+
+// Status devuelve todas las migraciones conocidas con su estado.
+func (m *Migrator) Status(ctx context.Context) ([]State, error) {
+	var states []State
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		states = make([]State, 0, len(m.migrations))
+		for _, mig := range m.migrations {
+			s := State{Migration: mig}
+			if at, ok := applied[mig.Version]; ok {
+				s.AppliedAt = &at
+			}
+			states = append(states, s)
+		}
+		return nil
+	})
+	return states, err
+}
+
+// Up aplica todas las migraciones pendientes, en orden de versión. Devuelve
+// cuántas aplicó; si una falla, las anteriores quedan aplicadas.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range Pending(m.migrations, applied) {
+			if err := m.apply(ctx, conn, mig, mig.Up, true); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down revierte las últimas n migraciones aplicadas.
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	count := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range Applied(m.migrations, applied, n) {
+			if strings.TrimSpace(mig.Down) == "" {
+				return fmt.Errorf("%03d_%s: %w", mig.Version, mig.Name, ErrNoDown)
+			}
+			if err := m.apply(ctx, conn, mig, mig.Down, false); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Pending devuelve las migraciones sin aplicar, en orden de versión.
+func Pending(all []Migration, applied map[int]time.Time) []Migration {
+	var pending []Migration
+	for _, mig := range all {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+	return pending
+}
+
+// Applied devuelve hasta n migraciones aplicadas, de la más nueva a la más vieja.
+func Applied(all []Migration, applied map[int]time.Time, n int) []Migration {
+	var out []Migration
+	for i := len(all) - 1; i >= 0 && len(out) < n; i-- {
+		if _, ok := applied[all[i].Version]; ok {
+			out = append(out, all[i])
+		}
+	}
+	return out
+}
+
+// locked corre fn en una conexión dedicada con el advisory lock tomado: el
+// lock es de sesión, así que todo tiene que pasar por la misma conexión.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Con ctx cancelado igual hay que soltar el lock antes de devolver la conexión al pool.
+		_, _ = conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey)
+	}()
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			service TEXT NOT NULL,
+			version BIGINT NOT NULL,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (service, version)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations WHERE service = $1`, m.service)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// apply corre el script y actualiza schema_migrations en la misma transacción.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration, script string, up bool) error {
+	direction := "down"
+	if up {
+		direction = "up"
+	}
+	start := time.Now()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Sin argumentos el driver usa el protocolo simple y acepta varias sentencias.
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("%s %03d_%s: %w", direction, mig.Version, mig.Name, err)
+	}
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (service, version, name) VALUES ($1, $2, $3)`,
+			m.service, mig.Version, mig.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE service = $1 AND version = $2`,
+			m.service, mig.Version)
+	}
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	m.logf("migration_applied service=%s direction=%s version=%d name=%s duration_ms=%d",
+		m.service, direction, mig.Version, mig.Name, time.Since(start).Milliseconds())
+	return nil
+}
+
+// Create escribe en dir los archivos de la siguiente versión y devuelve sus rutas.
+func Create(dir, name string) (string, string, error) {
+	if !regexp.MustCompile(`^[a-z0-9_]+$`).MatchString(name) {
+		return "", "", ErrInvalidName
+	}
+	existing, err := Load(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+	next := 1
+	if len(existing) > 0 {
+		next = existing[len(existing)-1].Version + 1
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%03d_%s", next, name))
+	up, down := base+".up.sql", base+".down.sql"
+	for _, path := range []string{up, down} {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", err
+		}
+		// Un up vacío no carga: el comentario deja el par válido hasta escribir el SQL.
+		_, err = fmt.Fprintf(f, "-- %s\n", strings.ReplaceAll(name, "_", " "))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return up, down, nil
+}
@@ -0,0 +1,101 @@
+package migrate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_add_role.up.sql":       {Data: []byte("ALTER TABLE users ADD COLUMN role TEXT;")},
+		"002_add_role.down.sql":     {Data: []byte("ALTER TABLE users DROP COLUMN role;")},
+		"001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id UUID);")},
+		"001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"010_seed.up.sql":           {Data: []byte("INSERT INTO users VALUES (gen_random_uuid());")},
+		"migrations.go":             {Data: []byte("package migrations")},
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(migrations) != 3 {
+		t.Fatalf("expected 3 migrations, got %d", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[1].Version != 2 || migrations[2].Version != 10 {
+		t.Fatalf("migrations not sorted by version: %+v", migrations)
+	}
+	if migrations[1].Name != "add_role" || migrations[1].Down == "" {
+		t.Fatalf("unexpected migration: %+v", migrations[1])
+	}
+	if migrations[2].Down != "" {
+		t.Fatalf("expected no down for 010, got %q", migrations[2].Down)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"down without up": {
+			"001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		},
+		"same version, two names": {
+			"001_create_users.up.sql": {Data: []byte("CREATE TABLE users (id UUID);")},
+			"001_create_roles.up.sql": {Data: []byte("CREATE TABLE roles (id UUID);")},
+		},
+	}
+	for name, fsys := range cases {
+		if _, err := Load(fsys); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestPendingAndApplied(t *testing.T) {
+	all := []Migration{{Version: 1}, {Version: 2}, {Version: 3}, {Version: 4}}
+	// 2 quedó sin aplicar (por ejemplo, una rama mergeada tarde).
+	applied := map[int]time.Time{1: {}, 3: {}, 4: {}}
+
+	pending := Pending(all, applied)
+	if len(pending) != 1 || pending[0].Version != 2 {
+		t.Fatalf("unexpected pending: %+v", pending)
+	}
+
+	last := Applied(all, applied, 2)
+	if len(last) != 2 || last[0].Version != 4 || last[1].Version != 3 {
+		t.Fatalf("unexpected applied: %+v", last)
+	}
+	if got := Applied(all, applied, 10); len(got) != 3 {
+		t.Fatalf("expected all 3 applied, got %d", len(got))
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "007_normalize_emails.up.sql"), []byte("SELECT 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	up, down, err := Create(dir, "add_organizations")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if filepath.Base(up) != "008_add_organizations.up.sql" || filepath.Base(down) != "008_add_organizations.down.sql" {
+		t.Fatalf("unexpected files: %s %s", up, down)
+	}
+
+	migrations, err := Load(os.DirFS(dir))
+	if err != nil {
+		t.Fatalf("created files should load: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(migrations))
+	}
+
+	if _, _, err := Create(dir, "Add-Orgs"); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("expected ErrInvalidName, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+// Package notify abstrae el envío de notificaciones al usuario (email, etc.).
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender es el punto de extensión: cualquier canal (SMTP, cola, proveedor
+// transaccional) solo tiene que implementar Send.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender escribe la notificación en el log. Es el sender por defecto en desarrollo.
+type LogSender struct{}
+
+func (LogSender) Send(_ context.Context, msg Message) error {
+	log.Printf("notification_sent to=%s subject=%q body=%q", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// SMTPSender envía la notificación como email de texto plano.
+type SMTPSender struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+func NewSMTPSender(addr, from, username, password string) *SMTPSender {
+	s := &SMTPSender{Addr: addr, From: from}
+	if username != "" {
+		host := addr
+		if i := strings.LastIndex(addr, ":"); i >= 0 {
+			host = addr[:i]
+		}
+		s.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+func (s *SMTPSender) Send(_ context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return fmt.Errorf("notify: invalid header value")
+	}
+	body := "From: " + s.From + "\r\n" +
+		"To: " + msg.To + "\r\n" +
+		"Subject: " + msg.Subject + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n\r\n" +
+		msg.Body
+	return smtp.SendMail(s.Addr, s.Auth, s.From, []string{msg.To}, []byte(body))
+}
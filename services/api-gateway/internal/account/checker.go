@@ -0,0 +1,105 @@
+// Package account consulta a user-service el estado de la cuenta del token,
+// con un cache corto para no agregar un round-trip por request.
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// StatusNotFound se devuelve cuando la cuenta ya no existe (purgada).
+const StatusNotFound = "not_found"
+
+// maxEntries acota el cache; al superarlo se descartan las entradas vencidas.
+const maxEntries = 10000
+
+type entry struct {
+	status    string
+	expiresAt time.Time
+}
+
+// HTTPChecker implementa middleware.AccountChecker contra GET /users/{id}.
+type HTTPChecker struct {
+	baseURL string
+	client  *http.Client
+	ttl     time.Duration
+
+	mu    sync.Mutex
+	cache map[string]entry
+	now   func() time.Time
+}
+
+// NewHTTPChecker crea el checker. ttl es la demora máxima con la que el
+// gateway ve una suspensión o desactivación.
+func NewHTTPChecker(userServiceURL string, ttl time.Duration) *HTTPChecker {
+	return &HTTPChecker{
+		baseURL: userServiceURL,
+		client:  &http.Client{Timeout: 2 * time.Second},
+		ttl:     ttl,
+		cache:   make(map[string]entry),
+		now:     time.Now,
+	}
+}
+
+func (c *HTTPChecker) Status(ctx context.Context, userID string) (string, error) {
+	now := c.now()
+
+	c.mu.Lock()
+	if e, ok := c.cache[userID]; ok && now.Before(e.expiresAt) {
+		c.mu.Unlock()
+		return e.status, nil
+	}
+	c.mu.Unlock()
+
+	status, err := c.fetch(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	if len(c.cache) >= maxEntries {
+		for k, e := range c.cache {
+			if !now.Before(e.expiresAt) {
+				delete(c.cache, k)
+			}
+		}
+	}
+	c.cache[userID] = entry{status: status, expiresAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+
+	return status, nil
+}
+
+func (c *HTTPChecker) fetch(ctx context.Context, userID string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/users/"+url.PathEscape(userID), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Internal-User-ID", "api-gateway")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return StatusNotFound, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("account check: unexpected status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("account check: %w", err)
+	}
+	return body.Status, nil
+}
@@ -0,0 +1,66 @@
+package account
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPChecker_CachesStatus(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path != "/users/user-1" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("X-Internal-User-ID") != "api-gateway" {
+			t.Errorf("missing internal user header")
+		}
+		_, _ = w.Write([]byte(`{"id":"user-1","status":"suspended"}`))
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	c := NewHTTPChecker(srv.URL, 30*time.Second)
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		status, err := c.Status(context.Background(), "user-1")
+		if err != nil || status != "suspended" {
+			t.Fatalf("expected suspended, got %q err=%v", status, err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 upstream call, got %d", calls.Load())
+	}
+
+	now = now.Add(31 * time.Second)
+	if _, err := c.Status(context.Background(), "user-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected cache to expire, got %d calls", calls.Load())
+	}
+}
+
+func TestHTTPChecker_NotFoundAndErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/users/gone" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	c := NewHTTPChecker(srv.URL, time.Second)
+	if status, err := c.Status(context.Background(), "gone"); err != nil || status != StatusNotFound {
+		t.Fatalf("expected not_found, got %q err=%v", status, err)
+	}
+	if _, err := c.Status(context.Background(), "user-1"); err == nil {
+		t.Fatalf("expected error on upstream failure")
+	}
+}
@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry es un registro de auditoría de un request hecho bajo impersonación.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id"`
+	ActorID    string    `json:"actor_id"`
+	SubjectID  string    `json:"subject_id"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	Blocked    bool      `json:"blocked"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// Logger persiste entradas de auditoría.
+type Logger interface {
+	Log(entry Entry)
+}
+
+// JSONLogger escribe una entrada JSON por línea (append-only).
+type JSONLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewJSONLogger(w io.Writer) *JSONLogger {
+	return &JSONLogger{w: w}
+}
+
+// NewFileLogger abre (o crea) path en modo append. Con path vacío escribe a stdout.
+func NewFileLogger(path string) (*JSONLogger, error) {
+	if path == "" {
+		return NewJSONLogger(os.Stdout), nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONLogger(f), nil
+}
+
+func (l *JSONLogger) Log(entry Entry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("audit_log_failed err=%v", err)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(append(data, '\n')); err != nil {
+		log.Printf("audit_log_failed err=%v", err)
+	}
+}
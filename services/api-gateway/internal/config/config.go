@@ -1,6 +1,9 @@
 package config
 
-import "os"
+import (
+	"os"
+	"time"
+)
 
 type Config struct {
 	HTTPAddr          string
@@ -8,6 +11,13 @@ type Config struct {
 	AuthServiceURL    string
 	UserServiceURL    string
 	BillingServiceURL string
+	PaymentServiceURL string
+	AuditLogPath      string
+	// SessionCacheTTL es cuánto tarda como máximo el gateway en ver una sesión revocada.
+	SessionCacheTTL time.Duration
+	// AccountCacheTTL es cuánto tarda como máximo el gateway en ver una cuenta
+	// suspendida o desactivada. 0 desactiva el chequeo.
+	AccountCacheTTL time.Duration
 }
 
 func Load() Config {
@@ -17,6 +27,10 @@ func Load() Config {
 		AuthServiceURL:    getEnv("AUTH_SERVICE_URL", "http://auth-service:8080"),
 		UserServiceURL:    getEnv("USER_SERVICE_URL", "http://user-service:8081"),
 		BillingServiceURL: getEnv("BILLING_SERVICE_URL", "http://billing-service:8083"),
+		PaymentServiceURL: getEnv("PAYMENT_SERVICE_URL", "http://payment-service:8084"),
+		AuditLogPath:      getEnv("AUDIT_LOG_PATH", ""),
+		SessionCacheTTL:   getDuration("SESSION_CACHE_TTL", 30*time.Second),
+		AccountCacheTTL:   getDuration("ACCOUNT_CACHE_TTL", 30*time.Second),
 	}
 }
 
@@ -26,3 +40,12 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
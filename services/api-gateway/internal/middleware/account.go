@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+)
+
+// AccountChecker devuelve el estado de la cuenta en user-service.
+type AccountChecker interface {
+	Status(ctx context.Context, userID string) (string, error)
+}
+
+// Accounts rechaza tokens de cuentas suspendidas, desactivadas o en borrado:
+// el JWT sigue siendo válido hasta expirar, pero la cuenta ya no.
+// Un status vacío se toma como activo. Debe ir después de JWT.
+func Accounts(checker AccountChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := r.Context().Value(UserIDKey).(string)
+
+			status, err := checker.Status(r.Context(), userID)
+			if err != nil {
+				log.Printf("gateway_account_check_failed user_id=%s err=%v", userID, err)
+				http.Error(w, "account check unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			if status != "" && status != "active" {
+				http.Error(w, "account is "+status, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type stubAccountChecker struct {
+	status string
+	err    error
+}
+
+func (s stubAccountChecker) Status(ctx context.Context, userID string) (string, error) {
+	return s.status, s.err
+}
+
+func TestAccounts(t *testing.T) {
+	tests := []struct {
+		name       string
+		checker    stubAccountChecker
+		wantStatus int
+	}{
+		{name: "active", checker: stubAccountChecker{status: "active"}, wantStatus: http.StatusOK},
+		{name: "legacy empty status", checker: stubAccountChecker{}, wantStatus: http.StatusOK},
+		{name: "suspended", checker: stubAccountChecker{status: "suspended"}, wantStatus: http.StatusForbidden},
+		{name: "deactivated", checker: stubAccountChecker{status: "deactivated"}, wantStatus: http.StatusForbidden},
+		{name: "pending deletion", checker: stubAccountChecker{status: "pending_deletion"}, wantStatus: http.StatusForbidden},
+		{name: "checker error", checker: stubAccountChecker{err: errors.New("down")}, wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/billing/invoices", nil)
+			req = req.WithContext(withUserID(req.Context(), "user-1"))
+			rr := httptest.NewRecorder()
+
+			Accounts(tt.checker)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})).ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"saas-subscription-platform/services/api-gateway/internal/audit"
+)
+
+// blockedRule describe una operación destructiva que no se permite mientras
+// se impersona a un usuario.
+type blockedRule struct {
+	Method     string
+	PathPrefix string
+	// PathSuffix acota la regla a una acción sobre el recurso (opcional).
+	PathSuffix string
+}
+
+var impersonationBlocked = []blockedRule{
+	{Method: http.MethodDelete, PathPrefix: "/api/users/"},
+	{Method: http.MethodPost, PathPrefix: "/api/users/", PathSuffix: "/erasure"},
+	{Method: http.MethodPost, PathPrefix: "/api/auth/impersonate"},
+	{Method: http.MethodPost, PathPrefix: "/api/auth/security/"},
+	{Method: http.MethodPost, PathPrefix: "/api/auth/password/change"},
+	{Method: http.MethodPost, PathPrefix: "/api/auth/email/change"},
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(statusCode int) {
+	w.status = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Impersonation bloquea operaciones destructivas con tokens de impersonación y
+// registra en el audit log cada request hecho bajo impersonación.
+// Debe ir después de JWT e InternalHeaders.
+func Impersonation(logger audit.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actorID, _ := r.Context().Value(ActorIDKey).(string)
+			if actorID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			subjectID, _ := r.Context().Value(UserIDKey).(string)
+			entry := audit.Entry{
+				Time:      start.UTC(),
+				RequestID: r.Header.Get(InternalRequestIDHeader),
+				ActorID:   actorID,
+				SubjectID: subjectID,
+				Method:    r.Method,
+				Path:      r.URL.Path,
+			}
+
+			for _, rule := range impersonationBlocked {
+				if r.Method == rule.Method && strings.HasPrefix(r.URL.Path, rule.PathPrefix) && strings.HasSuffix(r.URL.Path, rule.PathSuffix) {
+					entry.Status = http.StatusForbidden
+					entry.Blocked = true
+					logger.Log(entry)
+					http.Error(w, "operation not allowed while impersonating", http.StatusForbidden)
+					return
+				}
+			}
+
+			sw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r)
+
+			entry.Status = sw.status
+			entry.DurationMS = time.Since(start).Milliseconds()
+			logger.Log(entry)
+		})
+	}
+}
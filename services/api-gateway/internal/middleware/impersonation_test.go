@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"saas-subscription-platform/services/api-gateway/internal/audit"
+)
+
+type recordingLogger struct {
+	entries []audit.Entry
+}
+
+func (l *recordingLogger) Log(entry audit.Entry) {
+	l.entries = append(l.entries, entry)
+}
+
+func impersonatedRequest(method, path string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	ctx := context.WithValue(withUserID(req.Context(), "user-1"), ActorIDKey, "admin-1")
+	return req.WithContext(ctx)
+}
+
+func TestImpersonation_AuditsRequests(t *testing.T) {
+	logger := &recordingLogger{}
+	rr := httptest.NewRecorder()
+
+	Impersonation(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})).ServeHTTP(rr, impersonatedRequest(http.MethodGet, "/api/billing/invoices"))
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rr.Code)
+	}
+	if len(logger.entries) != 1 {
+		t.Fatalf("expected 1 audit entry, got %d", len(logger.entries))
+	}
+	e := logger.entries[0]
+	if e.ActorID != "admin-1" || e.SubjectID != "user-1" || e.Status != http.StatusAccepted || e.Blocked {
+		t.Fatalf("unexpected audit entry: %+v", e)
+	}
+}
+
+func TestImpersonation_BlocksDestructiveOperations(t *testing.T) {
+	logger := &recordingLogger{}
+	rr := httptest.NewRecorder()
+
+	Impersonation(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("handler should not be called")
+	})).ServeHTTP(rr, impersonatedRequest(http.MethodDelete, "/api/users/user-1"))
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+	if len(logger.entries) != 1 || !logger.entries[0].Blocked {
+		t.Fatalf("expected blocked audit entry, got %+v", logger.entries)
+	}
+}
+
+func TestImpersonation_BlocksErasure(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Impersonation(&recordingLogger{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("handler should not be called")
+	})).ServeHTTP(rr, impersonatedRequest(http.MethodPost, "/api/users/user-1/erasure"))
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+
+	// Otras acciones sobre el usuario siguen permitidas.
+	rr = httptest.NewRecorder()
+	Impersonation(&recordingLogger{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})).ServeHTTP(rr, impersonatedRequest(http.MethodPost, "/api/users/user-1/deactivate"))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rr.Code)
+	}
+}
+
+func TestImpersonation_BlocksCredentialChanges(t *testing.T) {
+	for _, path := range []string{"/api/auth/password/change", "/api/auth/email/change"} {
+		logger := &recordingLogger{}
+		rr := httptest.NewRecorder()
+
+		Impersonation(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Fatalf("handler should not be called for %s", path)
+		})).ServeHTTP(rr, impersonatedRequest(http.MethodPost, path))
+
+		if rr.Code != http.StatusForbidden {
+			t.Fatalf("%s: expected 403, got %d", path, rr.Code)
+		}
+	}
+}
+
+func TestImpersonation_IgnoresRegularTokens(t *testing.T) {
+	logger := &recordingLogger{}
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/api/users/user-1", nil)
+	req = req.WithContext(withUserID(req.Context(), "user-1"))
+
+	called := false
+	Impersonation(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})).ServeHTTP(rr, req)
+
+	if !called || len(logger.entries) != 0 {
+		t.Fatalf("expected passthrough without audit, called=%v entries=%d", called, len(logger.entries))
+	}
+}
@@ -5,23 +5,63 @@ import (
 
 	"github.com/google/uuid"
 
+	"saas-subscription-platform/libs/locale"
 	"saas-subscription-platform/libs/trace"
 )
 
 const (
 	InternalUserIDHeader    = "X-Internal-User-ID"
 	InternalRequestIDHeader = "X-Internal-Request-ID"
+	InternalUserRoleHeader  = "X-Internal-User-Role"
+	InternalActorIDHeader   = "X-Internal-Actor-ID"
+	InternalLocaleHeader    = locale.HeaderLocale
+	InternalTimezoneHeader  = locale.HeaderTimezone
+	InternalOrgIDHeader     = "X-Internal-Org-ID"
+	InternalOrgRoleHeader   = "X-Internal-Org-Role"
 )
 
+// identityHeaders solo pueden venir del gateway: si el cliente los manda se descartan.
+var identityHeaders = []string{
+	InternalUserIDHeader, InternalUserRoleHeader, InternalActorIDHeader,
+	InternalLocaleHeader, InternalTimezoneHeader,
+	InternalOrgIDHeader, InternalOrgRoleHeader,
+}
+
 // InternalHeaders agrega headers internos para que los microservicios confíen en ellos
 func InternalHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, h := range identityHeaders {
+			r.Header.Del(h)
+		}
+
 		// Obtener userID del contexto (agregado por el middleware JWT)
 		if userID := r.Context().Value(UserIDKey); userID != nil {
 			if userIDStr, ok := userID.(string); ok {
 				r.Header.Set(InternalUserIDHeader, userIDStr)
 			}
 		}
+		if role, ok := r.Context().Value(UserRoleKey).(string); ok {
+			r.Header.Set(InternalUserRoleHeader, role)
+		}
+		// Con impersonación viajan ambas identidades: el usuario (sub) y el agente (act).
+		if actorID, ok := r.Context().Value(ActorIDKey).(string); ok {
+			r.Header.Set(InternalActorIDHeader, actorID)
+		}
+
+		// Organización activa del token; sin ella los servicios usan el ámbito personal.
+		if orgID, ok := r.Context().Value(OrgIDKey).(string); ok {
+			r.Header.Set(InternalOrgIDHeader, orgID)
+			if orgRole, ok := r.Context().Value(OrgRoleKey).(string); ok {
+				r.Header.Set(InternalOrgRoleHeader, orgRole)
+			}
+		}
+
+		if loc, ok := r.Context().Value(UserLocaleKey).(string); ok {
+			r.Header.Set(InternalLocaleHeader, loc)
+		}
+		if tz, ok := r.Context().Value(UserTimezoneKey).(string); ok {
+			r.Header.Set(InternalTimezoneHeader, tz)
+		}
 
 		// Agregar request ID para trazabilidad
 		requestID := r.Header.Get("X-Request-ID")
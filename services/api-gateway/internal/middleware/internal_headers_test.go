@@ -35,3 +35,79 @@ func TestInternalHeaders_SetsHeaders(t *testing.T) {
 func withUserID(ctx context.Context, userID string) context.Context {
 	return context.WithValue(ctx, UserIDKey, userID)
 }
+
+func TestInternalHeaders_ForwardsActorAndDropsSpoofedHeaders(t *testing.T) {
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(InternalActorIDHeader, "spoofed")
+	req.Header.Set(InternalUserRoleHeader, "admin")
+	req = req.WithContext(withUserID(req.Context(), "user-1"))
+
+	var gotActor, gotRole string
+	InternalHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotActor = r.Header.Get(InternalActorIDHeader)
+		gotRole = r.Header.Get(InternalUserRoleHeader)
+	})).ServeHTTP(rr, req)
+
+	if gotActor != "" || gotRole != "" {
+		t.Fatalf("expected spoofed headers dropped, got actor=%q role=%q", gotActor, gotRole)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx := context.WithValue(withUserID(req.Context(), "user-1"), ActorIDKey, "admin-1")
+	ctx = context.WithValue(ctx, UserRoleKey, "user")
+	InternalHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotActor = r.Header.Get(InternalActorIDHeader)
+		gotRole = r.Header.Get(InternalUserRoleHeader)
+	})).ServeHTTP(rr, req.WithContext(ctx))
+
+	if gotActor != "admin-1" || gotRole != "user" {
+		t.Fatalf("expected actor/role forwarded, got actor=%q role=%q", gotActor, gotRole)
+	}
+}
+
+func TestInternalHeaders_ForwardsPreferences(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(InternalTimezoneHeader, "Spoofed/Zone")
+	ctx := context.WithValue(withUserID(req.Context(), "user-1"), UserLocaleKey, "es-AR")
+	req = req.WithContext(ctx)
+
+	var gotLocale, gotTZ string
+	InternalHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotLocale = r.Header.Get(InternalLocaleHeader)
+		gotTZ = r.Header.Get(InternalTimezoneHeader)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotLocale != "es-AR" {
+		t.Fatalf("expected locale header es-AR, got %q", gotLocale)
+	}
+	if gotTZ != "" {
+		t.Fatalf("expected spoofed timezone dropped, got %q", gotTZ)
+	}
+}
+
+func TestInternalHeaders_ForwardsActiveOrg(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(InternalOrgIDHeader, "spoofed-org")
+	req.Header.Set(InternalOrgRoleHeader, "owner")
+	req = req.WithContext(withUserID(req.Context(), "user-1"))
+
+	var gotOrg, gotOrgRole string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotOrg = r.Header.Get(InternalOrgIDHeader)
+		gotOrgRole = r.Header.Get(InternalOrgRoleHeader)
+	})
+	InternalHeaders(next).ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotOrg != "" || gotOrgRole != "" {
+		t.Fatalf("expected spoofed org headers dropped, got org=%q role=%q", gotOrg, gotOrgRole)
+	}
+
+	ctx := context.WithValue(withUserID(req.Context(), "user-1"), OrgIDKey, "org-1")
+	ctx = context.WithValue(ctx, OrgRoleKey, "member")
+	InternalHeaders(next).ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+
+	if gotOrg != "org-1" || gotOrgRole != "member" {
+		t.Fatalf("expected active org forwarded, got org=%q role=%q", gotOrg, gotOrgRole)
+	}
+}
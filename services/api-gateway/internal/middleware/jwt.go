@@ -10,7 +10,20 @@ import (
 
 type contextKey string
 
-const UserIDKey contextKey = "user_id"
+const (
+	UserIDKey   contextKey = "user_id"
+	UserRoleKey contextKey = "user_role"
+	// ActorIDKey está presente solo en tokens de impersonación (claim "act").
+	ActorIDKey contextKey = "actor_id"
+	// SessionIDKey es el claim "sid": la sesión revocable que respalda el token.
+	SessionIDKey contextKey = "session_id"
+	// UserLocaleKey y UserTimezoneKey son las preferencias del usuario (claims "locale" y "tz").
+	UserLocaleKey   contextKey = "user_locale"
+	UserTimezoneKey contextKey = "user_timezone"
+	// OrgIDKey y OrgRoleKey son la organización activa y el rol en ella (claims "org_id" y "org_role").
+	OrgIDKey   contextKey = "org_id"
+	OrgRoleKey contextKey = "org_role"
+)
 
 func JWT(secret string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -62,6 +75,32 @@ func JWT(secret string) func(http.Handler) http.Handler {
 			}
 
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			if role, ok := claims["role"].(string); ok && role != "" {
+				ctx = context.WithValue(ctx, UserRoleKey, role)
+			}
+			if loc, ok := claims["locale"].(string); ok && loc != "" {
+				ctx = context.WithValue(ctx, UserLocaleKey, loc)
+			}
+			if tz, ok := claims["tz"].(string); ok && tz != "" {
+				ctx = context.WithValue(ctx, UserTimezoneKey, tz)
+			}
+			if orgID, ok := claims["org_id"].(string); ok && orgID != "" {
+				ctx = context.WithValue(ctx, OrgIDKey, orgID)
+				if orgRole, ok := claims["org_role"].(string); ok {
+					ctx = context.WithValue(ctx, OrgRoleKey, orgRole)
+				}
+			}
+			if sid, ok := claims["sid"].(string); ok && sid != "" {
+				ctx = context.WithValue(ctx, SessionIDKey, sid)
+			}
+			if act, ok := claims["act"].(map[string]interface{}); ok {
+				actorID, _ := act["sub"].(string)
+				if actorID == "" {
+					http.Error(w, "invalid actor claim", http.StatusUnauthorized)
+					return
+				}
+				ctx = context.WithValue(ctx, ActorIDKey, actorID)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -60,3 +60,85 @@ func TestJWT_ValidTokenSetsContext(t *testing.T) {
 		t.Fatalf("expected user-1, got %s", gotUser)
 	}
 }
+
+func TestJWT_ImpersonationTokenSetsActor(t *testing.T) {
+	mw := JWT("secret")
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	claims := jwt.MapClaims{
+		"sub":  "user-1",
+		"role": "user",
+		"act":  map[string]interface{}{"sub": "admin-1"},
+		"exp":  time.Now().Add(time.Minute).Unix(),
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+signed)
+
+	var gotActor, gotRole string
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotActor, _ = r.Context().Value(ActorIDKey).(string)
+		gotRole, _ = r.Context().Value(UserRoleKey).(string)
+	})).ServeHTTP(rr, req)
+
+	if gotActor != "admin-1" {
+		t.Fatalf("expected actor admin-1, got %q", gotActor)
+	}
+	if gotRole != "user" {
+		t.Fatalf("expected role user, got %q", gotRole)
+	}
+}
+
+func TestJWT_PreferenceClaims(t *testing.T) {
+	mw := JWT("secret")
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	claims := jwt.MapClaims{
+		"sub":    "user-1",
+		"locale": "es-AR",
+		"tz":     "America/Argentina/Buenos_Aires",
+		"exp":    time.Now().Add(time.Minute).Unix(),
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+signed)
+
+	var gotLocale, gotTZ string
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotLocale, _ = r.Context().Value(UserLocaleKey).(string)
+		gotTZ, _ = r.Context().Value(UserTimezoneKey).(string)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotLocale != "es-AR" || gotTZ != "America/Argentina/Buenos_Aires" {
+		t.Fatalf("unexpected preferences locale=%q tz=%q", gotLocale, gotTZ)
+	}
+}
+
+func TestJWT_OrgClaims(t *testing.T) {
+	mw := JWT("secret")
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	claims := jwt.MapClaims{
+		"sub":      "user-1",
+		"org_id":   "org-1",
+		"org_role": "billing",
+		"exp":      time.Now().Add(time.Minute).Unix(),
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+signed)
+
+	var gotOrg, gotOrgRole string
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotOrg, _ = r.Context().Value(OrgIDKey).(string)
+		gotOrgRole, _ = r.Context().Value(OrgRoleKey).(string)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotOrg != "org-1" || gotOrgRole != "billing" {
+		t.Fatalf("unexpected org org_id=%q org_role=%q", gotOrg, gotOrgRole)
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+)
+
+// SessionChecker responde si la sesión de un token sigue activa en auth-service.
+type SessionChecker interface {
+	Active(ctx context.Context, userID, sessionID string) (bool, error)
+}
+
+// Sessions rechaza tokens cuya sesión fue revocada (ej: el usuario negó un
+// login sospechoso). Los tokens sin claim "sid" se dejan pasar: son stateless.
+// Debe ir después de JWT.
+func Sessions(checker SessionChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sessionID, _ := r.Context().Value(SessionIDKey).(string)
+			if sessionID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			userID, _ := r.Context().Value(UserIDKey).(string)
+
+			active, err := checker.Active(r.Context(), userID, sessionID)
+			if err != nil {
+				log.Printf("gateway_session_check_failed user_id=%s err=%v", userID, err)
+				http.Error(w, "session check unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			if !active {
+				http.Error(w, "session revoked", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type stubSessionChecker struct {
+	active bool
+	err    error
+	calls  int
+}
+
+func (s *stubSessionChecker) Active(ctx context.Context, userID, sessionID string) (bool, error) {
+	s.calls++
+	return s.active, s.err
+}
+
+func sessionRequest(sid string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/users/1", nil)
+	ctx := withUserID(req.Context(), "user-1")
+	if sid != "" {
+		ctx = context.WithValue(ctx, SessionIDKey, sid)
+	}
+	return req.WithContext(ctx)
+}
+
+func TestSessions(t *testing.T) {
+	tests := []struct {
+		name       string
+		sid        string
+		checker    *stubSessionChecker
+		wantStatus int
+		wantCalls  int
+	}{
+		{name: "token without sid skips check", checker: &stubSessionChecker{}, wantStatus: http.StatusOK},
+		{name: "active session", sid: "s-1", checker: &stubSessionChecker{active: true}, wantStatus: http.StatusOK, wantCalls: 1},
+		{name: "revoked session", sid: "s-1", checker: &stubSessionChecker{}, wantStatus: http.StatusUnauthorized, wantCalls: 1},
+		{name: "checker unavailable", sid: "s-1", checker: &stubSessionChecker{err: errors.New("down")}, wantStatus: http.StatusServiceUnavailable, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			Sessions(tt.checker)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})).ServeHTTP(rr, sessionRequest(tt.sid))
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if tt.checker.calls != tt.wantCalls {
+				t.Fatalf("expected %d checker calls, got %d", tt.wantCalls, tt.checker.calls)
+			}
+		})
+	}
+}
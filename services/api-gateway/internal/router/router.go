@@ -2,6 +2,7 @@ package router
 
 import (
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -28,20 +29,37 @@ func NewRouter() *Router {
 	return &Router{
 		client: &http.Client{
 			Timeout: 30 * time.Second,
+			// Un reverse proxy no sigue redirects: se los devuelve al cliente
+			// (ej: el redirect al IdP en el login SSO).
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
 		},
 		routes: []Route{
 			// Auth routes (no auth required)
 			{Path: "/api/auth/register", TargetURL: "", RequiresAuth: false},
 			{Path: "/api/auth/login", TargetURL: "", RequiresAuth: false},
+			{Path: "/api/auth/sso", TargetURL: "", RequiresAuth: false},
+			{Path: "/api/auth/email/confirm", TargetURL: "", RequiresAuth: false},
+			{Path: "/api/auth/password/reset", TargetURL: "", RequiresAuth: false},
 
 			// Protected routes (require auth)
 			{Path: "/api/auth/me", TargetURL: "", RequiresAuth: true},
+			{Path: "/api/auth/impersonate", TargetURL: "", RequiresAuth: true},
+			{Path: "/api/auth/security", TargetURL: "", RequiresAuth: true},
+			{Path: "/api/auth/password/change", TargetURL: "", RequiresAuth: true},
+			{Path: "/api/auth/email/change", TargetURL: "", RequiresAuth: true},
+			{Path: "/api/auth/switch-org", TargetURL: "", RequiresAuth: true},
 
 			// User routes (require auth)
 			{Path: "/api/users", TargetURL: "", RequiresAuth: true},
+			{Path: "/api/orgs", TargetURL: "", RequiresAuth: true},
 
 			// Billing routes (require auth)
 			{Path: "/api/billing", TargetURL: "", RequiresAuth: true},
+
+			// Payment routes (require auth)
+			{Path: "/api/payments", TargetURL: "", RequiresAuth: true},
 		},
 	}
 }
@@ -63,7 +81,7 @@ func (r *Router) SetAuthServiceURL(url string) {
 
 func (r *Router) SetUserServiceURL(url string) {
 	for i := range r.routes {
-		if strings.HasPrefix(r.routes[i].Path, "/api/users") {
+		if strings.HasPrefix(r.routes[i].Path, "/api/users") || strings.HasPrefix(r.routes[i].Path, "/api/orgs") {
 			r.routes[i].TargetURL = url
 		}
 	}
@@ -77,6 +95,14 @@ func (r *Router) SetBillingServiceURL(url string) {
 	}
 }
 
+func (r *Router) SetPaymentServiceURL(url string) {
+	for i := range r.routes {
+		if strings.HasPrefix(r.routes[i].Path, "/api/payments") {
+			r.routes[i].TargetURL = url
+		}
+	}
+}
+
 func (r *Router) FindRoute(path string) *Route {
 	for _, route := range r.routes {
 		if strings.HasPrefix(path, route.Path) || path == route.Path {
@@ -100,7 +126,7 @@ func (r *Router) Proxy(w http.ResponseWriter, req *http.Request, targetURL strin
 	proxyReq.URL.Host = target.Host
 	proxyReq.RequestURI = ""
 
-	// Map paths: /api/auth/* -> /*, /api/users/* -> /*, /api/billing/* -> /*
+	// Map paths: /api/auth/* -> /*, /api/users/* -> /users/*, /api/orgs/* -> /orgs/*, /api/billing/* -> /*, /api/payments/* -> /*
 	if strings.HasPrefix(req.URL.Path, "/api/auth") {
 		// Remove /api/auth prefix
 		proxyReq.URL.Path = strings.TrimPrefix(req.URL.Path, "/api/auth")
@@ -120,6 +146,9 @@ func (r *Router) Proxy(w http.ResponseWriter, req *http.Request, targetURL strin
 			}
 			proxyReq.URL.Path = "/users" + suffix
 		}
+	} else if strings.HasPrefix(req.URL.Path, "/api/orgs") {
+		// Las organizaciones también viven en user-service
+		proxyReq.URL.Path = strings.TrimPrefix(req.URL.Path, "/api")
 	} else if strings.HasPrefix(req.URL.Path, "/api/billing") {
 		// Remove /api/billing prefix, keep the rest
 		proxyReq.URL.Path = strings.TrimPrefix(req.URL.Path, "/api/billing")
@@ -128,6 +157,14 @@ func (r *Router) Proxy(w http.ResponseWriter, req *http.Request, targetURL strin
 		} else if !strings.HasPrefix(proxyReq.URL.Path, "/") {
 			proxyReq.URL.Path = "/" + proxyReq.URL.Path
 		}
+	} else if strings.HasPrefix(req.URL.Path, "/api/payments") {
+		// Igual que billing: payment-service expone /payment_intents en la raíz
+		proxyReq.URL.Path = strings.TrimPrefix(req.URL.Path, "/api/payments")
+		if proxyReq.URL.Path == "" {
+			proxyReq.URL.Path = "/"
+		} else if !strings.HasPrefix(proxyReq.URL.Path, "/") {
+			proxyReq.URL.Path = "/" + proxyReq.URL.Path
+		}
 	}
 
 	// Copy query parameters
@@ -138,7 +175,7 @@ func (r *Router) Proxy(w http.ResponseWriter, req *http.Request, targetURL strin
 	for key, values := range req.Header {
 		if key != "Host" {
 			// Remove Authorization header for internal services (they use X-Internal-User-ID)
-			if key == "Authorization" && (strings.HasPrefix(req.URL.Path, "/api/users") || strings.HasPrefix(req.URL.Path, "/api/billing")) {
+			if key == "Authorization" && !strings.HasPrefix(req.URL.Path, "/api/auth") {
 				continue
 			}
 			for _, value := range values {
@@ -147,6 +184,15 @@ func (r *Router) Proxy(w http.ResponseWriter, req *http.Request, targetURL strin
 		}
 	}
 
+	// IP real del cliente al final de X-Forwarded-For: los servicios confían
+	// solo en la última entrada (las anteriores las puede mandar el cliente).
+	if ip, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		if prior := req.Header.Get("X-Forwarded-For"); prior != "" {
+			ip = prior + ", " + ip
+		}
+		proxyReq.Header.Set("X-Forwarded-For", ip)
+	}
+
 	// Forward the request
 	resp, err := r.client.Do(proxyReq)
 	if err != nil {
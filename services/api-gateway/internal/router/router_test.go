@@ -85,6 +85,32 @@ func TestProxy_RewritesUserPathAndDropsAuthHeader(t *testing.T) {
 	}
 }
 
+func TestProxy_RoutesOrgsToUserService(t *testing.T) {
+	var forwardedAuth string
+	r := NewRouterWithClient(stubClient{doFn: func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host != "user.test" || req.URL.Path != "/orgs" {
+			t.Fatalf("expected user.test/orgs, got %s%s", req.URL.Host, req.URL.Path)
+		}
+		forwardedAuth = req.Header.Get("Authorization")
+		body := io.NopCloser(strings.NewReader(`{"organizations":[]}`))
+		return &http.Response{StatusCode: http.StatusOK, Body: body, Header: http.Header{}}, nil
+	}})
+	r.SetUserServiceURL("http://user.test")
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/orgs", nil)
+	req.Header.Set("Authorization", "Bearer token")
+
+	r.ServeHTTP(rr, req)
+
+	if forwardedAuth != "" {
+		t.Fatalf("expected Authorization to be stripped, got %q", forwardedAuth)
+	}
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+}
+
 func TestProxy_RewritesBillingPathAndKeepsQuery(t *testing.T) {
 	r := NewRouterWithClient(stubClient{doFn: func(req *http.Request) (*http.Response, error) {
 		if req.URL.Path != "/invoices" {
@@ -107,3 +133,69 @@ func TestProxy_RewritesBillingPathAndKeepsQuery(t *testing.T) {
 		t.Fatalf("expected 200, got %d", rr.Code)
 	}
 }
+
+func TestProxy_RewritesPaymentsPath(t *testing.T) {
+	r := NewRouterWithClient(stubClient{doFn: func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host != "payments.test" {
+			t.Fatalf("expected payment-service host, got %s", req.URL.Host)
+		}
+		if req.URL.Path != "/payment_intents/7/confirm" {
+			t.Fatalf("expected /payment_intents/7/confirm, got %s", req.URL.Path)
+		}
+		body := io.NopCloser(strings.NewReader(`{}`))
+		return &http.Response{StatusCode: http.StatusOK, Body: body, Header: http.Header{}}, nil
+	}})
+	r.SetBillingServiceURL("http://billing.test")
+	r.SetPaymentServiceURL("http://payments.test")
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/payments/payment_intents/7/confirm", nil)
+
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+}
+
+func TestProxy_RewritesSSOPathAndKeepsRedirect(t *testing.T) {
+	r := NewRouterWithClient(stubClient{doFn: func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/sso/acme/login" {
+			t.Fatalf("expected /sso/acme/login, got %s", req.URL.Path)
+		}
+		body := io.NopCloser(strings.NewReader(""))
+		return &http.Response{StatusCode: http.StatusFound, Body: body, Header: http.Header{"Location": []string{"https://idp.example.com/sso"}}}, nil
+	}})
+	r.SetAuthServiceURL("http://auth.test")
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/sso/acme/login", nil)
+
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusFound {
+		t.Fatalf("expected 302, got %d", rr.Code)
+	}
+	if loc := rr.Header().Get("Location"); loc != "https://idp.example.com/sso" {
+		t.Fatalf("expected Location preserved, got %q", loc)
+	}
+}
+
+func TestProxy_AppendsClientIPToForwardedFor(t *testing.T) {
+	var forwarded string
+	r := NewRouterWithClient(stubClient{doFn: func(req *http.Request) (*http.Response, error) {
+		forwarded = req.Header.Get("X-Forwarded-For")
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")), Header: http.Header{}}, nil
+	}})
+	r.SetAuthServiceURL("http://auth.test")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+	req.RemoteAddr = "203.0.113.10:51234"
+	req.Header.Set("X-Forwarded-For", "1.1.1.1")
+
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if forwarded != "1.1.1.1, 203.0.113.10" {
+		t.Fatalf("expected client IP appended, got %q", forwarded)
+	}
+}
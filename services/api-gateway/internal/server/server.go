@@ -2,10 +2,14 @@ package server
 
 import (
 	"context"
+	"log"
 	"net/http"
+	"saas-subscription-platform/services/api-gateway/internal/account"
+	"saas-subscription-platform/services/api-gateway/internal/audit"
 	"saas-subscription-platform/services/api-gateway/internal/config"
 	"saas-subscription-platform/services/api-gateway/internal/middleware"
 	"saas-subscription-platform/services/api-gateway/internal/router"
+	"saas-subscription-platform/services/api-gateway/internal/session"
 	"time"
 )
 
@@ -18,9 +22,26 @@ func New(cfg config.Config) *Server {
 	gatewayRouter.SetAuthServiceURL(cfg.AuthServiceURL)
 	gatewayRouter.SetUserServiceURL(cfg.UserServiceURL)
 	gatewayRouter.SetBillingServiceURL(cfg.BillingServiceURL)
+	gatewayRouter.SetPaymentServiceURL(cfg.PaymentServiceURL)
+
+	auditLogger, err := audit.NewFileLogger(cfg.AuditLogPath)
+	if err != nil {
+		log.Fatalf("audit log init failed: %v", err)
+	}
 
 	jwtMiddleware := middleware.JWT(cfg.JWTSecret)
 	internalHeadersMiddleware := middleware.InternalHeaders
+	impersonationMiddleware := middleware.Impersonation(auditLogger)
+	sessionsMiddleware := middleware.Sessions(session.NewHTTPChecker(cfg.AuthServiceURL, cfg.SessionCacheTTL))
+
+	accountsMiddleware := func(h http.Handler) http.Handler { return h }
+	if cfg.AccountCacheTTL > 0 {
+		accountsMiddleware = middleware.Accounts(account.NewHTTPChecker(cfg.UserServiceURL, cfg.AccountCacheTTL))
+	}
+
+	protected := func(h http.Handler) http.Handler {
+		return jwtMiddleware(sessionsMiddleware(accountsMiddleware(internalHeadersMiddleware(impersonationMiddleware(h)))))
+	}
 
 	mux := http.NewServeMux()
 
@@ -33,11 +54,25 @@ func New(cfg config.Config) *Server {
 	// Public routes (no auth)
 	mux.HandleFunc("POST /api/auth/register", gatewayRouter.ServeHTTP)
 	mux.HandleFunc("POST /api/auth/login", gatewayRouter.ServeHTTP)
+	mux.HandleFunc("/api/auth/sso/", gatewayRouter.ServeHTTP)
+	mux.HandleFunc("GET /api/auth/email/confirm", gatewayRouter.ServeHTTP)
+	mux.HandleFunc("POST /api/auth/email/confirm", gatewayRouter.ServeHTTP)
+	mux.HandleFunc("POST /api/auth/password/reset", gatewayRouter.ServeHTTP)
+	mux.HandleFunc("POST /api/auth/password/reset/request", gatewayRouter.ServeHTTP)
 
 	// Protected routes (require auth)
-	mux.Handle("/api/auth/me", jwtMiddleware(internalHeadersMiddleware(gatewayRouter)))
-	mux.Handle("/api/users/", jwtMiddleware(internalHeadersMiddleware(gatewayRouter)))
-	mux.Handle("/api/billing/", jwtMiddleware(internalHeadersMiddleware(gatewayRouter)))
+	mux.Handle("/api/auth/me", protected(gatewayRouter))
+	mux.Handle("POST /api/auth/impersonate", protected(gatewayRouter))
+	mux.Handle("/api/auth/security/", protected(gatewayRouter))
+	mux.Handle("POST /api/auth/password/change", protected(gatewayRouter))
+	mux.Handle("POST /api/auth/email/change", protected(gatewayRouter))
+	mux.Handle("POST /api/auth/switch-org", protected(gatewayRouter))
+	mux.Handle("GET /api/users", protected(gatewayRouter))
+	mux.Handle("/api/users/", protected(gatewayRouter))
+	mux.Handle("/api/orgs", protected(gatewayRouter))
+	mux.Handle("/api/orgs/", protected(gatewayRouter))
+	mux.Handle("/api/billing/", protected(gatewayRouter))
+	mux.Handle("/api/payments/", protected(gatewayRouter))
 
 	return &Server{
 		httpServer: &http.Server{
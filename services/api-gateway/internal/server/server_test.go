@@ -130,3 +130,63 @@ func TestServer_ProtectedRoute_AddsInternalHeaders(t *testing.T) {
 		t.Fatalf("proxy did not hit user backend")
 	}
 }
+
+func TestServer_OrgRoutes_ReachBackends(t *testing.T) {
+	hits := make(chan string, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits <- r.Method + " " + r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	cfg := config.Config{
+		JWTSecret:         "secret",
+		AuthServiceURL:    backend.URL,
+		UserServiceURL:    backend.URL,
+		BillingServiceURL: "http://localhost",
+	}
+
+	srv := New(cfg)
+	ts := httptest.NewServer(srv.httpServer.Handler)
+	defer ts.Close()
+
+	token := makeToken(t, "secret", "user-1")
+
+	tests := []struct {
+		method, path, want string
+	}{
+		{http.MethodPost, "/api/orgs", "POST /orgs"},
+		{http.MethodGet, "/api/orgs", "GET /orgs"},
+		{http.MethodPost, "/api/orgs/org-1/invitations", "POST /orgs/org-1/invitations"},
+		{http.MethodDelete, "/api/orgs/org-1/invitations/inv-1", "DELETE /orgs/org-1/invitations/inv-1"},
+		{http.MethodPost, "/api/orgs/invitations/accept", "POST /orgs/invitations/accept"},
+		{http.MethodPost, "/api/auth/switch-org", "POST /switch-org"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, ts.URL+tt.path, strings.NewReader(`{}`))
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected 200, got %d", resp.StatusCode)
+			}
+			select {
+			case got := <-hits:
+				if got != tt.want {
+					t.Fatalf("expected %s, got %s", tt.want, got)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("proxy did not hit backend")
+			}
+		})
+	}
+}
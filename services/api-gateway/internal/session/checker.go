@@ -0,0 +1,100 @@
+// Package session consulta a auth-service si la sesión de un token sigue
+// activa, con un cache corto para no agregar un round-trip por request.
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// maxEntries acota el cache; al superarlo se descartan las entradas vencidas.
+const maxEntries = 10000
+
+type entry struct {
+	active    bool
+	expiresAt time.Time
+}
+
+// HTTPChecker implementa middleware.SessionChecker contra GET /internal/sessions/{id}.
+type HTTPChecker struct {
+	baseURL string
+	client  *http.Client
+	ttl     time.Duration
+
+	mu    sync.Mutex
+	cache map[string]entry
+	now   func() time.Time
+}
+
+// NewHTTPChecker crea el checker. ttl es la demora máxima con la que el
+// gateway ve una revocación.
+func NewHTTPChecker(authServiceURL string, ttl time.Duration) *HTTPChecker {
+	return &HTTPChecker{
+		baseURL: authServiceURL,
+		client:  &http.Client{Timeout: 2 * time.Second},
+		ttl:     ttl,
+		cache:   make(map[string]entry),
+		now:     time.Now,
+	}
+}
+
+func (c *HTTPChecker) Active(ctx context.Context, userID, sessionID string) (bool, error) {
+	key := userID + "/" + sessionID
+	now := c.now()
+
+	c.mu.Lock()
+	if e, ok := c.cache[key]; ok && now.Before(e.expiresAt) {
+		c.mu.Unlock()
+		return e.active, nil
+	}
+	c.mu.Unlock()
+
+	active, err := c.fetch(ctx, userID, sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	if len(c.cache) >= maxEntries {
+		for k, e := range c.cache {
+			if !now.Before(e.expiresAt) {
+				delete(c.cache, k)
+			}
+		}
+	}
+	c.cache[key] = entry{active: active, expiresAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+
+	return active, nil
+}
+
+func (c *HTTPChecker) fetch(ctx context.Context, userID, sessionID string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/internal/sessions/"+url.PathEscape(sessionID), nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("X-Internal-User-ID", userID)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("session check: unexpected status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Active bool `json:"active"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return false, fmt.Errorf("session check: %w", err)
+	}
+	return body.Active, nil
+}
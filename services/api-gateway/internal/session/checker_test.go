@@ -0,0 +1,70 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPChecker_CachesResult(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path != "/internal/sessions/sid-1" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("X-Internal-User-ID") != "user-1" {
+			t.Errorf("missing internal user header")
+		}
+		_, _ = w.Write([]byte(`{"active":true}`))
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	c := NewHTTPChecker(srv.URL, 30*time.Second)
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		active, err := c.Active(context.Background(), "user-1", "sid-1")
+		if err != nil || !active {
+			t.Fatalf("expected active session, got %v err=%v", active, err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 upstream call, got %d", calls.Load())
+	}
+
+	now = now.Add(31 * time.Second)
+	if _, err := c.Active(context.Background(), "user-1", "sid-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected cache to expire, got %d calls", calls.Load())
+	}
+}
+
+func TestHTTPChecker_Revoked(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"active":false}`))
+	}))
+	defer srv.Close()
+
+	active, err := NewHTTPChecker(srv.URL, time.Second).Active(context.Background(), "user-1", "sid-1")
+	if err != nil || active {
+		t.Fatalf("expected inactive session, got %v err=%v", active, err)
+	}
+}
+
+func TestHTTPChecker_UpstreamError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	if _, err := NewHTTPChecker(srv.URL, time.Second).Active(context.Background(), "user-1", "sid-1"); err == nil {
+		t.Fatalf("expected error on upstream failure")
+	}
+}
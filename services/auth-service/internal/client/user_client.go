@@ -19,6 +19,12 @@ var (
 	ErrUserNotFound = errors.New("user not found")
 	ErrUserExists   = errors.New("user already exists")
 	ErrServiceError = errors.New("user service error")
+	// ErrEmailMismatch: el email actual ya no es el esperado (el cambio ya se aplicó o hubo otro en el medio).
+	ErrEmailMismatch = errors.New("current email does not match")
+	// ErrNotMember: el usuario no pertenece a la organización.
+	ErrNotMember = errors.New("user is not a member of the organization")
+	// ErrInvalidInvitation: la invitación no existe, ya se usó, fue revocada o venció.
+	ErrInvalidInvitation = errors.New("invalid or expired invitation")
 )
 
 type UserClient struct {
@@ -29,6 +35,8 @@ type UserClient struct {
 type CreateUserRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// SSOTenant marca la cuenta como provisionada por ese tenant SAML.
+	SSOTenant string `json:"sso_tenant,omitempty"`
 }
 
 type CreateUserResponse struct {
@@ -41,9 +49,34 @@ type GetUserByEmailResponse struct {
 	ID        string `json:"id"`
 	Email     string `json:"email"`
 	Password  string `json:"password"`
+	Role      string `json:"role"`
+	Name      string `json:"name"`
+	Locale    string `json:"locale"`
+	Timezone  string `json:"timezone"`
+	AvatarURL string `json:"avatar_url"`
+	Phone     string `json:"phone"`
+	Status    string `json:"status"`
+	// SSOTenant es el tenant SAML que creó la cuenta ("" si se registró con password).
+	SSOTenant string `json:"sso_tenant"`
 	CreatedAt string `json:"created_at"`
 }
 
+// MembershipResponse es el rol de un usuario en una organización.
+type MembershipResponse struct {
+	OrgID  string `json:"org_id"`
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// InvitationResponse es una invitación abierta a una organización.
+type InvitationResponse struct {
+	ID        string `json:"id"`
+	OrgID     string `json:"org_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	ExpiresAt string `json:"expires_at"`
+}
+
 func NewUserClient(baseURL string) *UserClient {
 	return &UserClient{
 		baseURL: baseURL,
@@ -75,12 +108,16 @@ func (c *UserClient) CreateUser(email, password string, headers map[string]strin
 }
 
 func (c *UserClient) CreateUserWithContext(ctx context.Context, email, password string, headers map[string]string) (CreateUserResponse, error) {
-	start := time.Now()
+	return c.createUser(ctx, CreateUserRequest{Email: email, Password: password}, headers)
+}
 
-	reqBody := CreateUserRequest{
-		Email:    email,
-		Password: password,
-	}
+// CreateSSOUserWithContext crea una cuenta provisionada just-in-time por el tenant SAML tenantID.
+func (c *UserClient) CreateSSOUserWithContext(ctx context.Context, email, password, tenantID string, headers map[string]string) (CreateUserResponse, error) {
+	return c.createUser(ctx, CreateUserRequest{Email: email, Password: password, SSOTenant: tenantID}, headers)
+}
+
+func (c *UserClient) createUser(ctx context.Context, reqBody CreateUserRequest, headers map[string]string) (CreateUserResponse, error) {
+	start := time.Now()
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
@@ -231,3 +268,179 @@ func (c *UserClient) GetUserByIDWithContext(ctx context.Context, userID string,
 
 	return userResp, nil
 }
+
+// UpdatePasswordWithContext guarda un hash nuevo (ruta interna, solo auth-service).
+func (c *UserClient) UpdatePasswordWithContext(ctx context.Context, userID, passwordHash string, headers map[string]string) error {
+	status, body, err := c.putJSON(ctx, "/users/"+url.PathEscape(userID)+"/password", "/users/{id}/password",
+		map[string]string{"password": passwordHash}, headers)
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case http.StatusNoContent, http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrUserNotFound
+	default:
+		return fmt.Errorf("%w: status %d, body: %s", ErrServiceError, status, string(body))
+	}
+}
+
+// ReplaceEmailWithContext cambia el email solo si el actual sigue siendo currentEmail.
+func (c *UserClient) ReplaceEmailWithContext(ctx context.Context, userID, currentEmail, newEmail string, headers map[string]string) error {
+	status, body, err := c.putJSON(ctx, "/users/"+url.PathEscape(userID)+"/email", "/users/{id}/email",
+		map[string]string{"current_email": currentEmail, "new_email": newEmail}, headers)
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case http.StatusNoContent, http.StatusOK:
+		return nil
+	case http.StatusPreconditionFailed:
+		return ErrEmailMismatch
+	case http.StatusConflict:
+		return ErrUserExists
+	default:
+		return fmt.Errorf("%w: status %d, body: %s", ErrServiceError, status, string(body))
+	}
+}
+
+// GetMembershipWithContext devuelve el rol de userID en orgID (ruta interna, solo auth-service).
+func (c *UserClient) GetMembershipWithContext(ctx context.Context, orgID, userID string, headers map[string]string) (MembershipResponse, error) {
+	start := time.Now()
+	const logPath = "/internal/orgs/{id}/members/{userId}"
+
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		c.baseURL+"/internal/orgs/"+url.PathEscape(orgID)+"/members/"+url.PathEscape(userID), nil)
+	if err != nil {
+		return MembershipResponse{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for key, value := range mergeHeaders(ctx, headers) {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		log.Printf("upstream_call failed service=user-service method=GET path=%s request_id=%s call_stack=%s duration_ms=%d err=%v",
+			logPath, trace.RequestIDFromContext(ctx), trace.CallStackFromContext(ctx), time.Since(start).Milliseconds(), err)
+		return MembershipResponse{}, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("upstream_call failed service=user-service method=GET path=%s request_id=%s call_stack=%s duration_ms=%d err=%v",
+			logPath, trace.RequestIDFromContext(ctx), trace.CallStackFromContext(ctx), time.Since(start).Milliseconds(), err)
+		return MembershipResponse{}, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	log.Printf("upstream_call service=user-service method=GET path=%s request_id=%s call_stack=%s status=%d duration_ms=%d",
+		logPath, trace.RequestIDFromContext(ctx), trace.CallStackFromContext(ctx), resp.StatusCode, time.Since(start).Milliseconds())
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return MembershipResponse{}, ErrNotMember
+	default:
+		return MembershipResponse{}, fmt.Errorf("%w: status %d, body: %s", ErrServiceError, resp.StatusCode, string(body))
+	}
+
+	var m MembershipResponse
+	if err := json.Unmarshal(body, &m); err != nil {
+		return MembershipResponse{}, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return m, nil
+}
+
+// LookupInvitationWithContext valida un token de invitación sin consumirlo.
+func (c *UserClient) LookupInvitationWithContext(ctx context.Context, token string, headers map[string]string) (InvitationResponse, error) {
+	status, body, err := c.sendJSON(ctx, "POST", "/internal/invitations/lookup", "/internal/invitations/lookup",
+		map[string]string{"token": token}, headers)
+	if err != nil {
+		return InvitationResponse{}, err
+	}
+	if err := invitationStatusError(status, body); err != nil {
+		return InvitationResponse{}, err
+	}
+
+	var inv InvitationResponse
+	if err := json.Unmarshal(body, &inv); err != nil {
+		return InvitationResponse{}, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return inv, nil
+}
+
+// AcceptInvitationWithContext consume el token y suma a userID a la organización.
+func (c *UserClient) AcceptInvitationWithContext(ctx context.Context, token, userID string, headers map[string]string) (MembershipResponse, error) {
+	status, body, err := c.sendJSON(ctx, "POST", "/internal/invitations/accept", "/internal/invitations/accept",
+		map[string]string{"token": token, "user_id": userID}, headers)
+	if err != nil {
+		return MembershipResponse{}, err
+	}
+	if err := invitationStatusError(status, body); err != nil {
+		return MembershipResponse{}, err
+	}
+
+	var m MembershipResponse
+	if err := json.Unmarshal(body, &m); err != nil {
+		return MembershipResponse{}, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return m, nil
+}
+
+func invitationStatusError(status int, body []byte) error {
+	switch status {
+	case http.StatusOK:
+		return nil
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusGone:
+		return ErrInvalidInvitation
+	default:
+		return fmt.Errorf("%w: status %d, body: %s", ErrServiceError, status, string(body))
+	}
+}
+
+func (c *UserClient) putJSON(ctx context.Context, path, logPath string, payload interface{}, headers map[string]string) (int, []byte, error) {
+	return c.sendJSON(ctx, "PUT", path, logPath, payload, headers)
+}
+
+func (c *UserClient) sendJSON(ctx context.Context, method, path, logPath string, payload interface{}, headers map[string]string) (int, []byte, error) {
+	start := time.Now()
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range mergeHeaders(ctx, headers) {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		log.Printf("upstream_call failed service=user-service method=%s path=%s request_id=%s call_stack=%s duration_ms=%d err=%v",
+			method, logPath, trace.RequestIDFromContext(ctx), trace.CallStackFromContext(ctx), time.Since(start).Milliseconds(), err)
+		return 0, nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("upstream_call failed service=user-service method=%s path=%s request_id=%s call_stack=%s duration_ms=%d err=%v",
+			method, logPath, trace.RequestIDFromContext(ctx), trace.CallStackFromContext(ctx), time.Since(start).Milliseconds(), err)
+		return 0, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	log.Printf("upstream_call service=user-service method=%s path=%s request_id=%s call_stack=%s status=%d duration_ms=%d",
+		method, logPath, trace.RequestIDFromContext(ctx), trace.CallStackFromContext(ctx), resp.StatusCode, time.Since(start).Milliseconds())
+
+	return resp.StatusCode, body, nil
+}
@@ -1,11 +1,44 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+)
 
 type Config struct {
 	HTTPAddr       string
 	JWTSecret      string
 	UserServiceURL string
+
+	// SSO SAML: archivo JSON con la configuración por tenant y URL pública
+	// base (vía gateway) de los endpoints /sso/{tenant}/...
+	SAMLTenantsFile string
+	SAMLSPBaseURL   string
+
+	// Sesiones y detección de logins anómalos. Sin DSN se desactivan y los
+	// tokens vuelven a ser stateless.
+	DBDSN       string
+	GeoIPDBPath string
+	// MigrateOnBoot aplica las migraciones pendientes al arrancar (requiere DSN).
+	MigrateOnBoot bool
+
+	// Política de passwords. Las listas de filtraciones son opcionales:
+	// BreachedList es un archivo (texto plano o hashes SHA-1) y
+	// BreachedRangeDir un directorio de rangos k-anonymity (un archivo por prefijo).
+	PasswordMinLength        int
+	PasswordBreachedList     string
+	PasswordBreachedRangeDir string
+
+	// Link de confirmación de cambio de email (recibe ?token=...).
+	EmailConfirmURL string
+	// Página donde se elige la password nueva al resetearla (recibe ?token=...).
+	PasswordResetURL string
+
+	// Notificaciones al usuario por SMTP; sin dirección se loguean.
+	SMTPAddr     string
+	SMTPFrom     string
+	SMTPUsername string
+	SMTPPassword string
 }
 
 func Load() Config {
@@ -13,6 +46,26 @@ func Load() Config {
 		HTTPAddr:       getEnv("AUTH_HTTP_ADDR", ":8080"),
 		JWTSecret:      getEnv("JWT_SECRET", "dev-secret"),
 		UserServiceURL: getEnv("USER_SERVICE_URL", "http://localhost:8081"),
+
+		SAMLTenantsFile: getEnv("SAML_TENANTS_FILE", ""),
+		SAMLSPBaseURL:   getEnv("SAML_SP_BASE_URL", "http://localhost:8080/api/auth/sso"),
+
+		DBDSN:       getEnv("AUTH_DB_DSN", ""),
+		GeoIPDBPath: getEnv("GEOIP_DB_PATH", ""),
+
+		MigrateOnBoot: getBool("AUTH_MIGRATE_ON_BOOT", false),
+
+		PasswordMinLength:        getInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordBreachedList:     getEnv("PASSWORD_BREACHED_LIST", ""),
+		PasswordBreachedRangeDir: getEnv("PASSWORD_BREACHED_RANGE_DIR", ""),
+
+		EmailConfirmURL:  getEnv("EMAIL_CONFIRM_URL", "http://localhost:8080/api/auth/email/confirm"),
+		PasswordResetURL: getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+
+		SMTPAddr:     getEnv("SMTP_ADDR", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", "no-reply@localhost"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
 	}
 }
 
@@ -22,3 +75,21 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getInt(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func getBool(key string, fallback bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
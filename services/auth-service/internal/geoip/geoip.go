@@ -0,0 +1,128 @@
+// Package geoip resuelve IPs a ubicaciones aproximadas usando un archivo CSV
+// local (sin llamadas externas). Formato por línea:
+//
+//	network,country,latitude,longitude
+//	203.0.113.0/24,AR,-34.60,-58.38
+//
+// Las líneas vacías, las que empiezan con '#' y un header "network,..." se ignoran.
+package geoip
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidDatabase = errors.New("geoip: invalid database")
+
+type Location struct {
+	Country   string
+	Latitude  float64
+	Longitude float64
+}
+
+// DB indexa las redes por longitud de prefijo para resolver siempre la más específica.
+type DB struct {
+	byPrefix map[int]map[string]Location
+	prefixes []int // ordenados de más a menos específico
+}
+
+// Open carga la base desde path. Un path vacío devuelve una base vacía.
+func Open(path string) (*DB, error) {
+	if path == "" {
+		return &DB{}, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+func Load(r io.Reader) (*DB, error) {
+	db := &DB{byPrefix: make(map[int]map[string]Location)}
+
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 4
+	cr.TrimLeadingSpace = true
+
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDatabase, err)
+		}
+		if line == 1 && strings.EqualFold(rec[0], "network") {
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(strings.TrimSpace(rec[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidDatabase, line, err)
+		}
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(rec[2]), 64)
+		lon, errLon := strconv.ParseFloat(strings.TrimSpace(rec[3]), 64)
+		if errLat != nil || errLon != nil || math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+			return nil, fmt.Errorf("%w: line %d: invalid coordinates", ErrInvalidDatabase, line)
+		}
+
+		ones, _ := network.Mask.Size()
+		if len(network.IP) == net.IPv4len {
+			ones += 96 // normalizamos todo a 16 bytes
+		}
+		if db.byPrefix[ones] == nil {
+			db.byPrefix[ones] = make(map[string]Location)
+			db.prefixes = append(db.prefixes, ones)
+		}
+		db.byPrefix[ones][network.IP.To16().String()] = Location{
+			Country:   strings.ToUpper(strings.TrimSpace(rec[1])),
+			Latitude:  lat,
+			Longitude: lon,
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(db.prefixes)))
+	return db, nil
+}
+
+// Lookup devuelve la ubicación de la red más específica que contiene ip.
+func (db *DB) Lookup(ip string) (Location, bool) {
+	if db == nil {
+		return Location{}, false
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return Location{}, false
+	}
+	parsed = parsed.To16()
+	for _, ones := range db.prefixes {
+		key := parsed.Mask(net.CIDRMask(ones, 128)).String()
+		if loc, ok := db.byPrefix[ones][key]; ok {
+			return loc, true
+		}
+	}
+	return Location{}, false
+}
+
+const earthRadiusKm = 6371.0
+
+// DistanceKm calcula la distancia de gran círculo (haversine) entre dos ubicaciones.
+func DistanceKm(a, b Location) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
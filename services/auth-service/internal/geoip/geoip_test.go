@@ -0,0 +1,56 @@
+package geoip
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testDB = `network,country,latitude,longitude
+# Buenos Aires
+203.0.113.0/24,AR,-34.60,-58.38
+203.0.0.0/16,UY,-34.90,-56.16
+2001:db8:1::/48,ES,40.42,-3.70
+`
+
+func TestLookup_MostSpecificNetwork(t *testing.T) {
+	db, err := Load(strings.NewReader(testDB))
+	require.NoError(t, err)
+
+	loc, ok := db.Lookup("203.0.113.10")
+	require.True(t, ok)
+	require.Equal(t, "AR", loc.Country)
+
+	loc, ok = db.Lookup("203.0.1.1")
+	require.True(t, ok)
+	require.Equal(t, "UY", loc.Country)
+
+	loc, ok = db.Lookup("2001:db8:1::42")
+	require.True(t, ok)
+	require.Equal(t, "ES", loc.Country)
+
+	_, ok = db.Lookup("198.51.100.1")
+	require.False(t, ok)
+
+	_, ok = db.Lookup("not-an-ip")
+	require.False(t, ok)
+}
+
+func TestLoad_InvalidDatabase(t *testing.T) {
+	_, err := Load(strings.NewReader("203.0.113.0/24,AR,-134.60,-58.38\n"))
+	require.ErrorIs(t, err, ErrInvalidDatabase)
+
+	_, err = Load(strings.NewReader("nope,AR,0,0\n"))
+	require.ErrorIs(t, err, ErrInvalidDatabase)
+}
+
+func TestDistanceKm(t *testing.T) {
+	buenosAires := Location{Latitude: -34.60, Longitude: -58.38}
+	madrid := Location{Latitude: 40.42, Longitude: -3.70}
+
+	d := DistanceKm(buenosAires, madrid)
+	require.InDelta(t, 10040, d, 50)
+	require.Zero(t, math.Round(DistanceKm(madrid, madrid)))
+}
@@ -2,9 +2,11 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 
+	"saas-subscription-platform/services/auth-service/internal/password"
 	"saas-subscription-platform/services/auth-service/internal/service"
 )
 
@@ -21,18 +23,40 @@ type credentials struct {
 	Password string `json:"password"`
 }
 
+// registerRequest admite un invite_token opcional para sumarse a una
+// organización al crear la cuenta.
+type registerRequest struct {
+	credentials
+	InviteToken string `json:"invite_token"`
+}
+
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
-	var c credentials
+	var c registerRequest
 	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
 		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.auth.RegisterWithContext(r.Context(), c.Email, c.Password); err != nil {
+	var err error
+	if c.InviteToken != "" {
+		err = h.auth.RegisterWithInvitationContext(r.Context(), c.Email, c.Password, c.InviteToken)
+	} else {
+		err = h.auth.RegisterWithContext(r.Context(), c.Email, c.Password)
+	}
+	if err != nil {
+		var policyErr *password.ValidationError
+		if errors.As(err, &policyErr) {
+			writePasswordPolicyError(w, policyErr)
+			return
+		}
 		if err == service.ErrUserExists {
 			http.Error(w, err.Error(), http.StatusConflict)
 			return
 		}
+		if err == service.ErrInvalidEmail || err == service.ErrInvalidInvitation {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		log.Printf("auth_register_failed err=%v", err)
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
@@ -48,11 +72,20 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.auth.LoginWithContext(r.Context(), c.Email, c.Password)
+	ctx := service.WithClientInfo(r.Context(), clientInfo(r))
+	token, err := h.auth.LoginWithContext(ctx, c.Email, c.Password)
 	if err != nil {
 		// No exponer si el user existe o no, pero loguear el error real para debugging.
 		log.Printf("auth_login_failed err=%v", err)
-		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, service.ErrAccountInactive) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
@@ -60,3 +93,13 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		"access_token": token,
 	})
 }
+
+// writePasswordPolicyError responde 422 con todas las reglas violadas.
+func writePasswordPolicyError(w http.ResponseWriter, err *password.ValidationError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":      "password_policy",
+		"violations": err.Violations,
+	})
+}
@@ -18,14 +18,24 @@ import (
 
 type stubUserClient struct {
 	createFn    func(ctx context.Context, email, password string, headers map[string]string) (client.CreateUserResponse, error)
+	createSSOFn func(ctx context.Context, email, password, tenantID string, headers map[string]string) (client.CreateUserResponse, error)
 	getByEmail  func(ctx context.Context, email string, headers map[string]string) (client.GetUserByEmailResponse, error)
 	getByIDFunc func(ctx context.Context, userID string, headers map[string]string) (client.GetUserByEmailResponse, error)
+	updatePwdFn func(ctx context.Context, userID, passwordHash string, headers map[string]string) error
+	replaceFn   func(ctx context.Context, userID, currentEmail, newEmail string, headers map[string]string) error
+	membershipF func(ctx context.Context, orgID, userID string, headers map[string]string) (client.MembershipResponse, error)
+	lookupInvFn func(ctx context.Context, token string, headers map[string]string) (client.InvitationResponse, error)
+	acceptInvFn func(ctx context.Context, token, userID string, headers map[string]string) (client.MembershipResponse, error)
 }
 
 func (s stubUserClient) CreateUserWithContext(ctx context.Context, email, password string, headers map[string]string) (client.CreateUserResponse, error) {
 	return s.createFn(ctx, email, password, headers)
 }
 
+func (s stubUserClient) CreateSSOUserWithContext(ctx context.Context, email, password, tenantID string, headers map[string]string) (client.CreateUserResponse, error) {
+	return s.createSSOFn(ctx, email, password, tenantID, headers)
+}
+
 func (s stubUserClient) GetUserByEmailWithContext(ctx context.Context, email string, headers map[string]string) (client.GetUserByEmailResponse, error) {
 	return s.getByEmail(ctx, email, headers)
 }
@@ -34,6 +44,26 @@ func (s stubUserClient) GetUserByIDWithContext(ctx context.Context, userID strin
 	return s.getByIDFunc(ctx, userID, headers)
 }
 
+func (s stubUserClient) UpdatePasswordWithContext(ctx context.Context, userID, passwordHash string, headers map[string]string) error {
+	return s.updatePwdFn(ctx, userID, passwordHash, headers)
+}
+
+func (s stubUserClient) ReplaceEmailWithContext(ctx context.Context, userID, currentEmail, newEmail string, headers map[string]string) error {
+	return s.replaceFn(ctx, userID, currentEmail, newEmail, headers)
+}
+
+func (s stubUserClient) GetMembershipWithContext(ctx context.Context, orgID, userID string, headers map[string]string) (client.MembershipResponse, error) {
+	return s.membershipF(ctx, orgID, userID, headers)
+}
+
+func (s stubUserClient) LookupInvitationWithContext(ctx context.Context, token string, headers map[string]string) (client.InvitationResponse, error) {
+	return s.lookupInvFn(ctx, token, headers)
+}
+
+func (s stubUserClient) AcceptInvitationWithContext(ctx context.Context, token, userID string, headers map[string]string) (client.MembershipResponse, error) {
+	return s.acceptInvFn(ctx, token, userID, headers)
+}
+
 func newAuthHandlerWithStub(c stubUserClient) *AuthHandler {
 	svc := service.NewAuthService("secret", c)
 	return NewAuthHandler(svc)
@@ -47,7 +77,7 @@ func TestRegisterHandler(t *testing.T) {
 		},
 	})
 
-	req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBufferString(`{"email":"alice@example.com","password":"pass"}`))
+	req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBufferString(`{"email":"alice@example.com","password":"lunar-tide-orchard"}`))
 	rr := httptest.NewRecorder()
 
 	h.Register(rr, req)
@@ -55,6 +85,42 @@ func TestRegisterHandler(t *testing.T) {
 	require.Equal(t, http.StatusCreated, rr.Code)
 }
 
+func TestRegisterHandler_WithInvitation(t *testing.T) {
+	accepted := ""
+	h := newAuthHandlerWithStub(stubUserClient{
+		lookupInvFn: func(ctx context.Context, token string, headers map[string]string) (client.InvitationResponse, error) {
+			if token != "inv-token" {
+				return client.InvitationResponse{}, client.ErrInvalidInvitation
+			}
+			return client.InvitationResponse{OrgID: "o-1", Email: "Alice@Example.com", Role: "member"}, nil
+		},
+		createFn: func(ctx context.Context, email, password string, headers map[string]string) (client.CreateUserResponse, error) {
+			return client.CreateUserResponse{ID: "u-1", Email: email}, nil
+		},
+		acceptInvFn: func(ctx context.Context, token, userID string, headers map[string]string) (client.MembershipResponse, error) {
+			accepted = userID
+			return client.MembershipResponse{OrgID: "o-1", UserID: userID, Role: "member"}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBufferString(`{"email":"alice@example.com","password":"lunar-tide-orchard","invite_token":"inv-token"}`))
+	rr := httptest.NewRecorder()
+	h.Register(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+	require.Equal(t, "u-1", accepted)
+
+	// Un email distinto al invitado no puede usar el token.
+	req = httptest.NewRequest(http.MethodPost, "/register", bytes.NewBufferString(`{"email":"mallory@example.com","password":"lunar-tide-orchard","invite_token":"inv-token"}`))
+	rr = httptest.NewRecorder()
+	h.Register(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/register", bytes.NewBufferString(`{"email":"alice@example.com","password":"lunar-tide-orchard","invite_token":"bogus"}`))
+	rr = httptest.NewRecorder()
+	h.Register(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
 func TestRegisterHandler_Conflict(t *testing.T) {
 	h := newAuthHandlerWithStub(stubUserClient{
 		createFn: func(ctx context.Context, email, password string, headers map[string]string) (client.CreateUserResponse, error) {
@@ -62,7 +128,7 @@ func TestRegisterHandler_Conflict(t *testing.T) {
 		},
 	})
 
-	req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBufferString(`{"email":"dup@example.com","password":"pass"}`))
+	req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBufferString(`{"email":"dup@example.com","password":"lunar-tide-orchard"}`))
 	rr := httptest.NewRecorder()
 
 	h.Register(rr, req)
@@ -70,6 +136,33 @@ func TestRegisterHandler_Conflict(t *testing.T) {
 	require.Equal(t, http.StatusConflict, rr.Code)
 }
 
+func TestRegisterHandler_PasswordPolicy(t *testing.T) {
+	h := newAuthHandlerWithStub(stubUserClient{
+		createFn: func(ctx context.Context, email, password string, headers map[string]string) (client.CreateUserResponse, error) {
+			t.Fatalf("user must not be created with a weak password")
+			return client.CreateUserResponse{}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBufferString(`{"email":"alice@example.com","password":"alice"}`))
+	rr := httptest.NewRecorder()
+
+	h.Register(rr, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	var body struct {
+		Error      string `json:"error"`
+		Violations []struct {
+			Rule string `json:"rule"`
+		} `json:"violations"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	require.Equal(t, "password_policy", body.Error)
+	require.Len(t, body.Violations, 2)
+	require.Equal(t, "min_length", body.Violations[0].Rule)
+	require.Equal(t, "contains_email", body.Violations[1].Rule)
+}
+
 func TestLoginHandler(t *testing.T) {
 	hashed, _ := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.DefaultCost)
 	h := newAuthHandlerWithStub(stubUserClient{
@@ -89,6 +182,22 @@ func TestLoginHandler(t *testing.T) {
 	require.NotEmpty(t, resp["access_token"])
 }
 
+func TestLoginHandler_SuspendedAccount(t *testing.T) {
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.DefaultCost)
+	h := newAuthHandlerWithStub(stubUserClient{
+		getByEmail: func(ctx context.Context, email string, headers map[string]string) (client.GetUserByEmailResponse, error) {
+			return client.GetUserByEmailResponse{ID: "u-1", Email: email, Password: string(hashed), Status: "suspended"}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(`{"email":"alice@example.com","password":"pass"}`))
+	rr := httptest.NewRecorder()
+
+	h.Login(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
 func TestLoginHandler_Invalid(t *testing.T) {
 	h := newAuthHandlerWithStub(stubUserClient{
 		getByEmail: func(ctx context.Context, email string, headers map[string]string) (client.GetUserByEmailResponse, error) {
@@ -107,7 +216,7 @@ func TestLoginHandler_Invalid(t *testing.T) {
 func TestMeHandler(t *testing.T) {
 	meHandler := Me(stubUserClient{
 		getByIDFunc: func(ctx context.Context, userID string, headers map[string]string) (client.GetUserByEmailResponse, error) {
-			return client.GetUserByEmailResponse{ID: userID, Email: "alice@example.com"}, nil
+			return client.GetUserByEmailResponse{ID: userID, Email: "alice@example.com", Name: "Alice", Locale: "es-AR", Timezone: "Europe/Madrid"}, nil
 		},
 	})
 
@@ -123,6 +232,9 @@ func TestMeHandler(t *testing.T) {
 	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
 	require.Equal(t, "u-1", resp["id"])
 	require.Equal(t, "alice@example.com", resp["email"])
+	require.Equal(t, "Alice", resp["name"])
+	require.Equal(t, "es-AR", resp["locale"])
+	require.Equal(t, "Europe/Madrid", resp["timezone"])
 }
 
 func TestMeHandler_NotFound(t *testing.T) {
@@ -152,3 +264,117 @@ func TestMeHandler_MissingUserID(t *testing.T) {
 
 	require.Equal(t, http.StatusUnauthorized, rr.Code)
 }
+
+func newImpersonateRequest(actorID, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/impersonate", bytes.NewBufferString(body))
+	return req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, actorID))
+}
+
+func TestImpersonateHandler(t *testing.T) {
+	h := newAuthHandlerWithStub(stubUserClient{
+		getByIDFunc: func(ctx context.Context, userID string, headers map[string]string) (client.GetUserByEmailResponse, error) {
+			if userID == "admin-1" {
+				return client.GetUserByEmailResponse{ID: userID, Role: "admin"}, nil
+			}
+			return client.GetUserByEmailResponse{ID: userID, Role: "user"}, nil
+		},
+	})
+
+	rr := httptest.NewRecorder()
+	h.Impersonate(rr, newImpersonateRequest("admin-1", `{"user_id":"u-1","reason":"ticket #42"}`))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp map[string]string
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.NotEmpty(t, resp["access_token"])
+}
+
+func TestImpersonateHandler_NotAdmin(t *testing.T) {
+	h := newAuthHandlerWithStub(stubUserClient{
+		getByIDFunc: func(ctx context.Context, userID string, headers map[string]string) (client.GetUserByEmailResponse, error) {
+			return client.GetUserByEmailResponse{ID: userID, Role: "support"}, nil
+		},
+	})
+
+	rr := httptest.NewRecorder()
+	h.Impersonate(rr, newImpersonateRequest("agent-1", `{"user_id":"u-1","reason":"ticket #42"}`))
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestImpersonateHandler_Nested(t *testing.T) {
+	h := newAuthHandlerWithStub(stubUserClient{})
+
+	req := newImpersonateRequest("u-1", `{"user_id":"u-2","reason":"x"}`)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ActorIDKey, "admin-1"))
+	rr := httptest.NewRecorder()
+	h.Impersonate(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestImpersonateHandler_MissingReason(t *testing.T) {
+	h := newAuthHandlerWithStub(stubUserClient{})
+
+	rr := httptest.NewRecorder()
+	h.Impersonate(rr, newImpersonateRequest("admin-1", `{"user_id":"u-1"}`))
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestChangePasswordHandler_BlockedWhileImpersonating(t *testing.T) {
+	h := newAuthHandlerWithStub(stubUserClient{})
+
+	req := httptest.NewRequest(http.MethodPost, "/password/change", bytes.NewBufferString(`{"current_password":"a","new_password":"b"}`))
+	ctx := context.WithValue(req.Context(), middleware.UserIDKey, "u-1")
+	req = req.WithContext(context.WithValue(ctx, middleware.ActorIDKey, "admin-1"))
+	rr := httptest.NewRecorder()
+
+	h.ChangePassword(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestChangePasswordHandler_WrongCurrentPassword(t *testing.T) {
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("current-password"), bcrypt.MinCost)
+	h := newAuthHandlerWithStub(stubUserClient{
+		getByIDFunc: func(ctx context.Context, userID string, headers map[string]string) (client.GetUserByEmailResponse, error) {
+			return client.GetUserByEmailResponse{ID: userID, Email: "alice@example.com"}, nil
+		},
+		getByEmail: func(ctx context.Context, email string, headers map[string]string) (client.GetUserByEmailResponse, error) {
+			return client.GetUserByEmailResponse{ID: "u-1", Email: email, Password: string(hashed)}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/password/change", bytes.NewBufferString(`{"current_password":"nope","new_password":"lunar-tide-orchard"}`))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "u-1"))
+	rr := httptest.NewRecorder()
+
+	h.ChangePassword(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestResetPasswordHandler(t *testing.T) {
+	h := newAuthHandlerWithStub(stubUserClient{})
+
+	rr := httptest.NewRecorder()
+	h.ResetPassword(rr, httptest.NewRequest(http.MethodPost, "/password/reset", bytes.NewBufferString(`{"new_password":"lunar-tide-orchard"}`)))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	h.ResetPassword(rr, httptest.NewRequest(http.MethodPost, "/password/reset", bytes.NewBufferString(`{"token":"garbage","new_password":"lunar-tide-orchard"}`)))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestConfirmEmailChangeHandler_InvalidToken(t *testing.T) {
+	h := newAuthHandlerWithStub(stubUserClient{})
+
+	rr := httptest.NewRecorder()
+	h.ConfirmEmailChange(rr, httptest.NewRequest(http.MethodGet, "/email/confirm", nil))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	h.ConfirmEmailChange(rr, httptest.NewRequest(http.MethodPost, "/email/confirm", bytes.NewBufferString(`{"token":"garbage"}`)))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
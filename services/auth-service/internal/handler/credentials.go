@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"saas-subscription-platform/services/auth-service/internal/middleware"
+	"saas-subscription-platform/services/auth-service/internal/password"
+	"saas-subscription-platform/services/auth-service/internal/service"
+)
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ChangePassword cambia la password del usuario autenticado. Exige la actual.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := credentialsOwner(w, r)
+	if !ok {
+		return
+	}
+
+	var req changePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CurrentPassword == "" {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	ctx := service.WithClientInfo(r.Context(), clientInfo(r))
+	token, err := h.auth.ChangePasswordWithContext(ctx, userID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		writeCredentialsError(w, err, "auth_password_change_failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"access_token": token,
+	})
+}
+
+type changeEmailRequest struct {
+	Password string `json:"password"`
+	NewEmail string `json:"new_email"`
+}
+
+// RequestEmailChange inicia el cambio de email; se aplica recién al confirmar.
+func (h *AuthHandler) RequestEmailChange(w http.ResponseWriter, r *http.Request) {
+	userID, ok := credentialsOwner(w, r)
+	if !ok {
+		return
+	}
+
+	var req changeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" || req.NewEmail == "" {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	ctx := service.WithClientInfo(r.Context(), clientInfo(r))
+	if err := h.auth.RequestEmailChangeWithContext(ctx, userID, req.Password, req.NewEmail); err != nil {
+		writeCredentialsError(w, err, "auth_email_change_failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ConfirmEmailChange aplica el cambio. Es pública: el link llega por email y
+// el token firmado es la única credencial. Acepta ?token= (link) o body JSON.
+func (h *AuthHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" && r.Method == http.MethodPost {
+		var body struct {
+			Token string `json:"token"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		token = body.Token
+	}
+	if token == "" {
+		http.Error(w, "missing token", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.auth.ConfirmEmailChangeWithContext(r.Context(), token); err != nil {
+		writeCredentialsError(w, err, "auth_email_confirm_failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RequestPasswordReset envía el link de reset. Es pública y responde 202
+// exista o no la cuenta.
+func (h *AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.auth.RequestPasswordResetWithContext(r.Context(), req.Email); err != nil {
+		writeCredentialsError(w, err, "auth_password_reset_request_failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword fija la password nueva con el token del link. Es pública: el
+// token firmado es la única credencial.
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.auth.ResetPasswordWithContext(r.Context(), req.Token, req.NewPassword); err != nil {
+		writeCredentialsError(w, err, "auth_password_reset_failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// credentialsOwner exige un usuario autenticado que no esté siendo impersonado.
+func credentialsOwner(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	if userID == "" {
+		http.Error(w, "user ID not found", http.StatusUnauthorized)
+		return "", false
+	}
+	if r.Context().Value(middleware.ActorIDKey) != nil {
+		http.Error(w, "credential changes are not allowed while impersonating", http.StatusForbidden)
+		return "", false
+	}
+	return userID, true
+}
+
+func writeCredentialsError(w http.ResponseWriter, err error, event string) {
+	var policyErr *password.ValidationError
+	switch {
+	case errors.As(err, &policyErr):
+		writePasswordPolicyError(w, policyErr)
+	case errors.Is(err, service.ErrInvalidCredentials):
+		http.Error(w, "current password is incorrect", http.StatusForbidden)
+	case errors.Is(err, service.ErrInvalidEmail):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrInvalidEmailToken), errors.Is(err, service.ErrInvalidResetToken):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrUserExists):
+		http.Error(w, "email already in use", http.StatusConflict)
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, "user not found", http.StatusNotFound)
+	default:
+		log.Printf("%s err=%v", event, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"saas-subscription-platform/services/auth-service/internal/middleware"
+	"saas-subscription-platform/services/auth-service/internal/service"
+)
+
+type impersonateRequest struct {
+	UserID string `json:"user_id"`
+	Reason string `json:"reason"`
+}
+
+// Impersonate emite un token de impersonación para el usuario pedido.
+// Solo admins; no se permite encadenar impersonaciones.
+func (h *AuthHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	if actorID == "" {
+		http.Error(w, "user ID not found", http.StatusUnauthorized)
+		return
+	}
+	if r.Context().Value(middleware.ActorIDKey) != nil {
+		http.Error(w, "already impersonating", http.StatusForbidden)
+		return
+	}
+
+	var req impersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.auth.ImpersonateWithContext(r.Context(), actorID, req.UserID, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrReasonRequired):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrForbidden):
+			http.Error(w, "impersonation not allowed", http.StatusForbidden)
+		case errors.Is(err, service.ErrUserNotFound):
+			http.Error(w, "user not found", http.StatusNotFound)
+		default:
+			log.Printf("auth_impersonate_failed err=%v", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"access_token": token,
+	})
+}
@@ -46,8 +46,14 @@ func Me(userClient UserFetcher) http.HandlerFunc {
 
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"id":    user.ID,
-			"email": user.Email,
+			"id":         user.ID,
+			"email":      user.Email,
+			"role":       user.Role,
+			"name":       user.Name,
+			"locale":     user.Locale,
+			"timezone":   user.Timezone,
+			"avatar_url": user.AvatarURL,
+			"phone":      user.Phone,
 		})
 	}
 }
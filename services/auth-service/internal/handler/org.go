@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"saas-subscription-platform/services/auth-service/internal/middleware"
+	"saas-subscription-platform/services/auth-service/internal/service"
+)
+
+type switchOrgRequest struct {
+	OrgID string `json:"org_id"`
+}
+
+// SwitchOrg cambia la organización activa y devuelve un token nuevo.
+// Con org_id vacío el token vuelve al ámbito personal.
+func (h *AuthHandler) SwitchOrg(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	if userID == "" {
+		http.Error(w, "user ID not found", http.StatusUnauthorized)
+		return
+	}
+	// El token de impersonación no se re-emite: perdería el claim "act".
+	if r.Context().Value(middleware.ActorIDKey) != nil {
+		http.Error(w, "switching organization is not allowed while impersonating", http.StatusForbidden)
+		return
+	}
+
+	var req switchOrgRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.auth.SwitchOrgWithContext(r.Context(), userID, req.OrgID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrForbidden):
+			http.Error(w, "not a member of the organization", http.StatusForbidden)
+		case errors.Is(err, service.ErrAccountInactive):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, service.ErrUserNotFound):
+			http.Error(w, "user not found", http.StatusNotFound)
+		default:
+			log.Printf("auth_switch_org_failed err=%v", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"access_token": token,
+	})
+}
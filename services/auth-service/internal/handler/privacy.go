@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"saas-subscription-platform/services/auth-service/internal/model"
+	"saas-subscription-platform/services/auth-service/internal/service"
+)
+
+// PrivacyHandler expone el export y el borrado GDPR que orquesta user-service.
+// security es nil si el servicio corre sin base: no guarda nada del usuario.
+type PrivacyHandler struct {
+	security *service.SecurityService
+}
+
+func NewPrivacyHandler(security *service.SecurityService) *PrivacyHandler {
+	return &PrivacyHandler{security: security}
+}
+
+type privacyRequest struct {
+	Email string `json:"email"`
+}
+
+// ExportUser devuelve sesiones, dispositivos, intentos de login y eventos de seguridad.
+func (h *PrivacyHandler) ExportUser(w http.ResponseWriter, r *http.Request) {
+	var req privacyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	data := model.UserData{
+		Sessions:      []model.Session{},
+		Devices:       []model.Device{},
+		LoginAttempts: []model.LoginAttempt{},
+		Events:        []model.SecurityEvent{},
+	}
+	if h.security != nil {
+		var err error
+		data, err = h.security.ExportUserData(r.Context(), r.PathValue("id"), req.Email)
+		if err != nil {
+			log.Printf("auth_user_export_failed user_id=%s err=%v", r.PathValue("id"), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// EraseUser borra todo lo que auth-service guarda del usuario.
+func (h *PrivacyHandler) EraseUser(w http.ResponseWriter, r *http.Request) {
+	var req privacyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if h.security != nil {
+		if err := h.security.EraseUserData(r.Context(), r.PathValue("id"), req.Email); err != nil {
+			log.Printf("auth_user_erase_failed user_id=%s err=%v", r.PathValue("id"), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"saas-subscription-platform/services/auth-service/internal/middleware"
+	"saas-subscription-platform/services/auth-service/internal/service"
+)
+
+// SecurityHandler expone la resolución de eventos de seguridad y la
+// verificación de sesiones que usa el gateway.
+type SecurityHandler struct {
+	security *service.SecurityService
+}
+
+func NewSecurityHandler(security *service.SecurityService) *SecurityHandler {
+	return &SecurityHandler{security: security}
+}
+
+// ConfirmEvent marca la actividad como reconocida por el usuario.
+func (h *SecurityHandler) ConfirmEvent(w http.ResponseWriter, r *http.Request) {
+	h.resolve(w, r, true)
+}
+
+// DenyEvent marca la actividad como no reconocida y revoca todas las sesiones.
+func (h *SecurityHandler) DenyEvent(w http.ResponseWriter, r *http.Request) {
+	h.resolve(w, r, false)
+}
+
+func (h *SecurityHandler) resolve(w http.ResponseWriter, r *http.Request, confirm bool) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	if userID == "" {
+		http.Error(w, "user ID not found", http.StatusUnauthorized)
+		return
+	}
+	// Solo el titular de la cuenta puede responder por su actividad.
+	if r.Context().Value(middleware.ActorIDKey) != nil {
+		http.Error(w, "not allowed while impersonating", http.StatusForbidden)
+		return
+	}
+
+	err := h.security.ResolveEvent(r.Context(), userID, r.PathValue("id"), confirm)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEventNotFound):
+			http.Error(w, "security event not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrEventResolved):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			log.Printf("auth_security_event_resolve_failed event_id=%s err=%v", r.PathValue("id"), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Session responde si la sesión {id} del usuario sigue activa. Es interna: la
+// consulta el gateway antes de aceptar un token con claim "sid".
+func (h *SecurityHandler) Session(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	if userID == "" {
+		http.Error(w, "user ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	active, err := h.security.SessionActive(r.Context(), r.PathValue("id"), userID)
+	if err != nil {
+		log.Printf("auth_session_check_failed session_id=%s err=%v", r.PathValue("id"), err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]bool{"active": active})
+}
+
+// clientInfo obtiene la IP real del cliente. El gateway agrega la IP que ve
+// al final de X-Forwarded-For, así que se toma la última entrada: las
+// anteriores las puede inventar el cliente.
+func clientInfo(r *http.Request) service.ClientInfo {
+	ip := ""
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		parts := strings.Split(xff, ",")
+		ip = strings.TrimSpace(parts[len(parts)-1])
+	}
+	if ip == "" {
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			ip = host
+		} else {
+			ip = r.RemoteAddr
+		}
+	}
+	return service.ClientInfo{IP: ip, UserAgent: r.UserAgent()}
+}
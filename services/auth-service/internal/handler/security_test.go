@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/auth-service/internal/middleware"
+	"saas-subscription-platform/services/auth-service/internal/model"
+	"saas-subscription-platform/services/auth-service/internal/repository"
+	"saas-subscription-platform/services/auth-service/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// stubSecurityStore implementa solo lo que usan estos tests; el resto entra en pánico.
+type stubSecurityStore struct {
+	service.SecurityStore
+	sessions map[string]model.Session
+	events   map[string]model.SecurityEvent
+	revoked  []string
+}
+
+func (s *stubSecurityStore) GetSession(_ context.Context, id string) (model.Session, error) {
+	if sess, ok := s.sessions[id]; ok {
+		return sess, nil
+	}
+	return model.Session{}, repository.ErrSessionNotFound
+}
+
+func (s *stubSecurityStore) GetEvent(_ context.Context, id string) (model.SecurityEvent, error) {
+	if ev, ok := s.events[id]; ok {
+		return ev, nil
+	}
+	return model.SecurityEvent{}, repository.ErrEventNotFound
+}
+
+func (s *stubSecurityStore) ResolveEvent(_ context.Context, id, status string) error {
+	ev := s.events[id]
+	ev.Status = status
+	s.events[id] = ev
+	return nil
+}
+
+func (s *stubSecurityStore) ForgetDevice(context.Context, model.Device) error { return nil }
+
+func (s *stubSecurityStore) RevokeUserSessions(_ context.Context, userID string) (int64, error) {
+	s.revoked = append(s.revoked, userID)
+	return 1, nil
+}
+
+func securityRequest(method, path, id, userID string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	req.SetPathValue("id", id)
+	return req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+}
+
+func TestSecurityHandler_DenyEvent(t *testing.T) {
+	store := &stubSecurityStore{events: map[string]model.SecurityEvent{
+		"ev-1": {ID: "ev-1", UserID: "u-1", Type: model.EventNewDevice, Status: model.EventPending, Fingerprint: "fp"},
+	}}
+	h := NewSecurityHandler(service.NewSecurityService(store, nil, nil))
+
+	rr := httptest.NewRecorder()
+	h.DenyEvent(rr, securityRequest(http.MethodPost, "/security/events/ev-1/deny", "ev-1", "u-2"))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	h.DenyEvent(rr, securityRequest(http.MethodPost, "/security/events/ev-1/deny", "ev-1", "u-1"))
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.Equal(t, []string{"u-1"}, store.revoked)
+
+	rr = httptest.NewRecorder()
+	h.ConfirmEvent(rr, securityRequest(http.MethodPost, "/security/events/ev-1/confirm", "ev-1", "u-1"))
+	require.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestSecurityHandler_DenyForbiddenWhileImpersonating(t *testing.T) {
+	h := NewSecurityHandler(service.NewSecurityService(&stubSecurityStore{}, nil, nil))
+
+	req := securityRequest(http.MethodPost, "/security/events/ev-1/deny", "ev-1", "u-1")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ActorIDKey, "admin-1"))
+	rr := httptest.NewRecorder()
+
+	h.DenyEvent(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestSecurityHandler_Session(t *testing.T) {
+	store := &stubSecurityStore{sessions: map[string]model.Session{
+		"s-1": {ID: "s-1", UserID: "u-1", ExpiresAt: time.Now().Add(time.Hour)},
+	}}
+	h := NewSecurityHandler(service.NewSecurityService(store, nil, nil))
+
+	tests := []struct {
+		id, userID string
+		want       string
+	}{
+		{"s-1", "u-1", `{"active":true}`},
+		{"s-1", "u-2", `{"active":false}`},
+		{"missing", "u-1", `{"active":false}`},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		h.Session(rr, securityRequest(http.MethodGet, "/internal/sessions/"+tt.id, tt.id, tt.userID))
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, tt.want, rr.Body.String())
+	}
+}
+
+func TestClientInfo_UsesLastForwardedFor(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.RemoteAddr = "10.0.0.2:5000"
+	req.Header.Set("User-Agent", "test-agent")
+
+	require.Equal(t, service.ClientInfo{IP: "10.0.0.2", UserAgent: "test-agent"}, clientInfo(req))
+
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 203.0.113.10")
+	require.Equal(t, "203.0.113.10", clientInfo(req).IP)
+}
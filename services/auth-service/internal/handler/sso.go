@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"saas-subscription-platform/services/auth-service/internal/saml"
+	"saas-subscription-platform/services/auth-service/internal/service"
+)
+
+// SSOHandler expone el flujo SAML 2.0 por tenant (SP-initiated e IdP-initiated).
+type SSOHandler struct {
+	sp   *saml.ServiceProvider
+	auth *service.AuthService
+}
+
+func NewSSOHandler(sp *saml.ServiceProvider, auth *service.AuthService) *SSOHandler {
+	return &SSOHandler{sp: sp, auth: auth}
+}
+
+// Login inicia el flujo SP-initiated redirigiendo al IdP del tenant.
+func (h *SSOHandler) Login(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.PathValue("tenant")
+
+	redirectURL, err := h.sp.AuthnRequestURL(tenantID, r.URL.Query().Get("relay_state"))
+	if err != nil {
+		if errors.Is(err, saml.ErrUnknownTenant) {
+			http.Error(w, "unknown tenant", http.StatusNotFound)
+			return
+		}
+		log.Printf("auth_sso_login_failed tenant=%s err=%v", tenantID, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+// ACS recibe el SAMLResponse (binding HTTP-POST), lo valida y emite el JWT.
+func (h *SSOHandler) ACS(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.PathValue("tenant")
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	samlResponse := r.PostForm.Get("SAMLResponse")
+	if samlResponse == "" {
+		http.Error(w, "missing SAMLResponse", http.StatusBadRequest)
+		return
+	}
+
+	assertion, err := h.sp.ParseResponse(tenantID, samlResponse)
+	if err != nil {
+		if errors.Is(err, saml.ErrUnknownTenant) {
+			http.Error(w, "unknown tenant", http.StatusNotFound)
+			return
+		}
+		log.Printf("auth_sso_assertion_rejected tenant=%s err=%v", tenantID, err)
+		http.Error(w, "invalid saml response", http.StatusUnauthorized)
+		return
+	}
+
+	tenant, err := h.sp.Tenant(tenantID)
+	if err != nil {
+		http.Error(w, "unknown tenant", http.StatusNotFound)
+		return
+	}
+	h.login(w, r, tenant, assertion)
+}
+
+// login emite el JWT de una assertion ya validada, si el email es de un
+// dominio del tenant. Se controla antes de buscar o provisionar la cuenta: el
+// IdP del tenant solo responde por sus propios usuarios.
+func (h *SSOHandler) login(w http.ResponseWriter, r *http.Request, tenant *saml.Tenant, assertion *saml.Assertion) {
+	if !tenant.AllowsEmail(assertion.Email) {
+		log.Printf("auth_sso_domain_rejected tenant=%s", tenant.ID)
+		http.Error(w, "email domain not allowed for this tenant", http.StatusForbidden)
+		return
+	}
+
+	token, err := h.auth.LoginWithSSOContext(service.WithClientInfo(r.Context(), clientInfo(r)), tenant.ID, assertion.Email)
+	if err != nil {
+		log.Printf("auth_sso_login_failed tenant=%s err=%v", tenant.ID, err)
+		if errors.Is(err, service.ErrAccountInactive) || errors.Is(err, service.ErrSSOLinkRefused) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidSSOIdentity) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"access_token": token,
+		"relay_state":  r.PostForm.Get("RelayState"),
+	})
+}
+
+// Metadata devuelve la metadata del SP para configurar el IdP del tenant.
+func (h *SSOHandler) Metadata(w http.ResponseWriter, r *http.Request) {
+	md, err := h.sp.Metadata(r.PathValue("tenant"))
+	if err != nil {
+		http.Error(w, "unknown tenant", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	_, _ = w.Write(md)
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"saas-subscription-platform/services/auth-service/internal/client"
+	"saas-subscription-platform/services/auth-service/internal/saml"
+	"saas-subscription-platform/services/auth-service/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newSSOHandler() *SSOHandler {
+	sp := saml.NewServiceProvider("https://sp.example.com/api/auth/sso", []*saml.Tenant{{
+		ID:          "acme",
+		IdPEntityID: "https://idp.example.com/metadata",
+		IdPSSOURL:   "https://idp.example.com/sso",
+	}})
+	return NewSSOHandler(sp, service.NewAuthService("secret", stubUserClient{}))
+}
+
+func TestSSOLoginHandler_RedirectsToIdP(t *testing.T) {
+	h := newSSOHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/sso/acme/login?relay_state=/app", nil)
+	req.SetPathValue("tenant", "acme")
+	rr := httptest.NewRecorder()
+
+	h.Login(rr, req)
+
+	require.Equal(t, http.StatusFound, rr.Code)
+	loc, err := url.Parse(rr.Header().Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, "idp.example.com", loc.Host)
+	require.NotEmpty(t, loc.Query().Get("SAMLRequest"))
+	require.Equal(t, "/app", loc.Query().Get("RelayState"))
+}
+
+func TestSSOLoginHandler_UnknownTenant(t *testing.T) {
+	h := newSSOHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/sso/nope/login", nil)
+	req.SetPathValue("tenant", "nope")
+	rr := httptest.NewRecorder()
+
+	h.Login(rr, req)
+
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestSSOACSHandler_MissingResponse(t *testing.T) {
+	h := newSSOHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/sso/acme/acs", strings.NewReader("RelayState=x"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("tenant", "acme")
+	rr := httptest.NewRecorder()
+
+	h.ACS(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestSSOACSHandler_RejectsInvalidAssertion(t *testing.T) {
+	h := newSSOHandler()
+
+	form := url.Values{"SAMLResponse": {"PHNhbWxwOlJlc3BvbnNlLz4="}}
+	req := httptest.NewRequest(http.MethodPost, "/sso/acme/acs", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("tenant", "acme")
+	rr := httptest.NewRecorder()
+
+	h.ACS(rr, req)
+
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestSSOACSHandler_Login(t *testing.T) {
+	tenant := &saml.Tenant{ID: "acme", AllowedDomains: []string{"acme.com"}}
+	users := map[string]client.GetUserByEmailResponse{
+		"admin@acme.com": {ID: "u-admin", Role: "admin", Status: "active", SSOTenant: "acme"},
+		"carol@acme.com": {ID: "u-carol", Role: "user", Status: "active"},
+		"dave@acme.com":  {ID: "u-dave", Role: "user", Status: "active", SSOTenant: "globex"},
+		"erin@acme.com":  {ID: "u-erin", Role: "user", Status: "active", SSOTenant: "acme"},
+	}
+	lookups := 0
+	h := NewSSOHandler(saml.NewServiceProvider("https://sp.example.com/api/auth/sso", []*saml.Tenant{tenant}),
+		service.NewAuthService("secret", stubUserClient{
+			getByEmail: func(ctx context.Context, email string, headers map[string]string) (client.GetUserByEmailResponse, error) {
+				lookups++
+				if u, ok := users[email]; ok {
+					return u, nil
+				}
+				return client.GetUserByEmailResponse{}, client.ErrUserNotFound
+			},
+		}))
+
+	tests := []struct {
+		name        string
+		email       string
+		wantCode    int
+		wantLookups int
+	}{
+		// Otro dominio: se rechaza sin siquiera buscar la cuenta.
+		{name: "cross-domain", email: "root@platform.com", wantCode: http.StatusForbidden},
+		{name: "admin", email: "admin@acme.com", wantCode: http.StatusForbidden, wantLookups: 1},
+		{name: "password account", email: "carol@acme.com", wantCode: http.StatusForbidden, wantLookups: 1},
+		{name: "other tenant", email: "dave@acme.com", wantCode: http.StatusForbidden, wantLookups: 1},
+		{name: "sso account", email: "erin@acme.com", wantCode: http.StatusOK, wantLookups: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookups = 0
+			req := httptest.NewRequest(http.MethodPost, "/sso/acme/acs", nil)
+			rr := httptest.NewRecorder()
+
+			h.login(rr, req, tenant, &saml.Assertion{Email: tt.email})
+
+			require.Equal(t, tt.wantCode, rr.Code)
+			require.Equal(t, tt.wantLookups, lookups)
+		})
+	}
+}
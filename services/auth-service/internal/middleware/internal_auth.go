@@ -9,7 +9,13 @@ import (
 
 type contextKey string
 
-const UserIDKey contextKey = "user_id"
+const (
+	UserIDKey  contextKey = "user_id"
+	ActorIDKey contextKey = "actor_id"
+)
+
+// HeaderActorID lo agrega el gateway cuando el token es de impersonación.
+const HeaderActorID = "X-Internal-Actor-ID"
 
 // InternalAuth lee el header interno X-Internal-User-ID y lo pone en el contexto
 // Los microservicios confían en este header que viene del API Gateway
@@ -22,7 +28,28 @@ func InternalAuth(next http.Handler) http.Handler {
 		}
 
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
+		if actorID := r.Header.Get(HeaderActorID); actorID != "" {
+			ctx = context.WithValue(ctx, ActorIDKey, actorID)
+		}
 		ctx = trace.ExtractAndUpdateContext(ctx, r, "auth-service")
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// RequireCaller restringe la ruta a llamadas servicio-a-servicio. Va después de
+// InternalAuth: el gateway pisa X-Internal-User-ID con el sub del JWT, así que
+// un cliente externo no puede hacerse pasar por otro servicio.
+func RequireCaller(callers ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			caller, _ := r.Context().Value(UserIDKey).(string)
+			for _, c := range callers {
+				if caller == c {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			http.Error(w, "forbidden", http.StatusForbidden)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package model
+
+import "time"
+
+// Session representa un token emitido (claim "sid"). Revocarla invalida el
+// token en el gateway aunque todavía no haya expirado.
+type Session struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	ActorID   string     `json:"actor_id,omitempty"`
+	IP        string     `json:"ip"`
+	UserAgent string     `json:"user_agent"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active indica si la sesión sigue vigente en el instante now.
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// Device identifica un dispositivo conocido de un usuario.
+type Device struct {
+	UserID      string `json:"user_id"`
+	Fingerprint string `json:"fingerprint"`
+	IPPrefix    string `json:"ip_prefix"`
+}
+
+// LoginAttempt es un intento de login con la ubicación GeoIP (si se resolvió).
+type LoginAttempt struct {
+	UserID    string    `json:"user_id,omitempty"`
+	Email     string    `json:"email"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Success   bool      `json:"success"`
+	Country   string    `json:"country"`
+	Latitude  *float64  `json:"latitude,omitempty"`
+	Longitude *float64  `json:"longitude,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Tipos de eventos de seguridad.
+const (
+	EventNewDevice        = "new_device"
+	EventImpossibleTravel = "impossible_travel"
+	EventFailureBurst     = "failure_burst"
+)
+
+// Estados de un evento de seguridad.
+const (
+	EventPending   = "pending"
+	EventConfirmed = "confirmed"
+	EventDenied    = "denied"
+)
+
+type SecurityEvent struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	Type        string     `json:"type"`
+	Status      string     `json:"status"`
+	IP          string     `json:"ip"`
+	UserAgent   string     `json:"user_agent"`
+	Country     string     `json:"country"`
+	Fingerprint string     `json:"-"`
+	IPPrefix    string     `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+}
+
+// UserData es todo lo que auth-service guarda de un usuario (export GDPR).
+type UserData struct {
+	Sessions      []Session       `json:"sessions"`
+	Devices       []Device        `json:"devices"`
+	LoginAttempts []LoginAttempt  `json:"login_attempts"`
+	Events        []SecurityEvent `json:"security_events"`
+}
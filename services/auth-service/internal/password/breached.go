@@ -0,0 +1,122 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// ListChecker usa una lista local de passwords filtradas (una por línea, en
+// texto plano o como hash SHA-1 hex). Se carga entera en memoria como hashes.
+type ListChecker struct {
+	hashes map[string]struct{}
+}
+
+func LoadList(path string) (*ListChecker, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadList(f)
+}
+
+func ReadList(r io.Reader) (*ListChecker, error) {
+	c := &ListChecker{hashes: make(map[string]struct{})}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		// Formato HIBP "HASH:COUNT" o hash suelto.
+		if h, _, _ := strings.Cut(line, ":"); isSHA1Hex(h) {
+			c.hashes[strings.ToUpper(h)] = struct{}{}
+			continue
+		}
+		c.hashes[sha1Hex(line)] = struct{}{}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *ListChecker) Breached(password string) (bool, error) {
+	_, ok := c.hashes[sha1Hex(password)]
+	return ok, nil
+}
+
+// RangeDirChecker implementa el esquema k-anonymity de HIBP offline: un
+// directorio con un archivo por prefijo de 5 caracteres del SHA-1 (ej:
+// "5BAA6"), cuyo contenido son líneas "SUFIJO:COUNT" (el mismo formato que
+// devuelve la API de rangos). Solo se lee el archivo del prefijo consultado.
+type RangeDirChecker struct {
+	dir string
+}
+
+func NewRangeDirChecker(dir string) (*RangeDirChecker, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, errors.New("password: breached range path is not a directory")
+	}
+	return &RangeDirChecker{dir: dir}, nil
+}
+
+func (c *RangeDirChecker) Breached(password string) (bool, error) {
+	hash := sha1Hex(password)
+	prefix, suffix := hash[:5], hash[5:]
+
+	f, err := os.Open(filepath.Join(c.dir, prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		s, count, _ := strings.Cut(strings.TrimSpace(sc.Text()), ":")
+		// Los rangos con padding de HIBP incluyen sufijos con count 0.
+		if strings.EqualFold(s, suffix) && count != "0" {
+			return true, nil
+		}
+	}
+	return false, sc.Err()
+}
+
+// MultiChecker combina varios corpus: alcanza con que uno reporte la password.
+type MultiChecker []BreachedChecker
+
+func (m MultiChecker) Breached(password string) (bool, error) {
+	for _, c := range m {
+		breached, err := c.Breached(password)
+		if err != nil || breached {
+			return breached, err
+		}
+	}
+	return false, nil
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,122 @@
+// Package password define la política de passwords de auth-service: reglas
+// locales (longitud, límite de bcrypt, no contener el email) y el chequeo
+// offline contra passwords filtradas.
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// bcryptMaxBytes es el máximo que bcrypt procesa: el resto se ignoraría (o
+// falla, según la versión), así que se rechaza en vez de truncar en silencio.
+const bcryptMaxBytes = 72
+
+// Reglas que puede violar una password.
+const (
+	RuleMinLength     = "min_length"
+	RuleMaxBytes      = "max_bytes"
+	RuleContainsEmail = "contains_email"
+	RuleBreached      = "breached"
+)
+
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError lista todas las reglas violadas, no solo la primera, para
+// que el cliente pueda mostrarlas juntas.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	rules := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		rules[i] = v.Rule
+	}
+	return "password policy violated: " + strings.Join(rules, ", ")
+}
+
+// BreachedChecker indica si una password aparece en un corpus de filtraciones.
+type BreachedChecker interface {
+	Breached(password string) (bool, error)
+}
+
+type Policy struct {
+	MinLength int // en caracteres (runas)
+	MaxBytes  int // nunca más que bcryptMaxBytes
+	// Breached es opcional; nil desactiva el chequeo.
+	Breached BreachedChecker
+}
+
+// DefaultPolicy es la política sin corpus de filtraciones.
+func DefaultPolicy() Policy {
+	return Policy{MinLength: 8, MaxBytes: bcryptMaxBytes}
+}
+
+// Validate devuelve *ValidationError si la password viola alguna regla. Otro
+// tipo de error indica que no se pudo consultar el corpus de filtraciones.
+func (p Policy) Validate(password, email string) error {
+	var violations []Violation
+
+	maxBytes := p.MaxBytes
+	if maxBytes <= 0 || maxBytes > bcryptMaxBytes {
+		maxBytes = bcryptMaxBytes
+	}
+
+	if n := utf8.RuneCountInString(password); n < p.MinLength {
+		violations = append(violations, Violation{
+			Rule:    RuleMinLength,
+			Message: fmt.Sprintf("password must be at least %d characters long", p.MinLength),
+		})
+	}
+	if len(password) > maxBytes {
+		violations = append(violations, Violation{
+			Rule:    RuleMaxBytes,
+			Message: fmt.Sprintf("password must be at most %d bytes long", maxBytes),
+		})
+	}
+	if containsEmail(password, email) {
+		violations = append(violations, Violation{
+			Rule:    RuleContainsEmail,
+			Message: "password must not contain your email address",
+		})
+	}
+
+	if p.Breached != nil && password != "" {
+		breached, err := p.Breached.Breached(password)
+		if err != nil {
+			return fmt.Errorf("breached password check failed: %w", err)
+		}
+		if breached {
+			violations = append(violations, Violation{
+				Rule:    RuleBreached,
+				Message: "password has appeared in a data breach; choose a different one",
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// containsEmail compara sin distinguir mayúsculas contra el email completo y
+// contra la parte local (si tiene al menos 3 caracteres, para no rechazar
+// passwords por coincidencias triviales como "al").
+func containsEmail(password, email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return false
+	}
+	lower := strings.ToLower(password)
+	if strings.Contains(lower, email) {
+		return true
+	}
+	local, _, found := strings.Cut(email, "@")
+	return found && utf8.RuneCountInString(local) >= 3 && strings.Contains(lower, local)
+}
@@ -0,0 +1,91 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func violatedRules(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr), "expected ValidationError, got %v", err)
+	rules := make([]string, len(verr.Violations))
+	for i, v := range verr.Violations {
+		rules[i] = v.Rule
+	}
+	return rules
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	breached, err := ReadList(strings.NewReader("password123\ncorrect horse battery staple\n"))
+	require.NoError(t, err)
+	p := Policy{MinLength: 10, MaxBytes: 72, Breached: breached}
+
+	tests := []struct {
+		name     string
+		password string
+		email    string
+		want     []string
+	}{
+		{name: "valid", password: "lunar-tide-orchard", email: "alice@example.com"},
+		{name: "empty", password: "", email: "alice@example.com", want: []string{RuleMinLength}},
+		{name: "too short", password: "short", email: "alice@example.com", want: []string{RuleMinLength}},
+		{name: "length counts characters not bytes", password: "ñandúñandú", email: "x@example.com"},
+		{name: "over bcrypt limit", password: strings.Repeat("a", 73), email: "x@example.com", want: []string{RuleMaxBytes}},
+		{name: "contains email", password: "ALICE@example.com!!", email: "alice@example.com", want: []string{RuleContainsEmail}},
+		{name: "contains local part", password: "my-alice-password", email: "alice@example.com", want: []string{RuleContainsEmail}},
+		{name: "short local part ignored", password: "algebra-is-fun", email: "al@example.com"},
+		{name: "breached", password: "password123", email: "bob@example.com", want: []string{RuleBreached}},
+		{name: "several rules", password: "bob", email: "bob@example.com", want: []string{RuleMinLength, RuleContainsEmail}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, violatedRules(t, p.Validate(tt.password, tt.email)))
+		})
+	}
+}
+
+func TestPolicy_MaxBytesNeverAboveBcrypt(t *testing.T) {
+	p := Policy{MinLength: 1, MaxBytes: 500}
+	require.Equal(t, []string{RuleMaxBytes}, violatedRules(t, p.Validate(strings.Repeat("a", 73), "")))
+}
+
+func TestReadList_AcceptsHashes(t *testing.T) {
+	// SHA-1("password") en formato HIBP.
+	c, err := ReadList(strings.NewReader("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\n"))
+	require.NoError(t, err)
+
+	breached, err := c.Breached("password")
+	require.NoError(t, err)
+	require.True(t, breached)
+}
+
+func TestRangeDirChecker(t *testing.T) {
+	dir := t.TempDir()
+	// SHA-1("password") = 5BAA6 1E4C9B93F3F0682250B6CF8331B7EE68FD8
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "5BAA6"), []byte(
+		"0018A45C4D1DEF81644B54AB7F969B88D65:0\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n"), 0o600))
+
+	c, err := NewRangeDirChecker(dir)
+	require.NoError(t, err)
+
+	breached, err := c.Breached("password")
+	require.NoError(t, err)
+	require.True(t, breached)
+
+	breached, err = c.Breached("lunar-tide-orchard")
+	require.NoError(t, err)
+	require.False(t, breached)
+
+	_, err = NewRangeDirChecker(filepath.Join(dir, "5BAA6"))
+	require.Error(t, err)
+}
@@ -0,0 +1,9 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+)
+
+func generateUUID() string {
+	return uuid.NewString()
+}
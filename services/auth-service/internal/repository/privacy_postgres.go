@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"saas-subscription-platform/services/auth-service/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ExportUserData junta sesiones, dispositivos, intentos de login y eventos del
+// usuario. Los intentos fallidos sin user_id se buscan por email.
+func (r *SecurityRepository) ExportUserData(ctx context.Context, userID, email string) (model.UserData, error) {
+	data := model.UserData{
+		Sessions:      []model.Session{},
+		Devices:       []model.Device{},
+		LoginAttempts: []model.LoginAttempt{},
+		Events:        []model.SecurityEvent{},
+	}
+
+	err := collect(ctx, r.db, &data.Sessions, `
+		SELECT id, user_id, COALESCE(actor_id::text, ''), ip, user_agent, created_at, expires_at, revoked_at
+		FROM sessions WHERE user_id = $1 ORDER BY created_at
+	`, []interface{}{userID}, func(rows pgx.Rows) (s model.Session, err error) {
+		err = rows.Scan(&s.ID, &s.UserID, &s.ActorID, &s.IP, &s.UserAgent, &s.CreatedAt, &s.ExpiresAt, &s.RevokedAt)
+		return s, err
+	})
+	if err != nil {
+		return model.UserData{}, fmt.Errorf("export sessions: %w", err)
+	}
+
+	err = collect(ctx, r.db, &data.Devices, `
+		SELECT user_id, fingerprint, ip_prefix FROM known_devices WHERE user_id = $1 ORDER BY first_seen_at
+	`, []interface{}{userID}, func(rows pgx.Rows) (d model.Device, err error) {
+		err = rows.Scan(&d.UserID, &d.Fingerprint, &d.IPPrefix)
+		return d, err
+	})
+	if err != nil {
+		return model.UserData{}, fmt.Errorf("export devices: %w", err)
+	}
+
+	err = collect(ctx, r.db, &data.LoginAttempts, `
+		SELECT COALESCE(user_id::text, ''), email, ip, user_agent, success, country, latitude, longitude, created_at
+		FROM login_attempts WHERE user_id = $1 OR email = $2 ORDER BY created_at
+	`, []interface{}{userID, email}, func(rows pgx.Rows) (a model.LoginAttempt, err error) {
+		err = rows.Scan(&a.UserID, &a.Email, &a.IP, &a.UserAgent, &a.Success, &a.Country, &a.Latitude, &a.Longitude, &a.CreatedAt)
+		return a, err
+	})
+	if err != nil {
+		return model.UserData{}, fmt.Errorf("export login attempts: %w", err)
+	}
+
+	err = collect(ctx, r.db, &data.Events, `
+		SELECT id, user_id, type, status, ip, user_agent, country, fingerprint, ip_prefix, created_at, resolved_at
+		FROM security_events WHERE user_id = $1 ORDER BY created_at
+	`, []interface{}{userID}, func(rows pgx.Rows) (ev model.SecurityEvent, err error) {
+		err = rows.Scan(&ev.ID, &ev.UserID, &ev.Type, &ev.Status, &ev.IP, &ev.UserAgent, &ev.Country,
+			&ev.Fingerprint, &ev.IPPrefix, &ev.CreatedAt, &ev.ResolvedAt)
+		return ev, err
+	})
+	if err != nil {
+		return model.UserData{}, fmt.Errorf("export security events: %w", err)
+	}
+
+	return data, nil
+}
+
+// EraseUserData borra todo lo que auth-service guarda del usuario. Cada DELETE
+// es idempotente, así que un borrado que falle a mitad se puede reintentar.
+func (r *SecurityRepository) EraseUserData(ctx context.Context, userID, email string) error {
+	statements := []struct {
+		name  string
+		query string
+		args  []interface{}
+	}{
+		{"sessions", `DELETE FROM sessions WHERE user_id = $1`, []interface{}{userID}},
+		{"devices", `DELETE FROM known_devices WHERE user_id = $1`, []interface{}{userID}},
+		{"login attempts", `DELETE FROM login_attempts WHERE user_id = $1 OR email = $2`, []interface{}{userID, email}},
+		{"security events", `DELETE FROM security_events WHERE user_id = $1`, []interface{}{userID}},
+	}
+	for _, st := range statements {
+		if _, err := r.db.Exec(ctx, st.query, st.args...); err != nil {
+			return fmt.Errorf("erase %s: %w", st.name, err)
+		}
+	}
+	return nil
+}
+
+// collect ejecuta query y agrega cada fila escaneada a dst.
+func collect[T any](ctx context.Context, db PgxPool, dst *[]T, query string, args []interface{}, scan func(pgx.Rows) (T, error)) error {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return err
+		}
+		*dst = append(*dst, item)
+	}
+	return rows.Err()
+}
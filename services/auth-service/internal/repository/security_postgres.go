@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"saas-subscription-platform/services/auth-service/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrEventNotFound   = errors.New("security event not found")
+)
+
+// PgxPool define las operaciones mínimas que usamos; la implementan *pgxpool.Pool y pgxmock.PgxPoolIface.
+type PgxPool interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+type SecurityRepository struct {
+	db PgxPool
+}
+
+func NewSecurityRepository(db PgxPool) *SecurityRepository {
+	return &SecurityRepository{db: db}
+}
+
+func (r *SecurityRepository) CreateSession(ctx context.Context, s model.Session) (model.Session, error) {
+	s.ID = generateUUID()
+
+	query := `
+		INSERT INTO sessions (id, user_id, actor_id, ip, user_agent, expires_at)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6)
+		RETURNING created_at
+	`
+	err := r.db.QueryRow(ctx, query, s.ID, s.UserID, s.ActorID, s.IP, s.UserAgent, s.ExpiresAt).Scan(&s.CreatedAt)
+	if err != nil {
+		return model.Session{}, err
+	}
+	return s, nil
+}
+
+func (r *SecurityRepository) GetSession(ctx context.Context, id string) (model.Session, error) {
+	var s model.Session
+
+	query := `
+		SELECT id, user_id, COALESCE(actor_id::text, ''), ip, user_agent, created_at, expires_at, revoked_at
+		FROM sessions
+		WHERE id = $1
+	`
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&s.ID, &s.UserID, &s.ActorID, &s.IP, &s.UserAgent, &s.CreatedAt, &s.ExpiresAt, &s.RevokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Session{}, ErrSessionNotFound
+		}
+		return model.Session{}, err
+	}
+	return s, nil
+}
+
+// RevokeUserSessions revoca todas las sesiones vigentes del usuario y devuelve cuántas fueron.
+func (r *SecurityRepository) RevokeUserSessions(ctx context.Context, userID string) (int64, error) {
+	query := `
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+	`
+	tag, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// HasDevices indica si el usuario ya tiene algún dispositivo registrado (primer login o no).
+func (r *SecurityRepository) HasDevices(ctx context.Context, userID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM known_devices WHERE user_id = $1)`, userID).Scan(&exists)
+	return exists, err
+}
+
+func (r *SecurityRepository) IsKnownDevice(ctx context.Context, d model.Device) (bool, error) {
+	var exists bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM known_devices
+			WHERE user_id = $1 AND fingerprint = $2 AND ip_prefix = $3
+		)
+	`
+	err := r.db.QueryRow(ctx, query, d.UserID, d.Fingerprint, d.IPPrefix).Scan(&exists)
+	return exists, err
+}
+
+func (r *SecurityRepository) TouchDevice(ctx context.Context, d model.Device) error {
+	query := `
+		INSERT INTO known_devices (user_id, fingerprint, ip_prefix)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, fingerprint, ip_prefix) DO UPDATE SET last_seen_at = now()
+	`
+	_, err := r.db.Exec(ctx, query, d.UserID, d.Fingerprint, d.IPPrefix)
+	return err
+}
+
+func (r *SecurityRepository) ForgetDevice(ctx context.Context, d model.Device) error {
+	query := `DELETE FROM known_devices WHERE user_id = $1 AND fingerprint = $2 AND ip_prefix = $3`
+	_, err := r.db.Exec(ctx, query, d.UserID, d.Fingerprint, d.IPPrefix)
+	return err
+}
+
+func (r *SecurityRepository) RecordLoginAttempt(ctx context.Context, a model.LoginAttempt) error {
+	query := `
+		INSERT INTO login_attempts (user_id, email, ip, user_agent, success, country, latitude, longitude)
+		VALUES (NULLIF($1, '')::uuid, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.db.Exec(ctx, query, a.UserID, a.Email, a.IP, a.UserAgent, a.Success, a.Country, a.Latitude, a.Longitude)
+	return err
+}
+
+// LastLocatedLogin devuelve el último login exitoso del usuario con ubicación GeoIP.
+// found es false si no hay ninguno.
+func (r *SecurityRepository) LastLocatedLogin(ctx context.Context, userID string) (a model.LoginAttempt, found bool, err error) {
+	query := `
+		SELECT COALESCE(user_id::text, ''), email, ip, user_agent, success, country, latitude, longitude, created_at
+		FROM login_attempts
+		WHERE user_id = $1 AND success AND latitude IS NOT NULL AND longitude IS NOT NULL
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	err = r.db.QueryRow(ctx, query, userID).Scan(
+		&a.UserID, &a.Email, &a.IP, &a.UserAgent, &a.Success, &a.Country, &a.Latitude, &a.Longitude, &a.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.LoginAttempt{}, false, nil
+		}
+		return model.LoginAttempt{}, false, err
+	}
+	return a, true, nil
+}
+
+func (r *SecurityRepository) CountFailuresSince(ctx context.Context, email string, since time.Time) (int, error) {
+	var n int
+	query := `SELECT COUNT(*) FROM login_attempts WHERE email = $1 AND NOT success AND created_at >= $2`
+	err := r.db.QueryRow(ctx, query, email, since).Scan(&n)
+	return n, err
+}
+
+// HasEventSince indica si el usuario tiene un evento de ese tipo desde since.
+func (r *SecurityRepository) HasEventSince(ctx context.Context, userID, eventType string, since time.Time) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM security_events WHERE user_id = $1 AND type = $2 AND created_at >= $3)`
+	err := r.db.QueryRow(ctx, query, userID, eventType, since).Scan(&exists)
+	return exists, err
+}
+
+func (r *SecurityRepository) CreateEvent(ctx context.Context, ev model.SecurityEvent) (model.SecurityEvent, error) {
+	ev.ID = generateUUID()
+	ev.Status = model.EventPending
+
+	query := `
+		INSERT INTO security_events (id, user_id, type, status, ip, user_agent, country, fingerprint, ip_prefix)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at
+	`
+	err := r.db.QueryRow(ctx, query,
+		ev.ID, ev.UserID, ev.Type, ev.Status, ev.IP, ev.UserAgent, ev.Country, ev.Fingerprint, ev.IPPrefix,
+	).Scan(&ev.CreatedAt)
+	if err != nil {
+		return model.SecurityEvent{}, err
+	}
+	return ev, nil
+}
+
+func (r *SecurityRepository) GetEvent(ctx context.Context, id string) (model.SecurityEvent, error) {
+	var ev model.SecurityEvent
+
+	query := `
+		SELECT id, user_id, type, status, ip, user_agent, country, fingerprint, ip_prefix, created_at, resolved_at
+		FROM security_events
+		WHERE id = $1
+	`
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&ev.ID, &ev.UserID, &ev.Type, &ev.Status, &ev.IP, &ev.UserAgent, &ev.Country,
+		&ev.Fingerprint, &ev.IPPrefix, &ev.CreatedAt, &ev.ResolvedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.SecurityEvent{}, ErrEventNotFound
+		}
+		return model.SecurityEvent{}, err
+	}
+	return ev, nil
+}
+
+// ResolveEvent pasa un evento pendiente a confirmed/denied. Devuelve
+// ErrEventNotFound si no existe o ya estaba resuelto.
+func (r *SecurityRepository) ResolveEvent(ctx context.Context, id, status string) error {
+	query := `
+		UPDATE security_events
+		SET status = $2, resolved_at = now()
+		WHERE id = $1 AND status = 'pending'
+	`
+	tag, err := r.db.Exec(ctx, query, id, status)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrEventNotFound
+	}
+	return nil
+}
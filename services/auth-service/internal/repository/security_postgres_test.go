@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/auth-service/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepo(t *testing.T) (*SecurityRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockPool.Close() })
+	return NewSecurityRepository(mockPool), mockPool
+}
+
+func TestSecurityRepository_CreateSession(t *testing.T) {
+	repo, mock := newTestRepo(t)
+	expires := time.Now().Add(time.Hour)
+
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO sessions")).
+		WithArgs(pgxmock.AnyArg(), "u-1", "", "203.0.113.10", "ua", expires).
+		WillReturnRows(pgxmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+
+	s, err := repo.CreateSession(context.Background(), model.Session{UserID: "u-1", IP: "203.0.113.10", UserAgent: "ua", ExpiresAt: expires})
+
+	require.NoError(t, err)
+	require.NotEmpty(t, s.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSecurityRepository_GetSessionNotFound(t *testing.T) {
+	repo, mock := newTestRepo(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM sessions")).
+		WithArgs("missing").
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err := repo.GetSession(context.Background(), "missing")
+
+	require.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestSecurityRepository_RevokeUserSessions(t *testing.T) {
+	repo, mock := newTestRepo(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE sessions")).
+		WithArgs("u-1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 3))
+
+	n, err := repo.RevokeUserSessions(context.Background(), "u-1")
+
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSecurityRepository_LastLocatedLoginNone(t *testing.T) {
+	repo, mock := newTestRepo(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM login_attempts")).
+		WithArgs("u-1").
+		WillReturnError(pgx.ErrNoRows)
+
+	_, found, err := repo.LastLocatedLogin(context.Background(), "u-1")
+
+	require.NoError(t, err)
+	require.False(t, found)
+}
+
+func TestSecurityRepository_ResolveEventAlreadyResolved(t *testing.T) {
+	repo, mock := newTestRepo(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE security_events")).
+		WithArgs("ev-1", model.EventDenied).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	err := repo.ResolveEvent(context.Background(), "ev-1", model.EventDenied)
+
+	require.ErrorIs(t, err, ErrEventNotFound)
+}
+
+func TestSecurityRepository_ExportUserData(t *testing.T) {
+	repo, mock := newTestRepo(t)
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM sessions WHERE user_id = $1")).
+		WithArgs("u-1").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "user_id", "actor_id", "ip", "user_agent", "created_at", "expires_at", "revoked_at"}).
+			AddRow("s-1", "u-1", "", "203.0.113.10", "ua", now, now.Add(time.Hour), nil))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM known_devices")).
+		WithArgs("u-1").
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "fingerprint", "ip_prefix"}))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM login_attempts WHERE user_id = $1 OR email = $2")).
+		WithArgs("u-1", "jane@example.com").
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "email", "ip", "user_agent", "success", "country", "latitude", "longitude", "created_at"}).
+			AddRow("", "jane@example.com", "203.0.113.10", "ua", false, "AR", nil, nil, now))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM security_events")).
+		WithArgs("u-1").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "user_id", "type", "status", "ip", "user_agent", "country", "fingerprint", "ip_prefix", "created_at", "resolved_at"}))
+
+	data, err := repo.ExportUserData(context.Background(), "u-1", "jane@example.com")
+
+	require.NoError(t, err)
+	require.Len(t, data.Sessions, 1)
+	require.Empty(t, data.Devices)
+	require.NotNil(t, data.Devices)
+	require.Len(t, data.LoginAttempts, 1)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSecurityRepository_EraseUserData(t *testing.T) {
+	repo, mock := newTestRepo(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM sessions")).WithArgs("u-1").WillReturnResult(pgxmock.NewResult("DELETE", 2))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM known_devices")).WithArgs("u-1").WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM login_attempts")).WithArgs("u-1", "jane@example.com").WillReturnResult(pgxmock.NewResult("DELETE", 5))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM security_events")).WithArgs("u-1").WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	require.NoError(t, repo.EraseUserData(context.Background(), "u-1", "jane@example.com"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
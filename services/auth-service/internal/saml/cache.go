@@ -0,0 +1,49 @@
+package saml
+
+import (
+	"sync"
+	"time"
+)
+
+// expiringSet guarda IDs hasta su expiración. Se usa para los AuthnRequest
+// pendientes (InResponseTo) y como cache anti-replay de assertions.
+//
+// Es en memoria: con varias réplicas del auth-service el balanceador debe
+// mantener afinidad durante el flujo SSO.
+type expiringSet struct {
+	mu    sync.Mutex
+	items map[string]time.Time
+}
+
+func newExpiringSet() *expiringSet {
+	return &expiringSet{items: make(map[string]time.Time)}
+}
+
+// Add registra id hasta expiresAt. Devuelve false si ya existía y no expiró.
+func (s *expiringSet) Add(id string, expiresAt, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gc(now)
+	if exp, ok := s.items[id]; ok && exp.After(now) {
+		return false
+	}
+	s.items[id] = expiresAt
+	return true
+}
+
+// Consume elimina id y devuelve true si existía y seguía vigente.
+func (s *expiringSet) Consume(id string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exp, ok := s.items[id]
+	delete(s.items, id)
+	return ok && exp.After(now)
+}
+
+func (s *expiringSet) gc(now time.Time) {
+	for id, exp := range s.items {
+		if !exp.After(now) {
+			delete(s.items, id)
+		}
+	}
+}
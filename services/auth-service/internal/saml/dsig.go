@@ -0,0 +1,160 @@
+package saml
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	nsDSig = "http://www.w3.org/2000/09/xmldsig#"
+
+	algExcC14N         = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algEnveloped       = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	algRSASHA256       = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algRSASHA512       = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	algDigestSHA256    = "http://www.w3.org/2001/04/xmlenc#sha256"
+	algDigestSHA512    = "http://www.w3.org/2001/04/xmlenc#sha512"
+	nsExcC14NInclusive = "http://www.w3.org/2001/10/xml-exc-c14n#"
+)
+
+var (
+	ErrSignatureMissing = errors.New("saml: signature missing")
+	ErrSignatureInvalid = errors.New("saml: signature invalid")
+)
+
+// verifyEnvelopedSignature valida la firma enveloped que es hija directa de el
+// y cuya única Reference apunta al ID de el. Solo se aceptan algoritmos
+// exc-c14n + RSA-SHA256/512; SHA-1 se rechaza.
+//
+// La clave pública sale siempre del certificado configurado para el tenant,
+// nunca del KeyInfo del mensaje.
+func verifyEnvelopedSignature(el *xmlElement, idAttr string, cert *x509.Certificate) error {
+	sig := el.Child(nsDSig, "Signature")
+	if sig == nil {
+		return ErrSignatureMissing
+	}
+	if len(el.ChildElements(nsDSig, "Signature")) != 1 {
+		return fmt.Errorf("%w: multiple signatures", ErrSignatureInvalid)
+	}
+
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: unsupported certificate key type", ErrSignatureInvalid)
+	}
+
+	signedInfo := sig.Child(nsDSig, "SignedInfo")
+	if signedInfo == nil {
+		return fmt.Errorf("%w: missing SignedInfo", ErrSignatureInvalid)
+	}
+
+	c14n := signedInfo.Child(nsDSig, "CanonicalizationMethod")
+	if c14n == nil || c14n.AttrValue("Algorithm") != algExcC14N {
+		return fmt.Errorf("%w: unsupported canonicalization method", ErrSignatureInvalid)
+	}
+
+	var hash crypto.Hash
+	sigMethod := signedInfo.Child(nsDSig, "SignatureMethod")
+	if sigMethod == nil {
+		return fmt.Errorf("%w: missing SignatureMethod", ErrSignatureInvalid)
+	}
+	switch sigMethod.AttrValue("Algorithm") {
+	case algRSASHA256:
+		hash = crypto.SHA256
+	case algRSASHA512:
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("%w: unsupported signature method %q", ErrSignatureInvalid, sigMethod.AttrValue("Algorithm"))
+	}
+
+	refs := signedInfo.ChildElements(nsDSig, "Reference")
+	if len(refs) != 1 {
+		return fmt.Errorf("%w: expected exactly one reference", ErrSignatureInvalid)
+	}
+	ref := refs[0]
+
+	// La referencia debe apuntar al elemento que estamos validando: evita
+	// ataques de "signature wrapping" donde la firma cubre otro nodo.
+	id := el.AttrValue(idAttr)
+	if id == "" || ref.AttrValue("URI") != "#"+id {
+		return fmt.Errorf("%w: reference does not cover the signed element", ErrSignatureInvalid)
+	}
+
+	var inclusive []string
+	enveloped := false
+	if transforms := ref.Child(nsDSig, "Transforms"); transforms != nil {
+		for _, tr := range transforms.ChildElements(nsDSig, "Transform") {
+			switch tr.AttrValue("Algorithm") {
+			case algEnveloped:
+				enveloped = true
+			case algExcC14N:
+				if inc := tr.Child(nsExcC14NInclusive, "InclusiveNamespaces"); inc != nil {
+					inclusive = strings.Fields(inc.AttrValue("PrefixList"))
+				}
+			default:
+				return fmt.Errorf("%w: unsupported transform %q", ErrSignatureInvalid, tr.AttrValue("Algorithm"))
+			}
+		}
+	}
+	if !enveloped {
+		return fmt.Errorf("%w: enveloped-signature transform required", ErrSignatureInvalid)
+	}
+
+	digestMethod := ref.Child(nsDSig, "DigestMethod")
+	if digestMethod == nil {
+		return fmt.Errorf("%w: missing DigestMethod", ErrSignatureInvalid)
+	}
+	var digest []byte
+	canonical := canonicalize(el, inclusive, sig)
+	switch digestMethod.AttrValue("Algorithm") {
+	case algDigestSHA256:
+		sum := sha256.Sum256(canonical)
+		digest = sum[:]
+	case algDigestSHA512:
+		sum := sha512.Sum512(canonical)
+		digest = sum[:]
+	default:
+		return fmt.Errorf("%w: unsupported digest method", ErrSignatureInvalid)
+	}
+
+	digestValue := ref.Child(nsDSig, "DigestValue")
+	if digestValue == nil {
+		return fmt.Errorf("%w: missing DigestValue", ErrSignatureInvalid)
+	}
+	expectedDigest, err := decodeBase64(digestValue.Text())
+	if err != nil {
+		return fmt.Errorf("%w: invalid DigestValue encoding", ErrSignatureInvalid)
+	}
+	if subtle.ConstantTimeCompare(digest, expectedDigest) != 1 {
+		return fmt.Errorf("%w: digest mismatch", ErrSignatureInvalid)
+	}
+
+	sigValue := sig.Child(nsDSig, "SignatureValue")
+	if sigValue == nil {
+		return fmt.Errorf("%w: missing SignatureValue", ErrSignatureInvalid)
+	}
+	rawSig, err := decodeBase64(sigValue.Text())
+	if err != nil {
+		return fmt.Errorf("%w: invalid SignatureValue encoding", ErrSignatureInvalid)
+	}
+
+	h := hash.New()
+	h.Write(canonicalize(signedInfo, nil, nil))
+	if err := rsa.VerifyPKCS1v15(pub, hash, h.Sum(nil), rawSig); err != nil {
+		return fmt.Errorf("%w: %v", ErrSignatureInvalid, err)
+	}
+	return nil
+}
+
+// decodeBase64 tolera saltos de línea y espacios, comunes en XML firmado.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+	return base64.StdEncoding.DecodeString(s)
+}
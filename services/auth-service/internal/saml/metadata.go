@@ -0,0 +1,100 @@
+package saml
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+const (
+	nsMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+	nsAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+
+	bindingHTTPRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	bindingHTTPPost     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+
+	statusSuccess        = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	nameIDEmailAddress   = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	subjectConfirmBearer = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+)
+
+var ErrInvalidMetadata = errors.New("saml: invalid idp metadata")
+
+// IdPMetadata es lo que necesitamos del EntityDescriptor del IdP.
+type IdPMetadata struct {
+	EntityID     string
+	SSOURL       string
+	Certificates []*x509.Certificate
+}
+
+// ParseIdPMetadata extrae entityID, endpoint SSO (HTTP-Redirect) y los
+// certificados de firma del XML de metadata del IdP.
+func ParseIdPMetadata(data []byte) (IdPMetadata, error) {
+	root, err := parseXMLTree(data)
+	if err != nil {
+		return IdPMetadata{}, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+
+	entity := root
+	if root.Is(nsMetadata, "EntitiesDescriptor") {
+		entity = root.Child(nsMetadata, "EntityDescriptor")
+	}
+	if entity == nil || !entity.Is(nsMetadata, "EntityDescriptor") {
+		return IdPMetadata{}, fmt.Errorf("%w: EntityDescriptor not found", ErrInvalidMetadata)
+	}
+
+	md := IdPMetadata{EntityID: entity.AttrValue("entityID")}
+	if md.EntityID == "" {
+		return IdPMetadata{}, fmt.Errorf("%w: missing entityID", ErrInvalidMetadata)
+	}
+
+	idp := entity.Child(nsMetadata, "IDPSSODescriptor")
+	if idp == nil {
+		return IdPMetadata{}, fmt.Errorf("%w: IDPSSODescriptor not found", ErrInvalidMetadata)
+	}
+
+	for _, sso := range idp.ChildElements(nsMetadata, "SingleSignOnService") {
+		if sso.AttrValue("Binding") == bindingHTTPRedirect {
+			md.SSOURL = sso.AttrValue("Location")
+			break
+		}
+	}
+
+	for _, kd := range idp.ChildElements(nsMetadata, "KeyDescriptor") {
+		if use := kd.AttrValue("use"); use != "" && use != "signing" {
+			continue
+		}
+		keyInfo := kd.Child(nsDSig, "KeyInfo")
+		if keyInfo == nil {
+			continue
+		}
+		x509Data := keyInfo.Child(nsDSig, "X509Data")
+		if x509Data == nil {
+			continue
+		}
+		for _, c := range x509Data.ChildElements(nsDSig, "X509Certificate") {
+			der, err := decodeBase64(c.Text())
+			if err != nil {
+				return IdPMetadata{}, fmt.Errorf("%w: invalid certificate encoding", ErrInvalidMetadata)
+			}
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return IdPMetadata{}, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+			}
+			md.Certificates = append(md.Certificates, cert)
+		}
+	}
+
+	return md, nil
+}
+
+// ParseCertificatePEM parsea un certificado X.509 en formato PEM.
+func ParseCertificatePEM(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("saml: invalid PEM certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
@@ -0,0 +1,419 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidResponse = errors.New("saml: invalid response")
+	ErrReplayDetected  = errors.New("saml: assertion already used")
+	ErrUnsolicited     = errors.New("saml: unsolicited response not allowed for tenant")
+)
+
+const (
+	defaultClockSkew      = 2 * time.Minute
+	authnRequestLifetime  = 10 * time.Minute
+	maxSAMLResponseLength = 256 * 1024
+)
+
+// Assertion es el resultado de una respuesta SAML validada.
+type Assertion struct {
+	ID           string
+	Issuer       string
+	NameID       string
+	NameIDFormat string
+	Email        string
+	Name         string
+	Attributes   map[string][]string
+}
+
+// ServiceProvider implementa el lado SP de SAML 2.0 (Web Browser SSO) para
+// múltiples tenants. Los endpoints de cada tenant cuelgan de BaseURL:
+//
+//	{BaseURL}/{tenant}/metadata  (entityID del SP)
+//	{BaseURL}/{tenant}/acs       (Assertion Consumer Service, HTTP-POST)
+type ServiceProvider struct {
+	baseURL   string
+	tenants   map[string]*Tenant
+	requests  *expiringSet
+	seen      *expiringSet
+	clockSkew time.Duration
+	now       func() time.Time
+}
+
+func NewServiceProvider(baseURL string, tenants []*Tenant) *ServiceProvider {
+	byID := make(map[string]*Tenant, len(tenants))
+	for _, t := range tenants {
+		byID[t.ID] = t
+	}
+	return &ServiceProvider{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		tenants:   byID,
+		requests:  newExpiringSet(),
+		seen:      newExpiringSet(),
+		clockSkew: defaultClockSkew,
+		now:       time.Now,
+	}
+}
+
+func (sp *ServiceProvider) Tenant(tenantID string) (*Tenant, error) {
+	t, ok := sp.tenants[tenantID]
+	if !ok {
+		return nil, ErrUnknownTenant
+	}
+	return t, nil
+}
+
+func (sp *ServiceProvider) EntityID(tenantID string) string {
+	return sp.baseURL + "/" + url.PathEscape(tenantID) + "/metadata"
+}
+
+func (sp *ServiceProvider) ACSURL(tenantID string) string {
+	return sp.baseURL + "/" + url.PathEscape(tenantID) + "/acs"
+}
+
+// AuthnRequestURL arma la URL de redirect (binding HTTP-Redirect) hacia el IdP
+// para un login iniciado por el SP, y registra el ID para validar InResponseTo.
+func (sp *ServiceProvider) AuthnRequestURL(tenantID, relayState string) (string, error) {
+	t, err := sp.Tenant(tenantID)
+	if err != nil {
+		return "", err
+	}
+	if t.IdPSSOURL == "" {
+		return "", fmt.Errorf("saml: tenant %s has no HTTP-Redirect SSO endpoint", tenantID)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+	now := sp.now().UTC()
+
+	var doc bytes.Buffer
+	doc.WriteString(`<samlp:AuthnRequest xmlns:samlp="` + nsProtocol + `" xmlns:saml="` + nsAssertion + `"`)
+	writeXMLAttr(&doc, "ID", id)
+	writeXMLAttr(&doc, "Version", "2.0")
+	writeXMLAttr(&doc, "IssueInstant", now.Format(time.RFC3339))
+	writeXMLAttr(&doc, "Destination", t.IdPSSOURL)
+	writeXMLAttr(&doc, "AssertionConsumerServiceURL", sp.ACSURL(tenantID))
+	writeXMLAttr(&doc, "ProtocolBinding", bindingHTTPPost)
+	doc.WriteString(`><saml:Issuer>`)
+	_ = xml.EscapeText(&doc, []byte(sp.EntityID(tenantID)))
+	doc.WriteString(`</saml:Issuer><samlp:NameIDPolicy Format="` + nameIDEmailAddress + `" AllowCreate="true"/></samlp:AuthnRequest>`)
+
+	var deflated bytes.Buffer
+	fw, err := flate.NewWriter(&deflated, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := fw.Write(doc.Bytes()); err != nil {
+		return "", err
+	}
+	if err := fw.Close(); err != nil {
+		return "", err
+	}
+
+	target, err := url.Parse(t.IdPSSOURL)
+	if err != nil {
+		return "", fmt.Errorf("saml: invalid idp sso url: %w", err)
+	}
+	q := target.Query()
+	q.Set("SAMLRequest", base64.StdEncoding.EncodeToString(deflated.Bytes()))
+	if relayState != "" {
+		q.Set("RelayState", relayState)
+	}
+	target.RawQuery = q.Encode()
+
+	sp.requests.Add(requestKey(tenantID, id), now.Add(authnRequestLifetime), now)
+	return target.String(), nil
+}
+
+// Metadata devuelve el EntityDescriptor del SP para cargar en el IdP.
+func (sp *ServiceProvider) Metadata(tenantID string) ([]byte, error) {
+	if _, err := sp.Tenant(tenantID); err != nil {
+		return nil, err
+	}
+	var doc bytes.Buffer
+	doc.WriteString(`<md:EntityDescriptor xmlns:md="` + nsMetadata + `"`)
+	writeXMLAttr(&doc, "entityID", sp.EntityID(tenantID))
+	doc.WriteString(`><md:SPSSODescriptor AuthnRequestsSigned="false" WantAssertionsSigned="true" protocolSupportEnumeration="` + nsProtocol + `">`)
+	doc.WriteString(`<md:NameIDFormat>` + nameIDEmailAddress + `</md:NameIDFormat>`)
+	doc.WriteString(`<md:AssertionConsumerService Binding="` + bindingHTTPPost + `"`)
+	writeXMLAttr(&doc, "Location", sp.ACSURL(tenantID))
+	doc.WriteString(` index="0" isDefault="true"/></md:SPSSODescriptor></md:EntityDescriptor>`)
+	return doc.Bytes(), nil
+}
+
+// ParseResponse decodifica y valida el SAMLResponse recibido en el ACS:
+// firma (de la Response o de la Assertion) contra el certificado del tenant,
+// issuer, destino, audiencia, ventanas de tiempo, InResponseTo y replay.
+func (sp *ServiceProvider) ParseResponse(tenantID, samlResponse string) (*Assertion, error) {
+	t, err := sp.Tenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(samlResponse) > maxSAMLResponseLength {
+		return nil, fmt.Errorf("%w: response too large", ErrInvalidResponse)
+	}
+
+	raw, err := decodeBase64(samlResponse)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid base64", ErrInvalidResponse)
+	}
+	root, err := parseXMLTree(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if !root.Is(nsProtocol, "Response") {
+		return nil, fmt.Errorf("%w: root element is not a Response", ErrInvalidResponse)
+	}
+
+	// IDs duplicados permiten ataques de wrapping: se rechazan de plano.
+	ids := map[string]bool{}
+	duplicated := false
+	root.walk(func(el *xmlElement) {
+		if id, ok := el.Attr("ID"); ok {
+			if ids[id] {
+				duplicated = true
+			}
+			ids[id] = true
+		}
+	})
+	if duplicated {
+		return nil, fmt.Errorf("%w: duplicated ID attributes", ErrInvalidResponse)
+	}
+
+	if root.Child(nsAssertion, "EncryptedAssertion") != nil {
+		return nil, fmt.Errorf("%w: encrypted assertions are not supported", ErrInvalidResponse)
+	}
+	assertions := root.ChildElements(nsAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, fmt.Errorf("%w: expected exactly one assertion", ErrInvalidResponse)
+	}
+	assertion := assertions[0]
+
+	if err := sp.verifySignature(t, root, assertion); err != nil {
+		return nil, err
+	}
+
+	now := sp.now()
+	acsURL := sp.ACSURL(tenantID)
+
+	if status := root.Child(nsProtocol, "Status"); status == nil {
+		return nil, fmt.Errorf("%w: missing status", ErrInvalidResponse)
+	} else if code := status.Child(nsProtocol, "StatusCode"); code == nil || code.AttrValue("Value") != statusSuccess {
+		return nil, fmt.Errorf("%w: idp returned a non-success status", ErrInvalidResponse)
+	}
+
+	if dest, ok := root.Attr("Destination"); ok && dest != acsURL {
+		return nil, fmt.Errorf("%w: unexpected destination", ErrInvalidResponse)
+	}
+	if issuer := root.Child(nsAssertion, "Issuer"); issuer != nil && issuer.Text() != t.IdPEntityID {
+		return nil, fmt.Errorf("%w: unexpected response issuer", ErrInvalidResponse)
+	}
+	issuer := assertion.Child(nsAssertion, "Issuer")
+	if issuer == nil || issuer.Text() != t.IdPEntityID {
+		return nil, fmt.Errorf("%w: unexpected assertion issuer", ErrInvalidResponse)
+	}
+
+	if err := sp.validateConditions(assertion, tenantID, now); err != nil {
+		return nil, err
+	}
+
+	subject := assertion.Child(nsAssertion, "Subject")
+	if subject == nil {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidResponse)
+	}
+	confirmationData, err := sp.bearerConfirmation(subject, acsURL, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// InResponseTo: si viene, tiene que corresponder a un AuthnRequest nuestro
+	// pendiente; si no viene, es un login iniciado por el IdP.
+	inResponseTo := root.AttrValue("InResponseTo")
+	if v := confirmationData.AttrValue("InResponseTo"); v != "" {
+		if inResponseTo != "" && v != inResponseTo {
+			return nil, fmt.Errorf("%w: InResponseTo mismatch", ErrInvalidResponse)
+		}
+		inResponseTo = v
+	}
+	if inResponseTo == "" {
+		if !t.AllowIdPInitiated {
+			return nil, ErrUnsolicited
+		}
+	} else if !sp.requests.Consume(requestKey(tenantID, inResponseTo), now) {
+		return nil, fmt.Errorf("%w: unknown or expired InResponseTo", ErrInvalidResponse)
+	}
+
+	result := &Assertion{
+		ID:         assertion.AttrValue("ID"),
+		Issuer:     issuer.Text(),
+		Attributes: map[string][]string{},
+	}
+	if nameID := subject.Child(nsAssertion, "NameID"); nameID != nil {
+		result.NameID = nameID.Text()
+		result.NameIDFormat = nameID.AttrValue("Format")
+	}
+	if stmt := assertion.Child(nsAssertion, "AttributeStatement"); stmt != nil {
+		for _, attr := range stmt.ChildElements(nsAssertion, "Attribute") {
+			name := attr.AttrValue("Name")
+			for _, v := range attr.ChildElements(nsAssertion, "AttributeValue") {
+				result.Attributes[name] = append(result.Attributes[name], v.Text())
+			}
+		}
+	}
+
+	result.Email = firstAttr(result.Attributes, t.AttributeMapping.Email)
+	if result.Email == "" && (t.AttributeMapping.Email == "" || result.NameIDFormat == nameIDEmailAddress) {
+		result.Email = result.NameID
+	}
+	result.Name = firstAttr(result.Attributes, t.AttributeMapping.Name)
+	if result.Email == "" {
+		return nil, fmt.Errorf("%w: assertion carries no email", ErrInvalidResponse)
+	}
+
+	// Anti-replay: el ID de la assertion se recuerda hasta que deja de ser válida.
+	expiresAt := confirmationData.AttrValue("NotOnOrAfter")
+	until, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid NotOnOrAfter", ErrInvalidResponse)
+	}
+	if !sp.seen.Add(requestKey(tenantID, result.ID), until.Add(sp.clockSkew), now) {
+		return nil, ErrReplayDetected
+	}
+
+	return result, nil
+}
+
+// verifySignature acepta una firma válida sobre la Assertion o sobre la
+// Response completa (que incluye a la Assertion). Se prueba con cada
+// certificado del tenant para soportar rotaciones.
+func (sp *ServiceProvider) verifySignature(t *Tenant, response, assertion *xmlElement) error {
+	target := assertion
+	if assertion.Child(nsDSig, "Signature") == nil {
+		target = response
+	}
+
+	var lastErr error = ErrSignatureMissing
+	for _, cert := range t.Certificates {
+		err := verifyEnvelopedSignature(target, "ID", cert)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+func (sp *ServiceProvider) validateConditions(assertion *xmlElement, tenantID string, now time.Time) error {
+	conditions := assertion.Child(nsAssertion, "Conditions")
+	if conditions == nil {
+		return fmt.Errorf("%w: missing conditions", ErrInvalidResponse)
+	}
+
+	if v, ok := conditions.Attr("NotBefore"); ok {
+		notBefore, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("%w: invalid NotBefore", ErrInvalidResponse)
+		}
+		if now.Add(sp.clockSkew).Before(notBefore) {
+			return fmt.Errorf("%w: assertion not yet valid", ErrInvalidResponse)
+		}
+	}
+	if v, ok := conditions.Attr("NotOnOrAfter"); ok {
+		notOnOrAfter, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("%w: invalid NotOnOrAfter", ErrInvalidResponse)
+		}
+		if !now.Add(-sp.clockSkew).Before(notOnOrAfter) {
+			return fmt.Errorf("%w: assertion expired", ErrInvalidResponse)
+		}
+	}
+
+	// Cada AudienceRestriction debe incluir nuestro entityID.
+	restrictions := conditions.ChildElements(nsAssertion, "AudienceRestriction")
+	if len(restrictions) == 0 {
+		return fmt.Errorf("%w: missing audience restriction", ErrInvalidResponse)
+	}
+	entityID := sp.EntityID(tenantID)
+	for _, r := range restrictions {
+		found := false
+		for _, aud := range r.ChildElements(nsAssertion, "Audience") {
+			if aud.Text() == entityID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%w: audience mismatch", ErrInvalidResponse)
+		}
+	}
+	return nil
+}
+
+func (sp *ServiceProvider) bearerConfirmation(subject *xmlElement, acsURL string, now time.Time) (*xmlElement, error) {
+	for _, sc := range subject.ChildElements(nsAssertion, "SubjectConfirmation") {
+		if sc.AttrValue("Method") != subjectConfirmBearer {
+			continue
+		}
+		data := sc.Child(nsAssertion, "SubjectConfirmationData")
+		if data == nil {
+			continue
+		}
+		if data.AttrValue("Recipient") != acsURL {
+			continue
+		}
+		notOnOrAfter, err := time.Parse(time.RFC3339, data.AttrValue("NotOnOrAfter"))
+		if err != nil || !now.Add(-sp.clockSkew).Before(notOnOrAfter) {
+			continue
+		}
+		if v, ok := data.Attr("NotBefore"); ok {
+			notBefore, err := time.Parse(time.RFC3339, v)
+			if err != nil || now.Add(sp.clockSkew).Before(notBefore) {
+				continue
+			}
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%w: no valid bearer subject confirmation", ErrInvalidResponse)
+}
+
+func firstAttr(attrs map[string][]string, name string) string {
+	if name == "" {
+		return ""
+	}
+	if v := attrs[name]; len(v) > 0 {
+		return strings.TrimSpace(v[0])
+	}
+	return ""
+}
+
+func requestKey(tenantID, id string) string {
+	return tenantID + "|" + id
+}
+
+// newID genera un ID válido como xsd:ID (no puede empezar con dígito).
+func newID() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "_" + hex.EncodeToString(b), nil
+}
+
+func writeXMLAttr(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(" " + name + `="`)
+	buf.WriteString(escapeAttr(value))
+	buf.WriteByte('"')
+}
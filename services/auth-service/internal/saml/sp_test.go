@@ -0,0 +1,334 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIdPEntityID = "https://idp.example.com/metadata"
+	testBaseURL     = "https://sp.example.com/api/auth/sso"
+)
+
+type testIdP struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+}
+
+func newTestIdP(t *testing.T) testIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return testIdP{key: key, cert: cert}
+}
+
+func (idp testIdP) metadata() string {
+	return `<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="` + testIdPEntityID + `">
+  <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="signing">
+      <ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#">
+        <ds:X509Data><ds:X509Certificate>` + base64.StdEncoding.EncodeToString(idp.cert.Raw) + `</ds:X509Certificate></ds:X509Data>
+      </ds:KeyInfo>
+    </md:KeyDescriptor>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="https://idp.example.com/sso"/>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>`
+}
+
+// sign inserta una firma enveloped (exc-c14n + RSA-SHA256) como primer hijo
+// después del Issuer del elemento con el ID dado.
+func (idp testIdP) sign(t *testing.T, doc, id string) string {
+	t.Helper()
+	root, err := parseXMLTree([]byte(doc))
+	require.NoError(t, err)
+
+	var target *xmlElement
+	root.walk(func(el *xmlElement) {
+		if el.AttrValue("ID") == id {
+			target = el
+		}
+	})
+	require.NotNil(t, target)
+
+	digest := sha256.Sum256(canonicalize(target, nil, nil))
+	signedInfo := `<ds:SignedInfo>` +
+		`<ds:CanonicalizationMethod Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"></ds:CanonicalizationMethod>` +
+		`<ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"></ds:SignatureMethod>` +
+		`<ds:Reference URI="#` + id + `"><ds:Transforms>` +
+		`<ds:Transform Algorithm="http://www.w3.org/2000/09/xmldsig#enveloped-signature"></ds:Transform>` +
+		`<ds:Transform Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"></ds:Transform>` +
+		`</ds:Transforms><ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"></ds:DigestMethod>` +
+		`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest[:]) + `</ds:DigestValue></ds:Reference></ds:SignedInfo>`
+
+	sigDoc, err := parseXMLTree([]byte(`<ds:Signature xmlns:ds="http://www.w3.org/2000/09/xmldsig#">` + signedInfo + `</ds:Signature>`))
+	require.NoError(t, err)
+	hashed := sha256.Sum256(canonicalize(sigDoc.Child(nsDSig, "SignedInfo"), nil, nil))
+	sigValue, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, hashed[:])
+	require.NoError(t, err)
+
+	signature := `<ds:Signature xmlns:ds="http://www.w3.org/2000/09/xmldsig#">` + signedInfo +
+		`<ds:SignatureValue>` + base64.StdEncoding.EncodeToString(sigValue) + `</ds:SignatureValue></ds:Signature>`
+
+	// Insertar después del </saml:Issuer> del elemento firmado.
+	start := strings.Index(doc, `ID="`+id+`"`)
+	require.Greater(t, start, 0)
+	issuerEnd := strings.Index(doc[start:], "</saml:Issuer>") + start + len("</saml:Issuer>")
+	return doc[:issuerEnd] + signature + doc[issuerEnd:]
+}
+
+type responseOpts struct {
+	inResponseTo string
+	audience     string
+	notOnOrAfter time.Time
+	assertionID  string
+	email        string
+}
+
+func buildResponse(now time.Time, o responseOpts) string {
+	acs := testBaseURL + "/acme/acs"
+	if o.audience == "" {
+		o.audience = testBaseURL + "/acme/metadata"
+	}
+	if o.notOnOrAfter.IsZero() {
+		o.notOnOrAfter = now.Add(5 * time.Minute)
+	}
+	if o.assertionID == "" {
+		o.assertionID = "_assertion1"
+	}
+	if o.email == "" {
+		o.email = "alice@acme.com"
+	}
+	irt := ""
+	if o.inResponseTo != "" {
+		irt = ` InResponseTo="` + o.inResponseTo + `"`
+	}
+	ts := func(t time.Time) string { return t.UTC().Format(time.RFC3339) }
+
+	return `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_response1" Version="2.0" IssueInstant="` + ts(now) + `" Destination="` + acs + `"` + irt + `>` +
+		`<saml:Issuer>` + testIdPEntityID + `</saml:Issuer>` +
+		`<samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>` +
+		`<saml:Assertion xmlns:xs="http://www.w3.org/2001/XMLSchema" ID="` + o.assertionID + `" Version="2.0" IssueInstant="` + ts(now) + `">` +
+		`<saml:Issuer>` + testIdPEntityID + `</saml:Issuer>` +
+		`<saml:Subject><saml:NameID Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress">` + o.email + `</saml:NameID>` +
+		`<saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">` +
+		`<saml:SubjectConfirmationData NotOnOrAfter="` + ts(o.notOnOrAfter) + `" Recipient="` + acs + `"` + irt + `/>` +
+		`</saml:SubjectConfirmation></saml:Subject>` +
+		`<saml:Conditions NotBefore="` + ts(now.Add(-time.Minute)) + `" NotOnOrAfter="` + ts(o.notOnOrAfter) + `">` +
+		`<saml:AudienceRestriction><saml:Audience>` + o.audience + `</saml:Audience></saml:AudienceRestriction>` +
+		`</saml:Conditions>` +
+		`<saml:AttributeStatement><saml:Attribute Name="displayName"><saml:AttributeValue>Alice &amp; Co</saml:AttributeValue></saml:Attribute></saml:AttributeStatement>` +
+		`</saml:Assertion></samlp:Response>`
+}
+
+func newTestSP(t *testing.T, idp testIdP, allowIdPInitiated bool) *ServiceProvider {
+	t.Helper()
+	tenant, err := NewTenant(TenantConfig{
+		ID:                "acme",
+		IdPMetadataXML:    idp.metadata(),
+		AttributeMapping:  AttributeMapping{Name: "displayName"},
+		AllowIdPInitiated: allowIdPInitiated,
+		AllowedDomains:    []string{"acme.com"},
+	})
+	require.NoError(t, err)
+	return NewServiceProvider(testBaseURL, []*Tenant{tenant})
+}
+
+func encode(doc string) string {
+	return base64.StdEncoding.EncodeToString([]byte(doc))
+}
+
+func TestParseIdPMetadata(t *testing.T) {
+	idp := newTestIdP(t)
+	md, err := ParseIdPMetadata([]byte(idp.metadata()))
+	require.NoError(t, err)
+	require.Equal(t, testIdPEntityID, md.EntityID)
+	require.Equal(t, "https://idp.example.com/sso", md.SSOURL)
+	require.Len(t, md.Certificates, 1)
+}
+
+func TestParseResponse_IdPInitiated(t *testing.T) {
+	idp := newTestIdP(t)
+	sp := newTestSP(t, idp, true)
+	now := time.Now()
+
+	doc := idp.sign(t, buildResponse(now, responseOpts{}), "_assertion1")
+	a, err := sp.ParseResponse("acme", encode(doc))
+	require.NoError(t, err)
+	require.Equal(t, "alice@acme.com", a.Email)
+	require.Equal(t, "Alice & Co", a.Name)
+	require.Equal(t, "_assertion1", a.ID)
+}
+
+func TestParseResponse_SignedResponse(t *testing.T) {
+	idp := newTestIdP(t)
+	sp := newTestSP(t, idp, true)
+
+	doc := idp.sign(t, buildResponse(time.Now(), responseOpts{}), "_response1")
+	_, err := sp.ParseResponse("acme", encode(doc))
+	require.NoError(t, err)
+}
+
+func TestParseResponse_SPInitiated(t *testing.T) {
+	idp := newTestIdP(t)
+	sp := newTestSP(t, idp, false)
+
+	redirect, err := sp.AuthnRequestURL("acme", "/dashboard")
+	require.NoError(t, err)
+	u, err := url.Parse(redirect)
+	require.NoError(t, err)
+	require.Equal(t, "/dashboard", u.Query().Get("RelayState"))
+
+	deflated, err := base64.StdEncoding.DecodeString(u.Query().Get("SAMLRequest"))
+	require.NoError(t, err)
+	raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	require.NoError(t, err)
+	req, err := parseXMLTree(raw)
+	require.NoError(t, err)
+	requestID := req.AttrValue("ID")
+	require.NotEmpty(t, requestID)
+	require.Equal(t, testBaseURL+"/acme/acs", req.AttrValue("AssertionConsumerServiceURL"))
+
+	doc := idp.sign(t, buildResponse(time.Now(), responseOpts{inResponseTo: requestID}), "_assertion1")
+	_, err = sp.ParseResponse("acme", encode(doc))
+	require.NoError(t, err)
+
+	// El mismo AuthnRequest no puede responderse dos veces.
+	doc = idp.sign(t, buildResponse(time.Now(), responseOpts{inResponseTo: requestID, assertionID: "_assertion2"}), "_assertion2")
+	_, err = sp.ParseResponse("acme", encode(doc))
+	require.ErrorIs(t, err, ErrInvalidResponse)
+}
+
+func TestParseResponse_Rejections(t *testing.T) {
+	idp := newTestIdP(t)
+	other := newTestIdP(t)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		doc     func() string
+		allow   bool
+		wantErr error
+	}{
+		{
+			name:    "unsigned",
+			doc:     func() string { return buildResponse(now, responseOpts{}) },
+			allow:   true,
+			wantErr: ErrSignatureMissing,
+		},
+		{
+			name:    "signed by another key",
+			doc:     func() string { return other.sign(t, buildResponse(now, responseOpts{}), "_assertion1") },
+			allow:   true,
+			wantErr: ErrSignatureInvalid,
+		},
+		{
+			name: "tampered after signing",
+			doc: func() string {
+				return strings.Replace(idp.sign(t, buildResponse(now, responseOpts{}), "_assertion1"), "alice@acme.com", "mallory@acme.com", 1)
+			},
+			allow:   true,
+			wantErr: ErrSignatureInvalid,
+		},
+		{
+			name: "wrong audience",
+			doc: func() string {
+				return idp.sign(t, buildResponse(now, responseOpts{audience: "https://other.example.com"}), "_assertion1")
+			},
+			allow:   true,
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name:    "expired",
+			doc:     func() string { return idp.sign(t, buildResponse(now.Add(-time.Hour), responseOpts{}), "_assertion1") },
+			allow:   true,
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name:    "unsolicited when not allowed",
+			doc:     func() string { return idp.sign(t, buildResponse(now, responseOpts{}), "_assertion1") },
+			allow:   false,
+			wantErr: ErrUnsolicited,
+		},
+		{
+			name: "unknown InResponseTo",
+			doc: func() string {
+				return idp.sign(t, buildResponse(now, responseOpts{inResponseTo: "_unknown"}), "_assertion1")
+			},
+			allow:   true,
+			wantErr: ErrInvalidResponse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp := newTestSP(t, idp, tt.allow)
+			_, err := sp.ParseResponse("acme", encode(tt.doc()))
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestParseResponse_Replay(t *testing.T) {
+	idp := newTestIdP(t)
+	sp := newTestSP(t, idp, true)
+
+	doc := encode(idp.sign(t, buildResponse(time.Now(), responseOpts{}), "_assertion1"))
+	_, err := sp.ParseResponse("acme", doc)
+	require.NoError(t, err)
+
+	_, err = sp.ParseResponse("acme", doc)
+	require.ErrorIs(t, err, ErrReplayDetected)
+}
+
+func TestParseResponse_UnknownTenant(t *testing.T) {
+	sp := NewServiceProvider(testBaseURL, nil)
+	_, err := sp.ParseResponse("nope", "")
+	require.ErrorIs(t, err, ErrUnknownTenant)
+}
+
+func TestTenant_AllowedDomains(t *testing.T) {
+	idp := newTestIdP(t)
+	_, err := NewTenant(TenantConfig{ID: "acme", IdPMetadataXML: idp.metadata()})
+	require.ErrorContains(t, err, "allowed_domains is required")
+
+	tenant, err := NewTenant(TenantConfig{ID: "acme", IdPMetadataXML: idp.metadata(), AllowedDomains: []string{" @Acme.com "}})
+	require.NoError(t, err)
+	require.True(t, tenant.AllowsEmail("alice@ACME.com"))
+	require.False(t, tenant.AllowsEmail("admin@platform.com"))
+	require.False(t, tenant.AllowsEmail("alice@eu.acme.com"))
+	require.False(t, tenant.AllowsEmail("acme.com"))
+}
+
+func TestCanonicalize_ExclusiveNamespaces(t *testing.T) {
+	root, err := parseXMLTree([]byte(`<a:root xmlns:a="urn:a" xmlns:b="urn:b" xmlns:c="urn:c"><b:child c:attr="1" z="2" y="&lt;&quot;">x &amp; y</b:child><empty/></a:root>`))
+	require.NoError(t, err)
+
+	child := root.Child("urn:b", "child")
+	require.Equal(t, `<b:child xmlns:b="urn:b" xmlns:c="urn:c" y="&lt;&quot;" z="2" c:attr="1">x &amp; y</b:child>`, string(canonicalize(child, nil, nil)))
+	require.Equal(t, `<a:root xmlns:a="urn:a"><b:child xmlns:b="urn:b" xmlns:c="urn:c" y="&lt;&quot;" z="2" c:attr="1">x &amp; y</b:child><empty></empty></a:root>`, string(canonicalize(root, nil, nil)))
+}
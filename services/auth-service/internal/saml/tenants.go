@@ -0,0 +1,147 @@
+package saml
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var ErrUnknownTenant = errors.New("saml: unknown tenant")
+
+// AttributeMapping indica qué atributos SAML del IdP se usan para cada campo.
+// Si Email está vacío se usa el NameID (cuando su formato es emailAddress).
+type AttributeMapping struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+// TenantConfig es la configuración SSO de un tenant tal como se declara en el
+// archivo SAML_TENANTS_FILE.
+type TenantConfig struct {
+	ID                string           `json:"id"`
+	IdPMetadataXML    string           `json:"idp_metadata_xml"`
+	IdPMetadataFile   string           `json:"idp_metadata_file"`
+	IdPCertificatePEM string           `json:"idp_certificate"`
+	AttributeMapping  AttributeMapping `json:"attribute_mapping"`
+	AllowIdPInitiated bool             `json:"allow_idp_initiated"`
+	// AllowedDomains son los dominios de email que el IdP del tenant puede
+	// afirmar (ej: ["acme.com"]); es obligatorio.
+	AllowedDomains []string `json:"allowed_domains"`
+}
+
+// Tenant es la configuración ya parseada y validada.
+type Tenant struct {
+	ID                string
+	IdPEntityID       string
+	IdPSSOURL         string
+	Certificates      []*x509.Certificate
+	AttributeMapping  AttributeMapping
+	AllowIdPInitiated bool
+	AllowedDomains    []string // en minúsculas
+}
+
+// AllowsEmail indica si el dominio de email es uno de los del tenant. Los
+// subdominios no se aceptan salvo que estén declarados.
+func (t *Tenant) AllowsEmail(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+	for _, d := range t.AllowedDomains {
+		if d == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// NewTenant parsea la metadata del IdP y el certificado opcional.
+// Si se configura idp_certificate, reemplaza a los certificados de la metadata.
+func NewTenant(cfg TenantConfig) (*Tenant, error) {
+	if strings.TrimSpace(cfg.ID) == "" {
+		return nil, errors.New("saml: tenant id is required")
+	}
+
+	domains := make([]string, 0, len(cfg.AllowedDomains))
+	for _, d := range cfg.AllowedDomains {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "@"))
+		if d == "" || strings.Contains(d, "@") {
+			return nil, fmt.Errorf("saml: tenant %s: invalid allowed domain %q", cfg.ID, d)
+		}
+		domains = append(domains, d)
+	}
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("saml: tenant %s: allowed_domains is required", cfg.ID)
+	}
+
+	metadataXML := []byte(cfg.IdPMetadataXML)
+	if len(metadataXML) == 0 && cfg.IdPMetadataFile != "" {
+		data, err := os.ReadFile(cfg.IdPMetadataFile)
+		if err != nil {
+			return nil, fmt.Errorf("saml: tenant %s: read metadata: %w", cfg.ID, err)
+		}
+		metadataXML = data
+	}
+	if len(metadataXML) == 0 {
+		return nil, fmt.Errorf("saml: tenant %s: idp metadata is required", cfg.ID)
+	}
+
+	md, err := ParseIdPMetadata(metadataXML)
+	if err != nil {
+		return nil, fmt.Errorf("tenant %s: %w", cfg.ID, err)
+	}
+
+	certs := md.Certificates
+	if cfg.IdPCertificatePEM != "" {
+		cert, err := ParseCertificatePEM([]byte(cfg.IdPCertificatePEM))
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", cfg.ID, err)
+		}
+		certs = []*x509.Certificate{cert}
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("saml: tenant %s: no signing certificate configured", cfg.ID)
+	}
+
+	return &Tenant{
+		ID:                cfg.ID,
+		IdPEntityID:       md.EntityID,
+		IdPSSOURL:         md.SSOURL,
+		Certificates:      certs,
+		AttributeMapping:  cfg.AttributeMapping,
+		AllowIdPInitiated: cfg.AllowIdPInitiated,
+		AllowedDomains:    domains,
+	}, nil
+}
+
+// LoadTenants lee un archivo JSON con un array de TenantConfig.
+// Un path vacío devuelve una lista vacía (SSO deshabilitado).
+func LoadTenants(path string) ([]*Tenant, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("saml: read tenants file: %w", err)
+	}
+
+	var configs []TenantConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("saml: parse tenants file: %w", err)
+	}
+
+	tenants := make([]*Tenant, 0, len(configs))
+	for _, cfg := range configs {
+		t, err := NewTenant(cfg)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, t)
+	}
+	return tenants, nil
+}
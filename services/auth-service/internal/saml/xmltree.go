@@ -0,0 +1,299 @@
+package saml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Árbol XML mínimo que preserva prefijos y declaraciones de namespace tal cual
+// vienen en el documento. encoding/xml los reescribe al decodificar, y para
+// verificar firmas XML-DSig necesitamos canonicalizar el documento original.
+
+type xmlAttr struct {
+	Prefix string
+	Local  string
+	Value  string
+}
+
+type nsDecl struct {
+	Prefix string // "" = namespace por defecto
+	URI    string
+}
+
+type xmlElement struct {
+	Prefix   string
+	Local    string
+	NSDecls  []nsDecl
+	Attrs    []xmlAttr
+	Children []interface{} // *xmlElement | string (texto)
+	Parent   *xmlElement
+}
+
+var errMalformedXML = errors.New("malformed xml")
+
+// parseXMLTree parsea data y devuelve el elemento raíz.
+// Rechaza DTDs para evitar ataques de expansión de entidades.
+func parseXMLTree(data []byte) (*xmlElement, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = true
+
+	var root, current *xmlElement
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errMalformedXML, err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &xmlElement{Prefix: t.Name.Space, Local: t.Name.Local, Parent: current}
+			for _, a := range t.Attr {
+				switch {
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					el.NSDecls = append(el.NSDecls, nsDecl{Prefix: "", URI: a.Value})
+				case a.Name.Space == "xmlns":
+					el.NSDecls = append(el.NSDecls, nsDecl{Prefix: a.Name.Local, URI: a.Value})
+				default:
+					el.Attrs = append(el.Attrs, xmlAttr{Prefix: a.Name.Space, Local: a.Name.Local, Value: a.Value})
+				}
+			}
+			if current == nil {
+				if root != nil {
+					return nil, fmt.Errorf("%w: multiple root elements", errMalformedXML)
+				}
+				root = el
+			} else {
+				current.Children = append(current.Children, el)
+			}
+			current = el
+		case xml.EndElement:
+			if current == nil || current.Prefix != t.Name.Space || current.Local != t.Name.Local {
+				return nil, fmt.Errorf("%w: unexpected end element %s", errMalformedXML, t.Name.Local)
+			}
+			current = current.Parent
+		case xml.CharData:
+			if current != nil {
+				current.Children = append(current.Children, string(t))
+			}
+		case xml.Directive:
+			return nil, fmt.Errorf("%w: DTD/directives are not allowed", errMalformedXML)
+		}
+	}
+
+	if root == nil || current != nil {
+		return nil, fmt.Errorf("%w: incomplete document", errMalformedXML)
+	}
+	return root, nil
+}
+
+// lookupNS resuelve el URI de un prefijo en el scope del elemento.
+func (e *xmlElement) lookupNS(prefix string) (string, bool) {
+	switch prefix {
+	case "xml":
+		return "http://www.w3.org/XML/1998/namespace", true
+	case "xmlns":
+		return "http://www.w3.org/2000/xmlns/", true
+	}
+	for el := e; el != nil; el = el.Parent {
+		for _, d := range el.NSDecls {
+			if d.Prefix == prefix {
+				return d.URI, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Namespace devuelve el URI del namespace del elemento.
+func (e *xmlElement) Namespace() string {
+	uri, _ := e.lookupNS(e.Prefix)
+	return uri
+}
+
+// Is indica si el elemento tiene el namespace y nombre local dados.
+func (e *xmlElement) Is(ns, local string) bool {
+	return e.Local == local && e.Namespace() == ns
+}
+
+func (e *xmlElement) Attr(local string) (string, bool) {
+	for _, a := range e.Attrs {
+		if a.Prefix == "" && a.Local == local {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+func (e *xmlElement) AttrValue(local string) string {
+	v, _ := e.Attr(local)
+	return v
+}
+
+// ChildElements devuelve los hijos que son elementos con ns/local dados.
+func (e *xmlElement) ChildElements(ns, local string) []*xmlElement {
+	var out []*xmlElement
+	for _, c := range e.Children {
+		if el, ok := c.(*xmlElement); ok && el.Is(ns, local) {
+			out = append(out, el)
+		}
+	}
+	return out
+}
+
+// Child devuelve el primer hijo con ns/local dados (o nil).
+func (e *xmlElement) Child(ns, local string) *xmlElement {
+	for _, c := range e.Children {
+		if el, ok := c.(*xmlElement); ok && el.Is(ns, local) {
+			return el
+		}
+	}
+	return nil
+}
+
+// Text concatena el texto de los hijos directos.
+func (e *xmlElement) Text() string {
+	var sb strings.Builder
+	for _, c := range e.Children {
+		if s, ok := c.(string); ok {
+			sb.WriteString(s)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// walk recorre el subárbol en pre-orden.
+func (e *xmlElement) walk(fn func(*xmlElement)) {
+	fn(e)
+	for _, c := range e.Children {
+		if el, ok := c.(*xmlElement); ok {
+			el.walk(fn)
+		}
+	}
+}
+
+// qname arma el nombre calificado tal como aparece en el documento.
+func qname(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+// canonicalize serializa el subárbol de e según Exclusive XML Canonicalization
+// 1.0 sin comentarios (http://www.w3.org/2001/10/xml-exc-c14n#).
+// inclusivePrefixes corresponde al PrefixList de InclusiveNamespaces y exclude
+// (opcional) se omite de la salida (transformación enveloped-signature).
+func canonicalize(e *xmlElement, inclusivePrefixes []string, exclude *xmlElement) []byte {
+	var buf bytes.Buffer
+	writeCanonical(&buf, e, map[string]string{}, inclusivePrefixes, exclude)
+	return buf.Bytes()
+}
+
+func writeCanonical(buf *bytes.Buffer, e *xmlElement, rendered map[string]string, inclusive []string, exclude *xmlElement) {
+	if e == exclude {
+		return
+	}
+
+	// Prefijos visiblemente utilizados por el elemento y sus atributos.
+	utilized := map[string]bool{e.Prefix: true}
+	for _, a := range e.Attrs {
+		if a.Prefix != "" && a.Prefix != "xml" {
+			utilized[a.Prefix] = true
+		}
+	}
+	for _, p := range inclusive {
+		if p == "#default" {
+			p = ""
+		}
+		if _, ok := e.lookupNS(p); ok {
+			utilized[p] = true
+		}
+	}
+
+	scope := make(map[string]string, len(rendered)+len(utilized))
+	for k, v := range rendered {
+		scope[k] = v
+	}
+
+	var decls []nsDecl
+	for p := range utilized {
+		uri, _ := e.lookupNS(p)
+		prev, wasRendered := rendered[p]
+		if p == "" && uri == "" {
+			// xmlns="" solo si un ancestro de la salida declaró un default no vacío.
+			if wasRendered && prev != "" {
+				decls = append(decls, nsDecl{Prefix: "", URI: ""})
+				scope[""] = ""
+			}
+			continue
+		}
+		if !wasRendered || prev != uri {
+			decls = append(decls, nsDecl{Prefix: p, URI: uri})
+			scope[p] = uri
+		}
+	}
+	sort.Slice(decls, func(i, j int) bool { return decls[i].Prefix < decls[j].Prefix })
+
+	attrs := make([]xmlAttr, len(e.Attrs))
+	copy(attrs, e.Attrs)
+	attrNS := func(a xmlAttr) string {
+		if a.Prefix == "" {
+			return ""
+		}
+		uri, _ := e.lookupNS(a.Prefix)
+		return uri
+	}
+	sort.SliceStable(attrs, func(i, j int) bool {
+		ni, nj := attrNS(attrs[i]), attrNS(attrs[j])
+		if ni != nj {
+			return ni < nj
+		}
+		return attrs[i].Local < attrs[j].Local
+	})
+
+	name := qname(e.Prefix, e.Local)
+	buf.WriteByte('<')
+	buf.WriteString(name)
+	for _, d := range decls {
+		if d.Prefix == "" {
+			buf.WriteString(` xmlns="`)
+		} else {
+			buf.WriteString(` xmlns:` + d.Prefix + `="`)
+		}
+		buf.WriteString(escapeAttr(d.URI))
+		buf.WriteByte('"')
+	}
+	for _, a := range attrs {
+		buf.WriteString(" " + qname(a.Prefix, a.Local) + `="`)
+		buf.WriteString(escapeAttr(a.Value))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('>')
+
+	for _, c := range e.Children {
+		switch v := c.(type) {
+		case string:
+			buf.WriteString(escapeText(v))
+		case *xmlElement:
+			writeCanonical(buf, v, scope, inclusive, exclude)
+		}
+	}
+
+	buf.WriteString("</" + name + ">")
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func escapeText(s string) string { return textEscaper.Replace(s) }
+func escapeAttr(s string) string { return attrEscaper.Replace(s) }
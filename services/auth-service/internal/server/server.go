@@ -2,11 +2,18 @@ package server
 
 import (
 	"context"
+	"log"
 	"net/http"
+	"saas-subscription-platform/libs/notify"
 	"saas-subscription-platform/services/auth-service/internal/client"
 	"saas-subscription-platform/services/auth-service/internal/config"
+	"saas-subscription-platform/services/auth-service/internal/db"
+	"saas-subscription-platform/services/auth-service/internal/geoip"
 	"saas-subscription-platform/services/auth-service/internal/handler"
 	"saas-subscription-platform/services/auth-service/internal/middleware"
+	"saas-subscription-platform/services/auth-service/internal/password"
+	"saas-subscription-platform/services/auth-service/internal/repository"
+	"saas-subscription-platform/services/auth-service/internal/saml"
 	"saas-subscription-platform/services/auth-service/internal/service"
 	"time"
 )
@@ -17,9 +24,68 @@ type Server struct {
 
 func New(cfg config.Config) *Server {
 	userClient := client.NewUserClient(cfg.UserServiceURL)
-	authSvc := service.NewAuthService(cfg.JWTSecret, userClient)
+
+	var sender notify.Sender = notify.LogSender{}
+	if cfg.SMTPAddr != "" {
+		sender = notify.NewSMTPSender(cfg.SMTPAddr, cfg.SMTPFrom, cfg.SMTPUsername, cfg.SMTPPassword)
+	}
+
+	var securitySvc *service.SecurityService
+	if cfg.DBDSN != "" {
+		pool, err := db.New(cfg.DBDSN)
+		if err != nil {
+			log.Fatalf("db connection failed: %v", err)
+		}
+		if cfg.MigrateOnBoot {
+			if err := db.Migrate(context.Background(), pool); err != nil {
+				log.Fatalf("migrations failed: %v", err)
+			}
+		}
+		geo, err := geoip.Open(cfg.GeoIPDBPath)
+		if err != nil {
+			log.Fatalf("geoip database load failed: %v", err)
+		}
+		securitySvc = service.NewSecurityService(repository.NewSecurityRepository(pool), geo, sender)
+	} else {
+		log.Println("AUTH_DB_DSN not set: sessions and login anomaly detection disabled")
+	}
+
+	passwordPolicy := password.DefaultPolicy()
+	passwordPolicy.MinLength = cfg.PasswordMinLength
+	var breached password.MultiChecker
+	if cfg.PasswordBreachedList != "" {
+		list, err := password.LoadList(cfg.PasswordBreachedList)
+		if err != nil {
+			log.Fatalf("breached password list load failed: %v", err)
+		}
+		breached = append(breached, list)
+	}
+	if cfg.PasswordBreachedRangeDir != "" {
+		ranges, err := password.NewRangeDirChecker(cfg.PasswordBreachedRangeDir)
+		if err != nil {
+			log.Fatalf("breached password ranges load failed: %v", err)
+		}
+		breached = append(breached, ranges)
+	}
+	if len(breached) > 0 {
+		passwordPolicy.Breached = breached
+	}
+
+	authSvc := service.NewAuthService(cfg.JWTSecret, userClient,
+		service.WithSecurity(securitySvc),
+		service.WithPasswordPolicy(passwordPolicy),
+		service.WithSender(sender),
+		service.WithEmailConfirmURL(cfg.EmailConfirmURL),
+		service.WithPasswordResetURL(cfg.PasswordResetURL),
+	)
 	authHandler := handler.NewAuthHandler(authSvc)
 
+	tenants, err := saml.LoadTenants(cfg.SAMLTenantsFile)
+	if err != nil {
+		log.Fatalf("saml tenants config failed: %v", err)
+	}
+	ssoHandler := handler.NewSSOHandler(saml.NewServiceProvider(cfg.SAMLSPBaseURL, tenants), authSvc)
+
 	internalAuthMiddleware := middleware.InternalAuth
 	requestLogger := middleware.RequestLogger("auth-service")
 
@@ -28,8 +94,39 @@ func New(cfg config.Config) *Server {
 	mux.HandleFunc("POST /register", authHandler.Register)
 	mux.HandleFunc("POST /login", authHandler.Login)
 
+	// SSO SAML por tenant (públicos: el IdP postea directo al ACS)
+	mux.HandleFunc("GET /sso/{tenant}/login", ssoHandler.Login)
+	mux.HandleFunc("POST /sso/{tenant}/acs", ssoHandler.ACS)
+	mux.HandleFunc("GET /sso/{tenant}/metadata", ssoHandler.Metadata)
+
 	// Protected route - ahora usa internal auth en lugar de JWT
 	mux.Handle("GET /me", internalAuthMiddleware(http.HandlerFunc(handler.Me(userClient))))
+	mux.Handle("POST /impersonate", internalAuthMiddleware(http.HandlerFunc(authHandler.Impersonate)))
+	mux.Handle("POST /switch-org", internalAuthMiddleware(http.HandlerFunc(authHandler.SwitchOrg)))
+	mux.Handle("POST /password/change", internalAuthMiddleware(http.HandlerFunc(authHandler.ChangePassword)))
+	mux.Handle("POST /email/change", internalAuthMiddleware(http.HandlerFunc(authHandler.RequestEmailChange)))
+
+	// Público: el token firmado del link es la credencial
+	mux.HandleFunc("GET /email/confirm", authHandler.ConfirmEmailChange)
+	mux.HandleFunc("POST /email/confirm", authHandler.ConfirmEmailChange)
+	mux.HandleFunc("POST /password/reset/request", authHandler.RequestPasswordReset)
+	mux.HandleFunc("POST /password/reset", authHandler.ResetPassword)
+
+	if securitySvc != nil {
+		securityHandler := handler.NewSecurityHandler(securitySvc)
+		mux.Handle("POST /security/events/{id}/confirm", internalAuthMiddleware(http.HandlerFunc(securityHandler.ConfirmEvent)))
+		mux.Handle("POST /security/events/{id}/deny", internalAuthMiddleware(http.HandlerFunc(securityHandler.DenyEvent)))
+		// Interno: lo consulta el gateway, no se expone vía /api/auth
+		mux.Handle("GET /internal/sessions/{id}", internalAuthMiddleware(http.HandlerFunc(securityHandler.Session)))
+	}
+
+	// Interno: export y borrado GDPR, solo los orquesta user-service
+	privacyHandler := handler.NewPrivacyHandler(securitySvc)
+	fromUserService := func(h http.HandlerFunc) http.Handler {
+		return internalAuthMiddleware(middleware.RequireCaller("user-service")(h))
+	}
+	mux.Handle("POST /internal/users/{id}/export", fromUserService(privacyHandler.ExportUser))
+	mux.Handle("POST /internal/users/{id}/erase", fromUserService(privacyHandler.EraseUser))
 
 	// Loguear el request completo (start/end) alrededor de todo el mux
 	h := requestLogger(mux)
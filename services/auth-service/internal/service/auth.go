@@ -2,9 +2,15 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"log"
+	"saas-subscription-platform/libs/emailaddr"
+	"saas-subscription-platform/libs/notify"
+	"saas-subscription-platform/libs/trace"
 	"saas-subscription-platform/services/auth-service/internal/client"
+	"saas-subscription-platform/services/auth-service/internal/password"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -14,50 +20,145 @@ import (
 // UserClient define las operaciones del cliente de usuarios que la capa de servicio necesita.
 type UserClient interface {
 	CreateUserWithContext(ctx context.Context, email, password string, headers map[string]string) (client.CreateUserResponse, error)
+	CreateSSOUserWithContext(ctx context.Context, email, password, tenantID string, headers map[string]string) (client.CreateUserResponse, error)
 	GetUserByEmailWithContext(ctx context.Context, email string, headers map[string]string) (client.GetUserByEmailResponse, error)
+	GetUserByIDWithContext(ctx context.Context, userID string, headers map[string]string) (client.GetUserByEmailResponse, error)
+	UpdatePasswordWithContext(ctx context.Context, userID, passwordHash string, headers map[string]string) error
+	ReplaceEmailWithContext(ctx context.Context, userID, currentEmail, newEmail string, headers map[string]string) error
+	GetMembershipWithContext(ctx context.Context, orgID, userID string, headers map[string]string) (client.MembershipResponse, error)
+	LookupInvitationWithContext(ctx context.Context, token string, headers map[string]string) (client.InvitationResponse, error)
+	AcceptInvitationWithContext(ctx context.Context, token, userID string, headers map[string]string) (client.MembershipResponse, error)
 }
 
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUserExists         = client.ErrUserExists
+	ErrInvalidSSOIdentity = errors.New("invalid sso identity")
+	ErrForbidden          = errors.New("forbidden")
+	ErrUserNotFound       = client.ErrUserNotFound
+	ErrReasonRequired     = errors.New("reason is required")
+	// ErrAccountInactive: la cuenta está suspendida, desactivada o en borrado.
+	ErrAccountInactive = errors.New("account is not active")
+	// ErrSSOLinkRefused: el email es de una cuenta que el tenant no puede usar
+	// (un admin, una registrada con password o una de otro tenant).
+	ErrSSOLinkRefused = errors.New("account cannot be linked to this sso tenant")
+)
+
+const (
+	roleAdmin    = "admin"
+	statusActive = "active"
+
+	accessTokenTTL        = 15 * time.Minute
+	impersonationTokenTTL = 10 * time.Minute
 )
 
 type AuthService struct {
 	jwtSecret  []byte
 	userClient UserClient
+	security   *SecurityService
+	passwords  password.Policy
+	sender     notify.Sender
+	// emailConfirmURL es la página (frontend) a la que apunta el link de
+	// confirmación de cambio de email; recibe ?token=...
+	emailConfirmURL string
+	// passwordResetURL es la página (frontend) donde se elige la password
+	// nueva; recibe ?token=...
+	passwordResetURL string
+}
+
+// Option configura dependencias opcionales del AuthService.
+type Option func(*AuthService)
+
+// WithSecurity activa sesiones revocables (claim "sid") y la detección de
+// logins anómalos. Sin él los tokens son stateless, como antes.
+func WithSecurity(security *SecurityService) Option {
+	return func(s *AuthService) {
+		s.security = security
+	}
+}
+
+// WithPasswordPolicy reemplaza la política de passwords por defecto.
+func WithPasswordPolicy(policy password.Policy) Option {
+	return func(s *AuthService) {
+		s.passwords = policy
+	}
+}
+
+// WithSender define el canal de notificaciones al usuario (default: log).
+func WithSender(sender notify.Sender) Option {
+	return func(s *AuthService) {
+		s.sender = sender
+	}
 }
 
-func NewAuthService(secret string, userClient UserClient) *AuthService {
-	return &AuthService{
-		jwtSecret:  []byte(secret),
-		userClient: userClient,
+// WithEmailConfirmURL define la URL base del link de confirmación de cambio de email.
+func WithEmailConfirmURL(u string) Option {
+	return func(s *AuthService) {
+		s.emailConfirmURL = u
 	}
 }
 
+// WithPasswordResetURL define la URL base del link de reset de password.
+func WithPasswordResetURL(u string) Option {
+	return func(s *AuthService) {
+		s.passwordResetURL = u
+	}
+}
+
+func NewAuthService(secret string, userClient UserClient, opts ...Option) *AuthService {
+	s := &AuthService{
+		jwtSecret:       []byte(secret),
+		userClient:      userClient,
+		passwords:       password.DefaultPolicy(),
+		sender:          notify.LogSender{},
+		emailConfirmURL: "http://localhost:8080/api/auth/email/confirm",
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
 // Register mantiene compatibilidad, pero usa context.Background().
 func (s *AuthService) Register(email, password string) error {
 	return s.RegisterWithContext(context.Background(), email, password)
 }
 
-func (s *AuthService) RegisterWithContext(ctx context.Context, email, password string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// RegisterWithContext devuelve ErrInvalidEmail si el email no es válido y
+// *password.ValidationError si la password no cumple la política.
+func (s *AuthService) RegisterWithContext(ctx context.Context, email, plain string) error {
+	email, err := emailaddr.Normalize(email)
+	if err != nil {
+		return ErrInvalidEmail
+	}
+	_, err = s.register(ctx, email, plain)
+	return err
+}
+
+// register crea el usuario con un email ya normalizado.
+func (s *AuthService) register(ctx context.Context, email, plain string) (client.CreateUserResponse, error) {
+	if err := s.passwords.Validate(plain, email); err != nil {
+		return client.CreateUserResponse{}, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.DefaultCost)
 	if err != nil {
-		return err
+		return client.CreateUserResponse{}, err
 	}
 
 	headers := map[string]string{
 		"X-Internal-User-ID": "auth-service",
 	}
 
-	_, err = s.userClient.CreateUserWithContext(ctx, email, string(hash), headers)
+	created, err := s.userClient.CreateUserWithContext(ctx, email, string(hash), headers)
 	if err == client.ErrUserExists {
-		return err
+		return client.CreateUserResponse{}, err
 	}
 	if err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
+		return client.CreateUserResponse{}, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	return nil
+	return created, nil
 }
 
 // Login mantiene compatibilidad, pero usa context.Background().
@@ -70,21 +171,234 @@ func (s *AuthService) LoginWithContext(ctx context.Context, email, password stri
 		"X-Internal-User-ID": "auth-service",
 	}
 
+	// Un email mal escrito no puede tener cuenta; se registra igual como intento fallido.
+	if normalized, err := emailaddr.Normalize(email); err == nil {
+		email = normalized
+	}
+
 	user, err := s.userClient.GetUserByEmailWithContext(ctx, email, headers)
 	if err != nil {
+		s.recordFailedLogin(ctx, client.GetUserByEmailResponse{Email: email})
 		return "", ErrInvalidCredentials
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		s.recordFailedLogin(ctx, user)
 		return "", ErrInvalidCredentials
 	}
+	// Se chequea después de la password para no revelar el estado de cuentas ajenas.
+	if !accountActive(user) {
+		return "", ErrAccountInactive
+	}
+
+	if err := s.recordSuccessfulLogin(ctx, user); err != nil {
+		return "", err
+	}
+	return s.issueToken(ctx, user)
+}
+
+func (s *AuthService) recordFailedLogin(ctx context.Context, user client.GetUserByEmailResponse) {
+	if s.security == nil {
+		return
+	}
+	// El fallo ya se responde como credenciales inválidas; no registrarlo no
+	// debe cambiar la respuesta al cliente.
+	ctx = WithPreferences(ctx, preferencesOf(user))
+	if err := s.security.RecordFailedLogin(ctx, user.ID, user.Email); err != nil {
+		log.Printf("auth_login_failure_not_recorded email=%s err=%v", user.Email, err)
+	}
+}
+
+func (s *AuthService) recordSuccessfulLogin(ctx context.Context, user client.GetUserByEmailResponse) error {
+	if s.security == nil {
+		return nil
+	}
+	return s.security.RecordSuccessfulLogin(WithPreferences(ctx, preferencesOf(user)), user.ID, user.Email)
+}
 
+// LoginWithSSOContext emite el JWT estándar para una identidad ya validada por
+// el IdP del tenant tenantID (SAML); el handler ya controló que el dominio del
+// email sea del tenant. Si el usuario no existe se crea just-in-time en
+// user-service con una password aleatoria inutilizable: solo podrá entrar por
+// SSO hasta que defina una propia. Una cuenta existente solo se usa si la
+// provisionó el mismo tenant y no es admin (si no, ErrSSOLinkRefused).
+func (s *AuthService) LoginWithSSOContext(ctx context.Context, tenantID, email string) (string, error) {
+	email, err := emailaddr.Normalize(email)
+	if err != nil || tenantID == "" {
+		return "", ErrInvalidSSOIdentity
+	}
+
+	headers := map[string]string{
+		"X-Internal-User-ID": "auth-service",
+	}
+
+	user, err := s.userClient.GetUserByEmailWithContext(ctx, email, headers)
+	if err == nil {
+		return s.loginSSOUser(ctx, tenantID, user)
+	}
+	if !errors.Is(err, client.ErrUserNotFound) {
+		return "", fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	randomPassword := make([]byte, 32)
+	if _, err := rand.Read(randomPassword); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword(randomPassword, bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	created, err := s.userClient.CreateSSOUserWithContext(ctx, email, string(hash), tenantID, headers)
+	if errors.Is(err, client.ErrUserExists) {
+		// Carrera con otro alta concurrente: la cuenta ya existe, pero pudo
+		// crearla un registro con password, así que pasa por los mismos controles.
+		existing, getErr := s.userClient.GetUserByEmailWithContext(ctx, email, headers)
+		if getErr != nil {
+			return "", fmt.Errorf("failed to fetch user: %w", getErr)
+		}
+		return s.loginSSOUser(ctx, tenantID, existing)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to provision user: %w", err)
+	}
+
+	user = client.GetUserByEmailResponse{ID: created.ID, Email: email, SSOTenant: tenantID}
+	if err := s.recordSuccessfulLogin(ctx, user); err != nil {
+		return "", err
+	}
+	return s.issueToken(ctx, user)
+}
+
+// loginSSOUser emite el token de una cuenta existente para un login SSO de tenantID.
+func (s *AuthService) loginSSOUser(ctx context.Context, tenantID string, user client.GetUserByEmailResponse) (string, error) {
+	if user.Role == roleAdmin || user.SSOTenant != tenantID {
+		return "", ErrSSOLinkRefused
+	}
+	if !accountActive(user) {
+		return "", ErrAccountInactive
+	}
+	if err := s.recordSuccessfulLogin(ctx, user); err != nil {
+		return "", err
+	}
+	return s.issueToken(ctx, user)
+}
+
+// ImpersonateWithContext emite un token de corta duración para que un admin
+// vea la plataforma como targetUserID. El token lleva sub = usuario objetivo y
+// el claim "act" (RFC 8693) con el agente, que el gateway propaga a los servicios.
+func (s *AuthService) ImpersonateWithContext(ctx context.Context, actorID, targetUserID, reason string) (string, error) {
+	if reason == "" {
+		return "", ErrReasonRequired
+	}
+	if actorID == "" || targetUserID == "" || actorID == targetUserID {
+		return "", ErrForbidden
+	}
+
+	headers := map[string]string{
+		"X-Internal-User-ID": "auth-service",
+	}
+
+	// El rol se verifica contra user-service, no contra lo que diga el token.
+	actor, err := s.userClient.GetUserByIDWithContext(ctx, actorID, headers)
+	if err != nil {
+		if errors.Is(err, client.ErrUserNotFound) {
+			return "", ErrForbidden
+		}
+		return "", fmt.Errorf("failed to fetch actor: %w", err)
+	}
+	if actor.Role != roleAdmin {
+		return "", ErrForbidden
+	}
+
+	target, err := s.userClient.GetUserByIDWithContext(ctx, targetUserID, headers)
+	if err != nil {
+		return "", err
+	}
+	// Impersonar a otro admin sería una escalada lateral de privilegios.
+	if target.Role == roleAdmin {
+		return "", ErrForbidden
+	}
+
+	expiresAt := time.Now().Add(impersonationTokenTTL)
 	claims := jwt.MapClaims{
-		"sub": user.ID,
-		"exp": time.Now().Add(15 * time.Minute).Unix(),
+		"sub":  target.ID,
+		"role": target.Role,
+		"act":  map[string]interface{}{"sub": actor.ID},
+		"exp":  expiresAt.Unix(),
+	}
+	// El agente ve la plataforma con el idioma y la zona del usuario.
+	addPreferenceClaims(claims, target)
+	if s.security != nil {
+		// La sesión queda a nombre del usuario objetivo: si niega actividad
+		// sospechosa, también se corta la impersonación en curso.
+		sid, err := s.security.StartSession(ctx, target.ID, actor.ID, expiresAt)
+		if err != nil {
+			return "", err
+		}
+		claims["sid"] = sid
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("audit_impersonation_started actor=%s subject=%s reason=%q request_id=%s",
+		actor.ID, target.ID, reason, trace.RequestIDFromContext(ctx))
+	return signed, nil
+}
+
+func (s *AuthService) issueToken(ctx context.Context, user client.GetUserByEmailResponse) (string, error) {
+	return s.issueOrgToken(ctx, user, client.MembershipResponse{})
+}
+
+// issueOrgToken emite el token con la organización activa (org_id/org_role)
+// si org no está vacía.
+func (s *AuthService) issueOrgToken(ctx context.Context, user client.GetUserByEmailResponse, org client.MembershipResponse) (string, error) {
+	role := user.Role
+	if role == "" {
+		role = "user"
+	}
+	expiresAt := time.Now().Add(accessTokenTTL)
+	claims := jwt.MapClaims{
+		"sub":  user.ID,
+		"role": role,
+		"exp":  expiresAt.Unix(),
+	}
+	addPreferenceClaims(claims, user)
+	if org.OrgID != "" {
+		claims["org_id"] = org.OrgID
+		claims["org_role"] = org.Role
+	}
+	if s.security != nil {
+		sid, err := s.security.StartSession(ctx, user.ID, "", expiresAt)
+		if err != nil {
+			return "", err
+		}
+		claims["sid"] = sid
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	return token.SignedString(s.jwtSecret)
 }
+
+// accountActive: un status vacío es una cuenta anterior a los estados de cuenta.
+func accountActive(user client.GetUserByEmailResponse) bool {
+	return user.Status == "" || user.Status == statusActive
+}
+
+// addPreferenceClaims agrega locale y tz para que el gateway los propague;
+// si faltan, cada servicio usa sus defaults.
+func addPreferenceClaims(claims jwt.MapClaims, user client.GetUserByEmailResponse) {
+	if user.Locale != "" {
+		claims["locale"] = user.Locale
+	}
+	if user.Timezone != "" {
+		claims["tz"] = user.Timezone
+	}
+}
+
+func preferencesOf(user client.GetUserByEmailResponse) Preferences {
+	return Preferences{Locale: user.Locale, Timezone: user.Timezone}
+}
@@ -2,11 +2,14 @@ package service
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"saas-subscription-platform/services/auth-service/internal/client"
+	"saas-subscription-platform/services/auth-service/internal/password"
 	"saas-subscription-platform/services/auth-service/internal/service/mocks"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
@@ -18,14 +21,41 @@ func TestAuthService_Register(t *testing.T) {
 	svc := NewAuthService("secret", mockUser)
 
 	mockUser.EXPECT().CreateUserWithContext(gomock.Any(), "alice@example.com", gomock.Any(), gomock.Any()).Return(client.CreateUserResponse{ID: "u-1"}, nil)
-	err := svc.Register("alice@example.com", "pass")
+	err := svc.Register("alice@example.com", "lunar-tide-orchard")
 	require.NoError(t, err)
 
 	mockUser.EXPECT().CreateUserWithContext(gomock.Any(), "dup@example.com", gomock.Any(), gomock.Any()).Return(client.CreateUserResponse{}, client.ErrUserExists)
-	err = svc.Register("dup@example.com", "pass")
+	err = svc.Register("dup@example.com", "lunar-tide-orchard")
 	require.ErrorIs(t, err, ErrUserExists)
 }
 
+func TestAuthService_RegisterNormalizesEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUser := mocks.NewMockUserClient(ctrl)
+	svc := NewAuthService("secret", mockUser)
+
+	mockUser.EXPECT().CreateUserWithContext(gomock.Any(), "Alice@example.com", gomock.Any(), gomock.Any()).Return(client.CreateUserResponse{ID: "u-1"}, nil)
+	require.NoError(t, svc.Register(" Alice@EXAMPLE.com ", "lunar-tide-orchard"))
+
+	require.ErrorIs(t, svc.Register("alice@", "lunar-tide-orchard"), ErrInvalidEmail)
+}
+
+func TestAuthService_RegisterRejectsWeakPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUser := mocks.NewMockUserClient(ctrl)
+	breached, err := password.ReadList(strings.NewReader("lunar-tide-orchard\n"))
+	require.NoError(t, err)
+	policy := password.DefaultPolicy()
+	policy.Breached = breached
+	svc := NewAuthService("secret", mockUser, WithPasswordPolicy(policy))
+
+	var verr *password.ValidationError
+	for _, weak := range []string{"", "short", "lunar-tide-orchard"} {
+		err := svc.Register("alice@example.com", weak)
+		require.ErrorAs(t, err, &verr, "password %q", weak)
+	}
+}
+
 func TestAuthService_Login(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockUser := mocks.NewMockUserClient(ctrl)
@@ -55,3 +85,190 @@ func TestAuthService_Login(t *testing.T) {
 	_, err = svc.LoginWithContext(context.Background(), "alice@example.com", "wrong")
 	require.ErrorIs(t, err, ErrInvalidCredentials)
 }
+
+func TestAuthService_LoginAddsPreferenceClaims(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUser := mocks.NewMockUserClient(ctrl)
+	svc := NewAuthService("secret", mockUser)
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.DefaultCost)
+	mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "alice@example.com", gomock.Any()).Return(client.GetUserByEmailResponse{
+		ID:       "u-1",
+		Email:    "alice@example.com",
+		Password: string(hashed),
+		Locale:   "es-AR",
+		Timezone: "America/Argentina/Buenos_Aires",
+	}, nil)
+
+	signed, err := svc.Login("alice@example.com", "pass")
+	require.NoError(t, err)
+
+	token, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return []byte("secret"), nil })
+	require.NoError(t, err)
+	claims := token.Claims.(jwt.MapClaims)
+	require.Equal(t, "es-AR", claims["locale"])
+	require.Equal(t, "America/Argentina/Buenos_Aires", claims["tz"])
+}
+
+func TestAuthService_LoginRejectsInactiveAccounts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUser := mocks.NewMockUserClient(ctrl)
+	svc := NewAuthService("secret", mockUser)
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.DefaultCost)
+	for _, status := range []string{"suspended", "deactivated", "pending_deletion"} {
+		mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "alice@example.com", gomock.Any()).Return(client.GetUserByEmailResponse{
+			ID:       "u-1",
+			Email:    "alice@example.com",
+			Password: string(hashed),
+			Status:   status,
+		}, nil)
+		_, err := svc.Login("alice@example.com", "pass")
+		require.ErrorIs(t, err, ErrAccountInactive, status)
+	}
+
+	// Con la password incorrecta no se revela el estado.
+	mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "alice@example.com", gomock.Any()).Return(client.GetUserByEmailResponse{
+		ID:       "u-1",
+		Email:    "alice@example.com",
+		Password: string(hashed),
+		Status:   "suspended",
+	}, nil)
+	_, err := svc.Login("alice@example.com", "wrong")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "alice@acme.com", gomock.Any()).Return(client.GetUserByEmailResponse{ID: "u-1", Status: "suspended", SSOTenant: "acme"}, nil)
+	_, err = svc.LoginWithSSOContext(context.Background(), "acme", "alice@acme.com")
+	require.ErrorIs(t, err, ErrAccountInactive)
+}
+
+func TestAuthService_LoginWithSSO(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUser := mocks.NewMockUserClient(ctrl)
+	svc := NewAuthService("secret", mockUser)
+
+	// Usuario existente del mismo tenant: no se crea nada.
+	mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "alice@acme.com", gomock.Any()).Return(client.GetUserByEmailResponse{ID: "u-1", SSOTenant: "acme"}, nil)
+	token, err := svc.LoginWithSSOContext(context.Background(), "acme", "alice@acme.com")
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	// Usuario nuevo: se provisiona just-in-time con una password aleatoria, marcado con el tenant.
+	mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "bob@acme.com", gomock.Any()).Return(client.GetUserByEmailResponse{}, client.ErrUserNotFound)
+	mockUser.EXPECT().CreateSSOUserWithContext(gomock.Any(), "bob@acme.com", gomock.Any(), "acme", gomock.Any()).DoAndReturn(
+		func(ctx context.Context, email, password, tenantID string, headers map[string]string) (client.CreateUserResponse, error) {
+			require.NotEmpty(t, password)
+			return client.CreateUserResponse{ID: "u-2", Email: email}, nil
+		})
+	token, err = svc.LoginWithSSOContext(context.Background(), "acme", "bob@acme.com")
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	_, err = svc.LoginWithSSOContext(context.Background(), "acme", "")
+	require.ErrorIs(t, err, ErrInvalidSSOIdentity)
+}
+
+func TestAuthService_LoginWithSSO_RefusesLink(t *testing.T) {
+	tests := []struct {
+		name string
+		user client.GetUserByEmailResponse
+	}{
+		{name: "admin", user: client.GetUserByEmailResponse{ID: "u-1", Role: "admin", SSOTenant: "acme"}},
+		{name: "password account", user: client.GetUserByEmailResponse{ID: "u-1", Role: "user"}},
+		{name: "other tenant", user: client.GetUserByEmailResponse{ID: "u-1", Role: "user", SSOTenant: "globex"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockUser := mocks.NewMockUserClient(ctrl)
+			svc := NewAuthService("secret", mockUser)
+
+			mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "root@acme.com", gomock.Any()).Return(tt.user, nil)
+			_, err := svc.LoginWithSSOContext(context.Background(), "acme", "root@acme.com")
+			require.ErrorIs(t, err, ErrSSOLinkRefused)
+		})
+	}
+
+	// Un registro con password que gana la carrera al alta JIT tampoco se vincula.
+	ctrl := gomock.NewController(t)
+	mockUser := mocks.NewMockUserClient(ctrl)
+	svc := NewAuthService("secret", mockUser)
+	gomock.InOrder(
+		mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "carol@acme.com", gomock.Any()).Return(client.GetUserByEmailResponse{}, client.ErrUserNotFound),
+		mockUser.EXPECT().CreateSSOUserWithContext(gomock.Any(), "carol@acme.com", gomock.Any(), "acme", gomock.Any()).Return(client.CreateUserResponse{}, client.ErrUserExists),
+		mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "carol@acme.com", gomock.Any()).Return(client.GetUserByEmailResponse{ID: "u-3", Role: "user"}, nil),
+	)
+	_, err := svc.LoginWithSSOContext(context.Background(), "acme", "carol@acme.com")
+	require.ErrorIs(t, err, ErrSSOLinkRefused)
+}
+
+func TestAuthService_Impersonate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUser := mocks.NewMockUserClient(ctrl)
+	svc := NewAuthService("secret", mockUser)
+
+	mockUser.EXPECT().GetUserByIDWithContext(gomock.Any(), "admin-1", gomock.Any()).Return(client.GetUserByEmailResponse{ID: "admin-1", Role: "admin"}, nil)
+	mockUser.EXPECT().GetUserByIDWithContext(gomock.Any(), "u-1", gomock.Any()).Return(client.GetUserByEmailResponse{ID: "u-1", Role: "user"}, nil)
+
+	signed, err := svc.ImpersonateWithContext(context.Background(), "admin-1", "u-1", "ticket #42")
+	require.NoError(t, err)
+
+	token, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return []byte("secret"), nil })
+	require.NoError(t, err)
+	claims := token.Claims.(jwt.MapClaims)
+	require.Equal(t, "u-1", claims["sub"])
+	require.Equal(t, map[string]interface{}{"sub": "admin-1"}, claims["act"])
+
+	// No se puede impersonar a otro admin.
+	mockUser.EXPECT().GetUserByIDWithContext(gomock.Any(), "admin-1", gomock.Any()).Return(client.GetUserByEmailResponse{ID: "admin-1", Role: "admin"}, nil)
+	mockUser.EXPECT().GetUserByIDWithContext(gomock.Any(), "admin-2", gomock.Any()).Return(client.GetUserByEmailResponse{ID: "admin-2", Role: "admin"}, nil)
+	_, err = svc.ImpersonateWithContext(context.Background(), "admin-1", "admin-2", "ticket #42")
+	require.ErrorIs(t, err, ErrForbidden)
+
+	_, err = svc.ImpersonateWithContext(context.Background(), "admin-1", "u-1", "")
+	require.ErrorIs(t, err, ErrReasonRequired)
+}
+
+func TestAuthService_SwitchOrg(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUser := mocks.NewMockUserClient(ctrl)
+	svc := NewAuthService("secret", mockUser)
+
+	mockUser.EXPECT().GetUserByIDWithContext(gomock.Any(), "u-1", gomock.Any()).Return(client.GetUserByEmailResponse{ID: "u-1", Role: "user"}, nil).Times(3)
+	mockUser.EXPECT().GetMembershipWithContext(gomock.Any(), "o-1", "u-1", gomock.Any()).Return(client.MembershipResponse{OrgID: "o-1", UserID: "u-1", Role: "billing"}, nil)
+
+	signed, err := svc.SwitchOrgWithContext(context.Background(), "u-1", "o-1")
+	require.NoError(t, err)
+
+	token, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return []byte("secret"), nil })
+	require.NoError(t, err)
+	claims := token.Claims.(jwt.MapClaims)
+	require.Equal(t, "u-1", claims["sub"])
+	require.Equal(t, "o-1", claims["org_id"])
+	require.Equal(t, "billing", claims["org_role"])
+
+	// Sin membresía no hay token para esa organización.
+	mockUser.EXPECT().GetMembershipWithContext(gomock.Any(), "o-2", "u-1", gomock.Any()).Return(client.MembershipResponse{}, client.ErrNotMember)
+	_, err = svc.SwitchOrgWithContext(context.Background(), "u-1", "o-2")
+	require.ErrorIs(t, err, ErrForbidden)
+
+	// org_id vacío vuelve al ámbito personal.
+	signed, err = svc.SwitchOrgWithContext(context.Background(), "u-1", "")
+	require.NoError(t, err)
+	token, err = jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return []byte("secret"), nil })
+	require.NoError(t, err)
+	require.NotContains(t, token.Claims.(jwt.MapClaims), "org_id")
+}
+
+func TestAuthService_RegisterWithInvitation_AcceptFailureKeepsAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUser := mocks.NewMockUserClient(ctrl)
+	svc := NewAuthService("secret", mockUser)
+
+	mockUser.EXPECT().LookupInvitationWithContext(gomock.Any(), "inv-token", gomock.Any()).Return(client.InvitationResponse{OrgID: "o-1", Email: "bob@example.com"}, nil)
+	mockUser.EXPECT().CreateUserWithContext(gomock.Any(), "bob@example.com", gomock.Any(), gomock.Any()).Return(client.CreateUserResponse{ID: "u-2"}, nil)
+	mockUser.EXPECT().AcceptInvitationWithContext(gomock.Any(), "inv-token", "u-2", gomock.Any()).Return(client.MembershipResponse{}, client.ErrInvalidInvitation)
+
+	// La cuenta ya existe: el fallo al aceptar no revierte el registro.
+	require.NoError(t, svc.RegisterWithInvitationContext(context.Background(), "bob@example.com", "lunar-tide-orchard", "inv-token"))
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"saas-subscription-platform/libs/emailaddr"
+	"saas-subscription-platform/libs/locale"
+	"saas-subscription-platform/libs/notify"
+	"saas-subscription-platform/libs/trace"
+	"saas-subscription-platform/services/auth-service/internal/client"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidEmail      = errors.New("invalid email")
+	ErrInvalidEmailToken = errors.New("invalid or expired email change token")
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+)
+
+const (
+	emailChangeTTL     = 24 * time.Hour
+	emailChangePurpose = "email_change"
+
+	passwordResetTTL     = time.Hour
+	passwordResetPurpose = "password_reset"
+)
+
+// ChangePasswordWithContext re-autentica con la password actual, aplica la
+// política a la nueva y cierra todas las sesiones abiertas. Devuelve un token
+// nuevo para que el cliente que hizo el cambio siga logueado.
+func (s *AuthService) ChangePasswordWithContext(ctx context.Context, userID, currentPassword, newPassword string) (string, error) {
+	user, err := s.verifyPassword(ctx, userID, currentPassword)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.passwords.Validate(newPassword, user.Email); err != nil {
+		return "", err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	if err := s.userClient.UpdatePasswordWithContext(ctx, user.ID, string(hash), internalHeaders()); err != nil {
+		return "", fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if s.security != nil {
+		if err := s.security.RevokeAllSessions(ctx, user.ID); err != nil {
+			return "", err
+		}
+	}
+
+	log.Printf("auth_password_changed user_id=%s request_id=%s", user.ID, trace.RequestIDFromContext(ctx))
+	s.notify(ctx, notify.Message{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body: "The password of your account was just changed and all other sessions were signed out.\n\n" +
+			"Time: " + locale.FormatTime(time.Now(), user.Timezone, user.Locale) + "\n\n" +
+			"If you did not do this, reset your password immediately and contact support.\n",
+	})
+
+	return s.issueToken(ctx, user)
+}
+
+// RequestEmailChangeWithContext envía un link de confirmación a la dirección
+// nueva y un aviso a la actual. El email no cambia hasta que se confirme.
+func (s *AuthService) RequestEmailChangeWithContext(ctx context.Context, userID, currentPassword, newEmail string) error {
+	newEmail, err := emailaddr.Normalize(newEmail)
+	if err != nil {
+		return ErrInvalidEmail
+	}
+
+	user, err := s.verifyPassword(ctx, userID, currentPassword)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		return ErrInvalidEmail
+	}
+
+	if _, err := s.userClient.GetUserByEmailWithContext(ctx, newEmail, internalHeaders()); err == nil {
+		return ErrUserExists
+	} else if !errors.Is(err, client.ErrUserNotFound) {
+		return fmt.Errorf("failed to check email availability: %w", err)
+	}
+
+	claims := jwt.MapClaims{
+		"purpose": emailChangePurpose,
+		"sub":     user.ID,
+		"old":     user.Email,
+		"new":     newEmail,
+		"exp":     time.Now().Add(emailChangeTTL).Unix(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.emailChangeKey())
+	if err != nil {
+		return err
+	}
+
+	confirmLink := s.emailConfirmURL + "?token=" + url.QueryEscape(token)
+	if err := s.sender.Send(ctx, notify.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: "Someone asked to use this address for their account.\n\n" +
+			"To confirm, open: " + confirmLink + "\n\nThe link expires in 24 hours. If it wasn't you, ignore this email.\n",
+	}); err != nil {
+		return fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+	s.notify(ctx, notify.Message{
+		To:      user.Email,
+		Subject: "Email change requested",
+		Body: "A request was made to change the email of your account to " + newEmail + ".\n\n" +
+			"The change only happens once the new address is confirmed. If it wasn't you, change your password now.\n",
+	})
+
+	log.Printf("auth_email_change_requested user_id=%s request_id=%s", user.ID, trace.RequestIDFromContext(ctx))
+	return nil
+}
+
+// ConfirmEmailChangeWithContext aplica el cambio del token. Es de un solo uso:
+// user-service solo reemplaza el email si el actual sigue siendo el del token.
+func (s *AuthService) ConfirmEmailChangeWithContext(ctx context.Context, token string) error {
+	parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		return s.emailChangeKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !parsed.Valid {
+		return ErrInvalidEmailToken
+	}
+	claims, _ := parsed.Claims.(jwt.MapClaims)
+	purpose, _ := claims["purpose"].(string)
+	userID, _ := claims["sub"].(string)
+	oldEmail, _ := claims["old"].(string)
+	newEmail, _ := claims["new"].(string)
+	if purpose != emailChangePurpose || userID == "" || oldEmail == "" || newEmail == "" {
+		return ErrInvalidEmailToken
+	}
+
+	err = s.userClient.ReplaceEmailWithContext(ctx, userID, oldEmail, newEmail, internalHeaders())
+	if err != nil {
+		if errors.Is(err, client.ErrEmailMismatch) {
+			return ErrInvalidEmailToken
+		}
+		if errors.Is(err, client.ErrUserExists) {
+			return ErrUserExists
+		}
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+
+	log.Printf("auth_email_changed user_id=%s request_id=%s", userID, trace.RequestIDFromContext(ctx))
+	s.notify(ctx, notify.Message{
+		To:      oldEmail,
+		Subject: "Your email address was changed",
+		Body:    "The email of your account was changed to " + newEmail + ". If you did not do this, contact support.\n",
+	})
+	return nil
+}
+
+// RequestPasswordResetWithContext envía un link para elegir una password nueva.
+// Si la cuenta no existe o no está activa no hace nada y tampoco falla, para
+// no revelar qué emails están registrados.
+func (s *AuthService) RequestPasswordResetWithContext(ctx context.Context, email string) error {
+	email, err := emailaddr.Normalize(email)
+	if err != nil {
+		return ErrInvalidEmail
+	}
+
+	user, err := s.userClient.GetUserByEmailWithContext(ctx, email, internalHeaders())
+	if errors.Is(err, client.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if !accountActive(user) {
+		return nil
+	}
+
+	claims := jwt.MapClaims{
+		"purpose": passwordResetPurpose,
+		"sub":     user.ID,
+		"exp":     time.Now().Add(passwordResetTTL).Unix(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.passwordResetKey(user.Password))
+	if err != nil {
+		return err
+	}
+
+	resetLink := s.passwordResetURL + "?token=" + url.QueryEscape(token)
+	if err := s.sender.Send(ctx, notify.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: "Someone asked to reset the password of your account.\n\n" +
+			"To choose a new one, open: " + resetLink + "\n\nThe link expires in 1 hour. If it wasn't you, ignore this email.\n",
+	}); err != nil {
+		return fmt.Errorf("failed to send password reset email: %w", err)
+	}
+
+	log.Printf("auth_password_reset_requested user_id=%s request_id=%s", user.ID, trace.RequestIDFromContext(ctx))
+	return nil
+}
+
+// ResetPasswordWithContext aplica la política a la password nueva, la guarda y
+// cierra todas las sesiones. El token es de un solo uso: se firma con el hash
+// de la password actual, así que deja de valer en cuanto la password cambia.
+func (s *AuthService) ResetPasswordWithContext(ctx context.Context, token, newPassword string) error {
+	var user client.GetUserByEmailResponse
+	var lookupErr error
+	parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		claims, _ := t.Claims.(jwt.MapClaims)
+		purpose, _ := claims["purpose"].(string)
+		userID, _ := claims["sub"].(string)
+		if purpose != passwordResetPurpose || userID == "" {
+			return nil, ErrInvalidResetToken
+		}
+		byID, err := s.userClient.GetUserByIDWithContext(ctx, userID, internalHeaders())
+		if err == nil {
+			// GET /users/{id} no expone el hash; se obtiene por email.
+			user, err = s.userClient.GetUserByEmailWithContext(ctx, byID.Email, internalHeaders())
+		}
+		if err != nil {
+			lookupErr = err
+			return nil, err
+		}
+		return s.passwordResetKey(user.Password), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !parsed.Valid {
+		if lookupErr != nil && !errors.Is(lookupErr, client.ErrUserNotFound) {
+			return fmt.Errorf("failed to fetch user: %w", lookupErr)
+		}
+		return ErrInvalidResetToken
+	}
+	if !accountActive(user) {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.passwords.Validate(newPassword, user.Email); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userClient.UpdatePasswordWithContext(ctx, user.ID, string(hash), internalHeaders()); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if s.security != nil {
+		if err := s.security.RevokeAllSessions(ctx, user.ID); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("auth_password_reset user_id=%s request_id=%s", user.ID, trace.RequestIDFromContext(ctx))
+	s.notify(ctx, notify.Message{
+		To:      user.Email,
+		Subject: "Your password was reset",
+		Body: "The password of your account was just reset and all sessions were signed out.\n\n" +
+			"Time: " + locale.FormatTime(time.Now(), user.Timezone, user.Locale) + "\n\n" +
+			"If you did not do this, contact support.\n",
+	})
+	return nil
+}
+
+// verifyPassword re-autentica al usuario. Los fallos cuentan para la
+// detección de ráfagas igual que un login fallido.
+func (s *AuthService) verifyPassword(ctx context.Context, userID, plain string) (client.GetUserByEmailResponse, error) {
+	byID, err := s.userClient.GetUserByIDWithContext(ctx, userID, internalHeaders())
+	if err != nil {
+		return client.GetUserByEmailResponse{}, err
+	}
+	// GET /users/{id} no expone el hash; se obtiene por email.
+	user, err := s.userClient.GetUserByEmailWithContext(ctx, byID.Email, internalHeaders())
+	if err != nil {
+		return client.GetUserByEmailResponse{}, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(plain)); err != nil {
+		s.recordFailedLogin(ctx, user)
+		return client.GetUserByEmailResponse{}, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// emailChangeKey deriva una clave propia para los tokens de cambio de email:
+// firmados con JWT_SECRET tal cual, el gateway los aceptaría como access token.
+func (s *AuthService) emailChangeKey() []byte {
+	sum := sha256.Sum256(append([]byte(emailChangePurpose+":"), s.jwtSecret...))
+	return sum[:]
+}
+
+// passwordResetKey deriva la clave de los tokens de reset a partir del hash
+// actual: cambiar la password invalida los links pendientes.
+func (s *AuthService) passwordResetKey(passwordHash string) []byte {
+	sum := sha256.Sum256([]byte(passwordResetPurpose + ":" + string(s.jwtSecret) + ":" + passwordHash))
+	return sum[:]
+}
+
+// notify envía avisos informativos; un fallo no revierte la operación.
+func (s *AuthService) notify(ctx context.Context, msg notify.Message) {
+	if err := s.sender.Send(ctx, msg); err != nil {
+		log.Printf("auth_notification_failed to=%s subject=%q err=%v", msg.To, msg.Subject, err)
+	}
+}
+
+func internalHeaders() map[string]string {
+	return map[string]string{
+		"X-Internal-User-ID": "auth-service",
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/auth-service/internal/client"
+	"saas-subscription-platform/services/auth-service/internal/password"
+	"saas-subscription-platform/services/auth-service/internal/service/mocks"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func expectUser(mockUser *mocks.MockUserClient, plain string) {
+	hashed, _ := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.MinCost)
+	user := client.GetUserByEmailResponse{ID: "u-1", Email: "alice@example.com", Password: string(hashed), Role: "user"}
+	mockUser.EXPECT().GetUserByIDWithContext(gomock.Any(), "u-1", gomock.Any()).Return(client.GetUserByEmailResponse{ID: "u-1", Email: user.Email}, nil)
+	mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "alice@example.com", gomock.Any()).Return(user, nil)
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUser := mocks.NewMockUserClient(ctrl)
+	security, _, _, now := newTestSecurity(t)
+	sender := &recordingSender{}
+	svc := NewAuthService("secret", mockUser, WithSecurity(security), WithSender(sender))
+	ctx := context.Background()
+
+	oldSession, err := security.StartSession(ctx, "u-1", "", now.Add(time.Hour))
+	require.NoError(t, err)
+
+	expectUser(mockUser, "old-password-1")
+	_, err = svc.ChangePasswordWithContext(ctx, "u-1", "wrong", "lunar-tide-orchard")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	expectUser(mockUser, "old-password-1")
+	_, err = svc.ChangePasswordWithContext(ctx, "u-1", "old-password-1", "short")
+	var verr *password.ValidationError
+	require.ErrorAs(t, err, &verr)
+
+	expectUser(mockUser, "old-password-1")
+	var storedHash string
+	mockUser.EXPECT().UpdatePasswordWithContext(gomock.Any(), "u-1", gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, hash string, _ map[string]string) error {
+			storedHash = hash
+			return nil
+		})
+	token, err := svc.ChangePasswordWithContext(ctx, "u-1", "old-password-1", "lunar-tide-orchard")
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NoError(t, bcrypt.CompareHashAndPassword([]byte(storedHash), []byte("lunar-tide-orchard")))
+
+	active, err := security.SessionActive(ctx, oldSession, "u-1")
+	require.NoError(t, err)
+	require.False(t, active, "existing sessions must be revoked")
+
+	require.Len(t, sender.messages, 1)
+	require.Equal(t, "alice@example.com", sender.messages[0].To)
+}
+
+func confirmToken(t *testing.T, body string) string {
+	t.Helper()
+	i := strings.Index(body, "?token=")
+	require.NotEqual(t, -1, i)
+	raw := strings.Fields(body[i+len("?token="):])[0]
+	token, err := url.QueryUnescape(raw)
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthService_EmailChange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUser := mocks.NewMockUserClient(ctrl)
+	sender := &recordingSender{}
+	svc := NewAuthService("secret", mockUser, WithSender(sender), WithEmailConfirmURL("https://app.example.com/confirm-email"))
+	ctx := context.Background()
+
+	require.ErrorIs(t, svc.RequestEmailChangeWithContext(ctx, "u-1", "pw", "not-an-email"), ErrInvalidEmail)
+
+	expectUser(mockUser, "current-password")
+	mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "taken@example.com", gomock.Any()).Return(client.GetUserByEmailResponse{ID: "u-2"}, nil)
+	require.ErrorIs(t, svc.RequestEmailChangeWithContext(ctx, "u-1", "current-password", "taken@example.com"), ErrUserExists)
+
+	expectUser(mockUser, "current-password")
+	mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "new@example.com", gomock.Any()).Return(client.GetUserByEmailResponse{}, client.ErrUserNotFound)
+	require.NoError(t, svc.RequestEmailChangeWithContext(ctx, "u-1", "current-password", "new@example.com"))
+
+	require.Len(t, sender.messages, 2)
+	require.Equal(t, "new@example.com", sender.messages[0].To)
+	require.Contains(t, sender.messages[0].Body, "https://app.example.com/confirm-email?token=")
+	require.Equal(t, "alice@example.com", sender.messages[1].To)
+	token := confirmToken(t, sender.messages[0].Body)
+
+	// El token de confirmación no sirve como access token.
+	_, err := jwt.Parse(token, func(*jwt.Token) (interface{}, error) { return []byte("secret"), nil })
+	require.Error(t, err)
+
+	require.ErrorIs(t, svc.ConfirmEmailChangeWithContext(ctx, token+"x"), ErrInvalidEmailToken)
+
+	mockUser.EXPECT().ReplaceEmailWithContext(gomock.Any(), "u-1", "alice@example.com", "new@example.com", gomock.Any()).Return(nil)
+	require.NoError(t, svc.ConfirmEmailChangeWithContext(ctx, token))
+	require.Equal(t, "alice@example.com", sender.messages[2].To)
+
+	// Reusar el link: el email actual ya no coincide.
+	mockUser.EXPECT().ReplaceEmailWithContext(gomock.Any(), "u-1", "alice@example.com", "new@example.com", gomock.Any()).Return(client.ErrEmailMismatch)
+	require.ErrorIs(t, svc.ConfirmEmailChangeWithContext(ctx, token), ErrInvalidEmailToken)
+}
+
+func TestAuthService_PasswordReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUser := mocks.NewMockUserClient(ctrl)
+	security, _, _, now := newTestSecurity(t)
+	sender := &recordingSender{}
+	svc := NewAuthService("secret", mockUser, WithSecurity(security), WithSender(sender), WithPasswordResetURL("https://app.example.com/reset-password"))
+	ctx := context.Background()
+
+	// Un email sin cuenta no falla ni envía nada.
+	mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "ghost@example.com", gomock.Any()).Return(client.GetUserByEmailResponse{}, client.ErrUserNotFound)
+	require.NoError(t, svc.RequestPasswordResetWithContext(ctx, "ghost@example.com"))
+	require.Empty(t, sender.messages)
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("forgotten-password"), bcrypt.MinCost)
+	user := client.GetUserByEmailResponse{ID: "u-1", Email: "alice@example.com", Password: string(hashed), Role: "user"}
+	mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "alice@example.com", gomock.Any()).Return(user, nil)
+	require.NoError(t, svc.RequestPasswordResetWithContext(ctx, "alice@example.com"))
+	require.Len(t, sender.messages, 1)
+	require.Contains(t, sender.messages[0].Body, "https://app.example.com/reset-password?token=")
+	token := confirmToken(t, sender.messages[0].Body)
+
+	oldSession, err := security.StartSession(ctx, "u-1", "", now.Add(time.Hour))
+	require.NoError(t, err)
+
+	expectLookup := func(u client.GetUserByEmailResponse) {
+		mockUser.EXPECT().GetUserByIDWithContext(gomock.Any(), "u-1", gomock.Any()).Return(client.GetUserByEmailResponse{ID: "u-1", Email: u.Email}, nil)
+		mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "alice@example.com", gomock.Any()).Return(u, nil)
+	}
+
+	// La política se aplica igual que en el registro y el cambio de password.
+	expectLookup(user)
+	err = svc.ResetPasswordWithContext(ctx, token, "alice")
+	var verr *password.ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, password.RuleMinLength, verr.Violations[0].Rule)
+
+	expectLookup(user)
+	var storedHash string
+	mockUser.EXPECT().UpdatePasswordWithContext(gomock.Any(), "u-1", gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, hash string, _ map[string]string) error {
+			storedHash = hash
+			return nil
+		})
+	require.NoError(t, svc.ResetPasswordWithContext(ctx, token, "lunar-tide-orchard"))
+	require.NoError(t, bcrypt.CompareHashAndPassword([]byte(storedHash), []byte("lunar-tide-orchard")))
+
+	active, err := security.SessionActive(ctx, oldSession, "u-1")
+	require.NoError(t, err)
+	require.False(t, active, "existing sessions must be revoked")
+
+	// Reusar el link: la password ya cambió, así que la firma no coincide.
+	expectUser(mockUser, "lunar-tide-orchard")
+	require.ErrorIs(t, svc.ResetPasswordWithContext(ctx, token, "another-long-password"), ErrInvalidResetToken)
+
+	require.ErrorIs(t, svc.ResetPasswordWithContext(ctx, "garbage", "lunar-tide-orchard"), ErrInvalidResetToken)
+}
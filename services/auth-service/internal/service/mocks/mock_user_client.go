@@ -45,6 +45,66 @@ func (mr *MockUserClientMockRecorder) CreateUserWithContext(ctx, email, password
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserWithContext", reflect.TypeOf((*MockUserClient)(nil).CreateUserWithContext), ctx, email, password, headers)
 }
 
+// CreateSSOUserWithContext mocks base method.
+func (m *MockUserClient) CreateSSOUserWithContext(ctx context.Context, email, password, tenantID string, headers map[string]string) (client.CreateUserResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSSOUserWithContext", ctx, email, password, tenantID, headers)
+	ret0, _ := ret[0].(client.CreateUserResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSSOUserWithContext indicates expected call.
+func (mr *MockUserClientMockRecorder) CreateSSOUserWithContext(ctx, email, password, tenantID, headers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSSOUserWithContext", reflect.TypeOf((*MockUserClient)(nil).CreateSSOUserWithContext), ctx, email, password, tenantID, headers)
+}
+
+// AcceptInvitationWithContext mocks base method.
+func (m *MockUserClient) AcceptInvitationWithContext(ctx context.Context, token, userID string, headers map[string]string) (client.MembershipResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcceptInvitationWithContext", ctx, token, userID, headers)
+	ret0, _ := ret[0].(client.MembershipResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcceptInvitationWithContext indicates expected call.
+func (mr *MockUserClientMockRecorder) AcceptInvitationWithContext(ctx, token, userID, headers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptInvitationWithContext", reflect.TypeOf((*MockUserClient)(nil).AcceptInvitationWithContext), ctx, token, userID, headers)
+}
+
+// GetMembershipWithContext mocks base method.
+func (m *MockUserClient) GetMembershipWithContext(ctx context.Context, orgID, userID string, headers map[string]string) (client.MembershipResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMembershipWithContext", ctx, orgID, userID, headers)
+	ret0, _ := ret[0].(client.MembershipResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMembershipWithContext indicates expected call.
+func (mr *MockUserClientMockRecorder) GetMembershipWithContext(ctx, orgID, userID, headers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMembershipWithContext", reflect.TypeOf((*MockUserClient)(nil).GetMembershipWithContext), ctx, orgID, userID, headers)
+}
+
+// LookupInvitationWithContext mocks base method.
+func (m *MockUserClient) LookupInvitationWithContext(ctx context.Context, token string, headers map[string]string) (client.InvitationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LookupInvitationWithContext", ctx, token, headers)
+	ret0, _ := ret[0].(client.InvitationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LookupInvitationWithContext indicates expected call.
+func (mr *MockUserClientMockRecorder) LookupInvitationWithContext(ctx, token, headers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LookupInvitationWithContext", reflect.TypeOf((*MockUserClient)(nil).LookupInvitationWithContext), ctx, token, headers)
+}
+
 // GetUserByEmailWithContext mocks base method.
 func (m *MockUserClient) GetUserByEmailWithContext(ctx context.Context, email string, headers map[string]string) (client.GetUserByEmailResponse, error) {
 	m.ctrl.T.Helper()
@@ -59,3 +119,46 @@ func (mr *MockUserClientMockRecorder) GetUserByEmailWithContext(ctx, email, head
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmailWithContext", reflect.TypeOf((*MockUserClient)(nil).GetUserByEmailWithContext), ctx, email, headers)
 }
+
+// GetUserByIDWithContext mocks base method.
+func (m *MockUserClient) GetUserByIDWithContext(ctx context.Context, userID string, headers map[string]string) (client.GetUserByEmailResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByIDWithContext", ctx, userID, headers)
+	ret0, _ := ret[0].(client.GetUserByEmailResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByIDWithContext indicates expected call.
+func (mr *MockUserClientMockRecorder) GetUserByIDWithContext(ctx, userID, headers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByIDWithContext", reflect.TypeOf((*MockUserClient)(nil).GetUserByIDWithContext), ctx, userID, headers)
+}
+
+// UpdatePasswordWithContext mocks base method.
+func (m *MockUserClient) UpdatePasswordWithContext(ctx context.Context, userID, passwordHash string, headers map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasswordWithContext", ctx, userID, passwordHash, headers)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePasswordWithContext indicates expected call.
+func (mr *MockUserClientMockRecorder) UpdatePasswordWithContext(ctx, userID, passwordHash, headers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordWithContext", reflect.TypeOf((*MockUserClient)(nil).UpdatePasswordWithContext), ctx, userID, passwordHash, headers)
+}
+
+// ReplaceEmailWithContext mocks base method.
+func (m *MockUserClient) ReplaceEmailWithContext(ctx context.Context, userID, currentEmail, newEmail string, headers map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceEmailWithContext", ctx, userID, currentEmail, newEmail, headers)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceEmailWithContext indicates expected call.
+func (mr *MockUserClientMockRecorder) ReplaceEmailWithContext(ctx, userID, currentEmail, newEmail, headers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceEmailWithContext", reflect.TypeOf((*MockUserClient)(nil).ReplaceEmailWithContext), ctx, userID, currentEmail, newEmail, headers)
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"saas-subscription-platform/libs/emailaddr"
+	"saas-subscription-platform/libs/trace"
+	"saas-subscription-platform/services/auth-service/internal/client"
+)
+
+// ErrInvalidInvitation: el token de invitación no es válido, ya se usó o venció,
+// o fue emitido para otro email.
+var ErrInvalidInvitation = client.ErrInvalidInvitation
+
+// SwitchOrgWithContext emite un token nuevo con orgID como organización activa.
+// La membresía y el rol se verifican contra user-service; un orgID vacío
+// vuelve al ámbito personal.
+func (s *AuthService) SwitchOrgWithContext(ctx context.Context, userID, orgID string) (string, error) {
+	user, err := s.userClient.GetUserByIDWithContext(ctx, userID, internalHeaders())
+	if err != nil {
+		return "", err
+	}
+	if !accountActive(user) {
+		return "", ErrAccountInactive
+	}
+
+	var membership client.MembershipResponse
+	if orgID != "" {
+		membership, err = s.userClient.GetMembershipWithContext(ctx, orgID, userID, internalHeaders())
+		if err != nil {
+			if errors.Is(err, client.ErrNotMember) {
+				return "", ErrForbidden
+			}
+			return "", fmt.Errorf("failed to fetch membership: %w", err)
+		}
+	}
+
+	token, err := s.issueOrgToken(ctx, user, membership)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("auth_org_switched user_id=%s org_id=%s role=%s request_id=%s",
+		user.ID, orgID, membership.Role, trace.RequestIDFromContext(ctx))
+	return token, nil
+}
+
+// RegisterWithInvitationContext registra al usuario y lo suma a la organización
+// que lo invitó. El email debe coincidir con el de la invitación; si la
+// aceptación falla después de crear la cuenta, el registro igual se mantiene y
+// la invitación puede aceptarse luego desde la sesión.
+func (s *AuthService) RegisterWithInvitationContext(ctx context.Context, email, plain, inviteToken string) error {
+	email, err := emailaddr.Normalize(email)
+	if err != nil {
+		return ErrInvalidEmail
+	}
+
+	inv, err := s.userClient.LookupInvitationWithContext(ctx, inviteToken, internalHeaders())
+	if err != nil {
+		if errors.Is(err, client.ErrInvalidInvitation) {
+			return ErrInvalidInvitation
+		}
+		return fmt.Errorf("failed to lookup invitation: %w", err)
+	}
+	if !strings.EqualFold(inv.Email, email) {
+		return ErrInvalidInvitation
+	}
+
+	created, err := s.register(ctx, email, plain)
+	if err != nil {
+		return err
+	}
+
+	membership, err := s.userClient.AcceptInvitationWithContext(ctx, inviteToken, created.ID, internalHeaders())
+	if err != nil {
+		log.Printf("auth_invitation_accept_failed user_id=%s org_id=%s request_id=%s err=%v",
+			created.ID, inv.OrgID, trace.RequestIDFromContext(ctx), err)
+		return nil
+	}
+	log.Printf("auth_registered_with_invitation user_id=%s org_id=%s role=%s request_id=%s",
+		created.ID, membership.OrgID, membership.Role, trace.RequestIDFromContext(ctx))
+	return nil
+}
@@ -0,0 +1,395 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"saas-subscription-platform/libs/locale"
+	"saas-subscription-platform/libs/notify"
+	"saas-subscription-platform/libs/trace"
+	"saas-subscription-platform/services/auth-service/internal/geoip"
+	"saas-subscription-platform/services/auth-service/internal/model"
+	"saas-subscription-platform/services/auth-service/internal/repository"
+)
+
+// SecurityStore es la persistencia de sesiones, dispositivos conocidos,
+// intentos de login y eventos de seguridad.
+type SecurityStore interface {
+	CreateSession(ctx context.Context, s model.Session) (model.Session, error)
+	GetSession(ctx context.Context, id string) (model.Session, error)
+	RevokeUserSessions(ctx context.Context, userID string) (int64, error)
+	HasDevices(ctx context.Context, userID string) (bool, error)
+	IsKnownDevice(ctx context.Context, d model.Device) (bool, error)
+	TouchDevice(ctx context.Context, d model.Device) error
+	ForgetDevice(ctx context.Context, d model.Device) error
+	RecordLoginAttempt(ctx context.Context, a model.LoginAttempt) error
+	LastLocatedLogin(ctx context.Context, userID string) (model.LoginAttempt, bool, error)
+	CountFailuresSince(ctx context.Context, email string, since time.Time) (int, error)
+	HasEventSince(ctx context.Context, userID, eventType string, since time.Time) (bool, error)
+	CreateEvent(ctx context.Context, ev model.SecurityEvent) (model.SecurityEvent, error)
+	GetEvent(ctx context.Context, id string) (model.SecurityEvent, error)
+	ResolveEvent(ctx context.Context, id, status string) error
+	ExportUserData(ctx context.Context, userID, email string) (model.UserData, error)
+	EraseUserData(ctx context.Context, userID, email string) error
+}
+
+var (
+	ErrEventNotFound = repository.ErrEventNotFound
+	ErrEventResolved = errors.New("security event already resolved")
+)
+
+const (
+	// Ráfaga de fallos: failureBurstThreshold intentos fallidos dentro de failureBurstWindow.
+	failureBurstThreshold = 5
+	failureBurstWindow    = 15 * time.Minute
+
+	// Viaje imposible: más rápido que un avión comercial. Por debajo de
+	// minTravelKm no alertamos (imprecisión propia de GeoIP).
+	maxTravelSpeedKmh = 900.0
+	minTravelKm       = 300.0
+)
+
+// ClientInfo es el origen de un login: IP real del cliente y user-agent.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+type clientInfoKey struct{}
+
+// WithClientInfo adjunta el origen del request al contexto para que los
+// flujos de login (password, SSO) lo usen en la detección de anomalías.
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+func clientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
+
+// Preferences son el idioma y la zona horaria con que se formatean los avisos al usuario.
+type Preferences struct {
+	Locale   string
+	Timezone string
+}
+
+type preferencesKey struct{}
+
+// WithPreferences adjunta las preferencias del usuario que inicia sesión.
+func WithPreferences(ctx context.Context, prefs Preferences) context.Context {
+	return context.WithValue(ctx, preferencesKey{}, prefs)
+}
+
+func preferencesFromContext(ctx context.Context) Preferences {
+	prefs, _ := ctx.Value(preferencesKey{}).(Preferences)
+	return prefs
+}
+
+// SecurityService mantiene el perfil de dispositivos por usuario, detecta
+// logins anómalos y gestiona las sesiones revocables.
+type SecurityService struct {
+	store  SecurityStore
+	geo    *geoip.DB
+	sender notify.Sender
+	now    func() time.Time
+}
+
+func NewSecurityService(store SecurityStore, geo *geoip.DB, sender notify.Sender) *SecurityService {
+	if sender == nil {
+		sender = notify.LogSender{}
+	}
+	return &SecurityService{store: store, geo: geo, sender: sender, now: time.Now}
+}
+
+// RecordFailedLogin registra el intento fallido y, al alcanzar el umbral de la
+// ventana, emite un evento failure_burst (solo si la cuenta existe y no hay
+// otro en la misma ventana).
+func (s *SecurityService) RecordFailedLogin(ctx context.Context, userID, email string) error {
+	info := clientInfoFromContext(ctx)
+	attempt := model.LoginAttempt{UserID: userID, Email: email, IP: info.IP, UserAgent: info.UserAgent}
+	if loc, ok := s.geo.Lookup(info.IP); ok {
+		attempt.Country = loc.Country
+	}
+	if err := s.store.RecordLoginAttempt(ctx, attempt); err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+	if userID == "" {
+		return nil
+	}
+
+	since := s.now().Add(-failureBurstWindow)
+	failures, err := s.store.CountFailuresSince(ctx, email, since)
+	if err != nil {
+		return fmt.Errorf("failed to count login failures: %w", err)
+	}
+	// >= y no igualdad: con intentos concurrentes el conteo puede saltar el
+	// umbral sin que ningún request lo vea exacto. El evento previo de la
+	// ventana evita uno por cada intento extra.
+	if failures < failureBurstThreshold {
+		return nil
+	}
+	flagged, err := s.store.HasEventSince(ctx, userID, model.EventFailureBurst, since)
+	if err != nil {
+		return fmt.Errorf("failed to check security events: %w", err)
+	}
+	if flagged {
+		return nil
+	}
+	return s.flag(ctx, email, model.SecurityEvent{
+		UserID:    userID,
+		Type:      model.EventFailureBurst,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+		Country:   attempt.Country,
+	})
+}
+
+// RecordSuccessfulLogin compara el login con el perfil del usuario, emite los
+// eventos que correspondan y actualiza el perfil.
+func (s *SecurityService) RecordSuccessfulLogin(ctx context.Context, userID, email string) error {
+	info := clientInfoFromContext(ctx)
+	device := model.Device{
+		UserID:      userID,
+		Fingerprint: DeviceFingerprint(info.UserAgent),
+		IPPrefix:    IPPrefix(info.IP),
+	}
+	attempt := model.LoginAttempt{UserID: userID, Email: email, IP: info.IP, UserAgent: info.UserAgent, Success: true}
+	loc, located := s.geo.Lookup(info.IP)
+	if located {
+		attempt.Country = loc.Country
+		attempt.Latitude = &loc.Latitude
+		attempt.Longitude = &loc.Longitude
+	}
+
+	var flagged []model.SecurityEvent
+
+	hasDevices, err := s.store.HasDevices(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load device profile: %w", err)
+	}
+	// El primer login construye el perfil: no hay contra qué comparar.
+	if hasDevices {
+		known, err := s.store.IsKnownDevice(ctx, device)
+		if err != nil {
+			return fmt.Errorf("failed to load device profile: %w", err)
+		}
+		if !known {
+			flagged = append(flagged, model.SecurityEvent{Type: model.EventNewDevice})
+		}
+	}
+
+	if located {
+		prev, found, err := s.store.LastLocatedLogin(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to load last login: %w", err)
+		}
+		if found && impossibleTravel(prev, loc, s.now()) {
+			flagged = append(flagged, model.SecurityEvent{Type: model.EventImpossibleTravel})
+		}
+	}
+
+	if err := s.store.TouchDevice(ctx, device); err != nil {
+		return fmt.Errorf("failed to update device profile: %w", err)
+	}
+	if err := s.store.RecordLoginAttempt(ctx, attempt); err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+
+	for _, ev := range flagged {
+		ev.UserID = userID
+		ev.IP = info.IP
+		ev.UserAgent = info.UserAgent
+		ev.Country = attempt.Country
+		ev.Fingerprint = device.Fingerprint
+		ev.IPPrefix = device.IPPrefix
+		if err := s.flag(ctx, email, ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flag persiste el evento y notifica al usuario. Un fallo del sender no
+// invalida el evento: queda registrado y consultable igual.
+func (s *SecurityService) flag(ctx context.Context, email string, ev model.SecurityEvent) error {
+	ev, err := s.store.CreateEvent(ctx, ev)
+	if err != nil {
+		return fmt.Errorf("failed to create security event: %w", err)
+	}
+	log.Printf("security_event_flagged event_id=%s user_id=%s type=%s ip=%s country=%s request_id=%s",
+		ev.ID, ev.UserID, ev.Type, ev.IP, ev.Country, trace.RequestIDFromContext(ctx))
+
+	if err := s.sender.Send(ctx, securityNotification(email, ev, preferencesFromContext(ctx))); err != nil {
+		log.Printf("security_notification_failed event_id=%s err=%v", ev.ID, err)
+	}
+	return nil
+}
+
+func securityNotification(email string, ev model.SecurityEvent, prefs Preferences) notify.Message {
+	var what string
+	switch ev.Type {
+	case model.EventNewDevice:
+		what = "A new device signed in to your account."
+	case model.EventImpossibleTravel:
+		what = "Your account signed in from a location too far from your previous sign-in."
+	case model.EventFailureBurst:
+		what = "There were several failed attempts to sign in to your account."
+	}
+	location := ev.Country
+	if location == "" {
+		location = "unknown location"
+	}
+	body := fmt.Sprintf(
+		"%s\n\nIP: %s (%s)\nDevice: %s\nTime: %s\n\n"+
+			"If this was you, confirm it: POST /api/auth/security/events/%s/confirm\n"+
+			"If it was not, deny it and all your sessions will be signed out: POST /api/auth/security/events/%s/deny\n",
+		what, ev.IP, location, ev.UserAgent, locale.FormatTime(ev.CreatedAt, prefs.Timezone, prefs.Locale), ev.ID, ev.ID,
+	)
+	return notify.Message{To: email, Subject: "Security alert: unusual activity on your account", Body: body}
+}
+
+// ResolveEvent confirma o niega un evento del usuario. Negarlo revoca todas
+// sus sesiones y saca el dispositivo del perfil para que vuelva a alertar.
+func (s *SecurityService) ResolveEvent(ctx context.Context, userID, eventID string, confirm bool) error {
+	ev, err := s.store.GetEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	// Un evento ajeno se reporta como inexistente: no revelamos IDs de otros.
+	if ev.UserID != userID {
+		return ErrEventNotFound
+	}
+	if ev.Status != model.EventPending {
+		return ErrEventResolved
+	}
+
+	status := model.EventConfirmed
+	if !confirm {
+		status = model.EventDenied
+	}
+	if err := s.store.ResolveEvent(ctx, eventID, status); err != nil {
+		if errors.Is(err, repository.ErrEventNotFound) {
+			return ErrEventResolved
+		}
+		return err
+	}
+
+	if confirm {
+		log.Printf("security_event_confirmed event_id=%s user_id=%s", eventID, userID)
+		return nil
+	}
+
+	if ev.Fingerprint != "" {
+		if err := s.store.ForgetDevice(ctx, model.Device{UserID: userID, Fingerprint: ev.Fingerprint, IPPrefix: ev.IPPrefix}); err != nil {
+			return fmt.Errorf("failed to forget device: %w", err)
+		}
+	}
+	revoked, err := s.store.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	log.Printf("security_event_denied event_id=%s user_id=%s sessions_revoked=%d request_id=%s",
+		eventID, userID, revoked, trace.RequestIDFromContext(ctx))
+	return nil
+}
+
+// RevokeAllSessions cierra todas las sesiones vigentes del usuario.
+func (s *SecurityService) RevokeAllSessions(ctx context.Context, userID string) error {
+	revoked, err := s.store.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	log.Printf("sessions_revoked user_id=%s count=%d request_id=%s", userID, revoked, trace.RequestIDFromContext(ctx))
+	return nil
+}
+
+// ExportUserData devuelve lo que auth-service guarda del usuario (export GDPR).
+func (s *SecurityService) ExportUserData(ctx context.Context, userID, email string) (model.UserData, error) {
+	return s.store.ExportUserData(ctx, userID, email)
+}
+
+// EraseUserData borra sesiones, dispositivos, intentos de login y eventos del
+// usuario. Las sesiones borradas dejan de validar en el gateway de inmediato.
+func (s *SecurityService) EraseUserData(ctx context.Context, userID, email string) error {
+	if err := s.store.EraseUserData(ctx, userID, email); err != nil {
+		return fmt.Errorf("failed to erase user data: %w", err)
+	}
+	log.Printf("user_data_erased user_id=%s request_id=%s", userID, trace.RequestIDFromContext(ctx))
+	return nil
+}
+
+// StartSession registra la sesión que respalda un token (claim "sid").
+func (s *SecurityService) StartSession(ctx context.Context, userID, actorID string, expiresAt time.Time) (string, error) {
+	info := clientInfoFromContext(ctx)
+	session, err := s.store.CreateSession(ctx, model.Session{
+		UserID:    userID,
+		ActorID:   actorID,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	return session.ID, nil
+}
+
+// SessionActive indica si la sesión existe, es del usuario y no fue revocada ni expiró.
+func (s *SecurityService) SessionActive(ctx context.Context, sessionID, userID string) (bool, error) {
+	session, err := s.store.GetSession(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return session.UserID == userID && session.Active(s.now()), nil
+}
+
+func impossibleTravel(prev model.LoginAttempt, cur geoip.Location, now time.Time) bool {
+	if prev.Latitude == nil || prev.Longitude == nil {
+		return false
+	}
+	km := geoip.DistanceKm(geoip.Location{Latitude: *prev.Latitude, Longitude: *prev.Longitude}, cur)
+	if km < minTravelKm {
+		return false
+	}
+	hours := now.Sub(prev.CreatedAt).Hours()
+	if hours <= 0 {
+		return true
+	}
+	return km/hours > maxTravelSpeedKmh
+}
+
+var versionPattern = regexp.MustCompile(`\d+([._]\d+)*`)
+
+// DeviceFingerprint resume el user-agent sin números de versión, para que
+// una actualización del navegador no cuente como dispositivo nuevo.
+func DeviceFingerprint(userAgent string) string {
+	normalized := strings.ToLower(versionPattern.ReplaceAllString(userAgent, ""))
+	normalized = strings.Join(strings.Fields(normalized), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:8])
+}
+
+// IPPrefix agrupa IPs por red (/24 en IPv4, /48 en IPv6) para tolerar
+// asignaciones dinámicas del mismo proveedor.
+func IPPrefix(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
@@ -0,0 +1,504 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/libs/notify"
+	"saas-subscription-platform/services/auth-service/internal/client"
+	"saas-subscription-platform/services/auth-service/internal/geoip"
+	"saas-subscription-platform/services/auth-service/internal/model"
+	"saas-subscription-platform/services/auth-service/internal/repository"
+	"saas-subscription-platform/services/auth-service/internal/service/mocks"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memSecurityStore es un SecurityStore en memoria para probar la lógica de detección.
+type memSecurityStore struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	sessions map[string]model.Session
+	devices  map[model.Device]bool
+	attempts []model.LoginAttempt
+	events   map[string]model.SecurityEvent
+}
+
+func newMemSecurityStore(now func() time.Time) *memSecurityStore {
+	return &memSecurityStore{
+		now:      now,
+		sessions: make(map[string]model.Session),
+		devices:  make(map[model.Device]bool),
+		events:   make(map[string]model.SecurityEvent),
+	}
+}
+
+func (m *memSecurityStore) CreateSession(_ context.Context, s model.Session) (model.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s.ID = uuid.NewString()
+	s.CreatedAt = m.now()
+	m.sessions[s.ID] = s
+	return s, nil
+}
+
+func (m *memSecurityStore) GetSession(_ context.Context, id string) (model.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return model.Session{}, repository.ErrSessionNotFound
+	}
+	return s, nil
+}
+
+func (m *memSecurityStore) RevokeUserSessions(_ context.Context, userID string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	now := m.now()
+	for id, s := range m.sessions {
+		if s.UserID == userID && s.Active(now) {
+			s.RevokedAt = &now
+			m.sessions[id] = s
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memSecurityStore) HasDevices(_ context.Context, userID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for d := range m.devices {
+		if d.UserID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memSecurityStore) IsKnownDevice(_ context.Context, d model.Device) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.devices[d], nil
+}
+
+func (m *memSecurityStore) TouchDevice(_ context.Context, d model.Device) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.devices[d] = true
+	return nil
+}
+
+func (m *memSecurityStore) ForgetDevice(_ context.Context, d model.Device) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.devices, d)
+	return nil
+}
+
+func (m *memSecurityStore) RecordLoginAttempt(_ context.Context, a model.LoginAttempt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a.CreatedAt = m.now()
+	m.attempts = append(m.attempts, a)
+	return nil
+}
+
+func (m *memSecurityStore) LastLocatedLogin(_ context.Context, userID string) (model.LoginAttempt, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.attempts) - 1; i >= 0; i-- {
+		a := m.attempts[i]
+		if a.UserID == userID && a.Success && a.Latitude != nil {
+			return a, true, nil
+		}
+	}
+	return model.LoginAttempt{}, false, nil
+}
+
+func (m *memSecurityStore) CountFailuresSince(_ context.Context, email string, since time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, a := range m.attempts {
+		if a.Email == email && !a.Success && !a.CreatedAt.Before(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memSecurityStore) HasEventSince(_ context.Context, userID, eventType string, since time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, ev := range m.events {
+		if ev.UserID == userID && ev.Type == eventType && !ev.CreatedAt.Before(since) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memSecurityStore) CreateEvent(_ context.Context, ev model.SecurityEvent) (model.SecurityEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ev.ID = uuid.NewString()
+	ev.Status = model.EventPending
+	ev.CreatedAt = m.now()
+	m.events[ev.ID] = ev
+	return ev, nil
+}
+
+func (m *memSecurityStore) GetEvent(_ context.Context, id string) (model.SecurityEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ev, ok := m.events[id]
+	if !ok {
+		return model.SecurityEvent{}, repository.ErrEventNotFound
+	}
+	return ev, nil
+}
+
+func (m *memSecurityStore) ResolveEvent(_ context.Context, id, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ev, ok := m.events[id]
+	if !ok || ev.Status != model.EventPending {
+		return repository.ErrEventNotFound
+	}
+	now := m.now()
+	ev.Status = status
+	ev.ResolvedAt = &now
+	m.events[id] = ev
+	return nil
+}
+
+func (m *memSecurityStore) ExportUserData(_ context.Context, userID, email string) (model.UserData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data := model.UserData{}
+	for _, s := range m.sessions {
+		if s.UserID == userID {
+			data.Sessions = append(data.Sessions, s)
+		}
+	}
+	for d := range m.devices {
+		if d.UserID == userID {
+			data.Devices = append(data.Devices, d)
+		}
+	}
+	for _, a := range m.attempts {
+		if a.UserID == userID || a.Email == email {
+			data.LoginAttempts = append(data.LoginAttempts, a)
+		}
+	}
+	for _, ev := range m.events {
+		if ev.UserID == userID {
+			data.Events = append(data.Events, ev)
+		}
+	}
+	return data, nil
+}
+
+func (m *memSecurityStore) EraseUserData(_ context.Context, userID, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, s := range m.sessions {
+		if s.UserID == userID {
+			delete(m.sessions, id)
+		}
+	}
+	for d := range m.devices {
+		if d.UserID == userID {
+			delete(m.devices, d)
+		}
+	}
+	kept := m.attempts[:0]
+	for _, a := range m.attempts {
+		if a.UserID != userID && a.Email != email {
+			kept = append(kept, a)
+		}
+	}
+	m.attempts = kept
+	for id, ev := range m.events {
+		if ev.UserID == userID {
+			delete(m.events, id)
+		}
+	}
+	return nil
+}
+
+func (m *memSecurityStore) eventsOfType(typ string) []model.SecurityEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []model.SecurityEvent
+	for _, ev := range m.events {
+		if ev.Type == typ {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+type recordingSender struct {
+	messages []notify.Message
+}
+
+func (s *recordingSender) Send(_ context.Context, msg notify.Message) error {
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+const testGeoDB = `network,country,latitude,longitude
+203.0.113.0/24,AR,-34.60,-58.38
+198.51.100.0/24,ES,40.42,-3.70
+192.0.2.0/24,AR,-34.92,-57.95
+`
+
+const (
+	firefoxUA = "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0"
+	iphoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+)
+
+func newTestSecurity(t *testing.T) (*SecurityService, *memSecurityStore, *recordingSender, *time.Time) {
+	t.Helper()
+	geo, err := geoip.Load(strings.NewReader(testGeoDB))
+	require.NoError(t, err)
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	store := newMemSecurityStore(clock)
+	sender := &recordingSender{}
+	svc := NewSecurityService(store, geo, sender)
+	svc.now = clock
+	return svc, store, sender, &now
+}
+
+func from(ip, ua string) context.Context {
+	return WithClientInfo(context.Background(), ClientInfo{IP: ip, UserAgent: ua})
+}
+
+func TestSecurityService_FirstLoginBuildsProfile(t *testing.T) {
+	svc, store, sender, _ := newTestSecurity(t)
+
+	require.NoError(t, svc.RecordSuccessfulLogin(from("203.0.113.10", firefoxUA), "u-1", "alice@example.com"))
+
+	require.Empty(t, store.events)
+	require.Empty(t, sender.messages)
+	require.Len(t, store.devices, 1)
+}
+
+func TestSecurityService_NewDevice(t *testing.T) {
+	svc, store, sender, now := newTestSecurity(t)
+	require.NoError(t, svc.RecordSuccessfulLogin(from("203.0.113.10", firefoxUA), "u-1", "alice@example.com"))
+
+	// Mismo dispositivo tras actualizar el navegador y con otra IP del mismo /24: no alerta.
+	*now = now.Add(24 * time.Hour)
+	updatedUA := strings.ReplaceAll(firefoxUA, "128.0", "129.0")
+	require.NoError(t, svc.RecordSuccessfulLogin(from("203.0.113.77", updatedUA), "u-1", "alice@example.com"))
+	require.Empty(t, store.events)
+
+	*now = now.Add(time.Hour)
+	require.NoError(t, svc.RecordSuccessfulLogin(from("192.0.2.5", iphoneUA), "u-1", "alice@example.com"))
+
+	events := store.eventsOfType(model.EventNewDevice)
+	require.Len(t, events, 1)
+	require.Equal(t, "u-1", events[0].UserID)
+	require.Equal(t, "192.0.2.5", events[0].IP)
+	require.Equal(t, "AR", events[0].Country)
+	require.Empty(t, store.eventsOfType(model.EventImpossibleTravel))
+
+	require.Len(t, sender.messages, 1)
+	require.Equal(t, "alice@example.com", sender.messages[0].To)
+	require.Contains(t, sender.messages[0].Body, events[0].ID+"/deny")
+}
+
+func TestSecurityService_NotificationUsesPreferences(t *testing.T) {
+	svc, store, sender, _ := newTestSecurity(t)
+	require.NoError(t, svc.RecordSuccessfulLogin(from("203.0.113.10", firefoxUA), "u-1", "alice@example.com"))
+
+	ctx := WithPreferences(from("192.0.2.5", iphoneUA), Preferences{Locale: "es-AR", Timezone: "America/Argentina/Buenos_Aires"})
+	require.NoError(t, svc.RecordSuccessfulLogin(ctx, "u-1", "alice@example.com"))
+
+	events := store.eventsOfType(model.EventNewDevice)
+	require.Len(t, events, 1)
+	require.Len(t, sender.messages, 1)
+	// 12:00 UTC en Buenos Aires (UTC-3), con el orden día/mes del locale.
+	require.Contains(t, sender.messages[0].Body, "Time: 01/03/2025 09:00 -03")
+}
+
+func TestSecurityService_ImpossibleTravel(t *testing.T) {
+	svc, store, _, now := newTestSecurity(t)
+	require.NoError(t, svc.RecordSuccessfulLogin(from("203.0.113.10", firefoxUA), "u-1", "alice@example.com"))
+
+	// Buenos Aires -> Madrid (~10.000 km) en 2 horas.
+	*now = now.Add(2 * time.Hour)
+	require.NoError(t, svc.RecordSuccessfulLogin(from("198.51.100.20", firefoxUA), "u-1", "alice@example.com"))
+	require.Len(t, store.eventsOfType(model.EventImpossibleTravel), 1)
+
+	// Madrid -> Buenos Aires en 20 horas sí es posible.
+	*now = now.Add(20 * time.Hour)
+	require.NoError(t, svc.RecordSuccessfulLogin(from("203.0.113.10", firefoxUA), "u-1", "alice@example.com"))
+	require.Len(t, store.eventsOfType(model.EventImpossibleTravel), 1)
+}
+
+func TestSecurityService_FailureBurst(t *testing.T) {
+	svc, store, sender, now := newTestSecurity(t)
+	ctx := from("203.0.113.10", firefoxUA)
+
+	for i := 0; i < failureBurstThreshold+2; i++ {
+		require.NoError(t, svc.RecordFailedLogin(ctx, "u-1", "alice@example.com"))
+		*now = now.Add(time.Minute)
+	}
+	require.Len(t, store.eventsOfType(model.EventFailureBurst), 1)
+	require.Len(t, sender.messages, 1)
+
+	// Cuentas inexistentes no generan eventos.
+	for i := 0; i < failureBurstThreshold; i++ {
+		require.NoError(t, svc.RecordFailedLogin(ctx, "", "ghost@example.com"))
+	}
+	require.Len(t, store.eventsOfType(model.EventFailureBurst), 1)
+}
+
+func TestSecurityService_FailureBurstAboveThreshold(t *testing.T) {
+	svc, store, sender, now := newTestSecurity(t)
+	ctx := from("203.0.113.10", firefoxUA)
+
+	// Intentos concurrentes: el conteo pasa de 4 a 6 sin que ningún request vea 5.
+	for i := 0; i < failureBurstThreshold+1; i++ {
+		require.NoError(t, store.RecordLoginAttempt(ctx, model.LoginAttempt{UserID: "u-1", Email: "alice@example.com"}))
+	}
+	require.NoError(t, svc.RecordFailedLogin(ctx, "u-1", "alice@example.com"))
+	require.Len(t, store.eventsOfType(model.EventFailureBurst), 1)
+	require.Len(t, sender.messages, 1)
+
+	// Más fallos en la misma ventana no repiten el evento.
+	require.NoError(t, svc.RecordFailedLogin(ctx, "u-1", "alice@example.com"))
+	require.Len(t, store.eventsOfType(model.EventFailureBurst), 1)
+
+	// Pasada la ventana, una ráfaga nueva vuelve a alertar.
+	*now = now.Add(failureBurstWindow + time.Minute)
+	for i := 0; i < failureBurstThreshold; i++ {
+		require.NoError(t, svc.RecordFailedLogin(ctx, "u-1", "alice@example.com"))
+	}
+	require.Len(t, store.eventsOfType(model.EventFailureBurst), 2)
+}
+
+func TestSecurityService_DenyRevokesSessions(t *testing.T) {
+	svc, store, _, now := newTestSecurity(t)
+	require.NoError(t, svc.RecordSuccessfulLogin(from("203.0.113.10", firefoxUA), "u-1", "alice@example.com"))
+	legit, err := svc.StartSession(context.Background(), "u-1", "", now.Add(time.Hour))
+	require.NoError(t, err)
+
+	ctx := from("192.0.2.5", iphoneUA)
+	require.NoError(t, svc.RecordSuccessfulLogin(ctx, "u-1", "alice@example.com"))
+	suspicious, err := svc.StartSession(ctx, "u-1", "", now.Add(time.Hour))
+	require.NoError(t, err)
+	ev := store.eventsOfType(model.EventNewDevice)[0]
+
+	// Un evento ajeno no se puede resolver.
+	require.ErrorIs(t, svc.ResolveEvent(context.Background(), "u-2", ev.ID, false), ErrEventNotFound)
+
+	require.NoError(t, svc.ResolveEvent(context.Background(), "u-1", ev.ID, false))
+	require.ErrorIs(t, svc.ResolveEvent(context.Background(), "u-1", ev.ID, true), ErrEventResolved)
+
+	for _, sid := range []string{legit, suspicious} {
+		active, err := svc.SessionActive(context.Background(), sid, "u-1")
+		require.NoError(t, err)
+		require.False(t, active)
+	}
+
+	// El dispositivo negado sale del perfil: el próximo login desde ahí vuelve a alertar.
+	require.NoError(t, svc.RecordSuccessfulLogin(ctx, "u-1", "alice@example.com"))
+	require.Len(t, store.eventsOfType(model.EventNewDevice), 2)
+}
+
+func TestSecurityService_ConfirmKeepsSessions(t *testing.T) {
+	svc, store, _, now := newTestSecurity(t)
+	require.NoError(t, svc.RecordSuccessfulLogin(from("203.0.113.10", firefoxUA), "u-1", "alice@example.com"))
+	require.NoError(t, svc.RecordSuccessfulLogin(from("192.0.2.5", iphoneUA), "u-1", "alice@example.com"))
+	sid, err := svc.StartSession(context.Background(), "u-1", "", now.Add(time.Hour))
+	require.NoError(t, err)
+
+	ev := store.eventsOfType(model.EventNewDevice)[0]
+	require.NoError(t, svc.ResolveEvent(context.Background(), "u-1", ev.ID, true))
+
+	active, err := svc.SessionActive(context.Background(), sid, "u-1")
+	require.NoError(t, err)
+	require.True(t, active)
+
+	active, err = svc.SessionActive(context.Background(), sid, "u-2")
+	require.NoError(t, err)
+	require.False(t, active)
+}
+
+func TestSecurityService_ExportAndEraseUserData(t *testing.T) {
+	svc, store, _, _ := newTestSecurity(t)
+	require.NoError(t, svc.RecordSuccessfulLogin(from("203.0.113.10", firefoxUA), "u-1", "alice@example.com"))
+	require.NoError(t, svc.RecordFailedLogin(from("203.0.113.10", firefoxUA), "", "alice@example.com"))
+	_, err := svc.StartSession(context.Background(), "u-1", "", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, svc.RecordSuccessfulLogin(from("203.0.113.10", firefoxUA), "u-2", "bob@example.com"))
+
+	data, err := svc.ExportUserData(context.Background(), "u-1", "alice@example.com")
+	require.NoError(t, err)
+	require.Len(t, data.Sessions, 1)
+	require.Len(t, data.Devices, 1)
+	// Incluye el intento fallido que solo se registró con el email.
+	require.Len(t, data.LoginAttempts, 2)
+
+	require.NoError(t, svc.EraseUserData(context.Background(), "u-1", "alice@example.com"))
+	data, err = svc.ExportUserData(context.Background(), "u-1", "alice@example.com")
+	require.NoError(t, err)
+	require.Empty(t, data.Sessions)
+	require.Empty(t, data.Devices)
+	require.Empty(t, data.LoginAttempts)
+	// Los datos de otros usuarios no se tocan.
+	require.Len(t, store.attempts, 1)
+}
+
+func TestAuthService_LoginWithSecurity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUser := mocks.NewMockUserClient(ctrl)
+	security, store, _, _ := newTestSecurity(t)
+	svc := NewAuthService("secret", mockUser, WithSecurity(security))
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.DefaultCost)
+	user := client.GetUserByEmailResponse{ID: "u-1", Email: "alice@example.com", Password: string(hashed)}
+	mockUser.EXPECT().GetUserByEmailWithContext(gomock.Any(), "alice@example.com", gomock.Any()).Return(user, nil).Times(2)
+
+	ctx := from("203.0.113.10", firefoxUA)
+	_, err := svc.LoginWithContext(ctx, "alice@example.com", "wrong")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	signed, err := svc.LoginWithContext(ctx, "alice@example.com", "pass")
+	require.NoError(t, err)
+
+	token, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return []byte("secret"), nil })
+	require.NoError(t, err)
+	sid, _ := token.Claims.(jwt.MapClaims)["sid"].(string)
+	require.NotEmpty(t, sid)
+
+	active, err := security.SessionActive(context.Background(), sid, "u-1")
+	require.NoError(t, err)
+	require.True(t, active)
+	require.Len(t, store.attempts, 2)
+}
+
+func TestDeviceFingerprintAndIPPrefix(t *testing.T) {
+	require.Equal(t, DeviceFingerprint(firefoxUA), DeviceFingerprint(strings.ReplaceAll(firefoxUA, "128.0", "131.0")))
+	require.NotEqual(t, DeviceFingerprint(firefoxUA), DeviceFingerprint(iphoneUA))
+
+	require.Equal(t, "203.0.113.0/24", IPPrefix("203.0.113.99"))
+	require.Equal(t, "2001:db8:1::/48", IPPrefix("2001:db8:1:2::5"))
+	require.Equal(t, "", IPPrefix("bogus"))
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS security_events;
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS known_devices;
+DROP TABLE IF EXISTS sessions;
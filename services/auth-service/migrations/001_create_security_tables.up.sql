@@ -0,0 +1,59 @@
+-- Sesiones emitidas por auth-service (claim "sid" del JWT)
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    actor_id UUID,
+    ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id) WHERE revoked_at IS NULL;
+
+-- Dispositivos conocidos por usuario: fingerprint del user-agent + prefijo de IP
+CREATE TABLE IF NOT EXISTS known_devices (
+    user_id UUID NOT NULL,
+    fingerprint TEXT NOT NULL,
+    ip_prefix TEXT NOT NULL,
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, fingerprint, ip_prefix)
+);
+
+-- Intentos de login (exitosos y fallidos) con la ubicación GeoIP resuelta
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID,
+    email TEXT NOT NULL,
+    ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    success BOOLEAN NOT NULL,
+    country TEXT NOT NULL DEFAULT '',
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_email_created ON login_attempts (email, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_user_success ON login_attempts (user_id, created_at DESC) WHERE success;
+
+-- Eventos de seguridad que el usuario confirma o niega
+CREATE TABLE IF NOT EXISTS security_events (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    type TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    country TEXT NOT NULL DEFAULT '',
+    fingerprint TEXT NOT NULL DEFAULT '',
+    ip_prefix TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at TIMESTAMPTZ,
+    CONSTRAINT security_events_type_check CHECK (type IN ('new_device', 'impossible_travel', 'failure_burst')),
+    CONSTRAINT security_events_status_check CHECK (status IN ('pending', 'confirmed', 'denied'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_user ON security_events (user_id, created_at DESC);
@@ -0,0 +1,7 @@
+// Package migrations embebe el SQL versionado de auth-service (ver libs/migrate).
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
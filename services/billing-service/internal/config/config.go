@@ -1,16 +1,32 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"time"
+)
 
 type Config struct {
 	HTTPAddr string
 	DBDSN    string
+	// QueryTimeout corta cualquier query que tarde más, aunque el request siga vivo.
+	QueryTimeout time.Duration
+	// SlowQueryThreshold es a partir de cuánto se loguea una query como lenta.
+	SlowQueryThreshold time.Duration
+	// MigrateOnBoot aplica las migraciones pendientes al arrancar.
+	MigrateOnBoot bool
+	// RenewalInterval es cada cuánto el worker factura y renueva suscripciones.
+	RenewalInterval time.Duration
 }
 
 func Load() Config {
 	return Config{
-		HTTPAddr: getEnv("BILLING_HTTP_ADDR", ":8083"),
-		DBDSN:    getEnv("BILLING_DB_DSN", ""),
+		HTTPAddr:           getEnv("BILLING_HTTP_ADDR", ":8083"),
+		DBDSN:              getEnv("BILLING_DB_DSN", ""),
+		QueryTimeout:       getDuration("BILLING_DB_QUERY_TIMEOUT", 5*time.Second),
+		SlowQueryThreshold: getDuration("BILLING_DB_SLOW_QUERY_THRESHOLD", 500*time.Millisecond),
+		MigrateOnBoot:      getBool("BILLING_MIGRATE_ON_BOOT", false),
+		RenewalInterval:    getDuration("BILLING_RENEWAL_INTERVAL", time.Minute),
 	}
 }
 
@@ -20,3 +36,21 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return fallback
+}
+
+func getBool(key string, fallback bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
@@ -2,14 +2,52 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 
+	"saas-subscription-platform/libs/locale"
+	"saas-subscription-platform/libs/metadata"
+	"saas-subscription-platform/services/billing-service/internal/model"
 	"saas-subscription-platform/services/billing-service/internal/service"
 )
 
+// invoiceResponse agrega a la factura el monto y la fecha formateados con el
+// locale y la zona horaria del usuario que propaga el gateway.
+type invoiceResponse struct {
+	*model.Invoice
+	AmountFormatted    string `json:"amount_formatted"`
+	CreatedAtFormatted string `json:"created_at_formatted"`
+}
+
+func presentInvoice(r *http.Request, inv *model.Invoice) invoiceResponse {
+	loc := r.Header.Get(locale.HeaderLocale)
+	tz := r.Header.Get(locale.HeaderTimezone)
+	return invoiceResponse{
+		Invoice:            inv,
+		AmountFormatted:    locale.FormatMoney(inv.AmountCents, inv.Currency, loc),
+		CreatedAtFormatted: locale.FormatTime(inv.CreatedAt, tz, loc),
+	}
+}
+
+// requestScope lee el usuario y la organización activa de los headers internos del gateway.
+func requestScope(r *http.Request) service.Scope {
+	return service.Scope{
+		UserID:  r.Header.Get("X-Internal-User-ID"),
+		OrgID:   r.Header.Get("X-Internal-Org-ID"),
+		OrgRole: r.Header.Get("X-Internal-Org-Role"),
+		Role:    r.Header.Get("X-Internal-User-Role"),
+	}
+}
+
+func writeForbidden(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusForbidden)
+	_, _ = w.Write([]byte("organization role cannot access billing"))
+}
+
 type BillingHandler struct {
 	service *service.BillingService
 }
@@ -18,17 +56,24 @@ func NewBillingHandler(service *service.BillingService) *BillingHandler {
 	return &BillingHandler{service: service}
 }
 
+// CreateInvoice factura un monto libre (amount_cents + currency), un precio
+// del catálogo (price_id + quantity) o una lista de renglones (lines +
+// currency); solo una de las tres formas.
 func (h *BillingHandler) CreateInvoice(w http.ResponseWriter, r *http.Request) {
-	userID := r.Header.Get("X-Internal-User-ID")
-	if userID == "" {
+	scope := requestScope(r)
+	if scope.UserID == "" {
 		w.WriteHeader(http.StatusUnauthorized)
 		_, _ = w.Write([]byte("missing internal user id"))
 		return
 	}
 
 	var req struct {
-		AmountCents int64  `json:"amount_cents"`
-		Currency    string `json:"currency"`
+		AmountCents int64             `json:"amount_cents"`
+		Currency    string            `json:"currency"`
+		PriceID     *int              `json:"price_id"`
+		Quantity    int               `json:"quantity"`
+		Lines       []lineRequest     `json:"lines"`
+		Metadata    map[string]string `json:"metadata"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -36,7 +81,36 @@ func (h *BillingHandler) CreateInvoice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	invoice, err := h.service.CreateInvoice(userID, req.AmountCents, req.Currency)
+	var invoice *model.Invoice
+	var err error
+	switch {
+	case req.PriceID != nil && (req.AmountCents != 0 || req.Currency != ""):
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("use either price_id or amount_cents and currency"))
+		return
+	case req.PriceID == nil && req.Quantity != 0:
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("quantity requires price_id"))
+		return
+	case req.Lines != nil && (req.PriceID != nil || req.AmountCents != 0):
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("lines cannot be combined with price_id or amount_cents"))
+		return
+	case req.Lines != nil:
+		lines := make([]model.InvoiceLineItem, len(req.Lines))
+		for i, l := range req.Lines {
+			lines[i] = l.lineItem()
+		}
+		invoice, err = h.service.CreateInvoiceWithLines(r.Context(), scope, req.Currency, lines, req.Metadata)
+	case req.PriceID != nil:
+		invoice, err = h.service.CreateInvoiceFromPrice(r.Context(), scope, *req.PriceID, req.Quantity, req.Metadata)
+	default:
+		invoice, err = h.service.CreateInvoice(r.Context(), scope, req.AmountCents, req.Currency, req.Metadata)
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		writeForbidden(w)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		_, _ = w.Write([]byte(err.Error()))
@@ -44,12 +118,38 @@ func (h *BillingHandler) CreateInvoice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.WriteHeader(http.StatusCreated)
-	_ = json.NewEncoder(w).Encode(invoice)
+	_ = json.NewEncoder(w).Encode(presentInvoice(r, invoice))
+}
+
+// lineRequest es un renglón del body de CreateInvoice; los importes los
+// calcula el servicio.
+type lineRequest struct {
+	Description         string     `json:"description"`
+	PriceID             *int       `json:"price_id"`
+	Quantity            int        `json:"quantity"`
+	UnitAmountCents     int64      `json:"unit_amount_cents"`
+	DiscountAmountCents int64      `json:"discount_amount_cents"`
+	TaxAmountCents      int64      `json:"tax_amount_cents"`
+	PeriodStart         *time.Time `json:"period_start"`
+	PeriodEnd           *time.Time `json:"period_end"`
+}
+
+func (l lineRequest) lineItem() model.InvoiceLineItem {
+	return model.InvoiceLineItem{
+		Description:         l.Description,
+		PriceID:             l.PriceID,
+		Quantity:            l.Quantity,
+		UnitAmountCents:     l.UnitAmountCents,
+		DiscountAmountCents: l.DiscountAmountCents,
+		TaxAmountCents:      l.TaxAmountCents,
+		PeriodStart:         l.PeriodStart,
+		PeriodEnd:           l.PeriodEnd,
+	}
 }
 
 func (h *BillingHandler) GetInvoices(w http.ResponseWriter, r *http.Request) {
-	userID := r.Header.Get("X-Internal-User-ID")
-	if userID == "" {
+	scope := requestScope(r)
+	if scope.UserID == "" {
 		w.WriteHeader(http.StatusUnauthorized)
 		_, _ = w.Write([]byte("missing internal user id"))
 		return
@@ -58,21 +158,36 @@ func (h *BillingHandler) GetInvoices(w http.ResponseWriter, r *http.Request) {
 	status := r.URL.Query().Get("status")
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	meta, err := metadata.ParseFilter(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
 
-	invoices, err := h.service.ListInvoices(userID, status, limit, offset)
+	invoices, err := h.service.ListInvoices(r.Context(), scope, status, meta, limit, offset)
+	if errors.Is(err, service.ErrForbidden) {
+		writeForbidden(w)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		_, _ = w.Write([]byte("failed to fetch invoices"))
 		return
 	}
 
+	resp := make([]invoiceResponse, 0, len(invoices))
+	for _, inv := range invoices {
+		resp = append(resp, presentInvoice(r, inv))
+	}
+
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(invoices)
+	_ = json.NewEncoder(w).Encode(resp)
 }
 
 func (h *BillingHandler) GetInvoiceByID(w http.ResponseWriter, r *http.Request) {
-	userID := r.Header.Get("X-Internal-User-ID")
-	if userID == "" {
+	scope := requestScope(r)
+	if scope.UserID == "" {
 		w.WriteHeader(http.StatusUnauthorized)
 		_, _ = w.Write([]byte("missing internal user id"))
 		return
@@ -87,7 +202,11 @@ func (h *BillingHandler) GetInvoiceByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	invoice, err := h.service.GetInvoiceByID(userID, invoiceID)
+	invoice, err := h.service.GetInvoiceByID(r.Context(), scope, invoiceID)
+	if errors.Is(err, service.ErrForbidden) {
+		writeForbidden(w)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		_, _ = w.Write([]byte("failed to fetch invoice"))
@@ -100,5 +219,73 @@ func (h *BillingHandler) GetInvoiceByID(w http.ResponseWriter, r *http.Request)
 	}
 
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(invoice)
+	_ = json.NewEncoder(w).Encode(presentInvoice(r, invoice))
+}
+
+// UpdateInvoice modifica la metadata de la factura y, si es un borrador, sus
+// renglones. Body: {"metadata": {...}, "lines": [...]}; un valor "" de
+// metadata borra la clave y lines reemplaza todos los renglones.
+func (h *BillingHandler) UpdateInvoice(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+
+	invoiceID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid invoice id"))
+		return
+	}
+
+	var req struct {
+		Metadata map[string]string `json:"metadata"`
+		Lines    []lineRequest     `json:"lines"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Metadata == nil && req.Lines == nil) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+
+	var invoice *model.Invoice
+	if req.Lines != nil {
+		lines := make([]model.InvoiceLineItem, len(req.Lines))
+		for i, l := range req.Lines {
+			lines[i] = l.lineItem()
+		}
+		invoice, err = h.service.UpdateInvoiceLines(r.Context(), scope, invoiceID, lines)
+	}
+	if err == nil && req.Metadata != nil && (req.Lines == nil || invoice != nil) {
+		invoice, err = h.service.UpdateInvoiceMetadata(r.Context(), scope, invoiceID, req.Metadata)
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		writeForbidden(w)
+		return
+	}
+	if errors.Is(err, metadata.ErrInvalid) || errors.Is(err, service.ErrInvalidLines) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	if errors.Is(err, service.ErrInvoiceNotDraft) {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("failed to update invoice"))
+		return
+	}
+	if invoice == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("invoice not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(presentInvoice(r, invoice))
 }
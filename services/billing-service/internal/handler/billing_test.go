@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -19,31 +20,76 @@ import (
 
 type stubInvoiceStore struct {
 	createFn func(inv *model.Invoice) error
-	getByID  func(userID string, id int) (*model.Invoice, error)
+	getByID  func(scope repository.InvoiceScope, id int) (*model.Invoice, error)
 	listFn   func(filter repository.InvoiceFilter) ([]*model.Invoice, error)
+	exportFn func(userID string) ([]*model.Invoice, error)
+	pseudoFn func(userID, pseudonym string) (int64, error)
+	updateFn func(scope repository.InvoiceScope, id int, patch map[string]string) (*model.Invoice, error)
+	moveFn   func(id int, from []string, status string) (*model.Invoice, error)
+	// moveScopeFn, si está, recibe el scope de cada transición.
+	moveScopeFn func(scope repository.InvoiceScope)
+	linesFn     func(inv *model.Invoice) (*model.Invoice, error)
 }
 
-func (s stubInvoiceStore) CreateInvoice(inv *model.Invoice) error {
+func (s stubInvoiceStore) TransitionInvoice(_ context.Context, scope repository.InvoiceScope, id int, from []string, status string, _ time.Time) (*model.Invoice, error) {
+	if s.moveScopeFn != nil {
+		s.moveScopeFn(scope)
+	}
+	if s.moveFn == nil {
+		return nil, nil
+	}
+	return s.moveFn(id, from, status)
+}
+
+func (s stubInvoiceStore) ReplaceInvoiceLines(_ context.Context, _ repository.InvoiceScope, inv *model.Invoice) (*model.Invoice, error) {
+	if s.linesFn == nil {
+		return nil, nil
+	}
+	return s.linesFn(inv)
+}
+
+func (s stubInvoiceStore) CreateInvoice(_ context.Context, inv *model.Invoice) error {
 	if s.createFn == nil {
 		return nil
 	}
 	return s.createFn(inv)
 }
 
-func (s stubInvoiceStore) GetInvoiceByID(userID string, id int) (*model.Invoice, error) {
+func (s stubInvoiceStore) GetInvoiceByID(_ context.Context, scope repository.InvoiceScope, id int) (*model.Invoice, error) {
 	if s.getByID == nil {
 		return nil, nil
 	}
-	return s.getByID(userID, id)
+	return s.getByID(scope, id)
 }
 
-func (s stubInvoiceStore) GetInvoices(filter repository.InvoiceFilter) ([]*model.Invoice, error) {
+func (s stubInvoiceStore) GetInvoices(_ context.Context, filter repository.InvoiceFilter) ([]*model.Invoice, error) {
 	if s.listFn == nil {
 		return nil, nil
 	}
 	return s.listFn(filter)
 }
 
+func (s stubInvoiceStore) UpdateInvoiceMetadata(_ context.Context, scope repository.InvoiceScope, id int, patch map[string]string) (*model.Invoice, error) {
+	if s.updateFn == nil {
+		return nil, nil
+	}
+	return s.updateFn(scope, id, patch)
+}
+
+func (s stubInvoiceStore) ExportInvoices(_ context.Context, userID string) ([]*model.Invoice, error) {
+	if s.exportFn == nil {
+		return nil, nil
+	}
+	return s.exportFn(userID)
+}
+
+func (s stubInvoiceStore) PseudonymizeUser(_ context.Context, userID, pseudonym string) (int64, error) {
+	if s.pseudoFn == nil {
+		return 0, nil
+	}
+	return s.pseudoFn(userID, pseudonym)
+}
+
 func newHandler(store service.InvoiceStore) *BillingHandler {
 	svc := service.NewBillingService(store)
 	return NewBillingHandler(svc)
@@ -57,7 +103,7 @@ func TestCreateInvoiceHandler_Success(t *testing.T) {
 		},
 	})
 
-	body := bytes.NewBufferString(`{"amount_cents":1500,"currency":"USD"}`)
+	body := bytes.NewBufferString(`{"amount_cents":1500,"currency":"USD","metadata":{"crm_id":"C-42"}}`)
 	req := httptest.NewRequest(http.MethodPost, "/invoices", body)
 	req.Header.Set("X-Internal-User-ID", "user-1")
 	rr := httptest.NewRecorder()
@@ -70,6 +116,40 @@ func TestCreateInvoiceHandler_Success(t *testing.T) {
 	require.Equal(t, 1, resp.ID)
 	require.Equal(t, "user-1", resp.UserID)
 	require.Equal(t, int64(1500), resp.AmountCents)
+	require.Equal(t, map[string]string{"crm_id": "C-42"}, resp.Metadata)
+}
+
+func TestCreateInvoiceHandler_Lines(t *testing.T) {
+	h := newHandler(stubInvoiceStore{
+		createFn: func(inv *model.Invoice) error {
+			inv.ID = 1
+			return nil
+		},
+	})
+
+	body := bytes.NewBufferString(`{"currency":"USD","lines":[{"description":"Setup","unit_amount_cents":5000,"quantity":2,"tax_amount_cents":1050},{"description":"Support","unit_amount_cents":2000,"discount_amount_cents":500}]}`)
+	req := httptest.NewRequest(http.MethodPost, "/invoices", body)
+	req.Header.Set("X-Internal-User-ID", "user-1")
+	rr := httptest.NewRecorder()
+
+	h.CreateInvoice(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var resp model.Invoice
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Len(t, resp.Lines, 2)
+	require.Equal(t, int64(10000), resp.Lines[0].AmountCents)
+	require.Equal(t, int64(12000), resp.SubtotalCents)
+	require.Equal(t, int64(500), resp.TotalDiscountCents)
+	require.Equal(t, int64(1050), resp.TotalTaxCents)
+	require.Equal(t, int64(12550), resp.TotalCents)
+	require.Equal(t, int64(12550), resp.AmountDueCents)
+
+	req = httptest.NewRequest(http.MethodPost, "/invoices", bytes.NewBufferString(`{"amount_cents":100,"lines":[]}`))
+	req.Header.Set("X-Internal-User-ID", "user-1")
+	rr = httptest.NewRecorder()
+	h.CreateInvoice(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
 }
 
 func TestCreateInvoiceHandler_Invalid(t *testing.T) {
@@ -128,6 +208,89 @@ func TestGetInvoicesHandler_Success(t *testing.T) {
 	require.Equal(t, 1, resp[0].ID)
 }
 
+func TestGetInvoicesHandler_MetadataFilter(t *testing.T) {
+	var got repository.InvoiceFilter
+	h := newHandler(stubInvoiceStore{
+		listFn: func(filter repository.InvoiceFilter) ([]*model.Invoice, error) {
+			got = filter
+			return nil, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/invoices?metadata%5Bcrm_id%5D=C-42", nil)
+	req.Header.Set("X-Internal-User-ID", "user-1")
+	rr := httptest.NewRecorder()
+	h.GetInvoices(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, map[string]string{"crm_id": "C-42"}, got.Metadata)
+
+	req = httptest.NewRequest(http.MethodGet, "/invoices?metadata%5Bcrm_id%5D=", nil)
+	req.Header.Set("X-Internal-User-ID", "user-1")
+	rr = httptest.NewRecorder()
+	h.GetInvoices(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestUpdateInvoiceHandler_Metadata(t *testing.T) {
+	h := newHandler(stubInvoiceStore{
+		updateFn: func(scope repository.InvoiceScope, id int, patch map[string]string) (*model.Invoice, error) {
+			if id != 1 {
+				return nil, nil
+			}
+			require.Equal(t, map[string]string{"crm_id": "C-43", "legacy": ""}, patch)
+			return &model.Invoice{ID: 1, UserID: scope.UserID, Metadata: map[string]string{"crm_id": "C-43"}}, nil
+		},
+	})
+
+	patch := func(id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/invoices/"+id, bytes.NewBufferString(body))
+		req.Header.Set("X-Internal-User-ID", "user-1")
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		rr := httptest.NewRecorder()
+		h.UpdateInvoice(rr, req)
+		return rr
+	}
+
+	rr := patch("1", `{"metadata":{"crm_id":"C-43","legacy":""}}`)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp model.Invoice
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, map[string]string{"crm_id": "C-43"}, resp.Metadata)
+
+	require.Equal(t, http.StatusNotFound, patch("2", `{"metadata":{"crm_id":"C-43"}}`).Code)
+	require.Equal(t, http.StatusBadRequest, patch("1", `{}`).Code)
+	require.Equal(t, http.StatusBadRequest, patch("1", `{"metadata":{"a[b]":"x"}}`).Code)
+}
+
+func TestGetInvoicesHandler_FormatsWithUserPreferences(t *testing.T) {
+	created := time.Date(2026, 3, 4, 18, 30, 0, 0, time.UTC)
+	h := newHandler(stubInvoiceStore{
+		listFn: func(filter repository.InvoiceFilter) ([]*model.Invoice, error) {
+			return []*model.Invoice{{ID: 1, UserID: filter.UserID, AmountCents: 123450, Currency: "EUR", CreatedAt: created}}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/invoices", nil)
+	req.Header.Set("X-Internal-User-ID", "user-1")
+	req.Header.Set("X-Internal-User-Locale", "de-DE")
+	req.Header.Set("X-Internal-User-Timezone", "Europe/Berlin")
+	rr := httptest.NewRecorder()
+
+	h.GetInvoices(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp []struct {
+		AmountCents        int64  `json:"amount_cents"`
+		AmountFormatted    string `json:"amount_formatted"`
+		CreatedAtFormatted string `json:"created_at_formatted"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Len(t, resp, 1)
+	require.Equal(t, int64(123450), resp[0].AmountCents)
+	require.Equal(t, "€ 1.234,50", resp[0].AmountFormatted)
+	require.Equal(t, "04.03.2026 19:30 CET", resp[0].CreatedAtFormatted)
+}
+
 func TestGetInvoicesHandler_Error(t *testing.T) {
 	h := newHandler(stubInvoiceStore{
 		listFn: func(filter repository.InvoiceFilter) ([]*model.Invoice, error) {
@@ -146,8 +309,9 @@ func TestGetInvoicesHandler_Error(t *testing.T) {
 
 func TestGetInvoiceByIDHandler_Success(t *testing.T) {
 	h := newHandler(stubInvoiceStore{
-		getByID: func(userID string, id int) (*model.Invoice, error) {
-			return &model.Invoice{ID: id, UserID: userID}, nil
+		getByID: func(scope repository.InvoiceScope, id int) (*model.Invoice, error) {
+			return &model.Invoice{ID: id, UserID: scope.UserID, SubtotalCents: 1500,
+				Lines: []model.InvoiceLineItem{{ID: 9, Description: "Seats", Quantity: 1, UnitAmountCents: 1500, AmountCents: 1500}}}, nil
 		},
 	})
 
@@ -162,6 +326,9 @@ func TestGetInvoiceByIDHandler_Success(t *testing.T) {
 	var resp model.Invoice
 	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
 	require.Equal(t, 1, resp.ID)
+	require.Equal(t, int64(1500), resp.SubtotalCents)
+	require.Len(t, resp.Lines, 1)
+	require.Equal(t, "Seats", resp.Lines[0].Description)
 }
 
 func TestGetInvoiceByIDHandler_InvalidID(t *testing.T) {
@@ -179,7 +346,7 @@ func TestGetInvoiceByIDHandler_InvalidID(t *testing.T) {
 
 func TestGetInvoiceByIDHandler_NotFound(t *testing.T) {
 	h := newHandler(stubInvoiceStore{
-		getByID: func(userID string, id int) (*model.Invoice, error) { return nil, nil },
+		getByID: func(scope repository.InvoiceScope, id int) (*model.Invoice, error) { return nil, nil },
 	})
 
 	req := httptest.NewRequest(http.MethodGet, "/invoices/99", nil)
@@ -194,7 +361,9 @@ func TestGetInvoiceByIDHandler_NotFound(t *testing.T) {
 
 func TestGetInvoiceByIDHandler_Error(t *testing.T) {
 	h := newHandler(stubInvoiceStore{
-		getByID: func(userID string, id int) (*model.Invoice, error) { return nil, errors.New("db error") },
+		getByID: func(scope repository.InvoiceScope, id int) (*model.Invoice, error) {
+			return nil, errors.New("db error")
+		},
 	})
 
 	req := httptest.NewRequest(http.MethodGet, "/invoices/1", nil)
@@ -217,3 +386,67 @@ func TestGetInvoiceByIDHandler_MissingUser(t *testing.T) {
 
 	require.Equal(t, http.StatusUnauthorized, rr.Code)
 }
+
+func TestGetInvoicesHandler_OrgScope(t *testing.T) {
+	var got repository.InvoiceFilter
+	h := newHandler(stubInvoiceStore{
+		listFn: func(filter repository.InvoiceFilter) ([]*model.Invoice, error) {
+			got = filter
+			return nil, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/invoices", nil)
+	req.Header.Set("X-Internal-User-ID", "user-1")
+	req.Header.Set("X-Internal-Org-ID", "org-1")
+	req.Header.Set("X-Internal-Org-Role", "billing")
+	rr := httptest.NewRecorder()
+
+	h.GetInvoices(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "org-1", got.OrgID)
+
+	// Un member de la organización no ve la facturación.
+	req.Header.Set("X-Internal-Org-Role", "member")
+	rr = httptest.NewRecorder()
+
+	h.GetInvoices(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestEraseUserHandler_Pseudonymizes(t *testing.T) {
+	var gotUser, gotPseudonym string
+	h := newHandler(stubInvoiceStore{
+		pseudoFn: func(userID, pseudonym string) (int64, error) {
+			gotUser, gotPseudonym = userID, pseudonym
+			return 3, nil
+		},
+	})
+
+	body := bytes.NewBufferString(`{"pseudonym":"6f1c2a9e-3b4d-8e5f-9a0b-1c2d3e4f5a6b"}`)
+	req := httptest.NewRequest(http.MethodPost, "/internal/users/user-1/erase", body)
+	req.Header.Set("X-Internal-User-ID", "user-service")
+	req = mux.SetURLVars(req, map[string]string{"id": "user-1"})
+	rr := httptest.NewRecorder()
+
+	h.EraseUser(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "user-1", gotUser)
+	require.Equal(t, "6f1c2a9e-3b4d-8e5f-9a0b-1c2d3e4f5a6b", gotPseudonym)
+	require.JSONEq(t, `{"invoices_pseudonymized":3}`, rr.Body.String())
+}
+
+func TestEraseUserHandler_InvalidPseudonym(t *testing.T) {
+	h := newHandler(stubInvoiceStore{})
+
+	req := httptest.NewRequest(http.MethodPost, "/internal/users/user-1/erase", bytes.NewBufferString(`{"pseudonym":"jane"}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "user-1"})
+	rr := httptest.NewRecorder()
+
+	h.EraseUser(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
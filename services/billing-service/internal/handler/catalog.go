@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+	"saas-subscription-platform/services/billing-service/internal/service"
+)
+
+// CatalogHandler expone productos y precios. Cualquier usuario los lee; las
+// escrituras se restringen a admins en el router.
+type CatalogHandler struct {
+	service *service.CatalogService
+}
+
+func NewCatalogHandler(service *service.CatalogService) *CatalogHandler {
+	return &CatalogHandler{service: service}
+}
+
+// pathID lee el {id} de la ruta; si no es numérico responde 400 y devuelve false.
+func pathID(w http.ResponseWriter, r *http.Request, what string) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid " + what + " id"))
+		return 0, false
+	}
+	return id, true
+}
+
+// activeParam lee ?active=true|false; ausente no filtra.
+func activeParam(r *http.Request) (*bool, error) {
+	raw := r.URL.Query().Get("active")
+	if raw == "" {
+		return nil, nil
+	}
+	active, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, errors.New("active must be true or false")
+	}
+	return &active, nil
+}
+
+// writeCatalogError traduce los errores del catálogo; los desconocidos son 500 con fallback.
+func writeCatalogError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCatalog), errors.Is(err, service.ErrProductNotFound):
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+	case errors.Is(err, service.ErrProductHasPrices), errors.Is(err, service.ErrPriceInUse), errors.Is(err, service.ErrLookupKeyTaken):
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(err.Error()))
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(fallback))
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *CatalogHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+
+	product, err := h.service.CreateProduct(r.Context(), req.Name, req.Description)
+	if err != nil {
+		writeCatalogError(w, err, "failed to create product")
+		return
+	}
+	writeJSON(w, http.StatusCreated, product)
+}
+
+func (h *CatalogHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
+	active, err := activeParam(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	products, err := h.service.ListProducts(r.Context(), active, limit, offset)
+	if err != nil {
+		writeCatalogError(w, err, "failed to fetch products")
+		return
+	}
+	writeJSON(w, http.StatusOK, products)
+}
+
+func (h *CatalogHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "product")
+	if !ok {
+		return
+	}
+
+	product, err := h.service.GetProduct(r.Context(), id)
+	if err != nil {
+		writeCatalogError(w, err, "failed to fetch product")
+		return
+	}
+	if product == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("product not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, product)
+}
+
+// UpdateProduct cambia nombre, descripción o active (archivar = active false).
+func (h *CatalogHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "product")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		Active      *bool   `json:"active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+
+	product, err := h.service.UpdateProduct(r.Context(), id, model.ProductUpdate{
+		Name:        req.Name,
+		Description: req.Description,
+		Active:      req.Active,
+	})
+	if err != nil {
+		writeCatalogError(w, err, "failed to update product")
+		return
+	}
+	if product == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("product not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, product)
+}
+
+// DeleteProduct borra un producto sin precios; con precios responde 409 y hay
+// que archivarlo.
+func (h *CatalogHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "product")
+	if !ok {
+		return
+	}
+
+	found, err := h.service.DeleteProduct(r.Context(), id)
+	if err != nil {
+		writeCatalogError(w, err, "failed to delete product")
+		return
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("product not found"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreatePrice crea un precio. Uno escalonado lleva, en lugar de
+// unit_amount_cents: {"billing_scheme": "tiered", "tiers_mode": "graduated",
+// "tiers": [{"up_to": 1000, "unit_amount_cents": 2}, {"up_to": null,
+// "unit_amount_cents": 1, "flat_amount_cents": 500}]}.
+func (h *CatalogHandler) CreatePrice(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ProductID       int               `json:"product_id"`
+		Currency        string            `json:"currency"`
+		UnitAmountCents int64             `json:"unit_amount_cents"`
+		Interval        string            `json:"interval"`
+		IntervalCount   int               `json:"interval_count"`
+		UsageType       string            `json:"usage_type"`
+		AggregateUsage  *string           `json:"aggregate_usage"`
+		BillingScheme   string            `json:"billing_scheme"`
+		PackageSize     *int64            `json:"package_size"`
+		TiersMode       *string           `json:"tiers_mode"`
+		Tiers           []model.PriceTier `json:"tiers"`
+		LookupKey       *string           `json:"lookup_key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+
+	price, err := h.service.CreatePrice(r.Context(), model.Price{
+		ProductID:       req.ProductID,
+		Currency:        req.Currency,
+		UnitAmountCents: req.UnitAmountCents,
+		Interval:        req.Interval,
+		IntervalCount:   req.IntervalCount,
+		UsageType:       req.UsageType,
+		AggregateUsage:  req.AggregateUsage,
+		BillingScheme:   req.BillingScheme,
+		PackageSize:     req.PackageSize,
+		TiersMode:       req.TiersMode,
+		Tiers:           req.Tiers,
+		LookupKey:       req.LookupKey,
+	})
+	if err != nil {
+		writeCatalogError(w, err, "failed to create price")
+		return
+	}
+	writeJSON(w, http.StatusCreated, price)
+}
+
+// ListPrices filtra por ?product_id=, ?active= y uno o más ?lookup_key=.
+func (h *CatalogHandler) ListPrices(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	active, err := activeParam(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	filter := repository.PriceFilter{Active: active, LookupKeys: q["lookup_key"]}
+	if raw := q.Get("product_id"); raw != "" {
+		if filter.ProductID, err = strconv.Atoi(raw); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("invalid product id"))
+			return
+		}
+	}
+	filter.Limit, _ = strconv.Atoi(q.Get("limit"))
+	filter.Offset, _ = strconv.Atoi(q.Get("offset"))
+
+	prices, err := h.service.ListPrices(r.Context(), filter)
+	if err != nil {
+		writeCatalogError(w, err, "failed to fetch prices")
+		return
+	}
+	writeJSON(w, http.StatusOK, prices)
+}
+
+func (h *CatalogHandler) GetPrice(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "price")
+	if !ok {
+		return
+	}
+
+	price, err := h.service.GetPrice(r.Context(), id)
+	if err != nil {
+		writeCatalogError(w, err, "failed to fetch price")
+		return
+	}
+	if price == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("price not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, price)
+}
+
+// UpdatePrice cambia active y lookup_key en cualquier momento; monto, moneda e
+// intervalo solo mientras el precio no se usó (409 si no).
+func (h *CatalogHandler) UpdatePrice(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "price")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Active          *bool   `json:"active"`
+		LookupKey       *string `json:"lookup_key"`
+		UnitAmountCents *int64  `json:"unit_amount_cents"`
+		Currency        *string `json:"currency"`
+		Interval        *string `json:"interval"`
+		IntervalCount   *int    `json:"interval_count"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+
+	price, err := h.service.UpdatePrice(r.Context(), id, model.PriceUpdate{
+		Active:          req.Active,
+		LookupKey:       req.LookupKey,
+		UnitAmountCents: req.UnitAmountCents,
+		Currency:        req.Currency,
+		Interval:        req.Interval,
+		IntervalCount:   req.IntervalCount,
+	})
+	if err != nil {
+		writeCatalogError(w, err, "failed to update price")
+		return
+	}
+	if price == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("price not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, price)
+}
+
+// ArchivePrice es el DELETE de un precio: los precios no se borran, se
+// desactivan para que las facturas sigan apuntando a ellos.
+func (h *CatalogHandler) ArchivePrice(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "price")
+	if !ok {
+		return
+	}
+
+	price, err := h.service.ArchivePrice(r.Context(), id)
+	if err != nil {
+		writeCatalogError(w, err, "failed to archive price")
+		return
+	}
+	if price == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("price not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, price)
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+	"saas-subscription-platform/services/billing-service/internal/service"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+type stubCatalogStore struct {
+	service.CatalogStore
+	createPriceFn func(p *model.Price) error
+	getPriceFn    func(id int) (*model.Price, error)
+	listPricesFn  func(filter repository.PriceFilter) ([]*model.Price, error)
+	updatePriceFn func(id int, u model.PriceUpdate) (*model.Price, error)
+	deleteProdFn  func(id int) (bool, error)
+}
+
+func (s stubCatalogStore) CreatePrice(_ context.Context, p *model.Price) error {
+	return s.createPriceFn(p)
+}
+
+func (s stubCatalogStore) GetPrice(_ context.Context, id int) (*model.Price, error) {
+	return s.getPriceFn(id)
+}
+
+func (s stubCatalogStore) ListPrices(_ context.Context, filter repository.PriceFilter) ([]*model.Price, error) {
+	return s.listPricesFn(filter)
+}
+
+func (s stubCatalogStore) UpdatePrice(_ context.Context, id int, u model.PriceUpdate) (*model.Price, error) {
+	return s.updatePriceFn(id, u)
+}
+
+func (s stubCatalogStore) DeleteProduct(_ context.Context, id int) (bool, error) {
+	return s.deleteProdFn(id)
+}
+
+func newCatalogHandler(store service.CatalogStore) *CatalogHandler {
+	return NewCatalogHandler(service.NewCatalogService(store))
+}
+
+func TestCreatePriceHandler_Success(t *testing.T) {
+	h := newCatalogHandler(stubCatalogStore{
+		createPriceFn: func(p *model.Price) error {
+			p.ID = 7
+			return nil
+		},
+	})
+
+	body := bytes.NewBufferString(`{"product_id":1,"currency":"usd","unit_amount_cents":1500,"interval":"month","lookup_key":"pro_monthly"}`)
+	req := httptest.NewRequest(http.MethodPost, "/prices", body)
+	rr := httptest.NewRecorder()
+
+	h.CreatePrice(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var resp model.Price
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, 7, resp.ID)
+	require.Equal(t, "USD", resp.Currency)
+	require.Equal(t, 1, resp.IntervalCount)
+	require.Equal(t, "pro_monthly", *resp.LookupKey)
+	require.True(t, resp.Active)
+}
+
+func TestCreatePriceHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		storeErr error
+		want     int
+	}{
+		{name: "invalid interval", body: `{"product_id":1,"currency":"USD","interval":"quarter"}`, want: http.StatusBadRequest},
+		{name: "unknown product", body: `{"product_id":9,"currency":"USD","interval":"month"}`, storeErr: service.ErrProductNotFound, want: http.StatusBadRequest},
+		{name: "lookup key taken", body: `{"product_id":1,"currency":"USD","interval":"month","lookup_key":"pro"}`, storeErr: service.ErrLookupKeyTaken, want: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newCatalogHandler(stubCatalogStore{
+				createPriceFn: func(*model.Price) error { return tt.storeErr },
+			})
+			req := httptest.NewRequest(http.MethodPost, "/prices", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			h.CreatePrice(rr, req)
+
+			require.Equal(t, tt.want, rr.Code)
+		})
+	}
+}
+
+func TestUpdatePriceHandler_InUse(t *testing.T) {
+	h := newCatalogHandler(stubCatalogStore{
+		getPriceFn: func(id int) (*model.Price, error) {
+			return &model.Price{ID: id, Currency: "USD", UnitAmountCents: 1500, Interval: model.IntervalMonth, IntervalCount: 1}, nil
+		},
+		updatePriceFn: func(int, model.PriceUpdate) (*model.Price, error) {
+			return nil, service.ErrPriceInUse
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPatch, "/prices/7", bytes.NewBufferString(`{"unit_amount_cents":2000}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "7"})
+	rr := httptest.NewRecorder()
+
+	h.UpdatePrice(rr, req)
+
+	require.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestArchivePriceHandler(t *testing.T) {
+	h := newCatalogHandler(stubCatalogStore{
+		updatePriceFn: func(id int, u model.PriceUpdate) (*model.Price, error) {
+			return &model.Price{ID: id, Active: *u.Active}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodDelete, "/prices/7", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "7"})
+	rr := httptest.NewRecorder()
+
+	h.ArchivePrice(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp model.Price
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.False(t, resp.Active)
+}
+
+func TestListPricesHandler_Filters(t *testing.T) {
+	var got repository.PriceFilter
+	h := newCatalogHandler(stubCatalogStore{
+		listPricesFn: func(filter repository.PriceFilter) ([]*model.Price, error) {
+			got = filter
+			return []*model.Price{}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/prices?product_id=1&active=true&lookup_key=a&lookup_key=b", nil)
+	rr := httptest.NewRecorder()
+
+	h.ListPrices(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, 1, got.ProductID)
+	require.True(t, *got.Active)
+	require.Equal(t, []string{"a", "b"}, got.LookupKeys)
+}
+
+func TestDeleteProductHandler_HasPrices(t *testing.T) {
+	h := newCatalogHandler(stubCatalogStore{
+		deleteProdFn: func(int) (bool, error) { return true, service.ErrProductHasPrices },
+	})
+
+	req := httptest.NewRequest(http.MethodDelete, "/products/1", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	rr := httptest.NewRecorder()
+
+	h.DeleteProduct(rr, req)
+
+	require.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestCreateInvoiceHandler_FromPrice(t *testing.T) {
+	prices := stubCatalogStore{
+		getPriceFn: func(id int) (*model.Price, error) {
+			return &model.Price{ID: id, Currency: "EUR", UnitAmountCents: 1500, Interval: model.IntervalOneTime, Active: true}, nil
+		},
+	}
+	h := NewBillingHandler(service.NewBillingService(stubInvoiceStore{}, service.WithPrices(prices)))
+
+	req := httptest.NewRequest(http.MethodPost, "/invoices", bytes.NewBufferString(`{"price_id":7,"quantity":2}`))
+	req.Header.Set("X-Internal-User-ID", "user-1")
+	rr := httptest.NewRecorder()
+
+	h.CreateInvoice(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var resp model.Invoice
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, int64(3000), resp.AmountCents)
+	require.Equal(t, "EUR", resp.Currency)
+	require.Equal(t, 7, *resp.PriceID)
+	require.Equal(t, 2, *resp.Quantity)
+}
+
+func TestCreateInvoiceHandler_PriceAndAmount(t *testing.T) {
+	h := newHandler(stubInvoiceStore{})
+
+	req := httptest.NewRequest(http.MethodPost, "/invoices", bytes.NewBufferString(`{"price_id":7,"amount_cents":100}`))
+	req.Header.Set("X-Internal-User-ID", "user-1")
+	rr := httptest.NewRecorder()
+
+	h.CreateInvoice(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,344 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+	"saas-subscription-platform/services/billing-service/internal/service"
+)
+
+// writeDiscountError traduce los errores de cupones y descuentos; los
+// desconocidos son 500 con fallback.
+func writeDiscountError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		writeForbidden(w)
+	case errors.Is(err, service.ErrInvalidCoupon), errors.Is(err, service.ErrDiscountNotApplicable),
+		errors.Is(err, service.ErrCouponNotFound), errors.Is(err, service.ErrPriceUnavailable):
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+	case errors.Is(err, service.ErrNotRedeemable), errors.Is(err, service.ErrPromotionCodeTaken),
+		errors.Is(err, service.ErrInvoiceDiscounted), errors.Is(err, service.ErrInvoiceNotDraft),
+		errors.Is(err, service.ErrInvalidTransition):
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(err.Error()))
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(fallback))
+	}
+}
+
+// CreateCoupon crea un cupón. Body: {"name": "Launch", "percent_off": 20,
+// "duration": "repeating", "duration_in_months": 3}.
+func (h *BillingHandler) CreateCoupon(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name             string     `json:"name"`
+		PercentOff       *int       `json:"percent_off"`
+		AmountOffCents   *int64     `json:"amount_off_cents"`
+		Currency         *string    `json:"currency"`
+		Duration         string     `json:"duration"`
+		DurationInMonths *int       `json:"duration_in_months"`
+		MaxRedemptions   *int       `json:"max_redemptions"`
+		RedeemBy         *time.Time `json:"redeem_by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+
+	coupon, err := h.service.CreateCoupon(r.Context(), model.Coupon{
+		Name:             req.Name,
+		PercentOff:       req.PercentOff,
+		AmountOffCents:   req.AmountOffCents,
+		Currency:         req.Currency,
+		Duration:         req.Duration,
+		DurationInMonths: req.DurationInMonths,
+		MaxRedemptions:   req.MaxRedemptions,
+		RedeemBy:         req.RedeemBy,
+	})
+	if err != nil {
+		writeDiscountError(w, err, "failed to create coupon")
+		return
+	}
+	writeJSON(w, http.StatusCreated, coupon)
+}
+
+func (h *BillingHandler) ListCoupons(w http.ResponseWriter, r *http.Request) {
+	active, err := activeParam(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	coupons, err := h.service.ListCoupons(r.Context(), active, limit, offset)
+	if err != nil {
+		writeDiscountError(w, err, "failed to fetch coupons")
+		return
+	}
+	writeJSON(w, http.StatusOK, coupons)
+}
+
+// writeCoupon responde el cupón, 404 si es nil, o traduce err.
+func writeCoupon(w http.ResponseWriter, coupon *model.Coupon, err error, fallback string) {
+	switch {
+	case err != nil:
+		writeDiscountError(w, err, fallback)
+	case coupon == nil:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("coupon not found"))
+	default:
+		writeJSON(w, http.StatusOK, coupon)
+	}
+}
+
+func (h *BillingHandler) GetCoupon(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "coupon")
+	if !ok {
+		return
+	}
+	coupon, err := h.service.GetCoupon(r.Context(), id)
+	writeCoupon(w, coupon, err, "failed to fetch coupon")
+}
+
+// UpdateCoupon cambia nombre o active; los términos del descuento son fijos.
+func (h *BillingHandler) UpdateCoupon(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "coupon")
+	if !ok {
+		return
+	}
+	var req struct {
+		Name   *string `json:"name"`
+		Active *bool   `json:"active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+
+	coupon, err := h.service.UpdateCoupon(r.Context(), id, model.CouponUpdate{Name: req.Name, Active: req.Active})
+	writeCoupon(w, coupon, err, "failed to update coupon")
+}
+
+// ArchiveCoupon desactiva el cupón; los descuentos ya aplicados siguen.
+func (h *BillingHandler) ArchiveCoupon(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "coupon")
+	if !ok {
+		return
+	}
+	coupon, err := h.service.ArchiveCoupon(r.Context(), id)
+	writeCoupon(w, coupon, err, "failed to archive coupon")
+}
+
+// CreatePromotionCode crea un código para un cupón. Body: {"code": "LAUNCH20",
+// "coupon_id": 1, "restrictions": {"first_time_transaction": true}}.
+func (h *BillingHandler) CreatePromotionCode(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code           string                      `json:"code"`
+		CouponID       int                         `json:"coupon_id"`
+		MaxRedemptions *int                        `json:"max_redemptions"`
+		ExpiresAt      *time.Time                  `json:"expires_at"`
+		Restrictions   model.PromotionRestrictions `json:"restrictions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+
+	promo, err := h.service.CreatePromotionCode(r.Context(), model.PromotionCode{
+		Code:           req.Code,
+		CouponID:       req.CouponID,
+		MaxRedemptions: req.MaxRedemptions,
+		ExpiresAt:      req.ExpiresAt,
+		Restrictions:   req.Restrictions,
+	})
+	if err != nil {
+		writeDiscountError(w, err, "failed to create promotion code")
+		return
+	}
+	writeJSON(w, http.StatusCreated, promo)
+}
+
+// ListPromotionCodes filtra por ?coupon_id=, ?active= y ?code=.
+func (h *BillingHandler) ListPromotionCodes(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := repository.PromotionCodeFilter{Code: q.Get("code")}
+	var err error
+	if filter.Active, err = activeParam(r); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	if raw := q.Get("coupon_id"); raw != "" {
+		if filter.CouponID, err = strconv.Atoi(raw); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("coupon_id must be a number"))
+			return
+		}
+	}
+	filter.Limit, _ = strconv.Atoi(q.Get("limit"))
+	filter.Offset, _ = strconv.Atoi(q.Get("offset"))
+
+	promos, err := h.service.ListPromotionCodes(r.Context(), filter)
+	if err != nil {
+		writeDiscountError(w, err, "failed to fetch promotion codes")
+		return
+	}
+	writeJSON(w, http.StatusOK, promos)
+}
+
+// writePromotionCode responde el código, 404 si es nil, o traduce err.
+func writePromotionCode(w http.ResponseWriter, promo *model.PromotionCode, err error, fallback string) {
+	switch {
+	case err != nil:
+		writeDiscountError(w, err, fallback)
+	case promo == nil:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("promotion code not found"))
+	default:
+		writeJSON(w, http.StatusOK, promo)
+	}
+}
+
+func (h *BillingHandler) GetPromotionCode(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "promotion code")
+	if !ok {
+		return
+	}
+	promo, err := h.service.GetPromotionCode(r.Context(), id)
+	writePromotionCode(w, promo, err, "failed to fetch promotion code")
+}
+
+// UpdatePromotionCode activa o desactiva el código. Body: {"active": false}.
+func (h *BillingHandler) UpdatePromotionCode(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "promotion code")
+	if !ok {
+		return
+	}
+	var req struct {
+		Active *bool `json:"active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+
+	promo, err := h.service.UpdatePromotionCode(r.Context(), id, req.Active)
+	writePromotionCode(w, promo, err, "failed to update promotion code")
+}
+
+// discountRequest lee {"promotion_code": "..."} o, solo para admins,
+// {"coupon_id": 1}. Si no puede, responde y devuelve false.
+func discountRequest(w http.ResponseWriter, r *http.Request) (service.DiscountRequest, bool) {
+	var req struct {
+		CouponID      int    `json:"coupon_id"`
+		PromotionCode string `json:"promotion_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return service.DiscountRequest{}, false
+	}
+	// Los clientes canjean códigos; un cupón directo lo aplica un admin.
+	if req.CouponID != 0 && r.Header.Get("X-Internal-User-Role") != "admin" {
+		writeForbidden(w)
+		return service.DiscountRequest{}, false
+	}
+	return service.DiscountRequest{CouponID: req.CouponID, PromotionCode: req.PromotionCode}, true
+}
+
+// ApplyInvoiceDiscount canjea un cupón sobre un borrador y responde la
+// factura con los totales recalculados.
+func (h *BillingHandler) ApplyInvoiceDiscount(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r, "invoice")
+	if !ok {
+		return
+	}
+	req, ok := discountRequest(w, r)
+	if !ok {
+		return
+	}
+
+	invoice, err := h.service.ApplyInvoiceDiscount(r.Context(), scope, id, req)
+	switch {
+	case err != nil:
+		writeDiscountError(w, err, "failed to apply discount")
+	case invoice == nil:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("invoice not found"))
+	default:
+		writeJSON(w, http.StatusOK, presentInvoice(r, invoice))
+	}
+}
+
+// ApplySubscriptionDiscount canjea un cupón sobre la suscripción; rige desde
+// la próxima factura.
+func (h *BillingHandler) ApplySubscriptionDiscount(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r, "subscription")
+	if !ok {
+		return
+	}
+	req, ok := discountRequest(w, r)
+	if !ok {
+		return
+	}
+
+	discount, err := h.service.ApplySubscriptionDiscount(r.Context(), scope, id, req)
+	switch {
+	case err != nil:
+		writeDiscountError(w, err, "failed to apply discount")
+	case discount == nil:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("subscription not found"))
+	default:
+		writeJSON(w, http.StatusCreated, discount)
+	}
+}
+
+// RemoveSubscriptionDiscount termina el descuento vigente de la suscripción.
+func (h *BillingHandler) RemoveSubscriptionDiscount(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r, "subscription")
+	if !ok {
+		return
+	}
+
+	removed, err := h.service.RemoveSubscriptionDiscount(r.Context(), scope, id)
+	switch {
+	case err != nil:
+		writeDiscountError(w, err, "failed to remove discount")
+	case !removed:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("discount not found"))
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
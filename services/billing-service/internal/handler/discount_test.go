@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+	"saas-subscription-platform/services/billing-service/internal/service"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+type stubDiscountStore struct {
+	service.DiscountStore
+	findCodeFn  func(code string) (*model.PromotionCode, error)
+	getCouponFn func(id int) (*model.Coupon, error)
+	applyFn     func(inv *model.Invoice, d *model.Discount) (bool, error)
+}
+
+func (s stubDiscountStore) FindPromotionCode(_ context.Context, code string) (*model.PromotionCode, error) {
+	return s.findCodeFn(code)
+}
+
+func (s stubDiscountStore) GetCoupon(_ context.Context, id int) (*model.Coupon, error) {
+	return s.getCouponFn(id)
+}
+
+func (s stubDiscountStore) ApplyInvoiceDiscount(_ context.Context, _ repository.InvoiceScope, inv *model.Invoice, d *model.Discount) (bool, error) {
+	return s.applyFn(inv, d)
+}
+
+func TestApplyInvoiceDiscountHandler(t *testing.T) {
+	percent := 10
+	discounts := stubDiscountStore{
+		findCodeFn: func(code string) (*model.PromotionCode, error) {
+			if code != "SPRING10" {
+				return nil, nil
+			}
+			return &model.PromotionCode{ID: 9, Code: code, CouponID: 2, Active: true}, nil
+		},
+		getCouponFn: func(id int) (*model.Coupon, error) {
+			return &model.Coupon{ID: id, PercentOff: &percent, Duration: model.CouponOnce, Active: true}, nil
+		},
+		applyFn: func(inv *model.Invoice, d *model.Discount) (bool, error) {
+			require.Equal(t, int64(200), inv.TotalDiscountCents)
+			return true, nil
+		},
+	}
+	tests := []struct {
+		name string
+		role string
+		body string
+		want int
+	}{
+		{name: "promotion code", body: `{"promotion_code": "SPRING10"}`, want: http.StatusOK},
+		{name: "unknown promotion code", body: `{"promotion_code": "WINTER"}`, want: http.StatusBadRequest},
+		{name: "coupon as customer", body: `{"coupon_id": 2}`, want: http.StatusForbidden},
+		{name: "coupon as admin", role: "admin", body: `{"coupon_id": 2}`, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoices := stubInvoiceStore{
+				getByID: func(scope repository.InvoiceScope, id int) (*model.Invoice, error) {
+					return &model.Invoice{ID: id, Currency: "USD", Status: model.InvoiceDraft, SubtotalCents: 2000,
+						Lines: []model.InvoiceLineItem{{Quantity: 1, UnitAmountCents: 2000, AmountCents: 2000}}}, nil
+				},
+			}
+			h := NewBillingHandler(service.NewBillingService(invoices, service.WithDiscounts(discounts)))
+
+			req := httptest.NewRequest(http.MethodPost, "/invoices/5/discount", bytes.NewBufferString(tt.body))
+			req.Header.Set("X-Internal-User-ID", "user-1")
+			if tt.role != "" {
+				req.Header.Set("X-Internal-User-Role", tt.role)
+			}
+			req = mux.SetURLVars(req, map[string]string{"id": "5"})
+			rr := httptest.NewRecorder()
+
+			h.ApplyInvoiceDiscount(rr, req)
+
+			require.Equal(t, tt.want, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestCreateCouponHandler_Invalid(t *testing.T) {
+	h := NewBillingHandler(service.NewBillingService(stubInvoiceStore{}, service.WithDiscounts(stubDiscountStore{})))
+
+	req := httptest.NewRequest(http.MethodPost, "/coupons", bytes.NewBufferString(`{"name": "Half", "percent_off": 50, "duration": "repeating"}`))
+	rr := httptest.NewRecorder()
+
+	h.CreateCoupon(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), "duration_in_months")
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/service"
+)
+
+// transitionInvoice ejecuta una transición de estado sobre la factura del path
+// y responde la factura, 404 si no existe o 409 si su estado no la permite.
+func (h *BillingHandler) transitionInvoice(w http.ResponseWriter, r *http.Request,
+	move func(ctx context.Context, scope service.Scope, id int) (*model.Invoice, error)) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r, "invoice")
+	if !ok {
+		return
+	}
+
+	invoice, err := move(r.Context(), scope, id)
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		writeForbidden(w)
+	case errors.Is(err, service.ErrInvalidInvoiceTransition):
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(err.Error()))
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("failed to update invoice"))
+	case invoice == nil:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("invoice not found"))
+	default:
+		writeJSON(w, http.StatusOK, presentInvoice(r, invoice))
+	}
+}
+
+// FinalizeInvoice emite un borrador: le asigna número y fija su contenido.
+func (h *BillingHandler) FinalizeInvoice(w http.ResponseWriter, r *http.Request) {
+	h.transitionInvoice(w, r, h.service.FinalizeInvoice)
+}
+
+func (h *BillingHandler) VoidInvoice(w http.ResponseWriter, r *http.Request) {
+	h.transitionInvoice(w, r, h.service.VoidInvoice)
+}
+
+func (h *BillingHandler) MarkInvoiceUncollectible(w http.ResponseWriter, r *http.Request) {
+	h.transitionInvoice(w, r, h.service.MarkInvoiceUncollectible)
+}
+
+// RecordInvoicePayment es el aviso de payment-service de que cobró la factura.
+// Body: {"user_id": "...", "org_id": "...", "payment_intent_id": 3,
+// "amount_cents": 1000, "currency": "USD"}. Responde 200 también si la factura
+// ya estaba paga.
+func (h *BillingHandler) RecordInvoicePayment(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "invoice")
+	if !ok {
+		return
+	}
+	var req struct {
+		UserID          string `json:"user_id"`
+		OrgID           string `json:"org_id"`
+		PaymentIntentID int    `json:"payment_intent_id"`
+		AmountCents     int64  `json:"amount_cents"`
+		Currency        string `json:"currency"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+
+	invoice, err := h.service.RecordInvoicePayment(r.Context(), id, service.InvoicePayment{
+		UserID:          req.UserID,
+		OrgID:           req.OrgID,
+		PaymentIntentID: req.PaymentIntentID,
+		AmountCents:     req.AmountCents,
+		Currency:        req.Currency,
+	})
+	switch {
+	case errors.Is(err, service.ErrInvalidInvoiceTransition), errors.Is(err, service.ErrPaymentMismatch):
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(err.Error()))
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("failed to record payment"))
+	case invoice == nil:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("invoice not found"))
+	default:
+		writeJSON(w, http.StatusOK, invoice)
+	}
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+	"saas-subscription-platform/services/billing-service/internal/service"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoiceTransitionHandlers(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(h *BillingHandler) http.HandlerFunc
+		result  *model.Invoice
+		err     error
+		want    int
+	}{
+		{name: "finalize", handler: func(h *BillingHandler) http.HandlerFunc { return h.FinalizeInvoice },
+			result: &model.Invoice{ID: 5, Status: model.InvoiceOpen}, want: http.StatusOK},
+		{name: "void paid invoice", handler: func(h *BillingHandler) http.HandlerFunc { return h.VoidInvoice },
+			err: repository.ErrInvalidInvoiceTransition, want: http.StatusConflict},
+		{name: "mark uncollectible missing", handler: func(h *BillingHandler) http.HandlerFunc { return h.MarkInvoiceUncollectible },
+			want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHandler(stubInvoiceStore{
+				moveFn: func(id int, from []string, status string) (*model.Invoice, error) {
+					return tt.result, tt.err
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/invoices/5/x", nil)
+			req.Header.Set("X-Internal-User-ID", "user-1")
+			req = mux.SetURLVars(req, map[string]string{"id": "5"})
+			rr := httptest.NewRecorder()
+
+			tt.handler(h)(rr, req)
+
+			require.Equal(t, tt.want, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestVoidInvoiceHandler_AdminVoidsAnotherUsersInvoice(t *testing.T) {
+	var got repository.InvoiceScope
+	h := newHandler(stubInvoiceStore{
+		moveScopeFn: func(scope repository.InvoiceScope) { got = scope },
+		moveFn: func(id int, from []string, status string) (*model.Invoice, error) {
+			return &model.Invoice{ID: id, UserID: "user-1", Status: status}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/invoices/5/void", nil)
+	req.Header.Set("X-Internal-User-ID", "admin-1")
+	req.Header.Set("X-Internal-User-Role", "admin")
+	req = mux.SetURLVars(req, map[string]string{"id": "5"})
+	rr := httptest.NewRecorder()
+
+	h.VoidInvoice(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, repository.InvoiceScope{AllOwners: true}, got)
+	require.Contains(t, rr.Body.String(), `"user_id":"user-1"`)
+}
+
+func TestUpdateInvoiceHandler_LinesOnFinalizedInvoice(t *testing.T) {
+	h := newHandler(stubInvoiceStore{
+		getByID: func(scope repository.InvoiceScope, id int) (*model.Invoice, error) {
+			return &model.Invoice{ID: id, Currency: "USD", Status: model.InvoiceOpen}, nil
+		},
+	})
+
+	body := bytes.NewBufferString(`{"lines":[{"description":"Seats","unit_amount_cents":100}]}`)
+	req := httptest.NewRequest(http.MethodPatch, "/invoices/5", body)
+	req.Header.Set("X-Internal-User-ID", "user-1")
+	req = mux.SetURLVars(req, map[string]string{"id": "5"})
+	rr := httptest.NewRecorder()
+
+	h.UpdateInvoice(rr, req)
+
+	require.Equal(t, http.StatusConflict, rr.Code)
+	require.Equal(t, service.ErrInvoiceNotDraft.Error(), rr.Body.String())
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"saas-subscription-platform/services/billing-service/internal/service"
+)
+
+// ExportUser devuelve las facturas del usuario para el export GDPR que arma user-service.
+func (h *BillingHandler) ExportUser(w http.ResponseWriter, r *http.Request) {
+	invoices, err := h.service.ExportUserInvoices(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("failed to export invoices"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"invoices": invoices})
+}
+
+// EraseUser seudonimiza las facturas del usuario; user-service manda el seudónimo.
+func (h *BillingHandler) EraseUser(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Pseudonym string `json:"pseudonym"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+
+	n, err := h.service.PseudonymizeUser(r.Context(), mux.Vars(r)["id"], req.Pseudonym)
+	if errors.Is(err, service.ErrInvalidPseudonym) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("failed to pseudonymize invoices"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]int64{"invoices_pseudonymized": n})
+}
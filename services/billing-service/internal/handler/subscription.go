@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/service"
+)
+
+// writeSubscription responde la suscripción, 404 si es nil, o traduce err.
+func writeSubscription(w http.ResponseWriter, sub *model.Subscription, err error, status int) {
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		writeForbidden(w)
+	case errors.Is(err, service.ErrInvalidTransition):
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(err.Error()))
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("failed to update subscription"))
+	case sub == nil:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("subscription not found"))
+	default:
+		writeJSON(w, status, sub)
+	}
+}
+
+// CreateSubscription suscribe al usuario (o a su organización activa) a un
+// precio recurrente. Body: {"price_id": 1, "quantity": 1, "trial_days": 14}.
+func (h *BillingHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+
+	var req struct {
+		PriceID   int `json:"price_id"`
+		Quantity  int `json:"quantity"`
+		TrialDays int `json:"trial_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+
+	sub, err := h.service.CreateSubscription(r.Context(), scope, req.PriceID, req.Quantity, req.TrialDays)
+	if errors.Is(err, service.ErrForbidden) {
+		writeForbidden(w)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+func (h *BillingHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	offset, _ := strconv.Atoi(q.Get("offset"))
+
+	subs, err := h.service.ListSubscriptions(r.Context(), scope, q.Get("status"), limit, offset)
+	if errors.Is(err, service.ErrForbidden) {
+		writeForbidden(w)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("failed to fetch subscriptions"))
+		return
+	}
+	writeJSON(w, http.StatusOK, subs)
+}
+
+func (h *BillingHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r, "subscription")
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.GetSubscription(r.Context(), scope, id)
+	writeSubscription(w, sub, err, http.StatusOK)
+}
+
+// CancelSubscription cancela ya o, con {"at_period_end": true}, al terminar el
+// período en curso. El body es opcional.
+func (h *BillingHandler) CancelSubscription(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r, "subscription")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		AtPeriodEnd bool `json:"at_period_end"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("invalid request payload"))
+			return
+		}
+	}
+
+	sub, err := h.service.CancelSubscription(r.Context(), scope, id, req.AtPeriodEnd)
+	writeSubscription(w, sub, err, http.StatusOK)
+}
+
+func (h *BillingHandler) PauseSubscription(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r, "subscription")
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.PauseSubscription(r.Context(), scope, id)
+	writeSubscription(w, sub, err, http.StatusOK)
+}
+
+func (h *BillingHandler) ResumeSubscription(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r, "subscription")
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.ResumeSubscription(r.Context(), scope, id)
+	writeSubscription(w, sub, err, http.StatusOK)
+}
+
+// subscriptionChangeRequest es el body de los cambios de plan y su vista previa.
+type subscriptionChangeRequest struct {
+	PriceID           int        `json:"price_id"`
+	Quantity          int        `json:"quantity"`
+	ProrationBehavior string     `json:"proration_behavior"`
+	ProrationDate     *time.Time `json:"proration_date"`
+}
+
+// decodeSubscriptionChange lee el body; responde 400 y devuelve false si es inválido.
+func decodeSubscriptionChange(w http.ResponseWriter, r *http.Request) (service.SubscriptionChangeRequest, bool) {
+	var req subscriptionChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return service.SubscriptionChangeRequest{}, false
+	}
+	return service.SubscriptionChangeRequest{
+		PriceID:           req.PriceID,
+		Quantity:          req.Quantity,
+		ProrationBehavior: req.ProrationBehavior,
+		ProrationDate:     req.ProrationDate,
+	}, true
+}
+
+// writeChangeError traduce los errores de un cambio de plan; devuelve false si no hubo.
+func writeChangeError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrForbidden):
+		writeForbidden(w)
+	case errors.Is(err, service.ErrInvalidTransition), errors.Is(err, service.ErrSubscriptionChanged):
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(err.Error()))
+	case errors.Is(err, service.ErrInvalidChange), errors.Is(err, service.ErrPriceUnavailable):
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("failed to change subscription"))
+	}
+	return true
+}
+
+// ChangeSubscription cambia precio y/o cantidad. Body: {"price_id": 8,
+// "quantity": 3, "proration_behavior": "create_prorations", "proration_date": "..."};
+// proration_date permite confirmar los montos de una vista previa reciente.
+func (h *BillingHandler) ChangeSubscription(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r, "subscription")
+	if !ok {
+		return
+	}
+	req, ok := decodeSubscriptionChange(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := h.service.ChangeSubscription(r.Context(), scope, id, req)
+	if writeChangeError(w, err) {
+		return
+	}
+	if res == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("subscription not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// PreviewSubscriptionChange muestra el prorrateo de un cambio sin aplicarlo.
+// Mismo body que ChangeSubscription.
+func (h *BillingHandler) PreviewSubscriptionChange(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r, "subscription")
+	if !ok {
+		return
+	}
+	req, ok := decodeSubscriptionChange(w, r)
+	if !ok {
+		return
+	}
+
+	preview, err := h.service.PreviewSubscriptionChange(r.Context(), scope, id, req)
+	if writeChangeError(w, err) {
+		return
+	}
+	if preview == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("subscription not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, preview)
+}
+
+// GetUpcomingInvoice muestra la factura que emitirá la próxima renovación,
+// sin crearla.
+func (h *BillingHandler) GetUpcomingInvoice(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r, "subscription")
+	if !ok {
+		return
+	}
+
+	invoice, err := h.service.UpcomingInvoice(r.Context(), scope, id)
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		writeForbidden(w)
+	case errors.Is(err, service.ErrNoUpcomingInvoice):
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(err.Error()))
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("failed to preview upcoming invoice"))
+	case invoice == nil:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("subscription not found"))
+	default:
+		writeJSON(w, http.StatusOK, presentInvoice(r, invoice))
+	}
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+	"saas-subscription-platform/services/billing-service/internal/service"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+type stubSubscriptionStore struct {
+	service.SubscriptionStore
+	createFn     func(s *model.Subscription) error
+	transitionFn func(scope repository.InvoiceScope, id int, from []string, u model.SubscriptionUpdate) (*model.Subscription, error)
+	getFn        func(id int) (*model.Subscription, error)
+	changeFn     func(id int, c model.SubscriptionChange) (*model.Subscription, error)
+}
+
+func (s stubSubscriptionStore) GetSubscription(_ context.Context, _ repository.InvoiceScope, id int) (*model.Subscription, error) {
+	return s.getFn(id)
+}
+
+func (s stubSubscriptionStore) ChangeSubscription(_ context.Context, _ repository.InvoiceScope, id int, c model.SubscriptionChange) (*model.Subscription, error) {
+	return s.changeFn(id, c)
+}
+
+func (s stubSubscriptionStore) CreateSubscription(_ context.Context, sub *model.Subscription) error {
+	return s.createFn(sub)
+}
+
+func (s stubSubscriptionStore) TransitionSubscription(_ context.Context, scope repository.InvoiceScope, id int, from []string, u model.SubscriptionUpdate) (*model.Subscription, error) {
+	return s.transitionFn(scope, id, from, u)
+}
+
+func newSubscriptionHandler(subs service.SubscriptionStore) *BillingHandler {
+	prices := stubCatalogStore{
+		getPriceFn: func(id int) (*model.Price, error) {
+			return &model.Price{ID: id, Currency: "USD", UnitAmountCents: 1500, Interval: model.IntervalMonth, IntervalCount: 1, Active: true}, nil
+		},
+	}
+	return NewBillingHandler(service.NewBillingService(stubInvoiceStore{}, service.WithPrices(prices), service.WithSubscriptions(subs)))
+}
+
+func TestCreateSubscriptionHandler_Trial(t *testing.T) {
+	h := newSubscriptionHandler(stubSubscriptionStore{
+		createFn: func(s *model.Subscription) error {
+			s.ID = 3
+			return nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/subscriptions", bytes.NewBufferString(`{"price_id":7,"trial_days":14}`))
+	req.Header.Set("X-Internal-User-ID", "user-1")
+	rr := httptest.NewRecorder()
+
+	h.CreateSubscription(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var resp model.Subscription
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, 3, resp.ID)
+	require.Equal(t, model.SubscriptionTrialing, resp.Status)
+	require.NotNil(t, resp.TrialEnd)
+}
+
+func TestCancelSubscriptionHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		result *model.Subscription
+		err    error
+		want   int
+	}{
+		{name: "at period end", body: `{"at_period_end":true}`, result: &model.Subscription{ID: 3, CancelAtPeriodEnd: true}, want: http.StatusOK},
+		{name: "no body", result: &model.Subscription{ID: 3, Status: model.SubscriptionCanceled}, want: http.StatusOK},
+		{name: "already canceled", err: service.ErrInvalidTransition, want: http.StatusConflict},
+		{name: "not found", want: http.StatusNotFound},
+		{name: "bad body", body: `nope`, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotAtPeriodEnd bool
+			h := newSubscriptionHandler(stubSubscriptionStore{
+				transitionFn: func(_ repository.InvoiceScope, _ int, _ []string, u model.SubscriptionUpdate) (*model.Subscription, error) {
+					gotAtPeriodEnd = u.CancelAtPeriodEnd != nil
+					return tt.result, tt.err
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/subscriptions/3/cancel", bytes.NewBufferString(tt.body))
+			req.Header.Set("X-Internal-User-ID", "user-1")
+			req = mux.SetURLVars(req, map[string]string{"id": "3"})
+			rr := httptest.NewRecorder()
+
+			h.CancelSubscription(rr, req)
+
+			require.Equal(t, tt.want, rr.Code)
+			if tt.want == http.StatusOK {
+				require.Equal(t, tt.result.CancelAtPeriodEnd, gotAtPeriodEnd)
+			}
+		})
+	}
+}
+
+func TestPauseSubscriptionHandler_OrgMemberForbidden(t *testing.T) {
+	h := newSubscriptionHandler(stubSubscriptionStore{})
+
+	req := httptest.NewRequest(http.MethodPost, "/subscriptions/3/pause", nil)
+	req.Header.Set("X-Internal-User-ID", "user-1")
+	req.Header.Set("X-Internal-Org-ID", "org-1")
+	req.Header.Set("X-Internal-Org-Role", "member")
+	req = mux.SetURLVars(req, map[string]string{"id": "3"})
+	rr := httptest.NewRecorder()
+
+	h.PauseSubscription(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestChangeSubscriptionHandler(t *testing.T) {
+	active := &model.Subscription{ID: 3, PriceID: 7, Quantity: 1, Status: model.SubscriptionActive,
+		CurrentPeriodStart: time.Now().Add(-time.Hour), CurrentPeriodEnd: time.Now().Add(time.Hour)}
+	tests := []struct {
+		name      string
+		body      string
+		sub       *model.Subscription
+		changeErr error
+		want      int
+	}{
+		{name: "upgrade", body: `{"price_id":8}`, sub: active, want: http.StatusOK},
+		{name: "not found", body: `{"price_id":8}`, want: http.StatusNotFound},
+		{name: "bad behavior", body: `{"price_id":8,"proration_behavior":"later"}`, sub: active, want: http.StatusBadRequest},
+		{name: "changed concurrently", body: `{"price_id":8}`, sub: active, changeErr: repository.ErrSubscriptionChanged, want: http.StatusConflict},
+		{name: "bad body", body: `nope`, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newSubscriptionHandler(stubSubscriptionStore{
+				getFn: func(int) (*model.Subscription, error) { return tt.sub, nil },
+				changeFn: func(id int, c model.SubscriptionChange) (*model.Subscription, error) {
+					require.Len(t, c.PendingItems, 2)
+					return &model.Subscription{ID: id, PriceID: c.PriceID}, tt.changeErr
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPatch, "/subscriptions/3", bytes.NewBufferString(tt.body))
+			req.Header.Set("X-Internal-User-ID", "user-1")
+			req = mux.SetURLVars(req, map[string]string{"id": "3"})
+			rr := httptest.NewRecorder()
+
+			h.ChangeSubscription(rr, req)
+
+			require.Equal(t, tt.want, rr.Code, rr.Body.String())
+			if tt.want == http.StatusOK {
+				var resp service.SubscriptionChangeResult
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+				require.Equal(t, 8, resp.Subscription.PriceID)
+				require.Len(t, resp.Proration.Lines, 2)
+			}
+		})
+	}
+}
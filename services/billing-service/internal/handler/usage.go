@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/service"
+)
+
+// writeUsageError traduce los errores de ítems y uso; los desconocidos son
+// 500 con fallback.
+func writeUsageError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		writeForbidden(w)
+	case errors.Is(err, service.ErrInvalidUsage), errors.Is(err, service.ErrPriceUnavailable),
+		errors.Is(err, service.ErrSubscriptionItemNotFound), errors.Is(err, service.ErrUsagePeriodClosed):
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+	case errors.Is(err, service.ErrSubscriptionItemExists), errors.Is(err, service.ErrInvalidTransition):
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(err.Error()))
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(fallback))
+	}
+}
+
+// AddSubscriptionItem agrega un precio medido a la suscripción. Body: {"price_id": 7}.
+func (h *BillingHandler) AddSubscriptionItem(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r, "subscription")
+	if !ok {
+		return
+	}
+	var req struct {
+		PriceID int `json:"price_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+
+	item, err := h.service.AddSubscriptionItem(r.Context(), scope, id, req.PriceID)
+	switch {
+	case err != nil:
+		writeUsageError(w, err, "failed to add subscription item")
+	case item == nil:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("subscription not found"))
+	default:
+		writeJSON(w, http.StatusCreated, item)
+	}
+}
+
+func (h *BillingHandler) ListSubscriptionItems(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r, "subscription")
+	if !ok {
+		return
+	}
+
+	items, err := h.service.ListSubscriptionItems(r.Context(), scope, id)
+	switch {
+	case err != nil:
+		writeUsageError(w, err, "failed to list subscription items")
+	case items == nil:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("subscription not found"))
+	default:
+		writeJSON(w, http.StatusOK, items)
+	}
+}
+
+// GetSubscriptionUsage devuelve el uso sin facturar de cada ítem medido.
+func (h *BillingHandler) GetSubscriptionUsage(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r, "subscription")
+	if !ok {
+		return
+	}
+
+	usage, err := h.service.UsageSummary(r.Context(), scope, id)
+	switch {
+	case err != nil:
+		writeUsageError(w, err, "failed to get usage")
+	case usage == nil:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("subscription not found"))
+	default:
+		writeJSON(w, http.StatusOK, usage)
+	}
+}
+
+// RecordUsage recibe un registro de uso o un lote: {"records": [...]}. Cada
+// registro: {"subscription_item_id": 3, "idempotency_key": "evt-1",
+// "quantity": 10, "action": "increment", "timestamp": "2024-05-01T10:00:00Z"}.
+func (h *BillingHandler) RecordUsage(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	var req struct {
+		model.UsageRecord
+		Records *[]model.UsageRecord `json:"records"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+	records := []model.UsageRecord{req.UsageRecord}
+	if req.Records != nil {
+		records = *req.Records
+	}
+
+	result, err := h.service.RecordUsage(r.Context(), scope, records)
+	if err != nil {
+		writeUsageError(w, err, "failed to record usage")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
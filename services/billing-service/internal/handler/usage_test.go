@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+	"saas-subscription-platform/services/billing-service/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+type stubUsageStore struct {
+	service.UsageStore
+	recordFn func(records []model.UsageRecord) (int, error)
+}
+
+func (s stubUsageStore) RecordUsage(_ context.Context, _ repository.InvoiceScope, records []model.UsageRecord) (int, error) {
+	return s.recordFn(records)
+}
+
+func TestRecordUsageHandler(t *testing.T) {
+	usage := stubUsageStore{
+		recordFn: func(records []model.UsageRecord) (int, error) {
+			for _, rec := range records {
+				if rec.SubscriptionItemID == 99 {
+					return 0, repository.ErrSubscriptionItemNotFound
+				}
+			}
+			return len(records), nil
+		},
+	}
+	tests := []struct {
+		name string
+		body string
+		want int
+		resp string
+	}{
+		{name: "single record", body: `{"subscription_item_id": 3, "idempotency_key": "evt-1", "quantity": 10}`,
+			want: http.StatusOK, resp: `{"accepted":1,"duplicates":0}`},
+		{name: "batch", body: `{"records": [{"subscription_item_id": 3, "idempotency_key": "evt-1", "quantity": 10},
+			{"subscription_item_id": 3, "idempotency_key": "evt-2", "quantity": 5, "action": "set"}]}`,
+			want: http.StatusOK, resp: `{"accepted":2,"duplicates":0}`},
+		{name: "empty batch", body: `{"records": []}`, want: http.StatusBadRequest},
+		{name: "unknown item", body: `{"subscription_item_id": 99, "idempotency_key": "evt-1", "quantity": 1}`, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewBillingHandler(service.NewBillingService(stubInvoiceStore{}, service.WithUsage(usage)))
+
+			req := httptest.NewRequest(http.MethodPost, "/usage_records", bytes.NewBufferString(tt.body))
+			req.Header.Set("X-Internal-User-ID", "user-1")
+			rr := httptest.NewRecorder()
+
+			h.RecordUsage(rr, req)
+
+			require.Equal(t, tt.want, rr.Code, rr.Body.String())
+			if tt.resp != "" {
+				require.JSONEq(t, tt.resp, rr.Body.String())
+			}
+		})
+	}
+}
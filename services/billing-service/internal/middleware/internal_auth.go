@@ -4,12 +4,15 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+
+	"saas-subscription-platform/libs/trace"
 )
 
 // InternalAuthMux es equivalente al middleware InternalAuth del user-service,
 // pero adaptado a gorilla/mux (mux.MiddlewareFunc).
 //
-// Exige el header interno X-Internal-User-ID (agregado por el API Gateway).
+// Exige el header interno X-Internal-User-ID (agregado por el API Gateway) y
+// deja el request_id en el contexto para los logs de queries.
 func InternalAuthMux(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		userID := r.Header.Get("X-Internal-User-ID")
@@ -17,8 +20,44 @@ func InternalAuthMux(next http.Handler) http.Handler {
 			http.Error(w, "missing internal user ID", http.StatusUnauthorized)
 			return
 		}
-		next.ServeHTTP(w, r)
+		ctx := trace.ExtractAndUpdateContext(r.Context(), r, "billing-service")
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
 var _ mux.MiddlewareFunc = InternalAuthMux
+
+// RequireCaller restringe la ruta a llamadas servicio-a-servicio: el gateway
+// pisa X-Internal-User-ID con el sub del JWT, así que un cliente externo no
+// puede hacerse pasar por otro servicio.
+func RequireCaller(callers ...string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			caller := r.Header.Get("X-Internal-User-ID")
+			for _, c := range callers {
+				if caller == c {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			http.Error(w, "forbidden", http.StatusForbidden)
+		})
+	}
+}
+
+// RequireRole restringe la ruta a los roles de plataforma dados (header
+// X-Internal-User-Role que agrega el gateway desde el JWT).
+func RequireRole(roles ...string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := r.Header.Get("X-Internal-User-Role")
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			http.Error(w, "forbidden", http.StatusForbidden)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package model
+
+import "time"
+
+// Intervalos de facturación de un precio.
+const (
+	IntervalOneTime = "one_time"
+	IntervalDay     = "day"
+	IntervalWeek    = "week"
+	IntervalMonth   = "month"
+	IntervalYear    = "year"
+)
+
+// Product es algo que se vende; sus precios dicen cuánto y cada cuánto.
+type Product struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Price es un precio de un producto. Una vez usado (por una factura o una
+// suscripción) solo cambian Active y LookupKey; para cambiar el monto se crea
+// otro precio.
+type Price struct {
+	ID              int    `json:"id"`
+	ProductID       int    `json:"product_id"`
+	Currency        string `json:"currency"`
+	UnitAmountCents int64  `json:"unit_amount_cents"`
+	Interval        string `json:"interval"`
+	// IntervalCount es cada cuántos Interval se cobra (0 en pagos únicos).
+	IntervalCount int `json:"interval_count"`
+	// UsageType es licensed (se cobra la cantidad de la suscripción) o metered
+	// (se cobra el uso reportado, agregado según AggregateUsage).
+	UsageType      string  `json:"usage_type"`
+	AggregateUsage *string `json:"aggregate_usage,omitempty"`
+	// BillingScheme es per_unit, package (PackageSize unidades por
+	// UnitAmountCents) o tiered (Tiers según TiersMode); ver Amount.
+	BillingScheme string      `json:"billing_scheme"`
+	PackageSize   *int64      `json:"package_size,omitempty"`
+	TiersMode     *string     `json:"tiers_mode,omitempty"`
+	Tiers         []PriceTier `json:"tiers,omitempty"`
+	Active        bool        `json:"active"`
+	LookupKey     *string     `json:"lookup_key,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+// Recurring indica si el precio se cobra periódicamente.
+func (p Price) Recurring() bool {
+	return p.Interval != IntervalOneTime
+}
+
+// Metered indica si el precio se cobra por uso.
+func (p Price) Metered() bool {
+	return p.UsageType == UsageMetered
+}
+
+// PeriodBoundary es el fin del período n contado desde anchor (n=1 es el
+// primero). Los meses se suman siempre desde el ancla y no desde el período
+// anterior: con ancla el 31 los períodos terminan el 28/29 de febrero, el 31 de
+// marzo, el 30 de abril...; con ancla el 29 de febrero, el 28 en los años no
+// bisiestos. En un pago único devuelve anchor.
+func (p Price) PeriodBoundary(anchor time.Time, n int) time.Time {
+	switch p.Interval {
+	case IntervalDay:
+		return anchor.AddDate(0, 0, n*p.IntervalCount)
+	case IntervalWeek:
+		return anchor.AddDate(0, 0, 7*n*p.IntervalCount)
+	case IntervalMonth:
+		return addMonthsClamped(anchor, n*p.IntervalCount)
+	case IntervalYear:
+		return addMonthsClamped(anchor, 12*n*p.IntervalCount)
+	}
+	return anchor
+}
+
+// averagePeriod es la duración media de un período, para estimar en qué
+// período cae una fecha sin recorrerlos desde el ancla.
+var averagePeriod = map[string]time.Duration{
+	IntervalDay:   24 * time.Hour,
+	IntervalWeek:  7 * 24 * time.Hour,
+	IntervalMonth: 730*time.Hour + 29*time.Minute + 6*time.Second, // 365.2425 días / 12
+	IntervalYear:  8765*time.Hour + 49*time.Minute + 12*time.Second,
+}
+
+// NextPeriodEnd es el primer fin de período (contado desde anchor) posterior a after.
+func (p Price) NextPeriodEnd(anchor, after time.Time) time.Time {
+	if !p.Recurring() || p.IntervalCount <= 0 {
+		return after
+	}
+	n := 1
+	if elapsed := after.Sub(anchor); elapsed > 0 {
+		n = int(elapsed/(averagePeriod[p.Interval]*time.Duration(p.IntervalCount))) + 1
+	}
+	for n > 1 && p.PeriodBoundary(anchor, n-1).After(after) {
+		n--
+	}
+	for !p.PeriodBoundary(anchor, n).After(after) {
+		n++
+	}
+	return p.PeriodBoundary(anchor, n)
+}
+
+// addMonthsClamped suma months a t; si el día no existe en el mes de destino
+// usa el último día de ese mes.
+func addMonthsClamped(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// ProductUpdate son los campos a modificar de un producto; nil mantiene el valor actual.
+type ProductUpdate struct {
+	Name        *string
+	Description *string
+	Active      *bool
+}
+
+// PriceUpdate son los campos a modificar de un precio; nil mantiene el valor
+// actual. Monto, moneda e intervalo solo cambian mientras el precio no se usó.
+type PriceUpdate struct {
+	Active          *bool
+	LookupKey       *string // "" la borra
+	UnitAmountCents *int64
+	Currency        *string
+	Interval        *string
+	IntervalCount   *int
+}
+
+// Terms indica si el update toca monto, moneda o intervalo.
+func (u PriceUpdate) Terms() bool {
+	return u.UnitAmountCents != nil || u.Currency != nil || u.Interval != nil || u.IntervalCount != nil
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 10, 30, 0, 0, time.UTC)
+}
+
+func TestPrice_PeriodBoundary_MonthEndAnchors(t *testing.T) {
+	monthly := Price{Interval: IntervalMonth, IntervalCount: 1}
+	quarterly := Price{Interval: IntervalMonth, IntervalCount: 3}
+	yearly := Price{Interval: IntervalYear, IntervalCount: 1}
+	tests := []struct {
+		name   string
+		price  Price
+		anchor time.Time
+		n      int
+		want   time.Time
+	}{
+		{name: "31st into february", price: monthly, anchor: date(2023, time.January, 31), n: 1, want: date(2023, time.February, 28)},
+		{name: "31st into leap february", price: monthly, anchor: date(2024, time.January, 31), n: 1, want: date(2024, time.February, 29)},
+		{name: "31st back to 31st after february", price: monthly, anchor: date(2023, time.January, 31), n: 2, want: date(2023, time.March, 31)},
+		{name: "31st into 30-day month", price: monthly, anchor: date(2023, time.January, 31), n: 3, want: date(2023, time.April, 30)},
+		{name: "30th stays 30th", price: monthly, anchor: date(2023, time.January, 30), n: 2, want: date(2023, time.March, 30)},
+		{name: "across year end", price: monthly, anchor: date(2023, time.December, 31), n: 2, want: date(2024, time.February, 29)},
+		{name: "quarterly from 31st", price: quarterly, anchor: date(2023, time.August, 31), n: 2, want: date(2024, time.February, 29)},
+		{name: "leap day yearly", price: yearly, anchor: date(2024, time.February, 29), n: 1, want: date(2025, time.February, 28)},
+		{name: "leap day back on leap year", price: yearly, anchor: date(2024, time.February, 29), n: 4, want: date(2028, time.February, 29)},
+		{name: "weekly", price: Price{Interval: IntervalWeek, IntervalCount: 2}, anchor: date(2024, time.February, 20), n: 1, want: date(2024, time.March, 5)},
+		{name: "daily", price: Price{Interval: IntervalDay, IntervalCount: 1}, anchor: date(2024, time.February, 28), n: 2, want: date(2024, time.March, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.price.PeriodBoundary(tt.anchor, tt.n))
+		})
+	}
+}
+
+func TestPrice_NextPeriodEnd(t *testing.T) {
+	monthly := Price{Interval: IntervalMonth, IntervalCount: 1}
+	anchor := date(2023, time.January, 31)
+	tests := []struct {
+		name  string
+		price Price
+		after time.Time
+		want  time.Time
+	}{
+		{name: "from anchor", price: monthly, after: anchor, want: date(2023, time.February, 28)},
+		{name: "from clamped boundary", price: monthly, after: date(2023, time.February, 28), want: date(2023, time.March, 31)},
+		{name: "mid period", price: monthly, after: date(2023, time.April, 2), want: date(2023, time.April, 30)},
+		{name: "years later", price: monthly, after: date(2033, time.January, 31), want: date(2033, time.February, 28)},
+		{name: "one time", price: Price{Interval: IntervalOneTime}, after: anchor, want: anchor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.price.NextPeriodEnd(anchor, tt.after))
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package model
+
+import (
+	"math/big"
+	"time"
+)
+
+// Duraciones de un cupón aplicado a una suscripción.
+const (
+	// CouponOnce descuenta solo la próxima factura.
+	CouponOnce = "once"
+	// CouponRepeating descuenta las facturas de los primeros DurationInMonths meses.
+	CouponRepeating = "repeating"
+	// CouponForever descuenta todas las facturas.
+	CouponForever = "forever"
+)
+
+// Coupon es un descuento: un porcentaje (PercentOff) o un monto fijo en una
+// moneda (AmountOffCents + Currency). Los términos no cambian una vez creado;
+// solo Name y Active.
+type Coupon struct {
+	ID             int     `json:"id"`
+	Name           string  `json:"name"`
+	PercentOff     *int    `json:"percent_off,omitempty"`
+	AmountOffCents *int64  `json:"amount_off_cents,omitempty"`
+	Currency       *string `json:"currency,omitempty"`
+	Duration       string  `json:"duration"`
+	// DurationInMonths solo está en los cupones repeating.
+	DurationInMonths *int `json:"duration_in_months,omitempty"`
+	// MaxRedemptions y RedeemBy limitan los canjes; nil no limita.
+	MaxRedemptions *int       `json:"max_redemptions,omitempty"`
+	TimesRedeemed  int        `json:"times_redeemed"`
+	RedeemBy       *time.Time `json:"redeem_by,omitempty"`
+	Active         bool       `json:"active"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Redeemable indica si el cupón se puede canjear en at.
+func (c Coupon) Redeemable(at time.Time) bool {
+	return c.Active && (c.RedeemBy == nil || at.Before(*c.RedeemBy)) &&
+		(c.MaxRedemptions == nil || c.TimesRedeemed < *c.MaxRedemptions)
+}
+
+// DiscountEnd es hasta cuándo rige un canje que empieza en start: nil en los
+// cupones once (terminan al usarse) y forever.
+func (c Coupon) DiscountEnd(start time.Time) *time.Time {
+	if c.Duration != CouponRepeating || c.DurationInMonths == nil {
+		return nil
+	}
+	end := addMonthsClamped(start, *c.DurationInMonths)
+	return &end
+}
+
+// Apply suma el descuento del cupón al DiscountAmountCents de los renglones que
+// lo admiten: los de importe positivo que no son prorrateos, por lo que les
+// queda sin descontar. El porcentaje se calcula por renglón y se redondea al
+// centavo más cercano (las mitades suben); el monto fijo se reparte en
+// proporción a lo que queda de cada renglón y los centavos del redondeo van a
+// los primeros. El descuento nunca supera el importe del renglón.
+func (c Coupon) Apply(lines []InvoiceLineItem) {
+	var idx []int
+	var avail []*big.Int
+	sum := new(big.Int)
+	for i, l := range lines {
+		if l.Proration || l.AmountCents <= 0 || l.DiscountAmountCents >= l.AmountCents {
+			continue
+		}
+		a := big.NewInt(l.AmountCents - l.DiscountAmountCents)
+		idx = append(idx, i)
+		avail = append(avail, a)
+		sum.Add(sum, a)
+	}
+
+	if c.PercentOff != nil {
+		pct := big.NewInt(int64(*c.PercentOff))
+		for k, i := range idx {
+			// (a * pct * 2 + 100) / 200 redondea al centavo con las mitades hacia arriba.
+			d := new(big.Int).Mul(avail[k], pct)
+			d.Mul(d, big.NewInt(2)).Add(d, big.NewInt(100)).Quo(d, big.NewInt(200))
+			lines[i].DiscountAmountCents += d.Int64()
+		}
+		return
+	}
+	if c.AmountOffCents == nil || len(idx) == 0 {
+		return
+	}
+
+	off := big.NewInt(*c.AmountOffCents)
+	if sum.Cmp(off) <= 0 {
+		for k, i := range idx {
+			lines[i].DiscountAmountCents += avail[k].Int64()
+		}
+		return
+	}
+	shares := make([]int64, len(idx))
+	left := *c.AmountOffCents
+	for k := range idx {
+		share := new(big.Int).Mul(off, avail[k])
+		shares[k] = share.Quo(share, sum).Int64()
+		left -= shares[k]
+	}
+	// Cada renglón pierde menos de un centavo al truncar, así que alcanza una pasada.
+	for k := 0; left > 0 && k < len(idx); k++ {
+		if shares[k] < avail[k].Int64() {
+			shares[k]++
+			left--
+		}
+	}
+	for k, i := range idx {
+		lines[i].DiscountAmountCents += shares[k]
+	}
+}
+
+// PromotionCode es el código que escribe el cliente para canjear un cupón,
+// con sus propios límites y restricciones.
+type PromotionCode struct {
+	ID             int                   `json:"id"`
+	Code           string                `json:"code"`
+	CouponID       int                   `json:"coupon_id"`
+	Active         bool                  `json:"active"`
+	MaxRedemptions *int                  `json:"max_redemptions,omitempty"`
+	TimesRedeemed  int                   `json:"times_redeemed"`
+	ExpiresAt      *time.Time            `json:"expires_at,omitempty"`
+	Restrictions   PromotionRestrictions `json:"restrictions"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
+
+// PromotionRestrictions son las condiciones del cliente para usar el código.
+type PromotionRestrictions struct {
+	// FirstTimeTransaction: solo clientes sin facturas emitidas.
+	FirstTimeTransaction bool `json:"first_time_transaction"`
+	// MinimumAmountCents es el subtotal mínimo, en MinimumAmountCurrency.
+	MinimumAmountCents    *int64  `json:"minimum_amount_cents,omitempty"`
+	MinimumAmountCurrency *string `json:"minimum_amount_currency,omitempty"`
+}
+
+// Redeemable indica si el código se puede canjear en at (sin mirar su cupón).
+func (p PromotionCode) Redeemable(at time.Time) bool {
+	return p.Active && (p.ExpiresAt == nil || at.Before(*p.ExpiresAt)) &&
+		(p.MaxRedemptions == nil || p.TimesRedeemed < *p.MaxRedemptions)
+}
+
+// Discount es el canje de un cupón sobre una suscripción o una factura. Rige
+// desde Start hasta End; End nil es mientras no se use (once) o para siempre.
+type Discount struct {
+	ID              int        `json:"id"`
+	Coupon          Coupon     `json:"coupon"`
+	PromotionCodeID *int       `json:"promotion_code_id,omitempty"`
+	SubscriptionID  *int       `json:"subscription_id,omitempty"`
+	InvoiceID       *int       `json:"invoice_id,omitempty"`
+	Start           time.Time  `json:"start"`
+	End             *time.Time `json:"end,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// CouponUpdate son los campos a modificar de un cupón; nil mantiene el valor actual.
+type CouponUpdate struct {
+	Name   *string
+	Active *bool
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoupon_Apply(t *testing.T) {
+	percent := func(p int) Coupon { return Coupon{PercentOff: &p} }
+	amount := func(a int64) Coupon { return Coupon{AmountOffCents: &a} }
+
+	tests := []struct {
+		name   string
+		coupon Coupon
+		lines  []InvoiceLineItem
+		want   []int64
+	}{
+		{
+			name:   "percent per line rounds half up",
+			coupon: percent(25),
+			lines:  []InvoiceLineItem{{AmountCents: 1000}, {AmountCents: 10}, {AmountCents: 2}},
+			want:   []int64{250, 3, 1},
+		},
+		{
+			name:   "percent on what is left after a manual discount",
+			coupon: percent(50),
+			lines:  []InvoiceLineItem{{AmountCents: 1000, DiscountAmountCents: 200}},
+			want:   []int64{600},
+		},
+		{
+			name:   "hundred percent",
+			coupon: percent(100),
+			lines:  []InvoiceLineItem{{AmountCents: math.MaxInt64}},
+			want:   []int64{math.MaxInt64},
+		},
+		{
+			name:   "prorations and credits are not discounted",
+			coupon: percent(50),
+			lines:  []InvoiceLineItem{{AmountCents: 1000}, {AmountCents: 500, Proration: true}, {AmountCents: -300}},
+			want:   []int64{500, 0, 0},
+		},
+		{
+			name:   "amount split in proportion",
+			coupon: amount(1000),
+			lines:  []InvoiceLineItem{{AmountCents: 3000}, {AmountCents: 1000}},
+			want:   []int64{750, 250},
+		},
+		{
+			name:   "rounding cents go to the first lines",
+			coupon: amount(100),
+			lines:  []InvoiceLineItem{{AmountCents: 100}, {AmountCents: 100}, {AmountCents: 100}},
+			want:   []int64{34, 33, 33},
+		},
+		{
+			name:   "amount larger than the invoice",
+			coupon: amount(5000),
+			lines:  []InvoiceLineItem{{AmountCents: 1200}, {AmountCents: 800, DiscountAmountCents: 100}},
+			want:   []int64{1200, 800},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.coupon.Apply(tt.lines)
+			got := make([]int64, len(tt.lines))
+			for i, l := range tt.lines {
+				got[i] = l.DiscountAmountCents
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCoupon_Redeemable(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	one := 1
+	past := now.Add(-time.Second)
+
+	require.True(t, Coupon{Active: true}.Redeemable(now))
+	require.False(t, Coupon{Active: false}.Redeemable(now))
+	require.False(t, Coupon{Active: true, RedeemBy: &now}.Redeemable(now))
+	require.False(t, Coupon{Active: true, RedeemBy: &past}.Redeemable(now))
+	require.False(t, Coupon{Active: true, MaxRedemptions: &one, TimesRedeemed: 1}.Redeemable(now))
+}
+
+func TestCoupon_DiscountEnd(t *testing.T) {
+	start := time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC)
+	three := 3
+
+	require.Nil(t, Coupon{Duration: CouponOnce}.DiscountEnd(start))
+	require.Nil(t, Coupon{Duration: CouponForever}.DiscountEnd(start))
+	require.Equal(t, time.Date(2024, time.April, 30, 0, 0, 0, 0, time.UTC),
+		*Coupon{Duration: CouponRepeating, DurationInMonths: &three}.DiscountEnd(start))
+}
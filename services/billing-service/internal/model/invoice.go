@@ -1,13 +1,131 @@
 package model
 
-import "time"
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Estados de una factura. draft es editable; al finalizarla pasa a open, recibe
+// número y su contenido queda fijo. paid y void son terminales.
+const (
+	InvoiceDraft         = "draft"
+	InvoiceOpen          = "open"
+	InvoicePaid          = "paid"
+	InvoiceVoid          = "void"
+	InvoiceUncollectible = "uncollectible"
+)
+
+// ErrAmountTooLarge: un monto o un total no entra en int64.
+var ErrAmountTooLarge = errors.New("amount is too large")
 
 type Invoice struct {
-	ID          int       `json:"id"`
-	UserID      string    `json:"user_id"`
-	AmountCents int64     `json:"amount_cents"`
-	Currency    string    `json:"currency"`
-	Status      string    `json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID     int    `json:"id"`
+	UserID string `json:"user_id"`
+	// OrgID es la organización dueña de la factura; nil en facturas personales.
+	OrgID *string `json:"org_id,omitempty"`
+	// AmountCents es lo que se cobra: igual a AmountDueCents.
+	AmountCents int64  `json:"amount_cents"`
+	Currency    string `json:"currency"`
+	Status      string `json:"status"`
+	// Number se asigna al finalizar; nil en borradores.
+	Number            *string                  `json:"number,omitempty"`
+	StatusTransitions InvoiceStatusTransitions `json:"status_transitions"`
+	// Totales calculados a partir de Lines (ver ComputeTotals).
+	SubtotalCents      int64 `json:"subtotal_cents"`
+	TotalDiscountCents int64 `json:"total_discount_cents"`
+	TotalTaxCents      int64 `json:"total_tax_cents"`
+	TotalCents         int64 `json:"total_cents"`
+	AmountDueCents     int64 `json:"amount_due_cents"`
+	// PriceID y Quantity están si el monto salió de un precio del catálogo.
+	PriceID  *int `json:"price_id,omitempty"`
+	Quantity *int `json:"quantity,omitempty"`
+	// SubscriptionID y el período están en las facturas que genera la renovación.
+	SubscriptionID *int       `json:"subscription_id,omitempty"`
+	PeriodStart    *time.Time `json:"period_start,omitempty"`
+	PeriodEnd      *time.Time `json:"period_end,omitempty"`
+	// DiscountID es el descuento (de la factura o de su suscripción) aplicado en Lines.
+	DiscountID *int `json:"discount_id,omitempty"`
+	// Metadata son pares clave/valor libres de las integraciones.
+	Metadata map[string]string `json:"metadata"`
+	// Lines son los renglones de la factura; se guardan junto con ella.
+	Lines     []InvoiceLineItem `json:"lines,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// InvoiceStatusTransitions son los momentos en que la factura cambió de estado.
+type InvoiceStatusTransitions struct {
+	FinalizedAt           *time.Time `json:"finalized_at"`
+	PaidAt                *time.Time `json:"paid_at"`
+	VoidedAt              *time.Time `json:"voided_at"`
+	MarkedUncollectibleAt *time.Time `json:"marked_uncollectible_at"`
+}
+
+// InvoiceLineItem es un renglón de la factura: qué se cobra, cuántas unidades
+// y, si corresponde, de qué período. AmountCents es el importe antes de
+// descuentos e impuestos.
+type InvoiceLineItem struct {
+	ID                  int        `json:"id"`
+	Description         string     `json:"description"`
+	PriceID             *int       `json:"price_id,omitempty"`
+	Quantity            int        `json:"quantity"`
+	UnitAmountCents     int64      `json:"unit_amount_cents"`
+	AmountCents         int64      `json:"amount_cents"`
+	DiscountAmountCents int64      `json:"discount_amount_cents"`
+	TaxAmountCents      int64      `json:"tax_amount_cents"`
+	PeriodStart         *time.Time `json:"period_start,omitempty"`
+	PeriodEnd           *time.Time `json:"period_end,omitempty"`
+	// Proration marca los créditos (monto negativo) y débitos de un cambio de plan.
+	Proration bool `json:"proration"`
+	// PendingItemID es el prorrateo pendiente que este renglón factura.
+	PendingItemID *int `json:"-"`
+	// SubscriptionItemID es el ítem medido cuyo uso factura este renglón.
+	SubscriptionItemID *int `json:"subscription_item_id,omitempty"`
+}
+
+// ComputeTotals calcula los totales a partir de Lines: el subtotal es la suma
+// de los importes, el total le resta los descuentos y le suma los impuestos, y
+// amount_due es el total sin bajar de cero (un crédito no se paga al cliente ni
+// se arrastra a otra factura; queda solo en total_cents negativo).
+func (inv *Invoice) ComputeTotals() error {
+	var subtotal, discount, tax int64
+	var ok bool
+	for _, l := range inv.Lines {
+		if subtotal, ok = addCents(subtotal, l.AmountCents); !ok {
+			return ErrAmountTooLarge
+		}
+		if discount, ok = addCents(discount, l.DiscountAmountCents); !ok {
+			return ErrAmountTooLarge
+		}
+		if tax, ok = addCents(tax, l.TaxAmountCents); !ok {
+			return ErrAmountTooLarge
+		}
+	}
+	total, ok := addCents(subtotal, -discount)
+	if !ok {
+		return ErrAmountTooLarge
+	}
+	if total, ok = addCents(total, tax); !ok {
+		return ErrAmountTooLarge
+	}
+
+	inv.SubtotalCents = subtotal
+	inv.TotalDiscountCents = discount
+	inv.TotalTaxCents = tax
+	inv.TotalCents = total
+	inv.AmountDueCents = total
+	if total < 0 {
+		inv.AmountDueCents = 0
+	}
+	inv.AmountCents = inv.AmountDueCents
+	return nil
+}
+
+// addCents suma sin desbordar; ok es false si el resultado no entra en int64.
+func addCents(a, b int64) (int64, bool) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, false
+	}
+	return a + b, true
 }
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoice_ComputeTotals(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []InvoiceLineItem
+		want  Invoice
+	}{
+		{
+			name:  "no lines",
+			lines: nil,
+			want:  Invoice{},
+		},
+		{
+			name: "discounts and taxes",
+			lines: []InvoiceLineItem{
+				{AmountCents: 3000, DiscountAmountCents: 300, TaxAmountCents: 567},
+				{AmountCents: 1000, TaxAmountCents: 210},
+			},
+			want: Invoice{SubtotalCents: 4000, TotalDiscountCents: 300, TotalTaxCents: 777, TotalCents: 4477, AmountDueCents: 4477, AmountCents: 4477},
+		},
+		{
+			name: "credit does not make amount due negative",
+			lines: []InvoiceLineItem{
+				{AmountCents: 1500},
+				{AmountCents: -2948, Proration: true},
+			},
+			want: Invoice{SubtotalCents: -1448, TotalCents: -1448},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := Invoice{Lines: tt.lines}
+			require.NoError(t, inv.ComputeTotals())
+			tt.want.Lines = tt.lines
+			require.Equal(t, tt.want, inv)
+		})
+	}
+}
+
+func TestInvoice_ComputeTotals_Overflow(t *testing.T) {
+	inv := Invoice{Lines: []InvoiceLineItem{{AmountCents: math.MaxInt64}, {AmountCents: 1}}}
+	require.ErrorIs(t, inv.ComputeTotals(), ErrAmountTooLarge)
+
+	inv = Invoice{Lines: []InvoiceLineItem{{AmountCents: math.MaxInt64, TaxAmountCents: 1}}}
+	require.ErrorIs(t, inv.ComputeTotals(), ErrAmountTooLarge)
+}
@@ -0,0 +1,120 @@
+package model
+
+import "math"
+
+// Esquemas de cobro de un precio.
+const (
+	// SchemePerUnit cobra UnitAmountCents por unidad.
+	SchemePerUnit = "per_unit"
+	// SchemePackage cobra UnitAmountCents por paquete de PackageSize unidades,
+	// redondeando hacia arriba (11 unidades en paquetes de 10 son 2 paquetes).
+	SchemePackage = "package"
+	// SchemeTiered cobra según Tiers y TiersMode.
+	SchemeTiered = "tiered"
+)
+
+// Modos de un precio escalonado.
+const (
+	// TiersGraduated cobra cada unidad al precio del tramo en el que cae.
+	TiersGraduated = "graduated"
+	// TiersVolume cobra todas las unidades al precio del tramo al que llega el total.
+	TiersVolume = "volume"
+)
+
+// PriceTier es un tramo de un precio escalonado: las unidades hasta UpTo
+// (inclusive; nil en el último, sin tope) cuestan UnitAmountCents cada una, más
+// FlatAmountCents una vez si se llega al tramo.
+type PriceTier struct {
+	UpTo            *int64 `json:"up_to"`
+	UnitAmountCents int64  `json:"unit_amount_cents"`
+	FlatAmountCents int64  `json:"flat_amount_cents"`
+}
+
+// Amount es lo que cuestan quantity unidades del precio. 0 unidades no
+// cuestan nada, ni siquiera el cargo fijo de un tramo. Es la única cuenta de
+// importes a partir de un precio: la usan las facturas, las renovaciones, el
+// uso medido, los prorrateos y la vista previa de la próxima factura.
+func (p Price) Amount(quantity int64) (int64, error) {
+	if quantity <= 0 {
+		return 0, nil
+	}
+	switch p.BillingScheme {
+	case SchemePackage:
+		size := int64(1)
+		if p.PackageSize != nil && *p.PackageSize > 0 {
+			size = *p.PackageSize
+		}
+		packages := quantity / size
+		if quantity%size != 0 {
+			packages++
+		}
+		return mulCents(packages, p.UnitAmountCents)
+	case SchemeTiered:
+		if p.TiersMode != nil && *p.TiersMode == TiersVolume {
+			return volumeAmount(p.Tiers, quantity)
+		}
+		return graduatedAmount(p.Tiers, quantity)
+	}
+	return mulCents(quantity, p.UnitAmountCents)
+}
+
+// graduatedAmount suma, tramo por tramo, las unidades que caen en cada uno.
+func graduatedAmount(tiers []PriceTier, quantity int64) (int64, error) {
+	var total, from int64
+	for _, t := range tiers {
+		if quantity <= from {
+			break
+		}
+		units := quantity - from
+		if t.UpTo != nil && *t.UpTo < quantity {
+			units = *t.UpTo - from
+		}
+		amount, err := mulCents(units, t.UnitAmountCents)
+		if err != nil {
+			return 0, err
+		}
+		var ok bool
+		if total, ok = addCents(total, amount); !ok {
+			return 0, ErrAmountTooLarge
+		}
+		if total, ok = addCents(total, t.FlatAmountCents); !ok {
+			return 0, ErrAmountTooLarge
+		}
+		if t.UpTo == nil {
+			break
+		}
+		from = *t.UpTo
+	}
+	return total, nil
+}
+
+// volumeAmount cobra todas las unidades en el tramo al que llega quantity.
+func volumeAmount(tiers []PriceTier, quantity int64) (int64, error) {
+	if len(tiers) == 0 {
+		return 0, nil
+	}
+	tier := tiers[len(tiers)-1]
+	for _, t := range tiers {
+		if t.UpTo == nil || quantity <= *t.UpTo {
+			tier = t
+			break
+		}
+	}
+	amount, err := mulCents(quantity, tier.UnitAmountCents)
+	if err != nil {
+		return 0, err
+	}
+	total, ok := addCents(amount, tier.FlatAmountCents)
+	if !ok {
+		return 0, ErrAmountTooLarge
+	}
+	return total, nil
+}
+
+// mulCents multiplica sin desbordar (quantity y cents no negativos).
+func mulCents(quantity, cents int64) (int64, error) {
+	if quantity > 0 && cents > math.MaxInt64/quantity {
+		return 0, ErrAmountTooLarge
+	}
+	return quantity * cents, nil
+}
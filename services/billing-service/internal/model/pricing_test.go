@@ -0,0 +1,71 @@
+package model
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func upTo(n int64) *int64 { return &n }
+
+func tiered(mode string, tiers ...PriceTier) Price {
+	return Price{BillingScheme: SchemeTiered, TiersMode: &mode, Tiers: tiers}
+}
+
+func TestPrice_Amount(t *testing.T) {
+	size := int64(10)
+	// 1-5 a 10,00, 6-10 a 8,00 y desde 11 a 5,00 con un cargo fijo de 1,00.
+	steps := []PriceTier{
+		{UpTo: upTo(5), UnitAmountCents: 1000},
+		{UpTo: upTo(10), UnitAmountCents: 800},
+		{UnitAmountCents: 500, FlatAmountCents: 100},
+	}
+	// Hasta 10 unidades un fijo de 20,00; después 1,50 cada una.
+	base := []PriceTier{
+		{UpTo: upTo(10), FlatAmountCents: 2000},
+		{UnitAmountCents: 150},
+	}
+	tests := []struct {
+		name     string
+		price    Price
+		quantity int64
+		want     int64
+	}{
+		{"per unit", Price{UnitAmountCents: 250}, 3, 750},
+		{"per unit by default scheme", Price{BillingScheme: SchemePerUnit, UnitAmountCents: 250}, 4, 1000},
+		{"zero quantity", tiered(TiersGraduated, base...), 0, 0},
+		{"package rounds up", Price{BillingScheme: SchemePackage, PackageSize: &size, UnitAmountCents: 500}, 11, 1000},
+		{"package exact", Price{BillingScheme: SchemePackage, PackageSize: &size, UnitAmountCents: 500}, 10, 500},
+		{"package partial", Price{BillingScheme: SchemePackage, PackageSize: &size, UnitAmountCents: 500}, 1, 500},
+		{"graduated first tier", tiered(TiersGraduated, steps...), 3, 3000},
+		{"graduated tier boundary", tiered(TiersGraduated, steps...), 5, 5000},
+		{"graduated across tiers", tiered(TiersGraduated, steps...), 12, 5*1000 + 5*800 + 2*500 + 100},
+		{"volume first tier", tiered(TiersVolume, steps...), 5, 5000},
+		{"volume second tier", tiered(TiersVolume, steps...), 7, 7 * 800},
+		{"volume last tier", tiered(TiersVolume, steps...), 12, 12*500 + 100},
+		{"graduated flat fee", tiered(TiersGraduated, base...), 4, 2000},
+		{"graduated flat fee and units", tiered(TiersGraduated, base...), 14, 2000 + 4*150},
+		{"volume flat fee", tiered(TiersVolume, base...), 10, 2000},
+		{"volume past flat fee", tiered(TiersVolume, base...), 14, 14 * 150},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.price.Amount(tt.quantity)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPrice_Amount_Overflow(t *testing.T) {
+	_, err := Price{UnitAmountCents: math.MaxInt64}.Amount(2)
+	require.ErrorIs(t, err, ErrAmountTooLarge)
+
+	_, err = tiered(TiersGraduated, PriceTier{UpTo: upTo(1), UnitAmountCents: math.MaxInt64}, PriceTier{FlatAmountCents: 1}).Amount(2)
+	require.ErrorIs(t, err, ErrAmountTooLarge)
+
+	_, err = tiered(TiersVolume, PriceTier{UnitAmountCents: math.MaxInt64 / 2, FlatAmountCents: 2}).Amount(2)
+	require.ErrorIs(t, err, ErrAmountTooLarge)
+}
@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// Estados de una suscripción.
+const (
+	SubscriptionTrialing   = "trialing"
+	SubscriptionActive     = "active"
+	SubscriptionPastDue    = "past_due"
+	SubscriptionCanceled   = "canceled"
+	SubscriptionPaused     = "paused"
+	SubscriptionIncomplete = "incomplete"
+)
+
+// Subscription es el cobro recurrente de un precio a un cliente (el usuario
+// o, si hay una activa, su organización).
+type Subscription struct {
+	ID       int     `json:"id"`
+	UserID   string  `json:"user_id"`
+	OrgID    *string `json:"org_id,omitempty"`
+	PriceID  int     `json:"price_id"`
+	Quantity int     `json:"quantity"`
+	Status   string  `json:"status"`
+	// CurrentPeriodStart/End es el período en curso; durante la prueba coincide con ella.
+	CurrentPeriodStart time.Time  `json:"current_period_start"`
+	CurrentPeriodEnd   time.Time  `json:"current_period_end"`
+	TrialEnd           *time.Time `json:"trial_end,omitempty"`
+	// BillingCycleAnchor es desde donde se cuentan los períodos (ver Price.PeriodBoundary).
+	BillingCycleAnchor time.Time `json:"billing_cycle_anchor"`
+	// CancelAtPeriodEnd indica que se cancela al terminar el período en curso.
+	CancelAtPeriodEnd bool       `json:"cancel_at_period_end"`
+	CanceledAt        *time.Time `json:"canceled_at,omitempty"`
+	EndedAt           *time.Time `json:"ended_at,omitempty"`
+	PausedAt          *time.Time `json:"paused_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// SubscriptionUpdate son los cambios de una transición; nil mantiene el valor actual.
+type SubscriptionUpdate struct {
+	Status             *string
+	CancelAtPeriodEnd  *bool
+	CanceledAt         *time.Time
+	EndedAt            *time.Time
+	CurrentPeriodStart *time.Time
+	CurrentPeriodEnd   *time.Time
+	BillingCycleAnchor *time.Time
+}
+
+// SubscriptionChange es un cambio de precio o cantidad. Solo se aplica si la
+// suscripción sigue como cuando se calculó el prorrateo (Expected*).
+type SubscriptionChange struct {
+	PriceID           int
+	Quantity          int
+	ExpectedPriceID   int
+	ExpectedQuantity  int
+	ExpectedPeriodEnd time.Time
+	// PendingItems quedan para la próxima factura (create_prorations).
+	PendingItems []InvoiceLineItem
+	// Invoice, si no es nil, se crea junto con el cambio (always_invoice).
+	Invoice *Invoice
+}
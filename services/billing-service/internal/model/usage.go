@@ -0,0 +1,132 @@
+package model
+
+import "time"
+
+// Tipos de uso de un precio.
+const (
+	UsageLicensed = "licensed"
+	UsageMetered  = "metered"
+)
+
+// Cómo se agrega el uso de un período (Price.AggregateUsage).
+const (
+	AggregateSum              = "sum"
+	AggregateMax              = "max"
+	AggregateLastDuringPeriod = "last_during_period"
+)
+
+// Acciones de un registro de uso: increment suma al uso de su hora y set lo
+// reemplaza (una lectura, p. ej. los GB almacenados en ese momento).
+const (
+	UsageIncrement = "increment"
+	UsageSet       = "set"
+)
+
+// UsageBucketSize es el tamaño de los rollups de uso.
+const UsageBucketSize = time.Hour
+
+// SubscriptionItem es un precio medido dentro de una suscripción; el uso se
+// reporta contra el ítem.
+type SubscriptionItem struct {
+	ID             int `json:"id"`
+	SubscriptionID int `json:"subscription_id"`
+	PriceID        int `json:"price_id"`
+	// BilledThrough es hasta dónde se facturó el uso; lo posterior va en la
+	// próxima renovación.
+	BilledThrough time.Time `json:"billed_through"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// UsageRecord es un reporte de uso. IdempotencyKey es única por ítem: un
+// reintento con la misma clave no se cuenta de nuevo.
+type UsageRecord struct {
+	SubscriptionItemID int       `json:"subscription_item_id"`
+	IdempotencyKey     string    `json:"idempotency_key"`
+	Quantity           int64     `json:"quantity"`
+	Action             string    `json:"action"`
+	Timestamp          time.Time `json:"timestamp"`
+}
+
+// UsageRollup es el uso de un ítem en una hora (o en el tramo de hora dentro
+// de un período, ver UsageBucket).
+type UsageRollup struct {
+	SubscriptionItemID int
+	BucketStart        time.Time
+	Quantity           int64
+	// SetAt es el último set aplicado; los increments anteriores ya están
+	// incluidos en esa lectura.
+	SetAt          *time.Time
+	LastRecordedAt *time.Time
+	RecordCount    int
+}
+
+// UsageBucket es el inicio del rollup de un registro de at: la hora de at,
+// salvo que un borde de período posterior a esa hora (y no posterior a at) la
+// corte.
+func UsageBucket(at time.Time, boundaries ...time.Time) time.Time {
+	bucket := at.Truncate(UsageBucketSize)
+	for _, b := range boundaries {
+		if b.After(bucket) && !b.After(at) {
+			bucket = b
+		}
+	}
+	return bucket
+}
+
+// Add aplica el registro al rollup. Un increment o un set anterior al último
+// set no cambia la cantidad: esa lectura ya lo cubre.
+func (r *UsageRollup) Add(rec UsageRecord) error {
+	r.RecordCount++
+	if r.LastRecordedAt == nil || rec.Timestamp.After(*r.LastRecordedAt) {
+		at := rec.Timestamp
+		r.LastRecordedAt = &at
+	}
+	if r.SetAt != nil && rec.Timestamp.Before(*r.SetAt) {
+		return nil
+	}
+	if rec.Action == UsageSet {
+		at := rec.Timestamp
+		r.Quantity, r.SetAt = rec.Quantity, &at
+		return nil
+	}
+	quantity, ok := addCents(r.Quantity, rec.Quantity)
+	if !ok {
+		return ErrAmountTooLarge
+	}
+	r.Quantity = quantity
+	return nil
+}
+
+// AggregateUsage es el uso de un período a partir de sus rollups: la suma, el
+// máximo o el del último registro, según method. Sin rollups es 0.
+func AggregateUsage(method string, rollups []UsageRollup) (int64, error) {
+	var total int64
+	var last *time.Time
+	for _, r := range rollups {
+		switch method {
+		case AggregateMax:
+			if r.Quantity > total {
+				total = r.Quantity
+			}
+		case AggregateLastDuringPeriod:
+			if r.LastRecordedAt != nil && (last == nil || r.LastRecordedAt.After(*last)) {
+				total, last = r.Quantity, r.LastRecordedAt
+			}
+		default:
+			var ok bool
+			if total, ok = addCents(total, r.Quantity); !ok {
+				return 0, ErrAmountTooLarge
+			}
+		}
+	}
+	return total, nil
+}
+
+// UsageSummary es el uso todavía sin facturar de un ítem.
+type UsageSummary struct {
+	SubscriptionItemID int       `json:"subscription_item_id"`
+	PriceID            int       `json:"price_id"`
+	AggregateUsage     string    `json:"aggregate_usage"`
+	PeriodStart        time.Time `json:"period_start"`
+	TotalUsage         int64     `json:"total_usage"`
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageBucket(t *testing.T) {
+	periodStart := time.Date(2024, time.March, 1, 10, 45, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		at   time.Time
+		want time.Time
+	}{
+		{"hour", time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC), time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)},
+		{"before period boundary", time.Date(2024, time.March, 1, 10, 30, 0, 0, time.UTC), time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)},
+		{"at period boundary", periodStart, periodStart},
+		{"after period boundary", time.Date(2024, time.March, 1, 10, 59, 0, 0, time.UTC), periodStart},
+		{"next hour", time.Date(2024, time.March, 1, 11, 5, 0, 0, time.UTC), time.Date(2024, time.March, 1, 11, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, UsageBucket(tt.at, periodStart))
+		})
+	}
+}
+
+func TestUsageRollup_Add(t *testing.T) {
+	base := time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+	tests := []struct {
+		name    string
+		records []UsageRecord
+		want    int64
+	}{
+		{
+			name: "increments add up",
+			records: []UsageRecord{
+				{Action: UsageIncrement, Quantity: 5, Timestamp: at(1)},
+				{Action: UsageIncrement, Quantity: 7, Timestamp: at(2)},
+			},
+			want: 12,
+		},
+		{
+			name: "set replaces earlier usage",
+			records: []UsageRecord{
+				{Action: UsageIncrement, Quantity: 5, Timestamp: at(1)},
+				{Action: UsageSet, Quantity: 3, Timestamp: at(10)},
+				{Action: UsageIncrement, Quantity: 2, Timestamp: at(20)},
+			},
+			want: 5,
+		},
+		{
+			name: "late records before the last set are covered by it",
+			records: []UsageRecord{
+				{Action: UsageSet, Quantity: 40, Timestamp: at(30)},
+				{Action: UsageIncrement, Quantity: 5, Timestamp: at(10)},
+				{Action: UsageSet, Quantity: 10, Timestamp: at(20)},
+			},
+			want: 40,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r UsageRollup
+			for _, rec := range tt.records {
+				require.NoError(t, r.Add(rec))
+			}
+			require.Equal(t, tt.want, r.Quantity)
+			require.Equal(t, len(tt.records), r.RecordCount)
+		})
+	}
+}
+
+func TestUsageRollup_Add_Overflow(t *testing.T) {
+	r := UsageRollup{Quantity: math.MaxInt64}
+	require.ErrorIs(t, r.Add(UsageRecord{Action: UsageIncrement, Quantity: 1}), ErrAmountTooLarge)
+}
+
+func TestAggregateUsage(t *testing.T) {
+	first := time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)
+	second := first.Add(2 * time.Hour)
+	rollups := []UsageRollup{
+		{Quantity: 30, LastRecordedAt: &second},
+		{Quantity: 50, LastRecordedAt: &first},
+	}
+	tests := []struct {
+		method string
+		want   int64
+	}{
+		{AggregateSum, 80},
+		{AggregateMax, 50},
+		{AggregateLastDuringPeriod, 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			got, err := AggregateUsage(tt.method, rollups)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	got, err := AggregateUsage(AggregateSum, nil)
+	require.NoError(t, err)
+	require.Zero(t, got)
+}
@@ -0,0 +1,356 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/services/billing-service/internal/model"
+)
+
+var (
+	// ErrProductNotFound: el producto del precio no existe.
+	ErrProductNotFound = errors.New("product not found")
+	// ErrProductHasPrices: un producto con precios no se borra, se archiva.
+	ErrProductHasPrices = errors.New("product has prices; archive it instead")
+	// ErrPriceInUse: el precio ya se usó y su monto, moneda e intervalo no cambian.
+	ErrPriceInUse = errors.New("price is in use; create a new price instead")
+	// ErrLookupKeyTaken: otro precio activo ya tiene esa lookup key.
+	ErrLookupKeyTaken = errors.New("lookup key is already used by an active price")
+)
+
+// priceUsed es la condición "el precio ya se usó" (en una factura o alguno de
+// sus renglones, una suscripción o un ítem medido) sobre la fila de prices.
+// Las facturas armadas con renglones, los prorrateos y el uso medido solo lo
+// referencian desde invoice_line_items.
+const priceUsed = `(EXISTS (SELECT 1 FROM invoices WHERE invoices.price_id = prices.id)
+	OR EXISTS (SELECT 1 FROM invoice_line_items WHERE invoice_line_items.price_id = prices.id)
+	OR EXISTS (SELECT 1 FROM subscriptions WHERE subscriptions.price_id = prices.id)
+	OR EXISTS (SELECT 1 FROM subscription_items WHERE subscription_items.price_id = prices.id))`
+
+const productColumns = `id, name, description, active, created_at, updated_at`
+
+const priceColumns = `id, product_id, currency, unit_amount_cents, billing_interval, interval_count, usage_type, aggregate_usage,
+	billing_scheme, package_size, tiers_mode, tiers, active, lookup_key, created_at, updated_at`
+
+// ProductFilter acota el listado de productos; Active nil no filtra.
+type ProductFilter struct {
+	Active *bool
+	Limit  int
+	Offset int
+}
+
+// PriceFilter acota el listado de precios; los campos vacíos no filtran.
+type PriceFilter struct {
+	ProductID  int
+	Active     *bool
+	LookupKeys []string
+	Limit      int
+	Offset     int
+}
+
+type CatalogRepository struct {
+	db     *sql.DB
+	limits dbquery.Limits
+}
+
+// NewCatalogRepository aplica limits (deadline y log de queries lentas) a cada query.
+func NewCatalogRepository(db *sql.DB, limits dbquery.Limits) *CatalogRepository {
+	return &CatalogRepository{db: db, limits: limits}
+}
+
+func scanProduct(row rowScanner) (*model.Product, error) {
+	p := &model.Product{}
+	err := row.Scan(&p.ID, &p.Name, &p.Description, &p.Active, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+func scanPrice(row rowScanner) (*model.Price, error) {
+	p := &model.Price{}
+	err := row.Scan(&p.ID, &p.ProductID, &p.Currency, &p.UnitAmountCents, &p.Interval, &p.IntervalCount, &p.UsageType, &p.AggregateUsage,
+		&p.BillingScheme, &p.PackageSize, &p.TiersMode, (*jsonTiers)(&p.Tiers), &p.Active, &p.LookupKey, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+// jsonTiers adapta los tramos de un precio a la columna JSONB; sin tramos es NULL.
+type jsonTiers []model.PriceTier
+
+func (t jsonTiers) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal([]model.PriceTier(t))
+	return string(b), err
+}
+
+func (t *jsonTiers) Scan(src interface{}) error {
+	var raw []byte
+	switch v := src.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	case nil:
+		*t = nil
+		return nil
+	default:
+		return fmt.Errorf("unsupported tiers type %T", src)
+	}
+	var out []model.PriceTier
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return err
+	}
+	*t = out
+	return nil
+}
+
+func pqErrorCode(err error) pq.ErrorCode {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code
+	}
+	return ""
+}
+
+// pageClause agrega LIMIT/OFFSET con los mismos defaults que el listado de facturas.
+func pageClause(args []interface{}, limit, offset int) (string, []interface{}) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	args = append(args, limit, offset)
+	return fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args)), args
+}
+
+func (r *CatalogRepository) CreateProduct(ctx context.Context, p *model.Product) error {
+	ctx, done := r.limits.Start(ctx, "products.create")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `INSERT INTO products (name, description, active) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`
+	if err := r.db.QueryRowContext(ctx, query, p.Name, p.Description, p.Active).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create product: %w", err)
+	}
+	return nil
+}
+
+// GetProduct devuelve nil si el producto no existe.
+func (r *CatalogRepository) GetProduct(ctx context.Context, id int) (*model.Product, error) {
+	ctx, done := r.limits.Start(ctx, "products.get_by_id")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	p, err := scanProduct(r.db.QueryRowContext(ctx, `SELECT `+productColumns+` FROM products WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch product: %w", err)
+	}
+	return p, nil
+}
+
+func (r *CatalogRepository) ListProducts(ctx context.Context, filter ProductFilter) ([]*model.Product, error) {
+	ctx, done := r.limits.Start(ctx, "products.list")
+	defer done()
+
+	var args []interface{}
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT ` + productColumns + ` FROM products`
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		query += ` WHERE active = $1`
+	}
+	page, args := pageClause(args, filter.Limit, filter.Offset)
+	query += ` ORDER BY id` + page
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	products := []*model.Product{}
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// UpdateProduct devuelve nil si el producto no existe.
+func (r *CatalogRepository) UpdateProduct(ctx context.Context, id int, u model.ProductUpdate) (*model.Product, error) {
+	ctx, done := r.limits.Start(ctx, "products.update")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `UPDATE products SET
+			name = COALESCE($2, name),
+			description = COALESCE($3, description),
+			active = COALESCE($4, active),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING ` + productColumns
+	p, err := scanProduct(r.db.QueryRowContext(ctx, query, id, u.Name, u.Description, u.Active))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update product: %w", err)
+	}
+	return p, nil
+}
+
+// DeleteProduct borra un producto sin precios. Devuelve false si no existe y
+// ErrProductHasPrices si tiene alguno.
+func (r *CatalogRepository) DeleteProduct(ctx context.Context, id int) (bool, error) {
+	ctx, done := r.limits.Start(ctx, "products.delete")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `DELETE FROM products WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM prices WHERE product_id = $1)`
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete product: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return true, nil
+	}
+
+	p, err := r.GetProduct(ctx, id)
+	if err != nil || p == nil {
+		return false, err
+	}
+	return true, ErrProductHasPrices
+}
+
+func (r *CatalogRepository) CreatePrice(ctx context.Context, p *model.Price) error {
+	ctx, done := r.limits.Start(ctx, "prices.create")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `INSERT INTO prices (product_id, currency, unit_amount_cents, billing_interval, interval_count, active, lookup_key,
+			usage_type, aggregate_usage, billing_scheme, package_size, tiers_mode, tiers)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13::jsonb) RETURNING id, created_at, updated_at`
+	err := r.db.QueryRowContext(ctx, query, p.ProductID, p.Currency, p.UnitAmountCents, p.Interval, p.IntervalCount, p.Active, p.LookupKey,
+		p.UsageType, p.AggregateUsage, p.BillingScheme, p.PackageSize, p.TiersMode, jsonTiers(p.Tiers)).
+		Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	switch pqErrorCode(err) {
+	case "":
+	case "23503": // foreign_key_violation
+		return ErrProductNotFound
+	case "23505": // unique_violation
+		return ErrLookupKeyTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create price: %w", err)
+	}
+	return nil
+}
+
+// GetPrice devuelve nil si el precio no existe.
+func (r *CatalogRepository) GetPrice(ctx context.Context, id int) (*model.Price, error) {
+	ctx, done := r.limits.Start(ctx, "prices.get_by_id")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	p, err := scanPrice(r.db.QueryRowContext(ctx, `SELECT `+priceColumns+` FROM prices WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch price: %w", err)
+	}
+	return p, nil
+}
+
+func (r *CatalogRepository) ListPrices(ctx context.Context, filter PriceFilter) ([]*model.Price, error) {
+	ctx, done := r.limits.Start(ctx, "prices.list")
+	defer done()
+
+	var args []interface{}
+	var clauses []string
+	if filter.ProductID != 0 {
+		args = append(args, filter.ProductID)
+		clauses = append(clauses, fmt.Sprintf("product_id = $%d", len(args)))
+	}
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		clauses = append(clauses, fmt.Sprintf("active = $%d", len(args)))
+	}
+	if len(filter.LookupKeys) > 0 {
+		args = append(args, pq.Array(filter.LookupKeys))
+		clauses = append(clauses, fmt.Sprintf("lookup_key = ANY($%d)", len(args)))
+	}
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT ` + priceColumns + ` FROM prices`
+	if len(clauses) > 0 {
+		query += ` WHERE ` + strings.Join(clauses, " AND ")
+	}
+	page, args := pageClause(args, filter.Limit, filter.Offset)
+	query += ` ORDER BY id` + page
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch prices: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	prices := []*model.Price{}
+	for rows.Next() {
+		p, err := scanPrice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan price: %w", err)
+		}
+		prices = append(prices, p)
+	}
+	return prices, rows.Err()
+}
+
+// UpdatePrice devuelve nil si el precio no existe. Si u cambia monto, moneda o
+// intervalo y el precio ya se usó, no toca nada y devuelve ErrPriceInUse; la
+// condición va en el mismo UPDATE para no competir con una factura nueva.
+func (r *CatalogRepository) UpdatePrice(ctx context.Context, id int, u model.PriceUpdate) (*model.Price, error) {
+	ctx, done := r.limits.Start(ctx, "prices.update")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `UPDATE prices SET
+			active = COALESCE($2, active),
+			lookup_key = CASE WHEN $3::text IS NULL THEN lookup_key ELSE NULLIF($3::text, '') END,
+			unit_amount_cents = COALESCE($4, unit_amount_cents),
+			currency = COALESCE($5, currency),
+			billing_interval = COALESCE($6, billing_interval),
+			interval_count = COALESCE($7, interval_count),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND NOT ($8 AND ` + priceUsed + `)
+		RETURNING ` + priceColumns
+	p, err := scanPrice(r.db.QueryRowContext(ctx, query, id, u.Active, u.LookupKey, u.UnitAmountCents, u.Currency, u.Interval, u.IntervalCount, u.Terms()))
+	if pqErrorCode(err) == "23505" {
+		return nil, ErrLookupKeyTaken
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		existing, getErr := r.GetPrice(ctx, id)
+		if getErr != nil || existing == nil {
+			return nil, getErr
+		}
+		return nil, ErrPriceInUse
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update price: %w", err)
+	}
+	return p, nil
+}
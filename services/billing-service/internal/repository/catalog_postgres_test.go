@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/services/billing-service/internal/model"
+)
+
+// scriptedQuery es la respuesta del driver de prueba a la próxima query: la
+// query tiene que contener want y devuelve rows con columnas cols.
+type scriptedQuery struct {
+	want []string
+	cols []string
+	rows [][]driver.Value
+}
+
+// scriptedDriver es un driver database/sql mínimo que responde las queries en
+// orden; alcanza para probar el SQL que arma el repositorio sin Postgres.
+type scriptedDriver struct {
+	mu      sync.Mutex
+	queries []scriptedQuery
+}
+
+var (
+	scriptedMu      sync.Mutex
+	scriptedDrivers = map[string]*scriptedDriver{}
+)
+
+func init() {
+	sql.Register("scripted", scriptedRouter{})
+}
+
+type scriptedRouter struct{}
+
+func (scriptedRouter) Open(name string) (driver.Conn, error) {
+	scriptedMu.Lock()
+	defer scriptedMu.Unlock()
+	d, ok := scriptedDrivers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scripted db %q", name)
+	}
+	return &scriptedConn{d: d}, nil
+}
+
+func newScriptedDB(t *testing.T, queries ...scriptedQuery) *sql.DB {
+	t.Helper()
+	scriptedMu.Lock()
+	scriptedDrivers[t.Name()] = &scriptedDriver{queries: queries}
+	scriptedMu.Unlock()
+	db, err := sql.Open("scripted", t.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+		scriptedMu.Lock()
+		defer scriptedMu.Unlock()
+		require.Empty(t, scriptedDrivers[t.Name()].queries, "queries not run")
+		delete(scriptedDrivers, t.Name())
+	})
+	return db
+}
+
+type scriptedConn struct{ d *scriptedDriver }
+
+func (c *scriptedConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepare not supported")
+}
+func (c *scriptedConn) Close() error              { return nil }
+func (c *scriptedConn) Begin() (driver.Tx, error) { return nil, fmt.Errorf("tx not supported") }
+
+func (c *scriptedConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	if len(c.d.queries) == 0 {
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+	next := c.d.queries[0]
+	c.d.queries = c.d.queries[1:]
+	for _, want := range next.want {
+		if !strings.Contains(query, want) {
+			return nil, fmt.Errorf("query does not contain %q: %s", want, query)
+		}
+	}
+	return &scriptedRows{cols: next.cols, rows: next.rows}, nil
+}
+
+type scriptedRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *scriptedRows) Columns() []string { return r.cols }
+func (r *scriptedRows) Close() error      { return nil }
+
+func (r *scriptedRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestCatalogRepository_UpdatePrice_UsedByInvoiceLine(t *testing.T) {
+	cols := strings.Split(strings.Join(strings.Fields(priceColumns), ""), ",")
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	db := newScriptedDB(t,
+		// El precio solo figura en un renglón de factura: el UPDATE guardado no toca nada.
+		scriptedQuery{want: []string{"UPDATE prices", "invoice_line_items.price_id = prices.id"}, cols: cols},
+		scriptedQuery{want: []string{"FROM prices WHERE id = $1"}, cols: cols, rows: [][]driver.Value{{
+			int64(9), int64(1), "USD", int64(1500), "month", int64(1), "licensed", nil,
+			"per_unit", nil, nil, nil, true, nil, now, now,
+		}}},
+	)
+	repo := NewCatalogRepository(db, dbquery.Limits{})
+
+	amount := int64(2000)
+	_, err := repo.UpdatePrice(context.Background(), 9, model.PriceUpdate{UnitAmountCents: &amount})
+	require.ErrorIs(t, err, ErrPriceInUse)
+}
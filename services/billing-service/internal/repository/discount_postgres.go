@@ -0,0 +1,491 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/services/billing-service/internal/model"
+)
+
+var (
+	// ErrCouponNotFound: el cupón del código promocional no existe.
+	ErrCouponNotFound = errors.New("coupon not found")
+	// ErrPromotionCodeTaken: ya hay un código igual (sin distinguir mayúsculas).
+	ErrPromotionCodeTaken = errors.New("promotion code already exists")
+	// ErrNotRedeemable: el cupón o el código está inactivo, vencido o sin canjes disponibles.
+	ErrNotRedeemable = errors.New("coupon or promotion code is not redeemable")
+	// ErrInvoiceDiscounted: la factura ya tiene un descuento.
+	ErrInvoiceDiscounted = errors.New("invoice already has a discount")
+)
+
+const couponColumns = `id, name, percent_off, amount_off_cents, currency, duration, duration_in_months, max_redemptions,
+	times_redeemed, redeem_by, active, created_at, updated_at`
+
+const promotionCodeColumns = `id, code, coupon_id, active, max_redemptions, times_redeemed, expires_at,
+	first_time_transaction, minimum_amount_cents, minimum_amount_currency, created_at, updated_at`
+
+// discountSelect lee un descuento con su cupón (alias d y c).
+const discountSelect = `SELECT d.id, d.promotion_code_id, d.subscription_id, d.invoice_id, d.start_at, d.end_at, d.created_at,
+	c.id, c.name, c.percent_off, c.amount_off_cents, c.currency, c.duration, c.duration_in_months, c.max_redemptions,
+	c.times_redeemed, c.redeem_by, c.active, c.created_at, c.updated_at
+	FROM discounts d JOIN coupons c ON c.id = d.coupon_id`
+
+// CouponFilter acota el listado de cupones; Active nil no filtra.
+type CouponFilter struct {
+	Active *bool
+	Limit  int
+	Offset int
+}
+
+// PromotionCodeFilter acota el listado de códigos; los campos vacíos no filtran.
+type PromotionCodeFilter struct {
+	CouponID int
+	Active   *bool
+	Code     string
+	Limit    int
+	Offset   int
+}
+
+type DiscountRepository struct {
+	db     *sql.DB
+	limits dbquery.Limits
+}
+
+// NewDiscountRepository aplica limits (deadline y log de queries lentas) a cada query.
+func NewDiscountRepository(db *sql.DB, limits dbquery.Limits) *DiscountRepository {
+	return &DiscountRepository{db: db, limits: limits}
+}
+
+func scanCoupon(row rowScanner) (*model.Coupon, error) {
+	c := &model.Coupon{}
+	err := row.Scan(&c.ID, &c.Name, &c.PercentOff, &c.AmountOffCents, &c.Currency, &c.Duration, &c.DurationInMonths,
+		&c.MaxRedemptions, &c.TimesRedeemed, &c.RedeemBy, &c.Active, &c.CreatedAt, &c.UpdatedAt)
+	return c, err
+}
+
+func scanPromotionCode(row rowScanner) (*model.PromotionCode, error) {
+	p := &model.PromotionCode{}
+	err := row.Scan(&p.ID, &p.Code, &p.CouponID, &p.Active, &p.MaxRedemptions, &p.TimesRedeemed, &p.ExpiresAt,
+		&p.Restrictions.FirstTimeTransaction, &p.Restrictions.MinimumAmountCents, &p.Restrictions.MinimumAmountCurrency,
+		&p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+func scanDiscount(row rowScanner) (*model.Discount, error) {
+	d := &model.Discount{}
+	c := &d.Coupon
+	err := row.Scan(&d.ID, &d.PromotionCodeID, &d.SubscriptionID, &d.InvoiceID, &d.Start, &d.End, &d.CreatedAt,
+		&c.ID, &c.Name, &c.PercentOff, &c.AmountOffCents, &c.Currency, &c.Duration, &c.DurationInMonths,
+		&c.MaxRedemptions, &c.TimesRedeemed, &c.RedeemBy, &c.Active, &c.CreatedAt, &c.UpdatedAt)
+	return d, err
+}
+
+func (r *DiscountRepository) CreateCoupon(ctx context.Context, c *model.Coupon) error {
+	ctx, done := r.limits.Start(ctx, "coupons.create")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `INSERT INTO coupons (name, percent_off, amount_off_cents, currency, duration, duration_in_months, max_redemptions, redeem_by, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, times_redeemed, created_at, updated_at`
+	err := r.db.QueryRowContext(ctx, query, c.Name, c.PercentOff, c.AmountOffCents, c.Currency, c.Duration, c.DurationInMonths,
+		c.MaxRedemptions, c.RedeemBy, c.Active).Scan(&c.ID, &c.TimesRedeemed, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create coupon: %w", err)
+	}
+	return nil
+}
+
+// GetCoupon devuelve nil si el cupón no existe.
+func (r *DiscountRepository) GetCoupon(ctx context.Context, id int) (*model.Coupon, error) {
+	ctx, done := r.limits.Start(ctx, "coupons.get_by_id")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	c, err := scanCoupon(r.db.QueryRowContext(ctx, `SELECT `+couponColumns+` FROM coupons WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch coupon: %w", err)
+	}
+	return c, nil
+}
+
+func (r *DiscountRepository) ListCoupons(ctx context.Context, filter CouponFilter) ([]*model.Coupon, error) {
+	ctx, done := r.limits.Start(ctx, "coupons.list")
+	defer done()
+
+	var args []interface{}
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT ` + couponColumns + ` FROM coupons`
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		query += ` WHERE active = $1`
+	}
+	page, args := pageClause(args, filter.Limit, filter.Offset)
+	query += ` ORDER BY id` + page
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch coupons: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	coupons := []*model.Coupon{}
+	for rows.Next() {
+		c, err := scanCoupon(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan coupon: %w", err)
+		}
+		coupons = append(coupons, c)
+	}
+	return coupons, rows.Err()
+}
+
+// UpdateCoupon devuelve nil si el cupón no existe.
+func (r *DiscountRepository) UpdateCoupon(ctx context.Context, id int, u model.CouponUpdate) (*model.Coupon, error) {
+	ctx, done := r.limits.Start(ctx, "coupons.update")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `UPDATE coupons SET
+			name = COALESCE($2, name),
+			active = COALESCE($3, active),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING ` + couponColumns
+	c, err := scanCoupon(r.db.QueryRowContext(ctx, query, id, u.Name, u.Active))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update coupon: %w", err)
+	}
+	return c, nil
+}
+
+func (r *DiscountRepository) CreatePromotionCode(ctx context.Context, p *model.PromotionCode) error {
+	ctx, done := r.limits.Start(ctx, "promotion_codes.create")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `INSERT INTO promotion_codes (code, coupon_id, active, max_redemptions, expires_at, first_time_transaction,
+			minimum_amount_cents, minimum_amount_currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, times_redeemed, created_at, updated_at`
+	err := r.db.QueryRowContext(ctx, query, p.Code, p.CouponID, p.Active, p.MaxRedemptions, p.ExpiresAt,
+		p.Restrictions.FirstTimeTransaction, p.Restrictions.MinimumAmountCents, p.Restrictions.MinimumAmountCurrency).
+		Scan(&p.ID, &p.TimesRedeemed, &p.CreatedAt, &p.UpdatedAt)
+	switch pqErrorCode(err) {
+	case "":
+	case "23503": // foreign_key_violation
+		return ErrCouponNotFound
+	case "23505": // unique_violation
+		return ErrPromotionCodeTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create promotion code: %w", err)
+	}
+	return nil
+}
+
+// GetPromotionCode devuelve nil si el código no existe.
+func (r *DiscountRepository) GetPromotionCode(ctx context.Context, id int) (*model.PromotionCode, error) {
+	ctx, done := r.limits.Start(ctx, "promotion_codes.get_by_id")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	p, err := scanPromotionCode(r.db.QueryRowContext(ctx, `SELECT `+promotionCodeColumns+` FROM promotion_codes WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch promotion code: %w", err)
+	}
+	return p, nil
+}
+
+// FindPromotionCode busca por código sin distinguir mayúsculas; nil si no existe.
+func (r *DiscountRepository) FindPromotionCode(ctx context.Context, code string) (*model.PromotionCode, error) {
+	ctx, done := r.limits.Start(ctx, "promotion_codes.get_by_code")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT ` + promotionCodeColumns + ` FROM promotion_codes WHERE lower(code) = lower($1)`
+	p, err := scanPromotionCode(r.db.QueryRowContext(ctx, query, code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch promotion code: %w", err)
+	}
+	return p, nil
+}
+
+func (r *DiscountRepository) ListPromotionCodes(ctx context.Context, filter PromotionCodeFilter) ([]*model.PromotionCode, error) {
+	ctx, done := r.limits.Start(ctx, "promotion_codes.list")
+	defer done()
+
+	var args []interface{}
+	var clauses []string
+	if filter.CouponID != 0 {
+		args = append(args, filter.CouponID)
+		clauses = append(clauses, fmt.Sprintf("coupon_id = $%d", len(args)))
+	}
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		clauses = append(clauses, fmt.Sprintf("active = $%d", len(args)))
+	}
+	if filter.Code != "" {
+		args = append(args, filter.Code)
+		clauses = append(clauses, fmt.Sprintf("lower(code) = lower($%d)", len(args)))
+	}
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT ` + promotionCodeColumns + ` FROM promotion_codes`
+	if len(clauses) > 0 {
+		query += ` WHERE ` + strings.Join(clauses, " AND ")
+	}
+	page, args := pageClause(args, filter.Limit, filter.Offset)
+	query += ` ORDER BY id` + page
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch promotion codes: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	codes := []*model.PromotionCode{}
+	for rows.Next() {
+		p, err := scanPromotionCode(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan promotion code: %w", err)
+		}
+		codes = append(codes, p)
+	}
+	return codes, rows.Err()
+}
+
+// UpdatePromotionCode activa o desactiva el código; nil si no existe.
+func (r *DiscountRepository) UpdatePromotionCode(ctx context.Context, id int, active *bool) (*model.PromotionCode, error) {
+	ctx, done := r.limits.Start(ctx, "promotion_codes.update")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `UPDATE promotion_codes SET active = COALESCE($2, active), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 RETURNING ` + promotionCodeColumns
+	p, err := scanPromotionCode(r.db.QueryRowContext(ctx, query, id, active))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update promotion code: %w", err)
+	}
+	return p, nil
+}
+
+// HasInvoiced indica si el cliente del scope tiene alguna factura emitida
+// (los borradores y las anuladas no cuentan).
+func (r *DiscountRepository) HasInvoiced(ctx context.Context, scope InvoiceScope) (bool, error) {
+	ctx, done := r.limits.Start(ctx, "invoices.has_invoiced")
+	defer done()
+
+	cond, args := scope.where(nil)
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT EXISTS (SELECT 1 FROM invoices WHERE status IN ('open', 'paid', 'uncollectible') AND ` + cond + `)`
+	var invoiced bool
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&invoiced); err != nil {
+		return false, fmt.Errorf("failed to check invoices: %w", err)
+	}
+	return invoiced, nil
+}
+
+// GetDiscount devuelve el descuento con su cupón; nil si no existe.
+func (r *DiscountRepository) GetDiscount(ctx context.Context, id int) (*model.Discount, error) {
+	ctx, done := r.limits.Start(ctx, "discounts.get_by_id")
+	defer done()
+
+	d, err := scanDiscount(r.db.QueryRowContext(ctx, discountSelect+` WHERE d.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch discount: %w", err)
+	}
+	return d, nil
+}
+
+// SubscriptionDiscount devuelve el descuento de la suscripción que rige en
+// at; nil si no tiene.
+func (r *DiscountRepository) SubscriptionDiscount(ctx context.Context, subscriptionID int, at time.Time) (*model.Discount, error) {
+	ctx, done := r.limits.Start(ctx, "discounts.get_by_subscription")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := discountSelect + ` WHERE d.subscription_id = $1 AND d.start_at <= $2 AND (d.end_at IS NULL OR d.end_at > $2)
+		ORDER BY d.start_at DESC LIMIT 1`
+	d, err := scanDiscount(r.db.QueryRowContext(ctx, query, subscriptionID, at))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscription discount: %w", err)
+	}
+	return d, nil
+}
+
+// inTx ejecuta fn en una transacción: si fn falla no queda nada aplicado.
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// redeem suma un canje al código promocional (si hay) y al cupón de d, solo si
+// siguen canjeables en d.Start, y guarda el descuento. Deja d.Coupon con los
+// contadores actualizados y ErrNotRedeemable si alguno ya no se puede canjear.
+// Va en una transacción: si falla el segundo contador, el primero no debe
+// quedar sumado.
+func redeem(ctx context.Context, tx *sql.Tx, d *model.Discount) error {
+	if d.PromotionCodeID != nil {
+		//goland:noinspection SqlNoDataSourceInspection
+		res, err := tx.ExecContext(ctx, `UPDATE promotion_codes SET times_redeemed = times_redeemed + 1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND coupon_id = $2 AND active AND (expires_at IS NULL OR expires_at > $3)
+				AND (max_redemptions IS NULL OR times_redeemed < max_redemptions)`, *d.PromotionCodeID, d.Coupon.ID, d.Start)
+		if err != nil {
+			return fmt.Errorf("failed to redeem promotion code: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotRedeemable
+		}
+	}
+
+	//goland:noinspection SqlNoDataSourceInspection
+	coupon, err := scanCoupon(tx.QueryRowContext(ctx, `UPDATE coupons SET times_redeemed = times_redeemed + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND active AND (redeem_by IS NULL OR redeem_by > $2)
+			AND (max_redemptions IS NULL OR times_redeemed < max_redemptions)
+		RETURNING `+couponColumns, d.Coupon.ID, d.Start))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotRedeemable
+	}
+	if err != nil {
+		return fmt.Errorf("failed to redeem coupon: %w", err)
+	}
+	d.Coupon = *coupon
+
+	//goland:noinspection SqlNoDataSourceInspection
+	err = tx.QueryRowContext(ctx, `INSERT INTO discounts (coupon_id, promotion_code_id, subscription_id, invoice_id, start_at, end_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		d.Coupon.ID, d.PromotionCodeID, d.SubscriptionID, d.InvoiceID, d.Start, d.End).Scan(&d.ID, &d.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create discount: %w", err)
+	}
+	return nil
+}
+
+// ApplySubscriptionDiscount canjea d sobre la suscripción d.SubscriptionID del
+// scope y termina el descuento que tuviera. Devuelve false si la suscripción
+// no existe en el scope, ErrInvalidTransition si está cancelada y
+// ErrNotRedeemable si el canje ya no es posible.
+func (r *DiscountRepository) ApplySubscriptionDiscount(ctx context.Context, scope InvoiceScope, d *model.Discount) (bool, error) {
+	ctx, done := r.limits.Start(ctx, "discounts.apply_subscription")
+	defer done()
+
+	found := false
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		cond, args := scope.where([]interface{}{*d.SubscriptionID})
+		var status string
+		// El lock ordena los canjes concurrentes sobre la misma suscripción.
+		//goland:noinspection SqlNoDataSourceInspection
+		err := tx.QueryRowContext(ctx, `SELECT status FROM subscriptions WHERE id = $1 AND `+cond+` FOR UPDATE`, args...).Scan(&status)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to fetch subscription: %w", err)
+		}
+		found = true
+		if status == model.SubscriptionCanceled {
+			return ErrInvalidTransition
+		}
+
+		//goland:noinspection SqlNoDataSourceInspection
+		_, err = tx.ExecContext(ctx, `UPDATE discounts SET end_at = $2
+			WHERE subscription_id = $1 AND (end_at IS NULL OR end_at > $2)`, *d.SubscriptionID, d.Start)
+		if err != nil {
+			return fmt.Errorf("failed to end previous discount: %w", err)
+		}
+		return redeem(ctx, tx, d)
+	})
+	return found, err
+}
+
+// EndSubscriptionDiscount termina en at el descuento vigente de la
+// suscripción. Devuelve false si no tenía.
+func (r *DiscountRepository) EndSubscriptionDiscount(ctx context.Context, subscriptionID int, at time.Time) (bool, error) {
+	ctx, done := r.limits.Start(ctx, "discounts.end_subscription")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	res, err := r.db.ExecContext(ctx, `UPDATE discounts SET end_at = $2
+		WHERE subscription_id = $1 AND (end_at IS NULL OR end_at > $2)`, subscriptionID, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to end discount: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ApplyInvoiceDiscount canjea d sobre el borrador invoice del scope y guarda
+// sus renglones ya descontados (ver replaceDraftLines). Devuelve false si la
+// factura no existe en el scope, ErrInvoiceNotDraft si ya se finalizó,
+// ErrInvoiceDiscounted si ya tiene descuento y ErrNotRedeemable si el canje ya
+// no es posible.
+func (r *DiscountRepository) ApplyInvoiceDiscount(ctx context.Context, scope InvoiceScope, invoice *model.Invoice, d *model.Discount) (bool, error) {
+	ctx, done := r.limits.Start(ctx, "discounts.apply_invoice")
+	defer done()
+
+	d.InvoiceID = &invoice.ID
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := redeem(ctx, tx, d); err != nil {
+			return err
+		}
+		invoice.DiscountID = &d.ID
+		return replaceDraftLines(ctx, tx, scope, invoice)
+	})
+	if err != nil {
+		invoice.DiscountID = nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return true, err
+	}
+
+	cond, args := scope.where([]interface{}{invoice.ID})
+	var status string
+	var discountID sql.NullInt64
+	//goland:noinspection SqlNoDataSourceInspection
+	err = r.db.QueryRowContext(ctx, `SELECT status, discount_id FROM invoices WHERE id = $1 AND `+cond, args...).Scan(&status, &discountID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch invoice: %w", err)
+	}
+	if status != model.InvoiceDraft {
+		return true, ErrInvoiceNotDraft
+	}
+	return true, ErrInvoiceDiscounted
+}
@@ -1,66 +1,283 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/lib/pq"
+
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/libs/metadata"
 	"saas-subscription-platform/services/billing-service/internal/model"
 )
 
+// ErrPeriodInvoiced: el período de la suscripción ya tiene factura.
+var ErrPeriodInvoiced = errors.New("subscription period already invoiced")
+
+// ErrInvalidInvoiceTransition: la factura no está en un estado desde el que se pueda pasar al pedido.
+var ErrInvalidInvoiceTransition = errors.New("invalid invoice status transition")
+
+// ErrInvoiceNotDraft: solo los borradores se pueden editar.
+var ErrInvoiceNotDraft = errors.New("only draft invoices can be edited")
+
+// nextInvoiceNumber es el número de la próxima factura finalizada.
+const nextInvoiceNumber = `'INV-' || lpad(nextval('invoice_number_seq')::text, 6, '0')`
+
 type InvoiceRepository struct {
-	db *sql.DB
+	db     *sql.DB
+	limits dbquery.Limits
 }
 
-func NewInvoiceRepository(db *sql.DB) *InvoiceRepository {
-	return &InvoiceRepository{db: db}
+// NewInvoiceRepository aplica limits (deadline y log de queries lentas) a cada query.
+func NewInvoiceRepository(db *sql.DB, limits dbquery.Limits) *InvoiceRepository {
+	return &InvoiceRepository{db: db, limits: limits}
 }
 
-// InvoiceFilter permite acotar listados por usuario, estado y paginación.
-type InvoiceFilter struct {
+// InvoiceScope es de quién son las facturas: con OrgID, las de la organización;
+// sin él, las personales de UserID.
+type InvoiceScope struct {
 	UserID string
-	Status string
-	Limit  int
-	Offset int
+	OrgID  string
+	// AllOwners no filtra por dueño: la factura se busca solo por id. Es para
+	// los admins de la plataforma.
+	AllOwners bool
+}
+
+// where agrega a args el parámetro del scope y devuelve la condición que lo usa.
+func (s InvoiceScope) where(args []interface{}) (string, []interface{}) {
+	if s.AllOwners {
+		return "TRUE", args
+	}
+	if s.OrgID != "" {
+		args = append(args, s.OrgID)
+		return fmt.Sprintf("org_id = $%d", len(args)), args
+	}
+	args = append(args, s.UserID)
+	return fmt.Sprintf("user_id = $%d AND org_id IS NULL", len(args)), args
+}
+
+// InvoiceFilter permite acotar listados por usuario u organización, estado,
+// metadata (todos los pares deben coincidir) y paginación.
+type InvoiceFilter struct {
+	UserID   string
+	OrgID    string
+	Status   string
+	Metadata map[string]string
+	Limit    int
+	Offset   int
+}
+
+// invoiceColumns es el orden de columnas que espera scanInvoice.
+const invoiceColumns = `id, user_id, org_id, amount_cents, currency, status, price_id, quantity,
+	subscription_id, period_start, period_end, subtotal_cents, total_discount_cents, total_tax_cents, total_cents, amount_due_cents,
+	number, finalized_at, paid_at, voided_at, marked_uncollectible_at, discount_id, metadata, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanInvoice(row rowScanner) (*model.Invoice, error) {
+	invoice := &model.Invoice{}
+	err := row.Scan(&invoice.ID, &invoice.UserID, &invoice.OrgID, &invoice.AmountCents, &invoice.Currency, &invoice.Status,
+		&invoice.PriceID, &invoice.Quantity, &invoice.SubscriptionID, &invoice.PeriodStart, &invoice.PeriodEnd,
+		&invoice.SubtotalCents, &invoice.TotalDiscountCents, &invoice.TotalTaxCents, &invoice.TotalCents, &invoice.AmountDueCents,
+		&invoice.Number, &invoice.StatusTransitions.FinalizedAt, &invoice.StatusTransitions.PaidAt,
+		&invoice.StatusTransitions.VoidedAt, &invoice.StatusTransitions.MarkedUncollectibleAt, &invoice.DiscountID,
+		(*jsonMetadata)(&invoice.Metadata), &invoice.CreatedAt, &invoice.UpdatedAt)
+	return invoice, err
+}
+
+// jsonMetadata adapta la metadata a la columna JSONB (lib/pq no mapea maps).
+type jsonMetadata map[string]string
+
+func (m jsonMetadata) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(map[string]string(m))
+	return string(b), err
+}
+
+func (m *jsonMetadata) Scan(src interface{}) error {
+	var raw []byte
+	switch v := src.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	case nil:
+		*m = jsonMetadata{}
+		return nil
+	default:
+		return fmt.Errorf("unsupported metadata type %T", src)
+	}
+	out := jsonMetadata{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return err
+	}
+	*m = out
+	return nil
 }
 
-func (r *InvoiceRepository) CreateInvoice(invoice *model.Invoice) error {
+// lineRecord es un renglón como lo lee jsonb_to_recordset al insertarlo.
+type lineRecord struct {
+	Position      int  `json:"position"`
+	PendingItemID *int `json:"pending_item_id"`
+	model.InvoiceLineItem
+}
+
+func lineRecords(lines []model.InvoiceLineItem) (string, error) {
+	records := make([]lineRecord, len(lines))
+	for i, l := range lines {
+		records[i] = lineRecord{Position: i, PendingItemID: l.PendingItemID, InvoiceLineItem: l}
+	}
+	b, err := json.Marshal(records)
+	return string(b), err
+}
+
+// lineRecordset lee los renglones de lineRecords; %s es el parámetro con el JSON.
+const lineRecordset = `jsonb_to_recordset(%s::jsonb) AS l(position int, pending_item_id int, description text, price_id int,
+	quantity bigint, unit_amount_cents bigint, amount_cents bigint, discount_amount_cents bigint, tax_amount_cents bigint,
+	period_start timestamptz, period_end timestamptz, proration boolean, subscription_item_id int)`
+
+// insertLines inserta los renglones de lineRecordset para la factura de la CTE inv.
+const insertLines = `INSERT INTO invoice_line_items (invoice_id, position, description, price_id, quantity, unit_amount_cents, amount_cents,
+		discount_amount_cents, tax_amount_cents, period_start, period_end, proration, subscription_item_id)
+	SELECT inv.id, l.position, l.description, l.price_id, l.quantity, l.unit_amount_cents, l.amount_cents,
+		COALESCE(l.discount_amount_cents, 0), COALESCE(l.tax_amount_cents, 0), l.period_start, l.period_end, l.proration,
+		l.subscription_item_id
+	FROM inv, `
+
+// CreateInvoice guarda la factura con sus renglones en una sola sentencia y
+// marca como facturados los prorrateos pendientes y el uso (de period_start a
+// period_end del renglón) de los ítems medidos que incluye. Si no es un
+// borrador le asigna número (invoice.Number queda cargado) y, si aplica un
+// descuento de una sola vez (once), lo da por usado. Si la factura es de un
+// período de suscripción que ya se facturó no guarda nada y devuelve
+// ErrPeriodInvoiced.
+func (r *InvoiceRepository) CreateInvoice(ctx context.Context, invoice *model.Invoice) error {
+	ctx, done := r.limits.Start(ctx, "invoices.create")
+	defer done()
+
+	lines, err := lineRecords(invoice.Lines)
+	if err != nil {
+		return fmt.Errorf("failed to encode invoice lines: %w", err)
+	}
+
 	//goland:noinspection SqlNoDataSourceInspection
-	query := `INSERT INTO invoices (user_id, amount_cents, currency, status, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
-	err := r.db.QueryRow(query, invoice.UserID, invoice.AmountCents, invoice.Currency, invoice.Status, invoice.CreatedAt, invoice.UpdatedAt).Scan(&invoice.ID)
+	query := `WITH inv AS (
+			INSERT INTO invoices (user_id, org_id, amount_cents, currency, status, price_id, quantity,
+				subscription_id, period_start, period_end, metadata, created_at, updated_at,
+				subtotal_cents, total_discount_cents, total_tax_cents, total_cents, amount_due_cents,
+				number, finalized_at, paid_at, discount_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11::jsonb, $12, $13, $15, $16, $17, $18, $19,
+				CASE WHEN $5 <> 'draft' THEN ` + nextInvoiceNumber + ` END, $20, $21, $22)
+			ON CONFLICT (subscription_id, period_start) WHERE subscription_id IS NOT NULL DO NOTHING
+			RETURNING id, number, discount_id, created_at
+		), used AS (
+			UPDATE discounts SET end_at = inv.created_at
+			FROM inv, coupons
+			WHERE discounts.id = inv.discount_id AND coupons.id = discounts.coupon_id
+				AND coupons.duration = 'once' AND discounts.end_at IS NULL
+		), lines AS (
+			` + insertLines + fmt.Sprintf(lineRecordset, "$14") + `
+		), claimed AS (
+			UPDATE pending_invoice_items p SET invoice_id = inv.id
+			FROM inv, ` + fmt.Sprintf(lineRecordset, "$14") + `
+			WHERE p.id = l.pending_item_id AND p.invoice_id IS NULL
+		), usage_billed AS (
+			UPDATE subscription_items i SET billed_through = l.period_end
+			FROM inv, ` + fmt.Sprintf(lineRecordset, "$14") + `
+			WHERE i.id = l.subscription_item_id AND i.billed_through = l.period_start
+		)
+		SELECT id, number FROM inv`
+	err = r.db.QueryRowContext(ctx, query, invoice.UserID, invoice.OrgID, invoice.AmountCents, invoice.Currency, invoice.Status,
+		invoice.PriceID, invoice.Quantity, invoice.SubscriptionID, invoice.PeriodStart, invoice.PeriodEnd,
+		jsonMetadata(invoice.Metadata), invoice.CreatedAt, invoice.UpdatedAt, lines,
+		invoice.SubtotalCents, invoice.TotalDiscountCents, invoice.TotalTaxCents, invoice.TotalCents, invoice.AmountDueCents,
+		invoice.StatusTransitions.FinalizedAt, invoice.StatusTransitions.PaidAt, invoice.DiscountID).Scan(&invoice.ID, &invoice.Number)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPeriodInvoiced
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create invoice: %w", err)
 	}
 	return nil
 }
 
-func (r *InvoiceRepository) GetInvoiceByID(userID string, id int) (*model.Invoice, error) {
+// GetInvoiceByID devuelve la factura con sus renglones; nil si no existe o no es del scope.
+func (r *InvoiceRepository) GetInvoiceByID(ctx context.Context, scope InvoiceScope, id int) (*model.Invoice, error) {
+	ctx, done := r.limits.Start(ctx, "invoices.get_by_id")
+	defer done()
+
 	//goland:noinspection SqlNoDataSourceInspection
-	query := `SELECT id, user_id, amount_cents, currency, status, created_at, updated_at FROM invoices WHERE id = $1 AND user_id = $2`
-	invoice := &model.Invoice{}
-	if err := r.db.QueryRow(query, id, userID).Scan(&invoice.ID, &invoice.UserID, &invoice.AmountCents, &invoice.Currency, &invoice.Status, &invoice.CreatedAt, &invoice.UpdatedAt); err != nil {
+	cond, args := scope.where([]interface{}{id})
+	query := `SELECT ` + invoiceColumns + ` FROM invoices WHERE id = $1 AND ` + cond
+	invoice, err := scanInvoice(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to fetch invoice: %w", err)
 	}
+	if invoice.Lines, err = r.invoiceLines(ctx, invoice.ID); err != nil {
+		return nil, err
+	}
 	return invoice, nil
 }
 
-func (r *InvoiceRepository) GetInvoices(filter InvoiceFilter) ([]*model.Invoice, error) {
+// invoiceLines devuelve los renglones de la factura en orden.
+func (r *InvoiceRepository) invoiceLines(ctx context.Context, invoiceID int) ([]model.InvoiceLineItem, error) {
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT id, description, price_id, quantity, unit_amount_cents, amount_cents, discount_amount_cents, tax_amount_cents,
+			period_start, period_end, proration, subscription_item_id
+		FROM invoice_line_items WHERE invoice_id = $1 ORDER BY position`
+	rows, err := r.db.QueryContext(ctx, query, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch invoice lines: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	lines := []model.InvoiceLineItem{}
+	for rows.Next() {
+		var l model.InvoiceLineItem
+		if err := rows.Scan(&l.ID, &l.Description, &l.PriceID, &l.Quantity, &l.UnitAmountCents, &l.AmountCents,
+			&l.DiscountAmountCents, &l.TaxAmountCents, &l.PeriodStart, &l.PeriodEnd, &l.Proration, &l.SubscriptionItemID); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice line: %w", err)
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+func (r *InvoiceRepository) GetInvoices(ctx context.Context, filter InvoiceFilter) ([]*model.Invoice, error) {
+	ctx, done := r.limits.Start(ctx, "invoices.list")
+	defer done()
+
 	//goland:noinspection SqlNoDataSourceInspection
-	base := `SELECT id, user_id, amount_cents, currency, status, created_at, updated_at FROM invoices`
+	base := `SELECT ` + invoiceColumns + ` FROM invoices`
 	args := make([]interface{}, 0, 4)
 	clauses := make([]string, 0, 2)
 
-	if filter.UserID != "" {
-		args = append(args, filter.UserID)
-		clauses = append(clauses, fmt.Sprintf("user_id = $%d", len(args)))
+	if filter.UserID != "" || filter.OrgID != "" {
+		var cond string
+		cond, args = InvoiceScope{UserID: filter.UserID, OrgID: filter.OrgID}.where(args)
+		clauses = append(clauses, cond)
 	}
 	if filter.Status != "" {
 		args = append(args, filter.Status)
 		clauses = append(clauses, fmt.Sprintf("status = $%d", len(args)))
 	}
+	if len(filter.Metadata) > 0 {
+		args = append(args, jsonMetadata(filter.Metadata))
+		clauses = append(clauses, fmt.Sprintf("metadata @> $%d::jsonb", len(args)))
+	}
 
 	var sb strings.Builder
 	sb.WriteString(base)
@@ -83,7 +300,7 @@ func (r *InvoiceRepository) GetInvoices(filter InvoiceFilter) ([]*model.Invoice,
 	args = append(args, offset)
 	sb.WriteString(fmt.Sprintf(" OFFSET $%d", len(args)))
 
-	rows, err := r.db.Query(sb.String(), args...)
+	rows, err := r.db.QueryContext(ctx, sb.String(), args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch invoices: %w", err)
 	}
@@ -91,11 +308,175 @@ func (r *InvoiceRepository) GetInvoices(filter InvoiceFilter) ([]*model.Invoice,
 
 	var invoices []*model.Invoice
 	for rows.Next() {
-		invoice := &model.Invoice{}
-		if err := rows.Scan(&invoice.ID, &invoice.UserID, &invoice.AmountCents, &invoice.Currency, &invoice.Status, &invoice.CreatedAt, &invoice.UpdatedAt); err != nil {
+		invoice, err := scanInvoice(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan invoice: %w", err)
 		}
 		invoices = append(invoices, invoice)
 	}
-	return invoices, nil
+	// Si el request se cancela a mitad de la lectura, el error aparece acá y no en Next.
+	return invoices, rows.Err()
+}
+
+// ExportInvoices devuelve todas las facturas del usuario, sin paginar (export GDPR).
+func (r *InvoiceRepository) ExportInvoices(ctx context.Context, userID string) ([]*model.Invoice, error) {
+	ctx, done := r.limits.Start(ctx, "invoices.export")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT ` + invoiceColumns + ` FROM invoices WHERE user_id = $1 ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export invoices: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var invoices []*model.Invoice
+	for rows.Next() {
+		invoice, err := scanInvoice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+		invoices = append(invoices, invoice)
+	}
+	return invoices, rows.Err()
+}
+
+// UpdateInvoiceMetadata aplica un patch de metadata (los valores "" borran la
+// clave) a una factura del scope. Devuelve nil si no existe o no es del scope.
+// El merge y el límite de claves se resuelven en una sola sentencia.
+func (r *InvoiceRepository) UpdateInvoiceMetadata(ctx context.Context, scope InvoiceScope, id int, patch map[string]string) (*model.Invoice, error) {
+	ctx, done := r.limits.Start(ctx, "invoices.update_metadata")
+	defer done()
+
+	set, unset := metadata.Split(patch)
+	cond, args := scope.where([]interface{}{id, jsonMetadata(set), pq.Array(unset), metadata.MaxKeys})
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `UPDATE invoices SET metadata = (metadata || $2::jsonb) - $3::text[], updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ` + cond + `
+			AND (SELECT count(*) FROM jsonb_object_keys((metadata || $2::jsonb) - $3::text[])) <= $4
+		RETURNING ` + invoiceColumns
+	invoice, err := scanInvoice(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		// Sin fila: o la factura no es del scope o el resultado supera el límite.
+		existing, getErr := r.GetInvoiceByID(ctx, scope, id)
+		if getErr != nil || existing == nil {
+			return nil, getErr
+		}
+		return nil, fmt.Errorf("%w: at most %d keys", metadata.ErrInvalid, metadata.MaxKeys)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update invoice metadata: %w", err)
+	}
+	return invoice, nil
+}
+
+// invoiceTransitionSet es el SET de cada estado destino; $3 es el momento de la transición.
+var invoiceTransitionSet = map[string]string{
+	model.InvoiceOpen:          `status = 'open', number = COALESCE(number, ` + nextInvoiceNumber + `), finalized_at = $3`,
+	model.InvoicePaid:          `status = 'paid', paid_at = $3`,
+	model.InvoiceVoid:          `status = 'void', voided_at = $3`,
+	model.InvoiceUncollectible: `status = 'uncollectible', marked_uncollectible_at = $3`,
+}
+
+// TransitionInvoice pasa la factura a status si hoy está en alguno de from
+// (compare-and-swap) y registra el momento en status_transitions. Devuelve
+// nil si no existe en el scope y ErrInvalidInvoiceTransition si su estado no
+// está en from.
+func (r *InvoiceRepository) TransitionInvoice(ctx context.Context, scope InvoiceScope, id int, from []string, status string, at time.Time) (*model.Invoice, error) {
+	ctx, done := r.limits.Start(ctx, "invoices.transition")
+	defer done()
+
+	set, ok := invoiceTransitionSet[status]
+	if !ok {
+		return nil, fmt.Errorf("unknown invoice status %q", status)
+	}
+	cond, args := scope.where([]interface{}{id, pq.Array(from), at})
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `UPDATE invoices SET ` + set + `, updated_at = $3
+		WHERE id = $1 AND status = ANY($2) AND ` + cond + `
+		RETURNING ` + invoiceColumns
+	invoice, err := scanInvoice(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		existing, getErr := r.GetInvoiceByID(ctx, scope, id)
+		if getErr != nil || existing == nil {
+			return nil, getErr
+		}
+		return nil, ErrInvalidInvoiceTransition
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update invoice status: %w", err)
+	}
+	if invoice.Lines, err = r.invoiceLines(ctx, invoice.ID); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// rowQueryer es lo que comparten *sql.DB y *sql.Tx para las sentencias de una fila.
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// replaceDraftLines reemplaza los renglones y los totales de un borrador del
+// scope en una sola sentencia. Si invoice trae DiscountID lo fija, y solo si
+// la factura no tenía descuento. Devuelve sql.ErrNoRows si no lo actualizó.
+func replaceDraftLines(ctx context.Context, q rowQueryer, scope InvoiceScope, invoice *model.Invoice) error {
+	lines, err := lineRecords(invoice.Lines)
+	if err != nil {
+		return fmt.Errorf("failed to encode invoice lines: %w", err)
+	}
+	cond, args := scope.where([]interface{}{invoice.ID, invoice.AmountCents, invoice.SubtotalCents, invoice.TotalDiscountCents,
+		invoice.TotalTaxCents, invoice.TotalCents, invoice.AmountDueCents, invoice.UpdatedAt, lines, invoice.DiscountID})
+	// Todas las partes ven la misma foto: el DELETE no alcanza a los renglones nuevos.
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `WITH inv AS (
+			UPDATE invoices SET amount_cents = $2, subtotal_cents = $3, total_discount_cents = $4, total_tax_cents = $5,
+				total_cents = $6, amount_due_cents = $7, updated_at = $8, price_id = NULL, quantity = NULL,
+				discount_id = COALESCE($10, discount_id)
+			WHERE id = $1 AND status = 'draft' AND ($10::int IS NULL OR discount_id IS NULL) AND ` + cond + `
+			RETURNING id
+		), removed AS (
+			DELETE FROM invoice_line_items WHERE invoice_id IN (SELECT id FROM inv)
+		), lines AS (
+			` + insertLines + fmt.Sprintf(lineRecordset, "$9") + `
+		)
+		SELECT id FROM inv`
+	var updatedID int
+	return q.QueryRowContext(ctx, query, args...).Scan(&updatedID)
+}
+
+// ReplaceInvoiceLines reemplaza los renglones y los totales de un borrador.
+// Devuelve nil si no existe en el scope y ErrInvoiceNotDraft si ya se finalizó.
+func (r *InvoiceRepository) ReplaceInvoiceLines(ctx context.Context, scope InvoiceScope, invoice *model.Invoice) (*model.Invoice, error) {
+	ctx, done := r.limits.Start(ctx, "invoices.replace_lines")
+	defer done()
+
+	err := replaceDraftLines(ctx, r.db, scope, invoice)
+	if errors.Is(err, sql.ErrNoRows) {
+		existing, getErr := r.GetInvoiceByID(ctx, scope, invoice.ID)
+		if getErr != nil || existing == nil {
+			return nil, getErr
+		}
+		return nil, ErrInvoiceNotDraft
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update invoice lines: %w", err)
+	}
+	return r.GetInvoiceByID(ctx, scope, invoice.ID)
+}
+
+// PseudonymizeUser reemplaza el user_id de las facturas por un seudónimo: las
+// facturas se conservan (obligación contable) pero dejan de apuntar a la persona.
+func (r *InvoiceRepository) PseudonymizeUser(ctx context.Context, userID, pseudonym string) (int64, error) {
+	ctx, done := r.limits.Start(ctx, "invoices.pseudonymize_user")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `UPDATE invoices SET user_id = $2, updated_at = CURRENT_TIMESTAMP WHERE user_id = $1`
+	res, err := r.db.ExecContext(ctx, query, userID, pseudonym)
+	if err != nil {
+		return 0, fmt.Errorf("failed to pseudonymize invoices: %w", err)
+	}
+	return res.RowsAffected()
 }
@@ -0,0 +1,341 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/services/billing-service/internal/model"
+)
+
+// ErrInvalidTransition: la suscripción no está en un estado desde el que se
+// pueda pasar al pedido (o cambió en paralelo).
+var ErrInvalidTransition = errors.New("invalid subscription status transition")
+
+// subscriptionColumns es el orden de columnas que espera scanSubscription.
+const subscriptionColumns = `id, user_id, org_id, price_id, quantity, status, current_period_start, current_period_end, trial_end,
+	billing_cycle_anchor, cancel_at_period_end, canceled_at, ended_at, paused_at, created_at, updated_at`
+
+// SubscriptionFilter acota el listado de suscripciones del cliente.
+type SubscriptionFilter struct {
+	UserID string
+	OrgID  string
+	Status string
+	Limit  int
+	Offset int
+}
+
+type SubscriptionRepository struct {
+	db     *sql.DB
+	limits dbquery.Limits
+}
+
+// NewSubscriptionRepository aplica limits (deadline y log de queries lentas) a cada query.
+func NewSubscriptionRepository(db *sql.DB, limits dbquery.Limits) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db, limits: limits}
+}
+
+func scanSubscription(row rowScanner) (*model.Subscription, error) {
+	s := &model.Subscription{}
+	err := row.Scan(&s.ID, &s.UserID, &s.OrgID, &s.PriceID, &s.Quantity, &s.Status, &s.CurrentPeriodStart, &s.CurrentPeriodEnd, &s.TrialEnd,
+		&s.BillingCycleAnchor, &s.CancelAtPeriodEnd, &s.CanceledAt, &s.EndedAt, &s.PausedAt, &s.CreatedAt, &s.UpdatedAt)
+	return s, err
+}
+
+func (r *SubscriptionRepository) CreateSubscription(ctx context.Context, s *model.Subscription) error {
+	ctx, done := r.limits.Start(ctx, "subscriptions.create")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `INSERT INTO subscriptions (user_id, org_id, price_id, quantity, status, current_period_start, current_period_end, trial_end, billing_cycle_anchor, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+	err := r.db.QueryRowContext(ctx, query, s.UserID, s.OrgID, s.PriceID, s.Quantity, s.Status, s.CurrentPeriodStart, s.CurrentPeriodEnd, s.TrialEnd, s.BillingCycleAnchor, s.CreatedAt, s.UpdatedAt).
+		Scan(&s.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create subscription: %w", err)
+	}
+	return nil
+}
+
+// GetSubscription devuelve nil si la suscripción no existe o no es del scope.
+func (r *SubscriptionRepository) GetSubscription(ctx context.Context, scope InvoiceScope, id int) (*model.Subscription, error) {
+	ctx, done := r.limits.Start(ctx, "subscriptions.get_by_id")
+	defer done()
+
+	cond, args := scope.where([]interface{}{id})
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE id = $1 AND ` + cond
+	s, err := scanSubscription(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscription: %w", err)
+	}
+	return s, nil
+}
+
+func (r *SubscriptionRepository) ListSubscriptions(ctx context.Context, filter SubscriptionFilter) ([]*model.Subscription, error) {
+	ctx, done := r.limits.Start(ctx, "subscriptions.list")
+	defer done()
+
+	cond, args := InvoiceScope{UserID: filter.UserID, OrgID: filter.OrgID}.where(nil)
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE ` + cond
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	page, args := pageClause(args, filter.Limit, filter.Offset)
+	query += ` ORDER BY created_at DESC` + page
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscriptions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	subs := []*model.Subscription{}
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+// subscriptionSet aplica un SubscriptionUpdate con los parámetros de updateArgs
+// ($1 id y $2 estados de origen quedan para el WHERE). paused_at se fija al
+// pausar y se limpia al salir de paused.
+const subscriptionSet = `
+			status = COALESCE($3, status),
+			cancel_at_period_end = COALESCE($4, cancel_at_period_end),
+			canceled_at = COALESCE($5, canceled_at),
+			ended_at = COALESCE($6, ended_at),
+			current_period_start = COALESCE($7, current_period_start),
+			current_period_end = COALESCE($8, current_period_end),
+			billing_cycle_anchor = COALESCE($9, billing_cycle_anchor),
+			paused_at = CASE WHEN COALESCE($3, status) = 'paused' THEN COALESCE(paused_at, CURRENT_TIMESTAMP) END,
+			updated_at = CURRENT_TIMESTAMP`
+
+func updateArgs(id int, from []string, u model.SubscriptionUpdate) []interface{} {
+	return []interface{}{id, pq.Array(from), u.Status, u.CancelAtPeriodEnd, u.CanceledAt, u.EndedAt,
+		u.CurrentPeriodStart, u.CurrentPeriodEnd, u.BillingCycleAnchor}
+}
+
+// TransitionSubscription aplica u si la suscripción del scope está hoy en
+// alguno de from; el chequeo va en el mismo UPDATE para que dos transiciones
+// concurrentes no se pisen. Devuelve nil si no existe en el scope y
+// ErrInvalidTransition si está en otro estado.
+func (r *SubscriptionRepository) TransitionSubscription(ctx context.Context, scope InvoiceScope, id int, from []string, u model.SubscriptionUpdate) (*model.Subscription, error) {
+	ctx, done := r.limits.Start(ctx, "subscriptions.transition")
+	defer done()
+
+	cond, args := scope.where(updateArgs(id, from, u))
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `UPDATE subscriptions SET ` + subscriptionSet + `
+		WHERE id = $1 AND status = ANY($2) AND ` + cond + `
+		RETURNING ` + subscriptionColumns
+	s, err := scanSubscription(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		existing, getErr := r.GetSubscription(ctx, scope, id)
+		if getErr != nil || existing == nil {
+			return nil, getErr
+		}
+		return nil, ErrInvalidTransition
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update subscription: %w", err)
+	}
+	return s, nil
+}
+
+// periodInvoiced es la condición "el período en curso ya se facturó" sobre la fila de subscriptions.
+const periodInvoiced = `EXISTS (SELECT 1 FROM invoices
+	WHERE invoices.subscription_id = subscriptions.id AND invoices.period_start = subscriptions.current_period_start)`
+
+// UnbilledSubscriptions devuelve hasta limit suscripciones active o past_due
+// cuyo período en curso todavía no tiene factura.
+func (r *SubscriptionRepository) UnbilledSubscriptions(ctx context.Context, limit int) ([]*model.Subscription, error) {
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions
+		WHERE status IN ('active', 'past_due') AND NOT ` + periodInvoiced + `
+		ORDER BY current_period_start LIMIT $1`
+	return r.listRenewals(ctx, "subscriptions.list_unbilled", query, limit)
+}
+
+// DueSubscriptions devuelve hasta limit suscripciones cuyo período terminó
+// antes de now y se puede cerrar: en prueba, con cancelación al fin del
+// período, o con el período ya facturado (así no se saltea ninguno).
+func (r *SubscriptionRepository) DueSubscriptions(ctx context.Context, now time.Time, limit int) ([]*model.Subscription, error) {
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions
+		WHERE status IN ('trialing', 'active', 'past_due') AND current_period_end <= $2
+			AND (status = 'trialing' OR cancel_at_period_end OR ` + periodInvoiced + `)
+		ORDER BY current_period_end LIMIT $1`
+	return r.listRenewals(ctx, "subscriptions.list_due", query, limit, now)
+}
+
+func (r *SubscriptionRepository) listRenewals(ctx context.Context, name, query string, args ...interface{}) ([]*model.Subscription, error) {
+	ctx, done := r.limits.Start(ctx, name)
+	defer done()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscriptions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var subs []*model.Subscription
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+// AdvanceSubscription cierra el período que termina en periodEnd aplicando u.
+// Es un compare-and-swap sobre el fin del período: si otra réplica ya lo
+// cerró (o la suscripción cambió de estado) devuelve nil sin tocar nada.
+func (r *SubscriptionRepository) AdvanceSubscription(ctx context.Context, id int, periodEnd time.Time, from []string, u model.SubscriptionUpdate) (*model.Subscription, error) {
+	ctx, done := r.limits.Start(ctx, "subscriptions.advance")
+	defer done()
+
+	args := append(updateArgs(id, from, u), periodEnd)
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `UPDATE subscriptions SET ` + subscriptionSet + `
+		WHERE id = $1 AND status = ANY($2) AND current_period_end = $10
+		RETURNING ` + subscriptionColumns
+	s, err := scanSubscription(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to advance subscription: %w", err)
+	}
+	return s, nil
+}
+
+// ErrSubscriptionChanged: la suscripción cambió desde que se calculó el prorrateo.
+var ErrSubscriptionChanged = errors.New("subscription changed concurrently; retry")
+
+// withExtra agrega destinos al final de un Scan.
+type withExtra struct {
+	row   rowScanner
+	extra []interface{}
+}
+
+func (w withExtra) Scan(dest ...interface{}) error {
+	return w.row.Scan(append(dest, w.extra...)...)
+}
+
+// ChangeSubscription cambia precio y cantidad y guarda los prorrateos de
+// change (pendientes o en una factura nueva) en una sola sentencia. El cambio
+// solo se aplica si la suscripción sigue renovándose con el precio, la
+// cantidad y el período que se usaron para prorratear; si no, devuelve
+// ErrInvalidTransition (estado) o ErrSubscriptionChanged. Devuelve nil si no
+// existe en el scope. La factura, si hay, se crea finalizada (open); su ID
+// queda cargado en change.Invoice.
+func (r *SubscriptionRepository) ChangeSubscription(ctx context.Context, scope InvoiceScope, id int, change model.SubscriptionChange) (*model.Subscription, error) {
+	ctx, done := r.limits.Start(ctx, "subscriptions.change")
+	defer done()
+
+	pending, err := lineRecords(change.PendingItems)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode pending items: %w", err)
+	}
+	inv := change.Invoice
+	if inv == nil {
+		inv = &model.Invoice{}
+	}
+	lines, err := lineRecords(inv.Lines)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode invoice lines: %w", err)
+	}
+
+	cond, args := scope.where([]interface{}{id, change.PriceID, change.Quantity, change.ExpectedPriceID, change.ExpectedQuantity,
+		change.ExpectedPeriodEnd, pending, change.Invoice != nil, inv.AmountCents, inv.Currency, inv.Status, inv.CreatedAt, lines,
+		inv.SubtotalCents, inv.TotalDiscountCents, inv.TotalTaxCents, inv.TotalCents})
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `WITH upd AS (
+			UPDATE subscriptions SET price_id = $2, quantity = $3, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND status IN ('trialing', 'active', 'past_due')
+				AND price_id = $4 AND quantity = $5 AND current_period_end = $6 AND ` + cond + `
+			RETURNING *
+		), items AS (
+			INSERT INTO pending_invoice_items (subscription_id, description, price_id, quantity, unit_amount_cents, amount_cents,
+				period_start, period_end, proration)
+			SELECT upd.id, l.description, l.price_id, l.quantity, l.unit_amount_cents, l.amount_cents,
+				l.period_start, l.period_end, l.proration
+			FROM upd, ` + fmt.Sprintf(lineRecordset, "$7") + `
+		), inv AS (
+			INSERT INTO invoices (user_id, org_id, amount_cents, currency, status, subscription_id, metadata, created_at, updated_at,
+				subtotal_cents, total_discount_cents, total_tax_cents, total_cents, amount_due_cents, number, finalized_at)
+			SELECT upd.user_id, upd.org_id, $9::bigint, $10::text, $11::text, upd.id, '{}'::jsonb, $12::timestamptz, $12::timestamptz,
+				$14::bigint, $15::bigint, $16::bigint, $17::bigint, $9::bigint, ` + nextInvoiceNumber + `, $12::timestamptz
+			FROM upd WHERE $8::boolean
+			RETURNING id
+		), lines AS (
+			` + insertLines + fmt.Sprintf(lineRecordset, "$13") + `
+		)
+		SELECT ` + subscriptionColumns + `, (SELECT id FROM inv) FROM upd`
+
+	var invoiceID sql.NullInt64
+	s, err := scanSubscription(withExtra{r.db.QueryRowContext(ctx, query, args...), []interface{}{&invoiceID}})
+	if errors.Is(err, sql.ErrNoRows) {
+		existing, getErr := r.GetSubscription(ctx, scope, id)
+		if getErr != nil || existing == nil {
+			return nil, getErr
+		}
+		switch existing.Status {
+		case model.SubscriptionTrialing, model.SubscriptionActive, model.SubscriptionPastDue:
+			return nil, ErrSubscriptionChanged
+		}
+		return nil, ErrInvalidTransition
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to change subscription: %w", err)
+	}
+	if change.Invoice != nil && invoiceID.Valid {
+		change.Invoice.ID = int(invoiceID.Int64)
+	}
+	return s, nil
+}
+
+// PendingInvoiceItems devuelve los prorrateos de la suscripción que todavía no se facturaron.
+func (r *SubscriptionRepository) PendingInvoiceItems(ctx context.Context, subscriptionID int) ([]model.InvoiceLineItem, error) {
+	ctx, done := r.limits.Start(ctx, "pending_invoice_items.list")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT id, description, price_id, quantity, unit_amount_cents, amount_cents, period_start, period_end, proration
+		FROM pending_invoice_items WHERE subscription_id = $1 AND invoice_id IS NULL ORDER BY id`
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pending invoice items: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var items []model.InvoiceLineItem
+	for rows.Next() {
+		var item model.InvoiceLineItem
+		var id int
+		if err := rows.Scan(&id, &item.Description, &item.PriceID, &item.Quantity, &item.UnitAmountCents, &item.AmountCents,
+			&item.PeriodStart, &item.PeriodEnd, &item.Proration); err != nil {
+			return nil, fmt.Errorf("failed to scan pending invoice item: %w", err)
+		}
+		item.PendingItemID = &id
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,403 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/services/billing-service/internal/model"
+)
+
+var (
+	// ErrSubscriptionItemExists: la suscripción ya tiene un ítem con ese precio.
+	ErrSubscriptionItemExists = errors.New("subscription already has an item with this price")
+	// ErrSubscriptionItemNotFound: el ítem del registro de uso no existe o no es del scope.
+	ErrSubscriptionItemNotFound = errors.New("subscription item not found")
+	// ErrUsagePeriodClosed: el uso de ese momento ya se cerró para facturarlo.
+	ErrUsagePeriodClosed = errors.New("usage period is already closed for invoicing")
+)
+
+const subscriptionItemColumns = `id, subscription_id, price_id, billed_through, created_at`
+
+type UsageRepository struct {
+	db     *sql.DB
+	limits dbquery.Limits
+}
+
+// NewUsageRepository aplica limits (deadline y log de queries lentas) a cada query.
+func NewUsageRepository(db *sql.DB, limits dbquery.Limits) *UsageRepository {
+	return &UsageRepository{db: db, limits: limits}
+}
+
+func scanSubscriptionItem(row rowScanner) (*model.SubscriptionItem, error) {
+	item := &model.SubscriptionItem{}
+	err := row.Scan(&item.ID, &item.SubscriptionID, &item.PriceID, &item.BilledThrough, &item.CreatedAt)
+	return item, err
+}
+
+func scanUsageRollup(row rowScanner) (model.UsageRollup, error) {
+	var r model.UsageRollup
+	err := row.Scan(&r.SubscriptionItemID, &r.BucketStart, &r.Quantity, &r.SetAt, &r.LastRecordedAt, &r.RecordCount)
+	return r, err
+}
+
+// timestampArray codifica ts como text[] para castearlo a timestamptz[]; los nil van como NULL.
+func timestampArray(ts []*time.Time) interface{} {
+	out := make([]sql.NullString, len(ts))
+	for i, t := range ts {
+		if t != nil {
+			out[i] = sql.NullString{String: t.Format(time.RFC3339Nano), Valid: true}
+		}
+	}
+	return pq.Array(out)
+}
+
+// CreateSubscriptionItem agrega el ítem a la suscripción, con el uso abierto
+// desde item.CreatedAt. Devuelve false si la suscripción no existe en el scope,
+// ErrInvalidTransition si está cancelada y ErrSubscriptionItemExists si ya
+// tiene ese precio.
+func (r *UsageRepository) CreateSubscriptionItem(ctx context.Context, scope InvoiceScope, item *model.SubscriptionItem) (bool, error) {
+	ctx, done := r.limits.Start(ctx, "subscription_items.create")
+	defer done()
+
+	cond, args := scope.where([]interface{}{item.SubscriptionID, item.PriceID, item.CreatedAt})
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `INSERT INTO subscription_items (subscription_id, price_id, usage_closed_at, billed_through, created_at)
+		SELECT id, $2::int, $3::timestamptz, $3::timestamptz, $3::timestamptz FROM subscriptions
+		WHERE id = $1 AND status <> 'canceled' AND ` + cond + `
+		RETURNING id, billed_through`
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&item.ID, &item.BilledThrough)
+	if pqErrorCode(err) == "23505" {
+		return true, ErrSubscriptionItemExists
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		cond, args := scope.where([]interface{}{item.SubscriptionID})
+		var status string
+		//goland:noinspection SqlNoDataSourceInspection
+		err = r.db.QueryRowContext(ctx, `SELECT status FROM subscriptions WHERE id = $1 AND `+cond, args...).Scan(&status)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to fetch subscription: %w", err)
+		}
+		return true, ErrInvalidTransition
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create subscription item: %w", err)
+	}
+	return true, nil
+}
+
+func (r *UsageRepository) ListSubscriptionItems(ctx context.Context, subscriptionID int) ([]*model.SubscriptionItem, error) {
+	ctx, done := r.limits.Start(ctx, "subscription_items.list")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT ` + subscriptionItemColumns + ` FROM subscription_items WHERE subscription_id = $1 ORDER BY id`
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscription items: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	items := []*model.SubscriptionItem{}
+	for rows.Next() {
+		item, err := scanSubscriptionItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription item: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// usageItem es lo que la ingesta necesita del ítem: desde cuándo acepta uso
+// y los bordes de período que cortan los rollups.
+type usageItem struct {
+	closedAt    time.Time
+	periodStart time.Time
+	periodEnd   time.Time
+}
+
+type rollupKey struct {
+	itemID int
+	bucket int64
+}
+
+// RecordUsage guarda los registros (sus claves de idempotencia no se repiten
+// dentro de records) y suma a los rollups los que no estaban guardados.
+// Devuelve cuántos se aceptaron. Todos los ítems deben ser de suscripciones
+// del scope (ErrSubscriptionItemNotFound) y ningún registro puede ser anterior
+// al cierre de uso de su ítem (ErrUsagePeriodClosed); si no, no guarda nada.
+// Son cinco sentencias por lote, sin importar su tamaño.
+func (r *UsageRepository) RecordUsage(ctx context.Context, scope InvoiceScope, records []model.UsageRecord) (int, error) {
+	ctx, done := r.limits.Start(ctx, "usage_records.create")
+	defer done()
+
+	accepted := 0
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		items, err := lockUsageItems(ctx, tx, scope, records)
+		if err != nil {
+			return err
+		}
+		for i, rec := range records {
+			item, ok := items[rec.SubscriptionItemID]
+			if !ok {
+				return fmt.Errorf("%w: record %d", ErrSubscriptionItemNotFound, i)
+			}
+			if rec.Timestamp.Before(item.closedAt) {
+				return fmt.Errorf("%w: record %d", ErrUsagePeriodClosed, i)
+			}
+		}
+
+		inserted, err := insertUsageRecords(ctx, tx, records)
+		if err != nil || len(inserted) == 0 {
+			return err
+		}
+		accepted = len(inserted)
+
+		// Los registros de cada rollup se aplican en orden cronológico.
+		sort.SliceStable(inserted, func(i, j int) bool { return inserted[i].Timestamp.Before(inserted[j].Timestamp) })
+		pending := map[rollupKey][]model.UsageRecord{}
+		var keys []rollupKey
+		for _, rec := range inserted {
+			item := items[rec.SubscriptionItemID]
+			bucket := model.UsageBucket(rec.Timestamp, item.closedAt, item.periodStart, item.periodEnd)
+			key := rollupKey{itemID: rec.SubscriptionItemID, bucket: bucket.UnixNano()}
+			if _, ok := pending[key]; !ok {
+				keys = append(keys, key)
+			}
+			pending[key] = append(pending[key], rec)
+		}
+
+		rollups, err := lockUsageRollups(ctx, tx, keys)
+		if err != nil {
+			return err
+		}
+		for i := range rollups {
+			key := rollupKey{itemID: rollups[i].SubscriptionItemID, bucket: rollups[i].BucketStart.UnixNano()}
+			for _, rec := range pending[key] {
+				if err := rollups[i].Add(rec); err != nil {
+					return err
+				}
+			}
+		}
+		return saveUsageRollups(ctx, tx, rollups)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return accepted, nil
+}
+
+// lockUsageItems devuelve los ítems de records que son del scope. El lock
+// compartido frena a CloseUsage hasta que el lote termine.
+func lockUsageItems(ctx context.Context, tx *sql.Tx, scope InvoiceScope, records []model.UsageRecord) (map[int]usageItem, error) {
+	seen := map[int]bool{}
+	var ids pq.Int64Array
+	for _, rec := range records {
+		if !seen[rec.SubscriptionItemID] {
+			seen[rec.SubscriptionItemID] = true
+			ids = append(ids, int64(rec.SubscriptionItemID))
+		}
+	}
+
+	cond, args := scope.where([]interface{}{ids})
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT i.id, i.usage_closed_at, s.current_period_start, s.current_period_end
+		FROM subscription_items i JOIN subscriptions s ON s.id = i.subscription_id
+		WHERE i.id = ANY($1) AND ` + cond + `
+		ORDER BY i.id
+		FOR SHARE OF i`
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscription items: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	items := map[int]usageItem{}
+	for rows.Next() {
+		var id int
+		var item usageItem
+		if err := rows.Scan(&id, &item.closedAt, &item.periodStart, &item.periodEnd); err != nil {
+			return nil, fmt.Errorf("failed to scan subscription item: %w", err)
+		}
+		items[id] = item
+	}
+	return items, rows.Err()
+}
+
+// insertUsageRecords guarda los registros de una sola vez y devuelve los que
+// no estaban (los reintentos chocan con la clave de idempotencia).
+func insertUsageRecords(ctx context.Context, tx *sql.Tx, records []model.UsageRecord) ([]model.UsageRecord, error) {
+	type recordKey struct {
+		itemID int
+		key    string
+	}
+	byKey := make(map[recordKey]model.UsageRecord, len(records))
+	ids := make(pq.Int64Array, len(records))
+	keys := make(pq.StringArray, len(records))
+	quantities := make(pq.Int64Array, len(records))
+	actions := make(pq.StringArray, len(records))
+	timestamps := make([]*time.Time, len(records))
+	for i := range records {
+		rec := records[i]
+		byKey[recordKey{rec.SubscriptionItemID, rec.IdempotencyKey}] = rec
+		ids[i], keys[i], quantities[i], actions[i] = int64(rec.SubscriptionItemID), rec.IdempotencyKey, rec.Quantity, rec.Action
+		timestamps[i] = &records[i].Timestamp
+	}
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `INSERT INTO usage_records (subscription_item_id, idempotency_key, quantity, action, recorded_at)
+		SELECT * FROM unnest($1::int[], $2::text[], $3::bigint[], $4::text[], $5::timestamptz[])
+		ON CONFLICT (subscription_item_id, idempotency_key) DO NOTHING
+		RETURNING subscription_item_id, idempotency_key`
+	rows, err := tx.QueryContext(ctx, query, ids, keys, quantities, actions, timestampArray(timestamps))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create usage records: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var inserted []model.UsageRecord
+	for rows.Next() {
+		var k recordKey
+		if err := rows.Scan(&k.itemID, &k.key); err != nil {
+			return nil, fmt.Errorf("failed to scan usage record: %w", err)
+		}
+		inserted = append(inserted, byKey[k])
+	}
+	return inserted, rows.Err()
+}
+
+// lockUsageRollups crea los rollups que falten y los devuelve bloqueados, en
+// orden de clave para que dos lotes no se bloqueen mutuamente.
+func lockUsageRollups(ctx context.Context, tx *sql.Tx, keys []rollupKey) ([]model.UsageRollup, error) {
+	ids := make(pq.Int64Array, len(keys))
+	buckets := make([]*time.Time, len(keys))
+	for i, k := range keys {
+		bucket := time.Unix(0, k.bucket).UTC()
+		ids[i], buckets[i] = int64(k.itemID), &bucket
+	}
+
+	//goland:noinspection SqlNoDataSourceInspection
+	_, err := tx.ExecContext(ctx, `INSERT INTO usage_rollups (subscription_item_id, bucket_start)
+		SELECT * FROM unnest($1::int[], $2::timestamptz[])
+		ON CONFLICT (subscription_item_id, bucket_start) DO NOTHING`, ids, timestampArray(buckets))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create usage rollups: %w", err)
+	}
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT r.subscription_item_id, r.bucket_start, r.quantity, r.set_at, r.last_recorded_at, r.record_count
+		FROM usage_rollups r
+		JOIN unnest($1::int[], $2::timestamptz[]) AS k (subscription_item_id, bucket_start)
+			ON r.subscription_item_id = k.subscription_item_id AND r.bucket_start = k.bucket_start
+		ORDER BY r.subscription_item_id, r.bucket_start
+		FOR UPDATE OF r`
+	rows, err := tx.QueryContext(ctx, query, ids, timestampArray(buckets))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch usage rollups: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var rollups []model.UsageRollup
+	for rows.Next() {
+		rollup, err := scanUsageRollup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan usage rollup: %w", err)
+		}
+		rollups = append(rollups, rollup)
+	}
+	return rollups, rows.Err()
+}
+
+// saveUsageRollups escribe los rollups de una sola vez.
+func saveUsageRollups(ctx context.Context, tx *sql.Tx, rollups []model.UsageRollup) error {
+	ids := make(pq.Int64Array, len(rollups))
+	buckets := make([]*time.Time, len(rollups))
+	quantities := make(pq.Int64Array, len(rollups))
+	setAt := make([]*time.Time, len(rollups))
+	lastAt := make([]*time.Time, len(rollups))
+	counts := make(pq.Int64Array, len(rollups))
+	for i := range rollups {
+		r := &rollups[i]
+		ids[i], buckets[i], quantities[i] = int64(r.SubscriptionItemID), &r.BucketStart, r.Quantity
+		setAt[i], lastAt[i], counts[i] = r.SetAt, r.LastRecordedAt, int64(r.RecordCount)
+	}
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `UPDATE usage_rollups r SET
+			quantity = u.quantity, set_at = u.set_at, last_recorded_at = u.last_recorded_at, record_count = u.record_count
+		FROM unnest($1::int[], $2::timestamptz[], $3::bigint[], $4::timestamptz[], $5::timestamptz[], $6::int[])
+			AS u (subscription_item_id, bucket_start, quantity, set_at, last_recorded_at, record_count)
+		WHERE r.subscription_item_id = u.subscription_item_id AND r.bucket_start = u.bucket_start`
+	_, err := tx.ExecContext(ctx, query, ids, timestampArray(buckets), quantities, timestampArray(setAt), timestampArray(lastAt), counts)
+	if err != nil {
+		return fmt.Errorf("failed to update usage rollups: %w", err)
+	}
+	return nil
+}
+
+// CloseUsage deja de aceptar uso anterior a at en los ítems de la suscripción
+// (espera a los lotes en curso) y devuelve los ítems. Después de cerrar, los
+// rollups hasta at ya no cambian y se pueden facturar.
+func (r *UsageRepository) CloseUsage(ctx context.Context, subscriptionID int, at time.Time) ([]*model.SubscriptionItem, error) {
+	ctx, done := r.limits.Start(ctx, "subscription_items.close_usage")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `UPDATE subscription_items SET usage_closed_at = GREATEST(usage_closed_at, $2)
+		WHERE subscription_id = $1
+		RETURNING ` + subscriptionItemColumns
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close usage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	items := []*model.SubscriptionItem{}
+	for rows.Next() {
+		item, err := scanSubscriptionItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription item: %w", err)
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items, rows.Err()
+}
+
+// UsageRollups devuelve los rollups sin facturar de los ítems de la
+// suscripción (desde el billed_through de cada uno) anteriores a before.
+func (r *UsageRepository) UsageRollups(ctx context.Context, subscriptionID int, before time.Time) ([]model.UsageRollup, error) {
+	ctx, done := r.limits.Start(ctx, "usage_rollups.list")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT r.subscription_item_id, r.bucket_start, r.quantity, r.set_at, r.last_recorded_at, r.record_count
+		FROM usage_rollups r JOIN subscription_items i ON i.id = r.subscription_item_id
+		WHERE i.subscription_id = $1 AND r.bucket_start >= i.billed_through AND r.bucket_start < $2
+		ORDER BY r.subscription_item_id, r.bucket_start`
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch usage rollups: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	rollups := []model.UsageRollup{}
+	for rows.Next() {
+		rollup, err := scanUsageRollup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan usage rollup: %w", err)
+		}
+		rollups = append(rollups, rollup)
+	}
+	return rollups, rows.Err()
+}
@@ -6,18 +6,28 @@ import (
 
 	"github.com/gorilla/mux"
 
+	"saas-subscription-platform/libs/dbquery"
 	"saas-subscription-platform/services/billing-service/internal/handler"
 	"saas-subscription-platform/services/billing-service/internal/middleware"
 	"saas-subscription-platform/services/billing-service/internal/repository"
 	"saas-subscription-platform/services/billing-service/internal/service"
 )
 
+// NewBillingService arma el BillingService con sus repositorios; lo usan el
+// router y el worker de renovaciones.
+func NewBillingService(db *sql.DB, limits dbquery.Limits) *service.BillingService {
+	return service.NewBillingService(repository.NewInvoiceRepository(db, limits),
+		service.WithPrices(repository.NewCatalogRepository(db, limits)),
+		service.WithSubscriptions(repository.NewSubscriptionRepository(db, limits)),
+		service.WithDiscounts(repository.NewDiscountRepository(db, limits)),
+		service.WithUsage(repository.NewUsageRepository(db, limits)))
+}
+
 // NewRouter construye el router HTTP del billing-service.
-// La conexión a DB debe venir inyectada (configurada por env en server).
-func NewRouter(db *sql.DB) *mux.Router {
-	repo := repository.NewInvoiceRepository(db)
-	billingService := service.NewBillingService(repo)
-	h := handler.NewBillingHandler(billingService)
+// La conexión a DB y los límites de las queries vienen inyectados (configurados por env en server).
+func NewRouter(db *sql.DB, limits dbquery.Limits) *mux.Router {
+	h := handler.NewBillingHandler(NewBillingService(db, limits))
+	ch := handler.NewCatalogHandler(service.NewCatalogService(repository.NewCatalogRepository(db, limits)))
 
 	r := mux.NewRouter()
 
@@ -31,6 +41,67 @@ func NewRouter(db *sql.DB) *mux.Router {
 	protected.HandleFunc("/invoices", h.CreateInvoice).Methods(http.MethodPost)
 	protected.HandleFunc("/invoices", h.GetInvoices).Methods(http.MethodGet)
 	protected.HandleFunc("/invoices/{id}", h.GetInvoiceByID).Methods(http.MethodGet)
+	protected.HandleFunc("/invoices/{id}", h.UpdateInvoice).Methods(http.MethodPatch)
+	protected.HandleFunc("/invoices/{id}/finalize", h.FinalizeInvoice).Methods(http.MethodPost)
+	// Anular o dar por incobrable una factura: solo admins de la plataforma. No hay
+	// ruta pública para pagarla: paid solo se alcanza cuando payment-service
+	// avisa el cobro por /internal/invoices/{id}/pay.
+	adminOnly := middleware.RequireRole("admin")
+	protected.Handle("/invoices/{id}/void", adminOnly(http.HandlerFunc(h.VoidInvoice))).Methods(http.MethodPost)
+	protected.Handle("/invoices/{id}/mark-uncollectible", adminOnly(http.HandlerFunc(h.MarkInvoiceUncollectible))).Methods(http.MethodPost)
+	protected.HandleFunc("/invoices/{id}/discount", h.ApplyInvoiceDiscount).Methods(http.MethodPost)
+
+	protected.HandleFunc("/subscriptions", h.CreateSubscription).Methods(http.MethodPost)
+	protected.HandleFunc("/subscriptions", h.ListSubscriptions).Methods(http.MethodGet)
+	protected.HandleFunc("/subscriptions/{id}", h.GetSubscription).Methods(http.MethodGet)
+	protected.HandleFunc("/subscriptions/{id}", h.ChangeSubscription).Methods(http.MethodPatch)
+	protected.HandleFunc("/subscriptions/{id}/proration-preview", h.PreviewSubscriptionChange).Methods(http.MethodPost)
+	protected.HandleFunc("/subscriptions/{id}/upcoming-invoice", h.GetUpcomingInvoice).Methods(http.MethodGet)
+	protected.HandleFunc("/subscriptions/{id}/cancel", h.CancelSubscription).Methods(http.MethodPost)
+	protected.HandleFunc("/subscriptions/{id}/pause", h.PauseSubscription).Methods(http.MethodPost)
+	protected.HandleFunc("/subscriptions/{id}/resume", h.ResumeSubscription).Methods(http.MethodPost)
+	protected.HandleFunc("/subscriptions/{id}/discount", h.ApplySubscriptionDiscount).Methods(http.MethodPost)
+	protected.HandleFunc("/subscriptions/{id}/discount", h.RemoveSubscriptionDiscount).Methods(http.MethodDelete)
+	protected.HandleFunc("/subscriptions/{id}/items", h.AddSubscriptionItem).Methods(http.MethodPost)
+	protected.HandleFunc("/subscriptions/{id}/items", h.ListSubscriptionItems).Methods(http.MethodGet)
+	protected.HandleFunc("/subscriptions/{id}/usage", h.GetSubscriptionUsage).Methods(http.MethodGet)
+	protected.HandleFunc("/usage_records", h.RecordUsage).Methods(http.MethodPost)
+
+	// Catálogo: lo lee cualquier usuario, lo editan solo los admins de la plataforma
+	protected.HandleFunc("/products", ch.ListProducts).Methods(http.MethodGet)
+	protected.HandleFunc("/products/{id}", ch.GetProduct).Methods(http.MethodGet)
+	protected.Handle("/products", adminOnly(http.HandlerFunc(ch.CreateProduct))).Methods(http.MethodPost)
+	protected.Handle("/products/{id}", adminOnly(http.HandlerFunc(ch.UpdateProduct))).Methods(http.MethodPatch)
+	protected.Handle("/products/{id}", adminOnly(http.HandlerFunc(ch.DeleteProduct))).Methods(http.MethodDelete)
+	protected.HandleFunc("/prices", ch.ListPrices).Methods(http.MethodGet)
+	protected.HandleFunc("/prices/{id}", ch.GetPrice).Methods(http.MethodGet)
+	protected.Handle("/prices", adminOnly(http.HandlerFunc(ch.CreatePrice))).Methods(http.MethodPost)
+	protected.Handle("/prices/{id}", adminOnly(http.HandlerFunc(ch.UpdatePrice))).Methods(http.MethodPatch)
+	protected.Handle("/prices/{id}", adminOnly(http.HandlerFunc(ch.ArchivePrice))).Methods(http.MethodDelete)
+
+	// Cupones y códigos promocionales: solo admins; los clientes canjean códigos
+	// con los endpoints /discount de facturas y suscripciones
+	protected.Handle("/coupons", adminOnly(http.HandlerFunc(h.CreateCoupon))).Methods(http.MethodPost)
+	protected.Handle("/coupons", adminOnly(http.HandlerFunc(h.ListCoupons))).Methods(http.MethodGet)
+	protected.Handle("/coupons/{id}", adminOnly(http.HandlerFunc(h.GetCoupon))).Methods(http.MethodGet)
+	protected.Handle("/coupons/{id}", adminOnly(http.HandlerFunc(h.UpdateCoupon))).Methods(http.MethodPatch)
+	protected.Handle("/coupons/{id}", adminOnly(http.HandlerFunc(h.ArchiveCoupon))).Methods(http.MethodDelete)
+	protected.Handle("/promotion-codes", adminOnly(http.HandlerFunc(h.CreatePromotionCode))).Methods(http.MethodPost)
+	protected.Handle("/promotion-codes", adminOnly(http.HandlerFunc(h.ListPromotionCodes))).Methods(http.MethodGet)
+	protected.Handle("/promotion-codes/{id}", adminOnly(http.HandlerFunc(h.GetPromotionCode))).Methods(http.MethodGet)
+	protected.Handle("/promotion-codes/{id}", adminOnly(http.HandlerFunc(h.UpdatePromotionCode))).Methods(http.MethodPatch)
+
+	// Interno: payment-service avisa que cobró una factura. Va antes que el
+	// subrouter de /internal para no quedar detrás de RequireCaller("user-service").
+	payments := r.PathPrefix("/internal/invoices").Subrouter()
+	payments.Use(middleware.InternalAuthMux, middleware.RequireCaller("payment-service"))
+	payments.HandleFunc("/{id}/pay", h.RecordInvoicePayment).Methods(http.MethodPost)
+
+	// Interno: export y borrado GDPR, solo los orquesta user-service
+	internal := r.PathPrefix("/internal").Subrouter()
+	internal.Use(middleware.InternalAuthMux, middleware.RequireCaller("user-service"))
+	internal.HandleFunc("/users/{id}/export", h.ExportUser).Methods(http.MethodGet)
+	internal.HandleFunc("/users/{id}/erase", h.EraseUser).Methods(http.MethodPost)
 
 	return r
 }
@@ -0,0 +1,51 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"saas-subscription-platform/libs/dbquery"
+)
+
+func TestInvoiceTransitionsRequireAdmin(t *testing.T) {
+	// Sin DB: el 403 tiene que salir antes de llegar al handler.
+	r := NewRouter(nil, dbquery.Limits{})
+
+	for _, path := range []string{"/invoices/1/void", "/invoices/1/mark-uncollectible"} {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("X-Internal-User-ID", "u-1")
+		req.Header.Set("X-Internal-User-Role", "user")
+		rr := httptest.NewRecorder()
+
+		r.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusForbidden, rr.Code, path)
+	}
+}
+
+func TestInvoicePayOnlyThroughPaymentService(t *testing.T) {
+	r := NewRouter(nil, dbquery.Limits{})
+
+	tests := []struct {
+		name, path, caller, role string
+		want                     int
+	}{
+		{name: "no public route, even for admins", path: "/invoices/1/pay", caller: "u-1", role: "admin", want: http.StatusNotFound},
+		{name: "internal route rejects users", path: "/internal/invoices/1/pay", caller: "u-1", role: "admin", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			req.Header.Set("X-Internal-User-ID", tt.caller)
+			req.Header.Set("X-Internal-User-Role", tt.role)
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.want, rr.Code)
+		})
+	}
+}
@@ -1,14 +1,19 @@
 package server
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
 
 	_ "github.com/lib/pq"
 
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/libs/migrate"
 	"saas-subscription-platform/services/billing-service/internal/config"
 	"saas-subscription-platform/services/billing-service/internal/router"
+	"saas-subscription-platform/services/billing-service/migrations"
 )
 
 func Run() error {
@@ -24,7 +29,31 @@ func Run() error {
 		return err
 	}
 
-	r := router.NewRouter(db)
+	if cfg.MigrateOnBoot {
+		m, err := migrate.New(db, "billing-service", migrations.FS)
+		if err != nil {
+			return err
+		}
+		n, err := m.Up(context.Background())
+		if err != nil {
+			return fmt.Errorf("migrations failed: %w", err)
+		}
+		log.Printf("migrations_up service=billing-service applied=%d", n)
+	}
+
+	limits := dbquery.Limits{
+		Service:       "billing-service",
+		Timeout:       cfg.QueryTimeout,
+		SlowThreshold: cfg.SlowQueryThreshold,
+	}
+	r := router.NewRouter(db, limits)
+
+	// Las renovaciones corren en segundo plano mientras viva el server; son
+	// idempotentes, así que pueden correr en todas las réplicas.
+	ctx, stopRenewals := context.WithCancel(context.Background())
+	defer stopRenewals()
+	go router.NewBillingService(db, limits).RunRenewals(ctx, cfg.RenewalInterval)
+
 	log.Printf("Starting billing-service on %s", cfg.HTTPAddr)
 	return http.ListenAndServe(cfg.HTTPAddr, r)
 }
@@ -1,71 +1,343 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"time"
 
+	"saas-subscription-platform/libs/metadata"
 	"saas-subscription-platform/services/billing-service/internal/model"
 	"saas-subscription-platform/services/billing-service/internal/repository"
 )
 
 // InvoiceStore define las operaciones que la capa de servicio necesita del repositorio.
 type InvoiceStore interface {
-	CreateInvoice(invoice *model.Invoice) error
-	GetInvoiceByID(userID string, id int) (*model.Invoice, error)
-	GetInvoices(filter repository.InvoiceFilter) ([]*model.Invoice, error)
+	CreateInvoice(ctx context.Context, invoice *model.Invoice) error
+	GetInvoiceByID(ctx context.Context, scope repository.InvoiceScope, id int) (*model.Invoice, error)
+	GetInvoices(ctx context.Context, filter repository.InvoiceFilter) ([]*model.Invoice, error)
+	UpdateInvoiceMetadata(ctx context.Context, scope repository.InvoiceScope, id int, patch map[string]string) (*model.Invoice, error)
+	ExportInvoices(ctx context.Context, userID string) ([]*model.Invoice, error)
+	PseudonymizeUser(ctx context.Context, userID, pseudonym string) (int64, error)
+	TransitionInvoice(ctx context.Context, scope repository.InvoiceScope, id int, from []string, status string, at time.Time) (*model.Invoice, error)
+	ReplaceInvoiceLines(ctx context.Context, scope repository.InvoiceScope, invoice *model.Invoice) (*model.Invoice, error)
+}
+
+// ErrForbidden: el rol en la organización activa no da acceso a la facturación.
+var ErrForbidden = errors.New("forbidden")
+
+// Scope es el ámbito de un request según los headers del gateway: el usuario
+// y, si eligió una, la organización activa con su rol en ella.
+type Scope struct {
+	UserID  string
+	OrgID   string
+	OrgRole string
+	// Role es el rol de plataforma del usuario (X-Internal-User-Role).
+	Role string
+}
+
+// platformAdminRole es el rol de plataforma que opera sobre facturas ajenas.
+const platformAdminRole = "admin"
+
+// billingOrgRoles son los roles de organización que ven y crean facturas.
+var billingOrgRoles = map[string]bool{"owner": true, "admin": true, "billing": true}
+
+// invoiceScope valida el scope y lo traduce al del repositorio.
+func (sc Scope) invoiceScope() (repository.InvoiceScope, error) {
+	if sc.UserID == "" {
+		return repository.InvoiceScope{}, fmt.Errorf("user id is required")
+	}
+	if sc.OrgID != "" && !billingOrgRoles[sc.OrgRole] {
+		return repository.InvoiceScope{}, ErrForbidden
+	}
+	return repository.InvoiceScope{UserID: sc.UserID, OrgID: sc.OrgID}, nil
+}
+
+// ErrPriceUnavailable: el precio no existe o está archivado.
+var ErrPriceUnavailable = errors.New("price not found or archived")
+
+// PriceLookup resuelve los precios del catálogo al facturar.
+type PriceLookup interface {
+	GetPrice(ctx context.Context, id int) (*model.Price, error)
 }
 
 type BillingService struct {
-	repo InvoiceStore
+	repo      InvoiceStore
+	prices    PriceLookup
+	subs      SubscriptionStore
+	discounts DiscountStore
+	usage     UsageStore
+	now       func() time.Time
+}
+
+// Option configura dependencias opcionales de BillingService.
+type Option func(*BillingService)
+
+// WithPrices habilita facturar a partir de precios del catálogo.
+func WithPrices(prices PriceLookup) Option {
+	return func(s *BillingService) { s.prices = prices }
 }
 
-func NewBillingService(repo InvoiceStore) *BillingService {
-	return &BillingService{repo: repo}
+// WithClock reemplaza time.Now (para tests).
+func WithClock(now func() time.Time) Option {
+	return func(s *BillingService) { s.now = now }
 }
 
-func (s *BillingService) CreateInvoice(userID string, amountCents int64, currency string) (*model.Invoice, error) {
-	if userID == "" {
-		return nil, fmt.Errorf("user id is required")
+func NewBillingService(repo InvoiceStore, opts ...Option) *BillingService {
+	s := &BillingService{repo: repo, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// maxInvoiceLines limita los renglones de una factura creada por la API.
+const maxInvoiceLines = 100
+
+// CreateInvoice crea la factura a nombre del usuario y, si hay una activa, de
+// su organización, con un único renglón por el monto.
+func (s *BillingService) CreateInvoice(ctx context.Context, scope Scope, amountCents int64, currency string, meta map[string]string) (*model.Invoice, error) {
+	if currency == "" {
+		currency = "USD"
 	}
 	if amountCents <= 0 {
 		return nil, fmt.Errorf("amount must be positive")
 	}
+	return s.createInvoice(ctx, scope, &model.Invoice{
+		Currency: currency,
+		Lines: []model.InvoiceLineItem{{
+			Description:     "Invoice amount",
+			Quantity:        1,
+			UnitAmountCents: amountCents,
+			AmountCents:     amountCents,
+		}},
+	}, meta)
+}
+
+// CreateInvoiceFromPrice factura quantity unidades de un precio activo del
+// catálogo; monto y moneda salen del precio. quantity 0 equivale a 1.
+func (s *BillingService) CreateInvoiceFromPrice(ctx context.Context, scope Scope, priceID, quantity int, meta map[string]string) (*model.Invoice, error) {
+	if _, err := scope.invoiceScope(); err != nil {
+		return nil, err
+	}
+	price, err := s.activePrice(ctx, priceID)
+	if err != nil {
+		return nil, err
+	}
+	line, err := priceLine(price, quantity)
+	if err != nil {
+		return nil, err
+	}
+	return s.createInvoice(ctx, scope, &model.Invoice{
+		Currency: price.Currency,
+		PriceID:  &price.ID,
+		Quantity: &line.Quantity,
+		Lines:    []model.InvoiceLineItem{line},
+	}, meta)
+}
+
+// CreateInvoiceWithLines crea una factura con los renglones dados. De cada
+// renglón se toman descripción, cantidad (0 equivale a 1), precio unitario o
+// price_id, período, descuento e impuesto; los importes y los totales se
+// calculan acá. Con price_id el precio unitario sale del catálogo y su moneda
+// debe ser la de la factura.
+func (s *BillingService) CreateInvoiceWithLines(ctx context.Context, scope Scope, currency string, lines []model.InvoiceLineItem, meta map[string]string) (*model.Invoice, error) {
+	if _, err := scope.invoiceScope(); err != nil {
+		return nil, err
+	}
 	if currency == "" {
 		currency = "USD"
 	}
+	out, err := s.buildLines(ctx, currency, lines)
+	if err != nil {
+		return nil, err
+	}
+	return s.createInvoice(ctx, scope, &model.Invoice{Currency: currency, Lines: out}, meta)
+}
+
+// buildLines valida los renglones pedidos y calcula sus importes.
+func (s *BillingService) buildLines(ctx context.Context, currency string, lines []model.InvoiceLineItem) ([]model.InvoiceLineItem, error) {
+	if len(lines) == 0 || len(lines) > maxInvoiceLines {
+		return nil, fmt.Errorf("an invoice needs between 1 and %d lines", maxInvoiceLines)
+	}
+
+	out := make([]model.InvoiceLineItem, 0, len(lines))
+	for i, in := range lines {
+		var line model.InvoiceLineItem
+		var err error
+		if in.PriceID != nil {
+			var price *model.Price
+			if price, err = s.activePrice(ctx, *in.PriceID); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i, err)
+			}
+			if price.Currency != currency {
+				return nil, fmt.Errorf("line %d: price currency %s does not match invoice currency %s", i, price.Currency, currency)
+			}
+			line, err = priceLine(price, in.Quantity)
+		} else {
+			line, err = unitLine(in.UnitAmountCents, in.Quantity)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i, err)
+		}
+		if in.Description != "" {
+			line.Description = in.Description
+		}
+		if line.Description == "" || len(line.Description) > 500 {
+			return nil, fmt.Errorf("line %d: description must be between 1 and 500 characters", i)
+		}
+		if (in.PeriodStart == nil) != (in.PeriodEnd == nil) || (in.PeriodStart != nil && !in.PeriodEnd.After(*in.PeriodStart)) {
+			return nil, fmt.Errorf("line %d: period_start and period_end must be set together and in order", i)
+		}
+		if in.DiscountAmountCents < 0 || in.DiscountAmountCents > line.AmountCents {
+			return nil, fmt.Errorf("line %d: discount_amount_cents must be between 0 and the line amount", i)
+		}
+		if in.TaxAmountCents < 0 {
+			return nil, fmt.Errorf("line %d: tax_amount_cents must not be negative", i)
+		}
+		line.PeriodStart, line.PeriodEnd = in.PeriodStart, in.PeriodEnd
+		line.DiscountAmountCents, line.TaxAmountCents = in.DiscountAmountCents, in.TaxAmountCents
+		out = append(out, line)
+	}
+	return out, nil
+}
+
+// errMeteredPrice: un precio medido no tiene cantidad fija; se cobra por uso.
+var errMeteredPrice = errors.New("metered prices are billed by usage; add them as subscription items")
+
+// activePrice devuelve el precio si existe, está activo y no es medido.
+func (s *BillingService) activePrice(ctx context.Context, priceID int) (*model.Price, error) {
+	if s.prices == nil {
+		return nil, fmt.Errorf("price catalog is not configured")
+	}
+	price, err := s.prices.GetPrice(ctx, priceID)
+	if err != nil {
+		return nil, err
+	}
+	if price == nil || !price.Active {
+		return nil, ErrPriceUnavailable
+	}
+	if price.Metered() {
+		return nil, errMeteredPrice
+	}
+	return price, nil
+}
+
+// priceLine es el renglón de quantity unidades de price (0 equivale a 1). El
+// importe sale de price.Amount, así que en precios por paquete o escalonados
+// no es cantidad × precio unitario.
+func priceLine(price *model.Price, quantity int) (model.InvoiceLineItem, error) {
+	if quantity == 0 {
+		quantity = 1
+	}
+	if quantity < 0 {
+		return model.InvoiceLineItem{}, fmt.Errorf("quantity must be positive")
+	}
+	amount, err := price.Amount(int64(quantity))
+	if err != nil {
+		return model.InvoiceLineItem{}, err
+	}
+	return model.InvoiceLineItem{
+		Description:     fmt.Sprintf("Price #%d", price.ID),
+		PriceID:         &price.ID,
+		Quantity:        quantity,
+		UnitAmountCents: price.UnitAmountCents,
+		AmountCents:     amount,
+	}, nil
+}
 
-	now := time.Now()
-	invoice := &model.Invoice{
-		UserID:      userID,
-		AmountCents: amountCents,
-		Currency:    currency,
-		Status:      "pending",
-		CreatedAt:   now,
-		UpdatedAt:   now,
+// unitLine es un renglón de quantity unidades de unitAmount (0 equivale a 1).
+func unitLine(unitAmount int64, quantity int) (model.InvoiceLineItem, error) {
+	if quantity == 0 {
+		quantity = 1
 	}
+	if quantity < 0 {
+		return model.InvoiceLineItem{}, fmt.Errorf("quantity must be positive")
+	}
+	if unitAmount < 0 {
+		return model.InvoiceLineItem{}, fmt.Errorf("unit_amount_cents must not be negative")
+	}
+	if unitAmount > math.MaxInt64/int64(quantity) {
+		return model.InvoiceLineItem{}, fmt.Errorf("amount is too large")
+	}
+	return model.InvoiceLineItem{
+		Quantity:        quantity,
+		UnitAmountCents: unitAmount,
+		AmountCents:     unitAmount * int64(quantity),
+	}, nil
+}
 
-	if err := s.repo.CreateInvoice(invoice); err != nil {
+// createInvoice calcula los totales a partir de los renglones, completa el
+// dueño y las fechas, y guarda la factura como borrador.
+func (s *BillingService) createInvoice(ctx context.Context, scope Scope, invoice *model.Invoice, meta map[string]string) (*model.Invoice, error) {
+	owner, err := scope.invoiceScope()
+	if err != nil {
+		return nil, err
+	}
+	if err := invoice.ComputeTotals(); err != nil {
+		return nil, err
+	}
+	if invoice.AmountDueCents <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	if err := metadata.Validate(meta, false); err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		meta = map[string]string{}
+	}
+
+	now := s.now()
+	invoice.UserID = owner.UserID
+	invoice.Status = model.InvoiceDraft
+	invoice.Metadata = meta
+	invoice.CreatedAt = now
+	invoice.UpdatedAt = now
+	if owner.OrgID != "" {
+		invoice.OrgID = &owner.OrgID
+	}
+
+	if err := s.repo.CreateInvoice(ctx, invoice); err != nil {
 		return nil, err
 	}
 	return invoice, nil
 }
 
-func (s *BillingService) GetInvoiceByID(userID string, id int) (*model.Invoice, error) {
-	if userID == "" {
-		return nil, fmt.Errorf("user id is required")
+func (s *BillingService) GetInvoiceByID(ctx context.Context, scope Scope, id int) (*model.Invoice, error) {
+	owner, err := scope.invoiceScope()
+	if err != nil {
+		return nil, err
 	}
-	return s.repo.GetInvoiceByID(userID, id)
+	return s.repo.GetInvoiceByID(ctx, owner, id)
 }
 
-func (s *BillingService) ListInvoices(userID, status string, limit, offset int) ([]*model.Invoice, error) {
-	if userID == "" {
-		return nil, fmt.Errorf("user id is required")
+// ListInvoices filtra por estado y, si meta no está vacío, por pares de metadata.
+func (s *BillingService) ListInvoices(ctx context.Context, scope Scope, status string, meta map[string]string, limit, offset int) ([]*model.Invoice, error) {
+	owner, err := scope.invoiceScope()
+	if err != nil {
+		return nil, err
 	}
 	filter := repository.InvoiceFilter{
-		UserID: userID,
-		Status: status,
-		Limit:  limit,
-		Offset: offset,
+		UserID:   owner.UserID,
+		OrgID:    owner.OrgID,
+		Status:   status,
+		Metadata: meta,
+		Limit:    limit,
+		Offset:   offset,
+	}
+	return s.repo.GetInvoices(ctx, filter)
+}
+
+// UpdateInvoiceMetadata mezcla patch con la metadata de la factura; un valor ""
+// borra la clave. Devuelve nil si la factura no existe en el scope.
+func (s *BillingService) UpdateInvoiceMetadata(ctx context.Context, scope Scope, id int, patch map[string]string) (*model.Invoice, error) {
+	owner, err := scope.invoiceScope()
+	if err != nil {
+		return nil, err
+	}
+	if err := metadata.Validate(patch, true); err != nil {
+		return nil, err
 	}
-	return s.repo.GetInvoices(filter)
+	return s.repo.UpdateInvoiceMetadata(ctx, owner, id, patch)
 }
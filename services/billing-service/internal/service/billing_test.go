@@ -1,10 +1,12 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
 
+	"saas-subscription-platform/libs/metadata"
 	"saas-subscription-platform/services/billing-service/internal/model"
 	"saas-subscription-platform/services/billing-service/internal/repository"
 	"saas-subscription-platform/services/billing-service/internal/service/mocks"
@@ -18,27 +20,87 @@ func TestBillingService_CreateInvoice(t *testing.T) {
 	store := mocks.NewMockInvoiceStore(ctrl)
 	svc := NewBillingService(store)
 
-	store.EXPECT().CreateInvoice(gomock.Any()).DoAndReturn(func(inv *model.Invoice) error {
+	store.EXPECT().CreateInvoice(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, inv *model.Invoice) error {
 		inv.ID = 42
 		return nil
 	})
 
-	inv, err := svc.CreateInvoice("user-1", 1500, "USD")
+	inv, err := svc.CreateInvoice(context.Background(), Scope{UserID: "user-1"}, 1500, "USD", nil)
 	require.NoError(t, err)
 	require.Equal(t, 42, inv.ID)
 	require.Equal(t, int64(1500), inv.AmountCents)
-	require.Equal(t, "pending", inv.Status)
+	require.Equal(t, model.InvoiceDraft, inv.Status)
+	require.Nil(t, inv.Number)
 	require.WithinDuration(t, time.Now(), inv.CreatedAt, time.Second)
 }
 
 func TestBillingService_CreateInvoice_Validation(t *testing.T) {
 	svc := NewBillingService(nil)
 
-	_, err := svc.CreateInvoice("", 100, "USD")
+	_, err := svc.CreateInvoice(context.Background(), Scope{}, 100, "USD", nil)
 	require.Error(t, err)
 
-	_, err = svc.CreateInvoice("user-1", 0, "USD")
+	_, err = svc.CreateInvoice(context.Background(), Scope{UserID: "user-1"}, 0, "USD", nil)
 	require.Error(t, err)
+
+	_, err = svc.CreateInvoice(context.Background(), Scope{UserID: "user-1"}, 100, "USD", map[string]string{"crm_id": ""})
+	require.ErrorIs(t, err, metadata.ErrInvalid)
+}
+
+func TestBillingService_CreateInvoiceWithLines(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockInvoiceStore(ctrl)
+	prices := mocks.NewMockCatalogStore(ctrl)
+	svc := NewBillingService(store, WithPrices(prices))
+
+	priceID := 7
+	prices.EXPECT().GetPrice(gomock.Any(), 7).Return(&model.Price{ID: 7, Currency: "EUR", UnitAmountCents: 1500, Active: true}, nil)
+	store.EXPECT().CreateInvoice(gomock.Any(), gomock.Any()).Return(nil)
+
+	inv, err := svc.CreateInvoiceWithLines(context.Background(), Scope{UserID: "user-1"}, "EUR", []model.InvoiceLineItem{
+		// El importe del cliente se ignora: sale de cantidad por precio unitario.
+		{Description: "Seats", PriceID: &priceID, Quantity: 2, AmountCents: 1, TaxAmountCents: 630},
+		{Description: "Setup", UnitAmountCents: 5000, DiscountAmountCents: 1000},
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, inv.Lines, 2)
+	require.Equal(t, int64(1500), inv.Lines[0].UnitAmountCents)
+	require.Equal(t, int64(3000), inv.Lines[0].AmountCents)
+	require.Equal(t, 1, inv.Lines[1].Quantity)
+	require.Equal(t, int64(8000), inv.SubtotalCents)
+	require.Equal(t, int64(1000), inv.TotalDiscountCents)
+	require.Equal(t, int64(630), inv.TotalTaxCents)
+	require.Equal(t, int64(7630), inv.TotalCents)
+	require.Equal(t, int64(7630), inv.AmountDueCents)
+	require.Equal(t, int64(7630), inv.AmountCents)
+}
+
+func TestBillingService_CreateInvoiceWithLines_Validation(t *testing.T) {
+	priceID := 7
+	tests := []struct {
+		name  string
+		lines []model.InvoiceLineItem
+	}{
+		{name: "no lines"},
+		{name: "missing description", lines: []model.InvoiceLineItem{{UnitAmountCents: 100}}},
+		{name: "negative unit amount", lines: []model.InvoiceLineItem{{Description: "x", UnitAmountCents: -100}}},
+		{name: "discount above amount", lines: []model.InvoiceLineItem{{Description: "x", UnitAmountCents: 100, DiscountAmountCents: 101}}},
+		{name: "negative tax", lines: []model.InvoiceLineItem{{Description: "x", UnitAmountCents: 100, TaxAmountCents: -1}}},
+		{name: "zero total", lines: []model.InvoiceLineItem{{Description: "x", UnitAmountCents: 0}}},
+		{name: "price in another currency", lines: []model.InvoiceLineItem{{PriceID: &priceID}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			prices := mocks.NewMockCatalogStore(ctrl)
+			prices.EXPECT().GetPrice(gomock.Any(), 7).Return(&model.Price{ID: 7, Currency: "EUR", UnitAmountCents: 1500, Active: true}, nil).AnyTimes()
+			svc := NewBillingService(mocks.NewMockInvoiceStore(ctrl), WithPrices(prices))
+
+			_, err := svc.CreateInvoiceWithLines(context.Background(), Scope{UserID: "user-1"}, "USD", tt.lines, nil)
+			require.Error(t, err)
+		})
+	}
 }
 
 func TestBillingService_GetInvoiceByID(t *testing.T) {
@@ -47,13 +109,13 @@ func TestBillingService_GetInvoiceByID(t *testing.T) {
 	svc := NewBillingService(store)
 
 	expected := &model.Invoice{ID: 1, UserID: "user-1"}
-	store.EXPECT().GetInvoiceByID("user-1", 1).Return(expected, nil)
+	store.EXPECT().GetInvoiceByID(gomock.Any(), repository.InvoiceScope{UserID: "user-1"}, 1).Return(expected, nil)
 
-	inv, err := svc.GetInvoiceByID("user-1", 1)
+	inv, err := svc.GetInvoiceByID(context.Background(), Scope{UserID: "user-1"}, 1)
 	require.NoError(t, err)
 	require.Equal(t, expected, inv)
 
-	_, err = svc.GetInvoiceByID("", 1)
+	_, err = svc.GetInvoiceByID(context.Background(), Scope{}, 1)
 	require.Error(t, err)
 }
 
@@ -63,23 +125,78 @@ func TestBillingService_ListInvoices(t *testing.T) {
 	svc := NewBillingService(store)
 
 	expected := []*model.Invoice{{ID: 1}, {ID: 2}}
-	store.EXPECT().GetInvoices(repository.InvoiceFilter{UserID: "user-1", Status: "paid", Limit: 10, Offset: 5}).Return(expected, nil)
+	store.EXPECT().GetInvoices(gomock.Any(), repository.InvoiceFilter{UserID: "user-1", Status: "paid", Limit: 10, Offset: 5}).Return(expected, nil)
 
-	invoices, err := svc.ListInvoices("user-1", "paid", 10, 5)
+	invoices, err := svc.ListInvoices(context.Background(), Scope{UserID: "user-1"}, "paid", nil, 10, 5)
 	require.NoError(t, err)
 	require.Equal(t, expected, invoices)
 
-	_, err = svc.ListInvoices("", "paid", 10, 5)
+	meta := map[string]string{"crm_id": "C-42"}
+	store.EXPECT().GetInvoices(gomock.Any(), repository.InvoiceFilter{UserID: "user-1", Metadata: meta, Limit: 10}).Return(nil, nil)
+	_, err = svc.ListInvoices(context.Background(), Scope{UserID: "user-1"}, "", meta, 10, 0)
+	require.NoError(t, err)
+
+	_, err = svc.ListInvoices(context.Background(), Scope{}, "paid", nil, 10, 5)
 	require.Error(t, err)
 }
 
+func TestBillingService_OrgScope(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockInvoiceStore(ctrl)
+	svc := NewBillingService(store)
+
+	store.EXPECT().CreateInvoice(gomock.Any(), gomock.Any()).Return(nil)
+	inv, err := svc.CreateInvoice(context.Background(), Scope{UserID: "user-1", OrgID: "org-1", OrgRole: "admin"}, 100, "USD", nil)
+	require.NoError(t, err)
+	require.NotNil(t, inv.OrgID)
+	require.Equal(t, "org-1", *inv.OrgID)
+	require.Equal(t, "user-1", inv.UserID)
+
+	store.EXPECT().GetInvoiceByID(gomock.Any(), repository.InvoiceScope{UserID: "user-1", OrgID: "org-1"}, 7).Return(nil, nil)
+	_, err = svc.GetInvoiceByID(context.Background(), Scope{UserID: "user-1", OrgID: "org-1", OrgRole: "owner"}, 7)
+	require.NoError(t, err)
+
+	// member no tiene acceso a la facturación de la organización.
+	_, err = svc.ListInvoices(context.Background(), Scope{UserID: "user-1", OrgID: "org-1", OrgRole: "member"}, "", nil, 10, 0)
+	require.ErrorIs(t, err, ErrForbidden)
+}
+
 func TestBillingService_CreateInvoice_RepoError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	store := mocks.NewMockInvoiceStore(ctrl)
 	svc := NewBillingService(store)
 
-	store.EXPECT().CreateInvoice(gomock.Any()).Return(errors.New("db down"))
+	store.EXPECT().CreateInvoice(gomock.Any(), gomock.Any()).Return(errors.New("db down"))
 
-	_, err := svc.CreateInvoice("user-1", 100, "USD")
+	_, err := svc.CreateInvoice(context.Background(), Scope{UserID: "user-1"}, 100, "USD", nil)
 	require.Error(t, err)
 }
+
+func TestBillingService_ExportUserInvoices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockInvoiceStore(ctrl)
+	svc := NewBillingService(store)
+
+	store.EXPECT().ExportInvoices(gomock.Any(), "user-1").Return(nil, nil)
+
+	invoices, err := svc.ExportUserInvoices(context.Background(), "user-1")
+	require.NoError(t, err)
+	require.NotNil(t, invoices)
+	require.Empty(t, invoices)
+}
+
+func TestBillingService_PseudonymizeUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockInvoiceStore(ctrl)
+	svc := NewBillingService(store)
+
+	pseudonym := "6f1c2a9e-3b4d-8e5f-9a0b-1c2d3e4f5a6b"
+	store.EXPECT().PseudonymizeUser(gomock.Any(), "user-1", pseudonym).Return(int64(2), nil)
+
+	n, err := svc.PseudonymizeUser(context.Background(), "user-1", pseudonym)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+
+	_, err = svc.PseudonymizeUser(context.Background(), "user-1", "not-a-uuid")
+	require.ErrorIs(t, err, ErrInvalidPseudonym)
+}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+)
+
+// CatalogStore define las operaciones del catálogo que la capa de servicio
+// necesita del repositorio.
+type CatalogStore interface {
+	CreateProduct(ctx context.Context, p *model.Product) error
+	GetProduct(ctx context.Context, id int) (*model.Product, error)
+	ListProducts(ctx context.Context, filter repository.ProductFilter) ([]*model.Product, error)
+	UpdateProduct(ctx context.Context, id int, u model.ProductUpdate) (*model.Product, error)
+	DeleteProduct(ctx context.Context, id int) (bool, error)
+	CreatePrice(ctx context.Context, p *model.Price) error
+	GetPrice(ctx context.Context, id int) (*model.Price, error)
+	ListPrices(ctx context.Context, filter repository.PriceFilter) ([]*model.Price, error)
+	UpdatePrice(ctx context.Context, id int, u model.PriceUpdate) (*model.Price, error)
+}
+
+var (
+	// ErrInvalidCatalog: producto o precio con datos inválidos.
+	ErrInvalidCatalog = errors.New("invalid catalog entry")
+
+	ErrProductNotFound  = repository.ErrProductNotFound
+	ErrProductHasPrices = repository.ErrProductHasPrices
+	ErrPriceInUse       = repository.ErrPriceInUse
+	ErrLookupKeyTaken   = repository.ErrLookupKeyTaken
+)
+
+const (
+	maxProductName = 200
+	maxLookupKey   = 200
+	maxPriceTiers  = 20
+)
+
+// maxIntervalCount limita cada período a un año como máximo.
+var maxIntervalCount = map[string]int{
+	model.IntervalDay:   365,
+	model.IntervalWeek:  52,
+	model.IntervalMonth: 12,
+	model.IntervalYear:  1,
+}
+
+type CatalogService struct {
+	repo CatalogStore
+}
+
+func NewCatalogService(repo CatalogStore) *CatalogService {
+	return &CatalogService{repo: repo}
+}
+
+func invalidCatalog(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidCatalog, fmt.Sprintf(format, args...))
+}
+
+func validProductName(name string) error {
+	if name == "" || len(name) > maxProductName {
+		return invalidCatalog("name is required and must be at most %d characters", maxProductName)
+	}
+	return nil
+}
+
+// normalizePriceTerms valida moneda, monto, esquema de cobro, intervalo y uso
+// del precio y completa los defaults: moneda en mayúsculas, per_unit,
+// IntervalCount 1 si es recurrente, licensed y, si es metered, aggregate_usage
+// sum.
+func normalizePriceTerms(p *model.Price) error {
+	if err := normalizeUsage(p); err != nil {
+		return err
+	}
+	if err := normalizeScheme(p); err != nil {
+		return err
+	}
+	p.Currency = strings.ToUpper(strings.TrimSpace(p.Currency))
+	if len(p.Currency) != 3 || strings.Trim(p.Currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return invalidCatalog("currency must be a 3-letter ISO code")
+	}
+	if p.UnitAmountCents < 0 {
+		return invalidCatalog("unit_amount_cents must not be negative")
+	}
+	if p.Interval == model.IntervalOneTime {
+		if p.IntervalCount != 0 {
+			return invalidCatalog("interval_count must be 0 for one_time prices")
+		}
+		return nil
+	}
+	limit, ok := maxIntervalCount[p.Interval]
+	if !ok {
+		return invalidCatalog("interval must be one of one_time, day, week, month, year")
+	}
+	if p.IntervalCount == 0 {
+		p.IntervalCount = 1
+	}
+	if p.IntervalCount < 1 || p.IntervalCount > limit {
+		return invalidCatalog("interval_count must be between 1 and %d for %s prices", limit, p.Interval)
+	}
+	return nil
+}
+
+func normalizeUsage(p *model.Price) error {
+	switch p.UsageType {
+	case "", model.UsageLicensed:
+		if p.AggregateUsage != nil {
+			return invalidCatalog("aggregate_usage only applies to metered prices")
+		}
+		p.UsageType = model.UsageLicensed
+		return nil
+	case model.UsageMetered:
+	default:
+		return invalidCatalog("usage_type must be licensed or metered")
+	}
+	if p.Interval == model.IntervalOneTime {
+		return invalidCatalog("metered prices must be recurring")
+	}
+	if p.AggregateUsage == nil {
+		sum := model.AggregateSum
+		p.AggregateUsage = &sum
+	}
+	switch *p.AggregateUsage {
+	case model.AggregateSum, model.AggregateMax, model.AggregateLastDuringPeriod:
+		return nil
+	}
+	return invalidCatalog("aggregate_usage must be one of sum, max, last_during_period")
+}
+
+func normalizeScheme(p *model.Price) error {
+	switch p.BillingScheme {
+	case "", model.SchemePerUnit:
+		p.BillingScheme = model.SchemePerUnit
+		if p.PackageSize != nil || p.TiersMode != nil || len(p.Tiers) > 0 {
+			return invalidCatalog("package_size, tiers_mode and tiers do not apply to per_unit prices")
+		}
+		return nil
+	case model.SchemePackage:
+		if p.TiersMode != nil || len(p.Tiers) > 0 {
+			return invalidCatalog("tiers_mode and tiers only apply to tiered prices")
+		}
+		if p.PackageSize == nil || *p.PackageSize < 1 {
+			return invalidCatalog("package_size must be at least 1")
+		}
+		return nil
+	case model.SchemeTiered:
+	default:
+		return invalidCatalog("billing_scheme must be one of per_unit, package, tiered")
+	}
+
+	if p.PackageSize != nil {
+		return invalidCatalog("package_size only applies to package prices")
+	}
+	if p.TiersMode == nil || (*p.TiersMode != model.TiersGraduated && *p.TiersMode != model.TiersVolume) {
+		return invalidCatalog("tiers_mode must be graduated or volume")
+	}
+	// En un precio escalonado los montos van en los tramos.
+	if p.UnitAmountCents != 0 {
+		return invalidCatalog("unit_amount_cents must be 0 for tiered prices")
+	}
+	if len(p.Tiers) == 0 || len(p.Tiers) > maxPriceTiers {
+		return invalidCatalog("tiered prices need between 1 and %d tiers", maxPriceTiers)
+	}
+	var from int64
+	for i, t := range p.Tiers {
+		if t.UnitAmountCents < 0 || t.FlatAmountCents < 0 {
+			return invalidCatalog("tier %d: amounts must not be negative", i)
+		}
+		if last := i == len(p.Tiers)-1; last != (t.UpTo == nil) {
+			return invalidCatalog("tier %d: every tier but the last needs up_to, and the last must not set it", i)
+		}
+		if t.UpTo != nil {
+			if *t.UpTo <= from {
+				return invalidCatalog("tier %d: up_to must be greater than the previous tier's", i)
+			}
+			from = *t.UpTo
+		}
+	}
+	return nil
+}
+
+func validLookupKey(key string) error {
+	if len(key) > maxLookupKey || strings.TrimSpace(key) != key {
+		return invalidCatalog("lookup_key must be at most %d characters without surrounding spaces", maxLookupKey)
+	}
+	return nil
+}
+
+func (s *CatalogService) CreateProduct(ctx context.Context, name, description string) (*model.Product, error) {
+	name = strings.TrimSpace(name)
+	if err := validProductName(name); err != nil {
+		return nil, err
+	}
+	p := &model.Product{Name: name, Description: description, Active: true}
+	if err := s.repo.CreateProduct(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetProduct devuelve nil si el producto no existe.
+func (s *CatalogService) GetProduct(ctx context.Context, id int) (*model.Product, error) {
+	return s.repo.GetProduct(ctx, id)
+}
+
+func (s *CatalogService) ListProducts(ctx context.Context, active *bool, limit, offset int) ([]*model.Product, error) {
+	return s.repo.ListProducts(ctx, repository.ProductFilter{Active: active, Limit: limit, Offset: offset})
+}
+
+// UpdateProduct devuelve nil si el producto no existe.
+func (s *CatalogService) UpdateProduct(ctx context.Context, id int, u model.ProductUpdate) (*model.Product, error) {
+	if u.Name != nil {
+		name := strings.TrimSpace(*u.Name)
+		if err := validProductName(name); err != nil {
+			return nil, err
+		}
+		u.Name = &name
+	}
+	return s.repo.UpdateProduct(ctx, id, u)
+}
+
+// DeleteProduct solo borra productos sin precios; los demás se archivan con
+// active=false. Devuelve false si el producto no existe.
+func (s *CatalogService) DeleteProduct(ctx context.Context, id int) (bool, error) {
+	return s.repo.DeleteProduct(ctx, id)
+}
+
+// CreatePrice crea un precio activo para un producto existente.
+func (s *CatalogService) CreatePrice(ctx context.Context, p model.Price) (*model.Price, error) {
+	if p.ProductID <= 0 {
+		return nil, invalidCatalog("product_id is required")
+	}
+	if err := normalizePriceTerms(&p); err != nil {
+		return nil, err
+	}
+	if p.LookupKey != nil {
+		if err := validLookupKey(*p.LookupKey); err != nil {
+			return nil, err
+		}
+		if *p.LookupKey == "" {
+			p.LookupKey = nil
+		}
+	}
+	p.Active = true
+	if err := s.repo.CreatePrice(ctx, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetPrice devuelve nil si el precio no existe.
+func (s *CatalogService) GetPrice(ctx context.Context, id int) (*model.Price, error) {
+	return s.repo.GetPrice(ctx, id)
+}
+
+func (s *CatalogService) ListPrices(ctx context.Context, filter repository.PriceFilter) ([]*model.Price, error) {
+	return s.repo.ListPrices(ctx, filter)
+}
+
+// UpdatePrice devuelve nil si el precio no existe. Monto, moneda e intervalo
+// solo cambian si el precio nunca se usó (ErrPriceInUse si no).
+func (s *CatalogService) UpdatePrice(ctx context.Context, id int, u model.PriceUpdate) (*model.Price, error) {
+	if u.LookupKey != nil {
+		if err := validLookupKey(*u.LookupKey); err != nil {
+			return nil, err
+		}
+	}
+	if u.Terms() {
+		current, err := s.repo.GetPrice(ctx, id)
+		if err != nil || current == nil {
+			return nil, err
+		}
+		// Se valida el precio resultante, no cada campo suelto: cambiar a
+		// one_time sin interval_count debe dejarlo en 0.
+		next := *current
+		if u.UnitAmountCents != nil {
+			next.UnitAmountCents = *u.UnitAmountCents
+		}
+		if u.Currency != nil {
+			next.Currency = *u.Currency
+		}
+		if u.Interval != nil {
+			next.Interval = *u.Interval
+			if u.IntervalCount == nil {
+				next.IntervalCount = 0
+			}
+		}
+		if u.IntervalCount != nil {
+			next.IntervalCount = *u.IntervalCount
+		}
+		if err := normalizePriceTerms(&next); err != nil {
+			return nil, err
+		}
+		u.UnitAmountCents, u.Currency = &next.UnitAmountCents, &next.Currency
+		u.Interval, u.IntervalCount = &next.Interval, &next.IntervalCount
+	}
+	return s.repo.UpdatePrice(ctx, id, u)
+}
+
+// ArchivePrice desactiva el precio: deja de ofrecerse pero las facturas que lo
+// usan lo siguen referenciando. Devuelve nil si no existe.
+func (s *CatalogService) ArchivePrice(ctx context.Context, id int) (*model.Price, error) {
+	inactive := false
+	return s.repo.UpdatePrice(ctx, id, model.PriceUpdate{Active: &inactive})
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogService_CreatePrice_Validation(t *testing.T) {
+	key := "pro_monthly"
+	tests := []struct {
+		name      string
+		price     model.Price
+		wantErr   bool
+		wantCount int
+	}{
+		{name: "monthly defaults count to 1", price: model.Price{ProductID: 1, Currency: "usd", UnitAmountCents: 1500, Interval: model.IntervalMonth, LookupKey: &key}, wantCount: 1},
+		{name: "one time", price: model.Price{ProductID: 1, Currency: "EUR", UnitAmountCents: 0, Interval: model.IntervalOneTime}, wantCount: 0},
+		{name: "every 3 months", price: model.Price{ProductID: 1, Currency: "USD", UnitAmountCents: 100, Interval: model.IntervalMonth, IntervalCount: 3}, wantCount: 3},
+		{name: "missing product", price: model.Price{Currency: "USD", Interval: model.IntervalMonth}, wantErr: true},
+		{name: "bad currency", price: model.Price{ProductID: 1, Currency: "US1", Interval: model.IntervalMonth}, wantErr: true},
+		{name: "negative amount", price: model.Price{ProductID: 1, Currency: "USD", UnitAmountCents: -1, Interval: model.IntervalMonth}, wantErr: true},
+		{name: "unknown interval", price: model.Price{ProductID: 1, Currency: "USD", Interval: "quarter"}, wantErr: true},
+		{name: "one time with count", price: model.Price{ProductID: 1, Currency: "USD", Interval: model.IntervalOneTime, IntervalCount: 2}, wantErr: true},
+		{name: "more than a year", price: model.Price{ProductID: 1, Currency: "USD", Interval: model.IntervalMonth, IntervalCount: 13}, wantErr: true},
+		{name: "metered defaults to sum", price: model.Price{ProductID: 1, Currency: "USD", UnitAmountCents: 1, Interval: model.IntervalMonth, UsageType: model.UsageMetered}, wantCount: 1},
+		{name: "metered one time", price: model.Price{ProductID: 1, Currency: "USD", Interval: model.IntervalOneTime, UsageType: model.UsageMetered}, wantErr: true},
+		{name: "unknown aggregation", price: model.Price{ProductID: 1, Currency: "USD", Interval: model.IntervalMonth, UsageType: model.UsageMetered, AggregateUsage: &key}, wantErr: true},
+		{name: "licensed with aggregation", price: model.Price{ProductID: 1, Currency: "USD", Interval: model.IntervalMonth, AggregateUsage: &key}, wantErr: true},
+		{name: "package", price: model.Price{ProductID: 1, Currency: "USD", UnitAmountCents: 500, Interval: model.IntervalMonth, BillingScheme: model.SchemePackage, PackageSize: int64Ptr(10)}, wantCount: 1},
+		{name: "package without size", price: model.Price{ProductID: 1, Currency: "USD", UnitAmountCents: 500, Interval: model.IntervalMonth, BillingScheme: model.SchemePackage}, wantErr: true},
+		{name: "graduated", price: model.Price{ProductID: 1, Currency: "USD", Interval: model.IntervalMonth, BillingScheme: model.SchemeTiered, TiersMode: stringPtr(model.TiersGraduated),
+			Tiers: []model.PriceTier{{UpTo: int64Ptr(10), FlatAmountCents: 2000}, {UnitAmountCents: 150}}}, wantCount: 1},
+		{name: "tiered without mode", price: model.Price{ProductID: 1, Currency: "USD", Interval: model.IntervalMonth, BillingScheme: model.SchemeTiered,
+			Tiers: []model.PriceTier{{UnitAmountCents: 150}}}, wantErr: true},
+		{name: "tiered with unit amount", price: model.Price{ProductID: 1, Currency: "USD", UnitAmountCents: 100, Interval: model.IntervalMonth, BillingScheme: model.SchemeTiered,
+			TiersMode: stringPtr(model.TiersVolume), Tiers: []model.PriceTier{{UnitAmountCents: 150}}}, wantErr: true},
+		{name: "last tier bounded", price: model.Price{ProductID: 1, Currency: "USD", Interval: model.IntervalMonth, BillingScheme: model.SchemeTiered, TiersMode: stringPtr(model.TiersVolume),
+			Tiers: []model.PriceTier{{UpTo: int64Ptr(10), UnitAmountCents: 200}, {UpTo: int64Ptr(20), UnitAmountCents: 150}}}, wantErr: true},
+		{name: "tiers out of order", price: model.Price{ProductID: 1, Currency: "USD", Interval: model.IntervalMonth, BillingScheme: model.SchemeTiered, TiersMode: stringPtr(model.TiersVolume),
+			Tiers: []model.PriceTier{{UpTo: int64Ptr(10), UnitAmountCents: 200}, {UpTo: int64Ptr(10), UnitAmountCents: 150}, {UnitAmountCents: 100}}}, wantErr: true},
+		{name: "tiers on per unit", price: model.Price{ProductID: 1, Currency: "USD", UnitAmountCents: 100, Interval: model.IntervalMonth,
+			Tiers: []model.PriceTier{{UnitAmountCents: 100}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mocks.NewMockCatalogStore(ctrl)
+			svc := NewCatalogService(store)
+			if !tt.wantErr {
+				store.EXPECT().CreatePrice(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, p *model.Price) error {
+					p.ID = 7
+					return nil
+				})
+			}
+
+			price, err := svc.CreatePrice(context.Background(), tt.price)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidCatalog)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, 7, price.ID)
+			require.True(t, price.Active)
+			require.Len(t, price.Currency, 3)
+			require.Equal(t, tt.wantCount, price.IntervalCount)
+		})
+	}
+}
+
+func TestCatalogService_UpdatePrice_ValidatesResultingTerms(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockCatalogStore(ctrl)
+	svc := NewCatalogService(store)
+
+	current := &model.Price{ID: 7, ProductID: 1, Currency: "USD", UnitAmountCents: 1500, Interval: model.IntervalMonth, IntervalCount: 1, Active: true}
+	store.EXPECT().GetPrice(gomock.Any(), 7).Return(current, nil)
+	store.EXPECT().UpdatePrice(gomock.Any(), 7, gomock.Any()).DoAndReturn(func(_ context.Context, _ int, u model.PriceUpdate) (*model.Price, error) {
+		// Pasar a one_time sin interval_count deja el conteo en 0.
+		require.Equal(t, model.IntervalOneTime, *u.Interval)
+		require.Equal(t, 0, *u.IntervalCount)
+		require.Equal(t, int64(1500), *u.UnitAmountCents)
+		return &model.Price{ID: 7, Interval: *u.Interval}, nil
+	})
+
+	oneTime := model.IntervalOneTime
+	price, err := svc.UpdatePrice(context.Background(), 7, model.PriceUpdate{Interval: &oneTime})
+	require.NoError(t, err)
+	require.Equal(t, 7, price.ID)
+}
+
+func TestCatalogService_UpdatePrice_InUse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockCatalogStore(ctrl)
+	svc := NewCatalogService(store)
+
+	store.EXPECT().GetPrice(gomock.Any(), 7).Return(&model.Price{ID: 7, Currency: "USD", UnitAmountCents: 1500, Interval: model.IntervalMonth, IntervalCount: 1}, nil)
+	store.EXPECT().UpdatePrice(gomock.Any(), 7, gomock.Any()).Return(nil, ErrPriceInUse)
+
+	amount := int64(2000)
+	_, err := svc.UpdatePrice(context.Background(), 7, model.PriceUpdate{UnitAmountCents: &amount})
+	require.ErrorIs(t, err, ErrPriceInUse)
+}
+
+func TestCatalogService_ArchivePrice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockCatalogStore(ctrl)
+	svc := NewCatalogService(store)
+
+	store.EXPECT().UpdatePrice(gomock.Any(), 7, gomock.Any()).DoAndReturn(func(_ context.Context, _ int, u model.PriceUpdate) (*model.Price, error) {
+		require.False(t, u.Terms())
+		require.False(t, *u.Active)
+		return &model.Price{ID: 7}, nil
+	})
+
+	price, err := svc.ArchivePrice(context.Background(), 7)
+	require.NoError(t, err)
+	require.False(t, price.Active)
+}
+
+func TestCatalogService_CreateProduct_RequiresName(t *testing.T) {
+	svc := NewCatalogService(nil)
+
+	_, err := svc.CreateProduct(context.Background(), "   ", "")
+	require.ErrorIs(t, err, ErrInvalidCatalog)
+}
+
+func TestBillingService_CreateInvoiceFromPrice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockInvoiceStore(ctrl)
+	prices := mocks.NewMockCatalogStore(ctrl)
+	svc := NewBillingService(store, WithPrices(prices))
+
+	prices.EXPECT().GetPrice(gomock.Any(), 7).Return(&model.Price{ID: 7, Currency: "EUR", UnitAmountCents: 1500, Interval: model.IntervalOneTime, Active: true}, nil)
+	store.EXPECT().CreateInvoice(gomock.Any(), gomock.Any()).Return(nil)
+
+	inv, err := svc.CreateInvoiceFromPrice(context.Background(), Scope{UserID: "user-1"}, 7, 3, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(4500), inv.AmountCents)
+	require.Equal(t, "EUR", inv.Currency)
+	require.Equal(t, 7, *inv.PriceID)
+	require.Equal(t, 3, *inv.Quantity)
+}
+
+func TestBillingService_CreateInvoiceFromPrice_Unavailable(t *testing.T) {
+	tests := []struct {
+		name  string
+		price *model.Price
+		err   error
+	}{
+		{name: "missing", price: nil, err: ErrPriceUnavailable},
+		{name: "archived", price: &model.Price{ID: 7, Currency: "USD", UnitAmountCents: 100, Active: false}, err: ErrPriceUnavailable},
+		{name: "lookup error", price: nil, err: errors.New("db down")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			prices := mocks.NewMockCatalogStore(ctrl)
+			svc := NewBillingService(nil, WithPrices(prices))
+
+			var lookupErr error
+			if !errors.Is(tt.err, ErrPriceUnavailable) {
+				lookupErr = tt.err
+			}
+			prices.EXPECT().GetPrice(gomock.Any(), 7).Return(tt.price, lookupErr)
+
+			_, err := svc.CreateInvoiceFromPrice(context.Background(), Scope{UserID: "user-1"}, 7, 1, nil)
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,446 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+)
+
+// DiscountStore define las operaciones de cupones, códigos promocionales y
+// descuentos que la capa de servicio necesita del repositorio.
+type DiscountStore interface {
+	CreateCoupon(ctx context.Context, c *model.Coupon) error
+	GetCoupon(ctx context.Context, id int) (*model.Coupon, error)
+	ListCoupons(ctx context.Context, filter repository.CouponFilter) ([]*model.Coupon, error)
+	UpdateCoupon(ctx context.Context, id int, u model.CouponUpdate) (*model.Coupon, error)
+	CreatePromotionCode(ctx context.Context, p *model.PromotionCode) error
+	GetPromotionCode(ctx context.Context, id int) (*model.PromotionCode, error)
+	FindPromotionCode(ctx context.Context, code string) (*model.PromotionCode, error)
+	ListPromotionCodes(ctx context.Context, filter repository.PromotionCodeFilter) ([]*model.PromotionCode, error)
+	UpdatePromotionCode(ctx context.Context, id int, active *bool) (*model.PromotionCode, error)
+	HasInvoiced(ctx context.Context, scope repository.InvoiceScope) (bool, error)
+	GetDiscount(ctx context.Context, id int) (*model.Discount, error)
+	SubscriptionDiscount(ctx context.Context, subscriptionID int, at time.Time) (*model.Discount, error)
+	ApplySubscriptionDiscount(ctx context.Context, scope repository.InvoiceScope, d *model.Discount) (bool, error)
+	EndSubscriptionDiscount(ctx context.Context, subscriptionID int, at time.Time) (bool, error)
+	ApplyInvoiceDiscount(ctx context.Context, scope repository.InvoiceScope, invoice *model.Invoice, d *model.Discount) (bool, error)
+}
+
+var (
+	// ErrInvalidCoupon: cupón o código promocional con datos inválidos.
+	ErrInvalidCoupon = errors.New("invalid coupon")
+	// ErrDiscountNotApplicable: el descuento no se puede aplicar a esta factura o suscripción.
+	ErrDiscountNotApplicable = errors.New("discount not applicable")
+
+	ErrCouponNotFound     = repository.ErrCouponNotFound
+	ErrPromotionCodeTaken = repository.ErrPromotionCodeTaken
+	ErrNotRedeemable      = repository.ErrNotRedeemable
+	ErrInvoiceDiscounted  = repository.ErrInvoiceDiscounted
+)
+
+const (
+	maxCouponName = 200
+	// maxDiscountMonths limita la duración de un cupón repeating a tres años.
+	maxDiscountMonths = 36
+)
+
+// promotionCodePattern son los códigos que se pueden escribir sin ambigüedad.
+var promotionCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,50}$`)
+
+func invalidCoupon(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidCoupon, fmt.Sprintf(format, args...))
+}
+
+func notApplicable(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrDiscountNotApplicable, fmt.Sprintf(format, args...))
+}
+
+// WithDiscounts habilita cupones, códigos promocionales y descuentos.
+func WithDiscounts(discounts DiscountStore) Option {
+	return func(s *BillingService) { s.discounts = discounts }
+}
+
+func (s *BillingService) discountStore() (DiscountStore, error) {
+	if s.discounts == nil {
+		return nil, fmt.Errorf("discounts are not configured")
+	}
+	return s.discounts, nil
+}
+
+func validCurrency(currency string) bool {
+	return len(currency) == 3 && strings.Trim(currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+}
+
+// CreateCoupon crea un cupón activo. Lleva percent_off (1 a 100) o
+// amount_off_cents con currency; duration once, forever o repeating con
+// duration_in_months.
+func (s *BillingService) CreateCoupon(ctx context.Context, c model.Coupon) (*model.Coupon, error) {
+	store, err := s.discountStore()
+	if err != nil {
+		return nil, err
+	}
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" || len(c.Name) > maxCouponName {
+		return nil, invalidCoupon("name is required and must be at most %d characters", maxCouponName)
+	}
+	switch {
+	case (c.PercentOff == nil) == (c.AmountOffCents == nil):
+		return nil, invalidCoupon("use either percent_off or amount_off_cents")
+	case c.PercentOff != nil && (*c.PercentOff < 1 || *c.PercentOff > 100):
+		return nil, invalidCoupon("percent_off must be between 1 and 100")
+	case c.PercentOff != nil && c.Currency != nil:
+		return nil, invalidCoupon("currency only applies to amount_off_cents")
+	case c.AmountOffCents != nil && *c.AmountOffCents <= 0:
+		return nil, invalidCoupon("amount_off_cents must be positive")
+	}
+	if c.AmountOffCents != nil {
+		if c.Currency == nil {
+			return nil, invalidCoupon("amount_off_cents requires currency")
+		}
+		currency := strings.ToUpper(strings.TrimSpace(*c.Currency))
+		if !validCurrency(currency) {
+			return nil, invalidCoupon("currency must be a 3-letter ISO code")
+		}
+		c.Currency = &currency
+	}
+	switch c.Duration {
+	case model.CouponRepeating:
+		if c.DurationInMonths == nil || *c.DurationInMonths < 1 || *c.DurationInMonths > maxDiscountMonths {
+			return nil, invalidCoupon("duration_in_months must be between 1 and %d", maxDiscountMonths)
+		}
+	case model.CouponOnce, model.CouponForever:
+		if c.DurationInMonths != nil {
+			return nil, invalidCoupon("duration_in_months only applies to repeating coupons")
+		}
+	default:
+		return nil, invalidCoupon("duration must be once, repeating or forever")
+	}
+	if c.MaxRedemptions != nil && *c.MaxRedemptions < 1 {
+		return nil, invalidCoupon("max_redemptions must be positive")
+	}
+	if c.RedeemBy != nil && !c.RedeemBy.After(s.now()) {
+		return nil, invalidCoupon("redeem_by must be in the future")
+	}
+
+	c.Active = true
+	if err := store.CreateCoupon(ctx, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetCoupon devuelve nil si el cupón no existe.
+func (s *BillingService) GetCoupon(ctx context.Context, id int) (*model.Coupon, error) {
+	store, err := s.discountStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.GetCoupon(ctx, id)
+}
+
+func (s *BillingService) ListCoupons(ctx context.Context, active *bool, limit, offset int) ([]*model.Coupon, error) {
+	store, err := s.discountStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.ListCoupons(ctx, repository.CouponFilter{Active: active, Limit: limit, Offset: offset})
+}
+
+// UpdateCoupon cambia nombre o active; los términos del descuento no cambian.
+// Devuelve nil si el cupón no existe.
+func (s *BillingService) UpdateCoupon(ctx context.Context, id int, u model.CouponUpdate) (*model.Coupon, error) {
+	store, err := s.discountStore()
+	if err != nil {
+		return nil, err
+	}
+	if u.Name != nil {
+		name := strings.TrimSpace(*u.Name)
+		if name == "" || len(name) > maxCouponName {
+			return nil, invalidCoupon("name is required and must be at most %d characters", maxCouponName)
+		}
+		u.Name = &name
+	}
+	return store.UpdateCoupon(ctx, id, u)
+}
+
+// ArchiveCoupon desactiva el cupón: no admite canjes nuevos, pero los
+// descuentos ya aplicados siguen rigiendo. Devuelve nil si no existe.
+func (s *BillingService) ArchiveCoupon(ctx context.Context, id int) (*model.Coupon, error) {
+	inactive := false
+	return s.UpdateCoupon(ctx, id, model.CouponUpdate{Active: &inactive})
+}
+
+// CreatePromotionCode crea un código activo para un cupón existente.
+func (s *BillingService) CreatePromotionCode(ctx context.Context, p model.PromotionCode) (*model.PromotionCode, error) {
+	store, err := s.discountStore()
+	if err != nil {
+		return nil, err
+	}
+	if !promotionCodePattern.MatchString(p.Code) {
+		return nil, invalidCoupon("code must be 3 to 50 letters, digits, '-' or '_'")
+	}
+	if p.CouponID <= 0 {
+		return nil, invalidCoupon("coupon_id is required")
+	}
+	if p.MaxRedemptions != nil && *p.MaxRedemptions < 1 {
+		return nil, invalidCoupon("max_redemptions must be positive")
+	}
+	if p.ExpiresAt != nil && !p.ExpiresAt.After(s.now()) {
+		return nil, invalidCoupon("expires_at must be in the future")
+	}
+	r := &p.Restrictions
+	if (r.MinimumAmountCents == nil) != (r.MinimumAmountCurrency == nil) {
+		return nil, invalidCoupon("minimum_amount_cents and minimum_amount_currency go together")
+	}
+	if r.MinimumAmountCents != nil {
+		currency := strings.ToUpper(strings.TrimSpace(*r.MinimumAmountCurrency))
+		if *r.MinimumAmountCents <= 0 || !validCurrency(currency) {
+			return nil, invalidCoupon("minimum_amount_cents must be positive and minimum_amount_currency a 3-letter ISO code")
+		}
+		r.MinimumAmountCurrency = &currency
+	}
+
+	p.Active = true
+	if err := store.CreatePromotionCode(ctx, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetPromotionCode devuelve nil si el código no existe.
+func (s *BillingService) GetPromotionCode(ctx context.Context, id int) (*model.PromotionCode, error) {
+	store, err := s.discountStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.GetPromotionCode(ctx, id)
+}
+
+func (s *BillingService) ListPromotionCodes(ctx context.Context, filter repository.PromotionCodeFilter) ([]*model.PromotionCode, error) {
+	store, err := s.discountStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.ListPromotionCodes(ctx, filter)
+}
+
+// UpdatePromotionCode activa o desactiva el código; nil si no existe.
+func (s *BillingService) UpdatePromotionCode(ctx context.Context, id int, active *bool) (*model.PromotionCode, error) {
+	store, err := s.discountStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.UpdatePromotionCode(ctx, id, active)
+}
+
+// DiscountRequest es el cupón a canjear: por ID o, lo que usan los clientes,
+// por código promocional. Solo uno de los dos.
+type DiscountRequest struct {
+	CouponID      int
+	PromotionCode string
+}
+
+// resolveDiscount valida req para un cliente que paga subtotal en currency y
+// arma el descuento (sin guardar) que empieza ahora. Los límites de canje se
+// vuelven a comprobar al guardarlo.
+func (s *BillingService) resolveDiscount(ctx context.Context, store DiscountStore, owner repository.InvoiceScope, req DiscountRequest, currency string, subtotal int64) (*model.Discount, error) {
+	if (req.CouponID == 0) == (req.PromotionCode == "") {
+		return nil, notApplicable("use either coupon_id or promotion_code")
+	}
+	now := s.now()
+	d := &model.Discount{Start: now}
+
+	couponID := req.CouponID
+	var promo *model.PromotionCode
+	if req.PromotionCode != "" {
+		var err error
+		if promo, err = store.FindPromotionCode(ctx, req.PromotionCode); err != nil {
+			return nil, err
+		}
+		if promo == nil {
+			return nil, notApplicable("promotion code not found")
+		}
+		if !promo.Redeemable(now) {
+			return nil, ErrNotRedeemable
+		}
+		couponID = promo.CouponID
+		d.PromotionCodeID = &promo.ID
+	}
+
+	coupon, err := store.GetCoupon(ctx, couponID)
+	if err != nil {
+		return nil, err
+	}
+	if coupon == nil {
+		return nil, ErrCouponNotFound
+	}
+	if !coupon.Redeemable(now) {
+		return nil, ErrNotRedeemable
+	}
+	if coupon.Currency != nil && *coupon.Currency != currency {
+		return nil, notApplicable("coupon currency %s does not match %s", *coupon.Currency, currency)
+	}
+
+	if promo != nil {
+		r := promo.Restrictions
+		if r.MinimumAmountCents != nil && (*r.MinimumAmountCurrency != currency || subtotal < *r.MinimumAmountCents) {
+			return nil, notApplicable("promotion code requires a minimum of %d %s", *r.MinimumAmountCents, *r.MinimumAmountCurrency)
+		}
+		if r.FirstTimeTransaction {
+			invoiced, err := store.HasInvoiced(ctx, owner)
+			if err != nil {
+				return nil, err
+			}
+			if invoiced {
+				return nil, notApplicable("promotion code is only for first-time customers")
+			}
+		}
+	}
+
+	d.Coupon = *coupon
+	d.End = coupon.DiscountEnd(now)
+	return d, nil
+}
+
+// ApplySubscriptionDiscount canjea un cupón sobre la suscripción y reemplaza
+// el descuento que tuviera. Rige desde la próxima factura (el período en curso
+// ya se facturó por adelantado). Devuelve nil si no existe en el scope.
+func (s *BillingService) ApplySubscriptionDiscount(ctx context.Context, scope Scope, id int, req DiscountRequest) (*model.Discount, error) {
+	store, err := s.discountStore()
+	if err != nil {
+		return nil, err
+	}
+	sub, err := s.GetSubscription(ctx, scope, id)
+	if err != nil || sub == nil {
+		return nil, err
+	}
+	if sub.Status == model.SubscriptionCanceled {
+		return nil, ErrInvalidTransition
+	}
+	if s.prices == nil {
+		return nil, fmt.Errorf("price catalog is not configured")
+	}
+	price, err := s.prices.GetPrice(ctx, sub.PriceID)
+	if err != nil {
+		return nil, err
+	}
+	if price == nil {
+		return nil, ErrPriceUnavailable
+	}
+	// El mínimo de un código se compara con lo que se cobra por período.
+	amount, err := price.Amount(int64(sub.Quantity))
+	if err != nil {
+		amount = math.MaxInt64
+	}
+
+	owner, _ := scope.invoiceScope()
+	d, err := s.resolveDiscount(ctx, store, owner, req, price.Currency, amount)
+	if err != nil {
+		return nil, err
+	}
+	d.SubscriptionID = &sub.ID
+	found, err := store.ApplySubscriptionDiscount(ctx, owner, d)
+	if err != nil || !found {
+		return nil, err
+	}
+	return d, nil
+}
+
+// RemoveSubscriptionDiscount termina el descuento vigente de la suscripción.
+// Devuelve false si la suscripción no existe en el scope o no tiene descuento.
+func (s *BillingService) RemoveSubscriptionDiscount(ctx context.Context, scope Scope, id int) (bool, error) {
+	store, err := s.discountStore()
+	if err != nil {
+		return false, err
+	}
+	sub, err := s.GetSubscription(ctx, scope, id)
+	if err != nil || sub == nil {
+		return false, err
+	}
+	return store.EndSubscriptionDiscount(ctx, sub.ID, s.now())
+}
+
+// ApplyInvoiceDiscount canjea un cupón sobre un borrador: el descuento se
+// reparte en los renglones (ver model.Coupon.Apply) y se recalculan los
+// totales. Una factura admite un solo descuento. Devuelve nil si no existe en
+// el scope.
+func (s *BillingService) ApplyInvoiceDiscount(ctx context.Context, scope Scope, id int, req DiscountRequest) (*model.Invoice, error) {
+	store, err := s.discountStore()
+	if err != nil {
+		return nil, err
+	}
+	owner, err := scope.invoiceScope()
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.repo.GetInvoiceByID(ctx, owner, id)
+	if err != nil || current == nil {
+		return nil, err
+	}
+	if current.Status != model.InvoiceDraft {
+		return nil, ErrInvoiceNotDraft
+	}
+	if current.DiscountID != nil {
+		return nil, ErrInvoiceDiscounted
+	}
+
+	d, err := s.resolveDiscount(ctx, store, owner, req, current.Currency, current.SubtotalCents)
+	if err != nil {
+		return nil, err
+	}
+	// En una factura suelta el descuento se usa una vez, sea cual sea su duración.
+	d.End = &d.Start
+
+	invoice := &model.Invoice{ID: id, Lines: current.Lines, UpdatedAt: d.Start}
+	d.Coupon.Apply(invoice.Lines)
+	if err := invoice.ComputeTotals(); err != nil {
+		return nil, err
+	}
+	found, err := store.ApplyInvoiceDiscount(ctx, owner, invoice, d)
+	if err != nil || !found {
+		return nil, err
+	}
+	return s.repo.GetInvoiceByID(ctx, owner, id)
+}
+
+// invoiceDiscount aplica a los renglones de la factura el descuento con ID
+// discountID (el que ya tenía la factura al editar sus renglones).
+func (s *BillingService) invoiceDiscount(ctx context.Context, discountID int, lines []model.InvoiceLineItem) error {
+	store, err := s.discountStore()
+	if err != nil {
+		return err
+	}
+	d, err := store.GetDiscount(ctx, discountID)
+	if err != nil {
+		return err
+	}
+	if d != nil {
+		d.Coupon.Apply(lines)
+	}
+	return nil
+}
+
+// subscriptionDiscount aplica a invoice el descuento de la suscripción que
+// rige al empezar el período. Sin descuentos configurados no hace nada.
+func (s *BillingService) subscriptionDiscount(ctx context.Context, sub *model.Subscription, invoice *model.Invoice) error {
+	if s.discounts == nil {
+		return nil
+	}
+	d, err := s.discounts.SubscriptionDiscount(ctx, sub.ID, *invoice.PeriodStart)
+	if err != nil || d == nil {
+		return err
+	}
+	if d.Coupon.Currency != nil && *d.Coupon.Currency != invoice.Currency {
+		return nil
+	}
+	d.Coupon.Apply(invoice.Lines)
+	invoice.DiscountID = &d.ID
+	return nil
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+	"saas-subscription-platform/services/billing-service/internal/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(v int) *int          { return &v }
+func int64Ptr(v int64) *int64    { return &v }
+func stringPtr(v string) *string { return &v }
+
+func TestBillingService_CreateCoupon_Invalid(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	tests := []struct {
+		name   string
+		coupon model.Coupon
+	}{
+		{"missing name", model.Coupon{PercentOff: intPtr(10), Duration: model.CouponOnce}},
+		{"no amount", model.Coupon{Name: "x", Duration: model.CouponOnce}},
+		{"percent and amount", model.Coupon{Name: "x", PercentOff: intPtr(10), AmountOffCents: int64Ptr(500), Currency: stringPtr("USD"), Duration: model.CouponOnce}},
+		{"percent over 100", model.Coupon{Name: "x", PercentOff: intPtr(101), Duration: model.CouponOnce}},
+		{"amount without currency", model.Coupon{Name: "x", AmountOffCents: int64Ptr(500), Duration: model.CouponOnce}},
+		{"unknown duration", model.Coupon{Name: "x", PercentOff: intPtr(10), Duration: "weekly"}},
+		{"repeating without months", model.Coupon{Name: "x", PercentOff: intPtr(10), Duration: model.CouponRepeating}},
+		{"months on forever", model.Coupon{Name: "x", PercentOff: intPtr(10), Duration: model.CouponForever, DurationInMonths: intPtr(3)}},
+		{"redeem_by in the past", model.Coupon{Name: "x", PercentOff: intPtr(10), Duration: model.CouponOnce, RedeemBy: &past}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			svc := NewBillingService(mocks.NewMockInvoiceStore(ctrl), WithDiscounts(mocks.NewMockDiscountStore(ctrl)),
+				WithClock(func() time.Time { return now }))
+
+			_, err := svc.CreateCoupon(context.Background(), tt.coupon)
+			require.ErrorIs(t, err, ErrInvalidCoupon)
+		})
+	}
+}
+
+func TestBillingService_ApplyInvoiceDiscount(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockInvoiceStore(ctrl)
+	discounts := mocks.NewMockDiscountStore(ctrl)
+	svc := NewBillingService(store, WithDiscounts(discounts), WithClock(func() time.Time { return now }))
+	scope := repository.InvoiceScope{UserID: "user-1"}
+
+	draft := &model.Invoice{ID: 5, Currency: "USD", Status: model.InvoiceDraft, SubtotalCents: 5000, Lines: []model.InvoiceLineItem{
+		{Description: "Seats", Quantity: 1, UnitAmountCents: 5000, AmountCents: 5000},
+	}}
+	store.EXPECT().GetInvoiceByID(gomock.Any(), scope, 5).Return(draft, nil)
+	discounts.EXPECT().FindPromotionCode(gomock.Any(), "launch20").Return(&model.PromotionCode{ID: 9, CouponID: 2, Active: true,
+		Restrictions: model.PromotionRestrictions{FirstTimeTransaction: true}}, nil)
+	discounts.EXPECT().GetCoupon(gomock.Any(), 2).Return(&model.Coupon{ID: 2, PercentOff: intPtr(20), Duration: model.CouponForever, Active: true}, nil)
+	discounts.EXPECT().HasInvoiced(gomock.Any(), scope).Return(false, nil)
+	discounts.EXPECT().ApplyInvoiceDiscount(gomock.Any(), scope, gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ repository.InvoiceScope, inv *model.Invoice, d *model.Discount) (bool, error) {
+			require.Equal(t, 9, *d.PromotionCodeID)
+			require.Equal(t, 2, d.Coupon.ID)
+			require.Equal(t, now, d.Start)
+			require.Equal(t, now, *d.End)
+			require.Equal(t, int64(1000), inv.Lines[0].DiscountAmountCents)
+			require.Equal(t, int64(1000), inv.TotalDiscountCents)
+			require.Equal(t, int64(4000), inv.AmountDueCents)
+			return true, nil
+		})
+	store.EXPECT().GetInvoiceByID(gomock.Any(), scope, 5).Return(draft, nil)
+
+	_, err := svc.ApplyInvoiceDiscount(context.Background(), Scope{UserID: "user-1"}, 5, DiscountRequest{PromotionCode: "launch20"})
+	require.NoError(t, err)
+}
+
+func TestBillingService_ApplyInvoiceDiscount_Rejected(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Hour)
+	coupon := &model.Coupon{ID: 2, PercentOff: intPtr(20), Duration: model.CouponOnce, Active: true}
+	tests := []struct {
+		name    string
+		invoice *model.Invoice
+		req     DiscountRequest
+		setup   func(d *mocks.MockDiscountStore)
+		wantErr error
+	}{
+		{
+			name:    "coupon and code",
+			req:     DiscountRequest{CouponID: 2, PromotionCode: "launch20"},
+			wantErr: ErrDiscountNotApplicable,
+		},
+		{
+			name:    "already discounted",
+			invoice: &model.Invoice{ID: 5, Currency: "USD", Status: model.InvoiceDraft, DiscountID: intPtr(1)},
+			req:     DiscountRequest{CouponID: 2},
+			wantErr: ErrInvoiceDiscounted,
+		},
+		{
+			name:    "finalized invoice",
+			invoice: &model.Invoice{ID: 5, Currency: "USD", Status: model.InvoiceOpen},
+			req:     DiscountRequest{CouponID: 2},
+			wantErr: ErrInvoiceNotDraft,
+		},
+		{
+			name: "unknown code",
+			req:  DiscountRequest{PromotionCode: "nope"},
+			setup: func(d *mocks.MockDiscountStore) {
+				d.EXPECT().FindPromotionCode(gomock.Any(), "nope").Return(nil, nil)
+			},
+			wantErr: ErrDiscountNotApplicable,
+		},
+		{
+			name: "expired code",
+			req:  DiscountRequest{PromotionCode: "launch20"},
+			setup: func(d *mocks.MockDiscountStore) {
+				d.EXPECT().FindPromotionCode(gomock.Any(), "launch20").Return(&model.PromotionCode{ID: 9, CouponID: 2, Active: true, ExpiresAt: &expired}, nil)
+			},
+			wantErr: ErrNotRedeemable,
+		},
+		{
+			name: "currency mismatch",
+			req:  DiscountRequest{CouponID: 3},
+			setup: func(d *mocks.MockDiscountStore) {
+				d.EXPECT().GetCoupon(gomock.Any(), 3).Return(&model.Coupon{ID: 3, AmountOffCents: int64Ptr(500), Currency: stringPtr("EUR"),
+					Duration: model.CouponOnce, Active: true}, nil)
+			},
+			wantErr: ErrDiscountNotApplicable,
+		},
+		{
+			name: "below minimum amount",
+			req:  DiscountRequest{PromotionCode: "launch20"},
+			setup: func(d *mocks.MockDiscountStore) {
+				d.EXPECT().FindPromotionCode(gomock.Any(), "launch20").Return(&model.PromotionCode{ID: 9, CouponID: 2, Active: true,
+					Restrictions: model.PromotionRestrictions{MinimumAmountCents: int64Ptr(10000), MinimumAmountCurrency: stringPtr("USD")}}, nil)
+				d.EXPECT().GetCoupon(gomock.Any(), 2).Return(coupon, nil)
+			},
+			wantErr: ErrDiscountNotApplicable,
+		},
+		{
+			name: "not a first-time customer",
+			req:  DiscountRequest{PromotionCode: "launch20"},
+			setup: func(d *mocks.MockDiscountStore) {
+				d.EXPECT().FindPromotionCode(gomock.Any(), "launch20").Return(&model.PromotionCode{ID: 9, CouponID: 2, Active: true,
+					Restrictions: model.PromotionRestrictions{FirstTimeTransaction: true}}, nil)
+				d.EXPECT().GetCoupon(gomock.Any(), 2).Return(coupon, nil)
+				d.EXPECT().HasInvoiced(gomock.Any(), repository.InvoiceScope{UserID: "user-1"}).Return(true, nil)
+			},
+			wantErr: ErrDiscountNotApplicable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mocks.NewMockInvoiceStore(ctrl)
+			discounts := mocks.NewMockDiscountStore(ctrl)
+			svc := NewBillingService(store, WithDiscounts(discounts), WithClock(func() time.Time { return now }))
+
+			invoice := tt.invoice
+			if invoice == nil {
+				invoice = &model.Invoice{ID: 5, Currency: "USD", Status: model.InvoiceDraft, SubtotalCents: 5000}
+			}
+			store.EXPECT().GetInvoiceByID(gomock.Any(), gomock.Any(), 5).Return(invoice, nil)
+			if tt.setup != nil {
+				tt.setup(discounts)
+			}
+
+			_, err := svc.ApplyInvoiceDiscount(context.Background(), Scope{UserID: "user-1"}, 5, tt.req)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestBillingService_ApplySubscriptionDiscount(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	ctrl := gomock.NewController(t)
+	subs := mocks.NewMockSubscriptionStore(ctrl)
+	prices := mocks.NewMockCatalogStore(ctrl)
+	discounts := mocks.NewMockDiscountStore(ctrl)
+	svc := NewBillingService(mocks.NewMockInvoiceStore(ctrl), WithPrices(prices), WithSubscriptions(subs), WithDiscounts(discounts),
+		WithClock(func() time.Time { return now }))
+	scope := repository.InvoiceScope{UserID: "user-1"}
+
+	subs.EXPECT().GetSubscription(gomock.Any(), scope, 3).Return(&model.Subscription{ID: 3, UserID: "user-1", PriceID: 7, Quantity: 2,
+		Status: model.SubscriptionActive}, nil)
+	prices.EXPECT().GetPrice(gomock.Any(), 7).Return(&model.Price{ID: 7, Currency: "USD", UnitAmountCents: 1500}, nil)
+	discounts.EXPECT().GetCoupon(gomock.Any(), 2).Return(&model.Coupon{ID: 2, PercentOff: intPtr(50), Duration: model.CouponRepeating,
+		DurationInMonths: intPtr(3), Active: true}, nil)
+	discounts.EXPECT().ApplySubscriptionDiscount(gomock.Any(), scope, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ repository.InvoiceScope, d *model.Discount) (bool, error) {
+			require.Equal(t, 3, *d.SubscriptionID)
+			require.Equal(t, now, d.Start)
+			require.Equal(t, time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC), *d.End)
+			return true, nil
+		})
+
+	d, err := svc.ApplySubscriptionDiscount(context.Background(), Scope{UserID: "user-1"}, 3, DiscountRequest{CouponID: 2})
+	require.NoError(t, err)
+	require.NotNil(t, d)
+}
+
+func TestRenewSubscriptions_AppliesSubscriptionDiscount(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	ctrl := gomock.NewController(t)
+	invoices := mocks.NewMockInvoiceStore(ctrl)
+	subs := mocks.NewMockSubscriptionStore(ctrl)
+	prices := mocks.NewMockCatalogStore(ctrl)
+	discounts := mocks.NewMockDiscountStore(ctrl)
+	svc := NewBillingService(invoices, WithPrices(prices), WithSubscriptions(subs), WithDiscounts(discounts),
+		WithClock(func() time.Time { return now }))
+
+	start := time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)
+	sub := &model.Subscription{ID: 3, UserID: "user-1", PriceID: 7, Quantity: 2, Status: model.SubscriptionActive,
+		CurrentPeriodStart: start, CurrentPeriodEnd: now, BillingCycleAnchor: start}
+
+	gomock.InOrder(
+		subs.EXPECT().UnbilledSubscriptions(gomock.Any(), renewalBatch).Return([]*model.Subscription{sub}, nil),
+		subs.EXPECT().DueSubscriptions(gomock.Any(), now, renewalBatch).Return(nil, nil),
+		subs.EXPECT().UnbilledSubscriptions(gomock.Any(), renewalBatch).Return(nil, nil),
+	)
+	prices.EXPECT().GetPrice(gomock.Any(), 7).Return(&model.Price{ID: 7, Currency: "USD", UnitAmountCents: 1500, Interval: model.IntervalMonth, IntervalCount: 1}, nil)
+	subs.EXPECT().PendingInvoiceItems(gomock.Any(), 3).Return(nil, nil)
+	discounts.EXPECT().SubscriptionDiscount(gomock.Any(), 3, start).Return(&model.Discount{ID: 11,
+		Coupon: model.Coupon{ID: 2, AmountOffCents: int64Ptr(500), Currency: stringPtr("USD"), Duration: model.CouponOnce}}, nil)
+	invoices.EXPECT().CreateInvoice(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, inv *model.Invoice) error {
+		require.Equal(t, 11, *inv.DiscountID)
+		require.Equal(t, int64(3000), inv.SubtotalCents)
+		require.Equal(t, int64(500), inv.TotalDiscountCents)
+		require.Equal(t, int64(2500), inv.AmountDueCents)
+		return nil
+	})
+
+	res, err := svc.RenewSubscriptions(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, RenewalResult{Invoiced: 1}, res)
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+)
+
+var (
+	// ErrInvalidInvoiceTransition: la factura no admite la operación en su estado actual.
+	ErrInvalidInvoiceTransition = repository.ErrInvalidInvoiceTransition
+	// ErrInvoiceNotDraft: la factura ya se finalizó y su contenido no cambia.
+	ErrInvoiceNotDraft = repository.ErrInvoiceNotDraft
+	// ErrInvalidLines: los renglones pedidos no son válidos.
+	ErrInvalidLines = errors.New("invalid invoice lines")
+	// ErrPaymentMismatch: el cobro no coincide con el saldo de la factura.
+	ErrPaymentMismatch = errors.New("payment does not match the invoice amount due")
+)
+
+// invoiceTransitions es la máquina de estados de las facturas. Un borrador
+// solo se finaliza; una factura open se paga, se anula o se da por
+// incobrable, y una incobrable todavía se puede cobrar o anular.
+var invoiceTransitions = stateMachine{
+	model.InvoiceDraft:         {model.InvoiceOpen},
+	model.InvoiceOpen:          {model.InvoicePaid, model.InvoiceVoid, model.InvoiceUncollectible},
+	model.InvoiceUncollectible: {model.InvoicePaid, model.InvoiceVoid},
+	model.InvoicePaid:          {},
+	model.InvoiceVoid:          {},
+}
+
+// moveInvoice lleva la factura a to desde cualquier estado que la máquina de
+// estados permita. Un admin de la plataforma la busca solo por id, así puede
+// operar sobre la factura de cualquier cliente. Devuelve nil si no existe en
+// el scope.
+func (s *BillingService) moveInvoice(ctx context.Context, scope Scope, id int, to string) (*model.Invoice, error) {
+	if scope.Role == platformAdminRole && scope.UserID != "" {
+		invoice, err := s.repo.TransitionInvoice(ctx, repository.InvoiceScope{AllOwners: true}, id, invoiceTransitions.sourcesOf(to), to, s.now())
+		if err == nil && invoice != nil {
+			log.Printf("invoice_status_changed_by_admin invoice_id=%d admin_id=%s status=%s", id, scope.UserID, to)
+		}
+		return invoice, err
+	}
+	owner, err := scope.invoiceScope()
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.TransitionInvoice(ctx, owner, id, invoiceTransitions.sourcesOf(to), to, s.now())
+}
+
+// FinalizeInvoice emite un borrador: le asigna número y fija su contenido.
+func (s *BillingService) FinalizeInvoice(ctx context.Context, scope Scope, id int) (*model.Invoice, error) {
+	return s.moveInvoice(ctx, scope, id, model.InvoiceOpen)
+}
+
+// VoidInvoice anula una factura emitida que no se pagó.
+func (s *BillingService) VoidInvoice(ctx context.Context, scope Scope, id int) (*model.Invoice, error) {
+	return s.moveInvoice(ctx, scope, id, model.InvoiceVoid)
+}
+
+// MarkInvoiceUncollectible da por incobrable una factura emitida.
+func (s *BillingService) MarkInvoiceUncollectible(ctx context.Context, scope Scope, id int) (*model.Invoice, error) {
+	return s.moveInvoice(ctx, scope, id, model.InvoiceUncollectible)
+}
+
+// InvoicePayment es un cobro que informa payment-service: el dueño de la
+// factura, el payment intent que la pagó y lo que se cobró.
+type InvoicePayment struct {
+	UserID          string
+	OrgID           string
+	PaymentIntentID int
+	AmountCents     int64
+	Currency        string
+}
+
+// RecordInvoicePayment pasa a paid la factura que cobró payment-service. Es
+// idempotente: si la factura ya estaba paga la devuelve tal cual, así un
+// reintento del aviso no falla. Devuelve nil si no existe para ese dueño.
+func (s *BillingService) RecordInvoicePayment(ctx context.Context, id int, p InvoicePayment) (*model.Invoice, error) {
+	if p.UserID == "" {
+		return nil, fmt.Errorf("user id is required")
+	}
+	owner := repository.InvoiceScope{UserID: p.UserID, OrgID: p.OrgID}
+	current, err := s.repo.GetInvoiceByID(ctx, owner, id)
+	if err != nil || current == nil {
+		return nil, err
+	}
+	if current.Status == model.InvoicePaid {
+		return current, nil
+	}
+	if p.AmountCents != current.AmountDueCents || p.Currency != current.Currency {
+		return nil, ErrPaymentMismatch
+	}
+
+	invoice, err := s.repo.TransitionInvoice(ctx, owner, id, invoiceTransitions.sourcesOf(model.InvoicePaid), model.InvoicePaid, s.now())
+	if errors.Is(err, ErrInvalidInvoiceTransition) {
+		// Otro aviso del mismo cobro pudo ganarle la carrera.
+		if latest, getErr := s.repo.GetInvoiceByID(ctx, owner, id); getErr == nil && latest != nil && latest.Status == model.InvoicePaid {
+			return latest, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("invoice_paid invoice_id=%d payment_intent_id=%d", id, p.PaymentIntentID)
+	return invoice, nil
+}
+
+// UpdateInvoiceLines reemplaza los renglones de un borrador y recalcula los
+// totales. Devuelve nil si la factura no existe en el scope.
+func (s *BillingService) UpdateInvoiceLines(ctx context.Context, scope Scope, id int, lines []model.InvoiceLineItem) (*model.Invoice, error) {
+	owner, err := scope.invoiceScope()
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.repo.GetInvoiceByID(ctx, owner, id)
+	if err != nil || current == nil {
+		return nil, err
+	}
+	if current.Status != model.InvoiceDraft {
+		return nil, ErrInvoiceNotDraft
+	}
+
+	out, err := s.buildLines(ctx, current.Currency, lines)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLines, err)
+	}
+	invoice := &model.Invoice{ID: id, Lines: out, UpdatedAt: s.now()}
+	if err := invoice.ComputeTotals(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLines, err)
+	}
+	if invoice.AmountDueCents <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidLines)
+	}
+	// El descuento de la factura se reparte de nuevo sobre los renglones nuevos.
+	if current.DiscountID != nil {
+		if err := s.invoiceDiscount(ctx, *current.DiscountID, invoice.Lines); err != nil {
+			return nil, err
+		}
+		if err := invoice.ComputeTotals(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidLines, err)
+		}
+	}
+	return s.repo.ReplaceInvoiceLines(ctx, owner, invoice)
+}
+
+// issued marca una factura generada por el sistema como ya emitida: open, o
+// paid si no queda nada por cobrar.
+func issued(invoice *model.Invoice) {
+	at := invoice.CreatedAt
+	invoice.Status = model.InvoiceOpen
+	invoice.StatusTransitions.FinalizedAt = &at
+	if invoice.AmountDueCents == 0 {
+		invoice.Status = model.InvoicePaid
+		invoice.StatusTransitions.PaidAt = &at
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+	"saas-subscription-platform/services/billing-service/internal/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoiceStateMachine(t *testing.T) {
+	draft, open, paid, void, uncollectible := model.InvoiceDraft, model.InvoiceOpen, model.InvoicePaid, model.InvoiceVoid, model.InvoiceUncollectible
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{draft, open, true},
+		{draft, paid, false},
+		{draft, void, false},
+		{draft, uncollectible, false},
+
+		{open, paid, true},
+		{open, void, true},
+		{open, uncollectible, true},
+		{open, draft, false},
+
+		{uncollectible, paid, true},
+		{uncollectible, void, true},
+		{uncollectible, open, false},
+
+		{paid, void, false},
+		{paid, open, false},
+		{paid, uncollectible, false},
+		{void, open, false},
+		{void, paid, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			require.Equal(t, tt.allowed, invoiceTransitions.allows(tt.from, tt.to))
+		})
+	}
+}
+
+func TestBillingService_InvoiceTransitions(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		move     func(*BillingService) (*model.Invoice, error)
+		wantFrom []string
+		wantTo   string
+	}{
+		{
+			name: "finalize",
+			move: func(s *BillingService) (*model.Invoice, error) {
+				return s.FinalizeInvoice(context.Background(), Scope{UserID: "user-1"}, 5)
+			},
+			wantFrom: []string{model.InvoiceDraft},
+			wantTo:   model.InvoiceOpen,
+		},
+		{
+			name: "void",
+			move: func(s *BillingService) (*model.Invoice, error) {
+				return s.VoidInvoice(context.Background(), Scope{UserID: "user-1"}, 5)
+			},
+			wantFrom: []string{model.InvoiceOpen, model.InvoiceUncollectible},
+			wantTo:   model.InvoiceVoid,
+		},
+		{
+			name: "mark uncollectible",
+			move: func(s *BillingService) (*model.Invoice, error) {
+				return s.MarkInvoiceUncollectible(context.Background(), Scope{UserID: "user-1"}, 5)
+			},
+			wantFrom: []string{model.InvoiceOpen},
+			wantTo:   model.InvoiceUncollectible,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mocks.NewMockInvoiceStore(ctrl)
+			svc := NewBillingService(store, WithClock(func() time.Time { return now }))
+			store.EXPECT().TransitionInvoice(gomock.Any(), repository.InvoiceScope{UserID: "user-1"}, 5, tt.wantFrom, tt.wantTo, now).
+				Return(&model.Invoice{ID: 5, Status: tt.wantTo}, nil)
+
+			inv, err := tt.move(svc)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantTo, inv.Status)
+		})
+	}
+}
+
+func TestBillingService_VoidInvoice_AdminAnyOwner(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockInvoiceStore(ctrl)
+	svc := NewBillingService(store, WithClock(func() time.Time { return now }))
+	// La factura es de user-1; el admin la busca solo por id.
+	store.EXPECT().TransitionInvoice(gomock.Any(), repository.InvoiceScope{AllOwners: true}, 5,
+		[]string{model.InvoiceOpen, model.InvoiceUncollectible}, model.InvoiceVoid, now).
+		Return(&model.Invoice{ID: 5, UserID: "user-1", Status: model.InvoiceVoid}, nil)
+
+	inv, err := svc.VoidInvoice(context.Background(), Scope{UserID: "admin-1", Role: "admin"}, 5)
+	require.NoError(t, err)
+	require.Equal(t, "user-1", inv.UserID)
+	require.Equal(t, model.InvoiceVoid, inv.Status)
+}
+
+func TestBillingService_UpdateInvoiceLines(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockInvoiceStore(ctrl)
+	svc := NewBillingService(store)
+	scope := repository.InvoiceScope{UserID: "user-1"}
+
+	store.EXPECT().GetInvoiceByID(gomock.Any(), scope, 5).Return(&model.Invoice{ID: 5, Currency: "USD", Status: model.InvoiceDraft}, nil)
+	store.EXPECT().ReplaceInvoiceLines(gomock.Any(), scope, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ repository.InvoiceScope, inv *model.Invoice) (*model.Invoice, error) {
+			require.Equal(t, 5, inv.ID)
+			require.Equal(t, int64(6000), inv.SubtotalCents)
+			require.Equal(t, int64(6000), inv.AmountDueCents)
+			return inv, nil
+		})
+
+	_, err := svc.UpdateInvoiceLines(context.Background(), Scope{UserID: "user-1"}, 5, []model.InvoiceLineItem{
+		{Description: "Seats", UnitAmountCents: 2000, Quantity: 3},
+	})
+	require.NoError(t, err)
+}
+
+func TestBillingService_UpdateInvoiceLines_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		lines   []model.InvoiceLineItem
+		wantErr error
+	}{
+		{name: "finalized", status: model.InvoiceOpen, lines: []model.InvoiceLineItem{{Description: "x", UnitAmountCents: 100}}, wantErr: ErrInvoiceNotDraft},
+		{name: "paid", status: model.InvoicePaid, lines: []model.InvoiceLineItem{{Description: "x", UnitAmountCents: 100}}, wantErr: ErrInvoiceNotDraft},
+		{name: "invalid line", status: model.InvoiceDraft, lines: []model.InvoiceLineItem{{UnitAmountCents: 100}}, wantErr: ErrInvalidLines},
+		{name: "zero total", status: model.InvoiceDraft, lines: []model.InvoiceLineItem{{Description: "x"}}, wantErr: ErrInvalidLines},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mocks.NewMockInvoiceStore(ctrl)
+			svc := NewBillingService(store)
+			store.EXPECT().GetInvoiceByID(gomock.Any(), gomock.Any(), 5).Return(&model.Invoice{ID: 5, Currency: "USD", Status: tt.status}, nil)
+
+			_, err := svc.UpdateInvoiceLines(context.Background(), Scope{UserID: "user-1"}, 5, tt.lines)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestBillingService_RecordInvoicePayment(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	owner := repository.InvoiceScope{UserID: "user-1", OrgID: "org-1"}
+	payment := InvoicePayment{UserID: "user-1", OrgID: "org-1", PaymentIntentID: 9, AmountCents: 1500, Currency: "USD"}
+	tests := []struct {
+		name       string
+		current    *model.Invoice
+		payment    InvoicePayment
+		transition bool
+		wantErr    error
+		wantStatus string
+	}{
+		{name: "open invoice", current: &model.Invoice{ID: 5, Status: model.InvoiceOpen, AmountDueCents: 1500, Currency: "USD"},
+			payment: payment, transition: true, wantStatus: model.InvoicePaid},
+		{name: "already paid", current: &model.Invoice{ID: 5, Status: model.InvoicePaid, AmountDueCents: 1500, Currency: "USD"},
+			payment: payment, wantStatus: model.InvoicePaid},
+		{name: "amount mismatch", current: &model.Invoice{ID: 5, Status: model.InvoiceOpen, AmountDueCents: 2000, Currency: "USD"},
+			payment: payment, wantErr: ErrPaymentMismatch},
+		{name: "missing invoice", payment: payment},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mocks.NewMockInvoiceStore(ctrl)
+			svc := NewBillingService(store, WithClock(func() time.Time { return now }))
+			store.EXPECT().GetInvoiceByID(gomock.Any(), owner, 5).Return(tt.current, nil)
+			if tt.transition {
+				store.EXPECT().TransitionInvoice(gomock.Any(), owner, 5, []string{model.InvoiceOpen, model.InvoiceUncollectible}, model.InvoicePaid, now).
+					Return(&model.Invoice{ID: 5, Status: model.InvoicePaid}, nil)
+			}
+
+			inv, err := svc.RecordInvoicePayment(context.Background(), 5, tt.payment)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantStatus == "" {
+				require.Nil(t, inv)
+				return
+			}
+			require.Equal(t, tt.wantStatus, inv.Status)
+		})
+	}
+}
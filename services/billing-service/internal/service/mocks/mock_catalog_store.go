@@ -0,0 +1,144 @@
+// Code generated manually for tests; gomock-style mock for service.CatalogStore.
+package mocks
+
+import (
+	"context"
+	"reflect"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+
+	"github.com/golang/mock/gomock"
+)
+
+type MockCatalogStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockCatalogStoreMockRecorder
+}
+
+type MockCatalogStoreMockRecorder struct {
+	mock *MockCatalogStore
+}
+
+func NewMockCatalogStore(ctrl *gomock.Controller) *MockCatalogStore {
+	mock := &MockCatalogStore{ctrl: ctrl}
+	mock.recorder = &MockCatalogStoreMockRecorder{mock}
+	return mock
+}
+
+func (m *MockCatalogStore) EXPECT() *MockCatalogStoreMockRecorder { return m.recorder }
+
+func (m *MockCatalogStore) CreateProduct(ctx context.Context, p *model.Product) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateProduct", ctx, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *MockCatalogStoreMockRecorder) CreateProduct(ctx, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProduct", reflect.TypeOf((*MockCatalogStore)(nil).CreateProduct), ctx, p)
+}
+
+func (m *MockCatalogStore) GetProduct(ctx context.Context, id int) (*model.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProduct", ctx, id)
+	ret0, _ := ret[0].(*model.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockCatalogStoreMockRecorder) GetProduct(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProduct", reflect.TypeOf((*MockCatalogStore)(nil).GetProduct), ctx, id)
+}
+
+func (m *MockCatalogStore) ListProducts(ctx context.Context, filter repository.ProductFilter) ([]*model.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListProducts", ctx, filter)
+	ret0, _ := ret[0].([]*model.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockCatalogStoreMockRecorder) ListProducts(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListProducts", reflect.TypeOf((*MockCatalogStore)(nil).ListProducts), ctx, filter)
+}
+
+func (m *MockCatalogStore) UpdateProduct(ctx context.Context, id int, u model.ProductUpdate) (*model.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProduct", ctx, id, u)
+	ret0, _ := ret[0].(*model.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockCatalogStoreMockRecorder) UpdateProduct(ctx, id, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProduct", reflect.TypeOf((*MockCatalogStore)(nil).UpdateProduct), ctx, id, u)
+}
+
+func (m *MockCatalogStore) DeleteProduct(ctx context.Context, id int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProduct", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockCatalogStoreMockRecorder) DeleteProduct(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProduct", reflect.TypeOf((*MockCatalogStore)(nil).DeleteProduct), ctx, id)
+}
+
+func (m *MockCatalogStore) CreatePrice(ctx context.Context, p *model.Price) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePrice", ctx, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *MockCatalogStoreMockRecorder) CreatePrice(ctx, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePrice", reflect.TypeOf((*MockCatalogStore)(nil).CreatePrice), ctx, p)
+}
+
+func (m *MockCatalogStore) GetPrice(ctx context.Context, id int) (*model.Price, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPrice", ctx, id)
+	ret0, _ := ret[0].(*model.Price)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockCatalogStoreMockRecorder) GetPrice(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrice", reflect.TypeOf((*MockCatalogStore)(nil).GetPrice), ctx, id)
+}
+
+func (m *MockCatalogStore) ListPrices(ctx context.Context, filter repository.PriceFilter) ([]*model.Price, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPrices", ctx, filter)
+	ret0, _ := ret[0].([]*model.Price)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockCatalogStoreMockRecorder) ListPrices(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPrices", reflect.TypeOf((*MockCatalogStore)(nil).ListPrices), ctx, filter)
+}
+
+func (m *MockCatalogStore) UpdatePrice(ctx context.Context, id int, u model.PriceUpdate) (*model.Price, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePrice", ctx, id, u)
+	ret0, _ := ret[0].(*model.Price)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockCatalogStoreMockRecorder) UpdatePrice(ctx, id, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePrice", reflect.TypeOf((*MockCatalogStore)(nil).UpdatePrice), ctx, id, u)
+}
@@ -0,0 +1,223 @@
+// Code generated manually for tests; gomock-style mock for service.DiscountStore.
+package mocks
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+
+	"github.com/golang/mock/gomock"
+)
+
+type MockDiscountStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockDiscountStoreMockRecorder
+}
+
+type MockDiscountStoreMockRecorder struct {
+	mock *MockDiscountStore
+}
+
+func NewMockDiscountStore(ctrl *gomock.Controller) *MockDiscountStore {
+	mock := &MockDiscountStore{ctrl: ctrl}
+	mock.recorder = &MockDiscountStoreMockRecorder{mock}
+	return mock
+}
+
+func (m *MockDiscountStore) EXPECT() *MockDiscountStoreMockRecorder { return m.recorder }
+
+func (m *MockDiscountStore) CreateCoupon(ctx context.Context, c *model.Coupon) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCoupon", ctx, c)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *MockDiscountStoreMockRecorder) CreateCoupon(ctx, c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCoupon", reflect.TypeOf((*MockDiscountStore)(nil).CreateCoupon), ctx, c)
+}
+
+func (m *MockDiscountStore) GetCoupon(ctx context.Context, id int) (*model.Coupon, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCoupon", ctx, id)
+	ret0, _ := ret[0].(*model.Coupon)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockDiscountStoreMockRecorder) GetCoupon(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCoupon", reflect.TypeOf((*MockDiscountStore)(nil).GetCoupon), ctx, id)
+}
+
+func (m *MockDiscountStore) ListCoupons(ctx context.Context, filter repository.CouponFilter) ([]*model.Coupon, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCoupons", ctx, filter)
+	ret0, _ := ret[0].([]*model.Coupon)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockDiscountStoreMockRecorder) ListCoupons(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCoupons", reflect.TypeOf((*MockDiscountStore)(nil).ListCoupons), ctx, filter)
+}
+
+func (m *MockDiscountStore) UpdateCoupon(ctx context.Context, id int, u model.CouponUpdate) (*model.Coupon, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCoupon", ctx, id, u)
+	ret0, _ := ret[0].(*model.Coupon)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockDiscountStoreMockRecorder) UpdateCoupon(ctx, id, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCoupon", reflect.TypeOf((*MockDiscountStore)(nil).UpdateCoupon), ctx, id, u)
+}
+
+func (m *MockDiscountStore) CreatePromotionCode(ctx context.Context, p *model.PromotionCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePromotionCode", ctx, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *MockDiscountStoreMockRecorder) CreatePromotionCode(ctx, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePromotionCode", reflect.TypeOf((*MockDiscountStore)(nil).CreatePromotionCode), ctx, p)
+}
+
+func (m *MockDiscountStore) GetPromotionCode(ctx context.Context, id int) (*model.PromotionCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPromotionCode", ctx, id)
+	ret0, _ := ret[0].(*model.PromotionCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockDiscountStoreMockRecorder) GetPromotionCode(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPromotionCode", reflect.TypeOf((*MockDiscountStore)(nil).GetPromotionCode), ctx, id)
+}
+
+func (m *MockDiscountStore) FindPromotionCode(ctx context.Context, code string) (*model.PromotionCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPromotionCode", ctx, code)
+	ret0, _ := ret[0].(*model.PromotionCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockDiscountStoreMockRecorder) FindPromotionCode(ctx, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPromotionCode", reflect.TypeOf((*MockDiscountStore)(nil).FindPromotionCode), ctx, code)
+}
+
+func (m *MockDiscountStore) ListPromotionCodes(ctx context.Context, filter repository.PromotionCodeFilter) ([]*model.PromotionCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPromotionCodes", ctx, filter)
+	ret0, _ := ret[0].([]*model.PromotionCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockDiscountStoreMockRecorder) ListPromotionCodes(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPromotionCodes", reflect.TypeOf((*MockDiscountStore)(nil).ListPromotionCodes), ctx, filter)
+}
+
+func (m *MockDiscountStore) UpdatePromotionCode(ctx context.Context, id int, active *bool) (*model.PromotionCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePromotionCode", ctx, id, active)
+	ret0, _ := ret[0].(*model.PromotionCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockDiscountStoreMockRecorder) UpdatePromotionCode(ctx, id, active interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePromotionCode", reflect.TypeOf((*MockDiscountStore)(nil).UpdatePromotionCode), ctx, id, active)
+}
+
+func (m *MockDiscountStore) HasInvoiced(ctx context.Context, scope repository.InvoiceScope) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasInvoiced", ctx, scope)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockDiscountStoreMockRecorder) HasInvoiced(ctx, scope interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasInvoiced", reflect.TypeOf((*MockDiscountStore)(nil).HasInvoiced), ctx, scope)
+}
+
+func (m *MockDiscountStore) GetDiscount(ctx context.Context, id int) (*model.Discount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDiscount", ctx, id)
+	ret0, _ := ret[0].(*model.Discount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockDiscountStoreMockRecorder) GetDiscount(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDiscount", reflect.TypeOf((*MockDiscountStore)(nil).GetDiscount), ctx, id)
+}
+
+func (m *MockDiscountStore) SubscriptionDiscount(ctx context.Context, subscriptionID int, at time.Time) (*model.Discount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscriptionDiscount", ctx, subscriptionID, at)
+	ret0, _ := ret[0].(*model.Discount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockDiscountStoreMockRecorder) SubscriptionDiscount(ctx, subscriptionID, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscriptionDiscount", reflect.TypeOf((*MockDiscountStore)(nil).SubscriptionDiscount), ctx, subscriptionID, at)
+}
+
+func (m *MockDiscountStore) ApplySubscriptionDiscount(ctx context.Context, scope repository.InvoiceScope, d *model.Discount) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplySubscriptionDiscount", ctx, scope, d)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockDiscountStoreMockRecorder) ApplySubscriptionDiscount(ctx, scope, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplySubscriptionDiscount", reflect.TypeOf((*MockDiscountStore)(nil).ApplySubscriptionDiscount), ctx, scope, d)
+}
+
+func (m *MockDiscountStore) EndSubscriptionDiscount(ctx context.Context, subscriptionID int, at time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EndSubscriptionDiscount", ctx, subscriptionID, at)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockDiscountStoreMockRecorder) EndSubscriptionDiscount(ctx, subscriptionID, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EndSubscriptionDiscount", reflect.TypeOf((*MockDiscountStore)(nil).EndSubscriptionDiscount), ctx, subscriptionID, at)
+}
+
+func (m *MockDiscountStore) ApplyInvoiceDiscount(ctx context.Context, scope repository.InvoiceScope, invoice *model.Invoice, d *model.Discount) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyInvoiceDiscount", ctx, scope, invoice, d)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockDiscountStoreMockRecorder) ApplyInvoiceDiscount(ctx, scope, invoice, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyInvoiceDiscount", reflect.TypeOf((*MockDiscountStore)(nil).ApplyInvoiceDiscount), ctx, scope, invoice, d)
+}
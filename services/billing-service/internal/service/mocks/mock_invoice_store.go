@@ -2,7 +2,9 @@
 package mocks
 
 import (
+	"context"
 	"reflect"
+	"time"
 
 	"saas-subscription-platform/services/billing-service/internal/model"
 	"saas-subscription-platform/services/billing-service/internal/repository"
@@ -27,40 +29,105 @@ func NewMockInvoiceStore(ctrl *gomock.Controller) *MockInvoiceStore {
 
 func (m *MockInvoiceStore) EXPECT() *MockInvoiceStoreMockRecorder { return m.recorder }
 
-func (m *MockInvoiceStore) CreateInvoice(invoice *model.Invoice) error {
+func (m *MockInvoiceStore) CreateInvoice(ctx context.Context, invoice *model.Invoice) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateInvoice", invoice)
+	ret := m.ctrl.Call(m, "CreateInvoice", ctx, invoice)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-func (mr *MockInvoiceStoreMockRecorder) CreateInvoice(invoice interface{}) *gomock.Call {
+func (mr *MockInvoiceStoreMockRecorder) CreateInvoice(ctx, invoice interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInvoice", reflect.TypeOf((*MockInvoiceStore)(nil).CreateInvoice), invoice)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInvoice", reflect.TypeOf((*MockInvoiceStore)(nil).CreateInvoice), ctx, invoice)
 }
 
-func (m *MockInvoiceStore) GetInvoiceByID(userID string, id int) (*model.Invoice, error) {
+func (m *MockInvoiceStore) GetInvoiceByID(ctx context.Context, scope repository.InvoiceScope, id int) (*model.Invoice, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetInvoiceByID", userID, id)
+	ret := m.ctrl.Call(m, "GetInvoiceByID", ctx, scope, id)
 	ret0, _ := ret[0].(*model.Invoice)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-func (mr *MockInvoiceStoreMockRecorder) GetInvoiceByID(userID, id interface{}) *gomock.Call {
+func (mr *MockInvoiceStoreMockRecorder) GetInvoiceByID(ctx, scope, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvoiceByID", reflect.TypeOf((*MockInvoiceStore)(nil).GetInvoiceByID), userID, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvoiceByID", reflect.TypeOf((*MockInvoiceStore)(nil).GetInvoiceByID), ctx, scope, id)
 }
 
-func (m *MockInvoiceStore) GetInvoices(filter repository.InvoiceFilter) ([]*model.Invoice, error) {
+func (m *MockInvoiceStore) GetInvoices(ctx context.Context, filter repository.InvoiceFilter) ([]*model.Invoice, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetInvoices", filter)
+	ret := m.ctrl.Call(m, "GetInvoices", ctx, filter)
 	ret0, _ := ret[0].([]*model.Invoice)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-func (mr *MockInvoiceStoreMockRecorder) GetInvoices(filter interface{}) *gomock.Call {
+func (mr *MockInvoiceStoreMockRecorder) GetInvoices(ctx, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvoices", reflect.TypeOf((*MockInvoiceStore)(nil).GetInvoices), filter)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvoices", reflect.TypeOf((*MockInvoiceStore)(nil).GetInvoices), ctx, filter)
+}
+
+func (m *MockInvoiceStore) ExportInvoices(ctx context.Context, userID string) ([]*model.Invoice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportInvoices", ctx, userID)
+	ret0, _ := ret[0].([]*model.Invoice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockInvoiceStoreMockRecorder) ExportInvoices(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportInvoices", reflect.TypeOf((*MockInvoiceStore)(nil).ExportInvoices), ctx, userID)
+}
+
+func (m *MockInvoiceStore) UpdateInvoiceMetadata(ctx context.Context, scope repository.InvoiceScope, id int, patch map[string]string) (*model.Invoice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateInvoiceMetadata", ctx, scope, id, patch)
+	ret0, _ := ret[0].(*model.Invoice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockInvoiceStoreMockRecorder) UpdateInvoiceMetadata(ctx, scope, id, patch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateInvoiceMetadata", reflect.TypeOf((*MockInvoiceStore)(nil).UpdateInvoiceMetadata), ctx, scope, id, patch)
+}
+
+func (m *MockInvoiceStore) PseudonymizeUser(ctx context.Context, userID, pseudonym string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PseudonymizeUser", ctx, userID, pseudonym)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockInvoiceStoreMockRecorder) PseudonymizeUser(ctx, userID, pseudonym interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PseudonymizeUser", reflect.TypeOf((*MockInvoiceStore)(nil).PseudonymizeUser), ctx, userID, pseudonym)
+}
+
+func (m *MockInvoiceStore) TransitionInvoice(ctx context.Context, scope repository.InvoiceScope, id int, from []string, status string, at time.Time) (*model.Invoice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransitionInvoice", ctx, scope, id, from, status, at)
+	ret0, _ := ret[0].(*model.Invoice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockInvoiceStoreMockRecorder) TransitionInvoice(ctx, scope, id, from, status, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransitionInvoice", reflect.TypeOf((*MockInvoiceStore)(nil).TransitionInvoice), ctx, scope, id, from, status, at)
+}
+
+func (m *MockInvoiceStore) ReplaceInvoiceLines(ctx context.Context, scope repository.InvoiceScope, invoice *model.Invoice) (*model.Invoice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceInvoiceLines", ctx, scope, invoice)
+	ret0, _ := ret[0].(*model.Invoice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockInvoiceStoreMockRecorder) ReplaceInvoiceLines(ctx, scope, invoice interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceInvoiceLines", reflect.TypeOf((*MockInvoiceStore)(nil).ReplaceInvoiceLines), ctx, scope, invoice)
 }
@@ -0,0 +1,146 @@
+// Code generated manually for tests; gomock-style mock for service.SubscriptionStore.
+package mocks
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+
+	"github.com/golang/mock/gomock"
+)
+
+type MockSubscriptionStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockSubscriptionStoreMockRecorder
+}
+
+type MockSubscriptionStoreMockRecorder struct {
+	mock *MockSubscriptionStore
+}
+
+func NewMockSubscriptionStore(ctrl *gomock.Controller) *MockSubscriptionStore {
+	mock := &MockSubscriptionStore{ctrl: ctrl}
+	mock.recorder = &MockSubscriptionStoreMockRecorder{mock}
+	return mock
+}
+
+func (m *MockSubscriptionStore) EXPECT() *MockSubscriptionStoreMockRecorder { return m.recorder }
+
+func (m *MockSubscriptionStore) CreateSubscription(ctx context.Context, s *model.Subscription) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSubscription", ctx, s)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *MockSubscriptionStoreMockRecorder) CreateSubscription(ctx, s interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSubscription", reflect.TypeOf((*MockSubscriptionStore)(nil).CreateSubscription), ctx, s)
+}
+
+func (m *MockSubscriptionStore) GetSubscription(ctx context.Context, scope repository.InvoiceScope, id int) (*model.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubscription", ctx, scope, id)
+	ret0, _ := ret[0].(*model.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockSubscriptionStoreMockRecorder) GetSubscription(ctx, scope, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscription", reflect.TypeOf((*MockSubscriptionStore)(nil).GetSubscription), ctx, scope, id)
+}
+
+func (m *MockSubscriptionStore) ListSubscriptions(ctx context.Context, filter repository.SubscriptionFilter) ([]*model.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSubscriptions", ctx, filter)
+	ret0, _ := ret[0].([]*model.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockSubscriptionStoreMockRecorder) ListSubscriptions(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSubscriptions", reflect.TypeOf((*MockSubscriptionStore)(nil).ListSubscriptions), ctx, filter)
+}
+
+func (m *MockSubscriptionStore) TransitionSubscription(ctx context.Context, scope repository.InvoiceScope, id int, from []string, u model.SubscriptionUpdate) (*model.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransitionSubscription", ctx, scope, id, from, u)
+	ret0, _ := ret[0].(*model.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockSubscriptionStoreMockRecorder) TransitionSubscription(ctx, scope, id, from, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransitionSubscription", reflect.TypeOf((*MockSubscriptionStore)(nil).TransitionSubscription), ctx, scope, id, from, u)
+}
+
+func (m *MockSubscriptionStore) UnbilledSubscriptions(ctx context.Context, limit int) ([]*model.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnbilledSubscriptions", ctx, limit)
+	ret0, _ := ret[0].([]*model.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockSubscriptionStoreMockRecorder) UnbilledSubscriptions(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnbilledSubscriptions", reflect.TypeOf((*MockSubscriptionStore)(nil).UnbilledSubscriptions), ctx, limit)
+}
+
+func (m *MockSubscriptionStore) DueSubscriptions(ctx context.Context, now time.Time, limit int) ([]*model.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DueSubscriptions", ctx, now, limit)
+	ret0, _ := ret[0].([]*model.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockSubscriptionStoreMockRecorder) DueSubscriptions(ctx, now, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DueSubscriptions", reflect.TypeOf((*MockSubscriptionStore)(nil).DueSubscriptions), ctx, now, limit)
+}
+
+func (m *MockSubscriptionStore) AdvanceSubscription(ctx context.Context, id int, periodEnd time.Time, from []string, u model.SubscriptionUpdate) (*model.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdvanceSubscription", ctx, id, periodEnd, from, u)
+	ret0, _ := ret[0].(*model.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockSubscriptionStoreMockRecorder) AdvanceSubscription(ctx, id, periodEnd, from, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdvanceSubscription", reflect.TypeOf((*MockSubscriptionStore)(nil).AdvanceSubscription), ctx, id, periodEnd, from, u)
+}
+
+func (m *MockSubscriptionStore) ChangeSubscription(ctx context.Context, scope repository.InvoiceScope, id int, change model.SubscriptionChange) (*model.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeSubscription", ctx, scope, id, change)
+	ret0, _ := ret[0].(*model.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockSubscriptionStoreMockRecorder) ChangeSubscription(ctx, scope, id, change interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeSubscription", reflect.TypeOf((*MockSubscriptionStore)(nil).ChangeSubscription), ctx, scope, id, change)
+}
+
+func (m *MockSubscriptionStore) PendingInvoiceItems(ctx context.Context, subscriptionID int) ([]model.InvoiceLineItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PendingInvoiceItems", ctx, subscriptionID)
+	ret0, _ := ret[0].([]model.InvoiceLineItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockSubscriptionStoreMockRecorder) PendingInvoiceItems(ctx, subscriptionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingInvoiceItems", reflect.TypeOf((*MockSubscriptionStore)(nil).PendingInvoiceItems), ctx, subscriptionID)
+}
@@ -0,0 +1,95 @@
+// Code generated manually for tests; gomock-style mock for service.UsageStore.
+package mocks
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+
+	"github.com/golang/mock/gomock"
+)
+
+type MockUsageStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockUsageStoreMockRecorder
+}
+
+type MockUsageStoreMockRecorder struct {
+	mock *MockUsageStore
+}
+
+func NewMockUsageStore(ctrl *gomock.Controller) *MockUsageStore {
+	mock := &MockUsageStore{ctrl: ctrl}
+	mock.recorder = &MockUsageStoreMockRecorder{mock}
+	return mock
+}
+
+func (m *MockUsageStore) EXPECT() *MockUsageStoreMockRecorder { return m.recorder }
+
+func (m *MockUsageStore) CreateSubscriptionItem(ctx context.Context, scope repository.InvoiceScope, item *model.SubscriptionItem) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSubscriptionItem", ctx, scope, item)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockUsageStoreMockRecorder) CreateSubscriptionItem(ctx, scope, item interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSubscriptionItem", reflect.TypeOf((*MockUsageStore)(nil).CreateSubscriptionItem), ctx, scope, item)
+}
+
+func (m *MockUsageStore) ListSubscriptionItems(ctx context.Context, subscriptionID int) ([]*model.SubscriptionItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSubscriptionItems", ctx, subscriptionID)
+	ret0, _ := ret[0].([]*model.SubscriptionItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockUsageStoreMockRecorder) ListSubscriptionItems(ctx, subscriptionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSubscriptionItems", reflect.TypeOf((*MockUsageStore)(nil).ListSubscriptionItems), ctx, subscriptionID)
+}
+
+func (m *MockUsageStore) RecordUsage(ctx context.Context, scope repository.InvoiceScope, records []model.UsageRecord) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordUsage", ctx, scope, records)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockUsageStoreMockRecorder) RecordUsage(ctx, scope, records interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordUsage", reflect.TypeOf((*MockUsageStore)(nil).RecordUsage), ctx, scope, records)
+}
+
+func (m *MockUsageStore) CloseUsage(ctx context.Context, subscriptionID int, at time.Time) ([]*model.SubscriptionItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseUsage", ctx, subscriptionID, at)
+	ret0, _ := ret[0].([]*model.SubscriptionItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockUsageStoreMockRecorder) CloseUsage(ctx, subscriptionID, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseUsage", reflect.TypeOf((*MockUsageStore)(nil).CloseUsage), ctx, subscriptionID, at)
+}
+
+func (m *MockUsageStore) UsageRollups(ctx context.Context, subscriptionID int, before time.Time) ([]model.UsageRollup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsageRollups", ctx, subscriptionID, before)
+	ret0, _ := ret[0].([]model.UsageRollup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockUsageStoreMockRecorder) UsageRollups(ctx, subscriptionID, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsageRollups", reflect.TypeOf((*MockUsageStore)(nil).UsageRollups), ctx, subscriptionID, before)
+}
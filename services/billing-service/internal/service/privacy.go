@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+)
+
+// ErrInvalidPseudonym: el seudónimo tiene que ser un UUID porque ocupa la columna user_id.
+var ErrInvalidPseudonym = errors.New("pseudonym must be a uuid")
+
+// ExportUserInvoices devuelve todas las facturas del usuario para el export GDPR.
+func (s *BillingService) ExportUserInvoices(ctx context.Context, userID string) ([]*model.Invoice, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user id is required")
+	}
+	invoices, err := s.repo.ExportInvoices(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if invoices == nil {
+		invoices = []*model.Invoice{}
+	}
+	return invoices, nil
+}
+
+// PseudonymizeUser desvincula las facturas del usuario borrado. Es idempotente:
+// un reintento ya no encuentra facturas con el user_id original.
+func (s *BillingService) PseudonymizeUser(ctx context.Context, userID, pseudonym string) (int64, error) {
+	if userID == "" {
+		return 0, fmt.Errorf("user id is required")
+	}
+	if _, err := uuid.Parse(pseudonym); err != nil || pseudonym == userID {
+		return 0, ErrInvalidPseudonym
+	}
+	return s.repo.PseudonymizeUser(ctx, userID, pseudonym)
+}
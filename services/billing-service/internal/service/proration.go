@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+)
+
+// Comportamientos de prorrateo al cambiar precio o cantidad.
+const (
+	// ProrationCreate deja los prorrateos para la próxima factura del período.
+	ProrationCreate = "create_prorations"
+	// ProrationAlwaysInvoice factura los prorrateos en el momento.
+	ProrationAlwaysInvoice = "always_invoice"
+	// ProrationNone cambia sin prorratear: el precio nuevo rige desde el próximo período.
+	ProrationNone = "none"
+)
+
+// prorationDateTolerance es cuánto se puede alejar de ahora el proration_date
+// que manda el cliente: alcanza para confirmar una vista previa reciente, pero
+// no para fechar el cambio hacia atrás y cobrar crédito por tiempo ya usado.
+const prorationDateTolerance = 10 * time.Minute
+
+var (
+	// ErrInvalidChange: el cambio pedido no es válido para la suscripción.
+	ErrInvalidChange = errors.New("invalid subscription change")
+	// ErrSubscriptionChanged: la suscripción cambió mientras se prorrateaba; se puede reintentar.
+	ErrSubscriptionChanged = repository.ErrSubscriptionChanged
+)
+
+func invalidChange(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidChange, fmt.Sprintf(format, args...))
+}
+
+// SubscriptionChangeRequest es un cambio de precio y/o cantidad. Los ceros
+// mantienen el valor actual.
+type SubscriptionChangeRequest struct {
+	PriceID           int
+	Quantity          int
+	ProrationBehavior string
+	// ProrationDate fija el instante del prorrateo (debe caer en el período en
+	// curso y a no más de prorationDateTolerance de ahora); sirve para confirmar
+	// exactamente lo que mostró una vista previa.
+	ProrationDate *time.Time
+}
+
+// Proration es el resultado de prorratear un cambio.
+type Proration struct {
+	ProrationDate time.Time               `json:"proration_date"`
+	Currency      string                  `json:"currency"`
+	Lines         []model.InvoiceLineItem `json:"lines"`
+	// AmountCents es la suma de Lines: negativo si el cambio deja crédito.
+	AmountCents int64 `json:"amount_cents"`
+	// InvoiceID es la factura creada con always_invoice.
+	InvoiceID *int `json:"invoice_id,omitempty"`
+}
+
+// SubscriptionChangeResult es la suscripción ya cambiada y su prorrateo.
+type SubscriptionChangeResult struct {
+	Subscription *model.Subscription `json:"subscription"`
+	Proration    *Proration          `json:"proration"`
+}
+
+// prorate devuelve amount * remaining / total redondeado al centavo más
+// cercano, con las mitades hacia arriba. Se calcula con enteros de precisión
+// arbitraria para no perder centavos ni desbordar.
+func prorate(amount *big.Int, remaining, total int64) (int64, error) {
+	num := new(big.Int).Mul(amount, big.NewInt(remaining))
+	num.Mul(num, big.NewInt(2))
+	num.Add(num, big.NewInt(total))
+	den := big.NewInt(2 * total)
+	q := num.Quo(num, den)
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("amount is too large")
+	}
+	return q.Int64(), nil
+}
+
+// prorationLine es el renglón de prorrateo de quantity unidades de price por
+// el tiempo que va de from a end dentro del período [start, end). El crédito
+// (credit) es el mismo cálculo con signo negativo, así un cambio y su vuelta
+// atrás se cancelan al centavo.
+func prorationLine(desc string, price *model.Price, quantity int, from, start, end time.Time, credit bool) (model.InvoiceLineItem, error) {
+	full, err := price.Amount(int64(quantity))
+	if err != nil {
+		return model.InvoiceLineItem{}, err
+	}
+	amount, err := prorate(big.NewInt(full), end.Unix()-from.Unix(), end.Unix()-start.Unix())
+	if err != nil {
+		return model.InvoiceLineItem{}, err
+	}
+	if credit {
+		amount = -amount
+	}
+	priceID := price.ID
+	return model.InvoiceLineItem{
+		Description:     desc,
+		PriceID:         &priceID,
+		Quantity:        quantity,
+		UnitAmountCents: price.UnitAmountCents,
+		AmountCents:     amount,
+		PeriodStart:     &from,
+		PeriodEnd:       &end,
+		Proration:       true,
+	}, nil
+}
+
+// prorationPlan es un cambio ya validado y prorrateado.
+type prorationPlan struct {
+	owner     repository.InvoiceScope
+	sub       *model.Subscription
+	price     *model.Price
+	quantity  int
+	behavior  string
+	proration *Proration
+}
+
+// planChange valida req contra la suscripción y calcula el prorrateo: un
+// crédito por el tiempo que queda del período con el precio y la cantidad
+// actuales y un cargo por ese mismo tiempo con los nuevos. El tiempo se mide en
+// segundos. En prueba no se prorratea (no hay nada cobrado). Devuelve nil si la
+// suscripción no existe en el scope.
+func (s *BillingService) planChange(ctx context.Context, scope Scope, id int, req SubscriptionChangeRequest) (*prorationPlan, error) {
+	owner, err := scope.invoiceScope()
+	if err != nil {
+		return nil, err
+	}
+	subs, err := s.subscriptionStore()
+	if err != nil {
+		return nil, err
+	}
+	if s.prices == nil {
+		return nil, fmt.Errorf("price catalog is not configured")
+	}
+	behavior := req.ProrationBehavior
+	switch behavior {
+	case "":
+		behavior = ProrationCreate
+	case ProrationCreate, ProrationAlwaysInvoice, ProrationNone:
+	default:
+		return nil, invalidChange("proration_behavior must be %s, %s or %s", ProrationCreate, ProrationAlwaysInvoice, ProrationNone)
+	}
+	if req.Quantity < 0 {
+		return nil, invalidChange("quantity must be positive")
+	}
+
+	sub, err := subs.GetSubscription(ctx, owner, id)
+	if err != nil || sub == nil {
+		return nil, err
+	}
+	if !contains(renewingStatuses, sub.Status) {
+		return nil, ErrInvalidTransition
+	}
+
+	plan := &prorationPlan{owner: owner, sub: sub, quantity: sub.Quantity, behavior: behavior}
+	if req.Quantity > 0 {
+		plan.quantity = req.Quantity
+	}
+	current, err := s.prices.GetPrice(ctx, sub.PriceID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrPriceUnavailable
+	}
+	plan.price = current
+	if req.PriceID != 0 && req.PriceID != sub.PriceID {
+		price, err := s.prices.GetPrice(ctx, req.PriceID)
+		if err != nil {
+			return nil, err
+		}
+		if price == nil || !price.Active {
+			return nil, ErrPriceUnavailable
+		}
+		if price.Metered() {
+			return nil, invalidChange("%s", errMeteredPrice)
+		}
+		// Con otra moneda u otro intervalo el período en curso no se puede
+		// prorratear contra el nuevo.
+		if price.Currency != current.Currency || price.Interval != current.Interval || price.IntervalCount != current.IntervalCount {
+			return nil, invalidChange("new price must have the same currency and billing interval")
+		}
+		plan.price = price
+	}
+	if plan.price.ID == sub.PriceID && plan.quantity == sub.Quantity {
+		return nil, invalidChange("price_id or quantity must change")
+	}
+
+	start, end := sub.CurrentPeriodStart, sub.CurrentPeriodEnd
+	now := s.now().Truncate(time.Second)
+	at := now
+	if req.ProrationDate != nil {
+		at = req.ProrationDate.Truncate(time.Second)
+		if at.Before(start) || at.After(end) {
+			return nil, invalidChange("proration_date must be within the current period")
+		}
+		if d := at.Sub(now); d < -prorationDateTolerance || d > prorationDateTolerance {
+			return nil, invalidChange("proration_date must be within %s of now", prorationDateTolerance)
+		}
+	}
+	if at.Before(start) {
+		at = start
+	}
+	if at.After(end) {
+		at = end
+	}
+
+	p := &Proration{ProrationDate: at, Currency: current.Currency, Lines: []model.InvoiceLineItem{}}
+	plan.proration = p
+	if behavior == ProrationNone || sub.Status == model.SubscriptionTrialing || !end.After(at) || end.Unix() <= start.Unix() {
+		return plan, nil
+	}
+
+	credit, err := prorationLine(fmt.Sprintf("Unused time on subscription #%d (%d x price #%d)", sub.ID, sub.Quantity, current.ID),
+		current, sub.Quantity, at, start, end, true)
+	if err != nil {
+		return nil, err
+	}
+	debit, err := prorationLine(fmt.Sprintf("Remaining time on subscription #%d (%d x price #%d)", sub.ID, plan.quantity, plan.price.ID),
+		plan.price, plan.quantity, at, start, end, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range []model.InvoiceLineItem{credit, debit} {
+		// Un renglón de cero (p. ej. cambio en el último segundo) no aporta nada.
+		if line.AmountCents == 0 {
+			continue
+		}
+		p.Lines = append(p.Lines, line)
+		p.AmountCents += line.AmountCents
+	}
+	return plan, nil
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// PreviewSubscriptionChange calcula el prorrateo de un cambio sin aplicarlo.
+// Devuelve nil si la suscripción no existe en el scope.
+func (s *BillingService) PreviewSubscriptionChange(ctx context.Context, scope Scope, id int, req SubscriptionChangeRequest) (*Proration, error) {
+	plan, err := s.planChange(ctx, scope, id, req)
+	if err != nil || plan == nil {
+		return nil, err
+	}
+	return plan.proration, nil
+}
+
+// ChangeSubscription cambia precio y/o cantidad y registra el prorrateo según
+// el comportamiento pedido. Con always_invoice los renglones se facturan en el
+// momento, salvo que el total sea un crédito: entonces quedan pendientes y se
+// descuentan de la próxima factura, igual que con create_prorations. Si el
+// crédito supera esa factura, la deja en cero y el sobrante se pierde: no hay
+// saldo a favor que pase al período siguiente.
+// Devuelve ErrSubscriptionChanged si la suscripción cambió mientras tanto.
+func (s *BillingService) ChangeSubscription(ctx context.Context, scope Scope, id int, req SubscriptionChangeRequest) (*SubscriptionChangeResult, error) {
+	plan, err := s.planChange(ctx, scope, id, req)
+	if err != nil || plan == nil {
+		return nil, err
+	}
+	sub, p := plan.sub, plan.proration
+
+	change := model.SubscriptionChange{
+		PriceID:           plan.price.ID,
+		Quantity:          plan.quantity,
+		ExpectedPriceID:   sub.PriceID,
+		ExpectedQuantity:  sub.Quantity,
+		ExpectedPeriodEnd: sub.CurrentPeriodEnd,
+	}
+	if plan.behavior == ProrationAlwaysInvoice && p.AmountCents > 0 {
+		now := s.now()
+		change.Invoice = &model.Invoice{
+			UserID:         sub.UserID,
+			OrgID:          sub.OrgID,
+			Currency:       p.Currency,
+			SubscriptionID: &sub.ID,
+			Metadata:       map[string]string{},
+			Lines:          p.Lines,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		if err := change.Invoice.ComputeTotals(); err != nil {
+			return nil, err
+		}
+		issued(change.Invoice)
+	} else if plan.behavior != ProrationNone {
+		change.PendingItems = p.Lines
+	}
+
+	changed, err := s.subs.ChangeSubscription(ctx, plan.owner, id, change)
+	if err != nil || changed == nil {
+		return nil, err
+	}
+	if change.Invoice != nil && change.Invoice.ID != 0 {
+		invoiceID := change.Invoice.ID
+		p.InvoiceID = &invoiceID
+	}
+	return &SubscriptionChangeResult{Subscription: changed, Proration: p}, nil
+}
+
+// pendingLines agrega a lines los prorrateos pendientes de sub.
+func (s *BillingService) pendingLines(ctx context.Context, sub *model.Subscription, lines []model.InvoiceLineItem) ([]model.InvoiceLineItem, error) {
+	pending, err := s.subs.PendingInvoiceItems(ctx, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+	return append(lines, pending...), nil
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// Reglas de redondeo: cada renglón se redondea por separado al centavo más
+// cercano y las mitades suben; el crédito es el mismo monto con signo negativo.
+func TestProrate_Rounding(t *testing.T) {
+	tests := []struct {
+		name      string
+		amount    int64
+		remaining int64
+		total     int64
+		want      int64
+	}{
+		{name: "exact half period", amount: 3000, remaining: 15, total: 30, want: 1500},
+		{name: "full period", amount: 999, remaining: 100, total: 100, want: 999},
+		{name: "nothing left", amount: 999, remaining: 0, total: 100, want: 0},
+		{name: "half cent rounds up", amount: 1, remaining: 1, total: 2, want: 1},
+		{name: "below half rounds down", amount: 1, remaining: 1, total: 3, want: 0},
+		{name: "above half rounds up", amount: 1, remaining: 2, total: 3, want: 1},
+		{name: "2.5 rounds to 3", amount: 5, remaining: 1, total: 2, want: 3},
+		{name: "one second of a month", amount: 2592000, remaining: 1, total: 2592000, want: 1},
+		{name: "no overflow on large amounts", amount: math.MaxInt64, remaining: 86399, total: 86400, want: 9223265284863608506},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := prorate(big.NewInt(tt.amount), tt.remaining, tt.total)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	tooLarge := new(big.Int).Mul(big.NewInt(math.MaxInt64), big.NewInt(2))
+	_, err := prorate(tooLarge, 1, 1)
+	require.Error(t, err)
+}
+
+// El reloj de newSubscriptionService marca 2024-01-31 12:00 UTC: quedan
+// 2462400 de los 2505600 segundos del período.
+func prorationFixture() (*model.Subscription, *model.Price, *model.Price) {
+	sub := &model.Subscription{ID: 3, UserID: "user-1", PriceID: 7, Quantity: 1, Status: model.SubscriptionActive,
+		CurrentPeriodStart: time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC),
+		CurrentPeriodEnd:   time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)}
+	basic := &model.Price{ID: 7, Currency: "USD", UnitAmountCents: 1500, Interval: model.IntervalMonth, IntervalCount: 1, Active: true}
+	pro := &model.Price{ID: 8, Currency: "USD", UnitAmountCents: 3000, Interval: model.IntervalMonth, IntervalCount: 1, Active: true}
+	return sub, basic, pro
+}
+
+func TestBillingService_PreviewSubscriptionChange_Upgrade(t *testing.T) {
+	svc, subs, prices := newSubscriptionService(t)
+	sub, basic, pro := prorationFixture()
+	subs.EXPECT().GetSubscription(gomock.Any(), gomock.Any(), 3).Return(sub, nil)
+	prices.EXPECT().GetPrice(gomock.Any(), 7).Return(basic, nil)
+	prices.EXPECT().GetPrice(gomock.Any(), 8).Return(pro, nil)
+
+	p, err := svc.PreviewSubscriptionChange(context.Background(), Scope{UserID: "user-1"}, 3, SubscriptionChangeRequest{PriceID: 8})
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC), p.ProrationDate)
+	require.Len(t, p.Lines, 2)
+	// 1500 * 2462400 / 2505600 = 1474.14 y 3000 * ... = 2948.28
+	require.Equal(t, int64(-1474), p.Lines[0].AmountCents)
+	require.Equal(t, int64(2948), p.Lines[1].AmountCents)
+	require.Equal(t, int64(1474), p.AmountCents)
+	require.True(t, p.Lines[0].Proration)
+	require.Equal(t, p.ProrationDate, *p.Lines[1].PeriodStart)
+	require.Equal(t, sub.CurrentPeriodEnd, *p.Lines[1].PeriodEnd)
+}
+
+func TestBillingService_PreviewSubscriptionChange_RecentProrationDate(t *testing.T) {
+	svc, subs, prices := newSubscriptionService(t)
+	sub, basic, pro := prorationFixture()
+	subs.EXPECT().GetSubscription(gomock.Any(), gomock.Any(), 3).Return(sub, nil)
+	prices.EXPECT().GetPrice(gomock.Any(), 7).Return(basic, nil)
+	prices.EXPECT().GetPrice(gomock.Any(), 8).Return(pro, nil)
+
+	// La fecha de una vista previa de hace unos minutos se respeta tal cual.
+	at := time.Date(2024, time.January, 31, 11, 55, 0, 0, time.UTC)
+	p, err := svc.PreviewSubscriptionChange(context.Background(), Scope{UserID: "user-1"}, 3,
+		SubscriptionChangeRequest{PriceID: 8, ProrationDate: &at})
+	require.NoError(t, err)
+	require.Equal(t, at, p.ProrationDate)
+}
+
+func TestBillingService_ChangeSubscription_Behaviors(t *testing.T) {
+	tests := []struct {
+		name        string
+		behavior    string
+		priceID     int
+		quantity    int
+		wantPending int
+		wantInvoice int64
+	}{
+		{name: "default creates prorations", priceID: 8, wantPending: 2},
+		{name: "always invoice upgrade", behavior: ProrationAlwaysInvoice, priceID: 8, wantInvoice: 1474},
+		{name: "always invoice credit stays pending", behavior: ProrationAlwaysInvoice, priceID: 7, quantity: 1, wantPending: 2},
+		{name: "none", behavior: ProrationNone, priceID: 8},
+		{name: "quantity only", behavior: ProrationCreate, quantity: 3, wantPending: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, subs, prices := newSubscriptionService(t)
+			sub, basic, pro := prorationFixture()
+			if tt.priceID == 7 && tt.quantity == 1 {
+				// Baja de pro a basic.
+				sub.PriceID = 8
+				prices.EXPECT().GetPrice(gomock.Any(), 8).Return(pro, nil)
+				prices.EXPECT().GetPrice(gomock.Any(), 7).Return(basic, nil)
+			} else {
+				prices.EXPECT().GetPrice(gomock.Any(), 7).Return(basic, nil)
+				prices.EXPECT().GetPrice(gomock.Any(), 8).Return(pro, nil).AnyTimes()
+			}
+			subs.EXPECT().GetSubscription(gomock.Any(), gomock.Any(), 3).Return(sub, nil)
+			subs.EXPECT().ChangeSubscription(gomock.Any(), gomock.Any(), 3, gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ interface{}, _ int, c model.SubscriptionChange) (*model.Subscription, error) {
+					require.Equal(t, sub.PriceID, c.ExpectedPriceID)
+					require.Equal(t, sub.CurrentPeriodEnd, c.ExpectedPeriodEnd)
+					require.Len(t, c.PendingItems, tt.wantPending)
+					if tt.wantInvoice == 0 {
+						require.Nil(t, c.Invoice)
+						return &model.Subscription{ID: 3, PriceID: c.PriceID, Quantity: c.Quantity}, nil
+					}
+					require.Equal(t, tt.wantInvoice, c.Invoice.AmountCents)
+					require.Equal(t, 3, *c.Invoice.SubscriptionID)
+					require.Len(t, c.Invoice.Lines, 2)
+					c.Invoice.ID = 42
+					return &model.Subscription{ID: 3, PriceID: c.PriceID, Quantity: c.Quantity}, nil
+				})
+
+			res, err := svc.ChangeSubscription(context.Background(), Scope{UserID: "user-1"}, 3, SubscriptionChangeRequest{
+				PriceID: tt.priceID, Quantity: tt.quantity, ProrationBehavior: tt.behavior})
+			require.NoError(t, err)
+			if tt.wantInvoice != 0 {
+				require.Equal(t, 42, *res.Proration.InvoiceID)
+			} else {
+				require.Nil(t, res.Proration.InvoiceID)
+			}
+		})
+	}
+}
+
+func TestBillingService_ChangeSubscription_TrialNotProrated(t *testing.T) {
+	svc, subs, prices := newSubscriptionService(t)
+	sub, basic, pro := prorationFixture()
+	sub.Status = model.SubscriptionTrialing
+	subs.EXPECT().GetSubscription(gomock.Any(), gomock.Any(), 3).Return(sub, nil)
+	prices.EXPECT().GetPrice(gomock.Any(), 7).Return(basic, nil)
+	prices.EXPECT().GetPrice(gomock.Any(), 8).Return(pro, nil)
+	subs.EXPECT().ChangeSubscription(gomock.Any(), gomock.Any(), 3, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ interface{}, _ int, c model.SubscriptionChange) (*model.Subscription, error) {
+			require.Empty(t, c.PendingItems)
+			require.Nil(t, c.Invoice)
+			return &model.Subscription{ID: 3, PriceID: 8}, nil
+		})
+
+	res, err := svc.ChangeSubscription(context.Background(), Scope{UserID: "user-1"}, 3, SubscriptionChangeRequest{PriceID: 8})
+	require.NoError(t, err)
+	require.Empty(t, res.Proration.Lines)
+}
+
+func TestBillingService_PreviewSubscriptionChange_Rejected(t *testing.T) {
+	outside := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	backdated := time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		status  string
+		req     SubscriptionChangeRequest
+		newCur  string
+		wantErr error
+	}{
+		{name: "canceled", status: model.SubscriptionCanceled, req: SubscriptionChangeRequest{PriceID: 8}, wantErr: ErrInvalidTransition},
+		{name: "other currency", req: SubscriptionChangeRequest{PriceID: 8}, newCur: "EUR", wantErr: ErrInvalidChange},
+		{name: "nothing changes", req: SubscriptionChangeRequest{PriceID: 7, Quantity: 1}, wantErr: ErrInvalidChange},
+		{name: "proration date outside period", req: SubscriptionChangeRequest{PriceID: 8, ProrationDate: &outside}, wantErr: ErrInvalidChange},
+		{name: "backdated proration date", req: SubscriptionChangeRequest{PriceID: 8, ProrationDate: &backdated}, wantErr: ErrInvalidChange},
+		{name: "unknown behavior", req: SubscriptionChangeRequest{PriceID: 8, ProrationBehavior: "later"}, wantErr: ErrInvalidChange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, subs, prices := newSubscriptionService(t)
+			sub, basic, pro := prorationFixture()
+			if tt.status != "" {
+				sub.Status = tt.status
+			}
+			if tt.newCur != "" {
+				pro.Currency = tt.newCur
+			}
+			subs.EXPECT().GetSubscription(gomock.Any(), gomock.Any(), 3).Return(sub, nil).AnyTimes()
+			prices.EXPECT().GetPrice(gomock.Any(), 7).Return(basic, nil).AnyTimes()
+			prices.EXPECT().GetPrice(gomock.Any(), 8).Return(pro, nil).AnyTimes()
+
+			_, err := svc.PreviewSubscriptionChange(context.Background(), Scope{UserID: "user-1"}, 3, tt.req)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+)
+
+// renewalBatch es cuántas suscripciones procesa cada paso de una corrida; las
+// que quedan se toman en la siguiente.
+const renewalBatch = 100
+
+// RenewalResult resume una corrida de renovaciones.
+type RenewalResult struct {
+	Invoiced int
+	Advanced int
+}
+
+// RenewSubscriptions factura los períodos en curso que no tienen factura y
+// cierra los períodos vencidos. Cada paso es idempotente (índice único por
+// período y compare-and-swap sobre el fin del período), así que puede correr
+// en varias réplicas a la vez o reintentarse después de un crash.
+//
+// Se factura por adelantado: primero el período en curso y, al cerrarlo, el
+// siguiente. Un período vencido solo se cierra si ya se facturó, así que una
+// suscripción atrasada recupera un período por corrida sin saltear ninguno.
+func (s *BillingService) RenewSubscriptions(ctx context.Context) (RenewalResult, error) {
+	var res RenewalResult
+	subs, err := s.subscriptionStore()
+	if err != nil {
+		return res, err
+	}
+
+	invoiced, err := s.invoiceUnbilled(ctx, subs)
+	res.Invoiced += invoiced
+	if err != nil {
+		return res, err
+	}
+
+	due, err := subs.DueSubscriptions(ctx, s.now(), renewalBatch)
+	if err != nil {
+		return res, err
+	}
+	for _, sub := range due {
+		advanced, err := s.advancePeriod(ctx, subs, sub)
+		if err != nil {
+			log.Printf("subscription_renewal_failed subscription_id=%d step=advance err=%v", sub.ID, err)
+			continue
+		}
+		if advanced {
+			res.Advanced++
+		}
+	}
+
+	// Los períodos recién abiertos se facturan en la misma corrida.
+	invoiced, err = s.invoiceUnbilled(ctx, subs)
+	res.Invoiced += invoiced
+	return res, err
+}
+
+// RunRenewals ejecuta RenewSubscriptions cada interval hasta que ctx se cancele.
+func (s *BillingService) RunRenewals(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			res, err := s.RenewSubscriptions(ctx)
+			if err != nil {
+				log.Printf("subscription_renewals_failed err=%v", err)
+			}
+			if res.Invoiced > 0 || res.Advanced > 0 {
+				log.Printf("subscription_renewals_completed invoiced=%d advanced=%d", res.Invoiced, res.Advanced)
+			}
+		}
+	}
+}
+
+func (s *BillingService) invoiceUnbilled(ctx context.Context, subs SubscriptionStore) (int, error) {
+	unbilled, err := subs.UnbilledSubscriptions(ctx, renewalBatch)
+	if err != nil {
+		return 0, err
+	}
+	invoiced := 0
+	for _, sub := range unbilled {
+		created, err := s.invoicePeriod(ctx, sub)
+		if err != nil {
+			log.Printf("subscription_renewal_failed subscription_id=%d step=invoice err=%v", sub.ID, err)
+			continue
+		}
+		if created {
+			invoiced++
+		}
+	}
+	return invoiced, nil
+}
+
+// invoicePeriod factura el período en curso de sub junto con sus prorrateos
+// pendientes. Devuelve false si otra corrida ya lo facturó. El precio puede estar archivado: archivarlo no corta
+// las suscripciones existentes.
+func (s *BillingService) invoicePeriod(ctx context.Context, sub *model.Subscription) (bool, error) {
+	price, err := s.subscriptionPrice(ctx, sub)
+	if err != nil {
+		return false, err
+	}
+	start, end := sub.CurrentPeriodStart, sub.CurrentPeriodEnd
+	// El uso de los ítems medidos se cobra vencido, hasta el inicio del período.
+	usage, err := s.usageLines(ctx, sub, start)
+	if err != nil {
+		return false, err
+	}
+	invoice, err := s.renewalInvoice(ctx, sub, price, start, end, usage)
+	if err != nil {
+		return false, err
+	}
+	// La renovación emite la factura directamente, sin pasar por borrador.
+	issued(invoice)
+
+	err = s.repo.CreateInvoice(ctx, invoice)
+	if errors.Is(err, repository.ErrPeriodInvoiced) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	log.Printf("subscription_invoiced subscription_id=%d invoice_id=%d period_start=%s amount_cents=%d",
+		sub.ID, invoice.ID, start.Format(time.RFC3339), invoice.AmountCents)
+	return true, nil
+}
+
+// ErrNoUpcomingInvoice: la suscripción no se renueva (cancelada o por
+// cancelarse, pausada o incompleta).
+var ErrNoUpcomingInvoice = errors.New("subscription has no upcoming invoice")
+
+// UpcomingInvoice arma, sin guardarla, la factura que la renovación emitirá al
+// cerrar el período en curso: el período siguiente, el uso medido reportado
+// hasta ahora, los prorrateos pendientes y el descuento. Devuelve nil si la
+// suscripción no existe en el scope.
+func (s *BillingService) UpcomingInvoice(ctx context.Context, scope Scope, id int) (*model.Invoice, error) {
+	sub, err := s.GetSubscription(ctx, scope, id)
+	if err != nil || sub == nil {
+		return nil, err
+	}
+	switch sub.Status {
+	case model.SubscriptionTrialing, model.SubscriptionActive, model.SubscriptionPastDue:
+	default:
+		return nil, ErrNoUpcomingInvoice
+	}
+	if sub.CancelAtPeriodEnd {
+		return nil, ErrNoUpcomingInvoice
+	}
+	price, err := s.subscriptionPrice(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	start := sub.CurrentPeriodEnd
+	end := price.NextPeriodEnd(sub.BillingCycleAnchor, start)
+	usage, err := s.previewUsageLines(ctx, sub, start)
+	if err != nil {
+		return nil, err
+	}
+	invoice, err := s.renewalInvoice(ctx, sub, price, start, end, usage)
+	if err != nil {
+		return nil, err
+	}
+	invoice.Status = model.InvoiceDraft
+	return invoice, nil
+}
+
+// subscriptionPrice es el precio de sub; puede estar archivado.
+func (s *BillingService) subscriptionPrice(ctx context.Context, sub *model.Subscription) (*model.Price, error) {
+	if s.prices == nil {
+		return nil, fmt.Errorf("price catalog is not configured")
+	}
+	price, err := s.prices.GetPrice(ctx, sub.PriceID)
+	if err != nil {
+		return nil, err
+	}
+	if price == nil {
+		return nil, ErrPriceUnavailable
+	}
+	return price, nil
+}
+
+// renewalInvoice arma la factura de sub por el período [start, end): el
+// precio de la suscripción, el uso medido ya calculado, los prorrateos
+// pendientes y el descuento de la suscripción, con los totales. No la guarda.
+func (s *BillingService) renewalInvoice(ctx context.Context, sub *model.Subscription, price *model.Price, start, end time.Time,
+	usage []model.InvoiceLineItem) (*model.Invoice, error) {
+	amount, err := price.Amount(int64(sub.Quantity))
+	if err != nil {
+		return nil, err
+	}
+	lines := []model.InvoiceLineItem{{
+		Description:     fmt.Sprintf("Subscription #%d (%s - %s)", sub.ID, start.Format("2006-01-02"), end.Format("2006-01-02")),
+		PriceID:         &price.ID,
+		Quantity:        sub.Quantity,
+		UnitAmountCents: price.UnitAmountCents,
+		AmountCents:     amount,
+		PeriodStart:     &start,
+		PeriodEnd:       &end,
+	}}
+	lines = append(lines, usage...)
+	// Los prorrateos pendientes de cambios de plan se cobran (o acreditan) acá.
+	lines, err = s.pendingLines(ctx, sub, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	invoice := &model.Invoice{
+		UserID:         sub.UserID,
+		OrgID:          sub.OrgID,
+		Currency:       price.Currency,
+		PriceID:        &price.ID,
+		Quantity:       &sub.Quantity,
+		SubscriptionID: &sub.ID,
+		PeriodStart:    &start,
+		PeriodEnd:      &end,
+		Metadata:       map[string]string{},
+		Lines:          lines,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.subscriptionDiscount(ctx, sub, invoice); err != nil {
+		return nil, err
+	}
+	if err := invoice.ComputeTotals(); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// advancePeriod cierra el período vencido de sub: cancela si se pidió al fin
+// del período, termina la prueba o abre el período siguiente. Devuelve false
+// si otra corrida ya lo cerró.
+func (s *BillingService) advancePeriod(ctx context.Context, subs SubscriptionStore, sub *model.Subscription) (bool, error) {
+	end := sub.CurrentPeriodEnd
+	var u model.SubscriptionUpdate
+
+	if sub.CancelAtPeriodEnd {
+		canceled := model.SubscriptionCanceled
+		if !subscriptionTransitions.allows(sub.Status, canceled) {
+			return false, ErrInvalidTransition
+		}
+		u = model.SubscriptionUpdate{Status: &canceled, EndedAt: &end}
+	} else {
+		if s.prices == nil {
+			return false, fmt.Errorf("price catalog is not configured")
+		}
+		price, err := s.prices.GetPrice(ctx, sub.PriceID)
+		if err != nil {
+			return false, err
+		}
+		if price == nil {
+			return false, ErrPriceUnavailable
+		}
+		next := price.NextPeriodEnd(sub.BillingCycleAnchor, end)
+		u = model.SubscriptionUpdate{CurrentPeriodStart: &end, CurrentPeriodEnd: &next}
+		if sub.Status == model.SubscriptionTrialing {
+			active := model.SubscriptionActive
+			if !subscriptionTransitions.allows(sub.Status, active) {
+				return false, ErrInvalidTransition
+			}
+			u.Status = &active
+		}
+	}
+
+	advanced, err := subs.AdvanceSubscription(ctx, sub.ID, end, []string{sub.Status}, u)
+	if err != nil {
+		return false, err
+	}
+	return advanced != nil, nil
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+	"saas-subscription-platform/services/billing-service/internal/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+type renewalMocks struct {
+	invoices *mocks.MockInvoiceStore
+	subs     *mocks.MockSubscriptionStore
+	prices   *mocks.MockCatalogStore
+}
+
+func newRenewalService(t *testing.T, now time.Time) (*BillingService, renewalMocks) {
+	ctrl := gomock.NewController(t)
+	m := renewalMocks{
+		invoices: mocks.NewMockInvoiceStore(ctrl),
+		subs:     mocks.NewMockSubscriptionStore(ctrl),
+		prices:   mocks.NewMockCatalogStore(ctrl),
+	}
+	svc := NewBillingService(m.invoices, WithPrices(m.prices), WithSubscriptions(m.subs), WithClock(func() time.Time { return now }))
+	return svc, m
+}
+
+func TestRenewSubscriptions_InvoicesCurrentPeriod(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	svc, m := newRenewalService(t, now)
+
+	start := time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)
+	org := "org-1"
+	sub := &model.Subscription{ID: 3, UserID: "user-1", OrgID: &org, PriceID: 7, Quantity: 2, Status: model.SubscriptionActive,
+		CurrentPeriodStart: start, CurrentPeriodEnd: end, BillingCycleAnchor: start}
+
+	gomock.InOrder(
+		m.subs.EXPECT().UnbilledSubscriptions(gomock.Any(), renewalBatch).Return([]*model.Subscription{sub}, nil),
+		m.subs.EXPECT().DueSubscriptions(gomock.Any(), now, renewalBatch).Return(nil, nil),
+		m.subs.EXPECT().UnbilledSubscriptions(gomock.Any(), renewalBatch).Return(nil, nil),
+	)
+	m.prices.EXPECT().GetPrice(gomock.Any(), 7).Return(&model.Price{ID: 7, Currency: "USD", UnitAmountCents: 1500, Interval: model.IntervalMonth, IntervalCount: 1}, nil)
+	m.subs.EXPECT().PendingInvoiceItems(gomock.Any(), 3).Return(nil, nil)
+	m.invoices.EXPECT().CreateInvoice(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, inv *model.Invoice) error {
+		require.Equal(t, int64(3000), inv.AmountCents)
+		require.Equal(t, model.InvoiceOpen, inv.Status)
+		require.Equal(t, now, *inv.StatusTransitions.FinalizedAt)
+		require.Equal(t, "USD", inv.Currency)
+		require.Equal(t, &org, inv.OrgID)
+		require.Equal(t, 3, *inv.SubscriptionID)
+		require.Equal(t, start, *inv.PeriodStart)
+		require.Equal(t, end, *inv.PeriodEnd)
+		require.Len(t, inv.Lines, 1)
+		require.Equal(t, 2, inv.Lines[0].Quantity)
+		require.Equal(t, int64(1500), inv.Lines[0].UnitAmountCents)
+		require.Equal(t, int64(3000), inv.Lines[0].AmountCents)
+		return nil
+	})
+
+	res, err := svc.RenewSubscriptions(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, RenewalResult{Invoiced: 1}, res)
+}
+
+func TestRenewSubscriptions_InvoicesPendingProrations(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	svc, m := newRenewalService(t, now)
+	sub := &model.Subscription{ID: 3, UserID: "user-1", PriceID: 7, Quantity: 1, Status: model.SubscriptionActive,
+		CurrentPeriodStart: now, CurrentPeriodEnd: now.AddDate(0, 1, 0), BillingCycleAnchor: now}
+	credit, debit := 11, 12
+
+	m.subs.EXPECT().UnbilledSubscriptions(gomock.Any(), renewalBatch).Return([]*model.Subscription{sub}, nil)
+	m.subs.EXPECT().DueSubscriptions(gomock.Any(), now, renewalBatch).Return(nil, nil)
+	m.subs.EXPECT().UnbilledSubscriptions(gomock.Any(), renewalBatch).Return(nil, nil)
+	m.prices.EXPECT().GetPrice(gomock.Any(), 7).Return(&model.Price{ID: 7, Currency: "USD", UnitAmountCents: 1500}, nil)
+	m.subs.EXPECT().PendingInvoiceItems(gomock.Any(), 3).Return([]model.InvoiceLineItem{
+		{Description: "Unused time", Quantity: 1, UnitAmountCents: 1000, AmountCents: -500, Proration: true, PendingItemID: &credit},
+		{Description: "Remaining time", Quantity: 1, UnitAmountCents: 1500, AmountCents: 750, Proration: true, PendingItemID: &debit},
+	}, nil)
+	m.invoices.EXPECT().CreateInvoice(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, inv *model.Invoice) error {
+		require.Equal(t, int64(1500-500+750), inv.AmountCents)
+		require.Len(t, inv.Lines, 3)
+		require.False(t, inv.Lines[0].Proration)
+		require.Equal(t, &credit, inv.Lines[1].PendingItemID)
+		require.Equal(t, &debit, inv.Lines[2].PendingItemID)
+		return nil
+	})
+
+	res, err := svc.RenewSubscriptions(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, RenewalResult{Invoiced: 1}, res)
+}
+
+// Un crédito mayor que la factura la deja en cero; el sobrante no se arrastra
+// al período siguiente.
+func TestRenewSubscriptions_CreditBeyondInvoiceIsNotCarried(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	svc, m := newRenewalService(t, now)
+	sub := &model.Subscription{ID: 3, UserID: "user-1", PriceID: 7, Quantity: 1, Status: model.SubscriptionActive,
+		CurrentPeriodStart: now, CurrentPeriodEnd: now.AddDate(0, 1, 0), BillingCycleAnchor: now}
+	credit := 11
+
+	m.subs.EXPECT().UnbilledSubscriptions(gomock.Any(), renewalBatch).Return([]*model.Subscription{sub}, nil)
+	m.subs.EXPECT().DueSubscriptions(gomock.Any(), now, renewalBatch).Return(nil, nil)
+	m.subs.EXPECT().UnbilledSubscriptions(gomock.Any(), renewalBatch).Return(nil, nil)
+	m.prices.EXPECT().GetPrice(gomock.Any(), 7).Return(&model.Price{ID: 7, Currency: "USD", UnitAmountCents: 1500}, nil)
+	m.subs.EXPECT().PendingInvoiceItems(gomock.Any(), 3).Return([]model.InvoiceLineItem{
+		{Description: "Unused time", Quantity: 3, UnitAmountCents: 1500, AmountCents: -2500, Proration: true, PendingItemID: &credit},
+	}, nil)
+	m.invoices.EXPECT().CreateInvoice(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, inv *model.Invoice) error {
+		require.Equal(t, int64(-1000), inv.TotalCents)
+		require.Equal(t, int64(0), inv.AmountDueCents)
+		require.Equal(t, int64(0), inv.AmountCents)
+		// El crédito entero queda facturado: no vuelve a pendientes.
+		require.Len(t, inv.Lines, 2)
+		require.Equal(t, &credit, inv.Lines[1].PendingItemID)
+		return nil
+	})
+
+	res, err := svc.RenewSubscriptions(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, RenewalResult{Invoiced: 1}, res)
+}
+
+func TestRenewSubscriptions_PeriodAlreadyInvoiced(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	svc, m := newRenewalService(t, now)
+	sub := &model.Subscription{ID: 3, PriceID: 7, Quantity: 1, Status: model.SubscriptionActive}
+
+	m.subs.EXPECT().UnbilledSubscriptions(gomock.Any(), renewalBatch).Return([]*model.Subscription{sub}, nil).Times(2)
+	m.subs.EXPECT().DueSubscriptions(gomock.Any(), now, renewalBatch).Return(nil, nil)
+	m.prices.EXPECT().GetPrice(gomock.Any(), 7).Return(&model.Price{ID: 7, Currency: "USD", UnitAmountCents: 1500}, nil).Times(2)
+	m.subs.EXPECT().PendingInvoiceItems(gomock.Any(), 3).Return(nil, nil).Times(2)
+	// Otra réplica ya facturó el período: no es un error ni se cuenta.
+	m.invoices.EXPECT().CreateInvoice(gomock.Any(), gomock.Any()).Return(repository.ErrPeriodInvoiced).Times(2)
+
+	res, err := svc.RenewSubscriptions(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, RenewalResult{}, res)
+}
+
+func TestRenewSubscriptions_AdvancesDuePeriods(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	anchor := time.Date(2023, time.December, 31, 0, 0, 0, 0, time.UTC)
+	feb29 := time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)
+	mar31 := time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)
+	monthly := &model.Price{ID: 7, Currency: "USD", UnitAmountCents: 1500, Interval: model.IntervalMonth, IntervalCount: 1}
+
+	tests := []struct {
+		name       string
+		sub        *model.Subscription
+		wantFrom   []string
+		wantStatus *string
+		wantStart  *time.Time
+		wantEnd    *time.Time
+		wantEnded  *time.Time
+		swapped    bool
+	}{
+		{
+			name:      "active renews from month-end anchor",
+			sub:       &model.Subscription{ID: 1, PriceID: 7, Status: model.SubscriptionActive, CurrentPeriodEnd: feb29, BillingCycleAnchor: anchor},
+			wantFrom:  []string{model.SubscriptionActive},
+			wantStart: &feb29,
+			wantEnd:   &mar31,
+			swapped:   true,
+		},
+		{
+			name:       "trial ends",
+			sub:        &model.Subscription{ID: 2, PriceID: 7, Status: model.SubscriptionTrialing, CurrentPeriodEnd: feb29, BillingCycleAnchor: feb29},
+			wantFrom:   []string{model.SubscriptionTrialing},
+			wantStatus: strPtr(model.SubscriptionActive),
+			wantStart:  &feb29,
+			wantEnd:    timePtr(time.Date(2024, time.March, 29, 0, 0, 0, 0, time.UTC)),
+			swapped:    true,
+		},
+		{
+			name:       "cancel at period end",
+			sub:        &model.Subscription{ID: 3, PriceID: 7, Status: model.SubscriptionPastDue, CancelAtPeriodEnd: true, CurrentPeriodEnd: feb29, BillingCycleAnchor: anchor},
+			wantFrom:   []string{model.SubscriptionPastDue},
+			wantStatus: strPtr(model.SubscriptionCanceled),
+			wantEnded:  &feb29,
+			swapped:    true,
+		},
+		{
+			name:      "already advanced by another replica",
+			sub:       &model.Subscription{ID: 4, PriceID: 7, Status: model.SubscriptionActive, CurrentPeriodEnd: feb29, BillingCycleAnchor: anchor},
+			wantFrom:  []string{model.SubscriptionActive},
+			wantStart: &feb29,
+			wantEnd:   &mar31,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, m := newRenewalService(t, now)
+			m.subs.EXPECT().UnbilledSubscriptions(gomock.Any(), renewalBatch).Return(nil, nil).Times(2)
+			m.subs.EXPECT().DueSubscriptions(gomock.Any(), now, renewalBatch).Return([]*model.Subscription{tt.sub}, nil)
+			m.prices.EXPECT().GetPrice(gomock.Any(), 7).Return(monthly, nil).AnyTimes()
+			m.subs.EXPECT().AdvanceSubscription(gomock.Any(), tt.sub.ID, feb29, tt.wantFrom, gomock.Any()).
+				DoAndReturn(func(_ context.Context, id int, _ time.Time, _ []string, u model.SubscriptionUpdate) (*model.Subscription, error) {
+					require.Equal(t, tt.wantStatus, u.Status)
+					require.Equal(t, tt.wantStart, u.CurrentPeriodStart)
+					require.Equal(t, tt.wantEnd, u.CurrentPeriodEnd)
+					require.Equal(t, tt.wantEnded, u.EndedAt)
+					if !tt.swapped {
+						return nil, nil
+					}
+					return &model.Subscription{ID: id}, nil
+				})
+
+			res, err := svc.RenewSubscriptions(context.Background())
+			require.NoError(t, err)
+			if tt.swapped {
+				require.Equal(t, 1, res.Advanced)
+			} else {
+				require.Equal(t, 0, res.Advanced)
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func TestBillingService_UpcomingInvoice(t *testing.T) {
+	now := time.Date(2024, time.February, 10, 0, 0, 0, 0, time.UTC)
+	svc, m := newRenewalService(t, now)
+
+	start := time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)
+	sub := &model.Subscription{ID: 3, UserID: "user-1", PriceID: 7, Quantity: 14, Status: model.SubscriptionActive,
+		CurrentPeriodStart: start, CurrentPeriodEnd: start.AddDate(0, 1, 0), BillingCycleAnchor: start}
+	m.subs.EXPECT().GetSubscription(gomock.Any(), repository.InvoiceScope{UserID: "user-1"}, 3).Return(sub, nil)
+	// Hasta 10 asientos un fijo de 20,00 y después 1,50 cada uno.
+	m.prices.EXPECT().GetPrice(gomock.Any(), 7).Return(&model.Price{ID: 7, Currency: "USD", Interval: model.IntervalMonth, IntervalCount: 1,
+		BillingScheme: model.SchemeTiered, TiersMode: stringPtr(model.TiersGraduated),
+		Tiers: []model.PriceTier{{UpTo: int64Ptr(10), FlatAmountCents: 2000}, {UnitAmountCents: 150}}}, nil)
+	m.subs.EXPECT().PendingInvoiceItems(gomock.Any(), 3).Return(nil, nil)
+
+	inv, err := svc.UpcomingInvoice(context.Background(), Scope{UserID: "user-1"}, 3)
+	require.NoError(t, err)
+	require.Equal(t, model.InvoiceDraft, inv.Status)
+	require.Equal(t, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), *inv.PeriodStart)
+	require.Equal(t, time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC), *inv.PeriodEnd)
+	require.Len(t, inv.Lines, 1)
+	require.Equal(t, int64(2600), inv.Lines[0].AmountCents)
+	require.Equal(t, int64(2600), inv.AmountDueCents)
+}
+
+func TestBillingService_UpcomingInvoice_CancelAtPeriodEnd(t *testing.T) {
+	now := time.Date(2024, time.February, 10, 0, 0, 0, 0, time.UTC)
+	svc, m := newRenewalService(t, now)
+
+	m.subs.EXPECT().GetSubscription(gomock.Any(), repository.InvoiceScope{UserID: "user-1"}, 3).Return(
+		&model.Subscription{ID: 3, UserID: "user-1", PriceID: 7, Quantity: 1, Status: model.SubscriptionActive, CancelAtPeriodEnd: true}, nil)
+
+	_, err := svc.UpcomingInvoice(context.Background(), Scope{UserID: "user-1"}, 3)
+	require.ErrorIs(t, err, ErrNoUpcomingInvoice)
+}
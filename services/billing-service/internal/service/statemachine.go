@@ -0,0 +1,28 @@
+package service
+
+import "sort"
+
+// stateMachine son los estados a los que se puede pasar desde cada uno.
+type stateMachine map[string][]string
+
+// allows indica si se puede pasar de from a to.
+func (m stateMachine) allows(from, to string) bool {
+	for _, next := range m[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// sourcesOf devuelve, ordenados, los estados desde los que se puede llegar a to.
+func (m stateMachine) sourcesOf(to string) []string {
+	var from []string
+	for state := range m {
+		if m.allows(state, to) {
+			from = append(from, state)
+		}
+	}
+	sort.Strings(from)
+	return from
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+)
+
+// SubscriptionStore define las operaciones de suscripciones que la capa de
+// servicio necesita del repositorio.
+type SubscriptionStore interface {
+	CreateSubscription(ctx context.Context, s *model.Subscription) error
+	GetSubscription(ctx context.Context, scope repository.InvoiceScope, id int) (*model.Subscription, error)
+	ListSubscriptions(ctx context.Context, filter repository.SubscriptionFilter) ([]*model.Subscription, error)
+	TransitionSubscription(ctx context.Context, scope repository.InvoiceScope, id int, from []string, u model.SubscriptionUpdate) (*model.Subscription, error)
+	UnbilledSubscriptions(ctx context.Context, limit int) ([]*model.Subscription, error)
+	DueSubscriptions(ctx context.Context, now time.Time, limit int) ([]*model.Subscription, error)
+	AdvanceSubscription(ctx context.Context, id int, periodEnd time.Time, from []string, u model.SubscriptionUpdate) (*model.Subscription, error)
+	ChangeSubscription(ctx context.Context, scope repository.InvoiceScope, id int, change model.SubscriptionChange) (*model.Subscription, error)
+	PendingInvoiceItems(ctx context.Context, subscriptionID int) ([]model.InvoiceLineItem, error)
+}
+
+// ErrInvalidTransition: la suscripción no admite la operación en su estado actual.
+var ErrInvalidTransition = repository.ErrInvalidTransition
+
+// maxTrialDays limita la prueba gratis.
+const maxTrialDays = 730
+
+// subscriptionTransitions es la máquina de estados de las suscripciones: los
+// estados a los que se puede pasar desde cada uno. canceled es terminal.
+var subscriptionTransitions = stateMachine{
+	model.SubscriptionIncomplete: {model.SubscriptionActive, model.SubscriptionCanceled},
+	model.SubscriptionTrialing:   {model.SubscriptionActive, model.SubscriptionPastDue, model.SubscriptionPaused, model.SubscriptionCanceled},
+	model.SubscriptionActive:     {model.SubscriptionPastDue, model.SubscriptionPaused, model.SubscriptionCanceled},
+	model.SubscriptionPastDue:    {model.SubscriptionActive, model.SubscriptionCanceled},
+	model.SubscriptionPaused:     {model.SubscriptionActive, model.SubscriptionCanceled},
+	model.SubscriptionCanceled:   {},
+}
+
+// renewingStatuses son los estados en los que el período avanza; solo en
+// ellos tiene sentido cancelar al fin del período.
+var renewingStatuses = []string{model.SubscriptionTrialing, model.SubscriptionActive, model.SubscriptionPastDue}
+
+// WithSubscriptions habilita las suscripciones.
+func WithSubscriptions(subs SubscriptionStore) Option {
+	return func(s *BillingService) { s.subs = subs }
+}
+
+func (s *BillingService) subscriptionStore() (SubscriptionStore, error) {
+	if s.subs == nil {
+		return nil, fmt.Errorf("subscriptions are not configured")
+	}
+	return s.subs, nil
+}
+
+// recurringPrice devuelve el precio si está activo, es recurrente y no es
+// medido (esos se agregan como ítems).
+func (s *BillingService) recurringPrice(ctx context.Context, priceID int) (*model.Price, error) {
+	if s.prices == nil {
+		return nil, fmt.Errorf("price catalog is not configured")
+	}
+	price, err := s.prices.GetPrice(ctx, priceID)
+	if err != nil {
+		return nil, err
+	}
+	if price == nil || !price.Active {
+		return nil, ErrPriceUnavailable
+	}
+	if !price.Recurring() {
+		return nil, fmt.Errorf("price must be recurring")
+	}
+	if price.Metered() {
+		return nil, errMeteredPrice
+	}
+	return price, nil
+}
+
+// CreateSubscription suscribe al cliente del scope a un precio recurrente. Con
+// trialDays > 0 empieza en trialing y el primer período es la prueba.
+// quantity 0 equivale a 1.
+func (s *BillingService) CreateSubscription(ctx context.Context, scope Scope, priceID, quantity, trialDays int) (*model.Subscription, error) {
+	owner, err := scope.invoiceScope()
+	if err != nil {
+		return nil, err
+	}
+	subs, err := s.subscriptionStore()
+	if err != nil {
+		return nil, err
+	}
+	if quantity == 0 {
+		quantity = 1
+	}
+	if quantity < 0 {
+		return nil, fmt.Errorf("quantity must be positive")
+	}
+	if trialDays < 0 || trialDays > maxTrialDays {
+		return nil, fmt.Errorf("trial_days must be between 0 and %d", maxTrialDays)
+	}
+	price, err := s.recurringPrice(ctx, priceID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	sub := &model.Subscription{
+		UserID:             owner.UserID,
+		PriceID:            price.ID,
+		Quantity:           quantity,
+		Status:             model.SubscriptionActive,
+		CurrentPeriodStart: now,
+		CurrentPeriodEnd:   price.PeriodBoundary(now, 1),
+		BillingCycleAnchor: now,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	if trialDays > 0 {
+		// Los períodos pagos se cuentan desde el fin de la prueba.
+		trialEnd := now.AddDate(0, 0, trialDays)
+		sub.Status = model.SubscriptionTrialing
+		sub.CurrentPeriodEnd = trialEnd
+		sub.TrialEnd = &trialEnd
+		sub.BillingCycleAnchor = trialEnd
+	}
+	if owner.OrgID != "" {
+		sub.OrgID = &owner.OrgID
+	}
+
+	if err := subs.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// GetSubscription devuelve nil si la suscripción no existe en el scope.
+func (s *BillingService) GetSubscription(ctx context.Context, scope Scope, id int) (*model.Subscription, error) {
+	owner, err := scope.invoiceScope()
+	if err != nil {
+		return nil, err
+	}
+	subs, err := s.subscriptionStore()
+	if err != nil {
+		return nil, err
+	}
+	return subs.GetSubscription(ctx, owner, id)
+}
+
+func (s *BillingService) ListSubscriptions(ctx context.Context, scope Scope, status string, limit, offset int) ([]*model.Subscription, error) {
+	owner, err := scope.invoiceScope()
+	if err != nil {
+		return nil, err
+	}
+	subs, err := s.subscriptionStore()
+	if err != nil {
+		return nil, err
+	}
+	return subs.ListSubscriptions(ctx, repository.SubscriptionFilter{
+		UserID: owner.UserID,
+		OrgID:  owner.OrgID,
+		Status: status,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// transitionSubscription aplica u si la suscripción está hoy en alguno de
+// from. Devuelve nil si no existe en el scope.
+func (s *BillingService) transitionSubscription(ctx context.Context, scope Scope, id int, from []string, u model.SubscriptionUpdate) (*model.Subscription, error) {
+	owner, err := scope.invoiceScope()
+	if err != nil {
+		return nil, err
+	}
+	subs, err := s.subscriptionStore()
+	if err != nil {
+		return nil, err
+	}
+	return subs.TransitionSubscription(ctx, owner, id, from, u)
+}
+
+// moveSubscription lleva la suscripción a to desde cualquier estado que la
+// máquina de estados permita.
+func (s *BillingService) moveSubscription(ctx context.Context, scope Scope, id int, to string, u model.SubscriptionUpdate) (*model.Subscription, error) {
+	u.Status = &to
+	return s.transitionSubscription(ctx, scope, id, subscriptionTransitions.sourcesOf(to), u)
+}
+
+// CancelSubscription cancela ya o, con atPeriodEnd, al terminar el período en
+// curso (la suscripción sigue en su estado hasta entonces).
+func (s *BillingService) CancelSubscription(ctx context.Context, scope Scope, id int, atPeriodEnd bool) (*model.Subscription, error) {
+	now := s.now()
+	if atPeriodEnd {
+		cancel := true
+		return s.transitionSubscription(ctx, scope, id, renewingStatuses, model.SubscriptionUpdate{
+			CancelAtPeriodEnd: &cancel,
+			CanceledAt:        &now,
+		})
+	}
+	return s.moveSubscription(ctx, scope, id, model.SubscriptionCanceled, model.SubscriptionUpdate{
+		CanceledAt: &now,
+		EndedAt:    &now,
+	})
+}
+
+// PauseSubscription suspende el cobro hasta ResumeSubscription.
+func (s *BillingService) PauseSubscription(ctx context.Context, scope Scope, id int) (*model.Subscription, error) {
+	return s.moveSubscription(ctx, scope, id, model.SubscriptionPaused, model.SubscriptionUpdate{})
+}
+
+// ResumeSubscription reactiva una suscripción pausada; el período nuevo (y el
+// ancla de los siguientes) empieza al reanudar.
+func (s *BillingService) ResumeSubscription(ctx context.Context, scope Scope, id int) (*model.Subscription, error) {
+	current, err := s.GetSubscription(ctx, scope, id)
+	if err != nil || current == nil {
+		return nil, err
+	}
+	if current.Status != model.SubscriptionPaused {
+		return nil, ErrInvalidTransition
+	}
+	if s.prices == nil {
+		return nil, fmt.Errorf("price catalog is not configured")
+	}
+	price, err := s.prices.GetPrice(ctx, current.PriceID)
+	if err != nil {
+		return nil, err
+	}
+	if price == nil {
+		return nil, ErrPriceUnavailable
+	}
+
+	now := s.now()
+	end := price.PeriodBoundary(now, 1)
+	active := model.SubscriptionActive
+	return s.transitionSubscription(ctx, scope, id, []string{model.SubscriptionPaused}, model.SubscriptionUpdate{
+		Status:             &active,
+		CurrentPeriodStart: &now,
+		CurrentPeriodEnd:   &end,
+		BillingCycleAnchor: &now,
+	})
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+	"saas-subscription-platform/services/billing-service/internal/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionStateMachine(t *testing.T) {
+	const (
+		trialing   = model.SubscriptionTrialing
+		active     = model.SubscriptionActive
+		pastDue    = model.SubscriptionPastDue
+		canceled   = model.SubscriptionCanceled
+		paused     = model.SubscriptionPaused
+		incomplete = model.SubscriptionIncomplete
+	)
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{incomplete, active, true},
+		{incomplete, canceled, true},
+		{incomplete, trialing, false},
+		{incomplete, paused, false},
+		{incomplete, pastDue, false},
+
+		{trialing, active, true},
+		{trialing, pastDue, true},
+		{trialing, paused, true},
+		{trialing, canceled, true},
+		{trialing, incomplete, false},
+
+		{active, pastDue, true},
+		{active, paused, true},
+		{active, canceled, true},
+		{active, trialing, false},
+		{active, incomplete, false},
+		{active, active, false},
+
+		{pastDue, active, true},
+		{pastDue, canceled, true},
+		{pastDue, paused, false},
+		{pastDue, trialing, false},
+
+		{paused, active, true},
+		{paused, canceled, true},
+		{paused, pastDue, false},
+		{paused, trialing, false},
+
+		{canceled, active, false},
+		{canceled, trialing, false},
+		{canceled, paused, false},
+		{canceled, pastDue, false},
+		{canceled, incomplete, false},
+		{canceled, canceled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			require.Equal(t, tt.allowed, subscriptionTransitions.allows(tt.from, tt.to))
+		})
+	}
+}
+
+func TestSubscriptionStateMachine_Sources(t *testing.T) {
+	tests := []struct {
+		to   string
+		want []string
+	}{
+		{model.SubscriptionActive, []string{model.SubscriptionIncomplete, model.SubscriptionPastDue, model.SubscriptionPaused, model.SubscriptionTrialing}},
+		{model.SubscriptionPaused, []string{model.SubscriptionActive, model.SubscriptionTrialing}},
+		{model.SubscriptionCanceled, []string{model.SubscriptionActive, model.SubscriptionIncomplete, model.SubscriptionPastDue, model.SubscriptionPaused, model.SubscriptionTrialing}},
+		{model.SubscriptionTrialing, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.to, func(t *testing.T) {
+			require.Equal(t, tt.want, subscriptionTransitions.sourcesOf(tt.to))
+		})
+	}
+}
+
+func newSubscriptionService(t *testing.T) (*BillingService, *mocks.MockSubscriptionStore, *mocks.MockCatalogStore) {
+	ctrl := gomock.NewController(t)
+	subs := mocks.NewMockSubscriptionStore(ctrl)
+	prices := mocks.NewMockCatalogStore(ctrl)
+	now := func() time.Time { return time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC) }
+	return NewBillingService(nil, WithPrices(prices), WithSubscriptions(subs), WithClock(now)), subs, prices
+}
+
+func TestBillingService_CreateSubscription(t *testing.T) {
+	monthly := &model.Price{ID: 7, Currency: "USD", UnitAmountCents: 1500, Interval: model.IntervalMonth, IntervalCount: 1, Active: true}
+	tests := []struct {
+		name       string
+		scope      Scope
+		price      *model.Price
+		trialDays  int
+		wantStatus string
+		wantErr    error
+	}{
+		{name: "active", scope: Scope{UserID: "user-1"}, price: monthly, wantStatus: model.SubscriptionActive},
+		{name: "trial", scope: Scope{UserID: "user-1"}, price: monthly, trialDays: 14, wantStatus: model.SubscriptionTrialing},
+		{name: "org", scope: Scope{UserID: "user-1", OrgID: "org-1", OrgRole: "billing"}, price: monthly, wantStatus: model.SubscriptionActive},
+		{name: "archived price", scope: Scope{UserID: "user-1"}, price: &model.Price{ID: 7, Interval: model.IntervalMonth, IntervalCount: 1}, wantErr: ErrPriceUnavailable},
+		{name: "member of org", scope: Scope{UserID: "user-1", OrgID: "org-1", OrgRole: "member"}, wantErr: ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, subs, prices := newSubscriptionService(t)
+			if tt.price != nil {
+				prices.EXPECT().GetPrice(gomock.Any(), 7).Return(tt.price, nil)
+			}
+			if tt.wantErr == nil {
+				subs.EXPECT().CreateSubscription(gomock.Any(), gomock.Any()).Return(nil)
+			}
+
+			sub, err := svc.CreateSubscription(context.Background(), tt.scope, 7, 0, tt.trialDays)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantStatus, sub.Status)
+			require.Equal(t, 1, sub.Quantity)
+			require.Equal(t, tt.scope.OrgID != "", sub.OrgID != nil)
+			if tt.trialDays > 0 {
+				require.Equal(t, sub.CurrentPeriodStart.AddDate(0, 0, tt.trialDays), sub.CurrentPeriodEnd)
+				require.Equal(t, sub.CurrentPeriodEnd, *sub.TrialEnd)
+				require.Equal(t, *sub.TrialEnd, sub.BillingCycleAnchor)
+			} else {
+				// Ancla el 31: el primer período termina el último día de febrero.
+				require.Equal(t, time.Date(2024, time.February, 29, 12, 0, 0, 0, time.UTC), sub.CurrentPeriodEnd)
+				require.Equal(t, sub.CurrentPeriodStart, sub.BillingCycleAnchor)
+				require.Nil(t, sub.TrialEnd)
+			}
+		})
+	}
+}
+
+func TestBillingService_CreateSubscription_RejectsOneTimePrice(t *testing.T) {
+	svc, _, prices := newSubscriptionService(t)
+	prices.EXPECT().GetPrice(gomock.Any(), 7).Return(&model.Price{ID: 7, Interval: model.IntervalOneTime, Active: true}, nil)
+
+	_, err := svc.CreateSubscription(context.Background(), Scope{UserID: "user-1"}, 7, 1, 0)
+	require.Error(t, err)
+}
+
+func TestBillingService_SubscriptionTransitions(t *testing.T) {
+	scope := Scope{UserID: "user-1"}
+	owner := repository.InvoiceScope{UserID: "user-1"}
+	tests := []struct {
+		name       string
+		call       func(svc *BillingService) (*model.Subscription, error)
+		wantFrom   []string
+		wantStatus *string
+		check      func(t *testing.T, u model.SubscriptionUpdate)
+	}{
+		{
+			name: "cancel now",
+			call: func(svc *BillingService) (*model.Subscription, error) {
+				return svc.CancelSubscription(context.Background(), scope, 1, false)
+			},
+			wantFrom:   subscriptionTransitions.sourcesOf(model.SubscriptionCanceled),
+			wantStatus: strPtr(model.SubscriptionCanceled),
+			check: func(t *testing.T, u model.SubscriptionUpdate) {
+				require.NotNil(t, u.CanceledAt)
+				require.NotNil(t, u.EndedAt)
+			},
+		},
+		{
+			name: "cancel at period end",
+			call: func(svc *BillingService) (*model.Subscription, error) {
+				return svc.CancelSubscription(context.Background(), scope, 1, true)
+			},
+			wantFrom: []string{model.SubscriptionTrialing, model.SubscriptionActive, model.SubscriptionPastDue},
+			check: func(t *testing.T, u model.SubscriptionUpdate) {
+				require.True(t, *u.CancelAtPeriodEnd)
+				require.NotNil(t, u.CanceledAt)
+				require.Nil(t, u.EndedAt)
+			},
+		},
+		{
+			name: "pause",
+			call: func(svc *BillingService) (*model.Subscription, error) {
+				return svc.PauseSubscription(context.Background(), scope, 1)
+			},
+			wantFrom:   []string{model.SubscriptionActive, model.SubscriptionTrialing},
+			wantStatus: strPtr(model.SubscriptionPaused),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, subs, _ := newSubscriptionService(t)
+			subs.EXPECT().TransitionSubscription(gomock.Any(), owner, 1, tt.wantFrom, gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ repository.InvoiceScope, id int, _ []string, u model.SubscriptionUpdate) (*model.Subscription, error) {
+					require.Equal(t, tt.wantStatus, u.Status)
+					if tt.check != nil {
+						tt.check(t, u)
+					}
+					return &model.Subscription{ID: id}, nil
+				})
+
+			sub, err := tt.call(svc)
+			require.NoError(t, err)
+			require.Equal(t, 1, sub.ID)
+		})
+	}
+}
+
+func TestBillingService_SubscriptionTransition_Rejected(t *testing.T) {
+	svc, subs, _ := newSubscriptionService(t)
+	subs.EXPECT().TransitionSubscription(gomock.Any(), gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil, ErrInvalidTransition)
+
+	_, err := svc.PauseSubscription(context.Background(), Scope{UserID: "user-1"}, 1)
+	require.ErrorIs(t, err, ErrInvalidTransition)
+}
+
+func TestBillingService_ResumeSubscription(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		wantErr error
+	}{
+		{name: "paused", status: model.SubscriptionPaused},
+		{name: "active", status: model.SubscriptionActive, wantErr: ErrInvalidTransition},
+		{name: "canceled", status: model.SubscriptionCanceled, wantErr: ErrInvalidTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, subs, prices := newSubscriptionService(t)
+			subs.EXPECT().GetSubscription(gomock.Any(), gomock.Any(), 1).Return(&model.Subscription{ID: 1, PriceID: 7, Status: tt.status}, nil)
+			if tt.wantErr == nil {
+				prices.EXPECT().GetPrice(gomock.Any(), 7).Return(&model.Price{ID: 7, Interval: model.IntervalWeek, IntervalCount: 2}, nil)
+				subs.EXPECT().TransitionSubscription(gomock.Any(), gomock.Any(), 1, []string{model.SubscriptionPaused}, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ repository.InvoiceScope, id int, _ []string, u model.SubscriptionUpdate) (*model.Subscription, error) {
+						require.Equal(t, model.SubscriptionActive, *u.Status)
+						require.Equal(t, time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC), *u.CurrentPeriodStart)
+						require.Equal(t, u.CurrentPeriodStart.AddDate(0, 0, 14), *u.CurrentPeriodEnd)
+						require.Equal(t, u.CurrentPeriodStart, u.BillingCycleAnchor)
+						return &model.Subscription{ID: id, Status: *u.Status}, nil
+					})
+			}
+
+			sub, err := svc.ResumeSubscription(context.Background(), Scope{UserID: "user-1"}, 1)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, model.SubscriptionActive, sub.Status)
+		})
+	}
+}
+
+func strPtr(s string) *string { return &s }
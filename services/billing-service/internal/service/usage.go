@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+)
+
+// UsageStore define las operaciones de ítems medidos y uso que la capa de
+// servicio necesita del repositorio.
+type UsageStore interface {
+	CreateSubscriptionItem(ctx context.Context, scope repository.InvoiceScope, item *model.SubscriptionItem) (bool, error)
+	ListSubscriptionItems(ctx context.Context, subscriptionID int) ([]*model.SubscriptionItem, error)
+	RecordUsage(ctx context.Context, scope repository.InvoiceScope, records []model.UsageRecord) (int, error)
+	CloseUsage(ctx context.Context, subscriptionID int, at time.Time) ([]*model.SubscriptionItem, error)
+	UsageRollups(ctx context.Context, subscriptionID int, before time.Time) ([]model.UsageRollup, error)
+}
+
+var (
+	// ErrInvalidUsage: ítem o registro de uso con datos inválidos.
+	ErrInvalidUsage = errors.New("invalid usage")
+
+	ErrSubscriptionItemExists   = repository.ErrSubscriptionItemExists
+	ErrSubscriptionItemNotFound = repository.ErrSubscriptionItemNotFound
+	ErrUsagePeriodClosed        = repository.ErrUsagePeriodClosed
+)
+
+const (
+	// maxUsageBatch limita los registros de un POST /usage_records.
+	maxUsageBatch     = 1000
+	maxIdempotencyKey = 255
+	// maxUsageClockSkew es cuánto puede adelantarse un registro al reloj del servidor.
+	maxUsageClockSkew = 5 * time.Minute
+)
+
+func invalidUsage(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidUsage, fmt.Sprintf(format, args...))
+}
+
+// WithUsage habilita los precios medidos: ítems de suscripción y registros de uso.
+func WithUsage(usage UsageStore) Option {
+	return func(s *BillingService) { s.usage = usage }
+}
+
+func (s *BillingService) usageStore() (UsageStore, error) {
+	if s.usage == nil {
+		return nil, fmt.Errorf("usage is not configured")
+	}
+	return s.usage, nil
+}
+
+// AddSubscriptionItem agrega a la suscripción un precio medido, con la misma
+// moneda e intervalo que su precio. El uso se cuenta desde ahora y se factura
+// en cada renovación. Devuelve nil si la suscripción no existe en el scope.
+func (s *BillingService) AddSubscriptionItem(ctx context.Context, scope Scope, subscriptionID, priceID int) (*model.SubscriptionItem, error) {
+	store, err := s.usageStore()
+	if err != nil {
+		return nil, err
+	}
+	sub, err := s.GetSubscription(ctx, scope, subscriptionID)
+	if err != nil || sub == nil {
+		return nil, err
+	}
+	if sub.Status == model.SubscriptionCanceled {
+		return nil, ErrInvalidTransition
+	}
+	if s.prices == nil {
+		return nil, fmt.Errorf("price catalog is not configured")
+	}
+	base, err := s.prices.GetPrice(ctx, sub.PriceID)
+	if err != nil {
+		return nil, err
+	}
+	price, err := s.prices.GetPrice(ctx, priceID)
+	if err != nil {
+		return nil, err
+	}
+	if base == nil || price == nil || !price.Active {
+		return nil, ErrPriceUnavailable
+	}
+	if !price.Metered() {
+		return nil, invalidUsage("price must be metered")
+	}
+	if price.Currency != base.Currency || price.Interval != base.Interval || price.IntervalCount != base.IntervalCount {
+		return nil, invalidUsage("price must have the subscription's currency and billing interval")
+	}
+
+	owner, _ := scope.invoiceScope()
+	item := &model.SubscriptionItem{SubscriptionID: sub.ID, PriceID: price.ID, CreatedAt: s.now()}
+	found, err := store.CreateSubscriptionItem(ctx, owner, item)
+	if err != nil || !found {
+		return nil, err
+	}
+	return item, nil
+}
+
+// ListSubscriptionItems devuelve nil si la suscripción no existe en el scope.
+func (s *BillingService) ListSubscriptionItems(ctx context.Context, scope Scope, subscriptionID int) ([]*model.SubscriptionItem, error) {
+	store, err := s.usageStore()
+	if err != nil {
+		return nil, err
+	}
+	sub, err := s.GetSubscription(ctx, scope, subscriptionID)
+	if err != nil || sub == nil {
+		return nil, err
+	}
+	return store.ListSubscriptionItems(ctx, sub.ID)
+}
+
+// UsageResult cuenta los registros de un lote: los nuevos y los que ya se
+// habían recibido con la misma clave de idempotencia.
+type UsageResult struct {
+	Accepted   int `json:"accepted"`
+	Duplicates int `json:"duplicates"`
+}
+
+// RecordUsage guarda un lote de registros de uso de ítems del scope. action
+// vacía es increment y timestamp vacío es ahora. El lote se guarda entero o
+// no se guarda.
+func (s *BillingService) RecordUsage(ctx context.Context, scope Scope, records []model.UsageRecord) (*UsageResult, error) {
+	store, err := s.usageStore()
+	if err != nil {
+		return nil, err
+	}
+	owner, err := scope.invoiceScope()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 || len(records) > maxUsageBatch {
+		return nil, invalidUsage("a batch needs between 1 and %d records", maxUsageBatch)
+	}
+
+	now := s.now()
+	type recordKey struct {
+		itemID int
+		key    string
+	}
+	seen := make(map[recordKey]bool, len(records))
+	unique := make([]model.UsageRecord, 0, len(records))
+	for i, rec := range records {
+		if rec.SubscriptionItemID <= 0 {
+			return nil, invalidUsage("record %d: subscription_item_id is required", i)
+		}
+		if rec.IdempotencyKey == "" || len(rec.IdempotencyKey) > maxIdempotencyKey {
+			return nil, invalidUsage("record %d: idempotency_key is required and must be at most %d characters", i, maxIdempotencyKey)
+		}
+		if rec.Quantity < 0 {
+			return nil, invalidUsage("record %d: quantity must not be negative", i)
+		}
+		switch rec.Action {
+		case "":
+			rec.Action = model.UsageIncrement
+		case model.UsageIncrement, model.UsageSet:
+		default:
+			return nil, invalidUsage("record %d: action must be increment or set", i)
+		}
+		if rec.Timestamp.IsZero() {
+			rec.Timestamp = now
+		}
+		if rec.Timestamp.After(now.Add(maxUsageClockSkew)) {
+			return nil, invalidUsage("record %d: timestamp must not be in the future", i)
+		}
+		// Postgres guarda microsegundos; así el rollup ve lo mismo que la tabla.
+		rec.Timestamp = rec.Timestamp.Truncate(time.Microsecond)
+
+		// Una clave repetida dentro del lote es un reintento: vale la primera.
+		key := recordKey{rec.SubscriptionItemID, rec.IdempotencyKey}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, rec)
+	}
+
+	accepted, err := store.RecordUsage(ctx, owner, unique)
+	if err != nil {
+		return nil, err
+	}
+	return &UsageResult{Accepted: accepted, Duplicates: len(records) - accepted}, nil
+}
+
+// UsageSummary devuelve el uso sin facturar de cada ítem de la suscripción
+// (lo que se cobrará en la próxima renovación). Devuelve nil si la suscripción
+// no existe en el scope.
+func (s *BillingService) UsageSummary(ctx context.Context, scope Scope, subscriptionID int) ([]model.UsageSummary, error) {
+	store, err := s.usageStore()
+	if err != nil {
+		return nil, err
+	}
+	sub, err := s.GetSubscription(ctx, scope, subscriptionID)
+	if err != nil || sub == nil {
+		return nil, err
+	}
+	if s.prices == nil {
+		return nil, fmt.Errorf("price catalog is not configured")
+	}
+	items, err := store.ListSubscriptionItems(ctx, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Ningún registro aceptado es posterior a ahora más el desfase tolerado.
+	rollups, err := store.UsageRollups(ctx, sub.ID, s.now().Add(maxUsageClockSkew+time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+	byItem := groupRollups(rollups)
+
+	summaries := make([]model.UsageSummary, 0, len(items))
+	for _, item := range items {
+		price, err := s.prices.GetPrice(ctx, item.PriceID)
+		if err != nil {
+			return nil, err
+		}
+		if price == nil {
+			return nil, ErrPriceUnavailable
+		}
+		method := aggregation(price)
+		total, err := model.AggregateUsage(method, byItem[item.ID])
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, model.UsageSummary{
+			SubscriptionItemID: item.ID,
+			PriceID:            price.ID,
+			AggregateUsage:     method,
+			PeriodStart:        item.BilledThrough,
+			TotalUsage:         total,
+		})
+	}
+	return summaries, nil
+}
+
+func groupRollups(rollups []model.UsageRollup) map[int][]model.UsageRollup {
+	byItem := map[int][]model.UsageRollup{}
+	for _, r := range rollups {
+		byItem[r.SubscriptionItemID] = append(byItem[r.SubscriptionItemID], r)
+	}
+	return byItem
+}
+
+// aggregation es cómo se agrega el uso del precio (sum si no lo indica).
+func aggregation(price *model.Price) string {
+	if price.AggregateUsage == nil {
+		return model.AggregateSum
+	}
+	return *price.AggregateUsage
+}
+
+// usageLines arma un renglón por ítem medido de sub con el uso sin facturar
+// hasta start (el inicio del período que se factura), aunque sea 0. Antes de
+// leer el uso lo cierra: los registros anteriores a start que lleguen después
+// se rechazan en lugar de perderse.
+func (s *BillingService) usageLines(ctx context.Context, sub *model.Subscription, start time.Time) ([]model.InvoiceLineItem, error) {
+	if s.usage == nil {
+		return nil, nil
+	}
+	items, err := s.usage.CloseUsage(ctx, sub.ID, start)
+	if err != nil {
+		return nil, err
+	}
+	return s.billUsage(ctx, sub, items, start)
+}
+
+// previewUsageLines es usageLines sin cerrar el uso: los renglones con lo
+// reportado hasta ahora, para la vista previa de la próxima factura.
+func (s *BillingService) previewUsageLines(ctx context.Context, sub *model.Subscription, end time.Time) ([]model.InvoiceLineItem, error) {
+	if s.usage == nil {
+		return nil, nil
+	}
+	items, err := s.usage.ListSubscriptionItems(ctx, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.billUsage(ctx, sub, items, end)
+}
+
+// billUsage arma un renglón por ítem con el uso sin facturar hasta end.
+func (s *BillingService) billUsage(ctx context.Context, sub *model.Subscription, items []*model.SubscriptionItem, end time.Time) ([]model.InvoiceLineItem, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	rollups, err := s.usage.UsageRollups(ctx, sub.ID, end)
+	if err != nil {
+		return nil, err
+	}
+	byItem := groupRollups(rollups)
+
+	var lines []model.InvoiceLineItem
+	for _, item := range items {
+		if !item.BilledThrough.Before(end) {
+			continue
+		}
+		price, err := s.prices.GetPrice(ctx, item.PriceID)
+		if err != nil {
+			return nil, err
+		}
+		if price == nil {
+			return nil, ErrPriceUnavailable
+		}
+		line, err := usageLine(price, item, byItem[item.ID], end)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// usageLine es el renglón del uso de item desde su billed_through hasta end.
+func usageLine(price *model.Price, item *model.SubscriptionItem, rollups []model.UsageRollup, end time.Time) (model.InvoiceLineItem, error) {
+	quantity, err := model.AggregateUsage(aggregation(price), rollups)
+	if err != nil {
+		return model.InvoiceLineItem{}, err
+	}
+	amount, err := price.Amount(quantity)
+	if err != nil {
+		return model.InvoiceLineItem{}, err
+	}
+	start := item.BilledThrough
+	return model.InvoiceLineItem{
+		Description:        fmt.Sprintf("Usage of price #%d (%s - %s)", price.ID, start.Format("2006-01-02"), end.Format("2006-01-02")),
+		PriceID:            &price.ID,
+		Quantity:           int(quantity),
+		UnitAmountCents:    price.UnitAmountCents,
+		AmountCents:        amount,
+		PeriodStart:        &start,
+		PeriodEnd:          &end,
+		SubscriptionItemID: &item.ID,
+	}, nil
+}
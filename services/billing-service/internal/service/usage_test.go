@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/billing-service/internal/model"
+	"saas-subscription-platform/services/billing-service/internal/repository"
+	"saas-subscription-platform/services/billing-service/internal/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBillingService_RecordUsage_Invalid(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		records []model.UsageRecord
+	}{
+		{"empty batch", nil},
+		{"missing item", []model.UsageRecord{{IdempotencyKey: "a", Quantity: 1}}},
+		{"missing key", []model.UsageRecord{{SubscriptionItemID: 3, Quantity: 1}}},
+		{"negative quantity", []model.UsageRecord{{SubscriptionItemID: 3, IdempotencyKey: "a", Quantity: -1}}},
+		{"unknown action", []model.UsageRecord{{SubscriptionItemID: 3, IdempotencyKey: "a", Quantity: 1, Action: "decrement"}}},
+		{"future timestamp", []model.UsageRecord{{SubscriptionItemID: 3, IdempotencyKey: "a", Quantity: 1, Timestamp: now.Add(time.Hour)}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			svc := NewBillingService(mocks.NewMockInvoiceStore(ctrl), WithUsage(mocks.NewMockUsageStore(ctrl)),
+				WithClock(func() time.Time { return now }))
+
+			_, err := svc.RecordUsage(context.Background(), Scope{UserID: "user-1"}, tt.records)
+			require.ErrorIs(t, err, ErrInvalidUsage)
+		})
+	}
+}
+
+func TestBillingService_RecordUsage(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	ctrl := gomock.NewController(t)
+	usage := mocks.NewMockUsageStore(ctrl)
+	svc := NewBillingService(mocks.NewMockInvoiceStore(ctrl), WithUsage(usage), WithClock(func() time.Time { return now }))
+
+	at := now.Add(-time.Minute).Add(1500 * time.Nanosecond)
+	usage.EXPECT().RecordUsage(gomock.Any(), repository.InvoiceScope{UserID: "user-1"}, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ repository.InvoiceScope, records []model.UsageRecord) (int, error) {
+			require.Equal(t, []model.UsageRecord{
+				{SubscriptionItemID: 3, IdempotencyKey: "a", Quantity: 5, Action: model.UsageIncrement, Timestamp: at.Truncate(time.Microsecond)},
+				{SubscriptionItemID: 3, IdempotencyKey: "b", Quantity: 40, Action: model.UsageSet, Timestamp: now},
+				{SubscriptionItemID: 4, IdempotencyKey: "a", Quantity: 1, Action: model.UsageIncrement, Timestamp: now},
+			}, records)
+			// "b" ya se había recibido en un lote anterior.
+			return 2, nil
+		})
+
+	res, err := svc.RecordUsage(context.Background(), Scope{UserID: "user-1"}, []model.UsageRecord{
+		{SubscriptionItemID: 3, IdempotencyKey: "a", Quantity: 5, Timestamp: at},
+		{SubscriptionItemID: 3, IdempotencyKey: "a", Quantity: 7},
+		{SubscriptionItemID: 3, IdempotencyKey: "b", Quantity: 40, Action: model.UsageSet},
+		{SubscriptionItemID: 4, IdempotencyKey: "a", Quantity: 1},
+	})
+	require.NoError(t, err)
+	require.Equal(t, &UsageResult{Accepted: 2, Duplicates: 2}, res)
+}
+
+func TestBillingService_AddSubscriptionItem(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	base := &model.Price{ID: 7, Currency: "USD", Interval: model.IntervalMonth, IntervalCount: 1, UsageType: model.UsageLicensed, Active: true}
+	tests := []struct {
+		name  string
+		price *model.Price
+		err   error
+	}{
+		{"licensed price", &model.Price{ID: 8, Currency: "USD", Interval: model.IntervalMonth, IntervalCount: 1,
+			UsageType: model.UsageLicensed, Active: true}, ErrInvalidUsage},
+		{"other interval", &model.Price{ID: 8, Currency: "USD", Interval: model.IntervalYear, IntervalCount: 1,
+			UsageType: model.UsageMetered, AggregateUsage: stringPtr(model.AggregateSum), Active: true}, ErrInvalidUsage},
+		{"archived price", &model.Price{ID: 8, Currency: "USD", Interval: model.IntervalMonth, IntervalCount: 1,
+			UsageType: model.UsageMetered, AggregateUsage: stringPtr(model.AggregateSum)}, ErrPriceUnavailable},
+		{"metered price", &model.Price{ID: 8, Currency: "USD", Interval: model.IntervalMonth, IntervalCount: 1,
+			UsageType: model.UsageMetered, AggregateUsage: stringPtr(model.AggregateSum), Active: true}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			subs := mocks.NewMockSubscriptionStore(ctrl)
+			prices := mocks.NewMockCatalogStore(ctrl)
+			usage := mocks.NewMockUsageStore(ctrl)
+			svc := NewBillingService(mocks.NewMockInvoiceStore(ctrl), WithPrices(prices), WithSubscriptions(subs), WithUsage(usage),
+				WithClock(func() time.Time { return now }))
+
+			subs.EXPECT().GetSubscription(gomock.Any(), repository.InvoiceScope{UserID: "user-1"}, 3).Return(
+				&model.Subscription{ID: 3, UserID: "user-1", PriceID: 7, Quantity: 1, Status: model.SubscriptionActive}, nil)
+			prices.EXPECT().GetPrice(gomock.Any(), 7).Return(base, nil)
+			prices.EXPECT().GetPrice(gomock.Any(), 8).Return(tt.price, nil)
+			if tt.err == nil {
+				usage.EXPECT().CreateSubscriptionItem(gomock.Any(), repository.InvoiceScope{UserID: "user-1"}, gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ repository.InvoiceScope, item *model.SubscriptionItem) (bool, error) {
+						require.Equal(t, model.SubscriptionItem{SubscriptionID: 3, PriceID: 8, CreatedAt: now}, *item)
+						item.ID = 12
+						return true, nil
+					})
+			}
+
+			item, err := svc.AddSubscriptionItem(context.Background(), Scope{UserID: "user-1"}, 3, 8)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, 12, item.ID)
+		})
+	}
+}
+
+func TestRenewSubscriptions_BillsUsage(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	ctrl := gomock.NewController(t)
+	invoices := mocks.NewMockInvoiceStore(ctrl)
+	subs := mocks.NewMockSubscriptionStore(ctrl)
+	prices := mocks.NewMockCatalogStore(ctrl)
+	usage := mocks.NewMockUsageStore(ctrl)
+	svc := NewBillingService(invoices, WithPrices(prices), WithSubscriptions(subs), WithUsage(usage),
+		WithClock(func() time.Time { return now }))
+
+	start := time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)
+	sub := &model.Subscription{ID: 3, UserID: "user-1", PriceID: 7, Quantity: 1, Status: model.SubscriptionActive,
+		CurrentPeriodStart: start, CurrentPeriodEnd: now, BillingCycleAnchor: start}
+	previous := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	last := func(day int) *time.Time {
+		at := time.Date(2024, time.January, day, 0, 0, 0, 0, time.UTC)
+		return &at
+	}
+
+	gomock.InOrder(
+		subs.EXPECT().UnbilledSubscriptions(gomock.Any(), renewalBatch).Return([]*model.Subscription{sub}, nil),
+		subs.EXPECT().DueSubscriptions(gomock.Any(), now, renewalBatch).Return(nil, nil),
+		subs.EXPECT().UnbilledSubscriptions(gomock.Any(), renewalBatch).Return(nil, nil),
+	)
+	prices.EXPECT().GetPrice(gomock.Any(), 7).Return(&model.Price{ID: 7, Currency: "USD", UnitAmountCents: 1000,
+		Interval: model.IntervalMonth, IntervalCount: 1, UsageType: model.UsageLicensed}, nil)
+	prices.EXPECT().GetPrice(gomock.Any(), 8).Return(&model.Price{ID: 8, Currency: "USD", UnitAmountCents: 2,
+		Interval: model.IntervalMonth, IntervalCount: 1, UsageType: model.UsageMetered, AggregateUsage: stringPtr(model.AggregateSum)}, nil)
+	prices.EXPECT().GetPrice(gomock.Any(), 9).Return(&model.Price{ID: 9, Currency: "USD", UnitAmountCents: 10,
+		Interval: model.IntervalMonth, IntervalCount: 1, UsageType: model.UsageMetered, AggregateUsage: stringPtr(model.AggregateLastDuringPeriod)}, nil)
+	gomock.InOrder(
+		usage.EXPECT().CloseUsage(gomock.Any(), 3, start).Return([]*model.SubscriptionItem{
+			{ID: 21, SubscriptionID: 3, PriceID: 8, BilledThrough: previous},
+			{ID: 22, SubscriptionID: 3, PriceID: 9, BilledThrough: previous},
+		}, nil),
+		usage.EXPECT().UsageRollups(gomock.Any(), 3, start).Return([]model.UsageRollup{
+			{SubscriptionItemID: 21, Quantity: 300, LastRecordedAt: last(5)},
+			{SubscriptionItemID: 21, Quantity: 200, LastRecordedAt: last(20)},
+			{SubscriptionItemID: 22, Quantity: 40, LastRecordedAt: last(30)},
+			{SubscriptionItemID: 22, Quantity: 70, LastRecordedAt: last(10)},
+		}, nil),
+	)
+	subs.EXPECT().PendingInvoiceItems(gomock.Any(), 3).Return(nil, nil)
+	invoices.EXPECT().CreateInvoice(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, inv *model.Invoice) error {
+		require.Len(t, inv.Lines, 3)
+		require.Equal(t, 500, inv.Lines[1].Quantity)
+		require.Equal(t, int64(1000), inv.Lines[1].AmountCents)
+		require.Equal(t, 21, *inv.Lines[1].SubscriptionItemID)
+		require.Equal(t, previous, *inv.Lines[1].PeriodStart)
+		require.Equal(t, start, *inv.Lines[1].PeriodEnd)
+		require.Equal(t, 40, inv.Lines[2].Quantity)
+		require.Equal(t, int64(400), inv.Lines[2].AmountCents)
+		require.Equal(t, int64(2400), inv.SubtotalCents)
+		return nil
+	})
+
+	res, err := svc.RenewSubscriptions(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, RenewalResult{Invoiced: 1}, res)
+}
@@ -1,5 +1,5 @@
 -- Create invoices table
-CREATE TABLE invoices (
+CREATE TABLE IF NOT EXISTS invoices (
     id SERIAL PRIMARY KEY,
     user_id UUID NOT NULL,
     amount_cents BIGINT NOT NULL,
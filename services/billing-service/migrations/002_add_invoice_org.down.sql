@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_invoices_org_created_at;
+
+ALTER TABLE invoices DROP COLUMN IF EXISTS org_id;
@@ -0,0 +1,4 @@
+-- Facturas de una organización: org_id NULL son facturas personales del usuario
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS org_id UUID NULL;
+
+CREATE INDEX IF NOT EXISTS idx_invoices_org_created_at ON invoices (org_id, created_at DESC);
@@ -0,0 +1,5 @@
+-- Pares clave/valor libres de las integraciones (p. ej. el id del CRM)
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+-- Filtro del listado: metadata @> '{"clave": "valor"}'
+CREATE INDEX IF NOT EXISTS idx_invoices_metadata ON invoices USING gin (metadata jsonb_path_ops);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_invoices_price;
+ALTER TABLE invoices DROP COLUMN IF EXISTS quantity;
+ALTER TABLE invoices DROP COLUMN IF EXISTS price_id;
+
+DROP TABLE IF EXISTS prices;
+DROP TABLE IF EXISTS products;
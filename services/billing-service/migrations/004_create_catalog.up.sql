@@ -0,0 +1,36 @@
+-- Catálogo: qué se vende (products) y a qué precio y cada cuánto (prices)
+CREATE TABLE IF NOT EXISTS products (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Un precio no se borra: se archiva (active = false). interval_count es 0 en
+-- los precios de pago único.
+CREATE TABLE IF NOT EXISTS prices (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products (id),
+    currency VARCHAR(3) NOT NULL,
+    unit_amount_cents BIGINT NOT NULL CHECK (unit_amount_cents >= 0),
+    billing_interval VARCHAR(10) NOT NULL CHECK (billing_interval IN ('one_time', 'day', 'week', 'month', 'year')),
+    interval_count INT NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    lookup_key VARCHAR(200) NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((billing_interval = 'one_time') = (interval_count = 0))
+);
+
+CREATE INDEX IF NOT EXISTS idx_prices_product ON prices (product_id);
+
+-- Una lookup key apunta a un único precio activo; archivarlo la libera.
+CREATE UNIQUE INDEX IF NOT EXISTS prices_active_lookup_key_key ON prices (lookup_key) WHERE active AND lookup_key IS NOT NULL;
+
+-- Facturas creadas a partir de un precio del catálogo
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS price_id INT NULL REFERENCES prices (id);
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS quantity INT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_invoices_price ON invoices (price_id) WHERE price_id IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_subscriptions_price;
+DROP INDEX IF EXISTS idx_subscriptions_org;
+DROP INDEX IF EXISTS idx_subscriptions_user;
+
+DROP TABLE IF EXISTS subscriptions;
@@ -0,0 +1,24 @@
+-- Suscripciones: un cliente (usuario u organización, igual que en invoices)
+-- paga un precio recurrente del catálogo. Los estados y sus transiciones los
+-- valida BillingService.
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    org_id UUID NULL,
+    price_id INT NOT NULL REFERENCES prices (id),
+    quantity INT NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('trialing', 'active', 'past_due', 'canceled', 'paused', 'incomplete')),
+    current_period_start TIMESTAMPTZ NOT NULL,
+    current_period_end TIMESTAMPTZ NOT NULL,
+    trial_end TIMESTAMPTZ NULL,
+    cancel_at_period_end BOOLEAN NOT NULL DEFAULT FALSE,
+    canceled_at TIMESTAMPTZ NULL,
+    ended_at TIMESTAMPTZ NULL,
+    paused_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user ON subscriptions (user_id, created_at DESC) WHERE org_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_subscriptions_org ON subscriptions (org_id, created_at DESC) WHERE org_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_subscriptions_price ON subscriptions (price_id);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_invoice_line_items_invoice;
+DROP TABLE IF EXISTS invoice_line_items;
+
+DROP INDEX IF EXISTS invoices_subscription_period_key;
+ALTER TABLE invoices DROP COLUMN IF EXISTS period_end;
+ALTER TABLE invoices DROP COLUMN IF EXISTS period_start;
+ALTER TABLE invoices DROP COLUMN IF EXISTS subscription_id;
+
+DROP INDEX IF EXISTS idx_subscriptions_period_end;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS billing_cycle_anchor;
@@ -0,0 +1,36 @@
+-- Los períodos se calculan desde el ancla (no desde el período anterior) para
+-- que una suscripción del 31 no quede corrida al 28 después de febrero.
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS billing_cycle_anchor TIMESTAMPTZ NULL;
+UPDATE subscriptions SET billing_cycle_anchor = current_period_start WHERE billing_cycle_anchor IS NULL;
+ALTER TABLE subscriptions ALTER COLUMN billing_cycle_anchor SET NOT NULL;
+
+-- Renovaciones pendientes: el worker busca por fin de período
+CREATE INDEX IF NOT EXISTS idx_subscriptions_period_end ON subscriptions (current_period_end)
+    WHERE status IN ('trialing', 'active', 'past_due');
+
+-- Facturas de un período de una suscripción. El índice único es lo que hace
+-- idempotente al worker: un período se factura una sola vez aunque corran
+-- dos réplicas o se reintente después de un crash.
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS subscription_id INT NULL REFERENCES subscriptions (id);
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS period_start TIMESTAMPTZ NULL;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS period_end TIMESTAMPTZ NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS invoices_subscription_period_key ON invoices (subscription_id, period_start)
+    WHERE subscription_id IS NOT NULL;
+
+-- Renglones de la factura
+CREATE TABLE IF NOT EXISTS invoice_line_items (
+    id SERIAL PRIMARY KEY,
+    invoice_id INT NOT NULL REFERENCES invoices (id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    description TEXT NOT NULL,
+    price_id INT NULL REFERENCES prices (id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    unit_amount_cents BIGINT NOT NULL,
+    amount_cents BIGINT NOT NULL,
+    period_start TIMESTAMPTZ NULL,
+    period_end TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_line_items_invoice ON invoice_line_items (invoice_id, position);
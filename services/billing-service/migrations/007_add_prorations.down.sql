@@ -0,0 +1,4 @@
+ALTER TABLE invoice_line_items DROP COLUMN IF EXISTS proration;
+
+DROP INDEX IF EXISTS idx_pending_invoice_items_subscription;
+DROP TABLE IF EXISTS pending_invoice_items;
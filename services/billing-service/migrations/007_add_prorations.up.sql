@@ -0,0 +1,22 @@
+-- Prorrateos pendientes: créditos y débitos de un cambio de plan que se suman
+-- a la próxima factura de la suscripción. invoice_id queda fijado cuando una
+-- factura los incluye.
+CREATE TABLE IF NOT EXISTS pending_invoice_items (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES subscriptions (id),
+    description TEXT NOT NULL,
+    price_id INT NULL REFERENCES prices (id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    unit_amount_cents BIGINT NOT NULL,
+    amount_cents BIGINT NOT NULL,
+    period_start TIMESTAMPTZ NULL,
+    period_end TIMESTAMPTZ NULL,
+    proration BOOLEAN NOT NULL DEFAULT FALSE,
+    invoice_id INT NULL REFERENCES invoices (id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_pending_invoice_items_subscription ON pending_invoice_items (subscription_id)
+    WHERE invoice_id IS NULL;
+
+ALTER TABLE invoice_line_items ADD COLUMN IF NOT EXISTS proration BOOLEAN NOT NULL DEFAULT FALSE;
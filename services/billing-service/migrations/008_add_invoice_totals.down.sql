@@ -0,0 +1,8 @@
+ALTER TABLE invoices DROP COLUMN IF EXISTS amount_due_cents;
+ALTER TABLE invoices DROP COLUMN IF EXISTS total_cents;
+ALTER TABLE invoices DROP COLUMN IF EXISTS total_tax_cents;
+ALTER TABLE invoices DROP COLUMN IF EXISTS total_discount_cents;
+ALTER TABLE invoices DROP COLUMN IF EXISTS subtotal_cents;
+
+ALTER TABLE invoice_line_items DROP COLUMN IF EXISTS tax_amount_cents;
+ALTER TABLE invoice_line_items DROP COLUMN IF EXISTS discount_amount_cents;
@@ -0,0 +1,34 @@
+-- Descuentos e impuestos por renglón; los totales de la factura se calculan
+-- a partir de los renglones al crearla.
+ALTER TABLE invoice_line_items ADD COLUMN IF NOT EXISTS discount_amount_cents BIGINT NOT NULL DEFAULT 0
+    CHECK (discount_amount_cents >= 0);
+ALTER TABLE invoice_line_items ADD COLUMN IF NOT EXISTS tax_amount_cents BIGINT NOT NULL DEFAULT 0
+    CHECK (tax_amount_cents >= 0);
+
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS subtotal_cents BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS total_discount_cents BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS total_tax_cents BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS total_cents BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS amount_due_cents BIGINT NOT NULL DEFAULT 0;
+
+-- Las facturas anteriores tienen solo amount_cents: pasa a ser un único
+-- renglón sin descuentos ni impuestos.
+INSERT INTO invoice_line_items (invoice_id, position, description, price_id, quantity, unit_amount_cents, amount_cents,
+    period_start, period_end)
+SELECT i.id, 0, 'Invoice amount', i.price_id, COALESCE(i.quantity, 1),
+    CASE WHEN i.quantity IS NULL THEN i.amount_cents ELSE i.amount_cents / i.quantity END, i.amount_cents,
+    i.period_start, i.period_end
+FROM invoices i
+WHERE NOT EXISTS (SELECT 1 FROM invoice_line_items l WHERE l.invoice_id = i.id);
+
+UPDATE invoices i SET
+    subtotal_cents = t.subtotal,
+    total_discount_cents = t.discount,
+    total_tax_cents = t.tax,
+    total_cents = t.subtotal - t.discount + t.tax,
+    amount_due_cents = GREATEST(t.subtotal - t.discount + t.tax, 0)
+FROM (
+    SELECT invoice_id, SUM(amount_cents) AS subtotal, SUM(discount_amount_cents) AS discount, SUM(tax_amount_cents) AS tax
+    FROM invoice_line_items GROUP BY invoice_id
+) t
+WHERE t.invoice_id = i.id;
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS invoices_number_key;
+ALTER TABLE invoices DROP CONSTRAINT IF EXISTS invoices_status_check;
+ALTER TABLE invoices ALTER COLUMN status SET DEFAULT 'pending';
+UPDATE invoices SET status = 'pending' WHERE status IN ('draft', 'open');
+
+ALTER TABLE invoices DROP COLUMN IF EXISTS marked_uncollectible_at;
+ALTER TABLE invoices DROP COLUMN IF EXISTS voided_at;
+ALTER TABLE invoices DROP COLUMN IF EXISTS paid_at;
+ALTER TABLE invoices DROP COLUMN IF EXISTS finalized_at;
+ALTER TABLE invoices DROP COLUMN IF EXISTS number;
+
+DROP SEQUENCE IF EXISTS invoice_number_seq;
@@ -0,0 +1,28 @@
+-- Estados de la factura: draft (editable), open (emitida, con número), paid,
+-- void y uncollectible. Cada transición guarda cuándo ocurrió.
+CREATE SEQUENCE IF NOT EXISTS invoice_number_seq;
+
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS number VARCHAR(32) NULL;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS finalized_at TIMESTAMPTZ NULL;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS paid_at TIMESTAMPTZ NULL;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS voided_at TIMESTAMPTZ NULL;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS marked_uncollectible_at TIMESTAMPTZ NULL;
+
+-- Las facturas anteriores ('pending') ya se habían emitido: quedan open y se
+-- numeran en orden de creación.
+UPDATE invoices SET status = 'open', finalized_at = created_at
+WHERE status NOT IN ('draft', 'open', 'paid', 'void', 'uncollectible');
+
+WITH numbered AS (
+    SELECT id, row_number() OVER (ORDER BY id) AS n FROM invoices WHERE number IS NULL AND status <> 'draft'
+)
+UPDATE invoices i SET number = 'INV-' || lpad(numbered.n::text, 6, '0')
+FROM numbered WHERE i.id = numbered.id;
+
+SELECT setval('invoice_number_seq', GREATEST(count(*), 1), count(*) > 0) FROM invoices WHERE number IS NOT NULL;
+
+ALTER TABLE invoices ALTER COLUMN status SET DEFAULT 'draft';
+ALTER TABLE invoices ADD CONSTRAINT invoices_status_check
+    CHECK (status IN ('draft', 'open', 'paid', 'void', 'uncollectible'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS invoices_number_key ON invoices (number) WHERE number IS NOT NULL;
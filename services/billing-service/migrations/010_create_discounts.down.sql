@@ -0,0 +1,5 @@
+ALTER TABLE invoices DROP COLUMN IF EXISTS discount_id;
+
+DROP TABLE IF EXISTS discounts;
+DROP TABLE IF EXISTS promotion_codes;
+DROP TABLE IF EXISTS coupons;
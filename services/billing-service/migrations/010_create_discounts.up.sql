@@ -0,0 +1,63 @@
+-- Cupones: un descuento porcentual o de un monto fijo en una moneda. Los
+-- términos (descuento y duración) no cambian; para otra promoción se crea otro
+-- cupón. times_redeemed lo incrementa cada canje, con el límite en el mismo UPDATE.
+CREATE TABLE IF NOT EXISTS coupons (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    percent_off INT NULL CHECK (percent_off BETWEEN 1 AND 100),
+    amount_off_cents BIGINT NULL CHECK (amount_off_cents > 0),
+    currency VARCHAR(3) NULL,
+    duration VARCHAR(10) NOT NULL CHECK (duration IN ('once', 'repeating', 'forever')),
+    duration_in_months INT NULL,
+    max_redemptions INT NULL CHECK (max_redemptions > 0),
+    times_redeemed INT NOT NULL DEFAULT 0,
+    redeem_by TIMESTAMPTZ NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((percent_off IS NULL) <> (amount_off_cents IS NULL)),
+    CHECK ((amount_off_cents IS NULL) = (currency IS NULL)),
+    CHECK ((duration = 'repeating') = (duration_in_months IS NOT NULL))
+);
+
+-- Códigos promocionales: lo que escribe el cliente. Cada uno canjea un cupón,
+-- con sus propios límites y restricciones. El código no distingue mayúsculas.
+CREATE TABLE IF NOT EXISTS promotion_codes (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL,
+    coupon_id INT NOT NULL REFERENCES coupons (id),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    max_redemptions INT NULL CHECK (max_redemptions > 0),
+    times_redeemed INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NULL,
+    first_time_transaction BOOLEAN NOT NULL DEFAULT FALSE,
+    minimum_amount_cents BIGINT NULL CHECK (minimum_amount_cents > 0),
+    minimum_amount_currency VARCHAR(3) NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((minimum_amount_cents IS NULL) = (minimum_amount_currency IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS promotion_codes_code_key ON promotion_codes (lower(code));
+CREATE INDEX IF NOT EXISTS idx_promotion_codes_coupon ON promotion_codes (coupon_id);
+
+-- Descuentos aplicados: un canje de un cupón sobre una suscripción o una
+-- factura. Rige desde start_at hasta end_at (NULL: mientras no se use, en los
+-- de una vez, o para siempre).
+CREATE TABLE IF NOT EXISTS discounts (
+    id SERIAL PRIMARY KEY,
+    coupon_id INT NOT NULL REFERENCES coupons (id),
+    promotion_code_id INT NULL REFERENCES promotion_codes (id),
+    subscription_id INT NULL REFERENCES subscriptions (id),
+    invoice_id INT NULL REFERENCES invoices (id),
+    start_at TIMESTAMPTZ NOT NULL,
+    end_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((subscription_id IS NULL) <> (invoice_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_discounts_subscription ON discounts (subscription_id, start_at) WHERE subscription_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS discounts_invoice_key ON discounts (invoice_id) WHERE invoice_id IS NOT NULL;
+
+-- Descuento que se aplicó en cada factura
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS discount_id INT NULL REFERENCES discounts (id);
@@ -0,0 +1,12 @@
+ALTER TABLE invoice_line_items DROP CONSTRAINT IF EXISTS invoice_line_items_quantity_check;
+ALTER TABLE invoice_line_items ALTER COLUMN quantity TYPE INT;
+ALTER TABLE invoice_line_items ADD CONSTRAINT invoice_line_items_quantity_check CHECK (quantity > 0) NOT VALID;
+ALTER TABLE invoice_line_items DROP COLUMN IF EXISTS subscription_item_id;
+
+DROP TABLE IF EXISTS usage_rollups;
+DROP TABLE IF EXISTS usage_records;
+DROP TABLE IF EXISTS subscription_items;
+
+ALTER TABLE prices DROP CONSTRAINT IF EXISTS prices_usage_check;
+ALTER TABLE prices DROP COLUMN IF EXISTS aggregate_usage;
+ALTER TABLE prices DROP COLUMN IF EXISTS usage_type;
@@ -0,0 +1,53 @@
+-- Precios medidos (metered): se cobran por el uso reportado en el período,
+-- agregado según aggregate_usage. Solo los precios recurrentes pueden serlo.
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS usage_type VARCHAR(10) NOT NULL DEFAULT 'licensed'
+    CHECK (usage_type IN ('licensed', 'metered'));
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS aggregate_usage VARCHAR(20) NULL
+    CHECK (aggregate_usage IN ('sum', 'max', 'last_during_period'));
+ALTER TABLE prices DROP CONSTRAINT IF EXISTS prices_usage_check;
+ALTER TABLE prices ADD CONSTRAINT prices_usage_check
+    CHECK ((usage_type = 'metered') = (aggregate_usage IS NOT NULL) AND (usage_type = 'licensed' OR billing_interval <> 'one_time'));
+
+-- Un ítem es un precio medido dentro de una suscripción. El uso anterior a
+-- usage_closed_at ya no se acepta (la renovación lo cierra antes de leerlo) y
+-- el anterior a billed_through ya está facturado.
+CREATE TABLE IF NOT EXISTS subscription_items (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES subscriptions (id),
+    price_id INT NOT NULL REFERENCES prices (id),
+    usage_closed_at TIMESTAMPTZ NOT NULL,
+    billed_through TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (subscription_id, price_id)
+);
+
+-- Registros de uso tal como llegan; la clave de idempotencia evita contar dos
+-- veces un reintento.
+CREATE TABLE IF NOT EXISTS usage_records (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_item_id INT NOT NULL REFERENCES subscription_items (id),
+    idempotency_key VARCHAR(255) NOT NULL,
+    quantity BIGINT NOT NULL CHECK (quantity >= 0),
+    action VARCHAR(10) NOT NULL CHECK (action IN ('increment', 'set')),
+    recorded_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (subscription_item_id, idempotency_key)
+);
+
+-- Uso agregado por ítem y hora (cortando también en los bordes de período),
+-- actualizado al ingerir: facturar lee estas filas y no los registros.
+CREATE TABLE IF NOT EXISTS usage_rollups (
+    subscription_item_id INT NOT NULL REFERENCES subscription_items (id),
+    bucket_start TIMESTAMPTZ NOT NULL,
+    quantity BIGINT NOT NULL DEFAULT 0,
+    set_at TIMESTAMPTZ NULL,
+    last_recorded_at TIMESTAMPTZ NULL,
+    record_count INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (subscription_item_id, bucket_start)
+);
+
+-- Renglones de uso: la cantidad puede ser 0 y superar un INT.
+ALTER TABLE invoice_line_items ADD COLUMN IF NOT EXISTS subscription_item_id INT NULL REFERENCES subscription_items (id);
+ALTER TABLE invoice_line_items DROP CONSTRAINT IF EXISTS invoice_line_items_quantity_check;
+ALTER TABLE invoice_line_items ALTER COLUMN quantity TYPE BIGINT;
+ALTER TABLE invoice_line_items ADD CONSTRAINT invoice_line_items_quantity_check CHECK (quantity >= 0);
@@ -0,0 +1,5 @@
+ALTER TABLE prices DROP CONSTRAINT IF EXISTS prices_billing_scheme_check;
+ALTER TABLE prices DROP COLUMN IF EXISTS tiers;
+ALTER TABLE prices DROP COLUMN IF EXISTS tiers_mode;
+ALTER TABLE prices DROP COLUMN IF EXISTS package_size;
+ALTER TABLE prices DROP COLUMN IF EXISTS billing_scheme;
@@ -0,0 +1,15 @@
+-- Esquemas de cobro: per_unit (unit_amount_cents por unidad), package
+-- (unit_amount_cents por cada package_size unidades, redondeando hacia arriba)
+-- y tiered (los tramos de tiers, graduated o volume). Los tramos son un array
+-- JSON de {up_to, unit_amount_cents, flat_amount_cents}; como el resto de los
+-- términos, no cambian una vez usado el precio.
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS billing_scheme VARCHAR(10) NOT NULL DEFAULT 'per_unit'
+    CHECK (billing_scheme IN ('per_unit', 'package', 'tiered'));
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS package_size BIGINT NULL CHECK (package_size > 0);
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS tiers_mode VARCHAR(10) NULL CHECK (tiers_mode IN ('graduated', 'volume'));
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS tiers JSONB NULL;
+ALTER TABLE prices DROP CONSTRAINT IF EXISTS prices_billing_scheme_check;
+ALTER TABLE prices ADD CONSTRAINT prices_billing_scheme_check
+    CHECK ((billing_scheme = 'package') = (package_size IS NOT NULL)
+        AND (billing_scheme = 'tiered') = (tiers_mode IS NOT NULL)
+        AND (billing_scheme = 'tiered') = (tiers IS NOT NULL));
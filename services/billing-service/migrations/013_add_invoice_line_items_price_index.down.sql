@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_invoice_line_items_price;
@@ -0,0 +1,2 @@
+-- Para saber rápido si un precio ya se facturó en algún renglón (ver priceUsed).
+CREATE INDEX IF NOT EXISTS idx_invoice_line_items_price ON invoice_line_items (price_id) WHERE price_id IS NOT NULL;
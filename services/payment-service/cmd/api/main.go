@@ -0,0 +1,12 @@
+package main
+
+import (
+	"log"
+	"saas-subscription-platform/services/payment-service/internal/server"
+)
+
+func main() {
+	if err := server.Run(); err != nil {
+		log.Fatalf("failed to start server: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Package client es el cliente HTTP de billing-service: lee la factura que se
+// quiere cobrar y avisa cuando se pagó.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"saas-subscription-platform/libs/trace"
+)
+
+// callerID identifica a payment-service ante los endpoints /internal de billing-service.
+const callerID = "payment-service"
+
+var (
+	// ErrInvoiceNotFound: la factura no existe o no es del dueño indicado.
+	ErrInvoiceNotFound = errors.New("invoice not found")
+	// ErrInvoiceRejected: billing no acepta el pago (la factura se anuló o el
+	// monto no coincide). Reintentar no lo arregla.
+	ErrInvoiceRejected = errors.New("invoice rejected the payment")
+)
+
+// Caller es el usuario del request, con su organización activa; se reenvía a
+// billing-service para que aplique sus propios permisos.
+type Caller struct {
+	UserID  string
+	OrgID   string
+	OrgRole string
+}
+
+// Invoice es lo que payment-service necesita de una factura.
+type Invoice struct {
+	ID             int     `json:"id"`
+	UserID         string  `json:"user_id"`
+	OrgID          *string `json:"org_id"`
+	Status         string  `json:"status"`
+	AmountDueCents int64   `json:"amount_due_cents"`
+	Currency       string  `json:"currency"`
+}
+
+// Payment es el aviso de cobro: el dueño de la factura, el intent que la pagó
+// y lo que se cobró.
+type Payment struct {
+	UserID          string `json:"user_id"`
+	OrgID           string `json:"org_id,omitempty"`
+	PaymentIntentID int    `json:"payment_intent_id"`
+	AmountCents     int64  `json:"amount_cents"`
+	Currency        string `json:"currency"`
+}
+
+type BillingClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewBillingClient(baseURL string) *BillingClient {
+	return &BillingClient{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// GetInvoice lee la factura como la vería caller. Devuelve nil si no existe
+// o caller no la puede ver.
+func (c *BillingClient) GetInvoice(ctx context.Context, caller Caller, id int) (*Invoice, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/invoices/"+strconv.Itoa(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Internal-User-ID", caller.UserID)
+	if caller.OrgID != "" {
+		req.Header.Set("X-Internal-Org-ID", caller.OrgID)
+		req.Header.Set("X-Internal-Org-Role", caller.OrgRole)
+	}
+
+	body, status, err := c.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusForbidden:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("GET /invoices/%d: unexpected status %d", id, status)
+	}
+	var invoice Invoice
+	if err := json.Unmarshal(body, &invoice); err != nil {
+		return nil, fmt.Errorf("failed to decode invoice: %w", err)
+	}
+	return &invoice, nil
+}
+
+// MarkInvoicePaid avisa a billing-service que se cobró la factura. Es
+// idempotente del lado de billing: repetir el aviso de una factura paga no
+// falla.
+func (c *BillingClient) MarkInvoicePaid(ctx context.Context, id int, p Payment) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	path := "/internal/invoices/" + strconv.Itoa(id) + "/pay"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Internal-User-ID", callerID)
+	req.Header.Set("Content-Type", "application/json")
+
+	body, status, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrInvoiceNotFound
+	case http.StatusConflict:
+		return fmt.Errorf("%w: %s", ErrInvoiceRejected, bytes.TrimSpace(body))
+	}
+	return fmt.Errorf("POST %s: unexpected status %d", path, status)
+}
+
+func (c *BillingClient) do(ctx context.Context, req *http.Request) ([]byte, int, error) {
+	trace.InjectHeaders(ctx, req)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, 0, err
+	}
+	return body, resp.StatusCode, nil
+}
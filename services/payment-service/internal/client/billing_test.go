@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBillingClient_GetInvoice_ForwardsCaller(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/invoices/12", r.URL.Path)
+		require.Equal(t, "user-1", r.Header.Get("X-Internal-User-ID"))
+		require.Equal(t, "org-1", r.Header.Get("X-Internal-Org-ID"))
+		require.Equal(t, "billing", r.Header.Get("X-Internal-Org-Role"))
+		_, _ = w.Write([]byte(`{"id": 12, "status": "open", "amount_due_cents": 1500, "currency": "USD"}`))
+	}))
+	defer srv.Close()
+
+	invoice, err := NewBillingClient(srv.URL).GetInvoice(context.Background(), Caller{UserID: "user-1", OrgID: "org-1", OrgRole: "billing"}, 12)
+	require.NoError(t, err)
+	require.Equal(t, &Invoice{ID: 12, Status: "open", AmountDueCents: 1500, Currency: "USD"}, invoice)
+}
+
+func TestBillingClient_MarkInvoicePaid(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr error
+	}{
+		{"paid", http.StatusOK, nil},
+		{"voided invoice", http.StatusConflict, ErrInvoiceRejected},
+		{"missing invoice", http.StatusNotFound, ErrInvoiceNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/internal/invoices/12/pay", r.URL.Path)
+				require.Equal(t, callerID, r.Header.Get("X-Internal-User-ID"))
+				var p Payment
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+				require.Equal(t, Payment{UserID: "user-1", PaymentIntentID: 3, AmountCents: 1500, Currency: "USD"}, p)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			err := NewBillingClient(srv.URL).MarkInvoicePaid(context.Background(), 12,
+				Payment{UserID: "user-1", PaymentIntentID: 3, AmountCents: 1500, Currency: "USD"})
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+type Config struct {
+	HTTPAddr string
+	DBDSN    string
+	// QueryTimeout corta cualquier query que tarde más, aunque el request siga vivo.
+	QueryTimeout time.Duration
+	// SlowQueryThreshold es a partir de cuánto se loguea una query como lenta.
+	SlowQueryThreshold time.Duration
+	// MigrateOnBoot aplica las migraciones pendientes al arrancar.
+	MigrateOnBoot bool
+	// BillingServiceURL es de donde salen las facturas a cobrar y a quien se
+	// avisa cuando se pagan.
+	BillingServiceURL string
+	// Provider es la pasarela de pagos; por ahora solo "fake".
+	Provider string
+	// InvoiceSyncInterval es cada cuánto el worker reintenta avisar a billing
+	// los cobros que no se pudieron registrar.
+	InvoiceSyncInterval time.Duration
+}
+
+func Load() Config {
+	return Config{
+		HTTPAddr:            getEnv("PAYMENT_HTTP_ADDR", ":8084"),
+		DBDSN:               getEnv("PAYMENT_DB_DSN", ""),
+		QueryTimeout:        getDuration("PAYMENT_DB_QUERY_TIMEOUT", 5*time.Second),
+		SlowQueryThreshold:  getDuration("PAYMENT_DB_SLOW_QUERY_THRESHOLD", 500*time.Millisecond),
+		MigrateOnBoot:       getBool("PAYMENT_MIGRATE_ON_BOOT", false),
+		BillingServiceURL:   getEnv("BILLING_SERVICE_URL", "http://localhost:8083"),
+		Provider:            getEnv("PAYMENT_PROVIDER", "fake"),
+		InvoiceSyncInterval: getDuration("PAYMENT_INVOICE_SYNC_INTERVAL", 30*time.Second),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return fallback
+}
+
+func getBool(key string, fallback bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"saas-subscription-platform/services/payment-service/internal/model"
+	"saas-subscription-platform/services/payment-service/internal/provider"
+	"saas-subscription-platform/services/payment-service/internal/service"
+)
+
+type PaymentHandler struct {
+	service *service.PaymentService
+}
+
+func NewPaymentHandler(service *service.PaymentService) *PaymentHandler {
+	return &PaymentHandler{service: service}
+}
+
+// requestScope lee el usuario y la organización activa de los headers internos del gateway.
+func requestScope(r *http.Request) service.Scope {
+	return service.Scope{
+		UserID:  r.Header.Get("X-Internal-User-ID"),
+		OrgID:   r.Header.Get("X-Internal-Org-ID"),
+		OrgRole: r.Header.Get("X-Internal-Org-Role"),
+	}
+}
+
+// pathID lee el {id} del path; si no es un número responde 400.
+func pathID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid payment intent id"))
+		return 0, false
+	}
+	return id, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writePaymentError traduce los errores del servicio; los desconocidos son
+// 500 con fallback.
+func writePaymentError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(err.Error()))
+	case errors.Is(err, service.ErrInvalidIntent), errors.Is(err, service.ErrInvalidCard),
+		errors.Is(err, service.ErrInvoiceNotFound):
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+	case errors.Is(err, service.ErrInvoiceNotPayable), errors.Is(err, service.ErrInvoiceHasIntent),
+		errors.Is(err, service.ErrInvalidTransition):
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(err.Error()))
+	case errors.Is(err, service.ErrProviderUnavailable):
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte(err.Error()))
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(fallback))
+	}
+}
+
+// writeIntent responde el intent después de una operación, o 404 si no existe
+// en el scope. Si la pasarela acaba de rechazar la tarjeta va con 402, para
+// que el cliente no lo tome por cobrado.
+func writeIntent(w http.ResponseWriter, intent *model.PaymentIntent) {
+	switch {
+	case intent == nil:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("payment intent not found"))
+	case intent.Status == model.IntentRequiresPaymentMethod && intent.LastPaymentError != nil:
+		writeJSON(w, http.StatusPaymentRequired, intent)
+	default:
+		writeJSON(w, http.StatusOK, intent)
+	}
+}
+
+// CreateIntent crea un intent para una factura ({"invoice_id": 12}) o por un
+// monto libre ({"amount_cents": 1000, "currency": "USD"}); "capture_method"
+// es automatic (default) o manual.
+func (h *PaymentHandler) CreateIntent(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	var req struct {
+		InvoiceID     *int   `json:"invoice_id"`
+		AmountCents   int64  `json:"amount_cents"`
+		Currency      string `json:"currency"`
+		CaptureMethod string `json:"capture_method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+
+	intent, err := h.service.CreateIntent(r.Context(), scope, service.CreateIntentRequest{
+		InvoiceID:     req.InvoiceID,
+		AmountCents:   req.AmountCents,
+		Currency:      req.Currency,
+		CaptureMethod: req.CaptureMethod,
+	})
+	if err != nil {
+		writePaymentError(w, err, "failed to create payment intent")
+		return
+	}
+	writeJSON(w, http.StatusCreated, intent)
+}
+
+// ListIntents acepta ?invoice_id=, ?limit= y ?offset=.
+func (h *PaymentHandler) ListIntents(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	q := r.URL.Query()
+	var invoiceID *int
+	if raw := q.Get("invoice_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("invalid invoice_id"))
+			return
+		}
+		invoiceID = &id
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	offset, _ := strconv.Atoi(q.Get("offset"))
+
+	intents, err := h.service.ListIntents(r.Context(), scope, invoiceID, limit, offset)
+	if err != nil {
+		writePaymentError(w, err, "failed to list payment intents")
+		return
+	}
+	writeJSON(w, http.StatusOK, intents)
+}
+
+func (h *PaymentHandler) GetIntent(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	intent, err := h.service.GetIntent(r.Context(), scope, id)
+	switch {
+	case err != nil:
+		writePaymentError(w, err, "failed to get payment intent")
+	case intent == nil:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("payment intent not found"))
+	default:
+		writeJSON(w, http.StatusOK, intent)
+	}
+}
+
+// ConfirmIntent autoriza el cobro. Body: {"card": {"number": "4242424242424242",
+// "exp_month": 12, "exp_year": 2030, "cvc": "123"}}; para completar un 3DS
+// (status requires_action) va sin card.
+func (h *PaymentHandler) ConfirmIntent(w http.ResponseWriter, r *http.Request) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Card *provider.Card `json:"card"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid request payload"))
+		return
+	}
+
+	intent, err := h.service.ConfirmIntent(r.Context(), scope, id, req.Card)
+	if err != nil {
+		writePaymentError(w, err, "failed to confirm payment intent")
+		return
+	}
+	writeIntent(w, intent)
+}
+
+func (h *PaymentHandler) CaptureIntent(w http.ResponseWriter, r *http.Request) {
+	h.moveIntent(w, r, h.service.CaptureIntent, "failed to capture payment intent")
+}
+
+func (h *PaymentHandler) CancelIntent(w http.ResponseWriter, r *http.Request) {
+	h.moveIntent(w, r, h.service.CancelIntent, "failed to cancel payment intent")
+}
+
+// moveIntent ejecuta una operación sin body sobre el intent del path.
+func (h *PaymentHandler) moveIntent(w http.ResponseWriter, r *http.Request,
+	move func(ctx context.Context, scope service.Scope, id int) (*model.PaymentIntent, error), fallback string) {
+	scope := requestScope(r)
+	if scope.UserID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing internal user id"))
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	intent, err := move(r.Context(), scope, id)
+	if err != nil {
+		writePaymentError(w, err, fallback)
+		return
+	}
+	writeIntent(w, intent)
+}
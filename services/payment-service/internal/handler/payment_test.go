@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"saas-subscription-platform/services/payment-service/internal/model"
+	"saas-subscription-platform/services/payment-service/internal/provider"
+	"saas-subscription-platform/services/payment-service/internal/repository"
+	"saas-subscription-platform/services/payment-service/internal/service"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+// stubIntentStore guarda un intent y aplica las transiciones sin chequear el estado.
+type stubIntentStore struct {
+	service.IntentStore
+	intent *model.PaymentIntent
+}
+
+func (s *stubIntentStore) GetIntent(_ context.Context, _ repository.Scope, id int) (*model.PaymentIntent, error) {
+	if s.intent == nil || s.intent.ID != id {
+		return nil, nil
+	}
+	cp := *s.intent
+	return &cp, nil
+}
+
+func (s *stubIntentStore) TransitionIntent(_ context.Context, _ int, _ []string, u model.IntentUpdate) (*model.PaymentIntent, error) {
+	s.intent.Status = u.Status
+	if u.LastPaymentError != nil || u.ClearPaymentError {
+		s.intent.LastPaymentError = u.LastPaymentError
+	}
+	cp := *s.intent
+	return &cp, nil
+}
+
+func TestConfirmIntentHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		body string
+		want int
+	}{
+		{name: "authorized", id: "3", body: `{"card": {"number": "4242 4242 4242 4242", "exp_month": 12, "exp_year": 2099}}`,
+			want: http.StatusOK},
+		{name: "declined", id: "3", body: `{"card": {"number": "4000000000000002", "exp_month": 12, "exp_year": 2099}}`,
+			want: http.StatusPaymentRequired},
+		{name: "network error", id: "3", body: `{"card": {"number": "4000000000000119", "exp_month": 12, "exp_year": 2099}}`,
+			want: http.StatusBadGateway},
+		{name: "missing card", id: "3", want: http.StatusBadRequest},
+		{name: "unknown intent", id: "9", body: `{"card": {"number": "4242424242424242", "exp_month": 12, "exp_year": 2099}}`,
+			want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &stubIntentStore{intent: &model.PaymentIntent{ID: 3, UserID: "user-1", AmountCents: 1500, Currency: "USD",
+				Status: model.IntentRequiresPaymentMethod, CaptureMethod: model.CaptureManual, Provider: "fake"}}
+			h := NewPaymentHandler(service.NewPaymentService(store, nil, provider.NewFakeProvider()))
+
+			req := httptest.NewRequest(http.MethodPost, "/payment_intents/"+tt.id+"/confirm", bytes.NewBufferString(tt.body))
+			req.Header.Set("X-Internal-User-ID", "user-1")
+			req = mux.SetURLVars(req, map[string]string{"id": tt.id})
+			rr := httptest.NewRecorder()
+
+			h.ConfirmIntent(rr, req)
+
+			require.Equal(t, tt.want, rr.Code, rr.Body.String())
+		})
+	}
+}
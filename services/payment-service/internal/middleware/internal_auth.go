@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"saas-subscription-platform/libs/trace"
+)
+
+// InternalAuthMux exige el header interno X-Internal-User-ID (agregado por el
+// API Gateway) y deja el request_id en el contexto para los logs de queries.
+// Es el mismo middleware que el de billing-service.
+func InternalAuthMux(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Header.Get("X-Internal-User-ID")
+		if userID == "" {
+			http.Error(w, "missing internal user ID", http.StatusUnauthorized)
+			return
+		}
+		ctx := trace.ExtractAndUpdateContext(r.Context(), r, "payment-service")
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+var _ mux.MiddlewareFunc = InternalAuthMux
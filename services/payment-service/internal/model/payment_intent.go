@@ -0,0 +1,81 @@
+package model
+
+import "time"
+
+// Estados de un payment intent. Se crea en requires_payment_method; al
+// confirmarlo con una tarjeta pasa por processing mientras habla con la
+// pasarela y termina en requires_action (3DS), requires_capture (captura
+// manual), succeeded, o vuelve a requires_payment_method si la rechazan.
+// succeeded y canceled son terminales.
+const (
+	IntentRequiresPaymentMethod = "requires_payment_method"
+	IntentRequiresAction        = "requires_action"
+	IntentProcessing            = "processing"
+	IntentRequiresCapture       = "requires_capture"
+	IntentSucceeded             = "succeeded"
+	IntentCanceled              = "canceled"
+)
+
+// Formas de captura: automatic cobra al autorizar; manual deja el monto
+// reservado hasta que se capture o se cancele.
+const (
+	CaptureAutomatic = "automatic"
+	CaptureManual    = "manual"
+)
+
+type PaymentIntent struct {
+	ID     int    `json:"id"`
+	UserID string `json:"user_id"`
+	// OrgID es la organización que paga; nil en pagos personales.
+	OrgID *string `json:"org_id,omitempty"`
+	// InvoiceID es la factura de billing-service que se cobra, si la hay.
+	InvoiceID     *int   `json:"invoice_id,omitempty"`
+	AmountCents   int64  `json:"amount_cents"`
+	Currency      string `json:"currency"`
+	Status        string `json:"status"`
+	CaptureMethod string `json:"capture_method"`
+	// Provider y ProviderReference identifican la autorización en la pasarela.
+	Provider          string  `json:"provider"`
+	ProviderReference *string `json:"provider_reference,omitempty"`
+	// Attempts cuenta las confirmaciones; arma la clave de idempotencia de cada una.
+	Attempts int          `json:"-"`
+	Card     *PaymentCard `json:"card,omitempty"`
+	// LastPaymentError es el motivo del último rechazo o falla de la pasarela.
+	LastPaymentError *PaymentError `json:"last_payment_error,omitempty"`
+	SucceededAt      *time.Time    `json:"succeeded_at,omitempty"`
+	CanceledAt       *time.Time    `json:"canceled_at,omitempty"`
+	// InvoiceSyncedAt es cuándo billing-service registró el pago de la factura.
+	InvoiceSyncedAt  *time.Time `json:"invoice_synced_at,omitempty"`
+	InvoiceSyncError *string    `json:"invoice_sync_error,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// PaymentCard es lo único que se guarda de la tarjeta: nunca el número completo.
+type PaymentCard struct {
+	Brand string `json:"brand"`
+	Last4 string `json:"last4"`
+}
+
+// PaymentError es un rechazo de la pasarela (card_declined,
+// insufficient_funds...) o processing_error si no se la pudo contactar.
+type PaymentError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// IntentUpdate son los cambios de una transición de estado; los campos nil no
+// se tocan.
+type IntentUpdate struct {
+	Status            string
+	ProviderReference *string
+	Card              *PaymentCard
+	// LastPaymentError se guarda si no es nil; ClearPaymentError lo borra.
+	LastPaymentError  *PaymentError
+	ClearPaymentError bool
+	// Attempt suma una confirmación.
+	Attempt     bool
+	SucceededAt *time.Time
+	CanceledAt  *time.Time
+	UpdatedAt   time.Time
+}
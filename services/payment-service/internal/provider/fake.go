@@ -0,0 +1,125 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Tarjetas de prueba del FakeProvider. Cualquier otro número que pase el
+// chequeo de Luhn se autoriza; uno que no lo pase se rechaza con
+// incorrect_number.
+const (
+	CardSuccess           = "4242424242424242"
+	CardDeclined          = "4000000000000002"
+	CardInsufficientFunds = "4000000000009995"
+	CardRequires3DS       = "4000002500003155"
+	CardNetworkError      = "4000000000000119"
+)
+
+// Prefijos de las referencias del FakeProvider: la referencia dice en qué
+// estado quedó la autorización, así el fake no guarda nada entre llamadas.
+const (
+	fakeAuthorized = "fake_auth_"
+	fakePending3DS = "fake_3ds_"
+)
+
+// FakeProvider simula una pasarela según el número de tarjeta. Es
+// determinístico y no guarda estado, así que sirve para desarrollo, tests y
+// entornos con varias réplicas.
+type FakeProvider struct{}
+
+func NewFakeProvider() *FakeProvider { return &FakeProvider{} }
+
+func (p *FakeProvider) Name() string { return "fake" }
+
+func (p *FakeProvider) Authorize(_ context.Context, req AuthorizeRequest) (*Authorization, error) {
+	number := req.Card.Number
+	auth := &Authorization{CardBrand: cardBrand(number), CardLast4: last4(number)}
+	switch {
+	case number == CardNetworkError:
+		return nil, fmt.Errorf("%w: simulated network error", ErrUnavailable)
+	case number == CardDeclined:
+		auth.Outcome, auth.DeclineCode, auth.Message = OutcomeDeclined, "card_declined", "Your card was declined."
+	case number == CardInsufficientFunds:
+		auth.Outcome, auth.DeclineCode, auth.Message = OutcomeDeclined, "insufficient_funds", "Your card has insufficient funds."
+	case !luhnValid(number):
+		auth.Outcome, auth.DeclineCode, auth.Message = OutcomeDeclined, "incorrect_number", "Your card number is incorrect."
+	case number == CardRequires3DS:
+		auth.Outcome, auth.Reference = OutcomeRequiresAction, fakePending3DS+req.IdempotencyKey
+	default:
+		auth.Outcome, auth.Reference = OutcomeAuthorized, fakeAuthorized+req.IdempotencyKey
+	}
+	return auth, nil
+}
+
+// CompleteAction da siempre por aprobada la autenticación 3DS.
+func (p *FakeProvider) CompleteAction(_ context.Context, reference string) (*Authorization, error) {
+	key, ok := strings.CutPrefix(reference, fakePending3DS)
+	if !ok {
+		return nil, fmt.Errorf("authorization %q does not require action", reference)
+	}
+	return &Authorization{Outcome: OutcomeAuthorized, Reference: fakeAuthorized + key}, nil
+}
+
+func (p *FakeProvider) Capture(_ context.Context, reference string, amountCents int64) error {
+	if !strings.HasPrefix(reference, fakeAuthorized) {
+		return fmt.Errorf("authorization %q cannot be captured", reference)
+	}
+	if amountCents <= 0 {
+		return fmt.Errorf("invalid capture amount %d", amountCents)
+	}
+	return nil
+}
+
+func (p *FakeProvider) Void(_ context.Context, reference string) error {
+	if !strings.HasPrefix(reference, fakeAuthorized) && !strings.HasPrefix(reference, fakePending3DS) {
+		return fmt.Errorf("unknown authorization %q", reference)
+	}
+	return nil
+}
+
+// luhnValid es el dígito verificador de los números de tarjeta.
+func luhnValid(number string) bool {
+	if len(number) < 12 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < len(number); i++ {
+		c := number[len(number)-1-i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// cardBrand deduce la marca por el prefijo del número.
+func cardBrand(number string) string {
+	switch {
+	case strings.HasPrefix(number, "4"):
+		return "visa"
+	case strings.HasPrefix(number, "34"), strings.HasPrefix(number, "37"):
+		return "amex"
+	case len(number) >= 2 && number[0] == '5' && number[1] >= '1' && number[1] <= '5':
+		return "mastercard"
+	}
+	return "unknown"
+}
+
+func last4(number string) string {
+	if len(number) < 4 {
+		return number
+	}
+	return number[len(number)-4:]
+}
+
+var _ PaymentProvider = (*FakeProvider)(nil)
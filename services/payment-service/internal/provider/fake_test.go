@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeProvider_Authorize(t *testing.T) {
+	tests := []struct {
+		name        string
+		number      string
+		outcome     string
+		declineCode string
+	}{
+		{"success", CardSuccess, OutcomeAuthorized, ""},
+		{"declined", CardDeclined, OutcomeDeclined, "card_declined"},
+		{"insufficient funds", CardInsufficientFunds, OutcomeDeclined, "insufficient_funds"},
+		{"3DS required", CardRequires3DS, OutcomeRequiresAction, ""},
+		{"other valid card", "5555555555554444", OutcomeAuthorized, ""},
+		{"luhn failure", "4242424242424241", OutcomeDeclined, "incorrect_number"},
+	}
+
+	p := NewFakeProvider()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := p.Authorize(context.Background(), AuthorizeRequest{IdempotencyKey: "pi_1_1", AmountCents: 1000,
+				Currency: "USD", Card: Card{Number: tt.number, ExpMonth: 12, ExpYear: 2030}})
+			require.NoError(t, err)
+			require.Equal(t, tt.outcome, auth.Outcome)
+			require.Equal(t, tt.declineCode, auth.DeclineCode)
+			require.Equal(t, tt.number[len(tt.number)-4:], auth.CardLast4)
+		})
+	}
+}
+
+func TestFakeProvider_NetworkError(t *testing.T) {
+	_, err := NewFakeProvider().Authorize(context.Background(), AuthorizeRequest{IdempotencyKey: "pi_1_1", AmountCents: 1000,
+		Currency: "USD", Card: Card{Number: CardNetworkError}})
+	require.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestFakeProvider_3DSThenCapture(t *testing.T) {
+	ctx := context.Background()
+	p := NewFakeProvider()
+	auth, err := p.Authorize(ctx, AuthorizeRequest{IdempotencyKey: "pi_1_1", AmountCents: 1000, Currency: "USD",
+		Card: Card{Number: CardRequires3DS}})
+	require.NoError(t, err)
+	require.Equal(t, "visa", auth.CardBrand)
+	// Sin completar la autenticación no se puede capturar.
+	require.Error(t, p.Capture(ctx, auth.Reference, 1000))
+
+	done, err := p.CompleteAction(ctx, auth.Reference)
+	require.NoError(t, err)
+	require.Equal(t, OutcomeAuthorized, done.Outcome)
+	require.NoError(t, p.Capture(ctx, done.Reference, 1000))
+
+	_, err = p.CompleteAction(ctx, done.Reference)
+	require.Error(t, err)
+}
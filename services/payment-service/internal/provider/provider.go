@@ -0,0 +1,65 @@
+// Package provider define la pasarela de pagos que usa payment-service y trae
+// una implementación falsa y determinística para desarrollo y tests.
+package provider
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnavailable: no se pudo hablar con la pasarela. A diferencia de un
+// rechazo, el pedido se puede reintentar.
+var ErrUnavailable = errors.New("payment provider unavailable")
+
+// Resultados de una autorización.
+const (
+	OutcomeAuthorized     = "authorized"
+	OutcomeRequiresAction = "requires_action"
+	OutcomeDeclined       = "declined"
+)
+
+// Card son los datos de la tarjeta tal como llegan en la confirmación. Solo
+// viajan hasta la pasarela: payment-service no los guarda ni los loguea.
+type Card struct {
+	Number   string `json:"number"`
+	ExpMonth int    `json:"exp_month"`
+	ExpYear  int    `json:"exp_year"`
+	CVC      string `json:"cvc"`
+}
+
+// AuthorizeRequest pide reservar AmountCents en la tarjeta. La pasarela no
+// debe autorizar dos veces la misma IdempotencyKey.
+type AuthorizeRequest struct {
+	IdempotencyKey string
+	AmountCents    int64
+	Currency       string
+	Card           Card
+}
+
+// Authorization es la respuesta de la pasarela. Reference identifica la
+// autorización para completarla, capturarla o anularla; DeclineCode y Message
+// están si Outcome es declined.
+type Authorization struct {
+	Outcome     string
+	Reference   string
+	CardBrand   string
+	CardLast4   string
+	DeclineCode string
+	Message     string
+}
+
+// PaymentProvider es una pasarela de pagos. Un rechazo de la tarjeta no es un
+// error: vuelve como Authorization con Outcome declined. Los errores son
+// fallas de la pasarela (ErrUnavailable si se puede reintentar).
+type PaymentProvider interface {
+	// Name es el nombre con el que se guarda el intent.
+	Name() string
+	Authorize(ctx context.Context, req AuthorizeRequest) (*Authorization, error)
+	// CompleteAction termina la autenticación (3DS) de una autorización que
+	// devolvió requires_action.
+	CompleteAction(ctx context.Context, reference string) (*Authorization, error)
+	// Capture cobra amountCents de una autorización.
+	Capture(ctx context.Context, reference string, amountCents int64) error
+	// Void libera una autorización sin cobrarla.
+	Void(ctx context.Context, reference string) error
+}
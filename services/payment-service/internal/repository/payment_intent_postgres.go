@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/services/payment-service/internal/model"
+)
+
+var (
+	// ErrInvalidTransition: el intent no está en un estado desde el que se pueda
+	// pasar al pedido (o cambió en paralelo).
+	ErrInvalidTransition = errors.New("invalid payment intent status transition")
+	// ErrInvoiceHasIntent: la factura ya tiene un intent sin cancelar.
+	ErrInvoiceHasIntent = errors.New("invoice already has an active payment intent")
+)
+
+// intentColumns es el orden de columnas que espera scanIntent.
+const intentColumns = `id, user_id, org_id, invoice_id, amount_cents, currency, status, capture_method, provider, provider_reference,
+	attempts, card_brand, card_last4, last_error_code, last_error_message, succeeded_at, canceled_at, invoice_synced_at,
+	invoice_sync_error, created_at, updated_at`
+
+// Scope es el dueño de los intents: una organización o, sin ella, el usuario.
+type Scope struct {
+	UserID string
+	OrgID  string
+}
+
+// where agrega a args el parámetro del scope y devuelve la condición que lo usa.
+func (s Scope) where(args []interface{}) (string, []interface{}) {
+	if s.OrgID != "" {
+		args = append(args, s.OrgID)
+		return fmt.Sprintf("org_id = $%d", len(args)), args
+	}
+	args = append(args, s.UserID)
+	return fmt.Sprintf("user_id = $%d AND org_id IS NULL", len(args)), args
+}
+
+// IntentFilter acota el listado de intents del cliente.
+type IntentFilter struct {
+	UserID    string
+	OrgID     string
+	InvoiceID *int
+	Limit     int
+	Offset    int
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+type PaymentIntentRepository struct {
+	db     *sql.DB
+	limits dbquery.Limits
+}
+
+// NewPaymentIntentRepository aplica limits (deadline y log de queries lentas) a cada query.
+func NewPaymentIntentRepository(db *sql.DB, limits dbquery.Limits) *PaymentIntentRepository {
+	return &PaymentIntentRepository{db: db, limits: limits}
+}
+
+func scanIntent(row rowScanner) (*model.PaymentIntent, error) {
+	p := &model.PaymentIntent{}
+	var brand, last4, errCode, errMessage sql.NullString
+	err := row.Scan(&p.ID, &p.UserID, &p.OrgID, &p.InvoiceID, &p.AmountCents, &p.Currency, &p.Status, &p.CaptureMethod, &p.Provider,
+		&p.ProviderReference, &p.Attempts, &brand, &last4, &errCode, &errMessage, &p.SucceededAt, &p.CanceledAt, &p.InvoiceSyncedAt,
+		&p.InvoiceSyncError, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if brand.Valid || last4.Valid {
+		p.Card = &model.PaymentCard{Brand: brand.String, Last4: last4.String}
+	}
+	if errCode.Valid {
+		p.LastPaymentError = &model.PaymentError{Code: errCode.String, Message: errMessage.String}
+	}
+	return p, nil
+}
+
+// CreateIntent guarda un intent nuevo. Devuelve ErrInvoiceHasIntent si su
+// factura ya tiene otro sin cancelar.
+func (r *PaymentIntentRepository) CreateIntent(ctx context.Context, p *model.PaymentIntent) error {
+	ctx, done := r.limits.Start(ctx, "payment_intents.create")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `INSERT INTO payment_intents (user_id, org_id, invoice_id, amount_cents, currency, status, capture_method, provider, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
+	err := r.db.QueryRowContext(ctx, query, p.UserID, p.OrgID, p.InvoiceID, p.AmountCents, p.Currency, p.Status, p.CaptureMethod, p.Provider,
+		p.CreatedAt, p.UpdatedAt).Scan(&p.ID)
+	if pqErrorCode(err) == "23505" {
+		return ErrInvoiceHasIntent
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create payment intent: %w", err)
+	}
+	return nil
+}
+
+// GetIntent devuelve nil si el intent no existe en el scope.
+func (r *PaymentIntentRepository) GetIntent(ctx context.Context, scope Scope, id int) (*model.PaymentIntent, error) {
+	ctx, done := r.limits.Start(ctx, "payment_intents.get_by_id")
+	defer done()
+
+	cond, args := scope.where([]interface{}{id})
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT ` + intentColumns + ` FROM payment_intents WHERE id = $1 AND ` + cond
+	p, err := scanIntent(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch payment intent: %w", err)
+	}
+	return p, nil
+}
+
+func (r *PaymentIntentRepository) ListIntents(ctx context.Context, filter IntentFilter) ([]*model.PaymentIntent, error) {
+	ctx, done := r.limits.Start(ctx, "payment_intents.list")
+	defer done()
+
+	cond, args := Scope{UserID: filter.UserID, OrgID: filter.OrgID}.where(nil)
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT ` + intentColumns + ` FROM payment_intents WHERE ` + cond
+	if filter.InvoiceID != nil {
+		args = append(args, *filter.InvoiceID)
+		query += fmt.Sprintf(" AND invoice_id = $%d", len(args))
+	}
+	limit := filter.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	args = append(args, limit, max(filter.Offset, 0))
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	return r.list(ctx, query, args...)
+}
+
+// TransitionIntent aplica u si el intent está hoy en alguno de from; el
+// chequeo va en el mismo UPDATE para que dos confirmaciones concurrentes no
+// lleguen las dos a la pasarela. Devuelve nil si no existe y
+// ErrInvalidTransition si está en otro estado. No filtra por dueño: el
+// servicio ya leyó el intent con el scope del request.
+func (r *PaymentIntentRepository) TransitionIntent(ctx context.Context, id int, from []string, u model.IntentUpdate) (*model.PaymentIntent, error) {
+	ctx, done := r.limits.Start(ctx, "payment_intents.transition")
+	defer done()
+
+	var brand, last4, errCode, errMessage *string
+	if u.Card != nil {
+		brand, last4 = &u.Card.Brand, &u.Card.Last4
+	}
+	setError := u.LastPaymentError != nil || u.ClearPaymentError
+	if u.LastPaymentError != nil {
+		errCode, errMessage = &u.LastPaymentError.Code, &u.LastPaymentError.Message
+	}
+	attempts := 0
+	if u.Attempt {
+		attempts = 1
+	}
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `UPDATE payment_intents SET
+			status = $3,
+			provider_reference = COALESCE($4::varchar, provider_reference),
+			card_brand = COALESCE($5::varchar, card_brand),
+			card_last4 = COALESCE($6::varchar, card_last4),
+			last_error_code = CASE WHEN $7::boolean THEN $8::varchar ELSE last_error_code END,
+			last_error_message = CASE WHEN $7::boolean THEN $9::text ELSE last_error_message END,
+			attempts = attempts + $10::int,
+			succeeded_at = COALESCE($11::timestamptz, succeeded_at),
+			canceled_at = COALESCE($12::timestamptz, canceled_at),
+			updated_at = $13
+		WHERE id = $1 AND status = ANY($2)
+		RETURNING ` + intentColumns
+	p, err := scanIntent(r.db.QueryRowContext(ctx, query, id, pq.Array(from), u.Status, u.ProviderReference, brand, last4,
+		setError, errCode, errMessage, attempts, u.SucceededAt, u.CanceledAt, u.UpdatedAt))
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		//goland:noinspection SqlNoDataSourceInspection
+		if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM payment_intents WHERE id = $1)`, id).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to fetch payment intent: %w", err)
+		}
+		if !exists {
+			return nil, nil
+		}
+		return nil, ErrInvalidTransition
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update payment intent: %w", err)
+	}
+	return p, nil
+}
+
+// UnsyncedIntents devuelve hasta limit intents cobrados cuya factura todavía
+// no se marcó como paga en billing-service, los más viejos primero.
+func (r *PaymentIntentRepository) UnsyncedIntents(ctx context.Context, limit int) ([]*model.PaymentIntent, error) {
+	ctx, done := r.limits.Start(ctx, "payment_intents.list_unsynced")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `SELECT ` + intentColumns + ` FROM payment_intents
+		WHERE status = 'succeeded' AND invoice_id IS NOT NULL AND invoice_synced_at IS NULL
+		ORDER BY succeeded_at LIMIT $1`
+	return r.list(ctx, query, limit)
+}
+
+// MarkInvoiceSynced registra que billing-service ya procesó el pago del
+// intent; syncErr es el motivo si lo rechazó.
+func (r *PaymentIntentRepository) MarkInvoiceSynced(ctx context.Context, id int, at time.Time, syncErr *string) (*model.PaymentIntent, error) {
+	ctx, done := r.limits.Start(ctx, "payment_intents.mark_synced")
+	defer done()
+
+	//goland:noinspection SqlNoDataSourceInspection
+	query := `UPDATE payment_intents SET invoice_synced_at = $2, invoice_sync_error = $3, updated_at = $2
+		WHERE id = $1 AND invoice_synced_at IS NULL
+		RETURNING ` + intentColumns
+	p, err := scanIntent(r.db.QueryRowContext(ctx, query, id, at, syncErr))
+	if errors.Is(err, sql.ErrNoRows) {
+		// Otra réplica lo registró antes.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark invoice synced: %w", err)
+	}
+	return p, nil
+}
+
+func (r *PaymentIntentRepository) list(ctx context.Context, query string, args ...interface{}) ([]*model.PaymentIntent, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch payment intents: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	intents := []*model.PaymentIntent{}
+	for rows.Next() {
+		p, err := scanIntent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment intent: %w", err)
+		}
+		intents = append(intents, p)
+	}
+	return intents, rows.Err()
+}
+
+// pqErrorCode devuelve el código SQLSTATE de err, o "" si no viene de Postgres.
+func pqErrorCode(err error) pq.ErrorCode {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package router
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/services/payment-service/internal/client"
+	"saas-subscription-platform/services/payment-service/internal/handler"
+	"saas-subscription-platform/services/payment-service/internal/middleware"
+	"saas-subscription-platform/services/payment-service/internal/provider"
+	"saas-subscription-platform/services/payment-service/internal/repository"
+	"saas-subscription-platform/services/payment-service/internal/service"
+)
+
+// NewPaymentService arma el PaymentService con su repositorio, el cliente de
+// billing-service y la pasarela; lo usan el router y el worker de avisos.
+func NewPaymentService(db *sql.DB, limits dbquery.Limits, billingURL string, p provider.PaymentProvider) *service.PaymentService {
+	return service.NewPaymentService(repository.NewPaymentIntentRepository(db, limits), client.NewBillingClient(billingURL), p)
+}
+
+// NewRouter construye el router HTTP del payment-service.
+func NewRouter(svc *service.PaymentService) *mux.Router {
+	h := handler.NewPaymentHandler(svc)
+
+	r := mux.NewRouter()
+
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	}).Methods(http.MethodGet)
+
+	protected := r.NewRoute().Subrouter()
+	protected.Use(middleware.InternalAuthMux)
+	protected.HandleFunc("/payment_intents", h.CreateIntent).Methods(http.MethodPost)
+	protected.HandleFunc("/payment_intents", h.ListIntents).Methods(http.MethodGet)
+	protected.HandleFunc("/payment_intents/{id}", h.GetIntent).Methods(http.MethodGet)
+	protected.HandleFunc("/payment_intents/{id}/confirm", h.ConfirmIntent).Methods(http.MethodPost)
+	protected.HandleFunc("/payment_intents/{id}/capture", h.CaptureIntent).Methods(http.MethodPost)
+	protected.HandleFunc("/payment_intents/{id}/cancel", h.CancelIntent).Methods(http.MethodPost)
+
+	return r
+}
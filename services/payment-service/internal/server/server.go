@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+
+	_ "github.com/lib/pq"
+
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/libs/migrate"
+	"saas-subscription-platform/services/payment-service/internal/config"
+	"saas-subscription-platform/services/payment-service/internal/provider"
+	"saas-subscription-platform/services/payment-service/internal/router"
+	"saas-subscription-platform/services/payment-service/migrations"
+)
+
+func Run() error {
+	cfg := config.Load()
+
+	p, err := newProvider(cfg.Provider)
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("postgres", cfg.DBDSN)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := db.Ping(); err != nil {
+		return err
+	}
+
+	if cfg.MigrateOnBoot {
+		m, err := migrate.New(db, "payment-service", migrations.FS)
+		if err != nil {
+			return err
+		}
+		n, err := m.Up(context.Background())
+		if err != nil {
+			return fmt.Errorf("migrations failed: %w", err)
+		}
+		log.Printf("migrations_up service=payment-service applied=%d", n)
+	}
+
+	limits := dbquery.Limits{
+		Service:       "payment-service",
+		Timeout:       cfg.QueryTimeout,
+		SlowThreshold: cfg.SlowQueryThreshold,
+	}
+	svc := router.NewPaymentService(db, limits, cfg.BillingServiceURL, p)
+	r := router.NewRouter(svc)
+
+	// Los avisos de cobro que billing-service no recibió se reintentan en
+	// segundo plano; billing los acepta repetidos, así que pueden correr en
+	// todas las réplicas.
+	ctx, stopSync := context.WithCancel(context.Background())
+	defer stopSync()
+	go svc.RunInvoiceSync(ctx, cfg.InvoiceSyncInterval)
+
+	log.Printf("Starting payment-service on %s (provider=%s)", cfg.HTTPAddr, p.Name())
+	return http.ListenAndServe(cfg.HTTPAddr, r)
+}
+
+// newProvider elige la pasarela por nombre (PAYMENT_PROVIDER).
+func newProvider(name string) (provider.PaymentProvider, error) {
+	switch name {
+	case "fake":
+		return provider.NewFakeProvider(), nil
+	}
+	return nil, fmt.Errorf("unknown payment provider %q", name)
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"saas-subscription-platform/services/payment-service/internal/client"
+	"saas-subscription-platform/services/payment-service/internal/model"
+)
+
+// invoiceSyncBatch es cuántos cobros avisa el worker por vuelta.
+const invoiceSyncBatch = 50
+
+// syncInvoice avisa a billing-service que se cobró la factura del intent. Si
+// billing no responde devuelve el error y el aviso queda para el worker; si
+// rechaza el pago (factura anulada, monto distinto) reintentar no sirve, así
+// que el motivo queda en el intent para revisarlo a mano.
+func (s *PaymentService) syncInvoice(ctx context.Context, intent *model.PaymentIntent) (*model.PaymentIntent, error) {
+	if intent.InvoiceID == nil || intent.InvoiceSyncedAt != nil {
+		return intent, nil
+	}
+	payment := client.Payment{
+		UserID:          intent.UserID,
+		PaymentIntentID: intent.ID,
+		AmountCents:     intent.AmountCents,
+		Currency:        intent.Currency,
+	}
+	if intent.OrgID != nil {
+		payment.OrgID = *intent.OrgID
+	}
+
+	var syncErr *string
+	err := s.invoices.MarkInvoicePaid(ctx, *intent.InvoiceID, payment)
+	switch {
+	case errors.Is(err, client.ErrInvoiceRejected), errors.Is(err, client.ErrInvoiceNotFound):
+		msg := err.Error()
+		syncErr = &msg
+		log.Printf("invoice_sync_rejected payment_intent_id=%d invoice_id=%d err=%v", intent.ID, *intent.InvoiceID, err)
+	case err != nil:
+		return nil, err
+	}
+	synced, err := s.store.MarkInvoiceSynced(ctx, intent.ID, s.now(), syncErr)
+	if err != nil {
+		return nil, err
+	}
+	if synced == nil {
+		return intent, nil
+	}
+	return synced, nil
+}
+
+// SyncInvoices reintenta los avisos a billing-service pendientes y devuelve
+// cuántos se procesaron. Los que vuelven a fallar quedan para la próxima vuelta.
+func (s *PaymentService) SyncInvoices(ctx context.Context) (int, error) {
+	pending, err := s.store.UnsyncedIntents(ctx, invoiceSyncBatch)
+	if err != nil {
+		return 0, err
+	}
+	synced := 0
+	for _, intent := range pending {
+		if _, err := s.syncInvoice(ctx, intent); err != nil {
+			log.Printf("invoice_sync_failed payment_intent_id=%d err=%v", intent.ID, err)
+			continue
+		}
+		synced++
+	}
+	return synced, nil
+}
+
+// RunInvoiceSync ejecuta SyncInvoices cada interval hasta que ctx se cancele.
+func (s *PaymentService) RunInvoiceSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.SyncInvoices(ctx)
+			if err != nil {
+				log.Printf("invoice_sync_failed err=%v", err)
+			}
+			if n > 0 {
+				log.Printf("invoice_sync_completed synced=%d", n)
+			}
+		}
+	}
+}
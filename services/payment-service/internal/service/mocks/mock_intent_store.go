@@ -0,0 +1,107 @@
+// Code generated manually for tests; gomock-style mock for service.IntentStore.
+package mocks
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"saas-subscription-platform/services/payment-service/internal/model"
+	"saas-subscription-platform/services/payment-service/internal/repository"
+
+	"github.com/golang/mock/gomock"
+)
+
+type MockIntentStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockIntentStoreMockRecorder
+}
+
+type MockIntentStoreMockRecorder struct {
+	mock *MockIntentStore
+}
+
+func NewMockIntentStore(ctrl *gomock.Controller) *MockIntentStore {
+	mock := &MockIntentStore{ctrl: ctrl}
+	mock.recorder = &MockIntentStoreMockRecorder{mock}
+	return mock
+}
+
+func (m *MockIntentStore) EXPECT() *MockIntentStoreMockRecorder { return m.recorder }
+
+func (m *MockIntentStore) CreateIntent(ctx context.Context, p *model.PaymentIntent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIntent", ctx, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *MockIntentStoreMockRecorder) CreateIntent(ctx, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIntent", reflect.TypeOf((*MockIntentStore)(nil).CreateIntent), ctx, p)
+}
+
+func (m *MockIntentStore) GetIntent(ctx context.Context, scope repository.Scope, id int) (*model.PaymentIntent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIntent", ctx, scope, id)
+	ret0, _ := ret[0].(*model.PaymentIntent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockIntentStoreMockRecorder) GetIntent(ctx, scope, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIntent", reflect.TypeOf((*MockIntentStore)(nil).GetIntent), ctx, scope, id)
+}
+
+func (m *MockIntentStore) ListIntents(ctx context.Context, filter repository.IntentFilter) ([]*model.PaymentIntent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListIntents", ctx, filter)
+	ret0, _ := ret[0].([]*model.PaymentIntent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockIntentStoreMockRecorder) ListIntents(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIntents", reflect.TypeOf((*MockIntentStore)(nil).ListIntents), ctx, filter)
+}
+
+func (m *MockIntentStore) TransitionIntent(ctx context.Context, id int, from []string, u model.IntentUpdate) (*model.PaymentIntent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransitionIntent", ctx, id, from, u)
+	ret0, _ := ret[0].(*model.PaymentIntent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockIntentStoreMockRecorder) TransitionIntent(ctx, id, from, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransitionIntent", reflect.TypeOf((*MockIntentStore)(nil).TransitionIntent), ctx, id, from, u)
+}
+
+func (m *MockIntentStore) UnsyncedIntents(ctx context.Context, limit int) ([]*model.PaymentIntent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnsyncedIntents", ctx, limit)
+	ret0, _ := ret[0].([]*model.PaymentIntent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockIntentStoreMockRecorder) UnsyncedIntents(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnsyncedIntents", reflect.TypeOf((*MockIntentStore)(nil).UnsyncedIntents), ctx, limit)
+}
+
+func (m *MockIntentStore) MarkInvoiceSynced(ctx context.Context, id int, at time.Time, syncErr *string) (*model.PaymentIntent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkInvoiceSynced", ctx, id, at, syncErr)
+	ret0, _ := ret[0].(*model.PaymentIntent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockIntentStoreMockRecorder) MarkInvoiceSynced(ctx, id, at, syncErr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkInvoiceSynced", reflect.TypeOf((*MockIntentStore)(nil).MarkInvoiceSynced), ctx, id, at, syncErr)
+}
@@ -0,0 +1,53 @@
+// Code generated manually for tests; gomock-style mock for service.InvoiceClient.
+package mocks
+
+import (
+	"context"
+	"reflect"
+
+	"saas-subscription-platform/services/payment-service/internal/client"
+
+	"github.com/golang/mock/gomock"
+)
+
+type MockInvoiceClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockInvoiceClientMockRecorder
+}
+
+type MockInvoiceClientMockRecorder struct {
+	mock *MockInvoiceClient
+}
+
+func NewMockInvoiceClient(ctrl *gomock.Controller) *MockInvoiceClient {
+	mock := &MockInvoiceClient{ctrl: ctrl}
+	mock.recorder = &MockInvoiceClientMockRecorder{mock}
+	return mock
+}
+
+func (m *MockInvoiceClient) EXPECT() *MockInvoiceClientMockRecorder { return m.recorder }
+
+func (m *MockInvoiceClient) GetInvoice(ctx context.Context, caller client.Caller, id int) (*client.Invoice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInvoice", ctx, caller, id)
+	ret0, _ := ret[0].(*client.Invoice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockInvoiceClientMockRecorder) GetInvoice(ctx, caller, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvoice", reflect.TypeOf((*MockInvoiceClient)(nil).GetInvoice), ctx, caller, id)
+}
+
+func (m *MockInvoiceClient) MarkInvoicePaid(ctx context.Context, id int, p client.Payment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkInvoicePaid", ctx, id, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *MockInvoiceClientMockRecorder) MarkInvoicePaid(ctx, id, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkInvoicePaid", reflect.TypeOf((*MockInvoiceClient)(nil).MarkInvoicePaid), ctx, id, p)
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"saas-subscription-platform/services/payment-service/internal/client"
+	"saas-subscription-platform/services/payment-service/internal/model"
+	"saas-subscription-platform/services/payment-service/internal/provider"
+	"saas-subscription-platform/services/payment-service/internal/repository"
+)
+
+var (
+	// ErrForbidden: el rol del usuario en la organización activa no le permite pagar.
+	ErrForbidden = errors.New("organization role cannot access payments")
+	// ErrInvalidIntent: los datos del intent no son válidos.
+	ErrInvalidIntent = errors.New("invalid payment intent")
+	// ErrInvalidCard: falta la tarjeta o sus datos no son válidos.
+	ErrInvalidCard = errors.New("invalid card")
+	// ErrInvoiceNotFound: la factura no existe o el usuario no la puede ver.
+	ErrInvoiceNotFound = errors.New("invoice not found")
+	// ErrInvoiceNotPayable: la factura no está emitida o no tiene saldo.
+	ErrInvoiceNotPayable = errors.New("invoice is not payable")
+	// ErrInvoiceHasIntent: la factura ya tiene un intent sin cancelar.
+	ErrInvoiceHasIntent = repository.ErrInvoiceHasIntent
+	// ErrInvalidTransition: el intent no admite la operación en su estado actual.
+	ErrInvalidTransition = repository.ErrInvalidTransition
+	// ErrProviderUnavailable: la pasarela no respondió; se puede reintentar.
+	ErrProviderUnavailable = provider.ErrUnavailable
+)
+
+// Scope es el usuario del request y su organización activa, como en billing-service.
+type Scope struct {
+	UserID  string
+	OrgID   string
+	OrgRole string
+}
+
+// paymentOrgRoles son los roles de organización que pagan, los mismos que
+// ven y crean facturas en billing-service.
+var paymentOrgRoles = map[string]bool{"owner": true, "admin": true, "billing": true}
+
+// intentScope valida el scope y lo traduce al del repositorio.
+func (sc Scope) intentScope() (repository.Scope, error) {
+	if sc.UserID == "" {
+		return repository.Scope{}, fmt.Errorf("user id is required")
+	}
+	if sc.OrgID != "" && !paymentOrgRoles[sc.OrgRole] {
+		return repository.Scope{}, ErrForbidden
+	}
+	return repository.Scope{UserID: sc.UserID, OrgID: sc.OrgID}, nil
+}
+
+// IntentStore persiste los payment intents.
+type IntentStore interface {
+	CreateIntent(ctx context.Context, p *model.PaymentIntent) error
+	GetIntent(ctx context.Context, scope repository.Scope, id int) (*model.PaymentIntent, error)
+	ListIntents(ctx context.Context, filter repository.IntentFilter) ([]*model.PaymentIntent, error)
+	TransitionIntent(ctx context.Context, id int, from []string, u model.IntentUpdate) (*model.PaymentIntent, error)
+	UnsyncedIntents(ctx context.Context, limit int) ([]*model.PaymentIntent, error)
+	MarkInvoiceSynced(ctx context.Context, id int, at time.Time, syncErr *string) (*model.PaymentIntent, error)
+}
+
+// InvoiceClient es billing-service visto desde payment-service.
+type InvoiceClient interface {
+	GetInvoice(ctx context.Context, caller client.Caller, id int) (*client.Invoice, error)
+	MarkInvoicePaid(ctx context.Context, id int, p client.Payment) error
+}
+
+type PaymentService struct {
+	store    IntentStore
+	invoices InvoiceClient
+	provider provider.PaymentProvider
+	now      func() time.Time
+}
+
+type Option func(*PaymentService)
+
+// WithClock reemplaza time.Now (para tests).
+func WithClock(now func() time.Time) Option {
+	return func(s *PaymentService) { s.now = now }
+}
+
+func NewPaymentService(store IntentStore, invoices InvoiceClient, p provider.PaymentProvider, opts ...Option) *PaymentService {
+	s := &PaymentService{store: store, invoices: invoices, provider: p, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CreateIntentRequest pide cobrar una factura (InvoiceID) o un monto libre
+// (AmountCents + Currency), no las dos cosas.
+type CreateIntentRequest struct {
+	InvoiceID     *int
+	AmountCents   int64
+	Currency      string
+	CaptureMethod string
+}
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// CreateIntent crea un intent en requires_payment_method. Si cobra una
+// factura, el monto y la moneda salen de su saldo en billing-service.
+func (s *PaymentService) CreateIntent(ctx context.Context, scope Scope, req CreateIntentRequest) (*model.PaymentIntent, error) {
+	owner, err := scope.intentScope()
+	if err != nil {
+		return nil, err
+	}
+	capture := req.CaptureMethod
+	if capture == "" {
+		capture = model.CaptureAutomatic
+	}
+	if capture != model.CaptureAutomatic && capture != model.CaptureManual {
+		return nil, fmt.Errorf("%w: capture_method must be automatic or manual", ErrInvalidIntent)
+	}
+
+	amount, currency := req.AmountCents, strings.ToUpper(req.Currency)
+	if req.InvoiceID != nil {
+		if amount != 0 || currency != "" {
+			return nil, fmt.Errorf("%w: amount and currency come from the invoice", ErrInvalidIntent)
+		}
+		invoice, err := s.invoices.GetInvoice(ctx, client.Caller(scope), *req.InvoiceID)
+		if err != nil {
+			return nil, err
+		}
+		if invoice == nil {
+			return nil, ErrInvoiceNotFound
+		}
+		// Mismos estados desde los que billing-service deja pasar a paid.
+		if (invoice.Status != "open" && invoice.Status != "uncollectible") || invoice.AmountDueCents <= 0 {
+			return nil, ErrInvoiceNotPayable
+		}
+		amount, currency = invoice.AmountDueCents, invoice.Currency
+	}
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount_cents must be positive", ErrInvalidIntent)
+	}
+	if !currencyPattern.MatchString(currency) {
+		return nil, fmt.Errorf("%w: currency must be a 3-letter ISO code", ErrInvalidIntent)
+	}
+
+	now := s.now()
+	intent := &model.PaymentIntent{
+		UserID:        owner.UserID,
+		InvoiceID:     req.InvoiceID,
+		AmountCents:   amount,
+		Currency:      currency,
+		Status:        model.IntentRequiresPaymentMethod,
+		CaptureMethod: capture,
+		Provider:      s.provider.Name(),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if owner.OrgID != "" {
+		intent.OrgID = &owner.OrgID
+	}
+	if err := s.store.CreateIntent(ctx, intent); err != nil {
+		return nil, err
+	}
+	return intent, nil
+}
+
+// GetIntent devuelve nil si el intent no existe en el scope.
+func (s *PaymentService) GetIntent(ctx context.Context, scope Scope, id int) (*model.PaymentIntent, error) {
+	owner, err := scope.intentScope()
+	if err != nil {
+		return nil, err
+	}
+	return s.store.GetIntent(ctx, owner, id)
+}
+
+// ListIntents lista los intents del scope, opcionalmente los de una factura.
+func (s *PaymentService) ListIntents(ctx context.Context, scope Scope, invoiceID *int, limit, offset int) ([]*model.PaymentIntent, error) {
+	owner, err := scope.intentScope()
+	if err != nil {
+		return nil, err
+	}
+	return s.store.ListIntents(ctx, repository.IntentFilter{UserID: owner.UserID, OrgID: owner.OrgID, InvoiceID: invoiceID,
+		Limit: limit, Offset: offset})
+}
+
+// ConfirmIntent autoriza el cobro con card, o completa la autenticación 3DS
+// si el intent está en requires_action (ahí card no hace falta). Un rechazo no
+// es un error: el intent vuelve a requires_payment_method con
+// LastPaymentError. Devuelve nil si el intent no existe en el scope.
+func (s *PaymentService) ConfirmIntent(ctx context.Context, scope Scope, id int, card *provider.Card) (*model.PaymentIntent, error) {
+	intent, err := s.GetIntent(ctx, scope, id)
+	if err != nil || intent == nil {
+		return nil, err
+	}
+	switch intent.Status {
+	case model.IntentRequiresPaymentMethod:
+		if err := validateCard(card, s.now()); err != nil {
+			return nil, err
+		}
+	case model.IntentRequiresAction:
+		if intent.ProviderReference == nil {
+			return nil, ErrInvalidTransition
+		}
+	default:
+		return nil, ErrInvalidTransition
+	}
+
+	claimed, err := s.claim(ctx, intent, true)
+	if err != nil || claimed == nil {
+		return nil, err
+	}
+	var auth *provider.Authorization
+	if intent.Status == model.IntentRequiresAction {
+		auth, err = s.provider.CompleteAction(ctx, *intent.ProviderReference)
+	} else {
+		auth, err = s.provider.Authorize(ctx, provider.AuthorizeRequest{
+			IdempotencyKey: fmt.Sprintf("pi_%d_%d", claimed.ID, claimed.Attempts),
+			AmountCents:    claimed.AmountCents,
+			Currency:       claimed.Currency,
+			Card:           *card,
+		})
+	}
+	if err != nil {
+		return nil, s.release(ctx, id, model.IntentUpdate{Status: intent.Status}, err)
+	}
+
+	u := model.IntentUpdate{UpdatedAt: s.now()}
+	if auth.CardBrand != "" || auth.CardLast4 != "" {
+		u.Card = &model.PaymentCard{Brand: auth.CardBrand, Last4: auth.CardLast4}
+	}
+	switch auth.Outcome {
+	case provider.OutcomeDeclined:
+		u.Status = model.IntentRequiresPaymentMethod
+		u.LastPaymentError = &model.PaymentError{Code: auth.DeclineCode, Message: auth.Message}
+		return s.store.TransitionIntent(ctx, id, []string{model.IntentProcessing}, u)
+	case provider.OutcomeRequiresAction:
+		u.Status, u.ProviderReference, u.ClearPaymentError = model.IntentRequiresAction, &auth.Reference, true
+		return s.store.TransitionIntent(ctx, id, []string{model.IntentProcessing}, u)
+	case provider.OutcomeAuthorized:
+		u.ProviderReference, u.ClearPaymentError = &auth.Reference, true
+		if claimed.CaptureMethod == model.CaptureManual {
+			u.Status = model.IntentRequiresCapture
+			return s.store.TransitionIntent(ctx, id, []string{model.IntentProcessing}, u)
+		}
+		return s.capture(ctx, claimed, auth.Reference, u)
+	}
+	return nil, s.release(ctx, id, model.IntentUpdate{Status: intent.Status}, fmt.Errorf("unknown authorization outcome %q", auth.Outcome))
+}
+
+// CaptureIntent cobra un intent autorizado con captura manual, o reintenta
+// la captura automática que falló.
+func (s *PaymentService) CaptureIntent(ctx context.Context, scope Scope, id int) (*model.PaymentIntent, error) {
+	intent, err := s.GetIntent(ctx, scope, id)
+	if err != nil || intent == nil {
+		return nil, err
+	}
+	if intent.Status != model.IntentRequiresCapture || intent.ProviderReference == nil {
+		return nil, ErrInvalidTransition
+	}
+	claimed, err := s.claim(ctx, intent, false)
+	if err != nil || claimed == nil {
+		return nil, err
+	}
+	return s.capture(ctx, claimed, *intent.ProviderReference, model.IntentUpdate{ClearPaymentError: true, UpdatedAt: s.now()})
+}
+
+// CancelIntent cancela un intent que todavía no se cobró; si ya había una
+// autorización en la pasarela, la libera.
+func (s *PaymentService) CancelIntent(ctx context.Context, scope Scope, id int) (*model.PaymentIntent, error) {
+	intent, err := s.GetIntent(ctx, scope, id)
+	if err != nil || intent == nil {
+		return nil, err
+	}
+	switch intent.Status {
+	case model.IntentRequiresPaymentMethod, model.IntentRequiresAction, model.IntentRequiresCapture:
+	default:
+		return nil, ErrInvalidTransition
+	}
+
+	from := intent.Status
+	if intent.Status != model.IntentRequiresPaymentMethod && intent.ProviderReference != nil {
+		claimed, err := s.claim(ctx, intent, false)
+		if err != nil || claimed == nil {
+			return nil, err
+		}
+		if err := s.provider.Void(ctx, *intent.ProviderReference); err != nil {
+			return nil, s.release(ctx, claimed.ID, model.IntentUpdate{Status: intent.Status}, err)
+		}
+		from = model.IntentProcessing
+	}
+	now := s.now()
+	return s.store.TransitionIntent(ctx, id, []string{from}, model.IntentUpdate{Status: model.IntentCanceled, CanceledAt: &now, UpdatedAt: now})
+}
+
+// claim pasa el intent a processing antes de hablar con la pasarela, así una
+// confirmación o captura concurrente recibe ErrInvalidTransition en lugar de
+// cobrar dos veces.
+func (s *PaymentService) claim(ctx context.Context, intent *model.PaymentIntent, attempt bool) (*model.PaymentIntent, error) {
+	return s.store.TransitionIntent(ctx, intent.ID, []string{intent.Status},
+		model.IntentUpdate{Status: model.IntentProcessing, Attempt: attempt, UpdatedAt: s.now()})
+}
+
+// release aplica u a un intent reclamado cuando la pasarela falló, con el
+// motivo en LastPaymentError, y devuelve el error de la pasarela.
+func (s *PaymentService) release(ctx context.Context, id int, u model.IntentUpdate, cause error) error {
+	u.LastPaymentError = &model.PaymentError{Code: "processing_error", Message: "The payment provider could not process the request."}
+	u.ClearPaymentError, u.UpdatedAt = false, s.now()
+	if _, err := s.store.TransitionIntent(ctx, id, []string{model.IntentProcessing}, u); err != nil {
+		log.Printf("payment_intent_release_failed payment_intent_id=%d err=%v", id, err)
+	}
+	return cause
+}
+
+// capture cobra una autorización y aplica u con el intent en succeeded. Si la
+// pasarela falla, queda en requires_capture (con la autorización guardada)
+// para reintentar con CaptureIntent.
+func (s *PaymentService) capture(ctx context.Context, intent *model.PaymentIntent, reference string, u model.IntentUpdate) (*model.PaymentIntent, error) {
+	if err := s.provider.Capture(ctx, reference, intent.AmountCents); err != nil {
+		u.Status, u.ProviderReference = model.IntentRequiresCapture, &reference
+		return nil, s.release(ctx, intent.ID, u, err)
+	}
+	now := s.now()
+	u.Status, u.SucceededAt, u.UpdatedAt = model.IntentSucceeded, &now, now
+	succeeded, err := s.store.TransitionIntent(ctx, intent.ID, []string{model.IntentProcessing}, u)
+	if err != nil || succeeded == nil {
+		return succeeded, err
+	}
+	log.Printf("payment_intent_succeeded payment_intent_id=%d amount_cents=%d currency=%s", succeeded.ID, succeeded.AmountCents, succeeded.Currency)
+
+	synced, err := s.syncInvoice(ctx, succeeded)
+	if err != nil {
+		// El cobro ya está hecho: el aviso a billing lo reintenta RunInvoiceSync.
+		log.Printf("invoice_sync_failed payment_intent_id=%d err=%v", succeeded.ID, err)
+		return succeeded, nil
+	}
+	return synced, nil
+}
+
+// validateCard revisa el formato de la tarjeta antes de mandarla a la
+// pasarela; si el número existe o tiene fondos lo decide la pasarela.
+func validateCard(card *provider.Card, now time.Time) error {
+	if card == nil {
+		return fmt.Errorf("%w: card is required", ErrInvalidCard)
+	}
+	card.Number = strings.ReplaceAll(card.Number, " ", "")
+	if len(card.Number) < 12 || len(card.Number) > 19 || strings.Trim(card.Number, "0123456789") != "" {
+		return fmt.Errorf("%w: number must have 12 to 19 digits", ErrInvalidCard)
+	}
+	if card.ExpMonth < 1 || card.ExpMonth > 12 {
+		return fmt.Errorf("%w: exp_month must be between 1 and 12", ErrInvalidCard)
+	}
+	if y, m := now.Year(), int(now.Month()); card.ExpYear < y || (card.ExpYear == y && card.ExpMonth < m) {
+		return fmt.Errorf("%w: card is expired", ErrInvalidCard)
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/payment-service/internal/client"
+	"saas-subscription-platform/services/payment-service/internal/model"
+	"saas-subscription-platform/services/payment-service/internal/provider"
+	"saas-subscription-platform/services/payment-service/internal/repository"
+	"saas-subscription-platform/services/payment-service/internal/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+func intPtr(v int) *int { return &v }
+
+// intentStore arma un MockIntentStore con un solo intent que aplica las
+// transiciones como el repositorio (compare-and-swap sobre el estado).
+func intentStore(ctrl *gomock.Controller, intent *model.PaymentIntent) *mocks.MockIntentStore {
+	store := mocks.NewMockIntentStore(ctrl)
+	snapshot := func() *model.PaymentIntent {
+		cp := *intent
+		return &cp
+	}
+	store.EXPECT().GetIntent(gomock.Any(), gomock.Any(), intent.ID).DoAndReturn(
+		func(context.Context, repository.Scope, int) (*model.PaymentIntent, error) { return snapshot(), nil }).AnyTimes()
+	store.EXPECT().TransitionIntent(gomock.Any(), intent.ID, gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ int, from []string, u model.IntentUpdate) (*model.PaymentIntent, error) {
+			if !slices.Contains(from, intent.Status) {
+				return nil, ErrInvalidTransition
+			}
+			intent.Status = u.Status
+			if u.ProviderReference != nil {
+				intent.ProviderReference = u.ProviderReference
+			}
+			if u.Card != nil {
+				intent.Card = u.Card
+			}
+			if u.LastPaymentError != nil || u.ClearPaymentError {
+				intent.LastPaymentError = u.LastPaymentError
+			}
+			if u.Attempt {
+				intent.Attempts++
+			}
+			if u.SucceededAt != nil {
+				intent.SucceededAt = u.SucceededAt
+			}
+			if u.CanceledAt != nil {
+				intent.CanceledAt = u.CanceledAt
+			}
+			return snapshot(), nil
+		}).AnyTimes()
+	store.EXPECT().MarkInvoiceSynced(gomock.Any(), intent.ID, testNow, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ int, at time.Time, syncErr *string) (*model.PaymentIntent, error) {
+			intent.InvoiceSyncedAt, intent.InvoiceSyncError = &at, syncErr
+			return snapshot(), nil
+		}).AnyTimes()
+	return store
+}
+
+func card(number string) *provider.Card {
+	return &provider.Card{Number: number, ExpMonth: 12, ExpYear: 2030, CVC: "123"}
+}
+
+func TestPaymentService_CreateIntent_Invoice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockIntentStore(ctrl)
+	invoices := mocks.NewMockInvoiceClient(ctrl)
+	svc := NewPaymentService(store, invoices, provider.NewFakeProvider(), WithClock(func() time.Time { return testNow }))
+	scope := Scope{UserID: "user-1", OrgID: "org-1", OrgRole: "billing"}
+
+	invoices.EXPECT().GetInvoice(gomock.Any(), client.Caller{UserID: "user-1", OrgID: "org-1", OrgRole: "billing"}, 12).
+		Return(&client.Invoice{ID: 12, Status: "open", AmountDueCents: 1500, Currency: "USD"}, nil)
+	store.EXPECT().CreateIntent(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, p *model.PaymentIntent) error {
+		p.ID = 3
+		return nil
+	})
+
+	intent, err := svc.CreateIntent(context.Background(), scope, CreateIntentRequest{InvoiceID: intPtr(12)})
+	require.NoError(t, err)
+	require.Equal(t, 3, intent.ID)
+	require.Equal(t, int64(1500), intent.AmountCents)
+	require.Equal(t, "USD", intent.Currency)
+	require.Equal(t, "org-1", *intent.OrgID)
+	require.Equal(t, model.IntentRequiresPaymentMethod, intent.Status)
+	require.Equal(t, model.CaptureAutomatic, intent.CaptureMethod)
+	require.Equal(t, "fake", intent.Provider)
+}
+
+func TestPaymentService_CreateIntent_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   Scope
+		req     CreateIntentRequest
+		invoice *client.Invoice
+		wantErr error
+	}{
+		{name: "member role", scope: Scope{UserID: "user-1", OrgID: "org-1", OrgRole: "member"},
+			req: CreateIntentRequest{AmountCents: 100, Currency: "USD"}, wantErr: ErrForbidden},
+		{name: "amount with invoice", req: CreateIntentRequest{InvoiceID: intPtr(12), AmountCents: 100}, wantErr: ErrInvalidIntent},
+		{name: "unknown capture method", req: CreateIntentRequest{AmountCents: 100, Currency: "USD", CaptureMethod: "later"}, wantErr: ErrInvalidIntent},
+		{name: "zero amount", req: CreateIntentRequest{Currency: "USD"}, wantErr: ErrInvalidIntent},
+		{name: "bad currency", req: CreateIntentRequest{AmountCents: 100, Currency: "DOLLARS"}, wantErr: ErrInvalidIntent},
+		{name: "missing invoice", req: CreateIntentRequest{InvoiceID: intPtr(12)}, wantErr: ErrInvoiceNotFound},
+		{name: "draft invoice", req: CreateIntentRequest{InvoiceID: intPtr(12)},
+			invoice: &client.Invoice{ID: 12, Status: "draft", AmountDueCents: 1500, Currency: "USD"}, wantErr: ErrInvoiceNotPayable},
+		{name: "paid invoice", req: CreateIntentRequest{InvoiceID: intPtr(12)},
+			invoice: &client.Invoice{ID: 12, Status: "paid", AmountDueCents: 1500, Currency: "USD"}, wantErr: ErrInvoiceNotPayable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			invoices := mocks.NewMockInvoiceClient(ctrl)
+			svc := NewPaymentService(mocks.NewMockIntentStore(ctrl), invoices, provider.NewFakeProvider())
+			if tt.scope.UserID == "" {
+				tt.scope = Scope{UserID: "user-1"}
+			}
+			if tt.req.AmountCents == 0 && tt.req.InvoiceID != nil {
+				invoices.EXPECT().GetInvoice(gomock.Any(), gomock.Any(), 12).Return(tt.invoice, nil)
+			}
+
+			_, err := svc.CreateIntent(context.Background(), tt.scope, tt.req)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestPaymentService_ConfirmIntent(t *testing.T) {
+	tests := []struct {
+		name       string
+		number     string
+		capture    string
+		wantStatus string
+		wantCode   string
+		wantErr    error
+		wantPaid   bool
+	}{
+		{name: "success", number: provider.CardSuccess, wantStatus: model.IntentSucceeded, wantPaid: true},
+		{name: "declined", number: provider.CardDeclined, wantStatus: model.IntentRequiresPaymentMethod, wantCode: "card_declined"},
+		{name: "insufficient funds", number: provider.CardInsufficientFunds, wantStatus: model.IntentRequiresPaymentMethod,
+			wantCode: "insufficient_funds"},
+		{name: "3DS required", number: provider.CardRequires3DS, wantStatus: model.IntentRequiresAction},
+		{name: "network error", number: provider.CardNetworkError, wantStatus: model.IntentRequiresPaymentMethod,
+			wantCode: "processing_error", wantErr: ErrProviderUnavailable},
+		{name: "manual capture", number: provider.CardSuccess, capture: model.CaptureManual, wantStatus: model.IntentRequiresCapture},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			capture := model.CaptureAutomatic
+			if tt.capture != "" {
+				capture = tt.capture
+			}
+			intent := &model.PaymentIntent{ID: 3, UserID: "user-1", InvoiceID: intPtr(12), AmountCents: 1500, Currency: "USD",
+				Status: model.IntentRequiresPaymentMethod, CaptureMethod: capture, Provider: "fake"}
+			invoices := mocks.NewMockInvoiceClient(ctrl)
+			svc := NewPaymentService(intentStore(ctrl, intent), invoices, provider.NewFakeProvider(),
+				WithClock(func() time.Time { return testNow }))
+			if tt.wantPaid {
+				invoices.EXPECT().MarkInvoicePaid(gomock.Any(), 12,
+					client.Payment{UserID: "user-1", PaymentIntentID: 3, AmountCents: 1500, Currency: "USD"}).Return(nil)
+			}
+
+			got, err := svc.ConfirmIntent(context.Background(), Scope{UserID: "user-1"}, 3, card(tt.number))
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantStatus, got.Status)
+			}
+			require.Equal(t, tt.wantStatus, intent.Status)
+			require.Equal(t, 1, intent.Attempts)
+			if tt.wantErr == nil {
+				require.Equal(t, tt.number[len(tt.number)-4:], intent.Card.Last4)
+			}
+			if tt.wantCode != "" {
+				require.Equal(t, tt.wantCode, intent.LastPaymentError.Code)
+			} else {
+				require.Nil(t, intent.LastPaymentError)
+			}
+			if tt.wantPaid {
+				require.Equal(t, testNow, *intent.InvoiceSyncedAt)
+			}
+		})
+	}
+}
+
+func TestPaymentService_ConfirmIntent_3DSThenCapture(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	intent := &model.PaymentIntent{ID: 3, UserID: "user-1", AmountCents: 1500, Currency: "USD",
+		Status: model.IntentRequiresPaymentMethod, CaptureMethod: model.CaptureManual, Provider: "fake"}
+	svc := NewPaymentService(intentStore(ctrl, intent), mocks.NewMockInvoiceClient(ctrl), provider.NewFakeProvider(),
+		WithClock(func() time.Time { return testNow }))
+	ctx, scope := context.Background(), Scope{UserID: "user-1"}
+
+	got, err := svc.ConfirmIntent(ctx, scope, 3, card(provider.CardRequires3DS))
+	require.NoError(t, err)
+	require.Equal(t, model.IntentRequiresAction, got.Status)
+
+	// Capturar antes de terminar la autenticación no está permitido.
+	_, err = svc.CaptureIntent(ctx, scope, 3)
+	require.ErrorIs(t, err, ErrInvalidTransition)
+
+	got, err = svc.ConfirmIntent(ctx, scope, 3, nil)
+	require.NoError(t, err)
+	require.Equal(t, model.IntentRequiresCapture, got.Status)
+	require.Equal(t, "3155", got.Card.Last4)
+
+	got, err = svc.CaptureIntent(ctx, scope, 3)
+	require.NoError(t, err)
+	require.Equal(t, model.IntentSucceeded, got.Status)
+	require.Equal(t, testNow, *got.SucceededAt)
+
+	_, err = svc.CancelIntent(ctx, scope, 3)
+	require.ErrorIs(t, err, ErrInvalidTransition)
+}
+
+func TestPaymentService_ConfirmIntent_InvalidCard(t *testing.T) {
+	tests := []struct {
+		name string
+		card *provider.Card
+	}{
+		{"missing card", nil},
+		{"short number", &provider.Card{Number: "4242", ExpMonth: 12, ExpYear: 2030}},
+		{"letters", &provider.Card{Number: "4242abcd42424242", ExpMonth: 12, ExpYear: 2030}},
+		{"bad month", &provider.Card{Number: provider.CardSuccess, ExpMonth: 13, ExpYear: 2030}},
+		{"expired", &provider.Card{Number: provider.CardSuccess, ExpMonth: 2, ExpYear: 2024}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			intent := &model.PaymentIntent{ID: 3, UserID: "user-1", AmountCents: 1500, Currency: "USD",
+				Status: model.IntentRequiresPaymentMethod, CaptureMethod: model.CaptureAutomatic}
+			svc := NewPaymentService(intentStore(ctrl, intent), mocks.NewMockInvoiceClient(ctrl), provider.NewFakeProvider(),
+				WithClock(func() time.Time { return testNow }))
+
+			_, err := svc.ConfirmIntent(context.Background(), Scope{UserID: "user-1"}, 3, tt.card)
+			require.ErrorIs(t, err, ErrInvalidCard)
+			require.Equal(t, 0, intent.Attempts)
+		})
+	}
+}
+
+func TestPaymentService_CancelIntent_VoidsAuthorization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	intent := &model.PaymentIntent{ID: 3, UserID: "user-1", AmountCents: 1500, Currency: "USD",
+		Status: model.IntentRequiresPaymentMethod, CaptureMethod: model.CaptureManual, Provider: "fake"}
+	svc := NewPaymentService(intentStore(ctrl, intent), mocks.NewMockInvoiceClient(ctrl), provider.NewFakeProvider(),
+		WithClock(func() time.Time { return testNow }))
+	ctx, scope := context.Background(), Scope{UserID: "user-1"}
+
+	_, err := svc.ConfirmIntent(ctx, scope, 3, card(provider.CardSuccess))
+	require.NoError(t, err)
+
+	got, err := svc.CancelIntent(ctx, scope, 3)
+	require.NoError(t, err)
+	require.Equal(t, model.IntentCanceled, got.Status)
+	require.Equal(t, testNow, *got.CanceledAt)
+}
+
+func TestPaymentService_SyncInvoices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockIntentStore(ctrl)
+	invoices := mocks.NewMockInvoiceClient(ctrl)
+	svc := NewPaymentService(store, invoices, provider.NewFakeProvider(), WithClock(func() time.Time { return testNow }))
+
+	succeeded := func(id, invoiceID int) *model.PaymentIntent {
+		return &model.PaymentIntent{ID: id, UserID: "user-1", InvoiceID: intPtr(invoiceID), AmountCents: 1500, Currency: "USD",
+			Status: model.IntentSucceeded}
+	}
+	store.EXPECT().UnsyncedIntents(gomock.Any(), invoiceSyncBatch).Return([]*model.PaymentIntent{
+		succeeded(1, 11), succeeded(2, 12), succeeded(3, 13),
+	}, nil)
+	invoices.EXPECT().MarkInvoicePaid(gomock.Any(), 11, gomock.Any()).Return(nil)
+	invoices.EXPECT().MarkInvoicePaid(gomock.Any(), 12, gomock.Any()).Return(errors.New("connection refused"))
+	invoices.EXPECT().MarkInvoicePaid(gomock.Any(), 13, gomock.Any()).Return(client.ErrInvoiceRejected)
+	store.EXPECT().MarkInvoiceSynced(gomock.Any(), 1, testNow, (*string)(nil)).Return(succeeded(1, 11), nil)
+	// La factura rechazada se da por procesada con el motivo; la 12 queda para la próxima vuelta.
+	store.EXPECT().MarkInvoiceSynced(gomock.Any(), 3, testNow, gomock.Not(gomock.Nil())).Return(succeeded(3, 13), nil)
+
+	n, err := svc.SyncInvoices(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_payment_intents_unsynced;
+DROP INDEX IF EXISTS payment_intents_live_invoice_key;
+DROP INDEX IF EXISTS idx_payment_intents_org;
+DROP INDEX IF EXISTS idx_payment_intents_user;
+
+DROP TABLE IF EXISTS payment_intents;
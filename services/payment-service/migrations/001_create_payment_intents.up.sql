@@ -0,0 +1,42 @@
+-- Payment intents: un cobro (de una factura de billing-service o de un monto
+-- libre) y su paso por la pasarela. De la tarjeta solo se guardan la marca y
+-- los últimos 4 dígitos. Los estados y sus transiciones los valida
+-- PaymentService.
+CREATE TABLE IF NOT EXISTS payment_intents (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    org_id UUID NULL,
+    invoice_id INT NULL,
+    amount_cents BIGINT NOT NULL CHECK (amount_cents > 0),
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(30) NOT NULL CHECK (status IN ('requires_payment_method', 'requires_action', 'processing',
+        'requires_capture', 'succeeded', 'canceled')),
+    capture_method VARCHAR(10) NOT NULL DEFAULT 'automatic' CHECK (capture_method IN ('automatic', 'manual')),
+    provider VARCHAR(50) NOT NULL,
+    provider_reference VARCHAR(255) NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    card_brand VARCHAR(20) NULL,
+    card_last4 VARCHAR(4) NULL,
+    last_error_code VARCHAR(50) NULL,
+    last_error_message TEXT NULL,
+    succeeded_at TIMESTAMPTZ NULL,
+    canceled_at TIMESTAMPTZ NULL,
+    -- invoice_synced_at es cuándo billing-service registró el pago; si lo
+    -- rechazó, invoice_sync_error dice por qué y no se reintenta.
+    invoice_synced_at TIMESTAMPTZ NULL,
+    invoice_sync_error TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_intents_user ON payment_intents (user_id, created_at DESC) WHERE org_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_payment_intents_org ON payment_intents (org_id, created_at DESC) WHERE org_id IS NOT NULL;
+
+-- Una factura tiene a lo sumo un intent vivo: para reintentar con otra
+-- tarjeta se confirma el mismo; para empezar de nuevo hay que cancelarlo.
+CREATE UNIQUE INDEX IF NOT EXISTS payment_intents_live_invoice_key ON payment_intents (invoice_id)
+    WHERE invoice_id IS NOT NULL AND status <> 'canceled';
+
+-- Cobros que todavía hay que avisar a billing-service (ver RunInvoiceSync).
+CREATE INDEX IF NOT EXISTS idx_payment_intents_unsynced ON payment_intents (succeeded_at)
+    WHERE status = 'succeeded' AND invoice_id IS NOT NULL AND invoice_synced_at IS NULL;
@@ -0,0 +1,120 @@
+// Package client contiene los clientes HTTP de los servicios que guardan datos
+// del usuario, para el export y el borrado GDPR.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"saas-subscription-platform/libs/trace"
+	"saas-subscription-platform/services/user-service/internal/model"
+)
+
+// callerID identifica a user-service ante los endpoints /internal de los demás servicios.
+const callerID = "user-service"
+
+type internalClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func newInternalClient(baseURL string) internalClient {
+	return internalClient{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// do llama a path y devuelve el cuerpo si el status es el esperado.
+func (c internalClient) do(ctx context.Context, method, path string, body interface{}, want int) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Internal-User-ID", callerID)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	trace.InjectHeaders(ctx, req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != want {
+		return nil, fmt.Errorf("%s %s: unexpected status %d", method, path, resp.StatusCode)
+	}
+	return respBody, nil
+}
+
+// AuthDataSource exporta y borra sesiones, dispositivos, intentos de login y
+// eventos de seguridad en auth-service.
+type AuthDataSource struct {
+	client internalClient
+}
+
+func NewAuthDataSource(baseURL string) *AuthDataSource {
+	return &AuthDataSource{client: newInternalClient(baseURL)}
+}
+
+func (a *AuthDataSource) Name() string { return "auth-service" }
+
+// auth-service también guarda intentos de login fallidos solo por email.
+type authDataRequest struct {
+	Email string `json:"email"`
+}
+
+func (a *AuthDataSource) Export(ctx context.Context, user model.User) (json.RawMessage, error) {
+	path := "/internal/users/" + url.PathEscape(user.ID) + "/export"
+	return a.client.do(ctx, http.MethodPost, path, authDataRequest{Email: user.Email}, http.StatusOK)
+}
+
+func (a *AuthDataSource) Erase(ctx context.Context, user model.User, _ string) error {
+	path := "/internal/users/" + url.PathEscape(user.ID) + "/erase"
+	_, err := a.client.do(ctx, http.MethodPost, path, authDataRequest{Email: user.Email}, http.StatusNoContent)
+	return err
+}
+
+// BillingDataSource exporta las facturas y las seudonimiza al borrar.
+type BillingDataSource struct {
+	client internalClient
+}
+
+func NewBillingDataSource(baseURL string) *BillingDataSource {
+	return &BillingDataSource{client: newInternalClient(baseURL)}
+}
+
+func (b *BillingDataSource) Name() string { return "billing-service" }
+
+func (b *BillingDataSource) Export(ctx context.Context, user model.User) (json.RawMessage, error) {
+	path := "/internal/users/" + url.PathEscape(user.ID) + "/export"
+	return b.client.do(ctx, http.MethodGet, path, nil, http.StatusOK)
+}
+
+func (b *BillingDataSource) Erase(ctx context.Context, user model.User, pseudonym string) error {
+	path := "/internal/users/" + url.PathEscape(user.ID) + "/erase"
+	body := map[string]string{"pseudonym": pseudonym}
+	_, err := b.client.do(ctx, http.MethodPost, path, body, http.StatusOK)
+	return err
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"saas-subscription-platform/services/user-service/internal/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBillingDataSource_Erase(t *testing.T) {
+	var gotCaller, gotPath, gotPseudonym string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCaller = r.Header.Get("X-Internal-User-ID")
+		gotPath = r.URL.Path
+		var body struct {
+			Pseudonym string `json:"pseudonym"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		gotPseudonym = body.Pseudonym
+		_, _ = w.Write([]byte(`{"invoices_pseudonymized":2}`))
+	}))
+	defer srv.Close()
+
+	err := NewBillingDataSource(srv.URL).Erase(context.Background(), model.User{ID: "u-1"}, "p-1")
+
+	require.NoError(t, err)
+	require.Equal(t, "user-service", gotCaller)
+	require.Equal(t, "/internal/users/u-1/erase", gotPath)
+	require.Equal(t, "p-1", gotPseudonym)
+}
+
+func TestAuthDataSource_ExportError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	_, err := NewAuthDataSource(srv.URL).Export(context.Background(), model.User{ID: "u-1", Email: "jane@example.com"})
+
+	require.ErrorContains(t, err, "unexpected status 500")
+}
@@ -1,16 +1,62 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"time"
+)
 
 type Config struct {
 	HTTPAddr string
 	DBDSN    string
+	// DeletionGracePeriod es cuánto se puede restaurar una cuenta borrada antes de purgarla.
+	DeletionGracePeriod time.Duration
+	// PurgeInterval es cada cuánto se buscan cuentas con la gracia vencida.
+	PurgeInterval time.Duration
+	// AuthServiceURL y BillingServiceURL son los servicios que participan del export/borrado GDPR.
+	AuthServiceURL    string
+	BillingServiceURL string
+	// PseudonymKey firma los seudónimos que reemplazan al usuario en las facturas.
+	// Cambiarla rompe la correlación entre borrados viejos y nuevos.
+	PseudonymKey string
+	// QueryTimeout corta cualquier query que tarde más, aunque el request siga vivo.
+	QueryTimeout time.Duration
+	// SlowQueryThreshold es a partir de cuánto se loguea una query como lenta.
+	SlowQueryThreshold time.Duration
+	// MigrateOnBoot aplica las migraciones pendientes al arrancar.
+	MigrateOnBoot bool
+	// InvitationKey firma los links de invitación a organizaciones.
+	InvitationKey string
+	// InvitationTTL es cuánto vale un link de invitación desde que se envía.
+	InvitationTTL time.Duration
+	// InvitationAcceptURL es la página (frontend) del link; recibe ?token=...
+	InvitationAcceptURL string
+	// Emails de invitación por SMTP; sin dirección se loguean.
+	SMTPAddr     string
+	SMTPFrom     string
+	SMTPUsername string
+	SMTPPassword string
 }
 
 func Load() Config {
 	return Config{
-		HTTPAddr: getEnv("USER_HTTP_ADDR", ":8081"),
-		DBDSN:    getEnv("USER_DB_DSN", ""),
+		HTTPAddr:            getEnv("USER_HTTP_ADDR", ":8081"),
+		DBDSN:               getEnv("USER_DB_DSN", ""),
+		DeletionGracePeriod: getDuration("USER_DELETION_GRACE_PERIOD", 30*24*time.Hour),
+		PurgeInterval:       getDuration("USER_PURGE_INTERVAL", time.Hour),
+		AuthServiceURL:      getEnv("AUTH_SERVICE_URL", "http://localhost:8082"),
+		BillingServiceURL:   getEnv("BILLING_SERVICE_URL", "http://localhost:8083"),
+		PseudonymKey:        getEnv("ERASURE_PSEUDONYM_KEY", "dev-pseudonym-key"),
+		QueryTimeout:        getDuration("USER_DB_QUERY_TIMEOUT", 5*time.Second),
+		SlowQueryThreshold:  getDuration("USER_DB_SLOW_QUERY_THRESHOLD", 500*time.Millisecond),
+		MigrateOnBoot:       getBool("USER_MIGRATE_ON_BOOT", false),
+		InvitationKey:       getEnv("ORG_INVITATION_KEY", "dev-invitation-key"),
+		InvitationTTL:       getDuration("ORG_INVITATION_TTL", 7*24*time.Hour),
+		InvitationAcceptURL: getEnv("ORG_INVITATION_ACCEPT_URL", "http://localhost:3000/invitations/accept"),
+		SMTPAddr:            getEnv("SMTP_ADDR", ""),
+		SMTPFrom:            getEnv("SMTP_FROM", "no-reply@localhost"),
+		SMTPUsername:        getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:        getEnv("SMTP_PASSWORD", ""),
 	}
 }
 
@@ -20,3 +66,21 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return fallback
+}
+
+func getBool(key string, fallback bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"context"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"saas-subscription-platform/libs/migrate"
+	"saas-subscription-platform/services/user-service/migrations"
+)
+
+// Migrate aplica las migraciones pendientes de user-service. Es seguro con varias
+// réplicas arrancando a la vez: la primera aplica y el resto espera el lock.
+func Migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	sqlDB := stdlib.OpenDBFromPool(pool)
+	defer func() { _ = sqlDB.Close() }()
+
+	m, err := migrate.New(sqlDB, "user-service", migrations.FS)
+	if err != nil {
+		return err
+	}
+	n, err := m.Up(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("migrations_up service=user-service applied=%d", n)
+	return nil
+}
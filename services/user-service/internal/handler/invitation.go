@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"saas-subscription-platform/libs/emailaddr"
+	"saas-subscription-platform/services/user-service/internal/middleware"
+	"saas-subscription-platform/services/user-service/internal/model"
+	"saas-subscription-platform/services/user-service/internal/repository"
+	"saas-subscription-platform/services/user-service/internal/service"
+)
+
+// InvitationHandler expone las invitaciones a organizaciones: la gestión (owners
+// y admins), la aceptación del invitado y las rutas internas del registro.
+type InvitationHandler struct {
+	invites *service.InvitationService
+}
+
+func NewInvitationHandler(invites *service.InvitationService) *InvitationHandler {
+	return &InvitationHandler{invites: invites}
+}
+
+type invitationResponse struct {
+	ID        string `json:"id"`
+	OrgID     string `json:"org_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	Status    string `json:"status"`
+	InvitedBy string `json:"invited_by"`
+	ExpiresAt string `json:"expires_at"`
+	CreatedAt string `json:"created_at"`
+}
+
+func newInvitationResponse(inv model.Invitation) invitationResponse {
+	status := "pending"
+	if !time.Now().Before(inv.ExpiresAt) {
+		status = "expired"
+	}
+	return invitationResponse{
+		ID:        inv.ID,
+		OrgID:     inv.OrgID,
+		Email:     inv.Email,
+		Role:      inv.Role,
+		Status:    status,
+		InvitedBy: inv.InvitedBy,
+		ExpiresAt: inv.ExpiresAt.Format(time.RFC3339),
+		CreatedAt: inv.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// invitationActor devuelve el usuario del request. Un agente no gestiona ni
+// acepta invitaciones a nombre del usuario.
+func invitationActor(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if middleware.ActorIDFromContext(r.Context()) != "" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return "", false
+	}
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	return userID, true
+}
+
+func writeInvitationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrOrgForbidden), errors.Is(err, service.ErrInvitationEmailMismatch):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrInvalidInviteRole), errors.Is(err, service.ErrInvalidInvitation),
+		errors.Is(err, emailaddr.ErrInvalidEmail):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrAlreadyMember), errors.Is(err, repository.ErrInvitationExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrInvitationExpired):
+		http.Error(w, err.Error(), http.StatusGone)
+	case errors.Is(err, repository.ErrInvitationNotFound), errors.Is(err, repository.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		log.Printf("Error handling invitation: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// CreateInvitation invita un email a la organización con un rol.
+func (h *InvitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := invitationActor(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	inv, err := h.invites.Invite(r.Context(), r.PathValue("id"), actorID, req.Email, req.Role)
+	if err != nil {
+		writeInvitationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(newInvitationResponse(inv))
+}
+
+// ListInvitations lista las invitaciones sin aceptar ni revocar.
+func (h *InvitationHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	invites, err := h.invites.ListOpen(r.Context(), r.PathValue("id"), actorID)
+	if err != nil {
+		writeInvitationError(w, err)
+		return
+	}
+
+	resp := make([]invitationResponse, 0, len(invites))
+	for _, inv := range invites {
+		resp = append(resp, newInvitationResponse(inv))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"invitations": resp})
+}
+
+// ResendInvitation manda un link nuevo y renueva el vencimiento.
+func (h *InvitationHandler) ResendInvitation(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := invitationActor(w, r)
+	if !ok {
+		return
+	}
+
+	inv, err := h.invites.Resend(r.Context(), r.PathValue("id"), actorID, r.PathValue("invitationId"))
+	if err != nil {
+		writeInvitationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(newInvitationResponse(inv))
+}
+
+func (h *InvitationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := invitationActor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.invites.Revoke(r.Context(), r.PathValue("id"), actorID, r.PathValue("invitationId")); err != nil {
+		writeInvitationError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptInvitation suma al usuario autenticado a la organización de la invitación.
+func (h *InvitationHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := invitationActor(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	h.accept(w, r, req.Token, userID)
+}
+
+// LookupInvitation es interno: auth-service valida la invitación antes de
+// registrar al invitado.
+func (h *InvitationHandler) LookupInvitation(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	inv, err := h.invites.Lookup(r.Context(), req.Token)
+	if err != nil {
+		writeInvitationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(newInvitationResponse(inv))
+}
+
+// AcceptInvitationFor es interno: auth-service acepta la invitación a nombre
+// de la cuenta que acaba de registrar.
+func (h *InvitationHandler) AcceptInvitationFor(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token  string `json:"token"`
+		UserID string `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" || req.UserID == "" {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	h.accept(w, r, req.Token, req.UserID)
+}
+
+func (h *InvitationHandler) accept(w http.ResponseWriter, r *http.Request, token, userID string) {
+	m, err := h.invites.Accept(r.Context(), token, userID)
+	if err != nil {
+		writeInvitationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(membershipResponse{OrgID: m.OrgID, UserID: m.UserID, Role: m.Role})
+}
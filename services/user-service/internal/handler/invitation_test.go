@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/libs/notify"
+	"saas-subscription-platform/services/user-service/internal/middleware"
+	"saas-subscription-platform/services/user-service/internal/model"
+	"saas-subscription-platform/services/user-service/internal/repository"
+	"saas-subscription-platform/services/user-service/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// stubInvitationStore guarda la última invitación creada.
+type stubInvitationStore struct {
+	created *model.Invitation
+}
+
+func (s *stubInvitationStore) Create(_ context.Context, inv model.Invitation) (model.Invitation, error) {
+	inv.ID = "i-1"
+	inv.CreatedAt = time.Now()
+	s.created = &inv
+	return inv, nil
+}
+
+func (s *stubInvitationStore) Get(_ context.Context, id string) (model.Invitation, error) {
+	if s.created == nil || s.created.ID != id {
+		return model.Invitation{}, repository.ErrInvitationNotFound
+	}
+	return *s.created, nil
+}
+
+func (s *stubInvitationStore) ListOpen(_ context.Context, orgID string) ([]model.Invitation, error) {
+	return nil, nil
+}
+
+func (s *stubInvitationStore) Rotate(_ context.Context, id, tokenHash string, expiresAt time.Time) error {
+	return nil
+}
+
+func (s *stubInvitationStore) Revoke(_ context.Context, id string) error {
+	return nil
+}
+
+func (s *stubInvitationStore) Accept(_ context.Context, id, tokenHash, userID string) (model.Membership, error) {
+	return model.Membership{OrgID: s.created.OrgID, UserID: userID, Role: s.created.Role}, nil
+}
+
+type captureSender struct{ last notify.Message }
+
+func (c *captureSender) Send(_ context.Context, msg notify.Message) error {
+	c.last = msg
+	return nil
+}
+
+func newInvitationHandler(store *stubInvitationStore, sender notify.Sender, ttl time.Duration) *InvitationHandler {
+	orgs := stubOrganizationStore{members: map[string]string{"o-1/u-1": model.OrgRoleOwner}}
+	users := stubUserStore{
+		getByEmailFn: func(email string) (model.User, error) { return model.User{}, repository.ErrUserNotFound },
+		getByIDFn:    func(userID string) (model.User, error) { return model.User{ID: userID, Email: "ana@example.com"}, nil },
+	}
+	svc := service.NewInvitationService(orgs, store, users, sender, []byte("k"),
+		service.WithInvitationTTL(ttl), service.WithInvitationAcceptURL("https://app.test/accept"))
+	return NewInvitationHandler(svc)
+}
+
+func TestCreateInvitationHandler(t *testing.T) {
+	sender := &captureSender{}
+	h := newInvitationHandler(&stubInvitationStore{}, sender, time.Hour)
+
+	req := httptest.NewRequest(http.MethodPost, "/orgs/o-1/invitations", bytes.NewBufferString(`{"email":"ana@example.com","role":"member"}`))
+	req.SetPathValue("id", "o-1")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "u-1"))
+	rr := httptest.NewRecorder()
+	h.CreateInvitation(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var resp invitationResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, "pending", resp.Status)
+	require.Equal(t, "ana@example.com", sender.last.To)
+	require.Contains(t, sender.last.Body, "https://app.test/accept?token=")
+	require.NotContains(t, rr.Body.String(), "token")
+
+	// Un agente impersonando no invita.
+	req = httptest.NewRequest(http.MethodPost, "/orgs/o-1/invitations", bytes.NewBufferString(`{"email":"bob@example.com","role":"member"}`))
+	req.SetPathValue("id", "o-1")
+	ctx := context.WithValue(req.Context(), middleware.UserIDKey, "u-1")
+	req = req.WithContext(context.WithValue(ctx, middleware.ActorIDKey, "agent-1"))
+	rr = httptest.NewRecorder()
+	h.CreateInvitation(rr, req)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestAcceptInvitationHandler_Expired(t *testing.T) {
+	store := &stubInvitationStore{}
+	sender := &captureSender{}
+	// Con TTL mínimo el link ya está vencido al aceptarlo.
+	h := newInvitationHandler(store, sender, time.Nanosecond)
+
+	req := httptest.NewRequest(http.MethodPost, "/orgs/o-1/invitations", bytes.NewBufferString(`{"email":"ana@example.com","role":"billing"}`))
+	req.SetPathValue("id", "o-1")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "u-1"))
+	h.CreateInvitation(httptest.NewRecorder(), req)
+	require.NotNil(t, store.created)
+
+	token := sender.last.Body[len("You were invited"):]
+	token = token[bytes.Index([]byte(token), []byte("token="))+len("token="):]
+	token = token[:bytes.IndexByte([]byte(token), '\n')]
+
+	time.Sleep(time.Millisecond)
+	req = httptest.NewRequest(http.MethodPost, "/internal/invitations/accept", bytes.NewBufferString(`{"token":"`+token+`","user_id":"u-2"}`))
+	rr := httptest.NewRecorder()
+	h.AcceptInvitationFor(rr, req)
+	require.Equal(t, http.StatusGone, rr.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/internal/invitations/accept", bytes.NewBufferString(`{"token":"bogus","user_id":"u-2"}`))
+	rr = httptest.NewRecorder()
+	h.AcceptInvitationFor(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
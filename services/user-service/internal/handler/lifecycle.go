@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"saas-subscription-platform/services/user-service/internal/middleware"
+	"saas-subscription-platform/services/user-service/internal/repository"
+	"saas-subscription-platform/services/user-service/internal/service"
+)
+
+// DeactivateUser desactiva la propia cuenta. Solo un admin puede reactivarla.
+func (h *UserHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+
+	if middleware.ActorIDFromContext(r.Context()) != "" {
+		http.Error(w, "deactivating accounts is not allowed while impersonating", http.StatusForbidden)
+		return
+	}
+	if caller, _ := r.Context().Value(middleware.UserIDKey).(string); caller != userID {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	h.writeLifecycleResult(w, "deactivate", h.userService.DeactivateUser(r.Context(), userID))
+}
+
+// SuspendUser bloquea una cuenta. Solo admins.
+func (h *UserHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	h.writeLifecycleResult(w, "suspend", h.userService.SuspendUser(r.Context(), r.PathValue("id")))
+}
+
+// RestoreUser reactiva una cuenta suspendida, desactivada o borrada dentro de la gracia. Solo admins.
+func (h *UserHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	h.writeLifecycleResult(w, "restore", h.userService.RestoreUser(r.Context(), r.PathValue("id")))
+}
+
+func (h *UserHandler) writeLifecycleResult(w http.ResponseWriter, action string, err error) {
+	switch err {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case repository.ErrUserNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case repository.ErrInvalidTransition:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case service.ErrGracePeriodExpired:
+		http.Error(w, err.Error(), http.StatusGone)
+	default:
+		log.Printf("Error on user %s: %v", action, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/middleware"
+	"saas-subscription-platform/services/user-service/internal/model"
+	"saas-subscription-platform/services/user-service/internal/repository"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newLifecycleRequest(path, callerID string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	req.SetPathValue("id", "u-1")
+	return req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, callerID))
+}
+
+func TestDeactivateUserHandler(t *testing.T) {
+	var gotStatus string
+	h := newHandlerWithStore(stubUserStore{
+		transitionFn: func(userID, status string, from ...string) error {
+			gotStatus = status
+			return nil
+		},
+	})
+
+	rr := httptest.NewRecorder()
+	h.DeactivateUser(rr, newLifecycleRequest("/users/u-1/deactivate", "u-1"))
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.Equal(t, model.StatusDeactivated, gotStatus)
+
+	// Solo el propio usuario.
+	rr = httptest.NewRecorder()
+	h.DeactivateUser(rr, newLifecycleRequest("/users/u-1/deactivate", "u-2"))
+	require.Equal(t, http.StatusForbidden, rr.Code)
+
+	req := newLifecycleRequest("/users/u-1/deactivate", "u-1")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ActorIDKey, "agent-1"))
+	rr = httptest.NewRecorder()
+	h.DeactivateUser(rr, req)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestRestoreUserHandler(t *testing.T) {
+	deletedAt := time.Now().Add(-365 * 24 * time.Hour)
+	h := newHandlerWithStore(stubUserStore{
+		restoreFn: func(userID string, deletedAfter time.Time) error {
+			return repository.ErrInvalidTransition
+		},
+		getByIDFn: func(userID string) (model.User, error) {
+			return model.User{ID: userID, Status: model.StatusPendingDeletion, DeletedAt: &deletedAt}, nil
+		},
+	})
+
+	rr := httptest.NewRecorder()
+	h.RestoreUser(rr, newLifecycleRequest("/users/u-1/restore", "admin-1"))
+
+	require.Equal(t, http.StatusGone, rr.Code)
+}
+
+func TestDeleteUserHandler_AlreadyPending(t *testing.T) {
+	h := newHandlerWithStore(stubUserStore{
+		deleteFn: func(userID string) error { return repository.ErrInvalidTransition },
+	})
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/u-1", nil)
+	req.SetPathValue("id", "u-1")
+	rr := httptest.NewRecorder()
+
+	h.DeleteUser(rr, req)
+
+	require.Equal(t, http.StatusConflict, rr.Code)
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"saas-subscription-platform/libs/metadata"
+	"saas-subscription-platform/services/user-service/internal/model"
+	"saas-subscription-platform/services/user-service/internal/service"
+)
+
+type listUsersResponse struct {
+	Users      []UserResponse `json:"users"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	Total      *int           `json:"total,omitempty"`
+}
+
+// ListUsers lista usuarios para soporte/administración.
+// Query: email, role, status, verified, created_from, created_to (RFC 3339),
+// metadata[clave]=valor, limit, cursor e include_total.
+func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	filter, err := parseUserFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	withTotal := q.Get("include_total") == "true"
+
+	page, err := h.userService.ListUsers(r.Context(), filter, q.Get("cursor"), withTotal)
+	if err != nil {
+		if err == service.ErrInvalidCursor {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error listing users: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := listUsersResponse{
+		Users:      make([]UserResponse, 0, len(page.Users)),
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	}
+	for _, user := range page.Users {
+		resp.Users = append(resp.Users, newUserResponse(user))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func parseUserFilter(q url.Values) (model.UserFilter, error) {
+	filter := model.UserFilter{
+		EmailContains: q.Get("email"),
+		Role:          q.Get("role"),
+		Status:        q.Get("status"),
+	}
+
+	switch filter.Role {
+	case "", model.RoleUser, model.RoleSupport, model.RoleAdmin:
+	default:
+		return model.UserFilter{}, errors.New("invalid role")
+	}
+
+	if v := q.Get("verified"); v != "" {
+		verified, err := strconv.ParseBool(v)
+		if err != nil {
+			return model.UserFilter{}, errors.New("invalid verified")
+		}
+		filter.Verified = &verified
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"created_from", &filter.CreatedFrom}, {"created_to", &filter.CreatedTo}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return model.UserFilter{}, errors.New("invalid " + p.name + ": expected RFC 3339")
+		}
+		*p.dst = &t
+	}
+
+	meta, err := metadata.ParseFilter(q)
+	if err != nil {
+		return model.UserFilter{}, err
+	}
+	filter.Metadata = meta
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return model.UserFilter{}, errors.New("invalid limit")
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/middleware"
+	"saas-subscription-platform/services/user-service/internal/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListUsersHandler(t *testing.T) {
+	verifiedAt := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	var got model.UserFilter
+	h := newHandlerWithStore(stubUserStore{
+		listFn: func(filter model.UserFilter) ([]model.User, error) {
+			got = filter
+			return []model.User{{ID: "u-1", Email: "alice@example.com", Password: "hash", Status: model.StatusActive, EmailVerifiedAt: &verifiedAt}}, nil
+		},
+		countFn: func(filter model.UserFilter) (int, error) { return 1, nil },
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users?email=alice&role=user&verified=true&created_from=2025-01-01T00:00:00Z&metadata%5Bcrm_id%5D=C-42&limit=10&include_total=true", nil)
+	rr := httptest.NewRecorder()
+
+	h.ListUsers(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "alice", got.EmailContains)
+	require.Equal(t, "user", got.Role)
+	require.True(t, *got.Verified)
+	require.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), *got.CreatedFrom)
+	require.Equal(t, map[string]string{"crm_id": "C-42"}, got.Metadata)
+	require.Equal(t, 11, got.Limit)
+
+	var resp listUsersResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Len(t, resp.Users, 1)
+	require.Empty(t, resp.Users[0].Password)
+	require.True(t, resp.Users[0].EmailVerified)
+	require.Equal(t, "active", resp.Users[0].Status)
+	require.NotNil(t, resp.Users[0].Metadata)
+	require.Empty(t, resp.NextCursor)
+	require.Equal(t, 1, *resp.Total)
+}
+
+func TestListUsersHandler_BadRequest(t *testing.T) {
+	h := newHandlerWithStore(stubUserStore{})
+
+	for _, query := range []string{"role=root", "verified=maybe", "created_to=yesterday", "limit=0", "cursor=%21%21", "metadata%5B%5D=x"} {
+		req := httptest.NewRequest(http.MethodGet, "/users?"+query, nil)
+		rr := httptest.NewRecorder()
+
+		h.ListUsers(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	h := middleware.RequireRole(model.RoleSupport, model.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for role, want := range map[string]int{"admin": http.StatusNoContent, "support": http.StatusNoContent, "user": http.StatusForbidden, "": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserRoleKey, role))
+		rr := httptest.NewRecorder()
+
+		h.ServeHTTP(rr, req)
+
+		require.Equal(t, want, rr.Code, role)
+	}
+}
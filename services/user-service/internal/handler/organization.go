@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/middleware"
+	"saas-subscription-platform/services/user-service/internal/model"
+	"saas-subscription-platform/services/user-service/internal/repository"
+	"saas-subscription-platform/services/user-service/internal/service"
+)
+
+// OrganizationHandler expone las organizaciones del usuario y la consulta de
+// membresías que usa auth-service al cambiar de organización activa.
+type OrganizationHandler struct {
+	orgs *service.OrganizationService
+}
+
+func NewOrganizationHandler(orgs *service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{orgs: orgs}
+}
+
+type organizationResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+}
+
+type membershipResponse struct {
+	OrgID  string `json:"org_id"`
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// CreateOrganization crea una organización con el usuario como owner.
+func (h *OrganizationHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	// Un agente no crea organizaciones a nombre del usuario.
+	if middleware.ActorIDFromContext(r.Context()) != "" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	org, err := h.orgs.CreateOrganization(r.Context(), userID, req.Name)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidOrgName) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error creating organization: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(organizationResponse{
+		ID:        org.ID,
+		Name:      org.Name,
+		Role:      model.OrgRoleOwner,
+		CreatedAt: org.CreatedAt.Format(time.RFC3339),
+	})
+}
+
+// ListMyOrganizations lista las organizaciones del usuario con su rol en cada una.
+func (h *OrganizationHandler) ListMyOrganizations(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	orgs, err := h.orgs.ListUserOrganizations(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error listing organizations: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]organizationResponse, 0, len(orgs))
+	for _, o := range orgs {
+		resp = append(resp, organizationResponse{
+			ID:        o.ID,
+			Name:      o.Name,
+			Role:      o.Role,
+			CreatedAt: o.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"organizations": resp})
+}
+
+// GetMembership es interno: auth-service lo consulta antes de emitir un token
+// con la organización activa.
+func (h *OrganizationHandler) GetMembership(w http.ResponseWriter, r *http.Request) {
+	m, err := h.orgs.GetMembership(r.Context(), r.PathValue("id"), r.PathValue("userId"))
+	if err != nil {
+		if errors.Is(err, repository.ErrMembershipNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Error getting membership: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(membershipResponse{OrgID: m.OrgID, UserID: m.UserID, Role: m.Role})
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/middleware"
+	"saas-subscription-platform/services/user-service/internal/model"
+	"saas-subscription-platform/services/user-service/internal/repository"
+	"saas-subscription-platform/services/user-service/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+type stubOrganizationStore struct {
+	members map[string]string // org_id/user_id -> role
+}
+
+func (s stubOrganizationStore) Create(_ context.Context, name, ownerID string) (model.Organization, error) {
+	return model.Organization{ID: "o-1", Name: name, CreatedBy: ownerID, CreatedAt: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)}, nil
+}
+
+func (s stubOrganizationStore) Get(_ context.Context, orgID string) (model.Organization, error) {
+	return model.Organization{ID: orgID, Name: "Acme"}, nil
+}
+
+func (s stubOrganizationStore) ListForUser(_ context.Context, userID string) ([]model.UserOrganization, error) {
+	return []model.UserOrganization{{Organization: model.Organization{ID: "o-1", Name: "Acme"}, Role: s.members["o-1/"+userID]}}, nil
+}
+
+func (s stubOrganizationStore) GetMembership(_ context.Context, orgID, userID string) (model.Membership, error) {
+	role, ok := s.members[orgID+"/"+userID]
+	if !ok {
+		return model.Membership{}, repository.ErrMembershipNotFound
+	}
+	return model.Membership{OrgID: orgID, UserID: userID, Role: role}, nil
+}
+
+func newOrganizationHandler() *OrganizationHandler {
+	store := stubOrganizationStore{members: map[string]string{"o-1/u-1": model.OrgRoleOwner}}
+	return NewOrganizationHandler(service.NewOrganizationService(store))
+}
+
+func TestCreateOrganizationHandler(t *testing.T) {
+	h := newOrganizationHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/orgs", bytes.NewBufferString(`{"name":"  Acme  "}`))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "u-1"))
+	rr := httptest.NewRecorder()
+	h.CreateOrganization(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var resp organizationResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, "Acme", resp.Name)
+	require.Equal(t, model.OrgRoleOwner, resp.Role)
+
+	req = httptest.NewRequest(http.MethodPost, "/orgs", bytes.NewBufferString(`{"name":" "}`))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "u-1"))
+	rr = httptest.NewRecorder()
+	h.CreateOrganization(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestGetMembershipHandler(t *testing.T) {
+	h := newOrganizationHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/internal/orgs/o-1/members/u-1", nil)
+	req.SetPathValue("id", "o-1")
+	req.SetPathValue("userId", "u-1")
+	rr := httptest.NewRecorder()
+	h.GetMembership(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp membershipResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, model.OrgRoleOwner, resp.Role)
+
+	req = httptest.NewRequest(http.MethodGet, "/internal/orgs/o-1/members/u-2", nil)
+	req.SetPathValue("id", "o-1")
+	req.SetPathValue("userId", "u-2")
+	rr = httptest.NewRecorder()
+	h.GetMembership(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/middleware"
+	"saas-subscription-platform/services/user-service/internal/model"
+	"saas-subscription-platform/services/user-service/internal/repository"
+	"saas-subscription-platform/services/user-service/internal/service"
+)
+
+// PrivacyHandler expone el export y el borrado GDPR de los datos del usuario.
+type PrivacyHandler struct {
+	privacy *service.PrivacyService
+}
+
+func NewPrivacyHandler(privacy *service.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{privacy: privacy}
+}
+
+type dataRequestStepResponse struct {
+	Service   string `json:"service"`
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+	Error     string `json:"error,omitempty"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type dataRequestResponse struct {
+	ID          string                    `json:"id"`
+	UserID      string                    `json:"user_id"`
+	Kind        string                    `json:"kind"`
+	Status      string                    `json:"status"`
+	RequestedBy string                    `json:"requested_by"`
+	CreatedAt   string                    `json:"created_at"`
+	CompletedAt *string                   `json:"completed_at"`
+	Steps       []dataRequestStepResponse `json:"steps"`
+}
+
+func newDataRequestResponse(req model.DataRequest) dataRequestResponse {
+	resp := dataRequestResponse{
+		ID:          req.ID,
+		UserID:      req.UserID,
+		Kind:        req.Kind,
+		Status:      req.Status,
+		RequestedBy: req.RequestedBy,
+		CreatedAt:   req.CreatedAt.Format(time.RFC3339),
+		Steps:       make([]dataRequestStepResponse, 0, len(req.Steps)),
+	}
+	if req.CompletedAt != nil {
+		v := req.CompletedAt.Format(time.RFC3339)
+		resp.CompletedAt = &v
+	}
+	for _, step := range req.Steps {
+		resp.Steps = append(resp.Steps, dataRequestStepResponse{
+			Service:   step.Service,
+			Status:    step.Status,
+			Attempts:  step.Attempts,
+			Error:     step.Error,
+			UpdatedAt: step.UpdatedAt.Format(time.RFC3339),
+		})
+	}
+	return resp
+}
+
+// canManageData: el titular o un admin, nunca durante una impersonación.
+func canManageData(r *http.Request, userID string) bool {
+	if middleware.ActorIDFromContext(r.Context()) != "" {
+		return false
+	}
+	caller, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.UserRoleKey).(string)
+	return caller == userID || role == model.RoleAdmin
+}
+
+// RequestExport junta los datos del usuario en todos los servicios.
+func (h *PrivacyHandler) RequestExport(w http.ResponseWriter, r *http.Request) {
+	h.start(w, r, h.privacy.RequestExport)
+}
+
+// RequestErasure anonimiza los datos del usuario en todos los servicios.
+func (h *PrivacyHandler) RequestErasure(w http.ResponseWriter, r *http.Request) {
+	h.start(w, r, h.privacy.RequestErasure)
+}
+
+func (h *PrivacyHandler) start(w http.ResponseWriter, r *http.Request, fn func(context.Context, string, string) (model.DataRequest, error)) {
+	userID := r.PathValue("id")
+	if !canManageData(r, userID) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	caller, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	req, err := fn(r.Context(), userID, caller)
+	if err != nil {
+		writePrivacyError(w, err)
+		return
+	}
+	writeDataRequest(w, req)
+}
+
+// GetDataRequest devuelve el avance del pedido en cada servicio.
+func (h *PrivacyHandler) GetDataRequest(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.ownedRequest(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(newDataRequestResponse(req))
+}
+
+// RetryDataRequest vuelve a ejecutar los pasos fallidos.
+func (h *PrivacyHandler) RetryDataRequest(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.ownedRequest(w, r); !ok {
+		return
+	}
+	req, err := h.privacy.Retry(r.Context(), r.PathValue("rid"))
+	if err != nil {
+		writePrivacyError(w, err)
+		return
+	}
+	writeDataRequest(w, req)
+}
+
+// DownloadArchive entrega el export: ZIP con un JSON por servicio (default) o
+// un único JSON con ?format=json.
+func (h *PrivacyHandler) DownloadArchive(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.ownedRequest(w, r); !ok {
+		return
+	}
+	req, files, err := h.privacy.Archive(r.Context(), r.PathValue("rid"))
+	if err != nil {
+		writePrivacyError(w, err)
+		return
+	}
+	manifest := newDataRequestResponse(req)
+
+	if r.URL.Query().Get("format") == "json" {
+		out := make(map[string]any, len(files)+1)
+		out["manifest"] = manifest
+		for svc, data := range files {
+			out[svc] = data
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="user-data-`+req.ID+`.json"`)
+		_ = json.NewEncoder(w).Encode(out)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="user-data-`+req.ID+`.zip"`)
+	zw := zip.NewWriter(w)
+	if err := writeZipJSON(zw, "manifest.json", manifest); err != nil {
+		log.Printf("Error writing data export archive %s: %v", req.ID, err)
+		return
+	}
+	for _, step := range req.Steps {
+		if err := writeZipJSON(zw, step.Service+".json", files[step.Service]); err != nil {
+			log.Printf("Error writing data export archive %s: %v", req.ID, err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("Error writing data export archive %s: %v", req.ID, err)
+	}
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v any) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// ownedRequest carga el pedido {rid} y verifica que sea del usuario {id}. Un
+// pedido de otro usuario responde 404 para no revelar que existe.
+func (h *PrivacyHandler) ownedRequest(w http.ResponseWriter, r *http.Request) (model.DataRequest, bool) {
+	userID := r.PathValue("id")
+	if !canManageData(r, userID) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return model.DataRequest{}, false
+	}
+	req, err := h.privacy.GetRequest(r.Context(), r.PathValue("rid"))
+	if err == nil && req.UserID != userID {
+		err = repository.ErrDataRequestNotFound
+	}
+	if err != nil {
+		writePrivacyError(w, err)
+		return model.DataRequest{}, false
+	}
+	return req, true
+}
+
+// writeDataRequest responde 201 si el pedido terminó en todos los servicios y
+// 202 si quedaron pasos fallidos para reintentar.
+func writeDataRequest(w http.ResponseWriter, req model.DataRequest) {
+	status := http.StatusCreated
+	if req.Status != model.DataRequestCompleted {
+		status = http.StatusAccepted
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(newDataRequestResponse(req))
+}
+
+func writePrivacyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		http.Error(w, "user not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrDataRequestNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrDataRequestNotReady), errors.Is(err, service.ErrDataRequestCompleted):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrArchiveDiscarded):
+		http.Error(w, err.Error(), http.StatusGone)
+	default:
+		log.Printf("Error on data request: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/middleware"
+	"saas-subscription-platform/services/user-service/internal/model"
+	"saas-subscription-platform/services/user-service/internal/repository"
+	"saas-subscription-platform/services/user-service/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// stubDataRequestStore guarda un único pedido en memoria.
+type stubDataRequestStore struct {
+	req *model.DataRequest
+}
+
+func (s *stubDataRequestStore) Create(_ context.Context, req model.DataRequest, services []string) (model.DataRequest, error) {
+	req.ID = "r-1"
+	req.CreatedAt = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, svc := range services {
+		req.Steps = append(req.Steps, model.DataRequestStep{Service: svc, Status: model.StepPending})
+	}
+	s.req = &req
+	return req, nil
+}
+
+func (s *stubDataRequestStore) Get(_ context.Context, id string) (model.DataRequest, error) {
+	if s.req == nil || s.req.ID != id {
+		return model.DataRequest{}, repository.ErrDataRequestNotFound
+	}
+	return *s.req, nil
+}
+
+func (s *stubDataRequestStore) SaveStep(_ context.Context, _ string, step model.DataRequestStep) error {
+	for i := range s.req.Steps {
+		if s.req.Steps[i].Service == step.Service {
+			s.req.Steps[i] = step
+		}
+	}
+	return nil
+}
+
+func (s *stubDataRequestStore) Finish(_ context.Context, _ string, status string) error {
+	s.req.Status = status
+	return nil
+}
+
+func (s *stubDataRequestStore) DiscardExports(context.Context, string) error { return nil }
+
+func newPrivacyHandler() *PrivacyHandler {
+	users := stubUserStore{
+		getByIDFn: func(userID string) (model.User, error) {
+			if userID != "u-1" {
+				return model.User{}, repository.ErrUserNotFound
+			}
+			return model.User{ID: "u-1", Email: "jane@example.com", Password: "hash"}, nil
+		},
+	}
+	return NewPrivacyHandler(service.NewPrivacyService(users, &stubDataRequestStore{}, []byte("k")))
+}
+
+func newPrivacyRequest(method, path, callerID, role string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	req.SetPathValue("id", "u-1")
+	req.SetPathValue("rid", "r-1")
+	ctx := context.WithValue(req.Context(), middleware.UserIDKey, callerID)
+	if role != "" {
+		ctx = context.WithValue(ctx, middleware.UserRoleKey, role)
+	}
+	return req.WithContext(ctx)
+}
+
+func TestPrivacyHandler_ExportAndDownload(t *testing.T) {
+	h := newPrivacyHandler()
+
+	rr := httptest.NewRecorder()
+	h.RequestExport(rr, newPrivacyRequest(http.MethodPost, "/users/u-1/export", "u-1", model.RoleUser))
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var created dataRequestResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
+	require.Equal(t, model.DataRequestCompleted, created.Status)
+	require.Len(t, created.Steps, 1)
+
+	rr = httptest.NewRecorder()
+	h.DownloadArchive(rr, newPrivacyRequest(http.MethodGet, "/users/u-1/data-requests/r-1/archive", "u-1", model.RoleUser))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+
+	zr, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	require.NoError(t, err)
+	names := make([]string, 0, len(zr.File))
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	require.Equal(t, []string{"manifest.json", "user-service.json"}, names)
+
+	rr = httptest.NewRecorder()
+	h.DownloadArchive(rr, newPrivacyRequest(http.MethodGet, "/users/u-1/data-requests/r-1/archive?format=json", "admin-1", model.RoleAdmin))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), `"user-service":{"id":"u-1"`)
+	require.NotContains(t, rr.Body.String(), "hash")
+}
+
+func TestPrivacyHandler_Authorization(t *testing.T) {
+	h := newPrivacyHandler()
+
+	// Otro usuario sin rol admin.
+	rr := httptest.NewRecorder()
+	h.RequestErasure(rr, newPrivacyRequest(http.MethodPost, "/users/u-1/erasure", "u-2", model.RoleSupport))
+	require.Equal(t, http.StatusForbidden, rr.Code)
+
+	// Ni siquiera un admin mientras impersona.
+	req := newPrivacyRequest(http.MethodPost, "/users/u-1/erasure", "u-1", model.RoleUser)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ActorIDKey, "admin-1"))
+	rr = httptest.NewRecorder()
+	h.RequestErasure(rr, req)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+
+	// Pedido inexistente.
+	rr = httptest.NewRecorder()
+	h.GetDataRequest(rr, newPrivacyRequest(http.MethodGet, "/users/u-1/data-requests/r-1", "u-1", model.RoleUser))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -2,12 +2,20 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"saas-subscription-platform/libs/emailaddr"
+	"saas-subscription-platform/libs/metadata"
+	"saas-subscription-platform/services/user-service/internal/middleware"
+	"saas-subscription-platform/services/user-service/internal/model"
 	"saas-subscription-platform/services/user-service/internal/repository"
 	"saas-subscription-platform/services/user-service/internal/service"
 )
 
+// authServiceCaller es el X-Internal-User-ID con el que llama auth-service.
+const authServiceCaller = "auth-service"
+
 type UserHandler struct {
 	userService *service.UserService
 }
@@ -19,35 +27,111 @@ func NewUserHandler(userService *service.UserService) *UserHandler {
 }
 
 type CreateUserRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string            `json:"email"`
+	Password string            `json:"password"`
+	Metadata map[string]string `json:"metadata"`
+	// SSOTenant lo manda solo auth-service al provisionar una cuenta por SAML.
+	SSOTenant string `json:"sso_tenant"`
 }
 
 type UpdateUserRequest struct {
-	Email    *string `json:"email"`
-	Password *string `json:"password"`
+	Email     *string `json:"email"`
+	Password  *string `json:"password"`
+	Name      *string `json:"name"`
+	Locale    *string `json:"locale"`
+	Timezone  *string `json:"timezone"`
+	AvatarURL *string `json:"avatar_url"`
+	Phone     *string `json:"phone"`
+	// Metadata se mezcla con la actual; un valor "" borra la clave.
+	Metadata map[string]string `json:"metadata"`
+}
+
+func (req UpdateUserRequest) profile() model.ProfileUpdate {
+	return model.ProfileUpdate{
+		Name:      req.Name,
+		Locale:    req.Locale,
+		Timezone:  req.Timezone,
+		AvatarURL: req.AvatarURL,
+		Phone:     req.Phone,
+	}
 }
 
 type UserResponse struct {
 	ID        string `json:"id"`
 	Email     string `json:"email"`
 	Password  string `json:"password,omitempty"`
+	Role      string `json:"role"`
+	Name      string `json:"name"`
+	Locale    string `json:"locale"`
+	Timezone  string `json:"timezone"`
+	AvatarURL string `json:"avatar_url"`
+	Phone     string `json:"phone"`
+	Status    string `json:"status"`
+	// Metadata es siempre un objeto (vacío si no hay pares).
+	Metadata map[string]string `json:"metadata"`
+	// EmailVerified es true una vez confirmado el email.
+	EmailVerified bool `json:"email_verified"`
+	// SSOTenant solo se expone a auth-service, junto con el hash de la password.
+	SSOTenant string `json:"sso_tenant,omitempty"`
 	CreatedAt string `json:"created_at"`
 }
 
+func newUserResponse(user model.User) UserResponse {
+	return UserResponse{
+		ID:        user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		Name:      user.Name,
+		Locale:    user.Locale,
+		Timezone:  user.Timezone,
+		AvatarURL: user.AvatarURL,
+		Phone:     user.Phone,
+		Status:    user.Status,
+		Metadata:  orEmptyMetadata(user.Metadata),
+		// EmailVerified se deriva de EmailVerifiedAt.
+		EmailVerified: user.EmailVerifiedAt != nil,
+		CreatedAt:     user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+func orEmptyMetadata(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+// writeProfileError responde 422 con el detalle de cada campo inválido.
+func writeProfileError(w http.ResponseWriter, perr *service.ProfileError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error":  "invalid_profile",
+		"fields": perr.Fields,
+	})
+}
+
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var req CreateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
 	}
+	if caller, _ := r.Context().Value(middleware.UserIDKey).(string); req.SSOTenant != "" && caller != authServiceCaller {
+		http.Error(w, "sso_tenant can only be set by auth-service", http.StatusForbidden)
+		return
+	}
 
-	user, err := h.userService.CreateUser(req.Email, req.Password)
+	user, err := h.userService.CreateUser(r.Context(), req.Email, req.Password, req.Metadata, req.SSOTenant)
 	if err != nil {
 		if err == repository.ErrUserExists {
 			http.Error(w, err.Error(), http.StatusConflict)
 			return
 		}
+		if err == emailaddr.ErrInvalidEmail || errors.Is(err, metadata.ErrInvalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		// Log the actual error for debugging
 		log.Printf("Error creating user: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -56,11 +140,7 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	_ = json.NewEncoder(w).Encode(UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	})
+	_ = json.NewEncoder(w).Encode(newUserResponse(user))
 }
 
 func (h *UserHandler) GetUserByEmail(w http.ResponseWriter, r *http.Request) {
@@ -70,7 +150,7 @@ func (h *UserHandler) GetUserByEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.GetUserByEmail(email)
+	user, err := h.userService.GetUserByEmail(r.Context(), email)
 	if err != nil {
 		if err == repository.ErrUserNotFound {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -83,12 +163,10 @@ func (h *UserHandler) GetUserByEmail(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		Password:  user.Password,
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	})
+	resp := newUserResponse(user)
+	resp.Password = user.Password
+	resp.SSOTenant = user.SSOTenant
+	_ = json.NewEncoder(w).Encode(resp)
 }
 
 func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
@@ -98,7 +176,7 @@ func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.GetUserByID(userID)
+	user, err := h.userService.GetUserByID(r.Context(), userID)
 	if err != nil {
 		if err == repository.ErrUserNotFound {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -111,11 +189,7 @@ func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	})
+	_ = json.NewEncoder(w).Encode(newUserResponse(user))
 }
 
 func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
@@ -131,19 +205,62 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Email == nil && req.Password == nil {
+	profile := req.profile()
+	if req.Email == nil && req.Password == nil && profile.Empty() && req.Metadata == nil {
 		http.Error(w, "no fields to update", http.StatusBadRequest)
 		return
 	}
 
-	err := h.userService.UpdateUser(userID, req.Email, req.Password)
+	// El email solo cambia vía auth-service (POST /api/auth/email/change), que
+	// lo aplica recién cuando se confirma la dirección nueva.
+	if req.Email != nil {
+		http.Error(w, "email must be changed through /api/auth/email/change", http.StatusForbidden)
+		return
+	}
+
+	// La password solo cambia vía auth-service (POST /api/auth/password/change),
+	// que exige la password actual y aplica la política.
+	if req.Password != nil {
+		if middleware.ActorIDFromContext(r.Context()) != "" {
+			http.Error(w, "password changes are not allowed while impersonating", http.StatusForbidden)
+			return
+		}
+		http.Error(w, "password must be changed through /api/auth/password/change", http.StatusForbidden)
+		return
+	}
+
+	// Validamos perfil y metadata antes de escribir para no dejar cambios a medias.
+	if err := metadata.Validate(req.Metadata, true); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !profile.Empty() {
+		if _, err := service.NormalizeProfile(profile); err != nil {
+			var perr *service.ProfileError
+			if errors.As(err, &perr) {
+				writeProfileError(w, perr)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// La metadata va primero: el límite de claves recién se conoce al mezclar.
+	var err error
+	if req.Metadata != nil {
+		_, err = h.userService.UpdateMetadata(r.Context(), userID, req.Metadata)
+	}
+	if err == nil && !profile.Empty() {
+		err = h.userService.UpdateProfile(r.Context(), userID, profile)
+	}
 	if err != nil {
 		if err == repository.ErrUserNotFound {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
-		if err == repository.ErrUserExists {
-			http.Error(w, err.Error(), http.StatusConflict)
+		if errors.Is(err, metadata.ErrInvalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 		// Log the actual error for debugging
@@ -155,6 +272,66 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+type setPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// SetPassword guarda el hash de la nueva password. Solo lo invoca auth-service.
+func (h *UserHandler) SetPassword(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+
+	var req setPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userService.SetPassword(r.Context(), userID, req.Password); err != nil {
+		if err == repository.ErrUserNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Error setting user password: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type replaceEmailRequest struct {
+	CurrentEmail string `json:"current_email"`
+	NewEmail     string `json:"new_email"`
+}
+
+// ReplaceEmail aplica un cambio de email ya confirmado. Solo lo invoca auth-service.
+func (h *UserHandler) ReplaceEmail(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+
+	var req replaceEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CurrentEmail == "" || req.NewEmail == "" {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userService.ReplaceEmail(r.Context(), userID, req.CurrentEmail, req.NewEmail); err != nil {
+		switch err {
+		case repository.ErrEmailMismatch:
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		case repository.ErrUserExists:
+			http.Error(w, err.Error(), http.StatusConflict)
+		case emailaddr.ErrInvalidEmail:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			log.Printf("Error replacing user email: %v", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	userID := r.PathValue("id")
 	if userID == "" {
@@ -162,12 +339,21 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.userService.DeleteUser(userID)
+	if middleware.ActorIDFromContext(r.Context()) != "" {
+		http.Error(w, "deleting users is not allowed while impersonating", http.StatusForbidden)
+		return
+	}
+
+	err := h.userService.DeleteUser(r.Context(), userID)
 	if err != nil {
 		if err == repository.ErrUserNotFound {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
+		if err == repository.ErrInvalidTransition {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		// Log the actual error for debugging
 		log.Printf("Error deleting user: %v", err)
 		http.Error(w, "internal server error", http.StatusInternalServerError)
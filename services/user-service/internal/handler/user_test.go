@@ -2,12 +2,15 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"saas-subscription-platform/services/user-service/internal/middleware"
 	"saas-subscription-platform/services/user-service/internal/model"
 	"saas-subscription-platform/services/user-service/internal/repository"
 	"saas-subscription-platform/services/user-service/internal/service"
@@ -16,33 +19,77 @@ import (
 )
 
 type stubUserStore struct {
-	createFn       func(email, password string) (model.User, error)
-	getByEmailFn   func(email string) (model.User, error)
-	getByIDFn      func(userID string) (model.User, error)
-	updateFieldsFn func(userID string, email, password *string) error
-	deleteFn       func(userID string) error
+	createFn        func(email, password string, metadata map[string]string, ssoTenant string) (model.User, error)
+	getByEmailFn    func(email string) (model.User, error)
+	getByIDFn       func(userID string) (model.User, error)
+	updateFieldsFn  func(userID string, email, password *string) error
+	updateProfileFn func(userID string, p model.ProfileUpdate) error
+	updateMetaFn    func(userID string, patch map[string]string) (map[string]string, error)
+	replaceEmailFn  func(userID, expected, newEmail string) error
+	deleteFn        func(userID string) error
+	listFn          func(filter model.UserFilter) ([]model.User, error)
+	countFn         func(filter model.UserFilter) (int, error)
+	transitionFn    func(userID, status string, from ...string) error
+	restoreFn       func(userID string, deletedAfter time.Time) error
+	anonymizeFn     func(userID string) error
 }
 
-func (s stubUserStore) Create(email, password string) (model.User, error) {
-	return s.createFn(email, password)
+func (s stubUserStore) Create(_ context.Context, email, password string, metadata map[string]string, ssoTenant string) (model.User, error) {
+	return s.createFn(email, password, metadata, ssoTenant)
 }
 
-func (s stubUserStore) GetByEmail(email string) (model.User, error) {
+func (s stubUserStore) GetByEmail(_ context.Context, email string) (model.User, error) {
 	return s.getByEmailFn(email)
 }
 
-func (s stubUserStore) GetByID(userID string) (model.User, error) {
+func (s stubUserStore) GetByID(_ context.Context, userID string) (model.User, error) {
 	return s.getByIDFn(userID)
 }
 
-func (s stubUserStore) UpdateFields(userID string, email, password *string) error {
+func (s stubUserStore) UpdateFields(_ context.Context, userID string, email, password *string) error {
 	return s.updateFieldsFn(userID, email, password)
 }
 
-func (s stubUserStore) Delete(userID string) error {
+func (s stubUserStore) UpdateProfile(_ context.Context, userID string, p model.ProfileUpdate) error {
+	return s.updateProfileFn(userID, p)
+}
+
+func (s stubUserStore) UpdateMetadata(_ context.Context, userID string, patch map[string]string) (map[string]string, error) {
+	return s.updateMetaFn(userID, patch)
+}
+
+func (s stubUserStore) ReplaceEmail(_ context.Context, userID, expected, newEmail string) error {
+	return s.replaceEmailFn(userID, expected, newEmail)
+}
+
+func (s stubUserStore) Delete(_ context.Context, userID string) error {
 	return s.deleteFn(userID)
 }
 
+func (s stubUserStore) List(_ context.Context, filter model.UserFilter) ([]model.User, error) {
+	return s.listFn(filter)
+}
+
+func (s stubUserStore) Count(_ context.Context, filter model.UserFilter) (int, error) {
+	return s.countFn(filter)
+}
+
+func (s stubUserStore) Transition(_ context.Context, userID, status string, from ...string) error {
+	return s.transitionFn(userID, status, from...)
+}
+
+func (s stubUserStore) Restore(_ context.Context, userID string, deletedAfter time.Time) error {
+	return s.restoreFn(userID, deletedAfter)
+}
+
+func (s stubUserStore) PurgeDeleted(_ context.Context, deletedBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s stubUserStore) Anonymize(_ context.Context, userID string) error {
+	return s.anonymizeFn(userID)
+}
+
 func newHandlerWithStore(store service.UserStore) *UserHandler {
 	svc := service.NewUserService(store)
 	return NewUserHandler(svc)
@@ -51,12 +98,12 @@ func newHandlerWithStore(store service.UserStore) *UserHandler {
 func TestCreateUserHandler(t *testing.T) {
 	createdAt := time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC)
 	h := newHandlerWithStore(stubUserStore{
-		createFn: func(email, password string) (model.User, error) {
-			return model.User{ID: "u-1", Email: email, CreatedAt: createdAt}, nil
+		createFn: func(email, password string, metadata map[string]string, ssoTenant string) (model.User, error) {
+			return model.User{ID: "u-1", Email: email, Metadata: metadata, CreatedAt: createdAt}, nil
 		},
 	})
 
-	body := bytes.NewBufferString(`{"email":"alice@example.com","password":"secret"}`)
+	body := bytes.NewBufferString(`{"email":"alice@example.com","password":"secret","metadata":{"crm_id":"C-42"}}`)
 	req := httptest.NewRequest(http.MethodPost, "/users", body)
 	rr := httptest.NewRecorder()
 
@@ -68,11 +115,12 @@ func TestCreateUserHandler(t *testing.T) {
 	require.Equal(t, "u-1", resp.ID)
 	require.Equal(t, "alice@example.com", resp.Email)
 	require.Equal(t, "2024-12-01T10:00:00Z", resp.CreatedAt)
+	require.Equal(t, "C-42", resp.Metadata["crm_id"])
 }
 
 func TestCreateUserHandler_Conflict(t *testing.T) {
 	h := newHandlerWithStore(stubUserStore{
-		createFn: func(email, password string) (model.User, error) {
+		createFn: func(email, password string, metadata map[string]string, ssoTenant string) (model.User, error) {
 			return model.User{}, repository.ErrUserExists
 		},
 	})
@@ -85,6 +133,42 @@ func TestCreateUserHandler_Conflict(t *testing.T) {
 	require.Equal(t, http.StatusConflict, rr.Code)
 }
 
+func TestCreateUserHandler_InvalidEmail(t *testing.T) {
+	h := newHandlerWithStore(stubUserStore{})
+
+	req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewBufferString(`{"email":"not an email","password":"x"}`))
+	rr := httptest.NewRecorder()
+
+	h.CreateUser(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestCreateUserHandler_SSOTenantOnlyFromAuthService(t *testing.T) {
+	var gotTenant string
+	h := newHandlerWithStore(stubUserStore{
+		createFn: func(email, password string, metadata map[string]string, ssoTenant string) (model.User, error) {
+			gotTenant = ssoTenant
+			return model.User{ID: "u-1", Email: email, SSOTenant: ssoTenant}, nil
+		},
+	})
+	body := `{"email":"alice@acme.com","password":"hash","sso_tenant":"acme"}`
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "u-9"))
+	rr := httptest.NewRecorder()
+	h.CreateUser(rr, req)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.Empty(t, gotTenant)
+
+	req = httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "auth-service"))
+	rr = httptest.NewRecorder()
+	h.CreateUser(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+	require.Equal(t, "acme", gotTenant)
+}
+
 func TestGetUserByEmailHandler(t *testing.T) {
 	createdAt := time.Date(2024, 12, 2, 9, 0, 0, 0, time.UTC)
 	h := newHandlerWithStore(stubUserStore{
@@ -123,40 +207,111 @@ func TestGetUserByEmailHandler_NotFound(t *testing.T) {
 	require.Equal(t, http.StatusNotFound, rr.Code)
 }
 
-func TestUpdateUserHandler(t *testing.T) {
-	var capturedEmail *string
+func TestUpdateUserHandler_EmailRejected(t *testing.T) {
+	// Sin updateFieldsFn: el email no se toca por PATCH.
+	h := newHandlerWithStore(stubUserStore{})
+
+	req := httptest.NewRequest(http.MethodPatch, "/users/u-1", bytes.NewBufferString(`{"email":"new@example.com","name":"Alice"}`))
+	req.SetPathValue("id", "u-1")
+	rr := httptest.NewRecorder()
+
+	h.UpdateUser(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.Contains(t, rr.Body.String(), "/api/auth/email/change")
+}
+
+func TestUpdateUserHandler_Profile(t *testing.T) {
+	var captured model.ProfileUpdate
 	h := newHandlerWithStore(stubUserStore{
-		updateFieldsFn: func(userID string, email, password *string) error {
-			capturedEmail = email
+		updateProfileFn: func(userID string, p model.ProfileUpdate) error {
+			captured = p
 			return nil
 		},
 	})
 
-	req := httptest.NewRequest(http.MethodPatch, "/users/u-1", bytes.NewBufferString(`{"email":"new@example.com"}`))
+	body := `{"name":" Alice ","locale":"es-ar","timezone":"America/Argentina/Buenos_Aires","phone":"+54 9 11 2233-4455"}`
+	req := httptest.NewRequest(http.MethodPatch, "/users/u-1", bytes.NewBufferString(body))
 	req.SetPathValue("id", "u-1")
 	rr := httptest.NewRecorder()
 
 	h.UpdateUser(rr, req)
 
 	require.Equal(t, http.StatusNoContent, rr.Code)
-	require.NotNil(t, capturedEmail)
-	require.Equal(t, "new@example.com", *capturedEmail)
+	require.Equal(t, "Alice", *captured.Name)
+	require.Equal(t, "es-AR", *captured.Locale)
+	require.Equal(t, "America/Argentina/Buenos_Aires", *captured.Timezone)
+	require.Equal(t, "+5491122334455", *captured.Phone)
+	require.Nil(t, captured.AvatarURL)
 }
 
-func TestUpdateUserHandler_Conflict(t *testing.T) {
+func TestUpdateUserHandler_Metadata(t *testing.T) {
+	var captured map[string]string
 	h := newHandlerWithStore(stubUserStore{
-		updateFieldsFn: func(userID string, email, password *string) error {
-			return repository.ErrUserExists
+		updateMetaFn: func(userID string, patch map[string]string) (map[string]string, error) {
+			captured = patch
+			return map[string]string{"crm_id": "C-42"}, nil
 		},
 	})
 
-	req := httptest.NewRequest(http.MethodPatch, "/users/u-1", bytes.NewBufferString(`{"email":"dup@example.com"}`))
+	req := httptest.NewRequest(http.MethodPatch, "/users/u-1", bytes.NewBufferString(`{"metadata":{"crm_id":"C-42","legacy_id":""}}`))
 	req.SetPathValue("id", "u-1")
 	rr := httptest.NewRecorder()
+	h.UpdateUser(rr, req)
+
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.Equal(t, map[string]string{"crm_id": "C-42", "legacy_id": ""}, captured)
 
+	// Una clave demasiado larga se rechaza antes de tocar la base.
+	req = httptest.NewRequest(http.MethodPatch, "/users/u-1", bytes.NewBufferString(`{"metadata":{"`+strings.Repeat("k", 41)+`":"v"}}`))
+	req.SetPathValue("id", "u-1")
+	rr = httptest.NewRecorder()
 	h.UpdateUser(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
 
-	require.Equal(t, http.StatusConflict, rr.Code)
+func TestUpdateUserHandler_InvalidProfile(t *testing.T) {
+	// Sin updateProfileFn: un perfil inválido no llega a la base.
+	h := newHandlerWithStore(stubUserStore{})
+
+	body := `{"locale":"??","timezone":"Mars/Olympus","avatar_url":"http://example.com/a.png","phone":"12345"}`
+	req := httptest.NewRequest(http.MethodPatch, "/users/u-1", bytes.NewBufferString(body))
+	req.SetPathValue("id", "u-1")
+	rr := httptest.NewRecorder()
+
+	h.UpdateUser(rr, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	var resp struct {
+		Error  string            `json:"error"`
+		Fields map[string]string `json:"fields"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, "invalid_profile", resp.Error)
+	require.Len(t, resp.Fields, 4)
+	require.Contains(t, resp.Fields, "avatar_url")
+}
+
+func TestGetUserByIDHandler_Profile(t *testing.T) {
+	h := newHandlerWithStore(stubUserStore{
+		getByIDFn: func(userID string) (model.User, error) {
+			return model.User{ID: userID, Email: "alice@example.com", Name: "Alice", Locale: "es-AR", Timezone: "Europe/Madrid", Password: "hash"}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/u-1", nil)
+	req.SetPathValue("id", "u-1")
+	rr := httptest.NewRecorder()
+
+	h.GetUserByID(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp UserResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, "Alice", resp.Name)
+	require.Equal(t, "es-AR", resp.Locale)
+	require.Equal(t, "Europe/Madrid", resp.Timezone)
+	require.Empty(t, resp.Password)
 }
 
 func TestDeleteUserHandler(t *testing.T) {
@@ -187,6 +342,22 @@ func TestDeleteUserHandler_NotFound(t *testing.T) {
 	require.Equal(t, http.StatusNotFound, rr.Code)
 }
 
+func TestDeleteUserHandler_OtherUserForbidden(t *testing.T) {
+	// Sin deleteFn: borrar una cuenta ajena no llega a la base.
+	h := newHandlerWithStore(stubUserStore{})
+	mux := http.NewServeMux()
+	mux.Handle("DELETE /users/{id}", middleware.InternalAuth(middleware.RequireSelfOrRole(model.RoleAdmin)(http.HandlerFunc(h.DeleteUser))))
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/u-1", nil)
+	req.Header.Set("X-Internal-User-ID", "u-2")
+	req.Header.Set(middleware.HeaderUserRole, model.RoleSupport)
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
 func TestUpdateUserHandler_NoFields(t *testing.T) {
 	h := newHandlerWithStore(stubUserStore{})
 
@@ -198,3 +369,137 @@ func TestUpdateUserHandler_NoFields(t *testing.T) {
 
 	require.Equal(t, http.StatusBadRequest, rr.Code)
 }
+
+func TestUpdateUserHandler_PasswordBlockedWhileImpersonating(t *testing.T) {
+	h := newHandlerWithStore(stubUserStore{})
+
+	req := httptest.NewRequest(http.MethodPatch, "/users/u-1", bytes.NewBufferString(`{"password":"x"}`))
+	req.SetPathValue("id", "u-1")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ActorIDKey, "agent-1"))
+	rr := httptest.NewRecorder()
+
+	h.UpdateUser(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestDeleteUserHandler_BlockedWhileImpersonating(t *testing.T) {
+	h := newHandlerWithStore(stubUserStore{})
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/u-1", nil)
+	req.SetPathValue("id", "u-1")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ActorIDKey, "agent-1"))
+	rr := httptest.NewRecorder()
+
+	h.DeleteUser(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestUpdateUserHandler_PasswordBlocked(t *testing.T) {
+	h := newHandlerWithStore(stubUserStore{})
+
+	req := httptest.NewRequest(http.MethodPatch, "/users/u-1", bytes.NewBufferString(`{"password":"hash"}`))
+	req.SetPathValue("id", "u-1")
+	rr := httptest.NewRecorder()
+
+	h.UpdateUser(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestSetPasswordHandler(t *testing.T) {
+	var captured *string
+	h := newHandlerWithStore(stubUserStore{
+		updateFieldsFn: func(userID string, email, password *string) error {
+			require.Nil(t, email)
+			captured = password
+			return nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/users/u-1/password", bytes.NewBufferString(`{"password":"new-hash"}`))
+	req.SetPathValue("id", "u-1")
+	rr := httptest.NewRecorder()
+
+	h.SetPassword(rr, req)
+
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.Equal(t, "new-hash", *captured)
+}
+
+func TestReplaceEmailHandler(t *testing.T) {
+	h := newHandlerWithStore(stubUserStore{
+		replaceEmailFn: func(userID, expected, newEmail string) error {
+			if expected != "old@example.com" {
+				return repository.ErrEmailMismatch
+			}
+			if newEmail == "taken@example.com" {
+				return repository.ErrUserExists
+			}
+			return nil
+		},
+	})
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"current_email":"old@example.com","new_email":"new@example.com"}`, http.StatusNoContent},
+		{`{"current_email":"stale@example.com","new_email":"new@example.com"}`, http.StatusPreconditionFailed},
+		{`{"current_email":"old@example.com","new_email":"taken@example.com"}`, http.StatusConflict},
+		{`{"current_email":"old@example.com"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/users/u-1/email", bytes.NewBufferString(tt.body))
+		req.SetPathValue("id", "u-1")
+		rr := httptest.NewRecorder()
+
+		h.ReplaceEmail(rr, req)
+
+		require.Equal(t, tt.want, rr.Code, tt.body)
+	}
+}
+
+func TestRequireSelfOrRole(t *testing.T) {
+	h := middleware.RequireSelfOrRole(model.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		caller, role string
+		want         int
+	}{
+		{caller: "u-1", role: "user", want: http.StatusNoContent},
+		{caller: "u-2", role: "admin", want: http.StatusNoContent},
+		{caller: "u-2", role: "user", want: http.StatusForbidden},
+		{caller: "u-2", role: "support", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPatch, "/users/u-1", nil)
+		req.SetPathValue("id", "u-1")
+		ctx := context.WithValue(req.Context(), middleware.UserIDKey, tt.caller)
+		req = req.WithContext(context.WithValue(ctx, middleware.UserRoleKey, tt.role))
+		rr := httptest.NewRecorder()
+
+		h.ServeHTTP(rr, req)
+
+		require.Equal(t, tt.want, rr.Code, tt.caller+"/"+tt.role)
+	}
+}
+
+func TestRequireCaller(t *testing.T) {
+	h := middleware.RequireCaller("auth-service")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for caller, want := range map[string]int{"auth-service": http.StatusNoContent, "u-1": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodPut, "/users/u-1/password", nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, caller))
+		rr := httptest.NewRecorder()
+
+		h.ServeHTTP(rr, req)
+
+		require.Equal(t, want, rr.Code, caller)
+	}
+}
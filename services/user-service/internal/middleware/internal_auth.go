@@ -9,7 +9,18 @@ import (
 
 type contextKey string
 
-const UserIDKey contextKey = "user_id"
+const (
+	UserIDKey   contextKey = "user_id"
+	ActorIDKey  contextKey = "actor_id"
+	UserRoleKey contextKey = "user_role"
+)
+
+// HeaderUserRole es el rol global del usuario, tomado del JWT por el gateway.
+const HeaderUserRole = "X-Internal-User-Role"
+
+// HeaderActorID lo agrega el gateway cuando el token es de impersonación:
+// identifica al agente de soporte que actúa en nombre del usuario.
+const HeaderActorID = "X-Internal-Actor-ID"
 
 // InternalAuth lee el header interno X-Internal-User-ID y lo pone en el contexto
 // Los microservicios confían en este header que viene del API Gateway
@@ -22,7 +33,65 @@ func InternalAuth(next http.Handler) http.Handler {
 		}
 
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
+		if actorID := r.Header.Get(HeaderActorID); actorID != "" {
+			ctx = context.WithValue(ctx, ActorIDKey, actorID)
+		}
+		if role := r.Header.Get(HeaderUserRole); role != "" {
+			ctx = context.WithValue(ctx, UserRoleKey, role)
+		}
 		ctx = trace.ExtractAndUpdateContext(ctx, r, "user-service")
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// ActorIDFromContext devuelve el agente que impersona al usuario ("" si no hay).
+func ActorIDFromContext(ctx context.Context) string {
+	if v, ok := ctx.Value(ActorIDKey).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// RequireCaller restringe una ruta a otro servicio interno (ej: "auth-service"),
+// identificado por el valor de X-Internal-User-ID. Debe ir después de InternalAuth.
+func RequireCaller(service string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if caller, _ := r.Context().Value(UserIDKey).(string); caller != service {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole restringe una ruta a los roles dados. Debe ir después de InternalAuth.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := r.Context().Value(UserRoleKey).(string)
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			http.Error(w, "forbidden", http.StatusForbidden)
+		})
+	}
+}
+
+// RequireSelfOrRole restringe una ruta /users/{id} al propio usuario o a los
+// roles dados. Debe ir después de InternalAuth.
+func RequireSelfOrRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if caller, _ := r.Context().Value(UserIDKey).(string); caller != "" && caller == r.PathValue("id") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			RequireRole(roles...)(next).ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Tipos de pedido GDPR.
+const (
+	DataRequestExport  = "export"
+	DataRequestErasure = "erasure"
+)
+
+// Estados de un pedido; completed solo cuando todos los pasos terminaron.
+const (
+	DataRequestPending   = "pending"
+	DataRequestCompleted = "completed"
+	DataRequestFailed    = "failed"
+)
+
+// Estados de cada paso (uno por servicio).
+const (
+	StepPending = "pending"
+	StepDone    = "done"
+	StepFailed  = "failed"
+)
+
+// DataRequest es un pedido de export o borrado de los datos de un usuario.
+type DataRequest struct {
+	ID          string
+	UserID      string
+	Kind        string
+	Status      string
+	RequestedBy string
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+	Steps       []DataRequestStep
+}
+
+// DataRequestStep es el avance del pedido en un servicio.
+type DataRequestStep struct {
+	Service  string
+	Status   string
+	Attempts int
+	Error    string
+	// Result son los datos exportados (solo en exports terminados).
+	Result    json.RawMessage
+	UpdatedAt time.Time
+}
@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// Roles dentro de una organización.
+const (
+	OrgRoleOwner   = "owner"   // todo, incluido borrar la organización
+	OrgRoleAdmin   = "admin"   // miembros y configuración
+	OrgRoleBilling = "billing" // facturas y medios de pago
+	OrgRoleMember  = "member"  // uso del producto
+)
+
+// ValidOrgRole indica si role es uno de los roles de organización.
+func ValidOrgRole(role string) bool {
+	switch role {
+	case OrgRoleOwner, OrgRoleAdmin, OrgRoleBilling, OrgRoleMember:
+		return true
+	}
+	return false
+}
+
+type Organization struct {
+	ID        string
+	Name      string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// Membership es la pertenencia de un usuario a una organización.
+type Membership struct {
+	OrgID     string
+	UserID    string
+	Role      string
+	CreatedAt time.Time
+}
+
+// UserOrganization es una organización vista desde uno de sus miembros.
+type UserOrganization struct {
+	Organization
+	Role string
+}
+
+// Invitation invita a un email a sumarse a una organización con un rol.
+// Está abierta mientras no se acepte ni se revoque; vence en ExpiresAt.
+type Invitation struct {
+	ID    string
+	OrgID string
+	Email string
+	Role  string
+	// TokenHash es el hash del secreto del link vigente; cambia con cada reenvío.
+	TokenHash  string
+	InvitedBy  string
+	ExpiresAt  time.Time
+	AcceptedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+// Open indica si la invitación todavía se puede aceptar, reenviar o revocar.
+func (i Invitation) Open() bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil
+}
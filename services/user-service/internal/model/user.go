@@ -2,10 +2,72 @@ package model
 
 import "time"
 
+// Estados de cuenta. Solo active puede loguearse y usar la API.
+const (
+	StatusActive          = "active"
+	StatusSuspended       = "suspended"        // por un admin
+	StatusDeactivated     = "deactivated"      // por el propio usuario
+	StatusPendingDeletion = "pending_deletion" // se purga al vencer la gracia
+)
+
+// Roles globales de usuario.
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
 type User struct {
 	ID        string
 	Email     string
 	Name      string
 	Password  string // hash
+	Role      string
+	Locale    string // tag BCP 47
+	Timezone  string // zona IANA
+	AvatarURL string
+	Phone     string // E.164
+	Status    string
+	// Metadata son pares clave/valor libres de las integraciones (nunca nil al leer).
+	Metadata map[string]string
+	// SSOTenant es el tenant SAML que creó la cuenta ("" si no vino por SSO).
+	SSOTenant string
+	// EmailVerifiedAt es nil hasta que el usuario confirma su email.
+	EmailVerifiedAt *time.Time
+	// DeletedAt es cuándo se pidió el borrado (solo con StatusPendingDeletion).
+	DeletedAt *time.Time
+	CreatedAt time.Time
+}
+
+// UserCursor es la posición de paginación keyset (created_at, id).
+type UserCursor struct {
 	CreatedAt time.Time
+	ID        string
+}
+
+// UserFilter son los criterios del listado de usuarios; los campos vacíos no filtran.
+type UserFilter struct {
+	EmailContains string
+	Role          string
+	Status        string
+	Verified      *bool
+	CreatedFrom   *time.Time        // inclusive
+	CreatedTo     *time.Time        // exclusive
+	Metadata      map[string]string // todos los pares deben coincidir
+	After         *UserCursor
+	Limit         int
+}
+
+// ProfileUpdate contiene los campos de perfil a modificar; nil mantiene el valor actual.
+type ProfileUpdate struct {
+	Name      *string
+	Locale    *string
+	Timezone  *string
+	AvatarURL *string
+	Phone     *string
+}
+
+// Empty indica que no hay ningún campo para actualizar.
+func (p ProfileUpdate) Empty() bool {
+	return p.Name == nil && p.Locale == nil && p.Timezone == nil && p.AvatarURL == nil && p.Phone == nil
 }
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/services/user-service/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrDataRequestNotFound = errors.New("data request not found")
+
+type DataRequestRepository struct {
+	db     PgxPool
+	limits dbquery.Limits
+}
+
+func NewDataRequestRepository(db PgxPool, opts ...Option) *DataRequestRepository {
+	r := &DataRequestRepository{db: db}
+	for _, opt := range opts {
+		opt(&r.limits)
+	}
+	return r
+}
+
+// Create guarda el pedido con un paso pendiente por servicio, en ese orden.
+func (r *DataRequestRepository) Create(ctx context.Context, req model.DataRequest, services []string) (model.DataRequest, error) {
+	ctx, done := r.limits.Start(ctx, "data_requests.create")
+	defer done()
+
+	req.ID = generateUUID()
+	req.Status = model.DataRequestPending
+
+	query := `
+		INSERT INTO data_requests (id, user_id, kind, status, requested_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+	err := r.db.QueryRow(ctx, query, req.ID, req.UserID, req.Kind, req.Status, req.RequestedBy).Scan(&req.CreatedAt)
+	if err != nil {
+		return model.DataRequest{}, err
+	}
+
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO data_request_steps (request_id, service, position)
+		SELECT $1, s.service, s.position
+		FROM unnest($2::text[]) WITH ORDINALITY AS s(service, position)
+	`, req.ID, services)
+	if err != nil {
+		return model.DataRequest{}, err
+	}
+
+	req.Steps = make([]model.DataRequestStep, 0, len(services))
+	for _, svc := range services {
+		req.Steps = append(req.Steps, model.DataRequestStep{Service: svc, Status: model.StepPending, UpdatedAt: req.CreatedAt})
+	}
+	return req, nil
+}
+
+// Get devuelve el pedido con sus pasos en el orden en que se crearon.
+func (r *DataRequestRepository) Get(ctx context.Context, id string) (model.DataRequest, error) {
+	ctx, done := r.limits.Start(ctx, "data_requests.get")
+	defer done()
+
+	var req model.DataRequest
+	query := `
+		SELECT id, user_id, kind, status, requested_by, created_at, completed_at
+		FROM data_requests
+		WHERE id = $1
+	`
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&req.ID, &req.UserID, &req.Kind, &req.Status, &req.RequestedBy, &req.CreatedAt, &req.CompletedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.DataRequest{}, ErrDataRequestNotFound
+		}
+		return model.DataRequest{}, err
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT service, status, attempts, error, result, updated_at
+		FROM data_request_steps
+		WHERE request_id = $1
+		ORDER BY position
+	`, id)
+	if err != nil {
+		return model.DataRequest{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var step model.DataRequestStep
+		if err := rows.Scan(&step.Service, &step.Status, &step.Attempts, &step.Error, &step.Result, &step.UpdatedAt); err != nil {
+			return model.DataRequest{}, err
+		}
+		req.Steps = append(req.Steps, step)
+	}
+	return req, rows.Err()
+}
+
+// SaveStep registra el resultado de un intento sobre el paso.
+func (r *DataRequestRepository) SaveStep(ctx context.Context, requestID string, step model.DataRequestStep) error {
+	ctx, done := r.limits.Start(ctx, "data_requests.save_step")
+	defer done()
+
+	query := `
+		UPDATE data_request_steps
+		SET status = $3, error = $4, result = $5, attempts = attempts + 1, updated_at = now()
+		WHERE request_id = $1 AND service = $2
+	`
+	ct, err := r.db.Exec(ctx, query, requestID, step.Service, step.Status, step.Error, step.Result)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrDataRequestNotFound
+	}
+	return nil
+}
+
+// Finish actualiza el estado del pedido; completed_at solo se fija al completarse.
+func (r *DataRequestRepository) Finish(ctx context.Context, id, status string) error {
+	ctx, done := r.limits.Start(ctx, "data_requests.finish")
+	defer done()
+
+	query := `
+		UPDATE data_requests
+		SET status = $2, completed_at = CASE WHEN $2 = 'completed' THEN now() ELSE NULL END
+		WHERE id = $1
+	`
+	ct, err := r.db.Exec(ctx, query, id, status)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrDataRequestNotFound
+	}
+	return nil
+}
+
+// DiscardExports borra los archivos exportados del usuario (se llama al borrarlo).
+func (r *DataRequestRepository) DiscardExports(ctx context.Context, userID string) error {
+	ctx, done := r.limits.Start(ctx, "data_requests.discard_exports")
+	defer done()
+
+	query := `
+		UPDATE data_request_steps
+		SET result = NULL
+		WHERE request_id IN (SELECT id FROM data_requests WHERE user_id = $1 AND kind = 'export')
+	`
+	_, err := r.db.Exec(ctx, query, userID)
+	return err
+}
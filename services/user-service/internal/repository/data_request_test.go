@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func newDataRequestRepo(t *testing.T) (*DataRequestRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockPool.Close() })
+	return NewDataRequestRepository(mockPool), mockPool
+}
+
+func TestDataRequestRepository_Create(t *testing.T) {
+	repo, mock := newDataRequestRepo(t)
+	created := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	services := []string{"auth-service", "billing-service", "user-service"}
+
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO data_requests")).
+		WithArgs(pgxmock.AnyArg(), "u-1", model.DataRequestErasure, model.DataRequestPending, "admin-1").
+		WillReturnRows(pgxmock.NewRows([]string{"created_at"}).AddRow(created))
+	mock.ExpectExec(regexp.QuoteMeta("FROM unnest($2::text[]) WITH ORDINALITY")).
+		WithArgs(pgxmock.AnyArg(), services).
+		WillReturnResult(pgxmock.NewResult("INSERT", 3))
+
+	req, err := repo.Create(context.Background(), model.DataRequest{UserID: "u-1", Kind: model.DataRequestErasure, RequestedBy: "admin-1"}, services)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, req.ID)
+	require.Len(t, req.Steps, 3)
+	require.Equal(t, "user-service", req.Steps[2].Service)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDataRequestRepository_Get(t *testing.T) {
+	repo, mock := newDataRequestRepo(t)
+	created := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM data_requests")).
+		WithArgs("r-1").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "user_id", "kind", "status", "requested_by", "created_at", "completed_at"}).
+			AddRow("r-1", "u-1", "export", "failed", "u-1", created, nil))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM data_request_steps")).
+		WithArgs("r-1").
+		WillReturnRows(pgxmock.NewRows([]string{"service", "status", "attempts", "error", "result", "updated_at"}).
+			AddRow("user-service", "done", 1, "", []byte(`{"id":"u-1"}`), created).
+			AddRow("auth-service", "failed", 2, "timeout", nil, created))
+
+	req, err := repo.Get(context.Background(), "r-1")
+
+	require.NoError(t, err)
+	require.Len(t, req.Steps, 2)
+	require.JSONEq(t, `{"id":"u-1"}`, string(req.Steps[0].Result))
+	require.Equal(t, "timeout", req.Steps[1].Error)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM data_requests")).
+		WithArgs("missing").
+		WillReturnError(pgx.ErrNoRows)
+	_, err = repo.Get(context.Background(), "missing")
+	require.ErrorIs(t, err, ErrDataRequestNotFound)
+}
@@ -4,10 +4,20 @@ import (
 	"errors"
 	"strings"
 
+	"saas-subscription-platform/libs/dbquery"
+
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// Option configura un repositorio.
+type Option func(*dbquery.Limits)
+
+// WithQueryLimits aplica a cada query un deadline y el log de queries lentas.
+func WithQueryLimits(l dbquery.Limits) Option {
+	return func(dst *dbquery.Limits) { *dst = l }
+}
+
 func generateUUID() string {
 	return uuid.NewString()
 }
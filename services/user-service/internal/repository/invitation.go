@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/services/user-service/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvitationNotFound = errors.New("invitation not found")
+	// ErrInvitationExists: ya hay una invitación abierta y vigente para ese email.
+	ErrInvitationExists = errors.New("invitation already pending for this email")
+)
+
+const invitationColumns = `id, org_id, email, role, token_hash, invited_by, expires_at, accepted_at, revoked_at, created_at`
+
+type InvitationRepository struct {
+	db     PgxPool
+	limits dbquery.Limits
+}
+
+func NewInvitationRepository(db PgxPool, opts ...Option) *InvitationRepository {
+	r := &InvitationRepository{db: db}
+	for _, opt := range opts {
+		opt(&r.limits)
+	}
+	return r
+}
+
+func scanInvitation(row pgx.Row) (model.Invitation, error) {
+	var inv model.Invitation
+	err := row.Scan(&inv.ID, &inv.OrgID, &inv.Email, &inv.Role, &inv.TokenHash, &inv.InvitedBy,
+		&inv.ExpiresAt, &inv.AcceptedAt, &inv.RevokedAt, &inv.CreatedAt)
+	return inv, err
+}
+
+// Create guarda una invitación nueva. Una invitación abierta pero vencida para
+// el mismo email se revoca antes, así se puede volver a invitar.
+func (r *InvitationRepository) Create(ctx context.Context, inv model.Invitation) (model.Invitation, error) {
+	ctx, done := r.limits.Start(ctx, "invitations.create")
+	defer done()
+
+	_, err := r.db.Exec(ctx, `
+		UPDATE org_invitations
+		SET revoked_at = now()
+		WHERE org_id = $1 AND lower(email) = lower($2)
+		  AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= now()
+	`, inv.OrgID, inv.Email)
+	if err != nil {
+		return model.Invitation{}, err
+	}
+
+	inv.ID = generateUUID()
+	err = r.db.QueryRow(ctx, `
+		INSERT INTO org_invitations (id, org_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`, inv.ID, inv.OrgID, inv.Email, inv.Role, inv.TokenHash, inv.InvitedBy, inv.ExpiresAt).Scan(&inv.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.Invitation{}, ErrInvitationExists
+		}
+		return model.Invitation{}, err
+	}
+	return inv, nil
+}
+
+func (r *InvitationRepository) Get(ctx context.Context, id string) (model.Invitation, error) {
+	ctx, done := r.limits.Start(ctx, "invitations.get")
+	defer done()
+
+	inv, err := scanInvitation(r.db.QueryRow(ctx, `SELECT `+invitationColumns+` FROM org_invitations WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Invitation{}, ErrInvitationNotFound
+		}
+		return model.Invitation{}, err
+	}
+	return inv, nil
+}
+
+// ListOpen devuelve las invitaciones abiertas de la organización (vencidas incluidas), las más nuevas primero.
+func (r *InvitationRepository) ListOpen(ctx context.Context, orgID string) ([]model.Invitation, error) {
+	ctx, done := r.limits.Start(ctx, "invitations.list_open")
+	defer done()
+
+	rows, err := r.db.Query(ctx, `
+		SELECT `+invitationColumns+`
+		FROM org_invitations
+		WHERE org_id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+		ORDER BY created_at DESC, id
+	`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []model.Invitation{}
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, rows.Err()
+}
+
+// Rotate cambia el token y el vencimiento de una invitación abierta (reenvío):
+// el link anterior deja de servir.
+func (r *InvitationRepository) Rotate(ctx context.Context, id, tokenHash string, expiresAt time.Time) error {
+	ctx, done := r.limits.Start(ctx, "invitations.rotate")
+	defer done()
+
+	tag, err := r.db.Exec(ctx, `
+		UPDATE org_invitations
+		SET token_hash = $2, expires_at = $3
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+	`, id, tokenHash, expiresAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+func (r *InvitationRepository) Revoke(ctx context.Context, id string) error {
+	ctx, done := r.limits.Start(ctx, "invitations.revoke")
+	defer done()
+
+	tag, err := r.db.Exec(ctx, `
+		UPDATE org_invitations
+		SET revoked_at = now()
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// Accept marca la invitación como usada y suma a userID a la organización, en
+// una sola sentencia: dos aceptaciones concurrentes no pueden pasar las dos.
+// Si el usuario ya era miembro conserva su rol.
+func (r *InvitationRepository) Accept(ctx context.Context, id, tokenHash, userID string) (model.Membership, error) {
+	ctx, done := r.limits.Start(ctx, "invitations.accept")
+	defer done()
+
+	m := model.Membership{UserID: userID}
+	err := r.db.QueryRow(ctx, `
+		WITH inv AS (
+			UPDATE org_invitations
+			SET accepted_at = now(), accepted_by = $3
+			WHERE id = $1 AND token_hash = $2
+			  AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > now()
+			RETURNING org_id, role
+		), ins AS (
+			INSERT INTO organization_members (org_id, user_id, role)
+			SELECT org_id, $3, role FROM inv
+			ON CONFLICT (org_id, user_id) DO NOTHING
+			RETURNING role, created_at
+		)
+		SELECT inv.org_id, COALESCE(ins.role, m.role), COALESCE(ins.created_at, m.created_at)
+		FROM inv
+		LEFT JOIN ins ON true
+		LEFT JOIN organization_members m ON m.org_id = inv.org_id AND m.user_id = $3
+	`, id, tokenHash, userID).Scan(&m.OrgID, &m.Role, &m.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Membership{}, ErrInvitationNotFound
+		}
+		return model.Membership{}, err
+	}
+	return m, nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func newInvitationRepo(t *testing.T) (*InvitationRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockPool.Close() })
+	return NewInvitationRepository(mockPool), mockPool
+}
+
+func TestInvitationRepository_CreateRevokesExpiredFirst(t *testing.T) {
+	repo, mock := newInvitationRepo(t)
+	created := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	inv := model.Invitation{OrgID: "o-1", Email: "ana@example.com", Role: model.OrgRoleMember, TokenHash: "hash", InvitedBy: "u-1", ExpiresAt: created.Add(time.Hour)}
+
+	mock.ExpectExec(regexp.QuoteMeta("expires_at <= now()")).
+		WithArgs("o-1", "ana@example.com").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO org_invitations")).
+		WithArgs(pgxmock.AnyArg(), "o-1", "ana@example.com", model.OrgRoleMember, "hash", "u-1", inv.ExpiresAt).
+		WillReturnRows(pgxmock.NewRows([]string{"created_at"}).AddRow(created))
+
+	got, err := repo.Create(context.Background(), inv)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, got.ID)
+	require.Equal(t, created, got.CreatedAt)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInvitationRepository_CreateOpenInvitationExists(t *testing.T) {
+	repo, mock := newInvitationRepo(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("expires_at <= now()")).
+		WithArgs("o-1", "ana@example.com").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO org_invitations")).
+		WithArgs(pgxmock.AnyArg(), "o-1", "ana@example.com", "", "hash", "", pgxmock.AnyArg()).
+		WillReturnError(&pgconn.PgError{Code: "23505"})
+
+	_, err := repo.Create(context.Background(), model.Invitation{OrgID: "o-1", Email: "ana@example.com", TokenHash: "hash"})
+
+	require.ErrorIs(t, err, ErrInvitationExists)
+}
+
+func TestInvitationRepository_Accept(t *testing.T) {
+	repo, mock := newInvitationRepo(t)
+	joined := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SET accepted_at = now(), accepted_by = $3")).
+		WithArgs("i-1", "hash", "u-2").
+		WillReturnRows(pgxmock.NewRows([]string{"org_id", "role", "created_at"}).AddRow("o-1", model.OrgRoleBilling, joined))
+
+	m, err := repo.Accept(context.Background(), "i-1", "hash", "u-2")
+
+	require.NoError(t, err)
+	require.Equal(t, model.Membership{OrgID: "o-1", UserID: "u-2", Role: model.OrgRoleBilling, CreatedAt: joined}, m)
+
+	// Usada, revocada, vencida o con un token viejo: no devuelve filas.
+	mock.ExpectQuery(regexp.QuoteMeta("SET accepted_at = now(), accepted_by = $3")).
+		WithArgs("i-1", "hash", "u-2").
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err = repo.Accept(context.Background(), "i-1", "hash", "u-2")
+	require.ErrorIs(t, err, ErrInvitationNotFound)
+}
+
+func TestInvitationRepository_RevokeClosedInvitation(t *testing.T) {
+	repo, mock := newInvitationRepo(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("SET revoked_at = now()")).
+		WithArgs("i-1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	require.ErrorIs(t, repo.Revoke(context.Background(), "i-1"), ErrInvitationNotFound)
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/services/user-service/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrMembershipNotFound   = errors.New("membership not found")
+)
+
+type OrganizationRepository struct {
+	db     PgxPool
+	limits dbquery.Limits
+}
+
+func NewOrganizationRepository(db PgxPool, opts ...Option) *OrganizationRepository {
+	r := &OrganizationRepository{db: db}
+	for _, opt := range opts {
+		opt(&r.limits)
+	}
+	return r
+}
+
+// Create guarda la organización con ownerID como owner, en una sola sentencia
+// para que nunca quede una organización sin dueño.
+func (r *OrganizationRepository) Create(ctx context.Context, name, ownerID string) (model.Organization, error) {
+	ctx, done := r.limits.Start(ctx, "organizations.create")
+	defer done()
+
+	org := model.Organization{ID: generateUUID(), Name: name, CreatedBy: ownerID}
+	query := `
+		WITH org AS (
+			INSERT INTO organizations (id, name, created_by)
+			VALUES ($1, $2, $3)
+			RETURNING id, created_at
+		)
+		INSERT INTO organization_members (org_id, user_id, role)
+		SELECT id, $3, 'owner' FROM org
+		RETURNING (SELECT created_at FROM org)
+	`
+	if err := r.db.QueryRow(ctx, query, org.ID, org.Name, ownerID).Scan(&org.CreatedAt); err != nil {
+		return model.Organization{}, err
+	}
+	return org, nil
+}
+
+func (r *OrganizationRepository) Get(ctx context.Context, orgID string) (model.Organization, error) {
+	ctx, done := r.limits.Start(ctx, "organizations.get")
+	defer done()
+
+	org := model.Organization{ID: orgID}
+	err := r.db.QueryRow(ctx, `
+		SELECT name, created_by, created_at
+		FROM organizations
+		WHERE id = $1
+	`, orgID).Scan(&org.Name, &org.CreatedBy, &org.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Organization{}, ErrOrganizationNotFound
+		}
+		return model.Organization{}, err
+	}
+	return org, nil
+}
+
+// ListForUser devuelve las organizaciones de las que userID es miembro, por nombre.
+func (r *OrganizationRepository) ListForUser(ctx context.Context, userID string) ([]model.UserOrganization, error) {
+	ctx, done := r.limits.Start(ctx, "organizations.list_for_user")
+	defer done()
+
+	rows, err := r.db.Query(ctx, `
+		SELECT o.id, o.name, o.created_by, o.created_at, m.role
+		FROM organization_members m
+		JOIN organizations o ON o.id = m.org_id
+		WHERE m.user_id = $1
+		ORDER BY o.name, o.id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []model.UserOrganization{}
+	for rows.Next() {
+		var o model.UserOrganization
+		if err := rows.Scan(&o.ID, &o.Name, &o.CreatedBy, &o.CreatedAt, &o.Role); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+// GetMembership devuelve el rol de userID en orgID.
+func (r *OrganizationRepository) GetMembership(ctx context.Context, orgID, userID string) (model.Membership, error) {
+	ctx, done := r.limits.Start(ctx, "organizations.get_membership")
+	defer done()
+
+	m := model.Membership{OrgID: orgID, UserID: userID}
+	err := r.db.QueryRow(ctx, `
+		SELECT role, created_at
+		FROM organization_members
+		WHERE org_id = $1 AND user_id = $2
+	`, orgID, userID).Scan(&m.Role, &m.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Membership{}, ErrMembershipNotFound
+		}
+		return model.Membership{}, err
+	}
+	return m, nil
+}
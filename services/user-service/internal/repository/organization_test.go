@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func newOrganizationRepo(t *testing.T) (*OrganizationRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockPool.Close() })
+	return NewOrganizationRepository(mockPool), mockPool
+}
+
+func TestOrganizationRepository_CreateAddsOwner(t *testing.T) {
+	repo, mock := newOrganizationRepo(t)
+	created := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, $3, 'owner' FROM org")).
+		WithArgs(pgxmock.AnyArg(), "Acme", "u-1").
+		WillReturnRows(pgxmock.NewRows([]string{"created_at"}).AddRow(created))
+
+	org, err := repo.Create(context.Background(), "Acme", "u-1")
+
+	require.NoError(t, err)
+	require.NotEmpty(t, org.ID)
+	require.Equal(t, "u-1", org.CreatedBy)
+	require.Equal(t, created, org.CreatedAt)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOrganizationRepository_ListForUser(t *testing.T) {
+	repo, mock := newOrganizationRepo(t)
+	created := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE m.user_id = $1")).
+		WithArgs("u-1").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name", "created_by", "created_at", "role"}).
+			AddRow("o-1", "Acme", "u-1", created, model.OrgRoleOwner).
+			AddRow("o-2", "Globex", "u-9", created, model.OrgRoleBilling))
+
+	orgs, err := repo.ListForUser(context.Background(), "u-1")
+
+	require.NoError(t, err)
+	require.Len(t, orgs, 2)
+	require.Equal(t, model.OrgRoleBilling, orgs[1].Role)
+}
+
+func TestOrganizationRepository_GetMembership(t *testing.T) {
+	repo, mock := newOrganizationRepo(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM organization_members")).
+		WithArgs("o-1", "u-1").
+		WillReturnRows(pgxmock.NewRows([]string{"role", "created_at"}).AddRow(model.OrgRoleAdmin, time.Now()))
+	m, err := repo.GetMembership(context.Background(), "o-1", "u-1")
+	require.NoError(t, err)
+	require.Equal(t, model.OrgRoleAdmin, m.Role)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM organization_members")).
+		WithArgs("o-1", "u-2").
+		WillReturnError(pgx.ErrNoRows)
+	_, err = repo.GetMembership(context.Background(), "o-1", "u-2")
+	require.ErrorIs(t, err, ErrMembershipNotFound)
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"saas-subscription-platform/services/user-service/internal/model"
+)
+
+// userWhere arma el WHERE del listado. Devuelve "" si no hay filtros.
+func userWhere(f model.UserFilter, withCursor bool) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.EmailContains != "" {
+		conds = append(conds, "email ILIKE '%' || "+arg(escapeLike(f.EmailContains))+" || '%'")
+	}
+	if f.Role != "" {
+		conds = append(conds, "role = "+arg(f.Role))
+	}
+	if f.Status != "" {
+		conds = append(conds, "status = "+arg(f.Status))
+	}
+	if f.Verified != nil {
+		if *f.Verified {
+			conds = append(conds, "email_verified_at IS NOT NULL")
+		} else {
+			conds = append(conds, "email_verified_at IS NULL")
+		}
+	}
+	if f.CreatedFrom != nil {
+		conds = append(conds, "created_at >= "+arg(*f.CreatedFrom))
+	}
+	if f.CreatedTo != nil {
+		conds = append(conds, "created_at < "+arg(*f.CreatedTo))
+	}
+	if len(f.Metadata) > 0 {
+		conds = append(conds, "metadata @> "+arg(f.Metadata)+"::jsonb")
+	}
+	if withCursor && f.After != nil {
+		conds = append(conds, "(created_at, id) < ("+arg(f.After.CreatedAt)+", "+arg(f.After.ID)+")")
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+// escapeLike evita que % y _ del término se interpreten como comodines.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// List devuelve hasta f.Limit usuarios, del más nuevo al más viejo, a partir de f.After.
+func (r *UserRepository) List(ctx context.Context, f model.UserFilter) ([]model.User, error) {
+	ctx, done := r.limits.Start(ctx, "users.list")
+	defer done()
+
+	where, args := userWhere(f, true)
+	args = append(args, f.Limit)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM users
+		%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d
+	`, userColumns, where, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]model.User, 0, f.Limit)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// Count devuelve cuántos usuarios cumplen el filtro, ignorando el cursor.
+func (r *UserRepository) Count(ctx context.Context, f model.UserFilter) (int, error) {
+	ctx, done := r.limits.Start(ctx, "users.count")
+	defer done()
+
+	where, args := userWhere(f, false)
+	var total int
+	err := r.db.QueryRow(ctx, "SELECT count(*) FROM users "+where, args...).Scan(&total)
+	return total, err
+}
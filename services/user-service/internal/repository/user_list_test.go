@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/model"
+
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRepository_ListWithFiltersAndCursor(t *testing.T) {
+	repo, mock := newTestRepo(t)
+
+	verified := true
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	after := model.UserCursor{CreatedAt: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), ID: "u-9"}
+	created := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE email ILIKE '%' || $1 || '%' AND role = $2 AND email_verified_at IS NOT NULL AND created_at >= $3 AND (created_at, id) < ($4, $5) ORDER BY created_at DESC, id DESC LIMIT $6")).
+		WithArgs(`50\%\_off`, "support", from, after.CreatedAt, after.ID, 2).
+		WillReturnRows(pgxmock.NewRows(userRowColumns).
+			AddRow("u-8", "a@example.com", "hash", "support", created, "", "en", "UTC", "", "", "active", &created, nil, map[string]string{}, "").
+			AddRow("u-7", "b@example.com", "hash", "support", created, "", "en", "UTC", "", "", "active", &created, nil, map[string]string{}, ""))
+
+	users, err := repo.List(context.Background(), model.UserFilter{
+		EmailContains: "50%_off",
+		Role:          "support",
+		Verified:      &verified,
+		CreatedFrom:   &from,
+		After:         &after,
+		Limit:         2,
+	})
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	require.Equal(t, "u-8", users[0].ID)
+	require.NotNil(t, users[0].EmailVerifiedAt)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_ListByMetadata(t *testing.T) {
+	repo, mock := newTestRepo(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE metadata @> $1::jsonb ORDER BY created_at DESC, id DESC LIMIT $2")).
+		WithArgs(map[string]string{"crm_id": "C-42"}, 10).
+		WillReturnRows(pgxmock.NewRows(userRowColumns))
+
+	_, err := repo.List(context.Background(), model.UserFilter{Metadata: map[string]string{"crm_id": "C-42"}, Limit: 10})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_CountIgnoresCursor(t *testing.T) {
+	repo, mock := newTestRepo(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM users WHERE status = $1")).
+		WithArgs("active").
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(42))
+
+	total, err := repo.Count(context.Background(), model.UserFilter{
+		Status: "active",
+		After:  &model.UserCursor{CreatedAt: time.Now(), ID: "u-1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 42, total)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,7 +3,11 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/libs/metadata"
 	"saas-subscription-platform/services/user-service/internal/model"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -12,40 +16,80 @@ import (
 var ErrUserNotFound = errors.New("user not found")
 var ErrUserExists = errors.New("user already exists")
 
+// ErrInvalidTransition indica que la cuenta no está en un estado desde el que se pueda pasar al pedido.
+var ErrInvalidTransition = errors.New("invalid account status transition")
+
+// ErrEmailMismatch indica que el email actual ya no es el esperado (cambio concurrente o confirmación reutilizada).
+var ErrEmailMismatch = errors.New("current email does not match")
+
 // PgxPool define las operaciones mínimas que usamos; la implementan *pgxpool.Pool y pgxmock.PgxPoolIface.
 type PgxPool interface {
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
 	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
 }
 
+// userColumns es el orden de columnas que espera scanUser.
+const userColumns = "id, email, password, role, created_at, name, locale, timezone, avatar_url, phone, status, email_verified_at, deleted_at, metadata, sso_tenant"
+
+func scanUser(row pgx.Row) (model.User, error) {
+	var user model.User
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Role, &user.CreatedAt,
+		&user.Name, &user.Locale, &user.Timezone, &user.AvatarURL, &user.Phone, &user.Status, &user.EmailVerifiedAt, &user.DeletedAt, &user.Metadata, &user.SSOTenant)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.User{}, ErrUserNotFound
+		}
+		return model.User{}, err
+	}
+	return user, nil
+}
+
 type UserRepository struct {
-	db PgxPool
+	db     PgxPool
+	limits dbquery.Limits
 }
 
-func NewUserRepository(db PgxPool) *UserRepository {
-	return &UserRepository{db: db}
+func NewUserRepository(db PgxPool, opts ...Option) *UserRepository {
+	r := &UserRepository{db: db}
+	for _, opt := range opts {
+		opt(&r.limits)
+	}
+	return r
 }
 
-func (r *UserRepository) Create(email, password string) (model.User, error) {
+// Create guarda un usuario nuevo; ssoTenant es el tenant SAML que lo
+// provisiona ("" en un registro con password).
+func (r *UserRepository) Create(ctx context.Context, email, password string, metadata map[string]string, ssoTenant string) (model.User, error) {
+	ctx, done := r.limits.Start(ctx, "users.create")
+	defer done()
+
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
 	user := model.User{
-		ID:       generateUUID(),
-		Email:    email,
-		Password: password,
+		ID:        generateUUID(),
+		Email:     email,
+		Password:  password,
+		Metadata:  metadata,
+		SSOTenant: ssoTenant,
 	}
 
 	query := `
-		INSERT INTO users (id, email, password)
-		VALUES ($1, $2, $3)
-		RETURNING created_at
+		INSERT INTO users (id, email, password, metadata, sso_tenant)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING role, created_at, locale, timezone, status
 	`
 
 	err := r.db.QueryRow(
-		context.Background(),
+		ctx,
 		query,
 		user.ID,
 		user.Email,
 		user.Password,
-	).Scan(&user.CreatedAt)
+		user.Metadata,
+		user.SSOTenant,
+	).Scan(&user.Role, &user.CreatedAt, &user.Locale, &user.Timezone, &user.Status)
 
 	if err != nil {
 		if isUniqueViolation(err) {
@@ -57,52 +101,38 @@ func (r *UserRepository) Create(email, password string) (model.User, error) {
 	return user, nil
 }
 
-func (r *UserRepository) GetByEmail(email string) (model.User, error) {
-	var user model.User
+// GetByEmail ignora mayúsculas, igual que el índice único sobre lower(email).
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (model.User, error) {
+	ctx, done := r.limits.Start(ctx, "users.get_by_email")
+	defer done()
 
 	query := `
-		SELECT id, email, password, created_at
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE email = $1
+		WHERE lower(email) = lower($1)
 	`
 
-	err := r.db.QueryRow(context.Background(), query, email).
-		Scan(&user.ID, &user.Email, &user.Password, &user.CreatedAt)
-
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return model.User{}, ErrUserNotFound
-		}
-		return model.User{}, err
-	}
-
-	return user, nil
+	return scanUser(r.db.QueryRow(ctx, query, email))
 }
 
-func (r *UserRepository) GetByID(userID string) (model.User, error) {
-	var user model.User
+func (r *UserRepository) GetByID(ctx context.Context, userID string) (model.User, error) {
+	ctx, done := r.limits.Start(ctx, "users.get_by_id")
+	defer done()
 
 	query := `
-		SELECT id, email, password, created_at
+		SELECT ` + userColumns + `
 		FROM users
 		WHERE id = $1
 	`
 
-	err := r.db.QueryRow(context.Background(), query, userID).
-		Scan(&user.ID, &user.Email, &user.Password, &user.CreatedAt)
-
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return model.User{}, ErrUserNotFound
-		}
-		return model.User{}, err
-	}
-
-	return user, nil
+	return scanUser(r.db.QueryRow(ctx, query, userID))
 }
 
 // UpdateFields actualiza email y/o password (si el puntero es nil, mantiene el valor actual).
-func (r *UserRepository) UpdateFields(userID string, email *string, password *string) error {
+func (r *UserRepository) UpdateFields(ctx context.Context, userID string, email *string, password *string) error {
+	ctx, done := r.limits.Start(ctx, "users.update_fields")
+	defer done()
+
 	query := `
 		UPDATE users
 		SET
@@ -111,7 +141,7 @@ func (r *UserRepository) UpdateFields(userID string, email *string, password *st
 		WHERE id = $3
 	`
 
-	ct, err := r.db.Exec(context.Background(), query, email, password, userID)
+	ct, err := r.db.Exec(ctx, query, email, password, userID)
 	if err != nil {
 		if isUniqueViolation(err) {
 			return ErrUserExists
@@ -126,9 +156,181 @@ func (r *UserRepository) UpdateFields(userID string, email *string, password *st
 	return nil
 }
 
-func (r *UserRepository) Delete(userID string) error {
-	query := `DELETE FROM users WHERE id = $1`
-	ct, err := r.db.Exec(context.Background(), query, userID)
+// UpdateProfile actualiza los campos de perfil presentes en p (nil mantiene el valor actual).
+func (r *UserRepository) UpdateProfile(ctx context.Context, userID string, p model.ProfileUpdate) error {
+	ctx, done := r.limits.Start(ctx, "users.update_profile")
+	defer done()
+
+	query := `
+		UPDATE users
+		SET
+			name = COALESCE($1, name),
+			locale = COALESCE($2, locale),
+			timezone = COALESCE($3, timezone),
+			avatar_url = COALESCE($4, avatar_url),
+			phone = COALESCE($5, phone)
+		WHERE id = $6
+	`
+
+	ct, err := r.db.Exec(ctx, query, p.Name, p.Locale, p.Timezone, p.AvatarURL, p.Phone, userID)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateMetadata aplica un patch de metadata (los valores "" borran la clave)
+// y devuelve el resultado. El merge y el límite de claves se resuelven en una
+// sola sentencia para que dos updates concurrentes no lo superen.
+func (r *UserRepository) UpdateMetadata(ctx context.Context, userID string, patch map[string]string) (map[string]string, error) {
+	ctx, done := r.limits.Start(ctx, "users.update_metadata")
+	defer done()
+
+	set, unset := metadata.Split(patch)
+	query := `
+		UPDATE users
+		SET metadata = (metadata || $2::jsonb) - $3::text[]
+		WHERE id = $1
+			AND (SELECT count(*) FROM jsonb_object_keys((metadata || $2::jsonb) - $3::text[])) <= $4
+		RETURNING metadata
+	`
+
+	var merged map[string]string
+	err := r.db.QueryRow(ctx, query, userID, set, unset, metadata.MaxKeys).Scan(&merged)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Sin fila: o el usuario no existe o el resultado supera el límite.
+		var exists bool
+		if err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("%w: at most %d keys", metadata.ErrInvalid, metadata.MaxKeys)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// ReplaceEmail cambia el email solo si el actual sigue siendo expected. El
+// compare-and-swap hace que cada confirmación de cambio sea de un solo uso.
+// El nuevo email queda verificado: solo se llega acá confirmando el link.
+func (r *UserRepository) ReplaceEmail(ctx context.Context, userID, expected, newEmail string) error {
+	ctx, done := r.limits.Start(ctx, "users.replace_email")
+	defer done()
+
+	query := `
+		UPDATE users
+		SET email = $3, email_verified_at = now()
+		WHERE id = $1 AND lower(email) = lower($2)
+	`
+
+	ct, err := r.db.Exec(ctx, query, userID, expected, newEmail)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrUserExists
+		}
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return ErrEmailMismatch
+	}
+
+	return nil
+}
+
+// Delete es el borrado lógico: la cuenta queda pending_deletion hasta que
+// PurgeDeleted la elimina. Las facturas siguen apuntando a un user_id válido.
+func (r *UserRepository) Delete(ctx context.Context, userID string) error {
+	return r.Transition(ctx, userID, model.StatusPendingDeletion,
+		model.StatusActive, model.StatusSuspended, model.StatusDeactivated)
+}
+
+// Transition pasa la cuenta a status si hoy está en alguno de from.
+func (r *UserRepository) Transition(ctx context.Context, userID, status string, from ...string) error {
+	ctx, done := r.limits.Start(ctx, "users.transition")
+	defer done()
+
+	query := `
+		UPDATE users
+		SET
+			status = $2,
+			status_changed_at = now(),
+			deleted_at = CASE WHEN $2 = 'pending_deletion' THEN now() ELSE NULL END
+		WHERE id = $1 AND status = ANY($3)
+	`
+
+	ct, err := r.db.Exec(ctx, query, userID, status, from)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return r.transitionError(ctx, userID)
+	}
+	return nil
+}
+
+// Restore reactiva la cuenta. Una cuenta pending_deletion solo se recupera si
+// el borrado se pidió después de deletedAfter (dentro de la gracia) y no fue
+// anonimizada.
+func (r *UserRepository) Restore(ctx context.Context, userID string, deletedAfter time.Time) error {
+	ctx, done := r.limits.Start(ctx, "users.restore")
+	defer done()
+
+	query := `
+		UPDATE users
+		SET status = 'active', status_changed_at = now(), deleted_at = NULL
+		WHERE id = $1
+			AND status IN ('suspended', 'deactivated', 'pending_deletion')
+			AND (status <> 'pending_deletion' OR deleted_at > $2)
+			AND erased_at IS NULL
+	`
+
+	ct, err := r.db.Exec(ctx, query, userID, deletedAfter)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return r.transitionError(ctx, userID)
+	}
+	return nil
+}
+
+// Anonymize reemplaza los datos personales de la cuenta (borrado GDPR). La
+// fila queda pending_deletion para que la purga la elimine; el email se
+// reemplaza por uno inválido pero único.
+func (r *UserRepository) Anonymize(ctx context.Context, userID string) error {
+	ctx, done := r.limits.Start(ctx, "users.anonymize")
+	defer done()
+
+	query := `
+		UPDATE users
+		SET
+			email = 'erased+' || id || '@invalid',
+			password = '',
+			name = '',
+			avatar_url = '',
+			phone = '',
+			metadata = '{}'::jsonb,
+			locale = 'en',
+			timezone = 'UTC',
+			email_verified_at = NULL,
+			status = 'pending_deletion',
+			status_changed_at = now(),
+			deleted_at = COALESCE(deleted_at, now()),
+			erased_at = COALESCE(erased_at, now())
+		WHERE id = $1
+	`
+
+	ct, err := r.db.Exec(ctx, query, userID)
 	if err != nil {
 		return err
 	}
@@ -137,3 +339,29 @@ func (r *UserRepository) Delete(userID string) error {
 	}
 	return nil
 }
+
+// transitionError distingue entre cuenta inexistente y estado incompatible.
+func (r *UserRepository) transitionError(ctx context.Context, userID string) error {
+	var status string
+	err := r.db.QueryRow(ctx, `SELECT status FROM users WHERE id = $1`, userID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrInvalidTransition
+}
+
+// PurgeDeleted elimina definitivamente las cuentas cuyo borrado se pidió antes de deletedBefore.
+func (r *UserRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, done := r.limits.Start(ctx, "users.purge_deleted")
+	defer done()
+
+	query := `DELETE FROM users WHERE status = 'pending_deletion' AND deleted_at < $1`
+	ct, err := r.db.Exec(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
@@ -1,16 +1,23 @@
 package repository
 
 import (
+	"context"
 	"regexp"
 	"testing"
 	"time"
 
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/libs/metadata"
+	"saas-subscription-platform/services/user-service/internal/model"
+
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	pgxmock "github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/require"
 )
 
+var userRowColumns = []string{"id", "email", "password", "role", "created_at", "name", "locale", "timezone", "avatar_url", "phone", "status", "email_verified_at", "deleted_at", "metadata", "sso_tenant"}
+
 func newTestRepo(t *testing.T) (*UserRepository, pgxmock.PgxPoolIface) {
 	t.Helper()
 	mockPool, err := pgxmock.NewPool()
@@ -22,14 +29,18 @@ func newTestRepo(t *testing.T) (*UserRepository, pgxmock.PgxPoolIface) {
 func TestUserRepository_Create(t *testing.T) {
 	repo, mock := newTestRepo(t)
 
-	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO users (id, email, password)")).
-		WithArgs(pgxmock.AnyArg(), "alice@example.com", "hash").
-		WillReturnRows(pgxmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO users (id, email, password, metadata, sso_tenant)")).
+		WithArgs(pgxmock.AnyArg(), "alice@example.com", "hash", map[string]string{"crm_id": "C-42"}, "").
+		WillReturnRows(pgxmock.NewRows([]string{"role", "created_at", "locale", "timezone", "status"}).AddRow("user", time.Now(), "en", "UTC", "active"))
 
-	user, err := repo.Create("alice@example.com", "hash")
+	user, err := repo.Create(context.Background(), "alice@example.com", "hash", map[string]string{"crm_id": "C-42"}, "")
 
 	require.NoError(t, err)
 	require.Equal(t, "alice@example.com", user.Email)
+	require.Equal(t, "user", user.Role)
+	require.Equal(t, "en", user.Locale)
+	require.Equal(t, "UTC", user.Timezone)
+	require.Equal(t, "C-42", user.Metadata["crm_id"])
 	require.NotEmpty(t, user.ID)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
@@ -37,11 +48,11 @@ func TestUserRepository_Create(t *testing.T) {
 func TestUserRepository_CreateDuplicate(t *testing.T) {
 	repo, mock := newTestRepo(t)
 
-	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO users (id, email, password)")).
-		WithArgs(pgxmock.AnyArg(), "dup@example.com", "hash").
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO users (id, email, password, metadata, sso_tenant)")).
+		WithArgs(pgxmock.AnyArg(), "dup@example.com", "hash", map[string]string{}, "").
 		WillReturnError(&pgconn.PgError{Code: "23505"})
 
-	_, err := repo.Create("dup@example.com", "hash")
+	_, err := repo.Create(context.Background(), "dup@example.com", "hash", nil, "")
 
 	require.ErrorIs(t, err, ErrUserExists)
 }
@@ -50,19 +61,26 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 	repo, mock := newTestRepo(t)
 	created := time.Now()
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, email, password, created_at FROM users")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, email, password, role, created_at, name, locale, timezone, avatar_url, phone, status, email_verified_at, deleted_at, metadata, sso_tenant FROM users WHERE lower(email) = lower($1)")).
 		WithArgs("alice@example.com").
-		WillReturnRows(pgxmock.NewRows([]string{"id", "email", "password", "created_at"}).AddRow("id-1", "alice@example.com", "hash", created))
+		WillReturnRows(pgxmock.NewRows(userRowColumns).
+			AddRow("id-1", "alice@example.com", "hash", "admin", created, "Alice", "es-AR", "America/Argentina/Buenos_Aires", "", "+5491122334455", "active", (*time.Time)(nil), (*time.Time)(nil), map[string]string{"crm_id": "C-42"}, ""))
 
-	user, err := repo.GetByEmail("alice@example.com")
+	user, err := repo.GetByEmail(context.Background(), "alice@example.com")
 	require.NoError(t, err)
 	require.Equal(t, "id-1", user.ID)
+	require.Equal(t, "admin", user.Role)
+	require.Equal(t, "Alice", user.Name)
+	require.Equal(t, "es-AR", user.Locale)
+	require.Equal(t, "America/Argentina/Buenos_Aires", user.Timezone)
+	require.Equal(t, "+5491122334455", user.Phone)
+	require.Nil(t, user.EmailVerifiedAt)
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, email, password, created_at FROM users")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, email, password, role, created_at, name, locale, timezone, avatar_url, phone, status, email_verified_at, deleted_at, metadata, sso_tenant FROM users")).
 		WithArgs("missing@example.com").
 		WillReturnError(pgx.ErrNoRows)
 
-	_, err = repo.GetByEmail("missing@example.com")
+	_, err = repo.GetByEmail(context.Background(), "missing@example.com")
 	require.ErrorIs(t, err, ErrUserNotFound)
 }
 
@@ -75,21 +93,167 @@ func TestUserRepository_UpdateAndDelete(t *testing.T) {
 		WithArgs(&newEmail, &newPass, "user-1").
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 
-	require.NoError(t, repo.UpdateFields("user-1", &newEmail, &newPass))
+	require.NoError(t, repo.UpdateFields(context.Background(), "user-1", &newEmail, &newPass))
 
 	mock.ExpectExec(regexp.QuoteMeta("UPDATE users")).
 		WithArgs((*string)(nil), (*string)(nil), "user-2").
 		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
 
-	require.ErrorIs(t, repo.UpdateFields("user-2", nil, nil), ErrUserNotFound)
+	require.ErrorIs(t, repo.UpdateFields(context.Background(), "user-2", nil, nil), ErrUserNotFound)
 
-	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM users")).
-		WithArgs("user-1").
-		WillReturnResult(pgxmock.NewResult("DELETE", 1))
-	require.NoError(t, repo.Delete("user-1"))
+	// Delete es lógico: pasa la cuenta a pending_deletion.
+	deletable := []string{"active", "suspended", "deactivated"}
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users")).
+		WithArgs("user-1", "pending_deletion", deletable).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	require.NoError(t, repo.Delete(context.Background(), "user-1"))
 
-	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM users")).
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users")).
+		WithArgs("user-3", "pending_deletion", deletable).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT status FROM users")).
 		WithArgs("user-3").
-		WillReturnResult(pgxmock.NewResult("DELETE", 0))
-	require.ErrorIs(t, repo.Delete("user-3"), ErrUserNotFound)
+		WillReturnError(pgx.ErrNoRows)
+	require.ErrorIs(t, repo.Delete(context.Background(), "user-3"), ErrUserNotFound)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users")).
+		WithArgs("user-4", "pending_deletion", deletable).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT status FROM users")).
+		WithArgs("user-4").
+		WillReturnRows(pgxmock.NewRows([]string{"status"}).AddRow("pending_deletion"))
+	require.ErrorIs(t, repo.Delete(context.Background(), "user-4"), ErrInvalidTransition)
+}
+
+func TestUserRepository_RestoreAndPurge(t *testing.T) {
+	repo, mock := newTestRepo(t)
+	cutoff := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec(regexp.QuoteMeta("SET status = 'active'")).
+		WithArgs("u-1", cutoff).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	require.NoError(t, repo.Restore(context.Background(), "u-1", cutoff))
+
+	mock.ExpectExec(regexp.QuoteMeta("SET status = 'active'")).
+		WithArgs("u-2", cutoff).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT status FROM users")).
+		WithArgs("u-2").
+		WillReturnRows(pgxmock.NewRows([]string{"status"}).AddRow("pending_deletion"))
+	require.ErrorIs(t, repo.Restore(context.Background(), "u-2", cutoff), ErrInvalidTransition)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM users WHERE status = 'pending_deletion' AND deleted_at < $1")).
+		WithArgs(cutoff).
+		WillReturnResult(pgxmock.NewResult("DELETE", 3))
+	purged, err := repo.PurgeDeleted(context.Background(), cutoff)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), purged)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_UpdateProfile(t *testing.T) {
+	repo, mock := newTestRepo(t)
+
+	name := "Alice"
+	tz := "Europe/Madrid"
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users")).
+		WithArgs(&name, (*string)(nil), &tz, (*string)(nil), (*string)(nil), "u-1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	require.NoError(t, repo.UpdateProfile(context.Background(), "u-1", model.ProfileUpdate{Name: &name, Timezone: &tz}))
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users")).
+		WithArgs(&name, (*string)(nil), (*string)(nil), (*string)(nil), (*string)(nil), "missing").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	require.ErrorIs(t, repo.UpdateProfile(context.Background(), "missing", model.ProfileUpdate{Name: &name}), ErrUserNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_UpdateMetadata(t *testing.T) {
+	repo, mock := newTestRepo(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SET metadata = (metadata || $2::jsonb) - $3::text[]")).
+		WithArgs("u-1", map[string]string{"crm_id": "C-42"}, []string{"legacy_id"}, metadata.MaxKeys).
+		WillReturnRows(pgxmock.NewRows([]string{"metadata"}).AddRow(map[string]string{"crm_id": "C-42"}))
+	merged, err := repo.UpdateMetadata(context.Background(), "u-1", map[string]string{"crm_id": "C-42", "legacy_id": ""})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"crm_id": "C-42"}, merged)
+
+	// Sin fila y el usuario existe: el merge superaba el límite de claves.
+	mock.ExpectQuery(regexp.QuoteMeta("SET metadata")).
+		WithArgs("u-1", pgxmock.AnyArg(), pgxmock.AnyArg(), metadata.MaxKeys).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS")).
+		WithArgs("u-1").
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+	_, err = repo.UpdateMetadata(context.Background(), "u-1", map[string]string{"k51": "v"})
+	require.ErrorIs(t, err, metadata.ErrInvalid)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SET metadata")).
+		WithArgs("missing", pgxmock.AnyArg(), pgxmock.AnyArg(), metadata.MaxKeys).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS")).
+		WithArgs("missing").
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(false))
+	_, err = repo.UpdateMetadata(context.Background(), "missing", map[string]string{"k": "v"})
+	require.ErrorIs(t, err, ErrUserNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_ReplaceEmail(t *testing.T) {
+	repo, mock := newTestRepo(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users")).
+		WithArgs("u-1", "old@example.com", "new@example.com").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	require.NoError(t, repo.ReplaceEmail(context.Background(), "u-1", "old@example.com", "new@example.com"))
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users")).
+		WithArgs("u-1", "old@example.com", "new@example.com").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	require.ErrorIs(t, repo.ReplaceEmail(context.Background(), "u-1", "old@example.com", "new@example.com"), ErrEmailMismatch)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users")).
+		WithArgs("u-1", "old@example.com", "dup@example.com").
+		WillReturnError(&pgconn.PgError{Code: "23505"})
+	require.ErrorIs(t, repo.ReplaceEmail(context.Background(), "u-1", "old@example.com", "dup@example.com"), ErrUserExists)
+}
+
+func TestUserRepository_Anonymize(t *testing.T) {
+	repo, mock := newTestRepo(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("email = 'erased+' || id || '@invalid'")).
+		WithArgs("u-1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	require.NoError(t, repo.Anonymize(context.Background(), "u-1"))
+
+	mock.ExpectExec(regexp.QuoteMeta("email = 'erased+' || id || '@invalid'")).
+		WithArgs("missing").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	require.ErrorIs(t, repo.Anonymize(context.Background(), "missing"), ErrUserNotFound)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_QueryDeadline(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+	repo := NewUserRepository(mock, WithQueryLimits(dbquery.Limits{Timeout: 10 * time.Millisecond}))
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM users")).
+		WithArgs("u-1").
+		WillReturnRows(pgxmock.NewRows(userRowColumns)).
+		WillDelayFor(time.Second)
+
+	_, err = repo.GetByID(context.Background(), "u-1")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Un request cancelado corta la query aunque el deadline no haya vencido.
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users")).
+		WithArgs("u-1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1)).
+		WillDelayFor(time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, repo.Anonymize(ctx, "u-1"), context.Canceled)
 }
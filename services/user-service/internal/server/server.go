@@ -4,10 +4,14 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"saas-subscription-platform/libs/dbquery"
+	"saas-subscription-platform/libs/notify"
+	"saas-subscription-platform/services/user-service/internal/client"
 	"saas-subscription-platform/services/user-service/internal/config"
 	"saas-subscription-platform/services/user-service/internal/db"
 	"saas-subscription-platform/services/user-service/internal/handler"
 	"saas-subscription-platform/services/user-service/internal/middleware"
+	"saas-subscription-platform/services/user-service/internal/model"
 	"saas-subscription-platform/services/user-service/internal/repository"
 	"saas-subscription-platform/services/user-service/internal/service"
 	"time"
@@ -15,6 +19,10 @@ import (
 
 type Server struct {
 	httpServer *http.Server
+
+	userService   *service.UserService
+	purgeInterval time.Duration
+	stopPurger    context.CancelFunc
 }
 
 func New(cfg config.Config) *Server {
@@ -22,11 +30,41 @@ func New(cfg config.Config) *Server {
 	if err != nil {
 		log.Fatalf("db connection failed: %v", err)
 	}
+	if cfg.MigrateOnBoot {
+		if err := db.Migrate(context.Background(), pool); err != nil {
+			log.Fatalf("migrations failed: %v", err)
+		}
+	}
 
-	userRepo := repository.NewUserRepository(pool)
-	userService := service.NewUserService(userRepo)
+	limits := repository.WithQueryLimits(dbquery.Limits{
+		Service:       "user-service",
+		Timeout:       cfg.QueryTimeout,
+		SlowThreshold: cfg.SlowQueryThreshold,
+	})
+	userRepo := repository.NewUserRepository(pool, limits)
+	userService := service.NewUserService(userRepo, service.WithDeletionGracePeriod(cfg.DeletionGracePeriod))
 	userHandler := handler.NewUserHandler(userService)
 
+	privacyService := service.NewPrivacyService(userRepo, repository.NewDataRequestRepository(pool, limits), []byte(cfg.PseudonymKey),
+		client.NewAuthDataSource(cfg.AuthServiceURL),
+		client.NewBillingDataSource(cfg.BillingServiceURL),
+	)
+	privacyHandler := handler.NewPrivacyHandler(privacyService)
+
+	orgRepo := repository.NewOrganizationRepository(pool, limits)
+	orgHandler := handler.NewOrganizationHandler(service.NewOrganizationService(orgRepo))
+
+	var sender notify.Sender = notify.LogSender{}
+	if cfg.SMTPAddr != "" {
+		sender = notify.NewSMTPSender(cfg.SMTPAddr, cfg.SMTPFrom, cfg.SMTPUsername, cfg.SMTPPassword)
+	}
+	invitationService := service.NewInvitationService(orgRepo, repository.NewInvitationRepository(pool, limits), userRepo, sender,
+		[]byte(cfg.InvitationKey),
+		service.WithInvitationTTL(cfg.InvitationTTL),
+		service.WithInvitationAcceptURL(cfg.InvitationAcceptURL),
+	)
+	invitationHandler := handler.NewInvitationHandler(invitationService)
+
 	internalAuthMiddleware := middleware.InternalAuth
 	requestLogger := middleware.RequestLogger("user-service")
 
@@ -35,27 +73,72 @@ func New(cfg config.Config) *Server {
 
 	// Protected routes - requieren header interno del API Gateway
 	mux.Handle("POST /users", internalAuthMiddleware(http.HandlerFunc(userHandler.CreateUser)))
-	mux.Handle("GET /users/email/{email}", internalAuthMiddleware(http.HandlerFunc(userHandler.GetUserByEmail)))
+	mux.Handle("GET /users", internalAuthMiddleware(middleware.RequireRole(model.RoleSupport, model.RoleAdmin)(http.HandlerFunc(userHandler.ListUsers))))
 	mux.Handle("GET /users/{id}", internalAuthMiddleware(http.HandlerFunc(userHandler.GetUserByID)))
-	mux.Handle("PATCH /users/{id}", internalAuthMiddleware(http.HandlerFunc(userHandler.UpdateUser)))
-	mux.Handle("DELETE /users/{id}", internalAuthMiddleware(http.HandlerFunc(userHandler.DeleteUser)))
+	mux.Handle("PATCH /users/{id}", internalAuthMiddleware(middleware.RequireSelfOrRole(model.RoleAdmin)(http.HandlerFunc(userHandler.UpdateUser))))
+	mux.Handle("DELETE /users/{id}", internalAuthMiddleware(middleware.RequireSelfOrRole(model.RoleAdmin)(http.HandlerFunc(userHandler.DeleteUser))))
+	mux.Handle("POST /users/{id}/deactivate", internalAuthMiddleware(http.HandlerFunc(userHandler.DeactivateUser)))
+
+	// Export y borrado GDPR: el titular o un admin (se valida en el handler)
+	mux.Handle("POST /users/{id}/export", internalAuthMiddleware(http.HandlerFunc(privacyHandler.RequestExport)))
+	mux.Handle("POST /users/{id}/erasure", internalAuthMiddleware(http.HandlerFunc(privacyHandler.RequestErasure)))
+	mux.Handle("GET /users/{id}/data-requests/{rid}", internalAuthMiddleware(http.HandlerFunc(privacyHandler.GetDataRequest)))
+	mux.Handle("GET /users/{id}/data-requests/{rid}/archive", internalAuthMiddleware(http.HandlerFunc(privacyHandler.DownloadArchive)))
+	mux.Handle("POST /users/{id}/data-requests/{rid}/retry", internalAuthMiddleware(http.HandlerFunc(privacyHandler.RetryDataRequest)))
+
+	// Organizaciones del usuario (la activa se elige en auth-service)
+	mux.Handle("POST /orgs", internalAuthMiddleware(http.HandlerFunc(orgHandler.CreateOrganization)))
+	mux.Handle("GET /orgs", internalAuthMiddleware(http.HandlerFunc(orgHandler.ListMyOrganizations)))
+
+	// Invitaciones: las gestionan owners y admins de la organización (se valida en el servicio)
+	mux.Handle("POST /orgs/{id}/invitations", internalAuthMiddleware(http.HandlerFunc(invitationHandler.CreateInvitation)))
+	mux.Handle("GET /orgs/{id}/invitations", internalAuthMiddleware(http.HandlerFunc(invitationHandler.ListInvitations)))
+	mux.Handle("POST /orgs/{id}/invitations/{invitationId}/resend", internalAuthMiddleware(http.HandlerFunc(invitationHandler.ResendInvitation)))
+	mux.Handle("DELETE /orgs/{id}/invitations/{invitationId}", internalAuthMiddleware(http.HandlerFunc(invitationHandler.RevokeInvitation)))
+	mux.Handle("POST /orgs/invitations/accept", internalAuthMiddleware(http.HandlerFunc(invitationHandler.AcceptInvitation)))
+
+	// Solo admins: ciclo de vida de cuentas ajenas
+	adminOnly := middleware.RequireRole(model.RoleAdmin)
+	mux.Handle("POST /users/{id}/suspend", internalAuthMiddleware(adminOnly(http.HandlerFunc(userHandler.SuspendUser))))
+	mux.Handle("POST /users/{id}/restore", internalAuthMiddleware(adminOnly(http.HandlerFunc(userHandler.RestoreUser))))
+
+	// Solo auth-service: lookup para login (incluye el hash) y cambios de credenciales
+	// ya validados (password actual, confirmación de email)
+	authServiceOnly := middleware.RequireCaller("auth-service")
+	mux.Handle("GET /users/email/{email}", internalAuthMiddleware(authServiceOnly(http.HandlerFunc(userHandler.GetUserByEmail))))
+	mux.Handle("PUT /users/{id}/password", internalAuthMiddleware(authServiceOnly(http.HandlerFunc(userHandler.SetPassword))))
+	mux.Handle("PUT /users/{id}/email", internalAuthMiddleware(authServiceOnly(http.HandlerFunc(userHandler.ReplaceEmail))))
+	mux.Handle("GET /internal/orgs/{id}/members/{userId}", internalAuthMiddleware(authServiceOnly(http.HandlerFunc(orgHandler.GetMembership))))
+	mux.Handle("POST /internal/invitations/lookup", internalAuthMiddleware(authServiceOnly(http.HandlerFunc(invitationHandler.LookupInvitation))))
+	mux.Handle("POST /internal/invitations/accept", internalAuthMiddleware(authServiceOnly(http.HandlerFunc(invitationHandler.AcceptInvitationFor))))
 
 	h := requestLogger(mux)
 
 	return &Server{
 		httpServer: &http.Server{
-			Addr:         cfg.HTTPAddr,
-			Handler:      h,
-			ReadTimeout:  5 * time.Second,
-			WriteTimeout: 10 * time.Second,
+			Addr:        cfg.HTTPAddr,
+			Handler:     h,
+			ReadTimeout: 5 * time.Second,
+			// Los pedidos GDPR llaman en serie a auth-service y billing-service.
+			WriteTimeout: 30 * time.Second,
 		},
+		userService:   userService,
+		purgeInterval: cfg.PurgeInterval,
 	}
 }
 
 func (s *Server) Start() error {
+	// La purga de cuentas borradas corre en segundo plano mientras viva el server.
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopPurger = cancel
+	go s.userService.RunPurger(ctx, s.purgeInterval)
+
 	return s.httpServer.ListenAndServe()
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.stopPurger != nil {
+		s.stopPurger()
+	}
 	return s.httpServer.Shutdown(ctx)
 }
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"saas-subscription-platform/libs/emailaddr"
+	"saas-subscription-platform/libs/notify"
+	"saas-subscription-platform/libs/trace"
+	"saas-subscription-platform/services/user-service/internal/model"
+	"saas-subscription-platform/services/user-service/internal/repository"
+)
+
+var (
+	// ErrOrgForbidden: el usuario no es miembro o su rol no alcanza para la operación.
+	ErrOrgForbidden = errors.New("not allowed in this organization")
+	// ErrInvalidInviteRole: se invita como admin, billing o member; owner no se delega por invitación.
+	ErrInvalidInviteRole = errors.New("role must be admin, billing or member")
+	// ErrAlreadyMember: el email invitado ya pertenece a la organización.
+	ErrAlreadyMember = errors.New("user is already a member of the organization")
+	// ErrInvalidInvitation: token mal formado, con firma inválida, reemplazado por
+	// un reenvío, ya usado o revocado.
+	ErrInvalidInvitation = errors.New("invalid invitation")
+	// ErrInvitationExpired: la invitación venció; un admin puede reenviarla.
+	ErrInvitationExpired = errors.New("invitation expired")
+	// ErrInvitationEmailMismatch: la invitación es para otro email.
+	ErrInvitationEmailMismatch = errors.New("invitation was sent to a different email")
+)
+
+const (
+	defaultInvitationTTL       = 7 * 24 * time.Hour
+	defaultInvitationAcceptURL = "http://localhost:3000/invitations/accept"
+)
+
+// InvitationStore persiste las invitaciones. El token nunca se guarda: solo su hash.
+type InvitationStore interface {
+	Create(ctx context.Context, inv model.Invitation) (model.Invitation, error)
+	Get(ctx context.Context, id string) (model.Invitation, error)
+	ListOpen(ctx context.Context, orgID string) ([]model.Invitation, error)
+	Rotate(ctx context.Context, id, tokenHash string, expiresAt time.Time) error
+	Revoke(ctx context.Context, id string) error
+	Accept(ctx context.Context, id, tokenHash, userID string) (model.Membership, error)
+}
+
+type InvitationService struct {
+	orgs      OrganizationStore
+	invites   InvitationStore
+	users     UserStore
+	sender    notify.Sender
+	key       []byte
+	ttl       time.Duration
+	acceptURL string
+	now       func() time.Time
+}
+
+// InvitationOption configura dependencias opcionales del InvitationService.
+type InvitationOption func(*InvitationService)
+
+// WithInvitationTTL define cuánto vale el link de una invitación (o de su último reenvío).
+func WithInvitationTTL(d time.Duration) InvitationOption {
+	return func(s *InvitationService) {
+		if d > 0 {
+			s.ttl = d
+		}
+	}
+}
+
+// WithInvitationAcceptURL es la página (frontend) a la que apunta el link del
+// email; recibe ?token=...
+func WithInvitationAcceptURL(u string) InvitationOption {
+	return func(s *InvitationService) {
+		if u != "" {
+			s.acceptURL = u
+		}
+	}
+}
+
+// NewInvitationService firma los tokens con key y manda los emails por sender.
+func NewInvitationService(orgs OrganizationStore, invites InvitationStore, users UserStore, sender notify.Sender, key []byte, opts ...InvitationOption) *InvitationService {
+	s := &InvitationService{
+		orgs:      orgs,
+		invites:   invites,
+		users:     users,
+		sender:    sender,
+		key:       key,
+		ttl:       defaultInvitationTTL,
+		acceptURL: defaultInvitationAcceptURL,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Invite crea la invitación y manda el email. Solo owners y admins invitan.
+// Si el email falla la invitación queda creada y se puede reenviar.
+func (s *InvitationService) Invite(ctx context.Context, orgID, inviterID, email, role string) (model.Invitation, error) {
+	if err := s.authorize(ctx, orgID, inviterID); err != nil {
+		return model.Invitation{}, err
+	}
+	if role == model.OrgRoleOwner || !model.ValidOrgRole(role) {
+		return model.Invitation{}, ErrInvalidInviteRole
+	}
+	email, err := emailaddr.Normalize(email)
+	if err != nil {
+		return model.Invitation{}, err
+	}
+
+	if user, err := s.users.GetByEmail(ctx, email); err == nil {
+		if _, err := s.orgs.GetMembership(ctx, orgID, user.ID); err == nil {
+			return model.Invitation{}, ErrAlreadyMember
+		} else if !errors.Is(err, repository.ErrMembershipNotFound) {
+			return model.Invitation{}, err
+		}
+	} else if !errors.Is(err, repository.ErrUserNotFound) {
+		return model.Invitation{}, err
+	}
+
+	nonce, err := newInvitationNonce()
+	if err != nil {
+		return model.Invitation{}, err
+	}
+	inv, err := s.invites.Create(ctx, model.Invitation{
+		OrgID:     orgID,
+		Email:     email,
+		Role:      role,
+		TokenHash: hashNonce(nonce),
+		InvitedBy: inviterID,
+		ExpiresAt: s.now().Add(s.ttl),
+	})
+	if err != nil {
+		return model.Invitation{}, err
+	}
+
+	log.Printf("org_invitation_created org_id=%s invitation_id=%s role=%s invited_by=%s request_id=%s",
+		orgID, inv.ID, role, inviterID, trace.RequestIDFromContext(ctx))
+	s.send(ctx, inv, s.sign(inv.ID, nonce))
+	return inv, nil
+}
+
+// ListOpen devuelve las invitaciones sin aceptar ni revocar, vencidas incluidas.
+func (s *InvitationService) ListOpen(ctx context.Context, orgID, actorID string) ([]model.Invitation, error) {
+	if err := s.authorize(ctx, orgID, actorID); err != nil {
+		return nil, err
+	}
+	return s.invites.ListOpen(ctx, orgID)
+}
+
+// Resend genera un link nuevo (el anterior deja de valer), renueva el vencimiento y reenvía el email.
+func (s *InvitationService) Resend(ctx context.Context, orgID, actorID, invitationID string) (model.Invitation, error) {
+	inv, err := s.openInvitation(ctx, orgID, actorID, invitationID)
+	if err != nil {
+		return model.Invitation{}, err
+	}
+
+	nonce, err := newInvitationNonce()
+	if err != nil {
+		return model.Invitation{}, err
+	}
+	inv.TokenHash = hashNonce(nonce)
+	inv.ExpiresAt = s.now().Add(s.ttl)
+	if err := s.invites.Rotate(ctx, inv.ID, inv.TokenHash, inv.ExpiresAt); err != nil {
+		return model.Invitation{}, err
+	}
+
+	log.Printf("org_invitation_resent org_id=%s invitation_id=%s by=%s request_id=%s",
+		orgID, inv.ID, actorID, trace.RequestIDFromContext(ctx))
+	s.send(ctx, inv, s.sign(inv.ID, nonce))
+	return inv, nil
+}
+
+func (s *InvitationService) Revoke(ctx context.Context, orgID, actorID, invitationID string) error {
+	inv, err := s.openInvitation(ctx, orgID, actorID, invitationID)
+	if err != nil {
+		return err
+	}
+	if err := s.invites.Revoke(ctx, inv.ID); err != nil {
+		return err
+	}
+	log.Printf("org_invitation_revoked org_id=%s invitation_id=%s by=%s request_id=%s",
+		orgID, inv.ID, actorID, trace.RequestIDFromContext(ctx))
+	return nil
+}
+
+// Lookup valida el token y devuelve la invitación si todavía se puede aceptar.
+// auth-service lo usa antes de registrar al invitado.
+func (s *InvitationService) Lookup(ctx context.Context, token string) (model.Invitation, error) {
+	return s.resolve(ctx, token)
+}
+
+// Accept suma a userID a la organización con el rol de la invitación. La
+// invitación es para un email: solo la acepta la cuenta con ese email.
+func (s *InvitationService) Accept(ctx context.Context, token, userID string) (model.Membership, error) {
+	inv, err := s.resolve(ctx, token)
+	if err != nil {
+		return model.Membership{}, err
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return model.Membership{}, err
+	}
+	if !strings.EqualFold(user.Email, inv.Email) {
+		return model.Membership{}, ErrInvitationEmailMismatch
+	}
+
+	// Hash, estado y vencimiento se vuelven a chequear al aceptar: entre la
+	// lectura y acá pudo haber un reenvío o una aceptación concurrente.
+	m, err := s.invites.Accept(ctx, inv.ID, inv.TokenHash, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvitationNotFound) {
+			return model.Membership{}, ErrInvalidInvitation
+		}
+		return model.Membership{}, err
+	}
+
+	log.Printf("org_invitation_accepted org_id=%s invitation_id=%s user_id=%s role=%s request_id=%s",
+		m.OrgID, inv.ID, userID, m.Role, trace.RequestIDFromContext(ctx))
+	return m, nil
+}
+
+// resolve verifica la firma del token y que corresponda al link vigente de
+// una invitación abierta y sin vencer.
+func (s *InvitationService) resolve(ctx context.Context, token string) (model.Invitation, error) {
+	id, nonce, ok := s.verify(token)
+	if !ok {
+		return model.Invitation{}, ErrInvalidInvitation
+	}
+	inv, err := s.invites.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvitationNotFound) {
+			return model.Invitation{}, ErrInvalidInvitation
+		}
+		return model.Invitation{}, err
+	}
+	// Un reenvío cambia el hash: el link anterior ya no sirve.
+	if !inv.Open() || !hmac.Equal([]byte(hashNonce(nonce)), []byte(inv.TokenHash)) {
+		return model.Invitation{}, ErrInvalidInvitation
+	}
+	if !s.now().Before(inv.ExpiresAt) {
+		return model.Invitation{}, ErrInvitationExpired
+	}
+	return inv, nil
+}
+
+// authorize exige que actorID sea owner o admin de orgID.
+func (s *InvitationService) authorize(ctx context.Context, orgID, actorID string) error {
+	m, err := s.orgs.GetMembership(ctx, orgID, actorID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMembershipNotFound) {
+			return ErrOrgForbidden
+		}
+		return err
+	}
+	if m.Role != model.OrgRoleOwner && m.Role != model.OrgRoleAdmin {
+		return ErrOrgForbidden
+	}
+	return nil
+}
+
+// openInvitation autoriza al actor y devuelve la invitación si es de orgID y sigue abierta.
+func (s *InvitationService) openInvitation(ctx context.Context, orgID, actorID, invitationID string) (model.Invitation, error) {
+	if err := s.authorize(ctx, orgID, actorID); err != nil {
+		return model.Invitation{}, err
+	}
+	inv, err := s.invites.Get(ctx, invitationID)
+	if err != nil {
+		return model.Invitation{}, err
+	}
+	if inv.OrgID != orgID || !inv.Open() {
+		return model.Invitation{}, repository.ErrInvitationNotFound
+	}
+	return inv, nil
+}
+
+// sign arma el token del link: "<id>.<nonce>.<firma>". La firma permite
+// descartar tokens inventados sin ir a la base; el nonce ata el link a su envío.
+func (s *InvitationService) sign(id, nonce string) string {
+	return id + "." + nonce + "." + s.mac(id, nonce)
+}
+
+func (s *InvitationService) verify(token string) (id, nonce string, ok bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(s.mac(parts[0], parts[1]))) {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func (s *InvitationService) mac(id, nonce string) string {
+	m := hmac.New(sha256.New, s.key)
+	m.Write([]byte("org-invitation:" + id + "." + nonce))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+// send manda el email de la invitación; un fallo se loguea y no revierte nada.
+func (s *InvitationService) send(ctx context.Context, inv model.Invitation, token string) {
+	orgName := "an organization"
+	if org, err := s.orgs.Get(ctx, inv.OrgID); err == nil {
+		orgName = org.Name
+	}
+	link := s.acceptURL + "?token=" + url.QueryEscape(token)
+	err := s.sender.Send(ctx, notify.Message{
+		To:      inv.Email,
+		Subject: "You've been invited to join " + orgName,
+		Body: "You were invited to join " + orgName + " as " + inv.Role + ".\n\n" +
+			"To accept, open: " + link + "\n\n" +
+			"The link expires on " + inv.ExpiresAt.UTC().Format(time.RFC1123) + ". If you don't have an account yet, you can create one from the same link.\n",
+	})
+	if err != nil {
+		log.Printf("org_invitation_email_failed invitation_id=%s err=%v request_id=%s",
+			inv.ID, err, trace.RequestIDFromContext(ctx))
+	}
+}
+
+func newInvitationNonce() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashNonce(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(sum[:])
+}
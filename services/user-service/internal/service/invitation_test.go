@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/libs/notify"
+	"saas-subscription-platform/services/user-service/internal/model"
+	"saas-subscription-platform/services/user-service/internal/repository"
+	"saas-subscription-platform/services/user-service/internal/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// memOrgStore guarda membresías en memoria, con clave "org/user".
+type memOrgStore struct {
+	members map[string]string
+}
+
+func (m *memOrgStore) Create(_ context.Context, name, ownerID string) (model.Organization, error) {
+	return model.Organization{}, nil
+}
+
+func (m *memOrgStore) Get(_ context.Context, orgID string) (model.Organization, error) {
+	return model.Organization{ID: orgID, Name: "Acme"}, nil
+}
+
+func (m *memOrgStore) ListForUser(_ context.Context, userID string) ([]model.UserOrganization, error) {
+	return nil, nil
+}
+
+func (m *memOrgStore) GetMembership(_ context.Context, orgID, userID string) (model.Membership, error) {
+	role, ok := m.members[orgID+"/"+userID]
+	if !ok {
+		return model.Membership{}, repository.ErrMembershipNotFound
+	}
+	return model.Membership{OrgID: orgID, UserID: userID, Role: role}, nil
+}
+
+// memInvitationStore replica en memoria las condiciones del repositorio.
+type memInvitationStore struct {
+	invites map[string]model.Invitation
+	orgs    *memOrgStore
+	now     func() time.Time
+}
+
+func (m *memInvitationStore) Create(_ context.Context, inv model.Invitation) (model.Invitation, error) {
+	for _, other := range m.invites {
+		if other.OrgID == inv.OrgID && other.Email == inv.Email && other.Open() && m.now().Before(other.ExpiresAt) {
+			return model.Invitation{}, repository.ErrInvitationExists
+		}
+	}
+	inv.ID = uuid.NewString()
+	m.invites[inv.ID] = inv
+	return inv, nil
+}
+
+func (m *memInvitationStore) Get(_ context.Context, id string) (model.Invitation, error) {
+	inv, ok := m.invites[id]
+	if !ok {
+		return model.Invitation{}, repository.ErrInvitationNotFound
+	}
+	return inv, nil
+}
+
+func (m *memInvitationStore) ListOpen(_ context.Context, orgID string) ([]model.Invitation, error) {
+	var out []model.Invitation
+	for _, inv := range m.invites {
+		if inv.OrgID == orgID && inv.Open() {
+			out = append(out, inv)
+		}
+	}
+	return out, nil
+}
+
+func (m *memInvitationStore) Rotate(_ context.Context, id, tokenHash string, expiresAt time.Time) error {
+	inv, ok := m.invites[id]
+	if !ok || !inv.Open() {
+		return repository.ErrInvitationNotFound
+	}
+	inv.TokenHash, inv.ExpiresAt = tokenHash, expiresAt
+	m.invites[id] = inv
+	return nil
+}
+
+func (m *memInvitationStore) Revoke(_ context.Context, id string) error {
+	inv, ok := m.invites[id]
+	if !ok || !inv.Open() {
+		return repository.ErrInvitationNotFound
+	}
+	now := m.now()
+	inv.RevokedAt = &now
+	m.invites[id] = inv
+	return nil
+}
+
+func (m *memInvitationStore) Accept(_ context.Context, id, tokenHash, userID string) (model.Membership, error) {
+	inv, ok := m.invites[id]
+	if !ok || !inv.Open() || inv.TokenHash != tokenHash || !m.now().Before(inv.ExpiresAt) {
+		return model.Membership{}, repository.ErrInvitationNotFound
+	}
+	now := m.now()
+	inv.AcceptedAt = &now
+	m.invites[id] = inv
+	m.orgs.members[inv.OrgID+"/"+userID] = inv.Role
+	return model.Membership{OrgID: inv.OrgID, UserID: userID, Role: inv.Role}, nil
+}
+
+// outbox guarda los emails enviados.
+type outbox struct{ sent []notify.Message }
+
+func (o *outbox) Send(_ context.Context, msg notify.Message) error {
+	o.sent = append(o.sent, msg)
+	return nil
+}
+
+var tokenInEmail = regexp.MustCompile(`token=([^\s]+)`)
+
+func (o *outbox) lastToken(t *testing.T) string {
+	t.Helper()
+	require.NotEmpty(t, o.sent)
+	match := tokenInEmail.FindStringSubmatch(o.sent[len(o.sent)-1].Body)
+	require.Len(t, match, 2)
+	return match[1]
+}
+
+type invitationFixture struct {
+	svc    *InvitationService
+	users  *mocks.MockUserStore
+	orgs   *memOrgStore
+	mail   *outbox
+	clock  *time.Time
+	invite model.Invitation
+}
+
+func newInvitationFixture(t *testing.T) *invitationFixture {
+	ctrl := gomock.NewController(t)
+	clock := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	f := &invitationFixture{
+		users: mocks.NewMockUserStore(ctrl),
+		orgs:  &memOrgStore{members: map[string]string{"o-1/owner-1": model.OrgRoleOwner, "o-1/member-1": model.OrgRoleMember}},
+		mail:  &outbox{},
+		clock: &clock,
+	}
+	now := func() time.Time { return *f.clock }
+	invites := &memInvitationStore{invites: map[string]model.Invitation{}, orgs: f.orgs, now: now}
+	f.svc = NewInvitationService(f.orgs, invites, f.users, f.mail, []byte("test-key"), WithInvitationTTL(48*time.Hour))
+	f.svc.now = now
+	return f
+}
+
+func TestInvitationService_InviteAndAccept(t *testing.T) {
+	f := newInvitationFixture(t)
+	f.users.EXPECT().GetByEmail(gomock.Any(), "ana@example.com").Return(model.User{}, repository.ErrUserNotFound)
+
+	inv, err := f.svc.Invite(context.Background(), "o-1", "owner-1", " ana@Example.COM ", model.OrgRoleBilling)
+	require.NoError(t, err)
+	require.Equal(t, "ana@example.com", inv.Email)
+	require.Equal(t, f.clock.Add(48*time.Hour), inv.ExpiresAt)
+	require.Equal(t, "ana@example.com", f.mail.sent[0].To)
+	require.Contains(t, f.mail.sent[0].Subject, "Acme")
+	token := f.mail.lastToken(t)
+
+	looked, err := f.svc.Lookup(context.Background(), token)
+	require.NoError(t, err)
+	require.Equal(t, inv.ID, looked.ID)
+
+	f.users.EXPECT().GetByID(gomock.Any(), "u-2").Return(model.User{ID: "u-2", Email: "ana@example.com"}, nil)
+	m, err := f.svc.Accept(context.Background(), token, "u-2")
+	require.NoError(t, err)
+	require.Equal(t, model.OrgRoleBilling, m.Role)
+	require.Equal(t, model.OrgRoleBilling, f.orgs.members["o-1/u-2"])
+
+	// De un solo uso.
+	_, err = f.svc.Accept(context.Background(), token, "u-2")
+	require.ErrorIs(t, err, ErrInvalidInvitation)
+}
+
+func TestInvitationService_InviteRequiresOwnerOrAdmin(t *testing.T) {
+	f := newInvitationFixture(t)
+
+	_, err := f.svc.Invite(context.Background(), "o-1", "member-1", "ana@example.com", model.OrgRoleMember)
+	require.ErrorIs(t, err, ErrOrgForbidden)
+
+	_, err = f.svc.Invite(context.Background(), "o-1", "stranger", "ana@example.com", model.OrgRoleMember)
+	require.ErrorIs(t, err, ErrOrgForbidden)
+
+	_, err = f.svc.Invite(context.Background(), "o-1", "owner-1", "ana@example.com", model.OrgRoleOwner)
+	require.ErrorIs(t, err, ErrInvalidInviteRole)
+}
+
+func TestInvitationService_InviteExistingMember(t *testing.T) {
+	f := newInvitationFixture(t)
+	f.users.EXPECT().GetByEmail(gomock.Any(), "bob@example.com").Return(model.User{ID: "member-1", Email: "bob@example.com"}, nil)
+
+	_, err := f.svc.Invite(context.Background(), "o-1", "owner-1", "bob@example.com", model.OrgRoleAdmin)
+	require.ErrorIs(t, err, ErrAlreadyMember)
+}
+
+func TestInvitationService_AcceptRejectsOtherEmailAndTamperedToken(t *testing.T) {
+	f := newInvitationFixture(t)
+	f.users.EXPECT().GetByEmail(gomock.Any(), "ana@example.com").Return(model.User{}, repository.ErrUserNotFound)
+	_, err := f.svc.Invite(context.Background(), "o-1", "owner-1", "ana@example.com", model.OrgRoleMember)
+	require.NoError(t, err)
+	token := f.mail.lastToken(t)
+
+	f.users.EXPECT().GetByID(gomock.Any(), "u-3").Return(model.User{ID: "u-3", Email: "eve@example.com"}, nil)
+	_, err = f.svc.Accept(context.Background(), token, "u-3")
+	require.ErrorIs(t, err, ErrInvitationEmailMismatch)
+
+	_, err = f.svc.Accept(context.Background(), token+"x", "u-3")
+	require.ErrorIs(t, err, ErrInvalidInvitation)
+	_, err = f.svc.Lookup(context.Background(), "not-a-token")
+	require.ErrorIs(t, err, ErrInvalidInvitation)
+}
+
+func TestInvitationService_ExpiryAndResend(t *testing.T) {
+	f := newInvitationFixture(t)
+	f.users.EXPECT().GetByEmail(gomock.Any(), "ana@example.com").Return(model.User{}, repository.ErrUserNotFound)
+	inv, err := f.svc.Invite(context.Background(), "o-1", "owner-1", "ana@example.com", model.OrgRoleMember)
+	require.NoError(t, err)
+	oldToken := f.mail.lastToken(t)
+
+	*f.clock = f.clock.Add(49 * time.Hour)
+	_, err = f.svc.Lookup(context.Background(), oldToken)
+	require.ErrorIs(t, err, ErrInvitationExpired)
+
+	_, err = f.svc.Resend(context.Background(), "o-1", "owner-1", inv.ID)
+	require.NoError(t, err)
+	newToken := f.mail.lastToken(t)
+	require.NotEqual(t, oldToken, newToken)
+
+	// El link anterior queda invalidado por el reenvío.
+	_, err = f.svc.Lookup(context.Background(), oldToken)
+	require.ErrorIs(t, err, ErrInvalidInvitation)
+	_, err = f.svc.Lookup(context.Background(), newToken)
+	require.NoError(t, err)
+}
+
+func TestInvitationService_Revoke(t *testing.T) {
+	f := newInvitationFixture(t)
+	f.users.EXPECT().GetByEmail(gomock.Any(), "ana@example.com").Return(model.User{}, repository.ErrUserNotFound)
+	inv, err := f.svc.Invite(context.Background(), "o-1", "owner-1", "ana@example.com", model.OrgRoleMember)
+	require.NoError(t, err)
+	token := f.mail.lastToken(t)
+
+	require.ErrorIs(t, f.svc.Revoke(context.Background(), "o-2", "owner-1", inv.ID), ErrOrgForbidden)
+	require.NoError(t, f.svc.Revoke(context.Background(), "o-1", "owner-1", inv.ID))
+	require.ErrorIs(t, f.svc.Revoke(context.Background(), "o-1", "owner-1", inv.ID), repository.ErrInvitationNotFound)
+
+	_, err = f.svc.Lookup(context.Background(), token)
+	require.ErrorIs(t, err, ErrInvalidInvitation)
+
+	open, err := f.svc.ListOpen(context.Background(), "o-1", "owner-1")
+	require.NoError(t, err)
+	require.Empty(t, open)
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/model"
+	"saas-subscription-platform/services/user-service/internal/repository"
+)
+
+// ErrGracePeriodExpired indica que la cuenta ya no se puede restaurar (se purgará).
+var ErrGracePeriodExpired = errors.New("deletion grace period expired")
+
+// SuspendUser bloquea la cuenta por decisión de un admin.
+func (s *UserService) SuspendUser(ctx context.Context, userID string) error {
+	return s.repo.Transition(ctx, userID, model.StatusSuspended, model.StatusActive, model.StatusDeactivated)
+}
+
+// DeactivateUser desactiva la cuenta a pedido del propio usuario.
+func (s *UserService) DeactivateUser(ctx context.Context, userID string) error {
+	return s.repo.Transition(ctx, userID, model.StatusDeactivated, model.StatusActive)
+}
+
+// RestoreUser reactiva una cuenta suspendida, desactivada o borrada dentro de la gracia.
+func (s *UserService) RestoreUser(ctx context.Context, userID string) error {
+	cutoff := s.now().Add(-s.gracePeriod)
+	err := s.repo.Restore(ctx, userID, cutoff)
+	if !errors.Is(err, repository.ErrInvalidTransition) {
+		return err
+	}
+	// Distinguimos "ya activa" de "gracia vencida" para dar un error útil.
+	user, getErr := s.repo.GetByID(ctx, userID)
+	if getErr != nil {
+		return getErr
+	}
+	if user.Status == model.StatusPendingDeletion && user.DeletedAt != nil && !user.DeletedAt.After(cutoff) {
+		return ErrGracePeriodExpired
+	}
+	return err
+}
+
+// PurgeDeleted elimina las cuentas cuya gracia venció.
+func (s *UserService) PurgeDeleted(ctx context.Context) (int64, error) {
+	return s.repo.PurgeDeleted(ctx, s.now().Add(-s.gracePeriod))
+}
+
+// RunPurger ejecuta PurgeDeleted cada interval hasta que ctx se cancele.
+func (s *UserService) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeDeleted(ctx)
+			if err != nil {
+				log.Printf("user_purge_failed err=%v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("user_purge_completed purged=%d", purged)
+			}
+		}
+	}
+}
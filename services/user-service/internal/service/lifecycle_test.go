@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/model"
+	"saas-subscription-platform/services/user-service/internal/repository"
+	"saas-subscription-platform/services/user-service/internal/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newLifecycleService(t *testing.T) (*UserService, *mocks.MockUserStore, time.Time) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockUserStore(ctrl)
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	svc := NewUserService(store, WithDeletionGracePeriod(7*24*time.Hour))
+	svc.now = func() time.Time { return now }
+	return svc, store, now
+}
+
+func TestUserService_SuspendAndDeactivate(t *testing.T) {
+	svc, store, _ := newLifecycleService(t)
+
+	store.EXPECT().Transition(gomock.Any(), "u-1", model.StatusSuspended, model.StatusActive, model.StatusDeactivated).Return(nil)
+	require.NoError(t, svc.SuspendUser(context.Background(), "u-1"))
+
+	store.EXPECT().Transition(gomock.Any(), "u-1", model.StatusDeactivated, model.StatusActive).Return(repository.ErrInvalidTransition)
+	require.ErrorIs(t, svc.DeactivateUser(context.Background(), "u-1"), repository.ErrInvalidTransition)
+}
+
+func TestUserService_RestoreUser(t *testing.T) {
+	svc, store, now := newLifecycleService(t)
+	cutoff := now.Add(-7 * 24 * time.Hour)
+
+	store.EXPECT().Restore(gomock.Any(), "u-1", cutoff).Return(nil)
+	require.NoError(t, svc.RestoreUser(context.Background(), "u-1"))
+
+	// Borrada hace más que la gracia: ya no se restaura.
+	deletedAt := now.Add(-8 * 24 * time.Hour)
+	store.EXPECT().Restore(gomock.Any(), "u-2", cutoff).Return(repository.ErrInvalidTransition)
+	store.EXPECT().GetByID(gomock.Any(), "u-2").Return(model.User{ID: "u-2", Status: model.StatusPendingDeletion, DeletedAt: &deletedAt}, nil)
+	require.ErrorIs(t, svc.RestoreUser(context.Background(), "u-2"), ErrGracePeriodExpired)
+
+	// Ya activa: conflicto, no gracia vencida.
+	store.EXPECT().Restore(gomock.Any(), "u-3", cutoff).Return(repository.ErrInvalidTransition)
+	store.EXPECT().GetByID(gomock.Any(), "u-3").Return(model.User{ID: "u-3", Status: model.StatusActive}, nil)
+	require.ErrorIs(t, svc.RestoreUser(context.Background(), "u-3"), repository.ErrInvalidTransition)
+}
+
+func TestUserService_PurgeDeletedUsesGracePeriod(t *testing.T) {
+	svc, store, now := newLifecycleService(t)
+
+	store.EXPECT().PurgeDeleted(gomock.Any(), now.Add(-7*24*time.Hour)).Return(int64(2), nil)
+	purged, err := svc.PurgeDeleted(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), purged)
+}
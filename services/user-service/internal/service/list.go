@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/model"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// UserPage es una página del listado. NextCursor es "" en la última página;
+// Total solo se calcula si se pide (cuesta un count(*) extra).
+type UserPage struct {
+	Users      []model.User
+	NextCursor string
+	Total      *int
+}
+
+// ListUsers lista usuarios con paginación keyset. cursor es el NextCursor de
+// la página anterior ("" para la primera).
+func (s *UserService) ListUsers(ctx context.Context, filter model.UserFilter, cursor string, withTotal bool) (UserPage, error) {
+	if cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return UserPage{}, err
+		}
+		filter.After = &after
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+	if filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+
+	// Pedimos uno de más para saber si hay otra página sin un count.
+	limit := filter.Limit
+	filter.Limit = limit + 1
+	users, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return UserPage{}, err
+	}
+	filter.Limit = limit
+
+	var page UserPage
+	if len(users) > limit {
+		users = users[:limit]
+		last := users[limit-1]
+		page.NextCursor = encodeCursor(model.UserCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	page.Users = users
+
+	if withTotal {
+		total, err := s.repo.Count(ctx, filter)
+		if err != nil {
+			return UserPage{}, err
+		}
+		page.Total = &total
+	}
+	return page, nil
+}
+
+// El cursor es opaco para el cliente: base64url("<created_at RFC3339Nano>|<id>").
+func encodeCursor(c model.UserCursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (model.UserCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return model.UserCursor{}, ErrInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return model.UserCursor{}, ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return model.UserCursor{}, ErrInvalidCursor
+	}
+	return model.UserCursor{CreatedAt: createdAt, ID: id}, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/model"
+	"saas-subscription-platform/services/user-service/internal/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func usersFrom(start time.Time, n int) []model.User {
+	users := make([]model.User, n)
+	for i := range users {
+		users[i] = model.User{ID: fmt.Sprintf("u-%d", i), CreatedAt: start.Add(-time.Duration(i) * time.Minute)}
+	}
+	return users
+}
+
+func TestUserService_ListUsersPaginates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockUserStore(ctrl)
+	svc := NewUserService(store)
+	start := time.Date(2025, 6, 1, 12, 0, 0, 123456000, time.UTC)
+
+	// Pide limit+1 para detectar la página siguiente.
+	store.EXPECT().List(gomock.Any(), model.UserFilter{Role: "user", Limit: 3}).Return(usersFrom(start, 3), nil)
+	page, err := svc.ListUsers(context.Background(), model.UserFilter{Role: "user", Limit: 2}, "", false)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 2)
+	require.NotEmpty(t, page.NextCursor)
+	require.Nil(t, page.Total)
+
+	// El cursor apunta al último usuario devuelto; el total ignora el cursor pero no el resto del filtro.
+	last := page.Users[1]
+	store.EXPECT().List(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, f model.UserFilter) ([]model.User, error) {
+		require.NotNil(t, f.After)
+		require.True(t, last.CreatedAt.Equal(f.After.CreatedAt))
+		require.Equal(t, last.ID, f.After.ID)
+		return usersFrom(start.Add(-time.Hour), 1), nil
+	})
+	store.EXPECT().Count(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, f model.UserFilter) (int, error) {
+		require.Equal(t, "user", f.Role)
+		return 3, nil
+	})
+	page, err = svc.ListUsers(context.Background(), model.UserFilter{Role: "user", Limit: 2}, page.NextCursor, true)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	require.Empty(t, page.NextCursor)
+	require.Equal(t, 3, *page.Total)
+}
+
+func TestUserService_ListUsersLimitsAndCursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockUserStore(ctrl)
+	svc := NewUserService(store)
+
+	store.EXPECT().List(gomock.Any(), model.UserFilter{Limit: defaultListLimit + 1}).Return(nil, nil)
+	_, err := svc.ListUsers(context.Background(), model.UserFilter{}, "", false)
+	require.NoError(t, err)
+
+	store.EXPECT().List(gomock.Any(), model.UserFilter{Limit: maxListLimit + 1}).Return(nil, nil)
+	_, err = svc.ListUsers(context.Background(), model.UserFilter{Limit: 10000}, "", false)
+	require.NoError(t, err)
+
+	for _, bad := range []string{"not base64!", "bm8tc2VwYXJhdG9y", "eWVzdGVyZGF5fHUtMQ"} {
+		_, err = svc.ListUsers(context.Background(), model.UserFilter{}, bad, false)
+		require.ErrorIs(t, err, ErrInvalidCursor, bad)
+	}
+}
@@ -0,0 +1,241 @@
+// Code generated manually for tests; gomock-style mock for service.UserStore.
+package mocks
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/model"
+
+	"github.com/golang/mock/gomock"
+)
+
+// MockUserStore is a mock of service.UserStore.
+type MockUserStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserStoreMockRecorder
+}
+
+// MockUserStoreMockRecorder records invocations for MockUserStore.
+type MockUserStoreMockRecorder struct {
+	mock *MockUserStore
+}
+
+// NewMockUserStore creates a new mock instance.
+func NewMockUserStore(ctrl *gomock.Controller) *MockUserStore {
+	mock := &MockUserStore{ctrl: ctrl}
+	mock.recorder = &MockUserStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns the recorder.
+func (m *MockUserStore) EXPECT() *MockUserStoreMockRecorder { return m.recorder }
+
+// Create mocks base method.
+func (m *MockUserStore) Create(ctx context.Context, email, password string, metadata map[string]string, ssoTenant string) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, email, password, metadata, ssoTenant)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates expected call.
+func (mr *MockUserStoreMockRecorder) Create(ctx, email, password, metadata, ssoTenant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserStore)(nil).Create), ctx, email, password, metadata, ssoTenant)
+}
+
+// GetByEmail mocks base method.
+func (m *MockUserStore) GetByEmail(ctx context.Context, email string) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByEmail", ctx, email)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByEmail indicates expected call.
+func (mr *MockUserStoreMockRecorder) GetByEmail(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByEmail", reflect.TypeOf((*MockUserStore)(nil).GetByEmail), ctx, email)
+}
+
+// GetByID mocks base method.
+func (m *MockUserStore) GetByID(ctx context.Context, userID string) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", ctx, userID)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates expected call.
+func (mr *MockUserStoreMockRecorder) GetByID(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockUserStore)(nil).GetByID), ctx, userID)
+}
+
+// UpdateFields mocks base method.
+func (m *MockUserStore) UpdateFields(ctx context.Context, userID string, email, password *string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateFields", ctx, userID, email, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateFields indicates expected call.
+func (mr *MockUserStoreMockRecorder) UpdateFields(ctx, userID, email, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFields", reflect.TypeOf((*MockUserStore)(nil).UpdateFields), ctx, userID, email, password)
+}
+
+// Delete mocks base method.
+func (m *MockUserStore) Delete(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates expected call.
+func (mr *MockUserStoreMockRecorder) Delete(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserStore)(nil).Delete), ctx, userID)
+}
+
+// UpdateMetadata mocks base method.
+func (m *MockUserStore) UpdateMetadata(ctx context.Context, userID string, patch map[string]string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMetadata", ctx, userID, patch)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateMetadata indicates expected call.
+func (mr *MockUserStoreMockRecorder) UpdateMetadata(ctx, userID, patch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMetadata", reflect.TypeOf((*MockUserStore)(nil).UpdateMetadata), ctx, userID, patch)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockUserStore) UpdateProfile(ctx context.Context, userID string, p model.ProfileUpdate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, userID, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProfile indicates expected call.
+func (mr *MockUserStoreMockRecorder) UpdateProfile(ctx, userID, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockUserStore)(nil).UpdateProfile), ctx, userID, p)
+}
+
+// ReplaceEmail mocks base method.
+func (m *MockUserStore) ReplaceEmail(ctx context.Context, userID, expected, newEmail string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceEmail", ctx, userID, expected, newEmail)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceEmail indicates expected call.
+func (mr *MockUserStoreMockRecorder) ReplaceEmail(ctx, userID, expected, newEmail interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceEmail", reflect.TypeOf((*MockUserStore)(nil).ReplaceEmail), ctx, userID, expected, newEmail)
+}
+
+// List mocks base method.
+func (m *MockUserStore) List(ctx context.Context, filter model.UserFilter) ([]model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, filter)
+	ret0, _ := ret[0].([]model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates expected call.
+func (mr *MockUserStoreMockRecorder) List(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockUserStore)(nil).List), ctx, filter)
+}
+
+// Count mocks base method.
+func (m *MockUserStore) Count(ctx context.Context, filter model.UserFilter) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, filter)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates expected call.
+func (mr *MockUserStoreMockRecorder) Count(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockUserStore)(nil).Count), ctx, filter)
+}
+
+// Transition mocks base method.
+func (m *MockUserStore) Transition(ctx context.Context, userID, status string, from ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, userID, status}
+	for _, a := range from {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Transition", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Transition indicates expected call.
+func (mr *MockUserStoreMockRecorder) Transition(ctx, userID, status interface{}, from ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, userID, status}, from...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transition", reflect.TypeOf((*MockUserStore)(nil).Transition), varargs...)
+}
+
+// Restore mocks base method.
+func (m *MockUserStore) Restore(ctx context.Context, userID string, deletedAfter time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, userID, deletedAfter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates expected call.
+func (mr *MockUserStoreMockRecorder) Restore(ctx, userID, deletedAfter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockUserStore)(nil).Restore), ctx, userID, deletedAfter)
+}
+
+// PurgeDeleted mocks base method.
+func (m *MockUserStore) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeleted", ctx, deletedBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeleted indicates expected call.
+func (mr *MockUserStoreMockRecorder) PurgeDeleted(ctx, deletedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockUserStore)(nil).PurgeDeleted), ctx, deletedBefore)
+}
+
+// Anonymize mocks base method.
+func (m *MockUserStore) Anonymize(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Anonymize", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Anonymize indicates expected call.
+func (mr *MockUserStoreMockRecorder) Anonymize(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Anonymize", reflect.TypeOf((*MockUserStore)(nil).Anonymize), ctx, userID)
+}
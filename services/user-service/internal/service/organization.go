@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"saas-subscription-platform/services/user-service/internal/model"
+)
+
+const maxOrgNameLength = 100
+
+// ErrInvalidOrgName: el nombre es obligatorio y de hasta 100 caracteres.
+var ErrInvalidOrgName = errors.New("organization name must be 1-100 characters")
+
+// OrganizationStore persiste organizaciones y membresías.
+type OrganizationStore interface {
+	Create(ctx context.Context, name, ownerID string) (model.Organization, error)
+	Get(ctx context.Context, orgID string) (model.Organization, error)
+	ListForUser(ctx context.Context, userID string) ([]model.UserOrganization, error)
+	GetMembership(ctx context.Context, orgID, userID string) (model.Membership, error)
+}
+
+type OrganizationService struct {
+	orgs OrganizationStore
+}
+
+func NewOrganizationService(orgs OrganizationStore) *OrganizationService {
+	return &OrganizationService{orgs: orgs}
+}
+
+// CreateOrganization crea la organización con ownerID como owner.
+func (s *OrganizationService) CreateOrganization(ctx context.Context, ownerID, name string) (model.Organization, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxOrgNameLength {
+		return model.Organization{}, ErrInvalidOrgName
+	}
+	return s.orgs.Create(ctx, name, ownerID)
+}
+
+// ListUserOrganizations devuelve las organizaciones del usuario con su rol en cada una.
+func (s *OrganizationService) ListUserOrganizations(ctx context.Context, userID string) ([]model.UserOrganization, error) {
+	return s.orgs.ListForUser(ctx, userID)
+}
+
+// GetMembership devuelve repository.ErrMembershipNotFound si el usuario no es miembro.
+func (s *OrganizationService) GetMembership(ctx context.Context, orgID, userID string) (model.Membership, error) {
+	return s.orgs.GetMembership(ctx, orgID, userID)
+}
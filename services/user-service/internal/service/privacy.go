@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+
+	"saas-subscription-platform/libs/trace"
+	"saas-subscription-platform/services/user-service/internal/model"
+)
+
+// ServiceName identifica el paso de user-service dentro de un pedido GDPR.
+const ServiceName = "user-service"
+
+var (
+	// ErrDataRequestNotReady: el export todavía tiene pasos sin terminar.
+	ErrDataRequestNotReady = errors.New("data request is not completed")
+	// ErrDataRequestCompleted: no hay pasos pendientes para reintentar.
+	ErrDataRequestCompleted = errors.New("data request already completed")
+	// ErrArchiveDiscarded: el export se descartó porque el usuario fue borrado.
+	ErrArchiveDiscarded = errors.New("export archive no longer available")
+)
+
+// DataSource es otro servicio que guarda datos del usuario. Erase tiene que ser
+// idempotente: un pedido fallido se reintenta entero sobre los pasos pendientes.
+type DataSource interface {
+	Name() string
+	Export(ctx context.Context, user model.User) (json.RawMessage, error)
+	Erase(ctx context.Context, user model.User, pseudonym string) error
+}
+
+// DataRequestStore persiste los pedidos y el avance por servicio.
+type DataRequestStore interface {
+	Create(ctx context.Context, req model.DataRequest, services []string) (model.DataRequest, error)
+	Get(ctx context.Context, id string) (model.DataRequest, error)
+	SaveStep(ctx context.Context, requestID string, step model.DataRequestStep) error
+	Finish(ctx context.Context, id, status string) error
+	DiscardExports(ctx context.Context, userID string) error
+}
+
+// PrivacyService orquesta el export y el borrado GDPR entre servicios.
+type PrivacyService struct {
+	users        UserStore
+	requests     DataRequestStore
+	sources      []DataSource
+	pseudonymKey []byte
+}
+
+func NewPrivacyService(users UserStore, requests DataRequestStore, pseudonymKey []byte, sources ...DataSource) *PrivacyService {
+	return &PrivacyService{
+		users:        users,
+		requests:     requests,
+		sources:      sources,
+		pseudonymKey: pseudonymKey,
+	}
+}
+
+// RequestExport junta los datos del usuario en todos los servicios.
+func (s *PrivacyService) RequestExport(ctx context.Context, userID, requestedBy string) (model.DataRequest, error) {
+	return s.start(ctx, userID, requestedBy, model.DataRequestExport)
+}
+
+// RequestErasure borra los datos del usuario en todos los servicios. Las
+// facturas se conservan con una referencia seudonimizada.
+func (s *PrivacyService) RequestErasure(ctx context.Context, userID, requestedBy string) (model.DataRequest, error) {
+	return s.start(ctx, userID, requestedBy, model.DataRequestErasure)
+}
+
+func (s *PrivacyService) start(ctx context.Context, userID, requestedBy, kind string) (model.DataRequest, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return model.DataRequest{}, err
+	}
+
+	// En un borrado user-service va último: si otro servicio falla, el
+	// reintento todavía tiene el email para encontrar sus datos.
+	services := make([]string, 0, len(s.sources)+1)
+	if kind == model.DataRequestExport {
+		services = append(services, ServiceName)
+	}
+	for _, src := range s.sources {
+		services = append(services, src.Name())
+	}
+	if kind == model.DataRequestErasure {
+		services = append(services, ServiceName)
+	}
+
+	req, err := s.requests.Create(ctx, model.DataRequest{UserID: userID, Kind: kind, RequestedBy: requestedBy}, services)
+	if err != nil {
+		return model.DataRequest{}, fmt.Errorf("failed to create data request: %w", err)
+	}
+	log.Printf("data_request_started id=%s kind=%s user_id=%s requested_by=%s request_id=%s",
+		req.ID, kind, userID, requestedBy, trace.RequestIDFromContext(ctx))
+
+	return s.run(ctx, req, user)
+}
+
+// Retry vuelve a ejecutar los pasos que no terminaron.
+func (s *PrivacyService) Retry(ctx context.Context, requestID string) (model.DataRequest, error) {
+	req, err := s.requests.Get(ctx, requestID)
+	if err != nil {
+		return model.DataRequest{}, err
+	}
+	if req.Status == model.DataRequestCompleted {
+		return model.DataRequest{}, ErrDataRequestCompleted
+	}
+	user, err := s.users.GetByID(ctx, req.UserID)
+	if err != nil {
+		return model.DataRequest{}, err
+	}
+	return s.run(ctx, req, user)
+}
+
+// GetRequest devuelve el pedido con el avance de cada servicio.
+func (s *PrivacyService) GetRequest(ctx context.Context, requestID string) (model.DataRequest, error) {
+	return s.requests.Get(ctx, requestID)
+}
+
+// Archive devuelve los datos exportados por servicio de un export completo.
+func (s *PrivacyService) Archive(ctx context.Context, requestID string) (model.DataRequest, map[string]json.RawMessage, error) {
+	req, err := s.requests.Get(ctx, requestID)
+	if err != nil {
+		return model.DataRequest{}, nil, err
+	}
+	if req.Kind != model.DataRequestExport || req.Status != model.DataRequestCompleted {
+		return req, nil, ErrDataRequestNotReady
+	}
+	files := make(map[string]json.RawMessage, len(req.Steps))
+	for _, step := range req.Steps {
+		if step.Result == nil {
+			return req, nil, ErrArchiveDiscarded
+		}
+		files[step.Service] = step.Result
+	}
+	return req, files, nil
+}
+
+// Pseudonym es la referencia estable que reemplaza al usuario en los registros
+// que se conservan. Es un HMAC del id con formato UUID (versión 8) para que
+// entre en las columnas user_id existentes y no se pueda revertir sin la clave.
+func (s *PrivacyService) Pseudonym(userID string) string {
+	mac := hmac.New(sha256.New, s.pseudonymKey)
+	mac.Write([]byte(userID))
+	var id uuid.UUID
+	copy(id[:], mac.Sum(nil))
+	id[6] = (id[6] & 0x0f) | 0x80
+	id[8] = (id[8] & 0x3f) | 0x80
+	return id.String()
+}
+
+func (s *PrivacyService) run(ctx context.Context, req model.DataRequest, user model.User) (model.DataRequest, error) {
+	// El avance se guarda aunque el cliente corte: un paso ya ejecutado en
+	// otro servicio tiene que quedar registrado para que el reintento lo saltee.
+	progressCtx := context.WithoutCancel(ctx)
+	failed := false
+	for i, step := range req.Steps {
+		if step.Status == model.StepDone {
+			continue
+		}
+		// El anonimizado local es irreversible: solo cuando el resto terminó.
+		if req.Kind == model.DataRequestErasure && step.Service == ServiceName && failed {
+			continue
+		}
+
+		result, err := s.execute(ctx, req.Kind, step.Service, user)
+		step.Attempts++
+		if err != nil {
+			failed = true
+			step.Status = model.StepFailed
+			step.Error = err.Error()
+			step.Result = nil
+			log.Printf("data_request_step_failed id=%s service=%s attempt=%d err=%v request_id=%s",
+				req.ID, step.Service, step.Attempts, err, trace.RequestIDFromContext(ctx))
+		} else {
+			step.Status = model.StepDone
+			step.Error = ""
+			step.Result = result
+		}
+		if err := s.requests.SaveStep(progressCtx, req.ID, step); err != nil {
+			return model.DataRequest{}, fmt.Errorf("failed to save data request step: %w", err)
+		}
+		req.Steps[i] = step
+	}
+
+	req.Status = model.DataRequestCompleted
+	for _, step := range req.Steps {
+		if step.Status != model.StepDone {
+			req.Status = model.DataRequestFailed
+			break
+		}
+	}
+	if err := s.requests.Finish(progressCtx, req.ID, req.Status); err != nil {
+		return model.DataRequest{}, fmt.Errorf("failed to finish data request: %w", err)
+	}
+	log.Printf("data_request_finished id=%s kind=%s status=%s request_id=%s",
+		req.ID, req.Kind, req.Status, trace.RequestIDFromContext(ctx))
+	return req, nil
+}
+
+func (s *PrivacyService) execute(ctx context.Context, kind, service string, user model.User) (json.RawMessage, error) {
+	if service == ServiceName {
+		if kind == model.DataRequestExport {
+			return json.Marshal(exportProfile(user))
+		}
+		if err := s.users.Anonymize(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		return nil, s.requests.DiscardExports(ctx, user.ID)
+	}
+
+	for _, src := range s.sources {
+		if src.Name() != service {
+			continue
+		}
+		if kind == model.DataRequestExport {
+			return src.Export(ctx, user)
+		}
+		return nil, src.Erase(ctx, user, s.Pseudonym(user.ID))
+	}
+	return nil, fmt.Errorf("unknown service %q", service)
+}
+
+// profileExport es lo que user-service guarda del usuario, sin el hash de la password.
+type profileExport struct {
+	ID              string            `json:"id"`
+	Email           string            `json:"email"`
+	Name            string            `json:"name"`
+	Role            string            `json:"role"`
+	Locale          string            `json:"locale"`
+	Timezone        string            `json:"timezone"`
+	AvatarURL       string            `json:"avatar_url"`
+	Phone           string            `json:"phone"`
+	Status          string            `json:"status"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	EmailVerifiedAt *string           `json:"email_verified_at"`
+	CreatedAt       string            `json:"created_at"`
+}
+
+func exportProfile(user model.User) profileExport {
+	p := profileExport{
+		ID:        user.ID,
+		Email:     user.Email,
+		Name:      user.Name,
+		Role:      user.Role,
+		Locale:    user.Locale,
+		Timezone:  user.Timezone,
+		AvatarURL: user.AvatarURL,
+		Phone:     user.Phone,
+		Status:    user.Status,
+		Metadata:  user.Metadata,
+		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if user.EmailVerifiedAt != nil {
+		v := user.EmailVerifiedAt.Format("2006-01-02T15:04:05Z07:00")
+		p.EmailVerifiedAt = &v
+	}
+	return p
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"saas-subscription-platform/services/user-service/internal/model"
+	"saas-subscription-platform/services/user-service/internal/repository"
+	"saas-subscription-platform/services/user-service/internal/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// memDataRequestStore guarda los pedidos en memoria.
+type memDataRequestStore struct {
+	requests  map[string]model.DataRequest
+	discarded []string
+}
+
+func newMemDataRequestStore() *memDataRequestStore {
+	return &memDataRequestStore{requests: make(map[string]model.DataRequest)}
+}
+
+func (m *memDataRequestStore) Create(_ context.Context, req model.DataRequest, services []string) (model.DataRequest, error) {
+	req.ID = uuid.NewString()
+	req.Status = model.DataRequestPending
+	req.CreatedAt = time.Now()
+	for _, svc := range services {
+		req.Steps = append(req.Steps, model.DataRequestStep{Service: svc, Status: model.StepPending})
+	}
+	m.requests[req.ID] = req
+	return m.clone(req), nil
+}
+
+func (m *memDataRequestStore) Get(_ context.Context, id string) (model.DataRequest, error) {
+	req, ok := m.requests[id]
+	if !ok {
+		return model.DataRequest{}, repository.ErrDataRequestNotFound
+	}
+	return m.clone(req), nil
+}
+
+func (m *memDataRequestStore) SaveStep(_ context.Context, requestID string, step model.DataRequestStep) error {
+	req := m.requests[requestID]
+	for i := range req.Steps {
+		if req.Steps[i].Service == step.Service {
+			req.Steps[i] = step
+		}
+	}
+	return nil
+}
+
+func (m *memDataRequestStore) Finish(_ context.Context, id, status string) error {
+	req := m.requests[id]
+	req.Status = status
+	m.requests[id] = req
+	return nil
+}
+
+func (m *memDataRequestStore) DiscardExports(_ context.Context, userID string) error {
+	m.discarded = append(m.discarded, userID)
+	return nil
+}
+
+func (m *memDataRequestStore) clone(req model.DataRequest) model.DataRequest {
+	req.Steps = append([]model.DataRequestStep(nil), req.Steps...)
+	return req
+}
+
+// fakeSource simula otro servicio; failErase hace fallar los borrados.
+type fakeSource struct {
+	name       string
+	failErase  bool
+	erased     []string
+	pseudonyms []string
+}
+
+func (f *fakeSource) Name() string { return f.name }
+
+func (f *fakeSource) Export(_ context.Context, user model.User) (json.RawMessage, error) {
+	return json.RawMessage(`{"from":"` + f.name + `"}`), nil
+}
+
+func (f *fakeSource) Erase(_ context.Context, user model.User, pseudonym string) error {
+	if f.failErase {
+		return errors.New("unavailable")
+	}
+	f.erased = append(f.erased, user.ID)
+	f.pseudonyms = append(f.pseudonyms, pseudonym)
+	return nil
+}
+
+func newPrivacyService(t *testing.T, sources ...DataSource) (*PrivacyService, *mocks.MockUserStore, *memDataRequestStore) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserStore(ctrl)
+	requests := newMemDataRequestStore()
+	return NewPrivacyService(users, requests, []byte("test-key"), sources...), users, requests
+}
+
+func TestPrivacyService_ExportCollectsEveryService(t *testing.T) {
+	auth := &fakeSource{name: "auth-service"}
+	svc, users, _ := newPrivacyService(t, auth)
+	users.EXPECT().GetByID(gomock.Any(), "u-1").Return(model.User{ID: "u-1", Email: "jane@example.com", Password: "hash"}, nil)
+
+	req, err := svc.RequestExport(context.Background(), "u-1", "u-1")
+	require.NoError(t, err)
+	require.Equal(t, model.DataRequestCompleted, req.Status)
+
+	_, files, err := svc.Archive(context.Background(), req.ID)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"from":"auth-service"}`, string(files["auth-service"]))
+	require.Contains(t, string(files[ServiceName]), "jane@example.com")
+	require.NotContains(t, string(files[ServiceName]), "hash")
+}
+
+func TestPrivacyService_ErasureRetriesFailedSteps(t *testing.T) {
+	auth := &fakeSource{name: "auth-service"}
+	billing := &fakeSource{name: "billing-service", failErase: true}
+	svc, users, requests := newPrivacyService(t, auth, billing)
+	users.EXPECT().GetByID(gomock.Any(), "u-1").Return(model.User{ID: "u-1", Email: "jane@example.com"}, nil).Times(2)
+
+	req, err := svc.RequestErasure(context.Background(), "u-1", "admin-1")
+	require.NoError(t, err)
+	require.Equal(t, model.DataRequestFailed, req.Status)
+	require.Equal(t, []string{"auth-service", "billing-service", ServiceName}, []string{req.Steps[0].Service, req.Steps[1].Service, req.Steps[2].Service})
+	require.Equal(t, model.StepDone, req.Steps[0].Status)
+	require.Equal(t, model.StepFailed, req.Steps[1].Status)
+	// Con un servicio fallido el usuario todavía no se anonimiza.
+	require.Equal(t, model.StepPending, req.Steps[2].Status)
+
+	// El reintento solo ejecuta lo pendiente y termina con el anonimizado local.
+	billing.failErase = false
+	users.EXPECT().Anonymize(gomock.Any(), "u-1").Return(nil)
+	req, err = svc.Retry(context.Background(), req.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.DataRequestCompleted, req.Status)
+	require.Len(t, auth.erased, 1)
+	require.Equal(t, 2, req.Steps[1].Attempts)
+	require.Equal(t, []string{"u-1"}, requests.discarded)
+	require.Equal(t, svc.Pseudonym("u-1"), billing.pseudonyms[0])
+
+	_, err = svc.Retry(context.Background(), req.ID)
+	require.ErrorIs(t, err, ErrDataRequestCompleted)
+}
+
+func TestPrivacyService_ArchiveRequiresCompletedExport(t *testing.T) {
+	svc, users, _ := newPrivacyService(t, &fakeSource{name: "auth-service", failErase: true})
+	users.EXPECT().GetByID(gomock.Any(), "u-1").Return(model.User{ID: "u-1"}, nil)
+	users.EXPECT().Anonymize(gomock.Any(), "u-1").Times(0)
+
+	req, err := svc.RequestErasure(context.Background(), "u-1", "u-1")
+	require.NoError(t, err)
+
+	_, _, err = svc.Archive(context.Background(), req.ID)
+	require.ErrorIs(t, err, ErrDataRequestNotReady)
+}
+
+func TestPrivacyService_Pseudonym(t *testing.T) {
+	svc, _, _ := newPrivacyService(t)
+
+	p := svc.Pseudonym("u-1")
+	id, err := uuid.Parse(p)
+	require.NoError(t, err)
+	require.Equal(t, uuid.Version(8), id.Version())
+	require.Equal(t, p, svc.Pseudonym("u-1"))
+	require.NotEqual(t, p, svc.Pseudonym("u-2"))
+
+	other := NewPrivacyService(nil, nil, []byte("other-key"))
+	require.NotEqual(t, p, other.Pseudonym("u-1"))
+}
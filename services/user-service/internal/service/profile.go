@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"saas-subscription-platform/libs/locale"
+	"saas-subscription-platform/services/user-service/internal/model"
+)
+
+const (
+	maxNameLength      = 100
+	maxAvatarURLLength = 2048
+)
+
+// e164 acepta "+" seguido de 8 a 15 dígitos sin cero inicial.
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// ProfileError lista los campos de perfil inválidos (campo -> motivo).
+type ProfileError struct {
+	Fields map[string]string
+}
+
+func (e *ProfileError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for field, reason := range e.Fields {
+		parts = append(parts, field+": "+reason)
+	}
+	sort.Strings(parts)
+	return "invalid profile: " + strings.Join(parts, "; ")
+}
+
+// NormalizeProfile valida los campos presentes y devuelve su forma canónica.
+// Nombre, avatar y teléfono aceptan "" para borrarlos; locale y timezone no.
+func NormalizeProfile(p model.ProfileUpdate) (model.ProfileUpdate, error) {
+	fields := map[string]string{}
+	var out model.ProfileUpdate
+
+	if p.Name != nil {
+		name := strings.TrimSpace(*p.Name)
+		switch {
+		case utf8.RuneCountInString(name) > maxNameLength:
+			fields["name"] = fmt.Sprintf("must be at most %d characters", maxNameLength)
+		case strings.IndexFunc(name, unicode.IsControl) >= 0:
+			fields["name"] = "must not contain control characters"
+		default:
+			out.Name = &name
+		}
+	}
+
+	if p.Locale != nil {
+		if loc, err := locale.ParseLocale(*p.Locale); err != nil {
+			fields["locale"] = "must be a BCP 47 language tag"
+		} else {
+			out.Locale = &loc
+		}
+	}
+
+	if p.Timezone != nil {
+		if tz, err := locale.ParseTimezone(*p.Timezone); err != nil {
+			fields["timezone"] = "must be an IANA time zone"
+		} else {
+			out.Timezone = &tz
+		}
+	}
+
+	if p.AvatarURL != nil {
+		raw := strings.TrimSpace(*p.AvatarURL)
+		if raw != "" {
+			u, err := url.Parse(raw)
+			if err != nil || u.Scheme != "https" || u.Host == "" || len(raw) > maxAvatarURLLength {
+				fields["avatar_url"] = "must be an absolute https URL"
+			}
+		}
+		if _, bad := fields["avatar_url"]; !bad {
+			out.AvatarURL = &raw
+		}
+	}
+
+	if p.Phone != nil {
+		phone := normalizePhone(*p.Phone)
+		if phone != "" && !e164.MatchString(phone) {
+			fields["phone"] = "must be in E.164 format (e.g. +5491122334455)"
+		} else {
+			out.Phone = &phone
+		}
+	}
+
+	if len(fields) > 0 {
+		return model.ProfileUpdate{}, &ProfileError{Fields: fields}
+	}
+	return out, nil
+}
+
+// normalizePhone quita separadores habituales (espacios, guiones, puntos, paréntesis).
+func normalizePhone(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+}
+
+// UpdateProfile valida y guarda los campos de perfil presentes.
+func (s *UserService) UpdateProfile(ctx context.Context, userID string, p model.ProfileUpdate) error {
+	normalized, err := NormalizeProfile(p)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdateProfile(ctx, userID, normalized)
+}
@@ -1,44 +1,126 @@
 package service
 
 import (
+	"context"
+	"time"
+
+	"saas-subscription-platform/libs/emailaddr"
+	"saas-subscription-platform/libs/metadata"
 	"saas-subscription-platform/services/user-service/internal/model"
+	"saas-subscription-platform/services/user-service/internal/repository"
 )
 
 // UserStore define las operaciones que la capa de servicio necesita del repositorio.
 type UserStore interface {
-	Create(email, password string) (model.User, error)
-	GetByEmail(email string) (model.User, error)
-	GetByID(userID string) (model.User, error)
-	UpdateFields(userID string, email, password *string) error
-	Delete(userID string) error
+	Create(ctx context.Context, email, password string, metadata map[string]string, ssoTenant string) (model.User, error)
+	GetByEmail(ctx context.Context, email string) (model.User, error)
+	GetByID(ctx context.Context, userID string) (model.User, error)
+	UpdateFields(ctx context.Context, userID string, email, password *string) error
+	UpdateProfile(ctx context.Context, userID string, p model.ProfileUpdate) error
+	UpdateMetadata(ctx context.Context, userID string, patch map[string]string) (map[string]string, error)
+	ReplaceEmail(ctx context.Context, userID, expected, newEmail string) error
+	Delete(ctx context.Context, userID string) error
+	Transition(ctx context.Context, userID, status string, from ...string) error
+	Restore(ctx context.Context, userID string, deletedAfter time.Time) error
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
+	Anonymize(ctx context.Context, userID string) error
+	List(ctx context.Context, filter model.UserFilter) ([]model.User, error)
+	Count(ctx context.Context, filter model.UserFilter) (int, error)
 }
 
+// defaultDeletionGracePeriod es cuánto se puede restaurar una cuenta borrada antes de la purga.
+const defaultDeletionGracePeriod = 30 * 24 * time.Hour
+
 type UserService struct {
-	repo UserStore
+	repo        UserStore
+	gracePeriod time.Duration
+	now         func() time.Time
+}
+
+// Option configura dependencias opcionales del servicio.
+type Option func(*UserService)
+
+// WithDeletionGracePeriod define la ventana de restauración de cuentas borradas.
+func WithDeletionGracePeriod(d time.Duration) Option {
+	return func(s *UserService) {
+		if d > 0 {
+			s.gracePeriod = d
+		}
+	}
 }
 
-func NewUserService(repo UserStore) *UserService {
-	return &UserService{
-		repo: repo,
+func NewUserService(repo UserStore, opts ...Option) *UserService {
+	s := &UserService{
+		repo:        repo,
+		gracePeriod: defaultDeletionGracePeriod,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-func (s *UserService) CreateUser(email, password string) (model.User, error) {
-	return s.repo.Create(email, password)
+// CreateUser devuelve emailaddr.ErrInvalidEmail si el email no es válido y un
+// error metadata.ErrInvalid si la metadata no respeta los límites. ssoTenant
+// marca la cuenta como provisionada por ese tenant SAML ("" si no).
+func (s *UserService) CreateUser(ctx context.Context, email, password string, meta map[string]string, ssoTenant string) (model.User, error) {
+	normalized, err := emailaddr.Normalize(email)
+	if err != nil {
+		return model.User{}, err
+	}
+	if err := metadata.Validate(meta, false); err != nil {
+		return model.User{}, err
+	}
+	return s.repo.Create(ctx, normalized, password, meta, ssoTenant)
 }
 
-func (s *UserService) GetUserByEmail(email string) (model.User, error) {
-	return s.repo.GetByEmail(email)
+// GetUserByEmail ignora mayúsculas; un email inválido no puede existir.
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
+	normalized, err := emailaddr.Normalize(email)
+	if err != nil {
+		return model.User{}, repository.ErrUserNotFound
+	}
+	return s.repo.GetByEmail(ctx, normalized)
 }
 
-func (s *UserService) GetUserByID(userID string) (model.User, error) {
-	return s.repo.GetByID(userID)
+func (s *UserService) GetUserByID(ctx context.Context, userID string) (model.User, error) {
+	return s.repo.GetByID(ctx, userID)
 }
 
-func (s *UserService) UpdateUser(userID string, email *string, password *string) error {
-	return s.repo.UpdateFields(userID, email, password)
+func (s *UserService) UpdateUser(ctx context.Context, userID string, email *string, password *string) error {
+	if email != nil {
+		normalized, err := emailaddr.Normalize(*email)
+		if err != nil {
+			return err
+		}
+		email = &normalized
+	}
+	return s.repo.UpdateFields(ctx, userID, email, password)
+}
+
+// UpdateMetadata mezcla patch con la metadata actual; un valor "" borra la clave.
+func (s *UserService) UpdateMetadata(ctx context.Context, userID string, patch map[string]string) (map[string]string, error) {
+	if err := metadata.Validate(patch, true); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateMetadata(ctx, userID, patch)
+}
+
+// SetPassword guarda un hash ya calculado por auth-service.
+func (s *UserService) SetPassword(ctx context.Context, userID, passwordHash string) error {
+	return s.repo.UpdateFields(ctx, userID, nil, &passwordHash)
+}
+
+func (s *UserService) ReplaceEmail(ctx context.Context, userID, expected, newEmail string) error {
+	normalized, err := emailaddr.Normalize(newEmail)
+	if err != nil {
+		return err
+	}
+	return s.repo.ReplaceEmail(ctx, userID, expected, normalized)
 }
 
-func (s *UserService) DeleteUser(userID string) error {
-	return s.repo.Delete(userID)
+// DeleteUser marca la cuenta para borrado; se purga al vencer la gracia.
+func (s *UserService) DeleteUser(ctx context.Context, userID string) error {
+	return s.repo.Delete(ctx, userID)
 }
@@ -1,9 +1,12 @@
 package service
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
 
+	"saas-subscription-platform/libs/emailaddr"
 	"saas-subscription-platform/services/user-service/internal/model"
 	"saas-subscription-platform/services/user-service/internal/repository"
 	"saas-subscription-platform/services/user-service/internal/service/mocks"
@@ -20,26 +23,76 @@ func TestUserService_DelegatesToStore(t *testing.T) {
 	createdAt := time.Now()
 	expectedUser := model.User{ID: "u-1", Email: "alice@example.com", Password: "hash", CreatedAt: createdAt}
 
-	store.EXPECT().Create("alice@example.com", "hash").Return(expectedUser, nil)
-	user, err := svc.CreateUser("alice@example.com", "hash")
+	store.EXPECT().Create(gomock.Any(), "alice@example.com", "hash", map[string]string(nil), "").Return(expectedUser, nil)
+	user, err := svc.CreateUser(context.Background(), "alice@example.com", "hash", nil, "")
 	require.NoError(t, err)
 	require.Equal(t, expectedUser, user)
 
-	store.EXPECT().GetByEmail("alice@example.com").Return(expectedUser, nil)
-	user, err = svc.GetUserByEmail("alice@example.com")
+	store.EXPECT().GetByEmail(gomock.Any(), "alice@example.com").Return(expectedUser, nil)
+	user, err = svc.GetUserByEmail(context.Background(), "alice@example.com")
 	require.NoError(t, err)
 	require.Equal(t, expectedUser, user)
 
-	store.EXPECT().GetByID("u-1").Return(expectedUser, nil)
-	user, err = svc.GetUserByID("u-1")
+	store.EXPECT().GetByID(gomock.Any(), "u-1").Return(expectedUser, nil)
+	user, err = svc.GetUserByID(context.Background(), "u-1")
 	require.NoError(t, err)
 	require.Equal(t, expectedUser, user)
 
 	newEmail := "new@example.com"
-	store.EXPECT().UpdateFields("u-1", &newEmail, (*string)(nil)).Return(repository.ErrUserExists)
-	err = svc.UpdateUser("u-1", &newEmail, nil)
+	store.EXPECT().UpdateFields(gomock.Any(), "u-1", &newEmail, (*string)(nil)).Return(repository.ErrUserExists)
+	err = svc.UpdateUser(context.Background(), "u-1", &newEmail, nil)
 	require.ErrorIs(t, err, repository.ErrUserExists)
 
-	store.EXPECT().Delete("u-1").Return(nil)
-	require.NoError(t, svc.DeleteUser("u-1"))
+	newHash := "new-hash"
+	store.EXPECT().UpdateFields(gomock.Any(), "u-1", (*string)(nil), &newHash).Return(nil)
+	require.NoError(t, svc.SetPassword(context.Background(), "u-1", "new-hash"))
+
+	store.EXPECT().ReplaceEmail(gomock.Any(), "u-1", "alice@example.com", "new@example.com").Return(repository.ErrEmailMismatch)
+	require.ErrorIs(t, svc.ReplaceEmail(context.Background(), "u-1", "alice@example.com", "new@example.com"), repository.ErrEmailMismatch)
+
+	store.EXPECT().Delete(gomock.Any(), "u-1").Return(nil)
+	require.NoError(t, svc.DeleteUser(context.Background(), "u-1"))
+}
+
+func TestUserService_NormalizesEmails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockUserStore(ctrl)
+	svc := NewUserService(store)
+
+	store.EXPECT().Create(gomock.Any(), "Alice@example.com", "hash", map[string]string(nil), "").Return(model.User{ID: "u-1"}, nil)
+	_, err := svc.CreateUser(context.Background(), "  Alice@EXAMPLE.com ", "hash", nil, "")
+	require.NoError(t, err)
+
+	store.EXPECT().GetByEmail(gomock.Any(), "alice@example.com").Return(model.User{ID: "u-1"}, nil)
+	_, err = svc.GetUserByEmail(context.Background(), "alice@Example.Com")
+	require.NoError(t, err)
+
+	// Un email inválido no puede existir: no se consulta la base.
+	_, err = svc.GetUserByEmail(context.Background(), "not-an-email")
+	require.ErrorIs(t, err, repository.ErrUserNotFound)
+
+	_, err = svc.CreateUser(context.Background(), "Alice <alice@example.com>", "hash", nil, "")
+	require.ErrorIs(t, err, emailaddr.ErrInvalidEmail)
+
+	bad := "alice@"
+	require.ErrorIs(t, svc.UpdateUser(context.Background(), "u-1", &bad, nil), emailaddr.ErrInvalidEmail)
+	require.ErrorIs(t, svc.ReplaceEmail(context.Background(), "u-1", "alice@example.com", bad), emailaddr.ErrInvalidEmail)
+}
+
+func TestUserService_UpdateProfileNormalizes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockUserStore(ctrl)
+	svc := NewUserService(store)
+
+	locale := "pt-br"
+	empty := ""
+	want := "pt-BR"
+	store.EXPECT().UpdateProfile(gomock.Any(), "u-1", model.ProfileUpdate{Locale: &want, AvatarURL: &empty}).Return(nil)
+	require.NoError(t, svc.UpdateProfile(context.Background(), "u-1", model.ProfileUpdate{Locale: &locale, AvatarURL: &empty}))
+
+	long := strings.Repeat("a", 101)
+	err := svc.UpdateProfile(context.Background(), "u-1", model.ProfileUpdate{Name: &long})
+	var perr *ProfileError
+	require.ErrorAs(t, err, &perr)
+	require.Contains(t, perr.Fields, "name")
 }
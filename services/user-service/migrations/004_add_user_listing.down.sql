@@ -0,0 +1,9 @@
+-- pg_trgm queda instalada: la extensión es de la base, no de este servicio.
+DROP INDEX IF EXISTS users_email_trgm_idx;
+DROP INDEX IF EXISTS users_status_created_at_idx;
+DROP INDEX IF EXISTS users_role_created_at_idx;
+DROP INDEX IF EXISTS users_created_at_id_idx;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_status_check;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
+ALTER TABLE users DROP COLUMN IF EXISTS status;
@@ -0,0 +1,15 @@
+-- Estado de la cuenta y verificación de email, filtrables desde el listado de soporte
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP NULL;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_status_check;
+ALTER TABLE users ADD CONSTRAINT users_status_check CHECK (status IN ('active'));
+
+-- Paginación keyset: ORDER BY created_at DESC, id DESC
+CREATE INDEX IF NOT EXISTS users_created_at_id_idx ON users (created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS users_role_created_at_idx ON users (role, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS users_status_created_at_idx ON users (status, created_at DESC, id DESC);
+
+-- Búsqueda por substring de email (ILIKE '%...%')
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS users_email_trgm_idx ON users USING gin (email gin_trgm_ops);
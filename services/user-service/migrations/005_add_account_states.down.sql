@@ -0,0 +1,7 @@
+-- Falla si quedan cuentas fuera de 'active': hay que resolverlas antes de volver atrás.
+DROP INDEX IF EXISTS users_pending_deletion_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS status_changed_at;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_status_check;
+ALTER TABLE users ADD CONSTRAINT users_status_check CHECK (status IN ('active'));
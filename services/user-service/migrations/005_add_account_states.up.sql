@@ -0,0 +1,10 @@
+-- Ciclo de vida de la cuenta: el borrado es lógico y se purga al vencer la gracia
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_status_check;
+ALTER TABLE users ADD CONSTRAINT users_status_check
+    CHECK (status IN ('active', 'suspended', 'deactivated', 'pending_deletion'));
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP NOT NULL DEFAULT now();
+-- deleted_at solo está presente con status = 'pending_deletion'
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP NULL;
+
+CREATE INDEX IF NOT EXISTS users_pending_deletion_idx ON users (deleted_at) WHERE status = 'pending_deletion';
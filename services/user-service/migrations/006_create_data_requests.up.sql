@@ -0,0 +1,30 @@
+-- Pedidos GDPR (export y borrado) con el avance por servicio para poder reintentar
+CREATE TABLE IF NOT EXISTS data_requests (
+    id UUID PRIMARY KEY,
+    -- sin FK: el pedido tiene que sobrevivir a la purga del usuario
+    user_id UUID NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('export', 'erasure')),
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'failed')),
+    requested_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    completed_at TIMESTAMP NULL
+);
+
+CREATE INDEX IF NOT EXISTS data_requests_user_idx ON data_requests (user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS data_request_steps (
+    request_id UUID NOT NULL REFERENCES data_requests (id) ON DELETE CASCADE,
+    service TEXT NOT NULL,
+    -- orden de ejecución: en un borrado, user-service va último
+    position INT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'done', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    -- datos exportados por el servicio; se borran si después se pide la eliminación
+    result JSONB NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (request_id, service)
+);
+
+-- erased_at marca cuentas anonimizadas: ya no se pueden restaurar
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erased_at TIMESTAMP NULL;
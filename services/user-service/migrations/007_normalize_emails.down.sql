@@ -0,0 +1,2 @@
+-- Los emails ya normalizados no se revierten; solo vuelve la unicidad exacta.
+DROP INDEX IF EXISTS users_email_lower_key;
@@ -0,0 +1,42 @@
+-- Emails sin distinguir mayúsculas: se normalizan los existentes (trim, NFC y
+-- dominio en minúsculas, igual que libs/emailaddr) y la unicidad pasa a ser
+-- sobre lower(email).
+--
+-- Si hay cuentas que solo difieren en mayúsculas, la migración las lista y
+-- aborta sin cambiar nada: hay que fusionarlas o renombrarlas a mano antes
+-- de volver a correrla.
+DO $$
+DECLARE
+    collision RECORD;
+    total INT := 0;
+BEGIN
+    FOR collision IN
+        SELECT lower(normalize(btrim(email), NFC)) AS normalized,
+               string_agg(id::text || ' <' || email || '>', ', ' ORDER BY created_at) AS accounts
+        FROM users
+        GROUP BY 1
+        HAVING COUNT(*) > 1
+    LOOP
+        total := total + 1;
+        RAISE WARNING 'email collision %: %', collision.normalized, collision.accounts;
+    END LOOP;
+
+    IF total > 0 THEN
+        RAISE EXCEPTION '% case-insensitive email collision(s) found; resolve them before migrating', total;
+    END IF;
+END
+$$;
+
+-- Direcciones sin '@' (datos viejos inválidos) se dejan como están.
+WITH normalized AS (
+    SELECT id, normalize(btrim(email), NFC) AS email
+    FROM users
+    WHERE email LIKE '%@%'
+)
+UPDATE users u
+SET email = substring(n.email from '^(.*)@[^@]*$') || '@' || lower(substring(n.email from '@([^@]*)$'))
+FROM normalized n
+WHERE u.id = n.id
+  AND u.email <> substring(n.email from '^(.*)@[^@]*$') || '@' || lower(substring(n.email from '@([^@]*)$'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (lower(email));
@@ -0,0 +1,18 @@
+-- Organizaciones (clientes de la plataforma) y quiénes pertenecen a cada una
+CREATE TABLE IF NOT EXISTS organizations (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    org_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'billing', 'member')),
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+-- "Mis organizaciones"
+CREATE INDEX IF NOT EXISTS organization_members_user_idx ON organization_members (user_id);
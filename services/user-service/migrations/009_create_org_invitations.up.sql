@@ -0,0 +1,20 @@
+-- Invitaciones por email a una organización. El token viaja solo en el email:
+-- acá se guarda su hash, que cambia con cada reenvío.
+CREATE TABLE IF NOT EXISTS org_invitations (
+    id UUID PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('admin', 'billing', 'member')),
+    token_hash TEXT NOT NULL,
+    invited_by UUID NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP NULL,
+    accepted_by UUID NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- Una sola invitación abierta por organización y email
+CREATE UNIQUE INDEX IF NOT EXISTS org_invitations_open_email_key
+    ON org_invitations (org_id, lower(email))
+    WHERE accepted_at IS NULL AND revoked_at IS NULL;
@@ -0,0 +1,3 @@
+-- Tenant SAML que creó la cuenta por just-in-time provisioning ('' si se
+-- registró con password). Un login SSO solo entra a cuentas de su tenant.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS sso_tenant TEXT NOT NULL DEFAULT '';